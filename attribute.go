@@ -73,12 +73,14 @@ func (n *Attribute) AType() enum.AttributeType {
 
 // SetAType sets the attribute type.
 func (n *Attribute) SetAType(v enum.AttributeType) {
+	n.doc.mustBeginMutation()
 	n.atype = v
 }
 
 // SetDefault marks (or unmarks) this attribute as a default attribute,
 // i.e. one the DTD supplied and the source document never wrote.
 func (n *Attribute) SetDefault(b bool) {
+	n.doc.mustBeginMutation()
 	n.defaultAttr = b
 }
 
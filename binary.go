@@ -0,0 +1,759 @@
+package helium
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/lestrrat-go/helium/enum"
+)
+
+// binaryMagic starts every binary document. Like the PNG signature, it
+// includes a high-bit byte and line endings, so that a file mangled by a
+// text-mode transfer is recognized as not being one.
+const binaryMagic = "\x89HLM\r\n\x1a\n"
+
+// binaryVersion is the version of the binary document format MarshalBinary
+// writes and the only one LoadBinary reads.
+const binaryVersion = 1
+
+var binaryChecksum = crc32.MakeTable(crc32.Castagnoli)
+
+// binaryMinRecord is the fewest bytes of input each of the counts in the
+// header stands for, in header order: an element, text, attribute, comment,
+// CDATA section or processing instruction record, a namespace declaration,
+// a namespace binding past the reference to it, and a byte of character
+// data. LoadBinary checks the counts against the input with it before
+// sizing the arenas by them.
+var binaryMinRecord = [...]int{9, 5, 9, 4, 4, 5, 1, 3, 1}
+
+// The records of the binary format. Nodes are tagged with their ElementType;
+// a zero tag ends a list of children.
+const binaryEnd = 0
+
+// MarshalBinary encodes doc in helium's binary document format, which
+// [LoadBinary] reads back much faster than the text can be parsed.
+//
+// The encoding keeps everything the parser records about a document, not
+// only its XML: the internal and external DTD subsets with their entity,
+// element, attribute and notation declarations, namespace declarations and
+// the bindings every node refers to, the ID table, the document URL and the
+// base URIs of nodes from external entities, line numbers, the XML
+// declaration, and the document properties. Source positions recorded by
+// Parser.TrackPositions and lexical markup recorded by
+// Parser.PreserveLexical are not carried over.
+//
+// The format is specific to helium. It starts with a signature and a format
+// version and ends with a CRC-32C checksum of what precedes it, so a
+// truncated or damaged file is rejected rather than misread. It is meant
+// for caching parsed documents, not for interchange: a future version of
+// helium may no longer read what this one writes, in which case the
+// document has to be parsed again from its text.
+func MarshalBinary(doc *Document) ([]byte, error) {
+	if doc == nil {
+		return nil, ErrNilNode
+	}
+	e := binaryEncoder{
+		strings:    map[string]uint64{"": 0},
+		namespaces: map[*Namespace]uint64{},
+	}
+	if len(doc.ids) > 0 {
+		e.elems = make(map[*Element]uint64)
+	}
+	e.uvarint(uint64(doc.etype))
+	e.string(doc.version)
+	e.string(doc.encoding)
+	e.varint(int64(doc.standalone))
+	e.string(doc.url)
+	e.uvarint(uint64(doc.properties))
+	e.bool(doc.idsSkip)
+	e.uvarint(uint64(len(doc.standaloneNormAttrs)))
+	for _, a := range doc.standaloneNormAttrs {
+		e.string(a.elem)
+		e.string(a.attr)
+	}
+	e.dtd(doc.intSubset)
+	e.dtd(doc.extSubset)
+	if err := e.children(doc); err != nil {
+		return nil, err
+	}
+
+	// The ID table refers to elements by their position in document order.
+	// IDs are written sorted, so that equal documents encode the same.
+	e.uvarint(uint64(len(doc.ids)))
+	for _, id := range slices.Sorted(maps.Keys(doc.ids)) {
+		elem := doc.ids[id]
+		e.string(id)
+		ord, ok := e.elems[elem]
+		if !ok {
+			return nil, fmt.Errorf("helium: ID %q refers to an element outside the document: %w", id, ErrInvalidOperation)
+		}
+		e.uvarint(ord)
+	}
+
+	// The header sizes the arenas the loader builds the tree in; the number
+	// of namespace bindings is only known once the body is written.
+	var c freezeCounts
+	c.count(doc)
+	c.namespaces = len(e.namespaces)
+	b := make([]byte, 0, len(binaryMagic)+10*binary.MaxVarintLen64+e.buf.Len()+4)
+	b = append(b, binaryMagic...)
+	b = binary.AppendUvarint(b, binaryVersion)
+	for _, n := range []int{c.elems, c.texts, c.attrs, c.comments, c.cdata, c.pis, c.nsDecls, c.namespaces, c.content} {
+		b = binary.AppendUvarint(b, uint64(n))
+	}
+	b = append(b, e.buf.Bytes()...)
+	return binary.LittleEndian.AppendUint32(b, crc32.Checksum(b, binaryChecksum)), nil
+}
+
+type binaryEncoder struct {
+	buf        bytes.Buffer
+	scratch    [binary.MaxVarintLen64]byte
+	strings    map[string]uint64
+	namespaces map[*Namespace]uint64
+	elems      map[*Element]uint64 // document-order position, for the ID table
+	nelems     uint64
+}
+
+func (e *binaryEncoder) uvarint(v uint64) {
+	e.buf.Write(binary.AppendUvarint(e.scratch[:0], v))
+}
+
+func (e *binaryEncoder) varint(v int64) {
+	e.buf.Write(binary.AppendVarint(e.scratch[:0], v))
+}
+
+func (e *binaryEncoder) bool(v bool) {
+	if v {
+		e.buf.WriteByte(1)
+	} else {
+		e.buf.WriteByte(0)
+	}
+}
+
+func (e *binaryEncoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.buf.Write(b)
+}
+
+// string writes s through the string table: a string seen before is written
+// as its index plus one, a new one as a zero followed by its bytes.
+func (e *binaryEncoder) string(s string) {
+	if i, ok := e.strings[s]; ok {
+		e.uvarint(i + 1)
+		return
+	}
+	e.strings[s] = uint64(len(e.strings))
+	e.uvarint(0)
+	e.uvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+// namespace writes a reference to ns: zero for none, one followed by the
+// binding the first time ns is referred to, and its index plus two after
+// that. Declarations and the nodes bound to them thereby share one
+// Namespace again when loaded.
+func (e *binaryEncoder) namespace(ns *Namespace) {
+	if ns == nil {
+		e.uvarint(0)
+		return
+	}
+	if i, ok := e.namespaces[ns]; ok {
+		e.uvarint(i + 2)
+		return
+	}
+	e.namespaces[ns] = uint64(len(e.namespaces))
+	e.uvarint(1)
+	e.uvarint(uint64(ns.etype))
+	e.string(ns.prefix)
+	e.string(ns.href)
+}
+
+// node writes the tag of n and the fields every node kind shares.
+func (e *binaryEncoder) node(n Node) {
+	dn := n.baseDocNode()
+	e.uvarint(uint64(n.Type()))
+	e.uvarint(uint64(dn.line))
+	e.string(dn.entityBaseURI)
+}
+
+func (e *binaryEncoder) dtd(dtd *DTD) {
+	if dtd == nil {
+		e.bool(false)
+		return
+	}
+	e.bool(true)
+	e.uvarint(uint64(dtd.line))
+	e.string(dtd.name)
+	e.string(dtd.externalID)
+	e.string(dtd.systemID)
+	for c := range Children(dtd) {
+		switch c := c.(type) {
+		case *Entity:
+			e.node(c)
+			e.string(c.name)
+			e.uvarint(uint64(c.entityType))
+			e.string(c.externalID)
+			e.string(c.systemID)
+			e.string(c.content)
+			e.string(c.orig)
+			e.string(c.replacement)
+			e.string(c.uri)
+			e.varint(int64(c.checked))
+			e.varint(c.expandedSize)
+		case *ElementDecl:
+			e.node(c)
+			e.string(c.name)
+			e.string(c.prefix)
+			e.uvarint(uint64(c.decltype))
+			e.elementContent(c.content)
+		case *AttributeDecl:
+			e.node(c)
+			e.string(c.name)
+			e.string(c.prefix)
+			e.string(c.elem)
+			e.uvarint(uint64(c.atype))
+			e.uvarint(uint64(c.def))
+			e.string(c.defvalue)
+			e.uvarint(uint64(len(c.tree)))
+			for _, v := range c.tree {
+				e.string(v)
+			}
+			e.bool(c.external)
+		case *Notation:
+			e.node(c)
+			e.string(c.name)
+			e.string(c.publicID)
+			e.string(c.systemID)
+		case *Comment:
+			e.node(c)
+			e.bytes(c.content)
+		case *ProcessingInstruction:
+			e.node(c)
+			e.string(c.target)
+			e.string(c.data)
+		}
+	}
+	e.uvarint(binaryEnd)
+}
+
+func (e *binaryEncoder) elementContent(c *ElementContent) {
+	if c == nil {
+		e.bool(false)
+		return
+	}
+	e.bool(true)
+	e.uvarint(uint64(c.ctype))
+	e.uvarint(uint64(c.coccur))
+	e.string(c.name)
+	e.string(c.prefix)
+	e.elementContent(c.c1)
+	e.elementContent(c.c2)
+}
+
+// children writes the owned children of n, in order, followed by the end
+// tag.
+func (e *binaryEncoder) children(n Node) error {
+	for c := range Children(n) {
+		switch c := c.(type) {
+		case *DTD:
+			// The subsets were written with the document; only the place of
+			// the internal one among the document's children is recorded.
+			if c != c.doc.intSubset {
+				continue
+			}
+			e.node(c)
+		case *Element:
+			if err := e.element(c); err != nil {
+				return err
+			}
+		case *Text:
+			e.node(c)
+			e.bytes(c.content)
+			e.bool(c.fromCharRef)
+		case *CDATASection:
+			e.node(c)
+			e.bytes(c.content)
+		case *Comment:
+			e.node(c)
+			e.bytes(c.content)
+		case *ProcessingInstruction:
+			e.node(c)
+			e.string(c.target)
+			e.string(c.data)
+		case *EntityRef:
+			e.node(c)
+			e.string(c.name)
+		case *XIncludeMarker:
+			e.node(c)
+			e.string(c.name)
+		default:
+			return fmt.Errorf("helium: cannot encode %s node: %w", c.Type(), ErrInvalidOperation)
+		}
+	}
+	e.uvarint(binaryEnd)
+	return nil
+}
+
+func (e *binaryEncoder) element(elem *Element) error {
+	if e.elems != nil {
+		e.elems[elem] = e.nelems
+	}
+	e.nelems++
+	e.node(elem)
+	e.string(elem.name)
+	e.namespace(elem.ns)
+	e.bool(elem.contentHasReference)
+	e.uvarint(uint64(len(elem.nsDefs)))
+	for _, ns := range elem.nsDefs {
+		e.namespace(ns)
+	}
+	var nattrs uint64
+	for a := elem.properties; a != nil; a = a.NextAttribute() {
+		nattrs++
+	}
+	e.uvarint(nattrs)
+	for a := elem.properties; a != nil; a = a.NextAttribute() {
+		e.node(a)
+		e.string(a.name)
+		e.namespace(a.ns)
+		e.uvarint(uint64(a.atype))
+		e.bool(a.defaultAttr)
+		e.bool(a.syntheticBase)
+		if err := e.children(a); err != nil {
+			return err
+		}
+	}
+	return e.children(elem)
+}
+
+// LoadBinary reads a document written by [MarshalBinary]. It returns an
+// error wrapping [ErrBinaryFormat] for input that is not a binary document
+// or is damaged, and one wrapping [ErrBinaryVersion] for a binary document
+// in a format version this helium does not read.
+//
+// The document is laid out much like one produced by [Freeze] — each kind
+// of node in one array, character data in a single buffer — but it is an
+// ordinary, mutable document.
+func LoadBinary(r io.Reader) (*Document, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < len(binaryMagic)+4 || string(b[:len(binaryMagic)]) != binaryMagic {
+		return nil, fmt.Errorf("%w: missing signature", ErrBinaryFormat)
+	}
+	body, sum := b[:len(b)-4], binary.LittleEndian.Uint32(b[len(b)-4:])
+	d := binaryDecoder{b: body, pos: len(binaryMagic)}
+	if v := d.uvarint(); d.err == nil && v != binaryVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrBinaryVersion, v)
+	}
+	if crc32.Checksum(body, binaryChecksum) != sum {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrBinaryFormat)
+	}
+	doc := d.document()
+	if d.err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBinaryFormat, d.err)
+	}
+	return doc, nil
+}
+
+type binaryDecoder struct {
+	b       []byte
+	pos     int
+	err     error
+	strings []string
+	ns      []*Namespace
+	f       *freezer
+	elems   []*Element // in document order, for the ID table
+	linked  bool       // the internal subset is among the document's children
+}
+
+func (d *binaryDecoder) fail(format string, args ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (d *binaryDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b[d.pos:])
+	if n <= 0 {
+		d.fail("truncated input at offset %d", d.pos)
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+func (d *binaryDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b[d.pos:])
+	if n <= 0 {
+		d.fail("truncated input at offset %d", d.pos)
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+// count reads a number of items, each of which takes at least a byte of
+// the input, so that a damaged count cannot cause a huge allocation.
+func (d *binaryDecoder) count() int {
+	v := d.uvarint()
+	if v > uint64(len(d.b)-d.pos) {
+		d.fail("count %d at offset %d exceeds the input", v, d.pos)
+		return 0
+	}
+	return int(v)
+}
+
+func (d *binaryDecoder) bool() bool {
+	return d.uvarint() != 0
+}
+
+func (d *binaryDecoder) bytes() []byte {
+	n := d.count()
+	if d.err != nil {
+		return nil
+	}
+	b := d.b[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *binaryDecoder) string() string {
+	i := d.uvarint()
+	if i > 0 {
+		if i > uint64(len(d.strings)) {
+			d.fail("string %d at offset %d is not defined", i-1, d.pos)
+			return ""
+		}
+		return d.strings[i-1]
+	}
+	s := d.f.intern(string(d.bytes()))
+	if d.err == nil {
+		d.strings = append(d.strings, s)
+	}
+	return s
+}
+
+func (d *binaryDecoder) namespace() *Namespace {
+	switch i := d.uvarint(); i {
+	case 0:
+		return nil
+	case 1:
+		etype := ElementType(d.uvarint())
+		prefix := d.string()
+		href := d.string()
+		ns := d.f.allocNamespace(etype, prefix, href)
+		d.ns = append(d.ns, ns)
+		return ns
+	default:
+		if i-2 >= uint64(len(d.ns)) {
+			d.fail("namespace %d at offset %d is not defined", i-2, d.pos)
+			return nil
+		}
+		return d.ns[i-2]
+	}
+}
+
+// base reads the fields every node kind shares, after the tag.
+func (d *binaryDecoder) base(dn *docnode, etype ElementType) {
+	dn.etype = etype
+	dn.doc = d.f.dst
+	dn.line = int(d.uvarint())
+	dn.entityBaseURI = d.string()
+}
+
+func (d *binaryDecoder) document() *Document {
+	// The string table starts with the empty string; the interning of the
+	// other strings needs the freezer, which needs the counts.
+	d.strings = append(d.strings, "")
+	var c freezeCounts
+	var need int
+	for i, n := range []*int{&c.elems, &c.texts, &c.attrs, &c.comments, &c.cdata, &c.pis, &c.nsDecls, &c.namespaces, &c.content} {
+		*n = d.count()
+		need += *n * binaryMinRecord[i]
+	}
+	if d.err == nil && need > len(d.b)-d.pos {
+		d.fail("node counts in the header exceed the input")
+	}
+	if d.err != nil {
+		return nil
+	}
+	doc := NewDocument("", "", StandaloneImplicitNo)
+	d.f = newFreezer(doc, &c)
+
+	doc.etype = ElementType(d.uvarint())
+	if doc.etype != DocumentNode && doc.etype != HTMLDocumentNode {
+		d.fail("unexpected document node type %d", doc.etype)
+		return nil
+	}
+	doc.version = d.string()
+	doc.encoding = d.string()
+	doc.standalone = DocumentStandaloneType(d.varint())
+	doc.url = d.string()
+	doc.properties = DocProperties(d.uvarint())
+	doc.idsSkip = d.bool()
+	if n := d.count(); n > 0 {
+		doc.standaloneNormAttrs = make([]standaloneNormAttr, n)
+		for i := range doc.standaloneNormAttrs {
+			doc.standaloneNormAttrs[i] = standaloneNormAttr{elem: d.string(), attr: d.string()}
+		}
+	}
+	doc.intSubset = d.dtd()
+	doc.extSubset = d.dtd()
+	if c.elems > 0 {
+		d.elems = make([]*Element, 0, c.elems)
+	}
+	d.children(doc)
+
+	if n := d.count(); n > 0 && d.err == nil {
+		doc.ids = make(map[string]*Element, n)
+		for range n {
+			id := d.string()
+			ord := d.uvarint()
+			if ord >= uint64(len(d.elems)) {
+				d.fail("ID %q refers to element %d, beyond the last one", id, ord)
+				return nil
+			}
+			doc.ids[id] = d.elems[ord]
+		}
+	}
+	if d.err == nil && d.pos != len(d.b) {
+		d.fail("%d bytes of trailing data", len(d.b)-d.pos)
+	}
+	return doc
+}
+
+func (d *binaryDecoder) dtd() *DTD {
+	if !d.bool() {
+		return nil
+	}
+	dtd := newDTD()
+	dtd.doc = d.f.dst
+	dtd.parent = d.f.dst
+	dtd.line = int(d.uvarint())
+	dtd.name = d.string()
+	dtd.externalID = d.string()
+	dtd.systemID = d.string()
+	for d.err == nil {
+		etype := ElementType(d.uvarint())
+		if etype == binaryEnd {
+			break
+		}
+		var n Node
+		switch etype {
+		case EntityNode:
+			ent := &Entity{}
+			d.base(&ent.docnode, etype)
+			ent.name = d.string()
+			ent.entityType = enum.EntityType(d.uvarint())
+			ent.externalID = d.string()
+			ent.systemID = d.string()
+			ent.content = d.string()
+			ent.orig = d.string()
+			ent.replacement = d.string()
+			ent.uri = d.string()
+			ent.checked = int(d.varint())
+			ent.expandedSize = d.varint()
+			switch ent.entityType {
+			case enum.InternalParameterEntity, enum.ExternalParameterEntity:
+				dtd.pentities[ent.name] = ent
+			default:
+				dtd.entities[ent.name] = ent
+			}
+			n = ent
+		case ElementDeclNode:
+			decl := newElementDecl()
+			d.base(&decl.docnode, etype)
+			decl.name = d.string()
+			decl.prefix = d.string()
+			decl.decltype = enum.ElementType(d.uvarint())
+			decl.content = d.elementContent(nil)
+			dtd.elements[decl.name+":"+decl.prefix] = decl
+			n = decl
+		case AttributeDeclNode:
+			decl := newAttributeDecl()
+			d.base(&decl.docnode, etype)
+			decl.name = d.string()
+			decl.prefix = d.string()
+			decl.elem = d.string()
+			decl.atype = enum.AttributeType(d.uvarint())
+			decl.def = enum.AttributeDefault(d.uvarint())
+			decl.defvalue = d.string()
+			if k := d.count(); k > 0 {
+				decl.tree = make(Enumeration, k)
+				for i := range decl.tree {
+					decl.tree[i] = d.string()
+				}
+			}
+			decl.external = d.bool()
+			dtd.attributes[attrDeclKey{local: decl.name, prefix: decl.prefix, elem: decl.elem}] = decl
+			n = decl
+		case NotationNode:
+			nota := &Notation{}
+			d.base(&nota.docnode, etype)
+			nota.name = d.string()
+			nota.publicID = d.string()
+			nota.systemID = d.string()
+			dtd.notations[nota.name] = nota
+			n = nota
+		case CommentNode:
+			n = d.leaf(etype)
+		case ProcessingInstructionNode:
+			n = d.leaf(etype)
+		default:
+			d.fail("unexpected %s node in DTD", etype)
+			return nil
+		}
+		if d.err == nil {
+			_ = appendFastChild(dtd, n)
+		}
+	}
+	return dtd
+}
+
+func (d *binaryDecoder) elementContent(parent *ElementContent) *ElementContent {
+	if d.err != nil || !d.bool() {
+		return nil
+	}
+	c := &ElementContent{parent: parent}
+	c.ctype = ElementContentType(d.uvarint())
+	c.coccur = ElementContentOccur(d.uvarint())
+	c.name = d.string()
+	c.prefix = d.string()
+	c.c1 = d.elementContent(c)
+	c.c2 = d.elementContent(c)
+	return c
+}
+
+// children reads a list of children into parent, up to the end tag.
+func (d *binaryDecoder) children(parent MutableNode) {
+	for d.err == nil {
+		etype := ElementType(d.uvarint())
+		if etype == binaryEnd {
+			return
+		}
+		var n Node
+		switch etype {
+		case DTDNode:
+			doc, ok := parent.(*Document)
+			if !ok || doc.intSubset == nil || d.linked {
+				d.fail("misplaced internal subset")
+				return
+			}
+			// The line and base URI were read with the subset.
+			_ = d.uvarint()
+			_ = d.string()
+			d.linked = true
+			n = doc.intSubset
+		case ElementNode:
+			n = d.element()
+		default:
+			n = d.leaf(etype)
+		}
+		if d.err == nil {
+			_ = appendFastChild(parent, n)
+		}
+	}
+}
+
+func (d *binaryDecoder) element() *Element {
+	e := d.f.allocElement()
+	if d.elems != nil {
+		d.elems = append(d.elems, e)
+	}
+	d.base(&e.docnode, ElementNode)
+	e.name = d.string()
+	e.ns = d.namespace()
+	e.contentHasReference = d.bool()
+	if n := d.count(); n > 0 {
+		e.nsDefs = d.f.allocNSDefs(n)
+		for i := range e.nsDefs {
+			e.nsDefs[i] = d.namespace()
+		}
+	}
+	var last *Attribute
+	for range d.count() {
+		if etype := ElementType(d.uvarint()); etype != AttributeNode {
+			d.fail("unexpected %s node among attributes", etype)
+			return nil
+		}
+		a := d.f.allocAttribute()
+		d.base(&a.docnode, AttributeNode)
+		a.name = d.string()
+		a.ns = d.namespace()
+		a.atype = enum.AttributeType(d.uvarint())
+		a.defaultAttr = d.bool()
+		a.syntheticBase = d.bool()
+		d.children(a)
+		last = linkAttribute(e, last, a)
+	}
+	d.children(e)
+	return e
+}
+
+// leaf reads a node that is neither an element nor the DTD, after its tag.
+func (d *binaryDecoder) leaf(etype ElementType) Node {
+	switch etype {
+	case TextNode:
+		t := d.f.allocText()
+		d.base(&t.docnode, etype)
+		t.name = textNodeName
+		t.content = d.f.text(d.bytes())
+		t.fromCharRef = d.bool()
+		return t
+	case CDATASectionNode:
+		c := d.f.allocCDATA()
+		d.base(&c.docnode, etype)
+		c.name = "(CDATA)"
+		c.content = d.f.text(d.bytes())
+		return c
+	case CommentNode:
+		c := d.f.allocComment()
+		d.base(&c.docnode, etype)
+		c.name = "(comment)"
+		c.content = d.f.text(d.bytes())
+		return c
+	case ProcessingInstructionNode:
+		pi := d.f.allocPI()
+		d.base(&pi.docnode, etype)
+		pi.target = d.string()
+		pi.data = d.string()
+		return pi
+	case EntityRefNode:
+		line := int(d.uvarint())
+		baseURI := d.string()
+		name := d.string()
+		if d.err != nil {
+			return nil
+		}
+		ref, err := d.f.dst.CreateReference(name)
+		if err != nil {
+			d.fail("entity reference %q: %w", name, err)
+			return nil
+		}
+		ref.line = line
+		ref.entityBaseURI = baseURI
+		return ref
+	case XIncludeStartNode, XIncludeEndNode:
+		line := int(d.uvarint())
+		baseURI := d.string()
+		m := NewXIncludeMarker(d.f.dst, etype, d.string())
+		m.line = line
+		m.entityBaseURI = baseURI
+		return m
+	}
+	d.fail("unexpected node type %d", etype)
+	return nil
+}
@@ -0,0 +1,241 @@
+package helium_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinary(t *testing.T) {
+	t.Parallel()
+
+	const src = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<!-- lead -->
+<!DOCTYPE catalog [
+  <!ENTITY pub "ACME">
+  <!NOTATION gif SYSTEM "image/gif">
+  <!ELEMENT catalog (book+)>
+  <!ELEMENT book (title, (note | sku)*)>
+  <!ATTLIST book id ID #IMPLIED kind (new|used) "new">
+  <!-- decls -->
+]>
+<catalog xmlns="urn:c" xmlns:x="urn:x" xml:base="http://example.com/dir/">
+  <book id="b1" x:lang="en"><title>Go &amp; XML</title><![CDATA[<raw>]]></book>
+  <?pi data?>
+  <book id="b2"><title>&pub;</title></book>
+</catalog>
+`
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+	doc.SetURL("file:///srv/catalog.xml")
+	want, err := helium.WriteString(doc)
+	require.NoError(t, err)
+
+	b, err := helium.MarshalBinary(doc)
+	require.NoError(t, err)
+	loaded, err := helium.LoadBinary(bytes.NewReader(b))
+	require.NoError(t, err)
+
+	t.Run("same document", func(t *testing.T) {
+		t.Parallel()
+		got, err := helium.WriteString(loaded)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+		require.Equal(t, doc.Standalone(), loaded.Standalone())
+		require.Equal(t, "UTF-8", loaded.RawEncoding())
+		require.Equal(t, doc.Properties(), loaded.Properties())
+		require.Equal(t, "file:///srv/catalog.xml", loaded.URL())
+	})
+
+	t.Run("ids lines and base URIs", func(t *testing.T) {
+		t.Parallel()
+		b2 := loaded.GetElementByID("b2")
+		require.NotNil(t, b2)
+		require.Same(t, loaded, b2.OwnerDocument())
+		require.Equal(t, "urn:c", b2.URI())
+		require.Equal(t, 14, b2.Line())
+		require.Equal(t, "http://example.com/dir/", helium.NodeGetBase(loaded, b2))
+
+		b1 := loaded.GetElementByID("b1")
+		v, ok := b1.GetAttributeNS("lang", "urn:x")
+		require.True(t, ok)
+		require.Equal(t, "en", v)
+	})
+
+	t.Run("DTD", func(t *testing.T) {
+		t.Parallel()
+		dtd := loaded.IntSubset()
+		require.NotNil(t, dtd)
+		ent, ok := dtd.LookupEntity("pub")
+		require.True(t, ok)
+		require.Equal(t, "ACME", string(ent.Content()))
+		_, ok = dtd.LookupNotation("gif")
+		require.True(t, ok)
+		decl, ok := dtd.LookupAttribute("kind", "", "book")
+		require.True(t, ok)
+		require.Equal(t, "new", decl.DefaultValue())
+		elem, ok := dtd.LookupElement("book", "")
+		require.True(t, ok)
+		require.Equal(t, "title", elem.ContentModel().First().Name())
+	})
+
+	t.Run("mutable", func(t *testing.T) {
+		t.Parallel()
+		cp, err := helium.LoadBinary(bytes.NewReader(b))
+		require.NoError(t, err)
+		root := cp.DocumentElement()
+		require.NoError(t, root.SetAttribute("n", "1"))
+		title := cp.GetElementByID("b1").FirstChild()
+		require.NoError(t, title.(*helium.Element).AppendText([]byte("!")))
+		got, err := helium.WriteString(root)
+		require.NoError(t, err)
+		require.Contains(t, got, `n="1"`)
+		require.Contains(t, got, "<title>Go &amp; XML!</title>")
+
+		// The first load is not affected.
+		got, err = helium.WriteString(loaded)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	})
+
+	t.Run("damaged input", func(t *testing.T) {
+		t.Parallel()
+		_, err := helium.LoadBinary(strings.NewReader("<catalog/>"))
+		require.ErrorIs(t, err, helium.ErrBinaryFormat)
+
+		_, err = helium.LoadBinary(bytes.NewReader(b[:len(b)/2]))
+		require.ErrorIs(t, err, helium.ErrBinaryFormat)
+
+		flipped := bytes.Clone(b)
+		flipped[len(flipped)/2] ^= 0x20
+		_, err = helium.LoadBinary(bytes.NewReader(flipped))
+		require.ErrorIs(t, err, helium.ErrBinaryFormat)
+
+		future := bytes.Clone(b)
+		future[8] = 99
+		_, err = helium.LoadBinary(bytes.NewReader(future))
+		require.ErrorIs(t, err, helium.ErrBinaryVersion)
+	})
+
+	t.Run("inflated counts", func(t *testing.T) {
+		t.Parallel()
+		// A header claiming as many elements as there are bytes left passes
+		// a check of one byte per record, but an element record takes
+		// several; the arenas must not be sized by such counts.
+		const size = 1 << 16
+		crafted := append([]byte(nil), b[:8]...)
+		crafted = binary.AppendUvarint(crafted, 1)
+		crafted = binary.AppendUvarint(crafted, size)
+		for range 8 {
+			crafted = binary.AppendUvarint(crafted, 0)
+		}
+		crafted = append(crafted, make([]byte, size)...)
+		crafted = binary.LittleEndian.AppendUint32(crafted, crc32.Checksum(crafted, crc32.MakeTable(crc32.Castagnoli)))
+		_, err := helium.LoadBinary(bytes.NewReader(crafted))
+		require.ErrorIs(t, err, helium.ErrBinaryFormat)
+		require.ErrorContains(t, err, "exceed the input")
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		t.Parallel()
+		var src strings.Builder
+		src.WriteString(`<!DOCTYPE r [<!ATTLIST e id ID #IMPLIED>]><r>`)
+		for i := range 50 {
+			fmt.Fprintf(&src, `<e id="e%d"/>`, i)
+		}
+		src.WriteString(`</r>`)
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src.String()))
+		require.NoError(t, err)
+		first, err := helium.MarshalBinary(doc)
+		require.NoError(t, err)
+		for range 10 {
+			again, err := helium.MarshalBinary(doc)
+			require.NoError(t, err)
+			require.Equal(t, first, again)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+		_, err := helium.MarshalBinary(nil)
+		require.ErrorIs(t, err, helium.ErrNilNode)
+	})
+}
+
+// TestBinaryCorpus round-trips the libxml2 test documents through the
+// binary format and checks that each serializes as it did before.
+func TestBinaryCorpus(t *testing.T) {
+	t.Parallel()
+
+	files, err := filepath.Glob("testdata/libxml2-compat/*")
+	require.NoError(t, err)
+	var n int
+	for _, file := range files {
+		if filepath.Ext(file) != "" && filepath.Ext(file) != ".xml" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		doc, err := helium.NewParser().BaseURI(file).Parse(t.Context(), data)
+		if err != nil {
+			continue
+		}
+		want, err := helium.WriteString(doc)
+		if err != nil {
+			continue
+		}
+		n++
+		b, err := helium.MarshalBinary(doc)
+		require.NoError(t, err, file)
+		loaded, err := helium.LoadBinary(bytes.NewReader(b))
+		require.NoError(t, err, file)
+		got, err := helium.WriteString(loaded)
+		require.NoError(t, err, file)
+		require.Equal(t, want, got, file)
+	}
+	require.NotZero(t, n)
+}
+
+// BenchmarkLoadBinary compares loading a document from the binary format
+// with parsing its text.
+func BenchmarkLoadBinary(b *testing.B) {
+	var src strings.Builder
+	src.WriteString(`<catalog xmlns="urn:c">`)
+	for i := range 1000 {
+		fmt.Fprintf(&src, `<book id="b%d" kind="new"><title>Title %d</title><!-- note --><price>%d.99</price></book>`, i, i, i)
+	}
+	src.WriteString(`</catalog>`)
+	text := []byte(src.String())
+	doc, err := helium.NewParser().Parse(b.Context(), text)
+	require.NoError(b, err)
+	data, err := helium.MarshalBinary(doc)
+	require.NoError(b, err)
+
+	b.Run("LoadBinary", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			_, err := helium.LoadBinary(bytes.NewReader(data))
+			require.NoError(b, err)
+		}
+	})
+	b.Run("Parse", func(b *testing.B) {
+		p := helium.NewParser()
+		b.ReportAllocs()
+		b.SetBytes(int64(len(text)))
+		for b.Loop() {
+			_, err := p.Parse(b.Context(), text)
+			require.NoError(b, err)
+		}
+	})
+}
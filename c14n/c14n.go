@@ -11,11 +11,13 @@ import (
 type Mode int
 
 // C14N10 selects Canonical XML 1.0, ExclusiveC14N10 selects Exclusive Canonical
-// XML 1.0, and C14N11 selects Canonical XML 1.1.
+// XML 1.0, C14N11 selects Canonical XML 1.1, and C14N20 selects Canonical XML
+// 2.0.
 const (
 	C14N10          Mode = iota // Canonical XML 1.0 (libxml2: XML_C14N_1_0)
 	ExclusiveC14N10             // Exclusive Canonical XML 1.0 (libxml2: XML_C14N_EXCLUSIVE_1_0)
 	C14N11                      // Canonical XML 1.1 (libxml2: XML_C14N_1_1)
+	C14N20                      // Canonical XML 2.0 (helium extension; libxml2 has no equivalent)
 )
 
 // canonicalizerCfg holds the configuration for a Canonicalizer.
@@ -26,6 +28,17 @@ type canonicalizerCfg struct {
 	nodeSetSet        bool // true once NodeSet was explicitly configured (even if empty)
 	inclusivePrefixes []string
 	strictXMLAttrs    bool
+	subtreeID         string
+	subtreeIDSet      bool // true once SubtreeByID was configured
+
+	// Canonical XML 2.0 parameters. The sets are replaced, never mutated, by
+	// the builders, so clones may share them.
+	trimText              bool
+	prefixRewrite         PrefixRewrite
+	qnameElements         map[qnameKey]struct{}
+	qnameAttrs            map[qnameKey]struct{}
+	qnameUnqualifiedAttrs map[unqualifiedAttrKey]struct{}
+	xpathElements         map[qnameKey]struct{}
 }
 
 // Canonicalizer configures XML canonicalization. It is a value-style
@@ -49,7 +62,8 @@ func (c Canonicalizer) clone() Canonicalizer {
 	return Canonicalizer{cfg: &cp}
 }
 
-// Comments enables comment output in the canonical form.
+// Comments enables comment output in the canonical form. In [C14N20] mode
+// it is the IgnoreComments=false parameter.
 func (c Canonicalizer) Comments() Canonicalizer {
 	c = c.clone()
 	c.cfg.withComments = true
@@ -107,6 +121,92 @@ func (c Canonicalizer) InclusiveNamespaces(prefixes []string) Canonicalizer {
 	return c
 }
 
+// SubtreeByID restricts canonicalization to the element whose ID is id and
+// its descendants, the nodes a same-document reference "#id" selects in an
+// XML Signature. An ID is the value, with surrounding whitespace removed, of
+// an xml:id attribute, of an attribute named Id, ID or id, or of an attribute
+// declared ID-typed by the DTD. No element or more than one element with the
+// ID is an error, so an ambiguous reference is never canonicalized.
+//
+// The element's ancestors are omitted but still contribute their in-scope
+// namespaces, and in Canonical XML 1.0 and 1.1 their xml:* attributes, as
+// the specifications require for a document subset. Comments in the subtree
+// are kept only when [Canonicalizer.Comments] is set.
+//
+// Unlike [Canonicalizer.NodeSet], SubtreeByID needs no tree, so it also
+// applies to a [Handler]. It cannot be combined with NodeSet.
+func (c Canonicalizer) SubtreeByID(id string) Canonicalizer {
+	c = c.clone()
+	c.cfg.subtreeID = id
+	c.cfg.subtreeIDSet = true
+	return c
+}
+
+// TrimTextNodes removes leading and trailing whitespace from text nodes, and
+// whitespace-only text nodes, outside the scope of xml:space="preserve". It
+// is the TrimTextNodes parameter of Canonical XML 2.0 and has no effect in
+// other modes.
+func (c Canonicalizer) TrimTextNodes() Canonicalizer {
+	c = c.clone()
+	c.cfg.trimText = true
+	return c
+}
+
+// PrefixRewrite sets the PrefixRewrite parameter of Canonical XML 2.0. With
+// [PrefixRewriteSequential] the output does not depend on the prefixes the
+// document chose, only on the namespaces. It has no effect in other modes.
+func (c Canonicalizer) PrefixRewrite(rewrite PrefixRewrite) Canonicalizer {
+	c = c.clone()
+	c.cfg.prefixRewrite = rewrite
+	return c
+}
+
+// QNameAwareElement declares that the text content of elements named local
+// in namespace uri is a QName. The namespace its prefix is bound to is then
+// declared on the element, and its prefix rewritten with the others. Content
+// that is not of the form prefix:local is written as it is. It is the
+// QNameAware/Element parameter of Canonical XML 2.0 and has no effect in
+// other modes.
+func (c Canonicalizer) QNameAwareElement(uri, local string) Canonicalizer {
+	c = c.clone()
+	c.cfg.qnameElements = withKey(c.cfg.qnameElements, qnameKey{uri: uri, local: local})
+	return c
+}
+
+// QNameAwareQualifiedAttribute declares that the value of attributes named
+// local in namespace uri is a QName, as [Canonicalizer.QNameAwareElement]
+// does for content. It is the QNameAware/QualifiedAttr parameter of Canonical
+// XML 2.0 and has no effect in other modes.
+func (c Canonicalizer) QNameAwareQualifiedAttribute(uri, local string) Canonicalizer {
+	c = c.clone()
+	c.cfg.qnameAttrs = withKey(c.cfg.qnameAttrs, qnameKey{uri: uri, local: local})
+	return c
+}
+
+// QNameAwareUnqualifiedAttribute declares that the value of the attribute
+// name, in no namespace, of elements named parentLocal in namespace parentURI
+// is a QName. It is the QNameAware/UnqualifiedAttr parameter of Canonical XML
+// 2.0 and has no effect in other modes.
+func (c Canonicalizer) QNameAwareUnqualifiedAttribute(parentURI, parentLocal, name string) Canonicalizer {
+	c = c.clone()
+	c.cfg.qnameUnqualifiedAttrs = withKey(c.cfg.qnameUnqualifiedAttrs, unqualifiedAttrKey{
+		parent: qnameKey{uri: parentURI, local: parentLocal},
+		name:   name,
+	})
+	return c
+}
+
+// QNameAwareXPathElement declares that the text content of elements named
+// local in namespace uri is an XPath expression. The namespaces of the
+// prefixes it uses outside string literals are declared on the element, and
+// the prefixes rewritten with the others. It is the QNameAware/XPathElement
+// parameter of Canonical XML 2.0 and has no effect in other modes.
+func (c Canonicalizer) QNameAwareXPathElement(uri, local string) Canonicalizer {
+	c = c.clone()
+	c.cfg.xpathElements = withKey(c.cfg.xpathElements, qnameKey{uri: uri, local: local})
+	return c
+}
+
 // Canonicalize writes the canonical form of doc to out.
 // (libxml2: xmlC14NDocSaveTo)
 func (c Canonicalizer) Canonicalize(doc *helium.Document, out io.Writer) error {
@@ -114,31 +214,61 @@ func (c Canonicalizer) Canonicalize(doc *helium.Document, out io.Writer) error {
 	if cfg == nil {
 		cfg = &canonicalizerCfg{}
 	}
-	can := &canonicalizer{
-		doc:  doc,
-		mode: cfg.mode,
-		out:  out,
+	var nodeSet map[helium.Node]struct{}
+	if cfg.subtreeIDSet {
+		if cfg.nodeSetSet {
+			return errSubtreeWithNodeSet
+		}
+		nodes, err := subtreeNodeSet(doc, cfg.subtreeID)
+		if err != nil {
+			return err
+		}
+		nodeSet = nodes
 	}
-	can.withComments = cfg.withComments
-	can.strictXMLAttrs = cfg.strictXMLAttrs
 	if cfg.nodeSetSet {
-		can.nodeSet = make(map[helium.Node]struct{}, len(cfg.nodeSet))
+		nodeSet = make(map[helium.Node]struct{}, len(cfg.nodeSet))
 		for _, n := range cfg.nodeSet {
-			can.nodeSet[n] = struct{}{}
+			nodeSet[n] = struct{}{}
 		}
 	}
-	if len(cfg.inclusivePrefixes) > 0 {
-		can.inclusivePrefixes = make(map[string]struct{}, len(cfg.inclusivePrefixes))
-		for _, p := range cfg.inclusivePrefixes {
-			if p == "#default" {
-				p = ""
-			}
-			can.inclusivePrefixes[p] = struct{}{}
+	if cfg.mode == C14N20 {
+		can := &canonicalizer20{
+			doc:     doc,
+			out:     out,
+			cfg:     cfg,
+			nodeSet: nodeSet,
 		}
+		return can.process()
+	}
+
+	can := &canonicalizer{
+		doc:     doc,
+		mode:    cfg.mode,
+		out:     out,
+		nodeSet: nodeSet,
 	}
+	can.withComments = cfg.withComments
+	can.strictXMLAttrs = cfg.strictXMLAttrs
+	can.inclusivePrefixes = cfg.inclusivePrefixSet()
 	return can.process()
 }
 
+// inclusivePrefixSet returns the configured inclusive prefixes as a set, or
+// nil when there are none.
+func (cfg *canonicalizerCfg) inclusivePrefixSet() map[string]struct{} {
+	if len(cfg.inclusivePrefixes) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(cfg.inclusivePrefixes))
+	for _, p := range cfg.inclusivePrefixes {
+		if p == "#default" {
+			p = ""
+		}
+		set[p] = struct{}{}
+	}
+	return set
+}
+
 // CanonicalizeTo returns the canonical form of doc as a byte slice.
 // (libxml2: xmlC14NDocSaveTo)
 func (c Canonicalizer) CanonicalizeTo(doc *helium.Document) ([]byte, error) {
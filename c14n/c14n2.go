@@ -0,0 +1,611 @@
+package c14n
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/internal/xmlchar"
+)
+
+// PrefixRewrite selects how Canonical XML 2.0 writes namespace prefixes.
+type PrefixRewrite int
+
+// PrefixRewriteNone keeps the prefixes of the document. PrefixRewriteSequential
+// replaces every prefix with "n" followed by a number, assigned to namespace
+// URIs in the order they are first output.
+const (
+	PrefixRewriteNone       PrefixRewrite = iota // c14n2:PrefixRewrite "none"
+	PrefixRewriteSequential                      // c14n2:PrefixRewrite "sequential"
+)
+
+// qnameKey names an element or an attribute by namespace URI and local name.
+type qnameKey struct {
+	uri   string
+	local string
+}
+
+// unqualifiedAttrKey names an attribute in no namespace by its own name and
+// the name of the element that carries it.
+type unqualifiedAttrKey struct {
+	parent qnameKey
+	name   string
+}
+
+// withKey returns a copy of set with k added, so a builder never mutates a
+// set another Canonicalizer shares.
+func withKey[K comparable](set map[K]struct{}, k K) map[K]struct{} {
+	out := make(map[K]struct{}, len(set)+1)
+	maps.Copy(out, set)
+	out[k] = struct{}{}
+	return out
+}
+
+// canonicalizer20 writes the Canonical XML 2.0 form of a document.
+//
+// Unlike the 1.x modes it has no namespace axis to select from: an output
+// element declares the namespaces it visibly utilizes, through its own name,
+// its attributes and, when configured, QNames in its content, and only when
+// an output ancestor has not already declared them. With a node set,
+// namespace nodes in the set are ignored and an omitted ancestor passes on no
+// xml:* attribute.
+type canonicalizer20 struct {
+	doc     *helium.Document
+	out     io.Writer
+	cfg     *canonicalizerCfg
+	nodeSet map[helium.Node]struct{} // nil = whole document
+	nsStack *visibleNSStack
+	// prefixes maps a namespace URI to its rewritten prefix under
+	// PrefixRewriteSequential, for the whole document.
+	prefixes map[string]string
+	// preserve records, for each open element, whether xml:space="preserve"
+	// is in effect, which turns TrimTextNodes off.
+	preserve []bool
+	// text holds the character data read since the last markup, written as
+	// one text node when the next markup is reached.
+	text []byte
+}
+
+// nsUse is a namespace an output element visibly utilizes.
+type nsUse struct {
+	prefix string
+	uri    string
+}
+
+func (c *canonicalizer20) process() error {
+	c.nsStack = newVisibleNSStack()
+	if c.cfg.prefixRewrite == PrefixRewriteSequential {
+		c.prefixes = make(map[string]string)
+	}
+	c.preserve = []bool{false}
+
+	beforeRoot := true
+	for child := range helium.Children(c.doc) {
+		switch child.Type() {
+		case helium.ElementNode:
+			elem, ok := helium.AsNode[*helium.Element](child)
+			if !ok {
+				continue
+			}
+			if err := c.processElement(elem); err != nil {
+				return err
+			}
+			if err := c.flushText(); err != nil {
+				return err
+			}
+			beforeRoot = false
+		case helium.ProcessingInstructionNode:
+			if !c.isVisible(child) {
+				continue
+			}
+			pi, ok := helium.AsNode[*helium.ProcessingInstruction](child)
+			if !ok {
+				continue
+			}
+			if err := c.writeTopLevel(beforeRoot, func() error {
+				return writeProcessingInstruction(c.out, pi.Name(), pi.Content())
+			}); err != nil {
+				return err
+			}
+		case helium.CommentNode:
+			if !c.cfg.withComments || !c.isVisible(child) {
+				continue
+			}
+			if err := c.writeTopLevel(beforeRoot, func() error {
+				return writeCommentText(c.out, child.Content())
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeTopLevel writes a node outside the document element, separated from
+// it by a newline.
+func (c *canonicalizer20) writeTopLevel(beforeRoot bool, write func() error) error {
+	if !beforeRoot {
+		if _, err := io.WriteString(c.out, "\n"); err != nil {
+			return err
+		}
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	if beforeRoot {
+		_, err := io.WriteString(c.out, "\n")
+		return err
+	}
+	return nil
+}
+
+func (c *canonicalizer20) isVisible(n helium.Node) bool {
+	if c.nodeSet == nil {
+		return true
+	}
+	_, ok := c.nodeSet[n]
+	return ok
+}
+
+// trimming reports whether TrimTextNodes applies at the current element.
+func (c *canonicalizer20) trimming() bool {
+	return c.cfg.trimText && !c.preserve[len(c.preserve)-1]
+}
+
+func (c *canonicalizer20) pushSpace(e *helium.Element) {
+	preserve := c.preserve[len(c.preserve)-1]
+	if attr, ok := xmlAttrOf(e, "space"); ok {
+		preserve = attr.Value() == "preserve"
+	}
+	c.preserve = append(c.preserve, preserve)
+}
+
+func (c *canonicalizer20) popSpace() {
+	c.preserve = c.preserve[:len(c.preserve)-1]
+}
+
+func (c *canonicalizer20) processElement(e *helium.Element) error {
+	visible := c.isVisible(e)
+	if visible {
+		// The text before the start tag belongs to the parent.
+		if err := c.flushText(); err != nil {
+			return err
+		}
+	}
+	c.pushSpace(e)
+	defer c.popSpace()
+	if !visible {
+		return c.processChildren(e)
+	}
+
+	c.nsStack.save()
+	defer c.nsStack.restore()
+
+	content, kind := c.qnameContent(e)
+	uses, err := c.utilizedNamespaces(e, content, kind)
+	if err != nil {
+		return err
+	}
+	decls := c.declarations(uses)
+
+	name := c.elementName(e)
+	if _, err := io.WriteString(c.out, "<"+name); err != nil {
+		return err
+	}
+	for _, ns := range decls {
+		if err := writeNamespaceDecl(c.out, ns.prefix, ns.uri); err != nil {
+			return err
+		}
+	}
+	if err := c.writeAttributes(e); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(c.out, ">"); err != nil {
+		return err
+	}
+
+	if kind != qnameContentNone {
+		// The content was read, and trimmed, up front to find the
+		// namespaces it uses.
+		if content != "" {
+			if err := escapeText(c.out, []byte(c.rewriteContent(e, content, kind))); err != nil {
+				return err
+			}
+		}
+	} else if err := c.processChildren(e); err != nil {
+		return err
+	}
+	if err := c.flushText(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(c.out, "</"+name+">")
+	return err
+}
+
+func (c *canonicalizer20) processChildren(n helium.Node) error {
+	for child := range helium.Children(n) {
+		if err := c.processNode(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *canonicalizer20) processNode(n helium.Node) error {
+	switch n.Type() {
+	case helium.ElementNode:
+		elem, ok := helium.AsNode[*helium.Element](n)
+		if !ok {
+			return nil
+		}
+		return c.processElement(elem)
+	case helium.TextNode, helium.CDATASectionNode:
+		if c.isVisible(n) {
+			c.text = append(c.text, n.Content()...)
+		}
+	case helium.ProcessingInstructionNode:
+		if !c.isVisible(n) {
+			return nil
+		}
+		pi, ok := helium.AsNode[*helium.ProcessingInstruction](n)
+		if !ok {
+			return nil
+		}
+		if err := c.flushText(); err != nil {
+			return err
+		}
+		return writeProcessingInstruction(c.out, pi.Name(), pi.Content())
+	case helium.CommentNode:
+		if !c.cfg.withComments || !c.isVisible(n) {
+			return nil
+		}
+		if err := c.flushText(); err != nil {
+			return err
+		}
+		return writeCommentText(c.out, n.Content())
+	case helium.EntityRefNode, helium.EntityNode:
+		// The replacement text of an unexpanded reference is canonicalized
+		// in place, and joins the text around it.
+		return c.processChildren(n)
+	}
+	return nil
+}
+
+// flushText writes the character data read since the last markup as one
+// text node, trimmed when TrimTextNodes applies.
+func (c *canonicalizer20) flushText() error {
+	if len(c.text) == 0 {
+		return nil
+	}
+	text := c.text
+	c.text = c.text[:0]
+	if c.trimming() {
+		text = trimXMLSpace(text)
+	}
+	return escapeText(c.out, text)
+}
+
+// trimXMLSpace removes leading and trailing XML whitespace.
+func trimXMLSpace(s []byte) []byte {
+	return bytes.Trim(s, " \t\r\n")
+}
+
+// qnameContentKind is how the content of a QName-aware element is read.
+type qnameContentKind int
+
+const (
+	qnameContentNone  qnameContentKind = iota // ordinary content
+	qnameContentQName                         // the content is a QName
+	qnameContentXPath                         // the content is an XPath expression
+)
+
+// qnameContent returns the text content of e and how it is read when e is a
+// QName-aware element whose content is text alone. Any other element has
+// ordinary content.
+func (c *canonicalizer20) qnameContent(e *helium.Element) (string, qnameContentKind) {
+	key := qnameKey{uri: e.URI(), local: e.LocalName()}
+	kind := qnameContentNone
+	if _, ok := c.cfg.qnameElements[key]; ok {
+		kind = qnameContentQName
+	} else if _, ok := c.cfg.xpathElements[key]; ok {
+		kind = qnameContentXPath
+	}
+	if kind == qnameContentNone {
+		return "", kind
+	}
+	var buf []byte
+	for child := range helium.Children(e) {
+		switch child.Type() {
+		case helium.TextNode, helium.CDATASectionNode:
+			if c.isVisible(child) {
+				buf = append(buf, child.Content()...)
+			}
+		case helium.CommentNode:
+			if c.cfg.withComments && c.isVisible(child) {
+				return "", qnameContentNone
+			}
+		default:
+			return "", qnameContentNone
+		}
+	}
+	if c.trimming() {
+		buf = trimXMLSpace(buf)
+	}
+	return string(buf), kind
+}
+
+// utilizedNamespaces returns the namespaces the output element e visibly
+// utilizes: those of its name, of its output attributes, and of the QNames in
+// its QName-aware attribute values and content. The xml namespace is never
+// declared, so it is left out.
+func (c *canonicalizer20) utilizedNamespaces(e *helium.Element, content string, kind qnameContentKind) ([]nsUse, error) {
+	var uses []nsUse
+	if ns := e.Namespace(); ns != nil {
+		uses = append(uses, nsUse{prefix: ns.Prefix(), uri: ns.URI()})
+	} else {
+		uses = append(uses, nsUse{})
+	}
+	for _, attr := range e.Attributes() {
+		if !c.isVisible(attr) {
+			continue
+		}
+		if p := attr.Prefix(); p != "" {
+			uses = append(uses, nsUse{prefix: p, uri: attr.URI()})
+		}
+		if c.qnameAwareAttr(e, attr) {
+			if prefix, _, ok := splitPrefixedName(attr.Value()); ok {
+				use, err := resolveContentPrefix(e, prefix)
+				if err != nil {
+					return nil, err
+				}
+				uses = append(uses, use)
+			}
+		}
+	}
+	switch kind {
+	case qnameContentQName:
+		if prefix, _, ok := splitPrefixedName(content); ok {
+			use, err := resolveContentPrefix(e, prefix)
+			if err != nil {
+				return nil, err
+			}
+			uses = append(uses, use)
+		}
+	case qnameContentXPath:
+		for _, ref := range xpathPrefixes(content) {
+			use, err := resolveContentPrefix(e, content[ref.start:ref.end])
+			if err != nil {
+				return nil, err
+			}
+			uses = append(uses, use)
+		}
+	}
+	return slices.DeleteFunc(uses, func(u nsUse) bool {
+		return u.uri == lexicon.NamespaceXML
+	}), nil
+}
+
+// qnameAwareAttr reports whether the value of attr, an attribute of e, is
+// configured to be a QName.
+func (c *canonicalizer20) qnameAwareAttr(e *helium.Element, attr *helium.Attribute) bool {
+	if attr.URI() != "" {
+		_, ok := c.cfg.qnameAttrs[qnameKey{uri: attr.URI(), local: attr.LocalName()}]
+		return ok
+	}
+	_, ok := c.cfg.qnameUnqualifiedAttrs[unqualifiedAttrKey{
+		parent: qnameKey{uri: e.URI(), local: e.LocalName()},
+		name:   attr.LocalName(),
+	}]
+	return ok
+}
+
+// resolveContentPrefix resolves a prefix used in the content or an attribute
+// value of e. A prefix that is not in scope cannot be declared on the output,
+// so it is an error.
+func resolveContentPrefix(e *helium.Element, prefix string) (nsUse, error) {
+	ns := helium.LookupNSByPrefix(e, prefix)
+	if ns == nil {
+		return nsUse{}, fmt.Errorf("c14n: prefix %q used in the content of element %s is not in scope", prefix, e.Name())
+	}
+	return nsUse{prefix: prefix, uri: ns.URI()}, nil
+}
+
+// declarations returns, sorted by prefix, the namespace declarations the
+// output element that utilizes uses writes, and records them as rendered.
+func (c *canonicalizer20) declarations(uses []nsUse) []nsSortEntry {
+	var out []nsSortEntry
+	if c.prefixes == nil {
+		for _, u := range uses {
+			if c.nsStack.needsOutput(u.prefix, u.uri) {
+				out = append(out, nsSortEntry{prefix: u.prefix, uri: u.uri})
+				c.nsStack.add(u.prefix, u.uri)
+			}
+		}
+		sortNamespaces(out)
+		return out
+	}
+
+	// Prefixes are assigned to the URIs an element uses in URI order. The
+	// null namespace is a URI like any other here, so an element in no
+	// namespace is written with a prefix bound to "".
+	uris := make([]string, 0, len(uses))
+	for _, u := range uses {
+		uris = append(uris, u.uri)
+	}
+	slices.Sort(uris)
+	for _, uri := range slices.Compact(uris) {
+		prefix := c.rewrittenPrefix(uri)
+		if rendered, found := c.nsStack.lookup(prefix); !found || rendered != uri {
+			out = append(out, nsSortEntry{prefix: prefix, uri: uri})
+			c.nsStack.add(prefix, uri)
+		}
+	}
+	sortNamespaces(out)
+	return out
+}
+
+// rewrittenPrefix returns the sequential prefix of uri, assigning the next one
+// when uri has none yet.
+func (c *canonicalizer20) rewrittenPrefix(uri string) string {
+	if p, ok := c.prefixes[uri]; ok {
+		return p
+	}
+	p := "n" + strconv.Itoa(len(c.prefixes))
+	c.prefixes[uri] = p
+	return p
+}
+
+// outputPrefix returns the prefix written for a name in namespace uri whose
+// prefix in the document is prefix.
+func (c *canonicalizer20) outputPrefix(prefix, uri string) string {
+	if c.prefixes == nil || uri == lexicon.NamespaceXML {
+		return prefix
+	}
+	return c.prefixes[uri]
+}
+
+func (c *canonicalizer20) elementName(e *helium.Element) string {
+	return qualifiedName(c.outputPrefix(e.Prefix(), e.URI()), e.LocalName())
+}
+
+func (c *canonicalizer20) writeAttributes(e *helium.Element) error {
+	attrs := make([]*helium.Attribute, 0, len(e.Attributes()))
+	for _, attr := range e.Attributes() {
+		if c.isVisible(attr) {
+			attrs = append(attrs, attr)
+		}
+	}
+	slices.SortFunc(attrs, func(a, b *helium.Attribute) int {
+		return compareAttrNames(a.URI(), a.LocalName(), b.URI(), b.LocalName())
+	})
+	for _, attr := range attrs {
+		name := attr.LocalName()
+		if attr.URI() != "" {
+			name = qualifiedName(c.outputPrefix(attr.Prefix(), attr.URI()), name)
+		}
+		value := attr.Value()
+		if c.prefixes != nil && c.qnameAwareAttr(e, attr) {
+			value = c.rewriteQName(e, value)
+		}
+		if _, err := io.WriteString(c.out, " "+name+`="`); err != nil {
+			return err
+		}
+		if err := escapeAttrValue(c.out, []byte(value)); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(c.out, `"`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteContent returns the content of a QName-aware element with its
+// prefixes rewritten, or unchanged when prefixes are kept.
+func (c *canonicalizer20) rewriteContent(e *helium.Element, content string, kind qnameContentKind) string {
+	if c.prefixes == nil {
+		return content
+	}
+	if kind == qnameContentQName {
+		return c.rewriteQName(e, content)
+	}
+	var b strings.Builder
+	last := 0
+	for _, ref := range xpathPrefixes(content) {
+		b.WriteString(content[last:ref.start])
+		b.WriteString(c.contentPrefix(e, content[ref.start:ref.end]))
+		last = ref.end
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// rewriteQName rewrites the prefix of a prefixed QName value.
+func (c *canonicalizer20) rewriteQName(e *helium.Element, value string) string {
+	prefix, local, ok := splitPrefixedName(value)
+	if !ok {
+		return value
+	}
+	return c.contentPrefix(e, prefix) + ":" + local
+}
+
+// contentPrefix returns the output prefix of a prefix used in the content or
+// an attribute value of e, which utilizedNamespaces has already resolved.
+func (c *canonicalizer20) contentPrefix(e *helium.Element, prefix string) string {
+	ns := helium.LookupNSByPrefix(e, prefix)
+	if ns == nil {
+		return prefix
+	}
+	return c.outputPrefix(prefix, ns.URI())
+}
+
+// splitPrefixedName splits a value of the form prefix:local, where both parts
+// are NCNames. Any other value, an unprefixed name included, is not read as a
+// QName.
+func splitPrefixedName(s string) (prefix, local string, ok bool) {
+	prefix, local, found := strings.Cut(s, ":")
+	if !found || !xmlchar.IsValidNCName(prefix) || !xmlchar.IsValidNCName(local) {
+		return "", "", false
+	}
+	return prefix, local, true
+}
+
+// prefixRef is the byte range of a namespace prefix in an XPath expression.
+type prefixRef struct {
+	start, end int
+}
+
+// xpathPrefixes returns the namespace prefixes an XPath expression uses, in
+// name tests, function names and variable references, in the order they
+// appear. Names inside string literals and axis names are not prefixes.
+func xpathPrefixes(expr string) []prefixRef {
+	var refs []prefixRef
+	for i := 0; i < len(expr); {
+		r, width := utf8.DecodeRuneInString(expr[i:])
+		switch {
+		case r == '"' || r == '\'':
+			end := strings.IndexRune(expr[i+1:], r)
+			if end < 0 {
+				return refs
+			}
+			i += end + 2
+			continue
+		case xmlchar.IsNCNameStartChar(r):
+			start := i
+			for i < len(expr) {
+				r, width := utf8.DecodeRuneInString(expr[i:])
+				if !xmlchar.IsNCNameChar(r) {
+					break
+				}
+				i += width
+			}
+			if i+1 >= len(expr) || expr[i] != ':' || expr[i+1] == ':' {
+				if strings.HasPrefix(expr[i:], "::") {
+					i += 2
+				}
+				continue
+			}
+			next, _ := utf8.DecodeRuneInString(expr[i+1:])
+			if next == '*' || xmlchar.IsNCNameStartChar(next) {
+				refs = append(refs, prefixRef{start: start, end: i})
+			}
+			i++
+			continue
+		case r >= '0' && r <= '9':
+			// An exponent is not the start of a name.
+			for i < len(expr) && (expr[i] >= '0' && expr[i] <= '9' || expr[i] == '.' || expr[i] == 'e' || expr[i] == 'E') {
+				i++
+			}
+			continue
+		}
+		i += width
+	}
+	return refs
+}
@@ -153,7 +153,12 @@ func checkRelativeNamespaceURI(e *helium.Element, ns *helium.Namespace) error {
 	if ns == nil {
 		return nil
 	}
-	uri := ns.URI()
+	return checkNamespaceURI(e.Name(), ns.URI())
+}
+
+// checkNamespaceURI reports an error when uri, a namespace URI in scope on the
+// element named elemName, is relative or malformed.
+func checkNamespaceURI(elemName, uri string) error {
 	if uri == "" {
 		return nil
 	}
@@ -165,11 +170,11 @@ func checkRelativeNamespaceURI(e *helium.Element, ns *helium.Namespace) error {
 	// opaque part (e.g. "urn:foo bar") that libxml2's parser rejects, so reject
 	// any whitespace/control byte up front — a valid URI never contains one.
 	if !validURIReference(uri) {
-		return fmt.Errorf("c14n: invalid namespace URI %q on element %s", uri, e.Name())
+		return fmt.Errorf("c14n: invalid namespace URI %q on element %s", uri, elemName)
 	}
 	parsed, err := url.Parse(uri)
 	if err != nil || parsed.Scheme == "" {
-		return fmt.Errorf("c14n: relative namespace URI %q on element %s", uri, e.Name())
+		return fmt.Errorf("c14n: relative namespace URI %q on element %s", uri, elemName)
 	}
 	return nil
 }
@@ -463,33 +468,43 @@ func (c *canonicalizer) processComment(cm *helium.Comment, beforeRoot bool) erro
 }
 
 func (c *canonicalizer) writePI(pi *helium.ProcessingInstruction) error {
-	if _, err := io.WriteString(c.out, "<?"); err != nil {
+	return writeProcessingInstruction(c.out, pi.Name(), pi.Content())
+}
+
+func (c *canonicalizer) writeComment(cm *helium.Comment) error {
+	return writeCommentText(c.out, cm.Content())
+}
+
+// writeProcessingInstruction writes the canonical form of a processing
+// instruction.
+func writeProcessingInstruction(w io.Writer, target string, data []byte) error {
+	if _, err := io.WriteString(w, "<?"); err != nil {
 		return err
 	}
-	if _, err := io.WriteString(c.out, pi.Name()); err != nil {
+	if _, err := io.WriteString(w, target); err != nil {
 		return err
 	}
-	data := pi.Content()
 	if len(data) > 0 {
-		if _, err := io.WriteString(c.out, " "); err != nil {
+		if _, err := io.WriteString(w, " "); err != nil {
 			return err
 		}
-		if err := escapePIOrComment(c.out, data); err != nil {
+		if err := escapePIOrComment(w, data); err != nil {
 			return err
 		}
 	}
-	_, err := io.WriteString(c.out, "?>")
+	_, err := io.WriteString(w, "?>")
 	return err
 }
 
-func (c *canonicalizer) writeComment(cm *helium.Comment) error {
-	if _, err := io.WriteString(c.out, "<!--"); err != nil {
+// writeCommentText writes the canonical form of a comment.
+func writeCommentText(w io.Writer, data []byte) error {
+	if _, err := io.WriteString(w, "<!--"); err != nil {
 		return err
 	}
-	if err := escapePIOrComment(c.out, cm.Content()); err != nil {
+	if err := escapePIOrComment(w, data); err != nil {
 		return err
 	}
-	_, err := io.WriteString(c.out, "-->")
+	_, err := io.WriteString(w, "-->")
 	return err
 }
 
@@ -1013,24 +1028,22 @@ func (c *canonicalizer) collectInScopeNamespaces(e *helium.Element) map[string]s
 }
 
 func (c *canonicalizer) writeNSDecl(prefix, uri string) error {
-	if _, err := io.WriteString(c.out, " xmlns"); err != nil {
-		return err
-	}
+	return writeNamespaceDecl(c.out, prefix, uri)
+}
+
+// writeNamespaceDecl writes a namespace declaration attribute.
+func writeNamespaceDecl(w io.Writer, prefix, uri string) error {
+	name := "xmlns"
 	if prefix != "" {
-		if _, err := io.WriteString(c.out, ":"); err != nil {
-			return err
-		}
-		if _, err := io.WriteString(c.out, prefix); err != nil {
-			return err
-		}
+		name += ":" + prefix
 	}
-	if _, err := io.WriteString(c.out, `="`); err != nil {
+	if _, err := io.WriteString(w, " "+name+`="`); err != nil {
 		return err
 	}
-	if err := escapeAttrValue(c.out, []byte(uri)); err != nil {
+	if err := escapeAttrValue(w, []byte(uri)); err != nil {
 		return err
 	}
-	_, err := io.WriteString(c.out, `"`)
+	_, err := io.WriteString(w, `"`)
 	return err
 }
 
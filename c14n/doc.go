@@ -1,6 +1,6 @@
 // Package c14n implements XML canonicalization (C14N) as defined by the W3C
-// specifications: Canonical XML 1.0, Exclusive Canonical XML 1.0, and
-// Canonical XML 1.1.
+// specifications: Canonical XML 1.0, Exclusive Canonical XML 1.0,
+// Canonical XML 1.1, and Canonical XML 2.0.
 //
 // Use [NewCanonicalizer] with a [Mode] to create a canonicalizer, then
 // configure it with fluent builder methods:
@@ -10,6 +10,31 @@
 //	    InclusiveNamespaces([]string{"ns1"}).
 //	    CanonicalizeTo(doc)
 //
+// # Canonical XML 2.0
+//
+// [C14N20] takes the parameters of the Canonical XML 2.0 specification as
+// builder methods: [Canonicalizer.Comments] for IgnoreComments=false,
+// [Canonicalizer.TrimTextNodes], [Canonicalizer.PrefixRewrite], and the
+// QNameAware* methods naming the elements and attributes whose content is a
+// QName or an XPath expression:
+//
+//	out, err := c14n.NewCanonicalizer(c14n.C14N20).
+//	    PrefixRewrite(c14n.PrefixRewriteSequential).
+//	    QNameAwareQualifiedAttribute("http://www.w3.org/2001/XMLSchema-instance", "type").
+//	    CanonicalizeTo(doc)
+//
+// # Streaming
+//
+// [Canonicalizer.Handler] returns a SAX handler that writes the canonical form
+// of a document while it is parsed, without building a tree, and
+// [Canonicalizer.SubtreeByID] limits the output to the subtree a
+// same-document reference selects:
+//
+//	h := c14n.NewCanonicalizer(c14n.ExclusiveC14N10).SubtreeByID("body").Handler(sha256.New())
+//	_, err := helium.NewParser().SAXHandler(h).ParseReader(ctx, r)
+//
+// Both are helium extensions not present in libxml2.
+//
 // # Builder Design
 //
 // Boolean toggles like [Canonicalizer.Comments] are parameterless methods
@@ -0,0 +1,681 @@
+package c14n
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/sax"
+)
+
+// Handler is a SAX2Handler that writes the canonical form of the document
+// whose events it receives. It is created by [Canonicalizer.Handler].
+//
+// Output is written as the events arrive, so a document can be canonicalized,
+// or digested by passing a [hash.Hash] as the writer, while it is parsed and
+// without building a tree. Memory grows with the depth of the document, not
+// with its size: for each open element the handler keeps the namespaces it
+// declares and, while a [Canonicalizer.SubtreeByID] subtree has not started,
+// its xml:* attributes.
+//
+// References to internal entities whose replacement text has no markup are
+// replaced by that text; other entity references cannot be canonicalized
+// from events and fail with an error wrapping [errors.ErrUnsupported]. Let
+// the parser substitute entities to avoid them. Handler answers GetEntity from
+// the declarations it has seen, so that a parser can resolve references to
+// entities of the internal subset.
+//
+// The first error, whether an event is invalid or the writer fails, is
+// returned by that event and every later one, so a parser stops at it.
+type Handler struct {
+	out            *bufio.Writer
+	mode           Mode
+	withComments   bool
+	inclusive      map[string]struct{}
+	strictXMLAttrs bool
+	subtree        bool
+	subtreeID      string
+	err            error
+
+	nsStack   *visibleNSStack
+	frames    []handlerFrame
+	apex      int // index in frames of the selected subtree's root, or -1
+	matched   bool
+	afterRoot bool
+
+	dtdOpen bool
+	dtdDoc  *helium.Document
+	dtd     *helium.DTD
+	idAttrs map[string]map[string]struct{} // element name → attributes declared ID-typed
+}
+
+// handlerFrame is an open element.
+type handlerFrame struct {
+	namespaces []sax.Namespace
+	xmlAttrs   []xmlAttr // xml:* attributes an omitted element passes on
+	visible    bool
+}
+
+// xmlAttr is an attribute in the xml namespace.
+type xmlAttr struct {
+	localName string
+	value     string
+}
+
+// handlerAttr is an attribute of the start tag being written.
+type handlerAttr struct {
+	prefix    string
+	localName string
+	nsURI     string
+	value     string
+}
+
+// Handler returns a SAX handler that writes the canonical form of the
+// document whose events it receives to out. The output is complete, and
+// flushed to out, once the handler has received EndDocument. The handler can
+// be given to a [helium.Parser] to canonicalize a document as it is parsed:
+//
+//	h := c14n.NewCanonicalizer(c14n.ExclusiveC14N10).Handler(sha256.New())
+//	_, err := helium.NewParser().SAXHandler(h).ParseReader(ctx, r)
+//
+// A node set needs a tree to select from, so a Canonicalizer configured with
+// [Canonicalizer.NodeSet] yields a handler that fails at the first event;
+// [Canonicalizer.SubtreeByID] selects a subtree without one. [C14N20] decides
+// an element's namespace declarations from its content, so it cannot be
+// written from events and the handler fails with an error wrapping
+// [errors.ErrUnsupported].
+func (c Canonicalizer) Handler(out io.Writer) *Handler {
+	cfg := c.cfg
+	if cfg == nil {
+		cfg = &canonicalizerCfg{}
+	}
+	h := &Handler{
+		out:            bufio.NewWriter(out),
+		mode:           cfg.mode,
+		withComments:   cfg.withComments,
+		inclusive:      cfg.inclusivePrefixSet(),
+		strictXMLAttrs: cfg.strictXMLAttrs,
+		subtree:        cfg.subtreeIDSet,
+		subtreeID:      cfg.subtreeID,
+		nsStack:        newVisibleNSStack(),
+		apex:           -1,
+	}
+	switch {
+	case cfg.mode == C14N20:
+		h.err = fmt.Errorf("c14n: Canonical XML 2.0 cannot be canonicalized from SAX events: %w", errors.ErrUnsupported)
+	case cfg.nodeSetSet && cfg.subtreeIDSet:
+		h.err = errSubtreeWithNodeSet
+	case cfg.nodeSetSet:
+		h.err = errors.New("c14n: a node set cannot be canonicalized from SAX events")
+	}
+	return h
+}
+
+// fail records the first error, which every later event returns.
+func (h *Handler) fail(err error) error {
+	if h.err == nil {
+		h.err = err
+	}
+	return h.err
+}
+
+// selected reports whether the innermost open element is output.
+func (h *Handler) selected() bool {
+	n := len(h.frames)
+	return n > 0 && h.frames[n-1].visible
+}
+
+// strict reports whether strict W3C xml:* handling applies. As for a tree, it
+// governs document subsets only.
+func (h *Handler) strict() bool {
+	return h.strictXMLAttrs && h.subtree
+}
+
+// lookupNS resolves prefix against the declarations of the open elements.
+func (h *Handler) lookupNS(prefix string) (string, bool) {
+	if prefix == lexicon.PrefixXML {
+		return lexicon.NamespaceXML, true
+	}
+	for _, f := range slices.Backward(h.frames) {
+		for _, ns := range f.namespaces {
+			if ns.Prefix() == prefix {
+				return ns.URI(), true
+			}
+		}
+	}
+	return "", false
+}
+
+// isID reports whether the element named name carries the subtree's ID.
+func (h *Handler) isID(name string, attrs []sax.Attribute) bool {
+	declared := h.idAttrs[name]
+	for _, a := range attrs {
+		if !isIDName(a.Name()) {
+			if _, ok := declared[a.Name()]; !ok {
+				continue
+			}
+		}
+		if strings.TrimSpace(a.Value()) == h.subtreeID {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) SetDocumentLocator(context.Context, sax.DocumentLocator) error {
+	return nil
+}
+
+func (h *Handler) StartDocument(context.Context) error {
+	return h.err
+}
+
+func (h *Handler) EndDocument(context.Context) error {
+	if h.err != nil {
+		return h.err
+	}
+	if h.subtree && !h.matched {
+		return h.fail(noSubtreeError(h.subtreeID))
+	}
+	return h.fail(h.out.Flush())
+}
+
+func (h *Handler) InternalSubset(_ context.Context, name, externalID, systemID string) error {
+	if h.err != nil {
+		return h.err
+	}
+	h.dtdOpen = true
+	h.dtdDoc = helium.NewDocument("1.0", "", helium.StandaloneImplicitNo)
+	dtd, err := h.dtdDoc.CreateInternalSubset(name, externalID, systemID)
+	if err != nil {
+		return h.fail(err)
+	}
+	h.dtd = dtd
+	return nil
+}
+
+func (h *Handler) ExternalSubset(context.Context, string, string, string) error {
+	h.dtdOpen = false
+	return h.err
+}
+
+func (h *Handler) EntityDecl(_ context.Context, name string, typ enum.EntityType, publicID, systemID, content string) error {
+	if h.err != nil || h.dtd == nil {
+		return h.err
+	}
+	if _, err := h.dtd.AddEntity(name, typ, publicID, systemID, content); err != nil {
+		return h.fail(err)
+	}
+	return nil
+}
+
+func (h *Handler) UnparsedEntityDecl(context.Context, string, string, string, string) error {
+	return h.err
+}
+
+func (h *Handler) NotationDecl(context.Context, string, string, string) error {
+	return h.err
+}
+
+func (h *Handler) ElementDecl(context.Context, string, enum.ElementType, sax.ElementContent) error {
+	return h.err
+}
+
+// AttributeDecl records attributes declared ID-typed, which
+// [Canonicalizer.SubtreeByID] matches.
+func (h *Handler) AttributeDecl(_ context.Context, elem, fullname string, typ enum.AttributeType, _ enum.AttributeDefault, _ string, _ sax.Enumeration) error {
+	if h.err != nil || typ != enum.AttrID {
+		return h.err
+	}
+	if h.idAttrs == nil {
+		h.idAttrs = make(map[string]map[string]struct{})
+	}
+	if h.idAttrs[elem] == nil {
+		h.idAttrs[elem] = make(map[string]struct{})
+	}
+	h.idAttrs[elem][fullname] = struct{}{}
+	return nil
+}
+
+func (h *Handler) StartElementNS(_ context.Context, localname, prefix, uri string, namespaces []sax.Namespace, attrs []sax.Attribute) error {
+	if h.err != nil {
+		return h.err
+	}
+	h.dtdOpen = false
+	name := qualifiedName(prefix, localname)
+	if err := checkNamespaceURI(name, uri); err != nil {
+		return h.fail(err)
+	}
+	for _, ns := range namespaces {
+		if err := checkNamespaceURI(name, ns.URI()); err != nil {
+			return h.fail(err)
+		}
+	}
+
+	visible := !h.subtree || h.apex >= 0
+	if h.subtree && h.isID(name, attrs) {
+		if h.matched {
+			return h.fail(ambiguousSubtreeError(h.subtreeID))
+		}
+		h.matched = true
+		h.apex = len(h.frames)
+		visible = true
+	}
+	f := handlerFrame{namespaces: namespaces, visible: visible}
+	if !visible && h.mode != ExclusiveC14N10 {
+		// An omitted element may be the ancestor of the subtree, which
+		// inherits its xml:* attributes.
+		for _, a := range attrs {
+			if a.Prefix() == lexicon.PrefixXML {
+				f.xmlAttrs = append(f.xmlAttrs, xmlAttr{localName: a.LocalName(), value: a.Value()})
+			}
+		}
+	}
+	h.frames = append(h.frames, f)
+	h.nsStack.save()
+	if !visible {
+		return nil
+	}
+
+	if _, err := io.WriteString(h.out, "<"+name); err != nil {
+		return h.fail(err)
+	}
+	if err := h.writeNamespaces(prefix, uri, namespaces, attrs); err != nil {
+		return h.fail(err)
+	}
+	if err := h.writeAttributes(attrs); err != nil {
+		return h.fail(err)
+	}
+	if _, err := io.WriteString(h.out, ">"); err != nil {
+		return h.fail(err)
+	}
+	return nil
+}
+
+// writeNamespaces writes the namespace axis of the element being started,
+// whose frame is on top of the stack.
+func (h *Handler) writeNamespaces(prefix, uri string, namespaces []sax.Namespace, attrs []sax.Attribute) error {
+	apex := h.apex == len(h.frames)-1
+	candidates := make(map[string]string)
+	if h.mode == ExclusiveC14N10 {
+		// Visibly utilized namespaces plus the inclusive prefixes.
+		if uri != "" {
+			candidates[prefix] = uri
+		} else if existing, found := h.nsStack.lookup(""); found && existing != "" {
+			candidates[""] = ""
+		}
+		for _, a := range attrs {
+			if p := a.Prefix(); p != "" {
+				u, ok := h.lookupNS(p)
+				if !ok {
+					return fmt.Errorf("c14n: namespace prefix %q of attribute %q is not declared", p, a.Name())
+				}
+				candidates[p] = u
+			}
+		}
+		for p := range h.inclusive {
+			if p == lexicon.PrefixXML {
+				continue
+			}
+			if u, ok := h.lookupNS(p); ok {
+				candidates[p] = u
+			}
+		}
+	} else {
+		// The parent rendered every namespace in its scope, so only the
+		// element's own declarations can differ, except on the root of a
+		// subtree, whose omitted ancestors rendered nothing.
+		if apex {
+			for _, f := range h.frames {
+				for _, ns := range f.namespaces {
+					candidates[ns.Prefix()] = ns.URI()
+				}
+			}
+		}
+		for _, ns := range namespaces {
+			candidates[ns.Prefix()] = ns.URI()
+		}
+	}
+
+	var toOutput []nsSortEntry
+	for p, u := range candidates {
+		// The xml prefix is never declared in scope. Like the tree
+		// canonicalizer, exclusive canonicalization of a whole document
+		// still renders it when an xml:* attribute visibly utilizes it; that
+		// of a subtree, which the tree canonicalizer handles as a node set,
+		// does not.
+		if p == lexicon.PrefixXML && (h.mode != ExclusiveC14N10 || h.subtree) {
+			continue
+		}
+		if h.nsStack.needsOutput(p, u) {
+			toOutput = append(toOutput, nsSortEntry{prefix: p, uri: u})
+			h.nsStack.add(p, u)
+		}
+	}
+	sortNamespaces(toOutput)
+	for _, ns := range toOutput {
+		if _, err := io.WriteString(h.out, " xmlns"); err != nil {
+			return err
+		}
+		if ns.prefix != "" {
+			if _, err := io.WriteString(h.out, ":"+ns.prefix); err != nil {
+				return err
+			}
+		}
+		if err := h.writeValue(ns.uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAttributes writes the attribute axis of the element being started,
+// whose frame is on top of the stack.
+func (h *Handler) writeAttributes(attrs []sax.Attribute) error {
+	entries := make([]handlerAttr, 0, len(attrs))
+	own := make(map[string]string)
+	for _, a := range attrs {
+		var nsURI string
+		if p := a.Prefix(); p != "" {
+			var ok bool
+			if nsURI, ok = h.lookupNS(p); !ok {
+				return fmt.Errorf("c14n: namespace prefix %q of attribute %q is not declared", p, a.Name())
+			}
+		}
+		if nsURI == lexicon.NamespaceXML {
+			own[a.LocalName()] = a.Value()
+			// C14N 1.1 handles xml:lang, xml:space and xml:base specially
+			// (below).
+			if h.mode == C14N11 && isInheritableXMLName(a.LocalName()) {
+				continue
+			}
+		}
+		entries = append(entries, handlerAttr{prefix: a.Prefix(), localName: a.LocalName(), nsURI: nsURI, value: a.Value()})
+	}
+
+	// The omitted ancestors of the root of a subtree, nearest last.
+	var omitted []handlerFrame
+	if h.apex == len(h.frames)-1 {
+		omitted = h.frames[:h.apex]
+	}
+	xmlEntry := func(localName, value string) handlerAttr {
+		return handlerAttr{prefix: lexicon.PrefixXML, localName: localName, nsURI: lexicon.NamespaceXML, value: value}
+	}
+
+	switch h.mode {
+	case C14N10:
+		// Import the nearest omitted-ancestor value of each xml:* attribute
+		// the element does not carry itself.
+		blocked := make(map[string]struct{}, len(own))
+		for ln := range own {
+			blocked[ln] = struct{}{}
+		}
+		for _, f := range slices.Backward(omitted) {
+			for _, a := range f.xmlAttrs {
+				if _, ok := blocked[a.localName]; ok {
+					continue
+				}
+				entries = append(entries, xmlEntry(a.localName, a.value))
+				blocked[a.localName] = struct{}{}
+			}
+		}
+	case C14N11:
+		for _, ln := range []string{"lang", "space"} {
+			if v, ok := own[ln]; ok {
+				entries = append(entries, xmlEntry(ln, v))
+				continue
+			}
+			if v, ok := nearestXMLAttr(omitted, ln); ok {
+				entries = append(entries, xmlEntry(ln, v))
+			}
+		}
+		base, ok, err := h.xmlBase11(omitted, own)
+		if err != nil {
+			return err
+		}
+		if ok {
+			entries = append(entries, xmlEntry(xmlBaseLocalName, base))
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b handlerAttr) int {
+		return compareAttrNames(a.nsURI, a.localName, b.nsURI, b.localName)
+	})
+	for _, a := range entries {
+		if h.strict() && a.nsURI == lexicon.NamespaceXML && a.localName == xmlBaseLocalName && !faithfulXMLBaseValue(a.value) {
+			return fmt.Errorf("c14n: xml:base %q cannot be canonicalized faithfully", a.value)
+		}
+		name := a.localName
+		if a.prefix != "" {
+			name = a.prefix + ":" + name
+		}
+		if _, err := io.WriteString(h.out, " "+name); err != nil {
+			return err
+		}
+		if err := h.writeValue(a.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xmlBase11 computes the C14N 1.1 xml:base value of an element from its own
+// xml:base and those of the omitted ancestors, as the tree canonicalizer's
+// processXMLBase11 does.
+func (h *Handler) xmlBase11(omitted []handlerFrame, own map[string]string) (string, bool, error) {
+	var chain []string
+	for _, f := range omitted {
+		for _, a := range f.xmlAttrs {
+			if a.localName == xmlBaseLocalName {
+				chain = append(chain, a.value)
+			}
+		}
+	}
+	ownBase, hasOwn := own[xmlBaseLocalName]
+	if h.strict() && len(chain) == 0 {
+		return ownBase, ownBase != "", nil
+	}
+	if hasOwn {
+		chain = append(chain, ownBase)
+	}
+	if len(chain) == 0 {
+		return "", false, nil
+	}
+	res, faithful := reduceXMLBase(chain)
+	if !faithful && h.strict() {
+		return "", false, fmt.Errorf("c14n: xml:base cannot be canonicalized faithfully")
+	}
+	return res, res != "", nil
+}
+
+// nearestXMLAttr returns the value of xml:localName on the nearest of the
+// omitted frames that carries one.
+func nearestXMLAttr(omitted []handlerFrame, localName string) (string, bool) {
+	for _, f := range slices.Backward(omitted) {
+		for _, a := range f.xmlAttrs {
+			if a.localName == localName {
+				return a.value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// isInheritableXMLName reports whether xml:localName is one of the attributes
+// that C14N 1.1 processes specially.
+func isInheritableXMLName(localName string) bool {
+	switch localName {
+	case "lang", "space", xmlBaseLocalName:
+		return true
+	}
+	return false
+}
+
+// writeValue writes `="value"` with the value escaped.
+func (h *Handler) writeValue(value string) error {
+	if _, err := io.WriteString(h.out, `="`); err != nil {
+		return err
+	}
+	if err := escapeAttrValue(h.out, []byte(value)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(h.out, `"`)
+	return err
+}
+
+func qualifiedName(prefix, localName string) string {
+	if prefix == "" {
+		return localName
+	}
+	return prefix + ":" + localName
+}
+
+func (h *Handler) EndElementNS(_ context.Context, localname, prefix, _ string) error {
+	if h.err != nil {
+		return h.err
+	}
+	n := len(h.frames)
+	if n == 0 {
+		return nil
+	}
+	if h.frames[n-1].visible {
+		if _, err := io.WriteString(h.out, "</"+qualifiedName(prefix, localname)+">"); err != nil {
+			return h.fail(err)
+		}
+	}
+	h.nsStack.restore()
+	h.frames = h.frames[:n-1]
+	if h.apex == n-1 {
+		h.apex = -1
+	}
+	if n == 1 {
+		h.afterRoot = true
+	}
+	return nil
+}
+
+func (h *Handler) Characters(_ context.Context, ch []byte) error {
+	if h.err != nil || !h.selected() {
+		return h.err
+	}
+	return h.fail(escapeText(h.out, ch))
+}
+
+func (h *Handler) IgnorableWhitespace(ctx context.Context, ch []byte) error {
+	return h.Characters(ctx, ch)
+}
+
+func (h *Handler) CDataBlock(ctx context.Context, value []byte) error {
+	return h.Characters(ctx, value)
+}
+
+func (h *Handler) Comment(_ context.Context, value []byte) error {
+	if h.err != nil || !h.withComments {
+		return h.err
+	}
+	return h.fail(h.writeMisc(func() error {
+		return writeCommentText(h.out, value)
+	}))
+}
+
+func (h *Handler) ProcessingInstruction(_ context.Context, target, data string) error {
+	if h.err != nil {
+		return h.err
+	}
+	return h.fail(h.writeMisc(func() error {
+		return writeProcessingInstruction(h.out, target, []byte(data))
+	}))
+}
+
+// writeMisc writes a comment or processing instruction with write. Inside the
+// document element it is written as is; outside, it is separated from the
+// document element by a newline, and it is left out of a subtree.
+func (h *Handler) writeMisc(write func() error) error {
+	if h.dtdOpen {
+		return nil
+	}
+	if len(h.frames) > 0 {
+		if !h.selected() {
+			return nil
+		}
+		return write()
+	}
+	if h.subtree {
+		return nil
+	}
+	if h.afterRoot {
+		if _, err := io.WriteString(h.out, "\n"); err != nil {
+			return err
+		}
+		return write()
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(h.out, "\n")
+	return err
+}
+
+// Reference replaces a reference to an internal entity with its text.
+func (h *Handler) Reference(ctx context.Context, name string) error {
+	if h.err != nil {
+		return h.err
+	}
+	if h.dtd != nil {
+		if ent, ok := h.dtd.LookupEntity(name); ok && ent.EntityType() == enum.InternalGeneralEntity {
+			if text := ent.Content(); !slices.Contains(text, '<') && !slices.Contains(text, '&') {
+				return h.Characters(ctx, text)
+			}
+		}
+	}
+	return h.fail(fmt.Errorf("c14n: reference to entity %q cannot be canonicalized without a tree: %w", name, errors.ErrUnsupported))
+}
+
+func (h *Handler) GetEntity(_ context.Context, name string) (sax.Entity, error) {
+	if h.dtd != nil {
+		if ent, ok := h.dtd.LookupEntity(name); ok {
+			return ent, nil
+		}
+	}
+	return nil, nil //nolint:nilnil // an undeclared entity is not an error here
+}
+
+func (h *Handler) GetParameterEntity(_ context.Context, name string) (sax.Entity, error) {
+	if h.dtd != nil {
+		if ent, ok := h.dtd.LookupParameterEntity(name); ok {
+			return ent, nil
+		}
+	}
+	return nil, nil //nolint:nilnil // an undeclared entity is not an error here
+}
+
+func (h *Handler) HasExternalSubset(context.Context) (bool, error) {
+	return false, sax.ErrHandlerUnspecified
+}
+
+func (h *Handler) HasInternalSubset(context.Context) (bool, error) {
+	return false, sax.ErrHandlerUnspecified
+}
+
+func (h *Handler) IsStandalone(context.Context) (bool, error) {
+	return false, sax.ErrHandlerUnspecified
+}
+
+func (h *Handler) ResolveEntity(context.Context, string, string) (sax.ParseInput, error) {
+	return nil, sax.ErrHandlerUnspecified
+}
+
+func (h *Handler) Error(context.Context, error) error {
+	return sax.ErrHandlerUnspecified
+}
+
+func (h *Handler) Warning(context.Context, error) error {
+	return sax.ErrHandlerUnspecified
+}
@@ -0,0 +1,213 @@
+package c14n_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/c14n"
+	"github.com/stretchr/testify/require"
+)
+
+// canonicalizeEvents parses src with h as the SAX handler.
+func canonicalizeEvents(t *testing.T, can c14n.Canonicalizer, src []byte, p helium.Parser) ([]byte, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	_, err := p.SAXHandler(can.Handler(&buf)).Parse(t.Context(), src)
+	return buf.Bytes(), err
+}
+
+// TestHandlerCorpus checks that canonicalizing parse events gives the bytes
+// the tree canonicalizer gives for every whole-document test document.
+func TestHandlerCorpus(t *testing.T) {
+	t.Parallel()
+	categories := map[string]c14n.Canonicalizer{
+		"without-comments":     c14n.NewCanonicalizer(c14n.C14N10),
+		"with-comments":        c14n.NewCanonicalizer(c14n.C14N10).Comments(),
+		"exc-without-comments": c14n.NewCanonicalizer(c14n.ExclusiveC14N10),
+		"1-1-without-comments": c14n.NewCanonicalizer(c14n.C14N11),
+	}
+	for category, can := range categories {
+		inputs, err := filepath.Glob(filepath.Join(testdataBase, category, "test", "*.xml"))
+		require.NoError(t, err)
+		require.NotEmpty(t, inputs)
+		for _, input := range inputs {
+			name := strings.TrimSuffix(filepath.Base(input), ".xml")
+			t.Run(category+"/"+name, func(t *testing.T) {
+				t.Parallel()
+				base := strings.TrimSuffix(input, ".xml")
+				if _, err := os.Stat(base + ".xpath"); err == nil {
+					t.Skip("node sets need a tree")
+				}
+				if name == "example-5" {
+					t.Skip("external parsed entities are loaded by the tree builder")
+				}
+				can := can
+				if _, err := os.Stat(base + ".ns"); err == nil {
+					can = can.InclusiveNamespaces(parseNSFile(t, base+".ns"))
+				}
+				want, err := can.CanonicalizeTo(parseTestDoc(t, input))
+				require.NoError(t, err)
+
+				data, err := os.ReadFile(input)
+				require.NoError(t, err)
+				p := helium.NewParser().BlockXXE(false).SubstituteEntities(true).LoadExternalDTD(true).DefaultDTDAttributes(true).BaseURI(input).FS(helium.PermissiveFS())
+				got, err := canonicalizeEvents(t, can, data, p)
+				require.NoError(t, err)
+				require.Equal(t, string(want), string(got))
+			})
+		}
+	}
+}
+
+const subtreeDoc = `<?xml version="1.0"?>
+<!DOCTYPE doc [<!ATTLIST a:item ref ID #IMPLIED>]>
+<?before root?>
+<doc xmlns="urn:default" xmlns:a="urn:a" xmlns:unused="urn:unused" xml:lang="en" xml:base="http://example.com/base/">
+  <!-- outside -->
+  <section xml:space="preserve" xml:base="sub/">
+    <a:item ref=" target " a:flag="1" xml:base="leaf/">
+      <!-- inside -->
+      <plain xmlns=""><?pi data?>text &amp; more</plain>
+      <a:child xml:lang="fr"/>
+    </a:item>
+  </section>
+  <other Id="other"/>
+</doc>
+<!-- after root -->`
+
+func TestHandlerSubtreeByID(t *testing.T) {
+	t.Parallel()
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(subtreeDoc))
+	require.NoError(t, err)
+	modes := map[string]c14n.Mode{
+		"C14N10":          c14n.C14N10,
+		"ExclusiveC14N10": c14n.ExclusiveC14N10,
+		"C14N11":          c14n.C14N11,
+	}
+	for name, mode := range modes {
+		for _, comments := range []bool{false, true} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				can := c14n.NewCanonicalizer(mode)
+				if comments {
+					can = can.Comments()
+				}
+				// The subtree is the node set of a same-document reference.
+				nodes := evaluateNodeSet(t, doc, `(//. | //@* | //namespace::*)[ancestor-or-self::*[@ref='target']]`, nil)
+				want, err := can.NodeSet(nodes).CanonicalizeTo(doc)
+				require.NoError(t, err)
+
+				got, err := can.SubtreeByID("target").CanonicalizeTo(doc)
+				require.NoError(t, err)
+				require.Equal(t, string(want), string(got))
+
+				got, err = canonicalizeEvents(t, can.SubtreeByID("target"), []byte(subtreeDoc), helium.NewParser())
+				require.NoError(t, err)
+				require.Equal(t, string(want), string(got))
+			})
+		}
+	}
+
+	t.Run("exclusive output", func(t *testing.T) {
+		t.Parallel()
+		got, err := canonicalizeEvents(t, c14n.NewCanonicalizer(c14n.ExclusiveC14N10).SubtreeByID("other"), []byte(subtreeDoc), helium.NewParser())
+		require.NoError(t, err)
+		require.Equal(t, `<other xmlns="urn:default" Id="other"></other>`, string(got))
+	})
+}
+
+func TestHandlerSubtreeErrors(t *testing.T) {
+	t.Parallel()
+	can := c14n.NewCanonicalizer(c14n.C14N10)
+
+	t.Run("no match", func(t *testing.T) {
+		t.Parallel()
+		_, err := canonicalizeEvents(t, can.SubtreeByID("missing"), []byte(subtreeDoc), helium.NewParser())
+		require.ErrorContains(t, err, `no element has the ID "missing"`)
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		t.Parallel()
+		const src = `<doc><a id="x"/><b xml:id="x"/></doc>`
+		_, err := canonicalizeEvents(t, can.SubtreeByID("x"), []byte(src), helium.NewParser())
+		require.ErrorContains(t, err, `more than one element has the ID "x"`)
+
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		_, err = can.SubtreeByID("x").CanonicalizeTo(doc)
+		require.ErrorContains(t, err, `more than one element has the ID "x"`)
+	})
+
+	t.Run("with a node set", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(subtreeDoc))
+		require.NoError(t, err)
+		_, err = can.NodeSet(nil).SubtreeByID("target").CanonicalizeTo(doc)
+		require.Error(t, err)
+		_, err = canonicalizeEvents(t, can.NodeSet(nil), []byte(subtreeDoc), helium.NewParser())
+		require.Error(t, err)
+	})
+}
+
+func TestHandlerErrors(t *testing.T) {
+	t.Parallel()
+	can := c14n.NewCanonicalizer(c14n.C14N10)
+
+	t.Run("relative namespace URI", func(t *testing.T) {
+		t.Parallel()
+		_, err := canonicalizeEvents(t, can, []byte(`<a xmlns="relative/uri"/>`), helium.NewParser())
+		require.ErrorContains(t, err, "relative namespace URI")
+	})
+
+	t.Run("entity with markup", func(t *testing.T) {
+		t.Parallel()
+		const src = `<!DOCTYPE a [<!ENTITY e "<b/>">]><a>&e;</a>`
+		_, err := canonicalizeEvents(t, can, []byte(src), helium.NewParser())
+		require.ErrorIs(t, err, errors.ErrUnsupported)
+	})
+
+	t.Run("text entity", func(t *testing.T) {
+		t.Parallel()
+		const src = `<!DOCTYPE a [<!ENTITY e "text">]><a>&e;</a>`
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		h := can.Handler(&buf)
+		require.NoError(t, helium.EmitSAX(t.Context(), doc, h))
+		require.Equal(t, `<a>text</a>`, buf.String())
+	})
+
+	t.Run("c14n 2.0", func(t *testing.T) {
+		t.Parallel()
+		_, err := canonicalizeEvents(t, c14n.NewCanonicalizer(c14n.C14N20), []byte(`<a/>`), helium.NewParser())
+		require.ErrorIs(t, err, errors.ErrUnsupported)
+	})
+
+	t.Run("failing writer", func(t *testing.T) {
+		t.Parallel()
+		w := &failWriter{limit: 0}
+		_, err := helium.NewParser().SAXHandler(can.Handler(w)).Parse(t.Context(), []byte(`<a/>`))
+		require.ErrorIs(t, err, errFailWriter)
+	})
+}
+
+func TestHandlerDigest(t *testing.T) {
+	t.Parallel()
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(subtreeDoc))
+	require.NoError(t, err)
+	can := c14n.NewCanonicalizer(c14n.ExclusiveC14N10)
+	want, err := can.CanonicalizeTo(doc)
+	require.NoError(t, err)
+
+	h := sha256.New()
+	_, err = helium.NewParser().SAXHandler(can.Handler(h)).Parse(t.Context(), []byte(subtreeDoc))
+	require.NoError(t, err)
+	sum := sha256.Sum256(want)
+	require.Equal(t, sum[:], h.Sum(nil))
+}
@@ -35,20 +35,25 @@ type attrSortEntry struct {
 // attributes sorted by (namespace URI, local name).
 func sortAttributes(attrs []attrSortEntry) {
 	slices.SortFunc(attrs, func(a, b attrSortEntry) int {
-		// No-namespace attrs come first
-		if a.nsURI == "" && b.nsURI != "" {
-			return -1
-		}
-		if a.nsURI != "" && b.nsURI == "" {
-			return 1
-		}
-		if a.nsURI == "" && b.nsURI == "" {
-			return cmp.Compare(a.localName, b.localName)
-		}
-		// Both have namespaces: sort by URI then local name
-		if c := cmp.Compare(a.nsURI, b.nsURI); c != 0 {
-			return c
-		}
-		return cmp.Compare(a.localName, b.localName)
+		return compareAttrNames(a.nsURI, a.localName, b.nsURI, b.localName)
 	})
 }
+
+// compareAttrNames orders two attribute names per C14N rules.
+func compareAttrNames(aURI, aLocal, bURI, bLocal string) int {
+	// No-namespace attrs come first
+	if aURI == "" && bURI != "" {
+		return -1
+	}
+	if aURI != "" && bURI == "" {
+		return 1
+	}
+	if aURI == "" && bURI == "" {
+		return cmp.Compare(aLocal, bLocal)
+	}
+	// Both have namespaces: sort by URI then local name
+	if c := cmp.Compare(aURI, bURI); c != 0 {
+		return c
+	}
+	return cmp.Compare(aLocal, bLocal)
+}
@@ -0,0 +1,74 @@
+package c14n
+
+import (
+	"errors"
+	"fmt"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/domutil"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+)
+
+var errSubtreeWithNodeSet = errors.New("c14n: SubtreeByID cannot be combined with NodeSet")
+
+// subtreeNodeSet returns the node set of the subtree rooted at the element of
+// doc whose ID is id: the element, its descendants, their attributes and their
+// namespace nodes, as the XPath expression of a same-document reference
+// selects them.
+func subtreeNodeSet(doc *helium.Document, id string) (map[helium.Node]struct{}, error) {
+	var matches []*helium.Element
+	if root := doc.DocumentElement(); root != nil {
+		matches = domutil.FindElementsByID(root, id)
+	}
+	switch len(matches) {
+	case 0:
+		return nil, noSubtreeError(id)
+	case 1:
+	default:
+		return nil, ambiguousSubtreeError(id)
+	}
+
+	set := make(map[helium.Node]struct{})
+	var walk func(e *helium.Element)
+	walk = func(e *helium.Element) {
+		set[e] = struct{}{}
+		for _, attr := range e.Attributes() {
+			set[attr] = struct{}{}
+		}
+		for prefix, ns := range domutil.InScopeNamespaces(e, true) {
+			// The namespace axis has no node for an undeclared default
+			// namespace.
+			if prefix == "" && ns.URI() == "" {
+				continue
+			}
+			set[helium.NewNamespaceNodeWrapper(ns, e)] = struct{}{}
+		}
+		for child := range helium.Children(e) {
+			set[child] = struct{}{}
+			if elem, ok := helium.AsNode[*helium.Element](child); ok {
+				walk(elem)
+			}
+		}
+	}
+	walk(matches[0])
+	return set, nil
+}
+
+func noSubtreeError(id string) error {
+	return fmt.Errorf("c14n: no element has the ID %q", id)
+}
+
+func ambiguousSubtreeError(id string) error {
+	return fmt.Errorf("c14n: more than one element has the ID %q", id)
+}
+
+// isIDName reports whether an attribute with the qualified name name is an ID
+// by name alone. The rule is the one same-document references are resolved
+// with in xmldsig1: xml:id and the casings Id, ID and id of the id token.
+func isIDName(name string) bool {
+	switch name {
+	case "Id", "ID", "id", lexicon.PrefixXML + ":id":
+		return true
+	}
+	return false
+}
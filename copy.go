@@ -104,7 +104,7 @@ func CopyDoc(src *Document) (*Document, error) {
 	// source's elements into the copy.
 	for id, srcElem := range src.ids {
 		if cp := corr.m[srcElem]; cp != nil {
-			dst.RegisterID(id, cp)
+			dst.registerID(id, cp)
 		}
 	}
 
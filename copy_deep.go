@@ -185,7 +185,7 @@ func copyLine(src, cp Node) {
 	if sdn == nil || cdn == nil {
 		return
 	}
-	cdn.SetLine(sdn.Line())
+	cdn.line = sdn.line
 }
 
 // recorded records the mapping and returns cp for convenient inline use.
@@ -122,6 +122,8 @@ func copyEntity(src *Entity, doc *Document) *Entity {
 	e := newEntity(src.name, src.entityType, src.externalID, src.systemID, src.content, src.orig)
 	e.replacement = src.replacement
 	e.uri = src.uri
+	e.resolvedURI = src.resolvedURI
+	e.textDeclVersion = src.textDeclVersion
 	e.checked = src.checked
 	e.expandedSize = src.expandedSize
 	e.doc = doc
@@ -146,6 +148,7 @@ func copyAttributeDecl(src *AttributeDecl, doc *Document) *AttributeDecl {
 	a.atype = src.atype
 	a.def = src.def
 	a.defvalue = src.defvalue
+	a.external = src.external
 	if src.tree != nil {
 		a.tree = make(Enumeration, len(src.tree))
 		copy(a.tree, src.tree)
@@ -56,6 +56,24 @@
 // Tree traversal helpers include [Walk], [Children], [Descendants], and
 // [ChildElements].
 //
+// # Extensions
+//
+// These features are helium extensions not present in libxml2:
+//
+//   - [Freeze] and [Document.Snapshot] — read-only copies of a document that
+//     any number of goroutines can read
+//   - [MarshalBinary] and [LoadBinary] — a binary format for caching parsed
+//     documents
+//   - [Document.EnableIndex] — name and value indexes for XPath lookups
+//   - [Document.Observe] — notifications of changes to a document
+//   - [Parser.PreserveLexical], [Parser.TrackPositions] and [Parser.Reparse]
+//     — lexical details, source positions and incremental reparsing
+//   - [Parser.Parallelism] — parallel parsing of large documents
+//   - [ResourceCache] — external resources loaded and parsed once for many
+//     documents
+//   - [EmitSAX], [DocumentBackend] and [SAXBackend] — conversions between
+//     trees, SAX events and stream.Writer output
+//
 // # Related packages
 //
 // Sub-packages provide additional XML processing:
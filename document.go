@@ -81,6 +81,32 @@ type Document struct {
 	// Declaration in a standalone="yes" document. See valid.go.
 	standaloneNormAttrs []standaloneNormAttr
 
+	// lexical records the source markup of a document parsed with
+	// Parser.PreserveLexical; nil otherwise. See lexical.go.
+	lexical *lexicalInfo
+
+	// source holds the input and the extent of each node in it for a
+	// document parsed with Parser.TrackPositions; nil otherwise. See
+	// position.go.
+	source *sourceMap
+
+	// readOnly marks a document produced by Freeze; the guarded mutation
+	// operations reject it. See freeze.go.
+	readOnly bool
+
+	// generation counts changes to the document, so that Snapshot can hand
+	// out the previous snapshot while nothing changed. See snapshot.go.
+	generation  uint64
+	snapshot    *Document
+	snapshotGen uint64
+
+	// index answers name and value lookups once EnableIndex was called;
+	// nil otherwise. See index.go.
+	index *Index
+
+	// observers are notified of mutations. See observe.go.
+	observers []*mutationObserver
+
 	// Slab allocators for high-frequency node types.
 	// These reduce per-node heap allocation overhead by allocating
 	// nodes in chunks and handing them out one at a time.
@@ -157,6 +183,7 @@ func NewDocument(version, encoding string, standalone DocumentStandaloneType) *D
 // later parse overwrite the live node. In that case Free is a no-op and GC
 // reclaims the chunks once they are no longer referenced.
 func (d *Document) Free() {
+	d.index = nil
 	if d.slabEscaped {
 		return
 	}
@@ -227,6 +254,7 @@ func (d *Document) Encoding() string {
 
 // SetEncoding sets the document's recorded encoding; an empty string clears it.
 func (d *Document) SetEncoding(enc string) {
+	d.mustBeginMutation()
 	d.encoding = enc
 }
 
@@ -263,6 +291,7 @@ func (d *Document) SkipIDs() bool {
 // false restores normal resolution against the existing table (or the lazy
 // tree walk for API-built documents).
 func (d *Document) SetSkipIDs(v bool) {
+	d.mustBeginMutation()
 	d.idsSkip = v
 }
 
@@ -276,6 +305,7 @@ func (d *Document) Version() string {
 // Serialization consults this to decide whether XML 1.1 restricted control
 // characters are emitted as character references instead of being rejected.
 func (d *Document) SetVersion(v string) {
+	d.mustBeginMutation()
 	d.version = v
 }
 
@@ -289,6 +319,7 @@ func (d *Document) URL() string {
 
 // SetURL sets the document URI.
 func (d *Document) SetURL(url string) {
+	d.mustBeginMutation()
 	d.url = url
 }
 
@@ -299,6 +330,7 @@ func (d *Document) Properties() DocProperties {
 
 // SetProperties replaces the document's property flags.
 func (d *Document) SetProperties(p DocProperties) {
+	d.mustBeginMutation()
 	d.properties = p
 }
 
@@ -323,6 +355,7 @@ func (d *Document) RemoveInternalSubset() {
 	if d.intSubset == nil {
 		return
 	}
+	d.mustBeginMutation()
 	unlinkNode(d.intSubset)
 	d.intSubset = nil
 }
@@ -1139,6 +1172,13 @@ func (d *Document) CreateCharRef(name string) (*EntityRef, error) {
 // ID table. This is called during parsing to build an O(1) lookup table
 // for GetElementByID, mirroring libxml2's xmlAddID.
 func (d *Document) RegisterID(id string, elem *Element) {
+	d.mustBeginMutation()
+	d.registerID(id, elem)
+}
+
+// registerID is RegisterID for the parser and the copying code, which fill
+// in a document as they build it rather than change it.
+func (d *Document) registerID(id string, elem *Element) {
 	if d.ids == nil {
 		d.ids = make(map[string]*Element)
 	}
@@ -58,6 +58,12 @@ type Notation struct {
 	systemID string
 }
 
+// PublicID returns the notation's public identifier, or "" if it has none.
+func (n *Notation) PublicID() string { return n.publicID }
+
+// SystemID returns the notation's system identifier, or "" if it has none.
+func (n *Notation) SystemID() string { return n.systemID }
+
 // AddChild appends cur as the last child of the notation node.
 func (n *Notation) AddChild(cur Node) error { return addChild(n, cur) }
 
@@ -276,6 +282,7 @@ func (dtd *DTD) RemoveElement(name, prefix string) *ElementDecl {
 	if !ok {
 		return nil
 	}
+	dtd.doc.mustBeginMutation()
 	delete(dtd.elements, key)
 	unlinkNode(decl)
 	return decl
@@ -1,6 +1,10 @@
 package helium
 
-import "github.com/lestrrat-go/helium/enum"
+import (
+	"slices"
+
+	"github.com/lestrrat-go/helium/enum"
+)
 
 // AttributeDecl is an xml attribute declaration from DTD.
 type AttributeDecl struct {
@@ -58,6 +62,30 @@ func (n *AttributeDecl) Elem() string {
 	return n.elem
 }
 
+// Prefix returns the namespace prefix of the declared attribute name, or ""
+// if it has none. [Node.Name] returns the local part.
+func (n *AttributeDecl) Prefix() string {
+	return n.prefix
+}
+
+// Default returns how the attribute is defaulted: #REQUIRED, #IMPLIED,
+// #FIXED, or an ordinary default value ([enum.AttrDefaultNone]).
+func (n *AttributeDecl) Default() enum.AttributeDefault {
+	return n.def
+}
+
+// DefaultValue returns the declared default or #FIXED value, or "" when
+// there is none.
+func (n *AttributeDecl) DefaultValue() string {
+	return n.defvalue
+}
+
+// Enumeration returns a copy of the values an enumerated or NOTATION
+// attribute may take.
+func (n *AttributeDecl) Enumeration() Enumeration {
+	return slices.Clone(n.tree)
+}
+
 func lookupAttributeDecl(doc *Document, name, prefix, elem string) *AttributeDecl {
 	if doc == nil {
 		return nil
@@ -21,6 +21,18 @@ func (e *ElementDecl) DeclType() enum.ElementType {
 	return e.decltype
 }
 
+// Prefix returns the namespace prefix of the declared element name, or ""
+// if it has none. [Node.Name] returns the local part.
+func (e *ElementDecl) Prefix() string {
+	return e.prefix
+}
+
+// ContentModel returns the declared content model. It is nil for EMPTY and
+// ANY declarations.
+func (e *ElementDecl) ContentModel() *ElementContent {
+	return e.content
+}
+
 // ElementContentType describes the kind of node in an [ElementContent] tree.
 type ElementContentType int
 
@@ -68,6 +80,40 @@ type ElementContent struct {
 	parent *ElementContent
 }
 
+// Type returns the kind of this content node.
+func (c *ElementContent) Type() ElementContentType {
+	return c.ctype
+}
+
+// Occurrence returns this content node's occurrence indicator.
+func (c *ElementContent) Occurrence() ElementContentOccur {
+	return c.coccur
+}
+
+// Name returns the local name of the element an [ElementContentElement] leaf
+// refers to, or "" for other kinds of node.
+func (c *ElementContent) Name() string {
+	return c.name
+}
+
+// Prefix returns the namespace prefix of the element an
+// [ElementContentElement] leaf refers to, or "" if it has none.
+func (c *ElementContent) Prefix() string {
+	return c.prefix
+}
+
+// First returns the first operand of a sequence or choice node, or nil for
+// a leaf. For a #PCDATA-first mixed model it is the #PCDATA leaf.
+func (c *ElementContent) First() *ElementContent {
+	return c.c1
+}
+
+// Second returns the second operand of a sequence or choice node, or nil
+// for a leaf.
+func (c *ElementContent) Second() *ElementContent {
+	return c.c2
+}
+
 // SetOccurrence sets this content node's occurrence indicator (once, ?, *, +)
 // and returns the node for fluent composition. It returns an error if occur is
 // not one of the defined [ElementContentOccur] values.
@@ -411,6 +411,50 @@ func TestDTDElement(t *testing.T) {
 	})
 }
 
+// TestDTDModelAccessors covers the read accessors that expose a parsed
+// declaration's content model, attribute defaults and notation identifiers.
+func TestDTDModelAccessors(t *testing.T) {
+	t.Parallel()
+
+	dtd, err := helium.ParseDTD(t.Context(), strings.NewReader(`<!ELEMENT p:list (title, (a|b)*)>
+<!ATTLIST p:list kind (x|y) "x" p:id ID #REQUIRED>
+<!NOTATION gif PUBLIC "-//GIF//EN" "image/gif">`))
+	require.NoError(t, err)
+
+	list, ok := dtd.LookupElement("list", "p")
+	require.True(t, ok)
+	require.Equal(t, "p", list.Prefix())
+	seq := list.ContentModel()
+	require.Equal(t, helium.ElementContentSeq, seq.Type())
+	require.Equal(t, helium.ElementContentOnce, seq.Occurrence())
+	require.Equal(t, helium.ElementContentElement, seq.First().Type())
+	require.Equal(t, "title", seq.First().Name())
+	require.Equal(t, "", seq.First().Prefix())
+	choice := seq.Second()
+	require.Equal(t, helium.ElementContentOr, choice.Type())
+	require.Equal(t, helium.ElementContentMult, choice.Occurrence())
+	require.Nil(t, choice.First().First())
+
+	kind, ok := dtd.LookupAttribute("kind", "", "p:list")
+	require.True(t, ok)
+	require.Equal(t, enum.AttrDefaultNone, kind.Default())
+	require.Equal(t, "x", kind.DefaultValue())
+	values := kind.Enumeration()
+	require.Equal(t, helium.Enumeration{"x", "y"}, values)
+	values[0] = "changed"
+	require.Equal(t, helium.Enumeration{"x", "y"}, kind.Enumeration())
+
+	id, ok := dtd.LookupAttribute("id", "p", "p:list")
+	require.True(t, ok)
+	require.Equal(t, "p", id.Prefix())
+	require.Equal(t, enum.AttrDefaultRequired, id.Default())
+
+	gif, ok := dtd.LookupNotation("gif")
+	require.True(t, ok)
+	require.Equal(t, "-//GIF//EN", gif.PublicID())
+	require.Equal(t, "image/gif", gif.SystemID())
+}
+
 // TestExternalIDPublicSystemLiteral covers XML 1.0 [75] ExternalID: the PUBLIC
 // form requires a following SystemLiteral, whereas NotationDecl [83] PublicID
 // permits PUBLIC with only a PubidLiteral.
@@ -0,0 +1,79 @@
+package helium
+
+import (
+	"context"
+	"io"
+)
+
+// DTDValidator validates documents against a DTD that is not part of them,
+// such as one returned by [ParseDTD] (libxml2: xmlValidateDtd, as used by
+// xmllint --dtdvalid). Unlike [Parser.ValidateDTD] it works on any in-memory
+// document, including one built programmatically or produced by a
+// transformation.
+//
+// It uses clone-on-write semantics: each builder method returns a new
+// DTDValidator sharing the underlying config until mutation.
+type DTDValidator struct {
+	cfg *dtdValidateConfig
+	dtd *DTD
+}
+
+type dtdValidateConfig struct {
+	errorHandler ErrorHandler
+}
+
+// NewDTDValidator creates a DTDValidator for dtd.
+func NewDTDValidator(dtd *DTD) DTDValidator {
+	return DTDValidator{cfg: &dtdValidateConfig{}, dtd: dtd}
+}
+
+func (v DTDValidator) clone() DTDValidator {
+	if v.cfg == nil {
+		return DTDValidator{cfg: &dtdValidateConfig{}, dtd: v.dtd}
+	}
+	cp := *v.cfg
+	return DTDValidator{cfg: &cp, dtd: v.dtd}
+}
+
+// ErrorHandler sets the handler that receives each validation error as a
+// [DTDValidationError]. A nil handler discards them. If the handler implements
+// [io.Closer] it is closed at the end of each Validate call.
+func (v DTDValidator) ErrorHandler(h ErrorHandler) DTDValidator {
+	v = v.clone()
+	v.cfg.errorHandler = h
+	return v
+}
+
+func (v DTDValidator) closeHandler() {
+	if v.cfg != nil && v.cfg.errorHandler != nil {
+		if cl, ok := v.cfg.errorHandler.(io.Closer); ok {
+			_ = cl.Close()
+		}
+	}
+}
+
+// Validate validates doc against the validator's DTD, which takes the place
+// of doc's own internal and external subsets: the DTD is treated as the
+// document's external subset, and any DOCTYPE in doc is ignored.
+//
+// It returns nil when doc is valid and [ErrDTDValidationFailed] when it is
+// not — or [ErrNoDTDFound], which wraps it, when the validator has no DTD.
+// It returns [ErrNilNode] when doc is nil. Individual validation errors are
+// delivered to the [ErrorHandler]. A nil ctx is normalized to
+// context.Background().
+func (v DTDValidator) Validate(ctx context.Context, doc *Document) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var handler ErrorHandler = NilErrorHandler{}
+	if v.cfg != nil && v.cfg.errorHandler != nil {
+		handler = v.cfg.errorHandler
+	}
+	defer v.closeHandler()
+
+	if doc == nil {
+		return ErrNilNode
+	}
+	return validateDocumentWith(ctx, doc, newValidCtx(handler, nil, v.dtd))
+}
@@ -0,0 +1,117 @@
+package helium_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/stretchr/testify/require"
+)
+
+const standaloneDTD = `<?xml version="1.0" encoding="UTF-8"?>
+<!ENTITY % content "(title, item*)">
+<!ELEMENT list %content;>
+<!ELEMENT title (#PCDATA)>
+<!ELEMENT item (#PCDATA)>
+<!ATTLIST item id ID #REQUIRED kind (a|b) "a">
+<![IGNORE[ <!ELEMENT ignored EMPTY> ]]>
+<!NOTATION gif SYSTEM "image/gif">
+<!ENTITY pic SYSTEM "pic.gif" NDATA gif>
+<!-- trailing comment -->
+`
+
+func TestParseDTD(t *testing.T) {
+	t.Parallel()
+
+	t.Run("parses an external subset", func(t *testing.T) {
+		t.Parallel()
+		dtd, err := helium.ParseDTD(t.Context(), strings.NewReader(standaloneDTD))
+		require.NoError(t, err)
+		require.Nil(t, dtd.OwnerDocument())
+
+		list, ok := dtd.LookupElement("list", "")
+		require.True(t, ok)
+		require.Equal(t, enum.ElementElementType, list.DeclType())
+		_, ok = dtd.LookupElement("ignored", "")
+		require.False(t, ok)
+		decl, ok := dtd.LookupAttribute("kind", "", "item")
+		require.True(t, ok)
+		require.Equal(t, enum.AttrEnumeration, decl.AType())
+		_, ok = dtd.LookupNotation("gif")
+		require.True(t, ok)
+		_, ok = dtd.LookupEntity("pic")
+		require.True(t, ok)
+	})
+
+	t.Run("resolves external parameter entities", func(t *testing.T) {
+		t.Parallel()
+		fsys := fstest.MapFS{"dtd/inc.ent": &fstest.MapFile{Data: []byte(`<!ELEMENT inc EMPTY>`)}}
+		dtd, err := helium.NewParser().
+			BlockXXE(false).
+			FS(fsys).
+			BaseURI("dtd/main.dtd").
+			ParseDTD(t.Context(), strings.NewReader(`<!ENTITY % inc SYSTEM "inc.ent">%inc;`))
+		require.NoError(t, err)
+		_, ok := dtd.LookupElement("inc", "")
+		require.True(t, ok)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+		_, err := helium.ParseDTD(t.Context(), strings.NewReader(`<!ELEMENT a (b`))
+		require.Error(t, err)
+
+		_, err = helium.NewParser().MaxExternalDTDBytes(8).ParseDTD(t.Context(), strings.NewReader(standaloneDTD))
+		require.ErrorIs(t, err, helium.ErrExternalDTDTooLarge)
+	})
+}
+
+func TestDTDValidator(t *testing.T) {
+	t.Parallel()
+
+	dtd, err := helium.ParseDTD(t.Context(), strings.NewReader(standaloneDTD))
+	require.NoError(t, err)
+
+	t.Run("valid document", func(t *testing.T) {
+		t.Parallel()
+		// The document's own DOCTYPE is ignored in favor of the validator's DTD.
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(`<!DOCTYPE list [<!ELEMENT list EMPTY>]><list><title>t</title><item id="i1">x</item></list>`))
+		require.NoError(t, err)
+		require.NoError(t, helium.NewDTDValidator(dtd).Validate(t.Context(), doc))
+	})
+
+	t.Run("built document", func(t *testing.T) {
+		t.Parallel()
+		doc := helium.NewDefaultDocument()
+		list, err := doc.CreateElement("list")
+		require.NoError(t, err)
+		require.NoError(t, doc.SetDocumentElement(list))
+		item, err := doc.CreateElement("item")
+		require.NoError(t, err)
+		require.NoError(t, item.SetAttribute("kind", "c"))
+		require.NoError(t, list.AddChild(item))
+
+		h := &collectingErrorHandler{}
+		err = helium.NewDTDValidator(dtd).ErrorHandler(h).Validate(t.Context(), doc)
+		require.ErrorIs(t, err, helium.ErrDTDValidationFailed)
+		require.NotErrorIs(t, err, helium.ErrNoDTDFound)
+		require.True(t, containsError(h.errs, "attribute id is required"), "%v", h.errs)
+		require.True(t, containsError(h.errs, `attribute kind value "c" is not among the enumerated set`), "%v", h.errs)
+		require.True(t, containsError(h.errs, "element list: content does not match"), "%v", h.errs)
+
+		var verr *helium.DTDValidationError
+		require.True(t, errors.As(h.errs[0], &verr))
+		require.Equal(t, helium.ErrorLevelError, verr.Level)
+	})
+
+	t.Run("validator without a DTD", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(`<list/>`))
+		require.NoError(t, err)
+		require.ErrorIs(t, helium.NewDTDValidator(nil).Validate(t.Context(), doc), helium.ErrNoDTDFound)
+		require.ErrorIs(t, helium.NewDTDValidator(dtd).Validate(t.Context(), nil), helium.ErrNilNode)
+	})
+}
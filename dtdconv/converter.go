@@ -0,0 +1,367 @@
+package dtdconv
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+)
+
+type converterConfig struct {
+	targetNamespace    string
+	hasTargetNamespace bool
+	roots              []string
+}
+
+// Converter turns a DTD into an equivalent XSD schema or RELAX NG grammar.
+// It uses clone-on-write semantics: each builder method returns a new
+// Converter sharing the underlying config until mutation.
+type Converter struct {
+	cfg *converterConfig
+}
+
+// NewConverter creates a Converter that takes namespaces from the DTD's own
+// xmlns attribute defaults.
+func NewConverter() Converter {
+	return Converter{cfg: &converterConfig{}}
+}
+
+func (c Converter) clone() Converter {
+	if c.cfg == nil {
+		return NewConverter()
+	}
+	cp := *c.cfg
+	cp.roots = slices.Clone(cp.roots)
+	return Converter{cfg: &cp}
+}
+
+// TargetNamespace sets the namespace of unprefixed element names, replacing
+// any default namespace the DTD declares through a defaulted or #FIXED xmlns
+// attribute. An empty uri puts them in no namespace.
+func (c Converter) TargetNamespace(uri string) Converter {
+	c = c.clone()
+	c.cfg.targetNamespace = uri
+	c.cfg.hasTargetNamespace = true
+	return c
+}
+
+// Roots sets the names of the elements a RELAX NG grammar accepts as the
+// document element. By default these are the DTD's own name, when it has
+// one, or else every declared element that no other element's content model
+// refers to. Roots does not affect XSD output, where every element is
+// declared globally.
+func (c Converter) Roots(names ...string) Converter {
+	c = c.clone()
+	c.cfg.roots = slices.Clone(names)
+	return c
+}
+
+// ToXSD returns an XML Schema document equivalent to dtd. The schema is
+// valid under both XSD 1.0 and XSD 1.1.
+//
+// Every element is declared globally and referenced from content models,
+// and every notation becomes an xs:notation. All element names must be in
+// one namespace, which becomes the target namespace; attributes must be
+// unprefixed or in the xml namespace.
+func (c Converter) ToXSD(ctx context.Context, dtd *helium.DTD) (*helium.Document, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	m, err := c.model(dtd)
+	if err != nil {
+		return nil, err
+	}
+	return (&xsdWriter{ctx: ctx, m: m}).write()
+}
+
+// ToRelaxNG returns a RELAX NG grammar, in the XML syntax, equivalent to
+// dtd. Each element gets a define of its own, data types come from the XSD
+// datatype library, and attribute defaults are recorded as
+// a:defaultValue annotations from the RELAX NG DTD Compatibility
+// specification.
+func (c Converter) ToRelaxNG(ctx context.Context, dtd *helium.DTD) (*helium.Document, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	m, err := c.model(dtd)
+	if err != nil {
+		return nil, err
+	}
+	return (&rngWriter{ctx: ctx, m: m}).write()
+}
+
+// model is the part of a DTD that both schema languages need: declarations
+// in the order the DTD makes them, attribute lists by element, and the
+// namespace of each prefix.
+type model struct {
+	cfg       *converterConfig
+	dtd       *helium.DTD
+	elements  []*helium.ElementDecl
+	declared  map[string]*helium.ElementDecl     // by qualified name
+	attrs     map[string][]*helium.AttributeDecl // by element qualified name
+	notations []*helium.Notation
+	bindings  map[string]string // prefix to namespace URI
+}
+
+func (c Converter) model(dtd *helium.DTD) (*model, error) {
+	if dtd == nil {
+		return nil, helium.ErrNilNode
+	}
+	cfg := c.cfg
+	if cfg == nil {
+		cfg = &converterConfig{}
+	}
+	m := &model{
+		cfg:      cfg,
+		dtd:      dtd,
+		declared: make(map[string]*helium.ElementDecl),
+		attrs:    make(map[string][]*helium.AttributeDecl),
+		bindings: make(map[string]string),
+	}
+	for n := dtd.FirstChild(); n != nil; n = n.NextSibling() {
+		switch n := n.(type) {
+		case *helium.ElementDecl:
+			if n.DeclType() == enum.UndefinedElementType {
+				continue
+			}
+			m.elements = append(m.elements, n)
+			m.declared[qname(n.Prefix(), n.Name())] = n
+		case *helium.AttributeDecl:
+			if err := m.bind(n); err != nil {
+				return nil, err
+			}
+			m.attrs[n.Elem()] = append(m.attrs[n.Elem()], n)
+		case *helium.Notation:
+			m.notations = append(m.notations, n)
+		}
+	}
+	if cfg.hasTargetNamespace {
+		m.bindings[""] = cfg.targetNamespace
+	}
+
+	for _, e := range m.elements {
+		if _, err := m.elementNS(e.Prefix(), e.Name()); err != nil {
+			return nil, err
+		}
+		if err := m.checkRefs(e, e.ContentModel()); err != nil {
+			return nil, err
+		}
+		for _, a := range m.attrs[qname(e.Prefix(), e.Name())] {
+			if isNSDecl(a) {
+				continue
+			}
+			if _, err := m.attributeNS(a.Prefix(), a.Name()); err != nil {
+				return nil, fmt.Errorf("element %s: %w", qname(e.Prefix(), e.Name()), err)
+			}
+		}
+	}
+	return m, nil
+}
+
+// bind records the namespace a defaulted or #FIXED xmlns attribute declares.
+// Both kinds are how DTDs written for namespace-aware processors name their
+// namespaces, since every instance then carries the declaration.
+func (m *model) bind(a *helium.AttributeDecl) error {
+	if !isNSDecl(a) {
+		return nil
+	}
+	if a.Default() != enum.AttrDefaultFixed && a.Default() != enum.AttrDefaultNone {
+		return nil
+	}
+	prefix := ""
+	if a.Prefix() == "xmlns" {
+		prefix = a.Name()
+	}
+	uri := a.DefaultValue()
+	if prev, ok := m.bindings[prefix]; ok && prev != uri {
+		return fmt.Errorf("prefix %q is declared as both %q and %q: %w", prefix, prev, uri, ErrUnsupported)
+	}
+	m.bindings[prefix] = uri
+	return nil
+}
+
+// checkRefs reports the first element in c that the DTD does not declare.
+func (m *model) checkRefs(e *helium.ElementDecl, c *helium.ElementContent) error {
+	if c == nil {
+		return nil
+	}
+	if c.Type() == helium.ElementContentElement {
+		if _, ok := m.declared[qname(c.Prefix(), c.Name())]; !ok {
+			return fmt.Errorf("element %s refers to %s: %w", qname(e.Prefix(), e.Name()), qname(c.Prefix(), c.Name()), ErrUndeclaredElement)
+		}
+		return nil
+	}
+	if err := m.checkRefs(e, c.First()); err != nil {
+		return err
+	}
+	return m.checkRefs(e, c.Second())
+}
+
+// elementNS returns the namespace of an element name.
+func (m *model) elementNS(prefix, local string) (string, error) {
+	if err := checkLocal(prefix, local); err != nil {
+		return "", err
+	}
+	if prefix == "" {
+		return m.bindings[""], nil
+	}
+	return m.prefixNS(prefix, local)
+}
+
+// attributeNS returns the namespace of an attribute name. Unprefixed
+// attributes are in no namespace, whatever the default namespace.
+func (m *model) attributeNS(prefix, local string) (string, error) {
+	if err := checkLocal(prefix, local); err != nil {
+		return "", err
+	}
+	if prefix == "" {
+		return "", nil
+	}
+	return m.prefixNS(prefix, local)
+}
+
+func (m *model) prefixNS(prefix, local string) (string, error) {
+	if prefix == "xml" {
+		return lexicon.NamespaceXML, nil
+	}
+	uri, ok := m.bindings[prefix]
+	if !ok || uri == "" || prefix == "xmlns" {
+		return "", fmt.Errorf("name %s: %w", qname(prefix, local), ErrUnboundPrefix)
+	}
+	return uri, nil
+}
+
+// ordered returns the declared elements in the order the DTD declares them,
+// restricted to those named.
+func (m *model) ordered(names map[string]bool) []*helium.ElementDecl {
+	var out []*helium.ElementDecl
+	for _, e := range m.elements {
+		if names[qname(e.Prefix(), e.Name())] {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// attributes returns the attribute declarations of e other than namespace
+// declarations.
+func (m *model) attributes(e *helium.ElementDecl) []*helium.AttributeDecl {
+	var out []*helium.AttributeDecl
+	for _, a := range m.attrs[qname(e.Prefix(), e.Name())] {
+		if !isNSDecl(a) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// checkLocal rejects names that a namespace-aware schema cannot spell: DTD
+// names may have more than one colon, or an empty part around one.
+func checkLocal(prefix, local string) error {
+	if local == "" || strings.ContainsRune(local, ':') {
+		return fmt.Errorf("name %q is not namespace-well-formed: %w", qname(prefix, local), ErrUnsupported)
+	}
+	return nil
+}
+
+func isNSDecl(a *helium.AttributeDecl) bool {
+	return (a.Prefix() == "" && a.Name() == "xmlns") || a.Prefix() == "xmlns"
+}
+
+func qname(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+// operands returns the operands of a sequence or choice node. The parser
+// stores a list such as (a, b, c) as nested binary nodes; nested nodes of
+// the same kind without an occurrence indicator of their own are unfolded
+// back into one list.
+func operands(c *helium.ElementContent) []*helium.ElementContent {
+	var out []*helium.ElementContent
+	var walk func(n *helium.ElementContent)
+	walk = func(n *helium.ElementContent) {
+		if n.Type() == c.Type() && n.Occurrence() == helium.ElementContentOnce {
+			walk(n.First())
+			walk(n.Second())
+			return
+		}
+		out = append(out, n)
+	}
+	walk(c.First())
+	walk(c.Second())
+	return out
+}
+
+// mixedNames returns the names of the elements a mixed content model
+// allows, in the order they are listed.
+func mixedNames(c *helium.ElementContent) []string {
+	var out []string
+	var walk func(n *helium.ElementContent)
+	walk = func(n *helium.ElementContent) {
+		switch n.Type() {
+		case helium.ElementContentElement:
+			if name := qname(n.Prefix(), n.Name()); !slices.Contains(out, name) {
+				out = append(out, name)
+			}
+		case helium.ElementContentSeq, helium.ElementContentOr:
+			walk(n.First())
+			walk(n.Second())
+		}
+	}
+	if c != nil {
+		walk(c)
+	}
+	return out
+}
+
+// builder appends elements in one namespace to a new document.
+type builder struct {
+	doc *helium.Document
+	ns  *helium.Namespace
+}
+
+// newBuilder creates a document whose document element is local in the
+// namespace uri, declared with prefix.
+func newBuilder(prefix, uri, local string) (*builder, *helium.Element, error) {
+	doc := helium.NewDefaultDocument()
+	ns, err := doc.CreateNamespace(prefix, uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := doc.CreateElementNS(local, ns)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := root.DeclareNamespace(prefix, uri); err != nil {
+		return nil, nil, err
+	}
+	if err := doc.SetDocumentElement(root); err != nil {
+		return nil, nil, err
+	}
+	return &builder{doc: doc, ns: ns}, root, nil
+}
+
+// add appends a new element named local to parent. attrs holds unqualified
+// attribute names and values, in pairs.
+func (b *builder) add(parent *helium.Element, local string, attrs ...string) (*helium.Element, error) {
+	e, err := b.doc.CreateElementNS(local, b.ns)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if err := e.SetAttribute(attrs[i], attrs[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	if err := parent.AddChild(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
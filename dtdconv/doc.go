@@ -0,0 +1,46 @@
+// Package dtdconv converts DTDs into XML Schema and RELAX NG schemas, in the
+// manner of James Clark's Trang.
+//
+// Use [NewConverter] with a [helium.DTD], whether parsed on its own with
+// [helium.ParseDTD] or taken from a document's DOCTYPE:
+//
+//	dtd, err := helium.ParseDTD(ctx, f)
+//	...
+//	schema, err := dtdconv.NewConverter().ToXSD(ctx, dtd)
+//
+// Both [Converter.ToXSD] and [Converter.ToRelaxNG] return the schema as a
+// [helium.Document], ready to be written out, edited further, or compiled
+// with the xsd or relaxng package.
+//
+// # Mapping
+//
+// Each element declaration becomes one schema declaration. Content models
+// keep their structure: sequences, choices and occurrence indicators map to
+// their schema counterparts, mixed content to a repeatable choice in mixed
+// content, and ANY to mixed content allowing every declared element.
+// Attribute types map to the XSD built-in types of the same name (CDATA to
+// string), and enumerated and NOTATION types to enumerations. #REQUIRED,
+// #FIXED and default values are kept; RELAX NG records defaults with the
+// a:defaultValue annotation of the DTD Compatibility specification.
+//
+// DTDs are not namespace-aware, so names are resolved the way the instance
+// documents would resolve them: a prefix is bound by a defaulted or #FIXED
+// xmlns:prefix attribute declared anywhere in the DTD, and unprefixed element
+// names are in the namespace of such an xmlns attribute, or the one given to
+// [Converter.TargetNamespace]. The xmlns attributes themselves are not
+// carried over. Names with an unbound prefix fail with [ErrUnboundPrefix],
+// and content models that refer to undeclared elements with
+// [ErrUndeclaredElement]. An XSD schema document describes one namespace, so
+// [Converter.ToXSD] fails with [ErrUnsupported] when elements are spread over
+// several namespaces; [Converter.ToRelaxNG] has no such restriction.
+//
+// Entity declarations, comments and attribute lists for undeclared elements
+// are not converted: parameter entities have already been expanded into the
+// declarations that use them.
+//
+// # Examples
+//
+// Example code for this package lives in the examples/ directory at the
+// repository root (files prefixed with dtdconv_). Because examples are in a
+// separate test module they do not appear in the generated documentation.
+package dtdconv
@@ -0,0 +1,213 @@
+package dtdconv_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/dtdconv"
+	"github.com/lestrrat-go/helium/relaxng"
+	"github.com/lestrrat-go/helium/xsd"
+	"github.com/stretchr/testify/require"
+)
+
+const catalogDTD = `<!ELEMENT catalog (title, (book|note)*, end?)>
+<!ATTLIST catalog xmlns CDATA #FIXED "urn:catalog" version CDATA "1.0" xml:lang CDATA #IMPLIED>
+<!ELEMENT title (#PCDATA)>
+<!ELEMENT book (#PCDATA|em)*>
+<!ATTLIST book id ID #REQUIRED kind (paper|ebook) "paper" cover NOTATION (gif) #IMPLIED>
+<!ELEMENT em (#PCDATA)>
+<!ATTLIST em see IDREF #IMPLIED>
+<!ELEMENT note ANY>
+<!ELEMENT end EMPTY>
+<!ATTLIST end reason NMTOKEN #FIXED "done">
+<!NOTATION gif PUBLIC "-//GIF//EN" "image/gif">
+`
+
+var catalogValid = []string{
+	`<catalog xmlns="urn:catalog"><title>T</title></catalog>`,
+	`<catalog xmlns="urn:catalog" version="2" xml:lang="en"><title>T</title><book id="b1" kind="ebook">A <em see="b1">B</em></book><note>x<end/><title>y</title></note><end reason="done"/></catalog>`,
+	`<catalog xmlns="urn:catalog"><title>T</title><book id="b1" cover="gif"/></catalog>`,
+}
+
+var catalogInvalid = []string{
+	`<catalog xmlns="urn:catalog"/>`,
+	`<catalog><title>T</title></catalog>`,
+	`<catalog xmlns="urn:catalog"><title>T</title><end/><book id="b1"/></catalog>`,
+	`<catalog xmlns="urn:catalog"><title>T</title><book/></catalog>`,
+	`<catalog xmlns="urn:catalog"><title>T</title><book id="b1" kind="audio"/></catalog>`,
+	`<catalog xmlns="urn:catalog"><title>T</title><end reason="other"/></catalog>`,
+	`<catalog xmlns="urn:catalog"><title><em>T</em></title></catalog>`,
+}
+
+func parseDTD(t *testing.T, src string) *helium.DTD {
+	t.Helper()
+	dtd, err := helium.ParseDTD(t.Context(), strings.NewReader(src))
+	require.NoError(t, err)
+	return dtd
+}
+
+func parse(t *testing.T, src string) *helium.Document {
+	t.Helper()
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+	return doc
+}
+
+// reparse round-trips doc through its serialization, as a user saving the
+// converted schema would.
+func reparse(t *testing.T, doc *helium.Document) *helium.Document {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, helium.NewWriter().WriteTo(&buf, doc))
+	return parse(t, buf.String())
+}
+
+func TestToXSD(t *testing.T) {
+	t.Parallel()
+
+	t.Run("validates like the DTD", func(t *testing.T) {
+		t.Parallel()
+		out, err := dtdconv.NewConverter().ToXSD(t.Context(), parseDTD(t, catalogDTD))
+		require.NoError(t, err)
+
+		for _, version := range []xsd.Version{xsd.Version10, xsd.Version11} {
+			schema, err := xsd.NewCompiler().Version(version).Compile(t.Context(), reparse(t, out))
+			require.NoError(t, err)
+			for _, src := range catalogValid {
+				require.NoError(t, xsd.NewValidator(schema).Validate(t.Context(), parse(t, src)), src)
+			}
+			for _, src := range catalogInvalid {
+				require.Error(t, xsd.NewValidator(schema).Validate(t.Context(), parse(t, src)), src)
+			}
+		}
+	})
+
+	t.Run("declarations", func(t *testing.T) {
+		t.Parallel()
+		out, err := dtdconv.NewConverter().ToXSD(t.Context(), parseDTD(t, catalogDTD))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, helium.NewWriter().WriteTo(&buf, out))
+		s := buf.String()
+		require.Contains(t, s, `targetNamespace="urn:catalog"`)
+		require.Contains(t, s, `<xs:element name="title" type="xs:string"/>`)
+		require.Contains(t, s, `<xs:attribute name="id" type="xs:ID" use="required"/>`)
+		require.Contains(t, s, `<xs:attribute name="see" type="xs:IDREF"/>`)
+		require.Contains(t, s, `<xs:attribute name="version" type="xs:string" default="1.0"/>`)
+		require.Contains(t, s, `<xs:attribute name="reason" type="xs:NMTOKEN" fixed="done"/>`)
+		require.Contains(t, s, `<xs:attribute ref="xml:lang"/>`)
+		require.Contains(t, s, `<xs:notation name="gif" public="-//GIF//EN" system="image/gif"/>`)
+		require.NotContains(t, s, `name="xmlns"`)
+	})
+
+	t.Run("target namespace", func(t *testing.T) {
+		t.Parallel()
+		dtd := parseDTD(t, `<!ELEMENT a (b+)><!ELEMENT b EMPTY>`)
+		out, err := dtdconv.NewConverter().ToXSD(t.Context(), dtd)
+		require.NoError(t, err)
+		require.False(t, out.DocumentElement().HasAttribute("targetNamespace"))
+
+		out, err = dtdconv.NewConverter().TargetNamespace("urn:t").ToXSD(t.Context(), dtd)
+		require.NoError(t, err)
+		schema, err := xsd.NewCompiler().Compile(t.Context(), reparse(t, out))
+		require.NoError(t, err)
+		require.NoError(t, xsd.NewValidator(schema).Validate(t.Context(), parse(t, `<a xmlns="urn:t"><b/><b/></a>`)))
+		require.Error(t, xsd.NewValidator(schema).Validate(t.Context(), parse(t, `<a xmlns="urn:t"/>`)))
+	})
+
+	t.Run("more than one namespace", func(t *testing.T) {
+		t.Parallel()
+		dtd := parseDTD(t, `<!ELEMENT a (x:b)><!ATTLIST a xmlns:x CDATA #FIXED "urn:x"><!ELEMENT x:b EMPTY>`)
+		_, err := dtdconv.NewConverter().ToXSD(t.Context(), dtd)
+		require.ErrorIs(t, err, dtdconv.ErrUnsupported)
+	})
+}
+
+func TestToRelaxNG(t *testing.T) {
+	t.Parallel()
+
+	compile := func(t *testing.T, doc *helium.Document) *relaxng.Grammar {
+		t.Helper()
+		grammar, err := relaxng.NewCompiler().Compile(t.Context(), reparse(t, doc))
+		require.NoError(t, err)
+		return grammar
+	}
+
+	t.Run("validates like the DTD", func(t *testing.T) {
+		t.Parallel()
+		out, err := dtdconv.NewConverter().ToRelaxNG(t.Context(), parseDTD(t, catalogDTD))
+		require.NoError(t, err)
+		grammar := compile(t, out)
+		for _, src := range catalogValid {
+			require.NoError(t, relaxng.NewValidator(grammar).Validate(t.Context(), parse(t, src)), src)
+		}
+		for _, src := range catalogInvalid {
+			require.Error(t, relaxng.NewValidator(grammar).Validate(t.Context(), parse(t, src)), src)
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, helium.NewWriter().WriteTo(&buf, out))
+		require.Contains(t, buf.String(), `<attribute name="kind" a:defaultValue="paper">`)
+	})
+
+	t.Run("several namespaces", func(t *testing.T) {
+		t.Parallel()
+		dtd := parseDTD(t, `<!ELEMENT doc (x:item*)>
+<!ATTLIST doc xmlns CDATA #FIXED "urn:d" xmlns:x CDATA #FIXED "urn:x">
+<!ELEMENT x:item (#PCDATA)>
+<!ATTLIST x:item x:flag (on|off) #REQUIRED>`)
+		grammar := compile(t, must(dtdconv.NewConverter().ToRelaxNG(t.Context(), dtd)))
+		require.NoError(t, relaxng.NewValidator(grammar).Validate(t.Context(), parse(t, `<doc xmlns="urn:d" xmlns:y="urn:x"><y:item y:flag="on">t</y:item></doc>`)))
+		require.Error(t, relaxng.NewValidator(grammar).Validate(t.Context(), parse(t, `<doc xmlns="urn:d"><item flag="on">t</item></doc>`)))
+	})
+
+	t.Run("start", func(t *testing.T) {
+		t.Parallel()
+		dtd := parseDTD(t, `<!ELEMENT a (b)><!ELEMENT b EMPTY><!ELEMENT c EMPTY>`)
+
+		// Elements that nothing refers to are the roots by default.
+		grammar := compile(t, must(dtdconv.NewConverter().ToRelaxNG(t.Context(), dtd)))
+		require.NoError(t, relaxng.NewValidator(grammar).Validate(t.Context(), parse(t, `<a><b/></a>`)))
+		require.NoError(t, relaxng.NewValidator(grammar).Validate(t.Context(), parse(t, `<c/>`)))
+		require.Error(t, relaxng.NewValidator(grammar).Validate(t.Context(), parse(t, `<b/>`)))
+
+		grammar = compile(t, must(dtdconv.NewConverter().Roots("b").ToRelaxNG(t.Context(), dtd)))
+		require.NoError(t, relaxng.NewValidator(grammar).Validate(t.Context(), parse(t, `<b/>`)))
+		require.Error(t, relaxng.NewValidator(grammar).Validate(t.Context(), parse(t, `<c/>`)))
+
+		_, err := dtdconv.NewConverter().Roots("z").ToRelaxNG(t.Context(), dtd)
+		require.ErrorIs(t, err, dtdconv.ErrUndeclaredElement)
+
+		// A document's own DTD names its root.
+		doc := parse(t, `<!DOCTYPE c [<!ELEMENT a (b)><!ELEMENT b EMPTY><!ELEMENT c EMPTY>]><c/>`)
+		grammar = compile(t, must(dtdconv.NewConverter().ToRelaxNG(t.Context(), doc.IntSubset())))
+		require.Error(t, relaxng.NewValidator(grammar).Validate(t.Context(), parse(t, `<a><b/></a>`)))
+	})
+}
+
+func TestConverterErrors(t *testing.T) {
+	t.Parallel()
+
+	c := dtdconv.NewConverter()
+	_, err := c.ToXSD(t.Context(), parseDTD(t, `<!ELEMENT a (b)>`))
+	require.ErrorIs(t, err, dtdconv.ErrUndeclaredElement)
+	_, err = c.ToRelaxNG(t.Context(), parseDTD(t, `<!ELEMENT p:a EMPTY>`))
+	require.ErrorIs(t, err, dtdconv.ErrUnboundPrefix)
+	_, err = c.ToXSD(t.Context(), parseDTD(t, `<!ELEMENT a EMPTY><!ATTLIST a p:b CDATA #IMPLIED>`))
+	require.ErrorIs(t, err, dtdconv.ErrUnboundPrefix)
+	_, err = c.ToXSD(t.Context(), parseDTD(t, `<!ELEMENT a:b:c EMPTY>`))
+	require.ErrorIs(t, err, dtdconv.ErrUnsupported)
+	_, err = c.ToXSD(t.Context(), parseDTD(t, `<!ELEMENT a EMPTY><!ATTLIST a xmlns CDATA #FIXED "urn:a"><!ATTLIST b xmlns CDATA #FIXED "urn:b">`))
+	require.ErrorIs(t, err, dtdconv.ErrUnsupported)
+	_, err = c.ToXSD(t.Context(), nil)
+	require.ErrorIs(t, err, helium.ErrNilNode)
+}
+
+func must(doc *helium.Document, err error) *helium.Document {
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
@@ -0,0 +1,17 @@
+package dtdconv
+
+import "errors"
+
+// ErrUndeclaredElement is returned when a content model refers to an element
+// that the DTD does not declare.
+var ErrUndeclaredElement = errors.New("dtdconv: reference to an undeclared element")
+
+// ErrUnboundPrefix is returned when a declared name has a prefix other than
+// xml that no xmlns attribute default in the DTD binds to a namespace.
+var ErrUnboundPrefix = errors.New("dtdconv: prefix is not bound to a namespace")
+
+// ErrUnsupported is returned when the DTD cannot be expressed in the target
+// schema language: a name that is not namespace-well-formed, a prefix bound
+// to two different namespaces, or, for XSD, elements or attributes spread
+// over more than one namespace.
+var ErrUnsupported = errors.New("dtdconv: DTD cannot be converted")
@@ -0,0 +1,338 @@
+package dtdconv
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+)
+
+// namespaceAnnotations is the namespace of the RELAX NG DTD Compatibility
+// annotations.
+const namespaceAnnotations = "http://relaxng.org/ns/compatibility/annotations/1.0"
+
+// rngDatatype maps the DTD attribute types that have a counterpart in the
+// XSD datatype library.
+var rngDatatype = map[enum.AttributeType]string{
+	enum.AttrID:       "ID",
+	enum.AttrIDRef:    "IDREF",
+	enum.AttrIDRefs:   "IDREFS",
+	enum.AttrEntity:   "ENTITY",
+	enum.AttrEntities: "ENTITIES",
+	enum.AttrNmtoken:  "NMTOKEN",
+	enum.AttrNmtokens: "NMTOKENS",
+}
+
+// rngWriter builds the grammar for one conversion.
+type rngWriter struct {
+	ctx     context.Context
+	m       *model
+	b       *builder
+	root    *helium.Element
+	ann     *helium.Namespace
+	defines map[string]string // element qualified name to define name
+}
+
+func (w *rngWriter) write() (*helium.Document, error) {
+	roots, err := w.roots()
+	if err != nil {
+		return nil, err
+	}
+
+	w.b, w.root, err = newBuilder("", lexicon.NamespaceRelaxNG, "grammar")
+	if err != nil {
+		return nil, err
+	}
+	if err := w.root.SetAttribute("datatypeLibrary", lexicon.NamespaceXSDDatatypes); err != nil {
+		return nil, err
+	}
+	if ns := w.m.bindings[""]; ns != "" {
+		if err := w.root.SetAttribute("ns", ns); err != nil {
+			return nil, err
+		}
+	}
+	for _, prefix := range slices.Sorted(maps.Keys(w.m.bindings)) {
+		if prefix == "" || prefix == "xml" || prefix == "xmlns" || w.m.bindings[prefix] == "" {
+			continue
+		}
+		if err := w.root.DeclareNamespace(prefix, w.m.bindings[prefix]); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.declareAnnotations(); err != nil {
+		return nil, err
+	}
+
+	w.defines = make(map[string]string)
+	used := make(map[string]bool)
+	for _, e := range w.m.elements {
+		name := qname(e.Prefix(), e.Name())
+		def := strings.ReplaceAll(name, ":", ".")
+		for i := 2; used[def]; i++ {
+			def = strings.ReplaceAll(name, ":", ".") + "-" + strconv.Itoa(i)
+		}
+		used[def] = true
+		w.defines[name] = def
+	}
+
+	start, err := w.b.add(w.root, "start")
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) > 1 {
+		if start, err = w.b.add(start, "choice"); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range roots {
+		if _, err := w.b.add(start, "ref", "name", w.defines[qname(e.Prefix(), e.Name())]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, e := range w.m.elements {
+		if err := w.ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := w.element(e); err != nil {
+			return nil, err
+		}
+	}
+	return w.b.doc, nil
+}
+
+// roots returns the elements the start pattern accepts.
+func (w *rngWriter) roots() ([]*helium.ElementDecl, error) {
+	names := make(map[string]bool)
+	if len(w.m.cfg.roots) > 0 {
+		for _, name := range w.m.cfg.roots {
+			if _, ok := w.m.declared[name]; !ok {
+				return nil, fmt.Errorf("root element %s: %w", name, ErrUndeclaredElement)
+			}
+			names[name] = true
+		}
+		return w.m.ordered(names), nil
+	}
+	if _, ok := w.m.declared[w.m.dtd.Name()]; ok {
+		names[w.m.dtd.Name()] = true
+		return w.m.ordered(names), nil
+	}
+
+	referenced := make(map[string]bool)
+	for _, e := range w.m.elements {
+		self := qname(e.Prefix(), e.Name())
+		for _, name := range mixedNames(e.ContentModel()) {
+			if name != self {
+				referenced[name] = true
+			}
+		}
+	}
+	for _, e := range w.m.elements {
+		names[qname(e.Prefix(), e.Name())] = !referenced[qname(e.Prefix(), e.Name())]
+	}
+	if roots := w.m.ordered(names); len(roots) > 0 {
+		return roots, nil
+	}
+	return w.m.elements, nil
+}
+
+// declareAnnotations declares a prefix for the annotation namespace when an
+// attribute has a default value, picking one the DTD does not use.
+func (w *rngWriter) declareAnnotations() error {
+	needed := false
+	for _, e := range w.m.elements {
+		for _, a := range w.m.attributes(e) {
+			if a.Default() == enum.AttrDefaultNone || a.Default() == enum.AttrDefaultFixed {
+				needed = true
+			}
+		}
+	}
+	if !needed {
+		return nil
+	}
+	prefix := "a"
+	for i := 1; w.m.bindings[prefix] != ""; i++ {
+		prefix = "a" + strconv.Itoa(i)
+	}
+	if err := w.root.DeclareNamespace(prefix, namespaceAnnotations); err != nil {
+		return err
+	}
+	ns, err := w.b.doc.CreateNamespace(prefix, namespaceAnnotations)
+	if err != nil {
+		return err
+	}
+	w.ann = ns
+	return nil
+}
+
+func (w *rngWriter) element(e *helium.ElementDecl) error {
+	name := qname(e.Prefix(), e.Name())
+	def, err := w.b.add(w.root, "define", "name", w.defines[name])
+	if err != nil {
+		return err
+	}
+	elem, err := w.b.add(def, "element", "name", name)
+	if err != nil {
+		return err
+	}
+	attrs := w.m.attributes(e)
+	if err := w.attributes(elem, attrs); err != nil {
+		return err
+	}
+
+	content := e.ContentModel()
+	switch e.DeclType() {
+	case enum.EmptyElementType:
+		if len(attrs) == 0 {
+			_, err = w.b.add(elem, "empty")
+		}
+		return err
+	case enum.AnyElementType:
+		names := make([]string, 0, len(w.m.elements))
+		for _, d := range w.m.elements {
+			names = append(names, qname(d.Prefix(), d.Name()))
+		}
+		return w.mixed(elem, names)
+	case enum.MixedElementType:
+		if names := mixedNames(content); len(names) > 0 {
+			return w.mixed(elem, names)
+		}
+		_, err = w.b.add(elem, "text")
+		return err
+	case enum.ElementElementType:
+		if content.Type() == helium.ElementContentSeq && content.Occurrence() == helium.ElementContentOnce {
+			// The element's content is already a group.
+			for _, op := range operands(content) {
+				if err := w.pattern(elem, op); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return w.pattern(elem, content)
+	}
+	return nil
+}
+
+// mixed adds text mixed with the named elements in any order and number.
+func (w *rngWriter) mixed(parent *helium.Element, names []string) error {
+	mixed, err := w.b.add(parent, "mixed")
+	if err != nil {
+		return err
+	}
+	zom, err := w.b.add(mixed, "zeroOrMore")
+	if err != nil {
+		return err
+	}
+	choice, err := w.b.add(zom, "choice")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := w.b.add(choice, "ref", "name", w.defines[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *rngWriter) pattern(parent *helium.Element, c *helium.ElementContent) error {
+	var err error
+	switch c.Occurrence() {
+	case helium.ElementContentOpt:
+		parent, err = w.b.add(parent, "optional")
+	case helium.ElementContentMult:
+		parent, err = w.b.add(parent, "zeroOrMore")
+	case helium.ElementContentPlus:
+		parent, err = w.b.add(parent, "oneOrMore")
+	}
+	if err != nil {
+		return err
+	}
+
+	switch c.Type() {
+	case helium.ElementContentElement:
+		_, err := w.b.add(parent, "ref", "name", w.defines[qname(c.Prefix(), c.Name())])
+		return err
+	case helium.ElementContentSeq, helium.ElementContentOr:
+		kind := "group"
+		if c.Type() == helium.ElementContentOr {
+			kind = "choice"
+		}
+		group, err := w.b.add(parent, kind)
+		if err != nil {
+			return err
+		}
+		for _, op := range operands(c) {
+			if err := w.pattern(group, op); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *rngWriter) attributes(parent *helium.Element, attrs []*helium.AttributeDecl) error {
+	for _, a := range attrs {
+		target := parent
+		if a.Default() != enum.AttrDefaultRequired {
+			var err error
+			if target, err = w.b.add(parent, "optional"); err != nil {
+				return err
+			}
+		}
+		attr, err := w.b.add(target, "attribute", "name", qname(a.Prefix(), a.Name()))
+		if err != nil {
+			return err
+		}
+		if a.Default() == enum.AttrDefaultNone || a.Default() == enum.AttrDefaultFixed {
+			if err := attr.SetAttributeNS("defaultValue", a.DefaultValue(), w.ann); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case a.Default() == enum.AttrDefaultFixed:
+			spec := []string{}
+			if a.AType() == enum.AttrCDATA {
+				spec = []string{"type", "string"}
+			}
+			v, err := w.b.add(attr, "value", spec...)
+			if err != nil {
+				return err
+			}
+			if err := v.AddChild(w.b.doc.CreateText([]byte(a.DefaultValue()))); err != nil {
+				return err
+			}
+		case a.AType() == enum.AttrEnumeration || a.AType() == enum.AttrNotation:
+			choice, err := w.b.add(attr, "choice")
+			if err != nil {
+				return err
+			}
+			for _, s := range a.Enumeration() {
+				v, err := w.b.add(choice, "value")
+				if err != nil {
+					return err
+				}
+				if err := v.AddChild(w.b.doc.CreateText([]byte(s))); err != nil {
+					return err
+				}
+			}
+		case a.AType() == enum.AttrCDATA:
+			if _, err := w.b.add(attr, "text"); err != nil {
+				return err
+			}
+		default:
+			if _, err := w.b.add(attr, "data", "type", rngDatatype[a.AType()]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
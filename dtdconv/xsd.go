@@ -0,0 +1,291 @@
+package dtdconv
+
+import (
+	"context"
+	"fmt"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+)
+
+// xsdBuiltin maps the DTD attribute types that have an XSD counterpart of
+// the same meaning.
+var xsdBuiltin = map[enum.AttributeType]string{
+	enum.AttrCDATA:    "xs:string",
+	enum.AttrID:       "xs:ID",
+	enum.AttrIDRef:    "xs:IDREF",
+	enum.AttrIDRefs:   "xs:IDREFS",
+	enum.AttrEntity:   "xs:ENTITY",
+	enum.AttrEntities: "xs:ENTITIES",
+	enum.AttrNmtoken:  "xs:NMTOKEN",
+	enum.AttrNmtokens: "xs:NMTOKENS",
+}
+
+// xsdWriter builds the schema for one conversion. Unprefixed QNames in the
+// schema resolve to the target namespace, which the xs:schema element
+// declares as its default namespace.
+type xsdWriter struct {
+	ctx  context.Context
+	m    *model
+	b    *builder
+	root *helium.Element
+}
+
+func (w *xsdWriter) write() (*helium.Document, error) {
+	tns, usesXML, err := w.namespaces()
+	if err != nil {
+		return nil, err
+	}
+
+	w.b, w.root, err = newBuilder("xs", lexicon.NamespaceXSD, "schema")
+	if err != nil {
+		return nil, err
+	}
+	if tns != "" {
+		if err := w.root.DeclareNamespace("", tns); err != nil {
+			return nil, err
+		}
+		if err := w.root.SetAttribute("targetNamespace", tns); err != nil {
+			return nil, err
+		}
+		if err := w.root.SetAttribute("elementFormDefault", "qualified"); err != nil {
+			return nil, err
+		}
+	}
+	if usesXML {
+		// The xml: attributes are built in; importing the namespace is
+		// enough to refer to them.
+		if _, err := w.b.add(w.root, "import", "namespace", lexicon.NamespaceXML); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, e := range w.m.elements {
+		if err := w.ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := w.element(e); err != nil {
+			return nil, err
+		}
+	}
+	for _, n := range w.m.notations {
+		attrs := []string{"name", n.Name()}
+		if n.PublicID() != "" {
+			attrs = append(attrs, "public", n.PublicID())
+		}
+		if n.SystemID() != "" {
+			attrs = append(attrs, "system", n.SystemID())
+		}
+		if _, err := w.b.add(w.root, "notation", attrs...); err != nil {
+			return nil, err
+		}
+	}
+	return w.b.doc, nil
+}
+
+// namespaces returns the target namespace, and whether any attribute is in
+// the xml namespace. A schema document describes a single namespace, so
+// every element must share one and other attributes must have none.
+func (w *xsdWriter) namespaces() (string, bool, error) {
+	tns := w.m.bindings[""]
+	usesXML := false
+	for i, e := range w.m.elements {
+		ns, err := w.m.elementNS(e.Prefix(), e.Name())
+		if err != nil {
+			return "", false, err
+		}
+		if i == 0 {
+			tns = ns
+		} else if ns != tns {
+			return "", false, fmt.Errorf("element %s is in namespace %q, not %q: %w", qname(e.Prefix(), e.Name()), ns, tns, ErrUnsupported)
+		}
+		for _, a := range w.m.attributes(e) {
+			ns, err := w.m.attributeNS(a.Prefix(), a.Name())
+			if err != nil {
+				return "", false, err
+			}
+			switch ns {
+			case "":
+			case lexicon.NamespaceXML:
+				usesXML = true
+			default:
+				return "", false, fmt.Errorf("attribute %s of element %s is in namespace %q: %w", qname(a.Prefix(), a.Name()), qname(e.Prefix(), e.Name()), ns, ErrUnsupported)
+			}
+		}
+	}
+	return tns, usesXML, nil
+}
+
+func (w *xsdWriter) element(e *helium.ElementDecl) error {
+	attrs := w.m.attributes(e)
+	content := e.ContentModel()
+
+	// Text-only content needs no complex type unless there are attributes.
+	if e.DeclType() == enum.MixedElementType && len(mixedNames(content)) == 0 {
+		if len(attrs) == 0 {
+			_, err := w.b.add(w.root, "element", "name", e.Name(), "type", "xs:string")
+			return err
+		}
+		decl, err := w.b.add(w.root, "element", "name", e.Name())
+		if err != nil {
+			return err
+		}
+		ct, err := w.b.add(decl, "complexType")
+		if err != nil {
+			return err
+		}
+		sc, err := w.b.add(ct, "simpleContent")
+		if err != nil {
+			return err
+		}
+		ext, err := w.b.add(sc, "extension", "base", "xs:string")
+		if err != nil {
+			return err
+		}
+		return w.attributes(ext, attrs)
+	}
+
+	decl, err := w.b.add(w.root, "element", "name", e.Name())
+	if err != nil {
+		return err
+	}
+	ct, err := w.b.add(decl, "complexType")
+	if err != nil {
+		return err
+	}
+	switch e.DeclType() {
+	case enum.AnyElementType:
+		var names []string
+		for _, d := range w.m.elements {
+			names = append(names, d.Name())
+		}
+		if err := w.mixed(ct, names); err != nil {
+			return err
+		}
+	case enum.MixedElementType:
+		var names []string
+		for _, name := range mixedNames(content) {
+			names = append(names, w.m.declared[name].Name())
+		}
+		if err := w.mixed(ct, names); err != nil {
+			return err
+		}
+	case enum.ElementElementType:
+		parent := ct
+		if content.Type() == helium.ElementContentElement {
+			// A model naming a single element still needs a group.
+			if parent, err = w.b.add(ct, "sequence"); err != nil {
+				return err
+			}
+		}
+		if err := w.particle(parent, content); err != nil {
+			return err
+		}
+	}
+	return w.attributes(ct, attrs)
+}
+
+// mixed makes ct a mixed type that allows the named elements in any order
+// and number.
+func (w *xsdWriter) mixed(ct *helium.Element, names []string) error {
+	if err := ct.SetAttribute("mixed", "true"); err != nil {
+		return err
+	}
+	choice, err := w.b.add(ct, "choice", "minOccurs", "0", "maxOccurs", "unbounded")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := w.b.add(choice, "element", "ref", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *xsdWriter) particle(parent *helium.Element, c *helium.ElementContent) error {
+	var occurs []string
+	switch c.Occurrence() {
+	case helium.ElementContentOpt:
+		occurs = []string{"minOccurs", "0"}
+	case helium.ElementContentMult:
+		occurs = []string{"minOccurs", "0", "maxOccurs", "unbounded"}
+	case helium.ElementContentPlus:
+		occurs = []string{"maxOccurs", "unbounded"}
+	}
+
+	switch c.Type() {
+	case helium.ElementContentElement:
+		_, err := w.b.add(parent, "element", append([]string{"ref", c.Name()}, occurs...)...)
+		return err
+	case helium.ElementContentSeq, helium.ElementContentOr:
+		kind := "sequence"
+		if c.Type() == helium.ElementContentOr {
+			kind = "choice"
+		}
+		group, err := w.b.add(parent, kind, occurs...)
+		if err != nil {
+			return err
+		}
+		for _, op := range operands(c) {
+			if err := w.particle(group, op); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *xsdWriter) attributes(parent *helium.Element, attrs []*helium.AttributeDecl) error {
+	for _, a := range attrs {
+		var spec []string
+		if a.Prefix() == "xml" {
+			spec = []string{"ref", qname(a.Prefix(), a.Name())}
+		} else {
+			spec = []string{"name", a.Name()}
+			if t, ok := xsdBuiltin[a.AType()]; ok {
+				spec = append(spec, "type", t)
+			}
+		}
+		switch a.Default() {
+		case enum.AttrDefaultRequired:
+			spec = append(spec, "use", "required")
+		case enum.AttrDefaultFixed:
+			spec = append(spec, "fixed", a.DefaultValue())
+		case enum.AttrDefaultNone:
+			spec = append(spec, "default", a.DefaultValue())
+		}
+		decl, err := w.b.add(parent, "attribute", spec...)
+		if err != nil {
+			return err
+		}
+
+		if a.Prefix() == "xml" {
+			continue
+		}
+		var base string
+		switch a.AType() {
+		case enum.AttrEnumeration:
+			base = "xs:token"
+		case enum.AttrNotation:
+			base = "xs:NOTATION"
+		default:
+			continue
+		}
+		st, err := w.b.add(decl, "simpleType")
+		if err != nil {
+			return err
+		}
+		r, err := w.b.add(st, "restriction", "base", base)
+		if err != nil {
+			return err
+		}
+		for _, v := range a.Enumeration() {
+			if _, err := w.b.add(r, "enumeration", "value", v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -62,6 +62,9 @@ func (n *Element) SetTreeDoc(doc *Document) {
 // colon; use SetAttributeNS for namespaced attributes. To parse entity
 // references in value into the attribute's child list, use SetParsedAttribute.
 func (n *Element) SetAttribute(name, value string) error {
+	if err := beginMutation(n); err != nil {
+		return err
+	}
 	if strings.ContainsRune(name, ':') {
 		return fmt.Errorf("attribute name %q contains a colon: use SetAttributeNS with a local name and Namespace parameter", name)
 	}
@@ -86,6 +89,9 @@ func (n *Element) SetAttribute(name, value string) error {
 // QName is replaced in place. The name must not contain a colon; use
 // SetParsedAttributeNS for namespaced attributes.
 func (n *Element) SetParsedAttribute(name, value string) error {
+	if err := beginMutation(n); err != nil {
+		return err
+	}
 	attr, err := n.doc.CreateAttribute(name, value, nil)
 	if err != nil {
 		return err
@@ -99,6 +105,9 @@ func (n *Element) SetParsedAttribute(name, value string) error {
 // The attribute has no children, distinguishing it from an attribute with
 // an empty string value.
 func (n *Element) SetBooleanAttribute(name string) error {
+	if err := beginMutation(n); err != nil {
+		return err
+	}
 	if strings.ContainsRune(name, ':') {
 		return fmt.Errorf("attribute name %q contains a colon", name)
 	}
@@ -134,6 +143,7 @@ func (n *Element) addProperty(attr *Attribute) {
 	if p == nil {
 		n.properties = attr
 		attr.parent = n
+		n.notifyAttribute(MutationAttributeSet, attr, nil)
 		return
 	}
 
@@ -163,6 +173,7 @@ func (n *Element) addProperty(attr *Attribute) {
 			pdn.parent = nil
 			pdn.prev = nil
 			pdn.next = nil
+			n.notifyAttribute(MutationAttributeSet, attr, p)
 			return
 		}
 
@@ -172,6 +183,22 @@ func (n *Element) addProperty(attr *Attribute) {
 	last.next = attr
 	attr.prev = last
 	attr.parent = n
+	n.notifyAttribute(MutationAttributeSet, attr, nil)
+}
+
+// notifyAttribute reports a change to the attribute attr of n to the
+// document's observers. old is the attribute whose value is reported as
+// OldValue: the one replaced, or attr itself when it was removed.
+func (n *Element) notifyAttribute(kind MutationKind, attr, old *Attribute) {
+	d := n.doc
+	if !d.observed() {
+		return
+	}
+	m := Mutation{Kind: kind, Target: n, Node: attr}
+	if old != nil {
+		m.OldValue = old.Value()
+	}
+	d.notify(m)
 }
 
 // SetAttributeNS creates or replaces the attribute with the given local name
@@ -183,6 +210,9 @@ func (n *Element) addProperty(attr *Attribute) {
 // references in value into the attribute's child list, use
 // SetParsedAttributeNS.
 func (n *Element) SetAttributeNS(localname, value string, ns *Namespace) error {
+	if err := beginMutation(n); err != nil {
+		return err
+	}
 	if strings.ContainsRune(localname, ':') {
 		return fmt.Errorf("attribute local name %q contains a colon", localname)
 	}
@@ -206,6 +236,9 @@ func (n *Element) SetAttributeNS(localname, value string, ns *Namespace) error {
 // (namespace URI + local name) or serialized QName is replaced in place. The
 // local name must not contain a colon.
 func (n *Element) SetParsedAttributeNS(localname, value string, ns *Namespace) error {
+	if err := beginMutation(n); err != nil {
+		return err
+	}
 	attr, err := n.doc.CreateAttribute(localname, value, ns)
 	if err != nil {
 		return err
@@ -307,6 +340,7 @@ func (n *Element) RemoveAttribute(name string) bool {
 	if !ok {
 		return false
 	}
+	mustBeginMutation(n)
 	n.spliceOutAttribute(attr)
 	return true
 }
@@ -318,6 +352,7 @@ func (n *Element) RemoveAttributeNS(localName, nsURI string) bool {
 	if !ok {
 		return false
 	}
+	mustBeginMutation(n)
 	n.spliceOutAttribute(attr)
 	return true
 }
@@ -354,6 +389,7 @@ func (n *Element) spliceOutAttribute(p *Attribute) {
 	pdn.parent = nil
 	pdn.prev = nil
 	pdn.next = nil
+	n.notifyAttribute(MutationAttributeRemoved, p, p)
 }
 
 // Attributes returns a newly allocated slice of the element's attributes in
@@ -0,0 +1,347 @@
+package helium
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/sax"
+)
+
+// EmitSAX walks node and fires the SAX2 events a parser with entity
+// substitution would fire for it, so an in-memory tree — a parsed document,
+// a subtree of one, or the result of an XSLT transformation — can be fed to
+// any SAX consumer, such as a sax.Filter chain or a sax.WriterHandler,
+// without serializing it and parsing it again.
+//
+// The events are framed by StartDocument and EndDocument whatever node is,
+// as if node were the whole content of a document. A document's DTD is
+// reported through InternalSubset, its declarations, and ExternalSubset.
+// Each element reports its namespace declarations; the first element
+// emitted also reports the ones it inherits from ancestors outside node,
+// and a declaration is added wherever a namespace the tree uses is not
+// declared, so the event stream is namespace-well-formed on its own.
+// Attributes the DTD defaults and the element does not specify are
+// reported with IsDefault set, as the parser reports them. An entity
+// reference node is reported through Reference.
+//
+// A handler error other than sax.ErrHandlerUnspecified stops the walk and
+// is returned, as is ctx's error once it is done. Node kinds that cannot
+// appear as document content, such as attributes, return an error wrapping
+// [ErrInvalidArgument].
+func EmitSAX(ctx context.Context, node Node, handler sax.SAX2Handler) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if isNilNode(node) {
+		return ErrNilNode
+	}
+	switch node.Type() {
+	case DocumentNode, HTMLDocumentNode, DocumentFragNode, ElementNode, TextNode,
+		CDATASectionNode, EntityRefNode, CommentNode, ProcessingInstructionNode, DTDNode:
+	default:
+		return fmt.Errorf("helium: cannot emit SAX events for %s: %w", node.Type(), ErrInvalidArgument)
+	}
+
+	e := &saxEmitter{h: handler, loc: &emitLocator{node: node}}
+	if doc := node.OwnerDocument(); doc != nil {
+		e.doc = doc
+		e.loc.systemID = doc.URL()
+	}
+	if doc, ok := node.(*Document); ok {
+		e.doc = doc
+		e.loc.systemID = doc.URL()
+	}
+	ctx = sax.WithDocumentLocator(ctx, e.loc)
+
+	if err := saxResult(handler.SetDocumentLocator(ctx, e.loc)); err != nil {
+		return err
+	}
+	if err := saxResult(handler.StartDocument(ctx)); err != nil {
+		return err
+	}
+	if err := e.emit(ctx, node); err != nil {
+		return err
+	}
+	return saxResult(handler.EndDocument(ctx))
+}
+
+// saxResult maps the result of a SAX callback the way the parser does: an
+// unhandled event is not an error.
+func saxResult(err error) error {
+	switch err {
+	case nil, sax.ErrHandlerUnspecified:
+		return nil
+	default:
+		return err
+	}
+}
+
+// emitLocator reports the line recorded on the node being emitted.
+type emitLocator struct {
+	node     Node
+	systemID string
+}
+
+func (l *emitLocator) LineNumber() int     { return l.node.Line() }
+func (l *emitLocator) ColumnNumber() int   { return 0 }
+func (l *emitLocator) GetPublicID() string { return "" }
+func (l *emitLocator) GetSystemID() string { return l.systemID }
+
+type saxEmitter struct {
+	h        sax.SAX2Handler
+	doc      *Document
+	loc      *emitLocator
+	started  bool                        // an element has been emitted
+	scope    []*Namespace                // bindings reported so far, innermost last
+	defaults map[string][]*AttributeDecl // DTD attribute defaults by element name
+}
+
+func (e *saxEmitter) emit(ctx context.Context, n Node) error {
+	e.loc.node = n
+	switch n.Type() {
+	case DocumentNode, HTMLDocumentNode, DocumentFragNode:
+		for child := range Children(n) {
+			if err := e.emit(ctx, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case DTDNode:
+		dtd, ok := AsNode[*DTD](n)
+		if !ok {
+			return nil
+		}
+		return e.emitDTD(ctx, dtd)
+	case ElementNode:
+		elem, ok := AsNode[*Element](n)
+		if !ok {
+			return nil
+		}
+		return e.emitElement(ctx, elem)
+	case TextNode:
+		return saxResult(e.h.Characters(ctx, rawContent(n)))
+	case CDATASectionNode:
+		return saxResult(e.h.CDataBlock(ctx, rawContent(n)))
+	case CommentNode:
+		return saxResult(e.h.Comment(ctx, rawContent(n)))
+	case ProcessingInstructionNode:
+		pi, ok := AsNode[*ProcessingInstruction](n)
+		if !ok {
+			return nil
+		}
+		return saxResult(e.h.ProcessingInstruction(ctx, pi.target, pi.data))
+	case EntityRefNode:
+		name := n.Name()
+		if body, ok := strings.CutPrefix(name, "#"); ok {
+			r, ok := parseCharRefBody(body)
+			if !ok {
+				return fmt.Errorf("helium: invalid character reference %q: %w", name, ErrInvalidArgument)
+			}
+			return saxResult(e.h.Characters(ctx, []byte(string(r))))
+		}
+		return saxResult(e.h.Reference(ctx, name))
+	}
+	// XInclude markers and any other node kinds produce no events.
+	return nil
+}
+
+func (e *saxEmitter) emitDTD(ctx context.Context, dtd *DTD) error {
+	name := dtd.Name()
+	if err := saxResult(e.h.InternalSubset(ctx, name, dtd.externalID, dtd.systemID)); err != nil {
+		return err
+	}
+	for child := range Children(dtd) {
+		e.loc.node = child
+		var err error
+		switch decl := child.(type) {
+		case *ElementDecl:
+			err = e.h.ElementDecl(ctx, dtdName(decl.prefix, decl.Name()), decl.decltype, decl.content)
+		case *AttributeDecl:
+			err = e.h.AttributeDecl(ctx, decl.elem, dtdName(decl.prefix, decl.Name()), decl.atype, decl.def, decl.defvalue, decl.tree)
+		case *Entity:
+			if decl.entityType == enum.ExternalGeneralUnparsedEntity {
+				err = e.h.UnparsedEntityDecl(ctx, decl.Name(), decl.externalID, decl.systemID, decl.content)
+			} else {
+				err = e.h.EntityDecl(ctx, decl.Name(), decl.entityType, decl.externalID, decl.systemID, decl.content)
+			}
+		case *Notation:
+			err = e.h.NotationDecl(ctx, decl.Name(), decl.publicID, decl.systemID)
+		case *Comment:
+			err = e.h.Comment(ctx, rawContent(decl))
+		case *ProcessingInstruction:
+			err = e.h.ProcessingInstruction(ctx, decl.target, decl.data)
+		}
+		if err := saxResult(err); err != nil {
+			return err
+		}
+	}
+	e.loc.node = dtd
+	return saxResult(e.h.ExternalSubset(ctx, name, dtd.externalID, dtd.systemID))
+}
+
+func (e *saxEmitter) emitElement(ctx context.Context, elem *Element) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var decls []*Namespace
+	if !e.started {
+		// The first element carries everything in scope, so bindings made
+		// on ancestors that are not emitted still reach the handler.
+		decls = collectInScopeNamespaces(elem)
+		e.started = true
+	} else {
+		decls = elem.Namespaces()
+	}
+	decls = dropXMLNamespace(decls)
+	mark := len(e.scope)
+	e.scope = append(e.scope, decls...)
+	defer func() { e.scope = e.scope[:mark] }()
+
+	prefix, uri := elem.Prefix(), elem.URI()
+	if uri != "" || prefix == "" {
+		// An element in no namespace must not inherit a default one.
+		decls = e.ensureBinding(decls, prefix, uri)
+	}
+
+	var attrs []sax.Attribute
+	for _, attr := range elem.Attributes() {
+		if p := attr.Prefix(); p != "" && p != lexicon.PrefixXML {
+			decls = e.ensureBinding(decls, p, attr.URI())
+		}
+		attrs = append(attrs, attr)
+	}
+	for _, decl := range e.attributeDefaults(elem.Name()) {
+		p, local := decl.prefix, decl.Name()
+		if p == lexicon.PrefixXMLNS || (p == "" && local == lexicon.PrefixXMLNS) {
+			continue
+		}
+		if p != "" && p != lexicon.PrefixXML && e.lookupNS(p) == nil {
+			continue
+		}
+		if hasAttribute(elem, p, local) {
+			continue
+		}
+		attrs = append(attrs, attrData{localname: local, prefix: p, value: decl.defvalue, isDefault: true})
+	}
+
+	var nslist []sax.Namespace
+	if len(decls) > 0 {
+		nslist = make([]sax.Namespace, len(decls))
+		for i, ns := range decls {
+			nslist[i] = ns
+		}
+	}
+	e.loc.node = elem
+	if err := saxResult(e.h.StartElementNS(ctx, elem.LocalName(), prefix, uri, nslist, attrs)); err != nil {
+		return err
+	}
+	for child := range Children(elem) {
+		if err := e.emit(ctx, child); err != nil {
+			return err
+		}
+	}
+	e.loc.node = elem
+	return saxResult(e.h.EndElementNS(ctx, elem.LocalName(), prefix, uri))
+}
+
+// ensureBinding adds a declaration of prefix to decls, and to the scope,
+// unless prefix is already bound to uri there. A declaration of prefix to
+// another URI on the same element is replaced: the binding the tree uses is
+// authoritative, as it is when the writer serializes the element.
+func (e *saxEmitter) ensureBinding(decls []*Namespace, prefix, uri string) []*Namespace {
+	if prefix == lexicon.PrefixXML {
+		return decls
+	}
+	cur := e.lookupNS(prefix)
+	if cur == nil && uri == "" {
+		// Nothing to undeclare.
+		return decls
+	}
+	if cur != nil && cur.URI() == uri {
+		return decls
+	}
+	ns := newNamespace(prefix, uri)
+	e.scope = append(e.scope, ns)
+	for i, d := range decls {
+		if d.Prefix() == prefix {
+			decls[i] = ns
+			return decls
+		}
+	}
+	return append(decls, ns)
+}
+
+func (e *saxEmitter) lookupNS(prefix string) *Namespace {
+	for i := len(e.scope) - 1; i >= 0; i-- {
+		if e.scope[i].Prefix() == prefix {
+			return e.scope[i]
+		}
+	}
+	return nil
+}
+
+// attributeDefaults returns the declarations that default an attribute of
+// the named element, internal subset first, in declaration order.
+func (e *saxEmitter) attributeDefaults(elem string) []*AttributeDecl {
+	if e.doc == nil {
+		return nil
+	}
+	if e.defaults == nil {
+		e.defaults = map[string][]*AttributeDecl{}
+		for _, dtd := range []*DTD{e.doc.IntSubset(), e.doc.ExtSubset()} {
+			if dtd == nil {
+				continue
+			}
+			for child := range Children(dtd) {
+				decl, ok := child.(*AttributeDecl)
+				if !ok || (decl.def != enum.AttrDefaultNone && decl.def != enum.AttrDefaultFixed) {
+					continue
+				}
+				if !containsAttributeDecl(e.defaults[decl.elem], decl) {
+					e.defaults[decl.elem] = append(e.defaults[decl.elem], decl)
+				}
+			}
+		}
+	}
+	return e.defaults[elem]
+}
+
+// containsAttributeDecl reports whether decls already declares the attribute
+// decl declares; the first declaration is binding.
+func containsAttributeDecl(decls []*AttributeDecl, decl *AttributeDecl) bool {
+	for _, d := range decls {
+		if d.prefix == decl.prefix && d.Name() == decl.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAttribute(elem *Element, prefix, local string) bool {
+	for _, attr := range elem.Attributes() {
+		if attr.Prefix() == prefix && attr.LocalName() == local {
+			return true
+		}
+	}
+	return false
+}
+
+// dropXMLNamespace removes declarations of the implicitly bound xml prefix.
+func dropXMLNamespace(decls []*Namespace) []*Namespace {
+	for i, ns := range decls {
+		if ns.Prefix() == lexicon.PrefixXML {
+			out := append([]*Namespace(nil), decls[:i]...)
+			for _, ns := range decls[i+1:] {
+				if ns.Prefix() != lexicon.PrefixXML {
+					out = append(out, ns)
+				}
+			}
+			return out
+		}
+	}
+	return decls
+}
@@ -0,0 +1,150 @@
+package helium_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/sax"
+	"github.com/lestrrat-go/helium/stream"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmitSAX(t *testing.T) {
+	t.Parallel()
+
+	t.Run("matches the parser's events", func(t *testing.T) {
+		t.Parallel()
+		src := []byte(`<?xml version="1.0"?>
+<!DOCTYPE doc [
+<!ELEMENT doc ANY>
+<!ATTLIST doc version CDATA "1.0" kind (a|b) #IMPLIED>
+<!ATTLIST p:item flag CDATA #FIXED "on">
+<!ENTITY ent "text">
+<!NOTATION gif SYSTEM "image/gif">
+<!ENTITY pic SYSTEM "pic.gif" NDATA gif>
+<!-- in the subset -->
+]>
+<!-- before -->
+<doc xmlns="urn:d" xmlns:p="urn:p" kind="a"><p:item p:a="1" xml:lang="en">hello</p:item><![CDATA[<raw>]]><?pi data?><plain xmlns="">x</plain></doc>
+<?after?>`)
+
+		var parsed bytes.Buffer
+		_, err := helium.NewParser().SAXHandler(newEventEmitter(&parsed)).Parse(t.Context(), src)
+		require.NoError(t, err)
+
+		doc, err := helium.NewParser().Parse(t.Context(), src)
+		require.NoError(t, err)
+		var emitted bytes.Buffer
+		require.NoError(t, helium.EmitSAX(t.Context(), doc, newEventEmitter(&emitted)))
+
+		// The parser also queries the handler while parsing; EmitSAX has
+		// nothing to resolve.
+		var want []string
+		for line := range strings.Lines(parsed.String()) {
+			if !strings.HasPrefix(line, "SAX.ResolveEntity(") && !strings.HasPrefix(line, "SAX.GetEntity(") {
+				want = append(want, line)
+			}
+		}
+		require.Equal(t, strings.Join(want, ""), emitted.String())
+		require.Contains(t, emitted.String(), "version='1.0...'")
+		require.Contains(t, emitted.String(), "flag='on...'")
+	})
+
+	t.Run("subtree inherits namespaces", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(`<r xmlns="urn:d" xmlns:p="urn:p" xmlns:unused="urn:u"><a><p:b p:x="1"/></a></r>`))
+		require.NoError(t, err)
+		a := doc.DocumentElement().FirstChild()
+
+		out := emitToWriter(t, a)
+		require.Equal(t, "<?xml version=\"1.0\"?>\n<a xmlns=\"urn:d\" xmlns:p=\"urn:p\" xmlns:unused=\"urn:u\"><p:b p:x=\"1\"/></a>\n", out)
+	})
+
+	t.Run("declares namespaces the tree uses without declaring", func(t *testing.T) {
+		t.Parallel()
+		doc := helium.NewDefaultDocument()
+		root, err := doc.CreateElement("root")
+		require.NoError(t, err)
+		require.NoError(t, root.SetActiveNamespace("", "urn:d"))
+		require.NoError(t, doc.SetDocumentElement(root))
+		child, err := doc.CreateElement("child")
+		require.NoError(t, err)
+		require.NoError(t, child.SetAttributeNS("a", "1", helium.NewNamespace("q", "urn:q")))
+		require.NoError(t, root.AddChild(child))
+
+		out := emitToWriter(t, doc)
+		require.Equal(t, "<?xml version=\"1.0\"?>\n<root xmlns=\"urn:d\"><child q:a=\"1\" xmlns=\"\" xmlns:q=\"urn:q\"/></root>\n", out)
+	})
+
+	t.Run("entity references", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(`<!DOCTYPE r [<!ENTITY e "ent">]><r>&e;&#65;</r>`))
+		require.NoError(t, err)
+		var refs []string
+		h := sax.New()
+		h.SetOnReference(sax.ReferenceFunc(func(_ context.Context, name string) error {
+			refs = append(refs, name)
+			return nil
+		}))
+		require.NoError(t, helium.EmitSAX(t.Context(), doc, h))
+		require.Equal(t, []string{"e"}, refs)
+	})
+
+	t.Run("handler errors stop the walk", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(`<r><a/><b/></r>`))
+		require.NoError(t, err)
+		boom := errors.New("boom")
+		var seen []string
+		h := sax.New()
+		h.SetOnStartElementNS(sax.StartElementNSFunc(func(_ context.Context, localname, _, _ string, _ []sax.Namespace, _ []sax.Attribute) error {
+			seen = append(seen, localname)
+			if localname == "a" {
+				return boom
+			}
+			return nil
+		}))
+		require.ErrorIs(t, helium.EmitSAX(t.Context(), doc, h), boom)
+		require.Equal(t, []string{"r", "a"}, seen)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		require.ErrorIs(t, helium.EmitSAX(ctx, doc, sax.New()), context.Canceled)
+	})
+
+	t.Run("rejects nodes that are not content", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(`<r a="1"/>`))
+		require.NoError(t, err)
+		attr := doc.DocumentElement().Attributes()[0]
+		require.ErrorIs(t, helium.EmitSAX(t.Context(), attr, sax.New()), helium.ErrInvalidArgument)
+		require.ErrorIs(t, helium.EmitSAX(t.Context(), nil, sax.New()), helium.ErrNilNode)
+	})
+
+	t.Run("locator reports lines", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte("<r>\n<a/>\n\n<b/></r>"))
+		require.NoError(t, err)
+		var lines []string
+		h := sax.New()
+		h.SetOnStartElementNS(sax.StartElementNSFunc(func(ctx context.Context, localname, _, _ string, _ []sax.Namespace, _ []sax.Attribute) error {
+			loc := sax.GetDocumentLocator(ctx)
+			lines = append(lines, localname+":"+strings.Repeat("|", loc.LineNumber()))
+			return nil
+		}))
+		require.NoError(t, helium.EmitSAX(t.Context(), doc, h))
+		require.Equal(t, []string{"r:|", "a:||", "b:||||"}, lines)
+	})
+}
+
+func emitToWriter(t *testing.T, n helium.Node) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := stream.NewWriter(&buf)
+	require.NoError(t, helium.EmitSAX(t.Context(), n, sax.NewWriterHandler(&w)))
+	return buf.String()
+}
@@ -162,6 +162,7 @@ func (e *Entity) MarkChecked() {
 }
 
 func (e *Entity) SetOrig(s string) {
+	e.doc.mustBeginMutation()
 	e.orig = s
 }
 
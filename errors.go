@@ -116,6 +116,33 @@ var (
 	// given name (or the document has no internal subset at all). Match with
 	// errors.Is.
 	ErrElementDeclNotFound = errors.New("element declaration not found")
+	// ErrNoSourcePositions is returned by Parser.Reparse for a document that
+	// was not parsed with Parser.TrackPositions and Parser.RetainSource, and
+	// so carries no source to apply the edit to. Match with errors.Is.
+	ErrNoSourcePositions = errors.New("document was not parsed with retained source positions")
+	// ErrReadOnly is returned by the guarded tree-mutation operations
+	// (AddChild, AddSibling, Replace, AppendText and the attribute and
+	// namespace setters) when the tree belongs to a document produced by
+	// Freeze; the mutations with no error result panic with it. Match with
+	// errors.Is.
+	ErrReadOnly = errors.New("document is read-only")
+	// ErrBinaryFormat is returned by LoadBinary for input that is not a
+	// binary document written by MarshalBinary, or that is truncated or
+	// damaged. Match with errors.Is.
+	ErrBinaryFormat = errors.New("not a valid binary document")
+	// ErrBinaryVersion is returned by LoadBinary for a binary document in a
+	// format version this version of helium does not read; the document has
+	// to be parsed again from its text. Match with errors.Is.
+	ErrBinaryVersion = errors.New("unsupported binary document version")
+	// ErrResourceTooLarge is returned by a resolver from
+	// LimitResourceResolver, when it opens or reads a resource larger than
+	// its limit. Match with errors.Is.
+	ErrResourceTooLarge = errors.New("resource exceeds maximum allowed size")
+	// ErrPushBufferTooLarge is returned by PushParser.Push when the input the
+	// parser holds, waiting for the end of a construct, exceeds the cap set
+	// via Parser.MaxPushBufferBytes (DefaultMaxPushBufferSize when none is
+	// configured). Match with errors.Is.
+	ErrPushBufferTooLarge = errors.New("push buffer exceeds maximum allowed size")
 	// ErrUnsupportedOutputEncoding is returned by the writer for an effective
 	// encoding it cannot faithfully emit. A malformed EncName label — whether
 	// from an explicit OutputEncoding override OR a document's own encoding set
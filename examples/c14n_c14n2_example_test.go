@@ -0,0 +1,40 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/c14n"
+)
+
+func Example_c14n_c14n2() {
+	const src = `<order xmlns="urn:orders" xmlns:sku="urn:sku" xmlns:unused="urn:unused">
+  <item type="sku:Widget">  sku:A-1  </item>
+</order>`
+
+	doc, err := helium.NewParser().Parse(context.Background(), []byte(src))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+
+	// Canonical XML 2.0 declares only the namespaces that are used and,
+	// with sequential rewriting, renames their prefixes to n0, n1, ...
+	// QName-aware parameters tell it which attribute values and element
+	// contents hold QNames, so that their prefixes are counted as used and
+	// rewritten too.
+	out, err := c14n.NewCanonicalizer(c14n.C14N20).
+		TrimTextNodes().
+		PrefixRewrite(c14n.PrefixRewriteSequential).
+		QNameAwareElement("urn:orders", "item").
+		QNameAwareUnqualifiedAttribute("urn:orders", "item", "type").
+		CanonicalizeTo(doc)
+	if err != nil {
+		fmt.Printf("failed to canonicalize: %s\n", err)
+		return
+	}
+	fmt.Println(string(out))
+	// Output:
+	// <n0:order xmlns:n0="urn:orders"><n0:item xmlns:n1="urn:sku" type="n1:Widget">n1:A-1</n0:item></n0:order>
+}
@@ -0,0 +1,52 @@
+package examples_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/c14n"
+)
+
+func Example_c14n_handler() {
+	const src = `<Envelope xmlns="urn:envelope" xmlns:p="urn:payload">
+  <p:Payload Id="body" p:version="2">
+    <p:Item sku="A-1">widget</p:Item>
+  </p:Payload>
+</Envelope>`
+
+	// Handler returns a SAX handler that writes canonical XML as the parser
+	// reports the document, so no tree is built and memory does not grow
+	// with the size of the input. SubtreeByID selects the element a
+	// same-document reference "#body" points to, and writing into a hash
+	// digests it without keeping the canonical bytes.
+	digest := sha256.New()
+	h := c14n.NewCanonicalizer(c14n.ExclusiveC14N10).SubtreeByID("body").Handler(digest)
+	if _, err := helium.NewParser().SAXHandler(h).ParseReader(context.Background(), strings.NewReader(src)); err != nil {
+		fmt.Printf("failed to canonicalize: %s\n", err)
+		return
+	}
+
+	// The digest is the one the tree canonicalizer gives for the same subtree.
+	doc, err := helium.NewParser().Parse(context.Background(), []byte(src))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+	out, err := c14n.NewCanonicalizer(c14n.ExclusiveC14N10).SubtreeByID("body").CanonicalizeTo(doc)
+	if err != nil {
+		fmt.Printf("failed to canonicalize: %s\n", err)
+		return
+	}
+	fmt.Println(string(out))
+	sum := sha256.Sum256(out)
+	fmt.Println(bytes.Equal(sum[:], digest.Sum(nil)))
+	// Output:
+	// <p:Payload xmlns:p="urn:payload" Id="body" p:version="2">
+	//     <p:Item sku="A-1">widget</p:Item>
+	//   </p:Payload>
+	// true
+}
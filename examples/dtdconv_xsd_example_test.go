@@ -0,0 +1,64 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/dtdconv"
+)
+
+func Example_dtdconv_xsd() {
+	ctx := context.Background()
+
+	dtd, err := helium.ParseDTD(ctx, strings.NewReader(`
+<!ELEMENT list (title, item*)>
+<!ELEMENT title (#PCDATA)>
+<!ELEMENT item (#PCDATA)>
+<!ATTLIST item id ID #REQUIRED status (open|done) "open">`))
+	if err != nil {
+		fmt.Printf("failed to parse DTD: %s\n", err)
+		return
+	}
+
+	schema, err := dtdconv.NewConverter().ToXSD(ctx, dtd)
+	if err != nil {
+		fmt.Printf("failed to convert: %s\n", err)
+		return
+	}
+	if err := helium.NewWriter().Format(true).XMLDeclaration(false).WriteTo(os.Stdout, schema); err != nil {
+		fmt.Printf("failed to write schema: %s\n", err)
+		return
+	}
+	// Output:
+	// <xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	//   <xs:element name="list">
+	//     <xs:complexType>
+	//       <xs:sequence>
+	//         <xs:element ref="title"/>
+	//         <xs:element ref="item" minOccurs="0" maxOccurs="unbounded"/>
+	//       </xs:sequence>
+	//     </xs:complexType>
+	//   </xs:element>
+	//   <xs:element name="title" type="xs:string"/>
+	//   <xs:element name="item">
+	//     <xs:complexType>
+	//       <xs:simpleContent>
+	//         <xs:extension base="xs:string">
+	//           <xs:attribute name="id" type="xs:ID" use="required"/>
+	//           <xs:attribute name="status" default="open">
+	//             <xs:simpleType>
+	//               <xs:restriction base="xs:token">
+	//                 <xs:enumeration value="open"/>
+	//                 <xs:enumeration value="done"/>
+	//               </xs:restriction>
+	//             </xs:simpleType>
+	//           </xs:attribute>
+	//         </xs:extension>
+	//       </xs:simpleContent>
+	//     </xs:complexType>
+	//   </xs:element>
+	// </xs:schema>
+}
@@ -0,0 +1,45 @@
+package examples_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/exi"
+)
+
+func Example_exi_roundtrip() {
+	ctx := context.Background()
+
+	doc, err := helium.NewParser().Parse(ctx, []byte(`<order id="42"><!--rush--><item>pen</item><item>ink</item></order>`))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+
+	// Encode with compression, keeping comments.
+	opts := exi.NewOptions().
+		Alignment(exi.AlignmentCompression).
+		Preserve(exi.PreserveComments)
+	var buf bytes.Buffer
+	if err := exi.NewEncoder().Options(opts).Encode(ctx, &buf, doc); err != nil {
+		fmt.Printf("failed to encode: %s\n", err)
+		return
+	}
+
+	// The options travel in the header, so the decoder needs none.
+	decoded, err := exi.NewDecoder().DecodeDocument(ctx, &buf)
+	if err != nil {
+		fmt.Printf("failed to decode: %s\n", err)
+		return
+	}
+	s, err := helium.WriteString(decoded.DocumentElement())
+	if err != nil {
+		fmt.Printf("failed to write: %s\n", err)
+		return
+	}
+	fmt.Println(s)
+	// Output:
+	// <order id="42"><!--rush--><item>pen</item><item>ink</item></order>
+}
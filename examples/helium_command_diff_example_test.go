@@ -0,0 +1,68 @@
+package examples_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func Example_helium_command_diff() {
+	workDir, err := os.MkdirTemp("", "helium-command-diff-*")
+	if err != nil {
+		fmt.Printf("failed to create temp dir: %s\n", err)
+		return
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	oldPath := filepath.Join(workDir, "old.xml")
+	newPath := filepath.Join(workDir, "new.xml")
+	patchPath := filepath.Join(workDir, "patch.xml")
+	if err := writeHeliumExampleFile(oldPath, `<config>
+  <server name="a" port="80"/>
+</config>`); err != nil {
+		fmt.Printf("failed to write old input: %s\n", err)
+		return
+	}
+	// Only the port changes; the new file is also formatted differently,
+	// which a line-based diff would report as changed lines.
+	if err := writeHeliumExampleFile(newPath, `<config>
+  <server port="8080"
+          name="a"/>
+</config>`); err != nil {
+		fmt.Printf("failed to write new input: %s\n", err)
+		return
+	}
+
+	// `helium diff` prints an RFC 5261 patch that turns the first document
+	// into the second.
+	stdout, stderr, exitCode := runHeliumCLI("diff", oldPath, newPath)
+	if exitCode != 0 || stderr != "" {
+		fmt.Printf("unexpected diff failure: exit=%d stderr=%q\n", exitCode, strings.TrimSpace(stderr))
+		return
+	}
+	fmt.Println("$ helium diff old.xml new.xml > patch.xml")
+	fmt.Println(strings.TrimSpace(stdout))
+	if err := writeHeliumExampleFile(patchPath, stdout); err != nil {
+		fmt.Printf("failed to write patch: %s\n", err)
+		return
+	}
+
+	// `helium patch` applies it and prints the resulting document.
+	stdout, stderr, exitCode = runHeliumCLI("patch", oldPath, patchPath)
+	if exitCode != 0 || stderr != "" {
+		fmt.Printf("unexpected patch failure: exit=%d stderr=%q\n", exitCode, strings.TrimSpace(stderr))
+		return
+	}
+	fmt.Println("$ helium patch old.xml patch.xml")
+	fmt.Println(strings.TrimSpace(stdout))
+	// Output:
+	// $ helium diff old.xml new.xml > patch.xml
+	// <?xml version="1.0"?>
+	// <diff><replace sel="/config/server/@port">8080</replace></diff>
+	// $ helium patch old.xml patch.xml
+	// <?xml version="1.0"?>
+	// <config>
+	//   <server name="a" port="8080"/>
+	// </config>
+}
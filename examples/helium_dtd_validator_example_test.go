@@ -0,0 +1,44 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+)
+
+func Example_helium_dtd_validator() {
+	// ParseDTD reads a standalone DTD, such as a .dtd file, without any
+	// document referencing it.
+	const schema = `<!ELEMENT note (to, body)>
+<!ELEMENT to (#PCDATA)>
+<!ELEMENT body (#PCDATA)>
+<!ATTLIST note priority (low|high) "low">`
+
+	dtd, err := helium.ParseDTD(context.Background(), strings.NewReader(schema))
+	if err != nil {
+		fmt.Printf("failed to parse DTD: %s\n", err)
+		return
+	}
+
+	// The document has no DOCTYPE of its own; the validator supplies it.
+	doc, err := helium.NewParser().Parse(context.Background(), []byte(`<note priority="urgent"><body>hi</body></note>`))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+
+	// Each violation is delivered to the ErrorHandler as a
+	// *helium.DTDValidationError; Validate returns ErrDTDValidationFailed.
+	collector := helium.NewErrorCollector(context.Background(), helium.ErrorLevelNone)
+	err = helium.NewDTDValidator(dtd).ErrorHandler(collector).Validate(context.Background(), doc)
+	fmt.Println(err)
+	for _, e := range collector.Errors() {
+		fmt.Println(e)
+	}
+	// Output:
+	// dtd: validation failed
+	// element note: attribute priority value "urgent" is not among the enumerated set
+	// element note: content does not match declared content model
+}
@@ -0,0 +1,38 @@
+package examples_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/sax"
+	"github.com/lestrrat-go/helium/stream"
+)
+
+func Example_helium_emit_sax() {
+	const src = `<catalog xmlns="urn:books"><book id="1"><title>Go</title></book></catalog>`
+
+	doc, err := helium.NewParser().Parse(context.Background(), []byte(src))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+
+	// EmitSAX replays a tree — here only the <book> subtree — as the SAX2
+	// events the parser would fire, so it can be fed to any SAX consumer.
+	// The default namespace <book> inherits from <catalog> is declared on
+	// the first element emitted.
+	var buf bytes.Buffer
+	w := stream.NewWriter(&buf)
+	book := doc.DocumentElement().FirstChild()
+	if err := helium.EmitSAX(context.Background(), book, sax.NewWriterHandler(&w)); err != nil {
+		fmt.Printf("failed to emit: %s\n", err)
+		return
+	}
+	_, _ = os.Stdout.Write(buf.Bytes())
+	// Output:
+	// <?xml version="1.0"?>
+	// <book id="1" xmlns="urn:books"><title>Go</title></book>
+}
@@ -0,0 +1,37 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/helium"
+)
+
+func Example_helium_formatter() {
+	// A Formatter indents element content and wraps start tags that do not
+	// fit in the line width. Mixed content is written exactly as it was, so
+	// the formatted document means the same thing as the original.
+	const src = `<catalog><book id="b1" title="An Introduction to Formatting" lang="en"/>
+
+
+<note>Read <em>this</em> first.</note></catalog>`
+
+	out, err := helium.NewFormatter().
+		Width(40).
+		AttributeWrap(helium.AttributeWrapAligned).
+		Format(context.Background(), []byte(src))
+	if err != nil {
+		fmt.Printf("format failed: %s\n", err)
+		return
+	}
+	_, _ = os.Stdout.Write(out)
+	// Output:
+	// <catalog>
+	//   <book id="b1"
+	//         title="An Introduction to Formatting"
+	//         lang="en"/>
+	//
+	//   <note>Read <em>this</em> first.</note>
+	// </catalog>
+}
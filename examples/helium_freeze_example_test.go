@@ -0,0 +1,49 @@
+package examples_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xpath3"
+)
+
+func Example_helium_freeze() {
+	doc, err := helium.NewParser().Parse(context.Background(), []byte(`<catalog><book id="1">Go</book><book id="2">XML</book></catalog>`))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+
+	// Freeze makes a read-only copy of a document that is only going to be
+	// queried. The parsed original is no longer needed.
+	frozen, err := helium.Freeze(doc)
+	if err != nil {
+		fmt.Printf("failed to freeze: %s\n", err)
+		return
+	}
+	doc.Free()
+
+	// Queries run against the frozen document as against any other.
+	expr, err := xpath3.NewCompiler().Compile(`string(//book[@id = "2"])`)
+	if err != nil {
+		fmt.Printf("compile error: %s\n", err)
+		return
+	}
+	r, err := xpath3.NewEvaluator(xpath3.DefaultEvaluatorOptions).
+		Evaluate(context.Background(), expr, frozen)
+	if err != nil {
+		fmt.Printf("xpath error: %s\n", err)
+		return
+	}
+	s, _ := r.IsString()
+	fmt.Println(s)
+
+	// Mutations are rejected.
+	err = frozen.DocumentElement().SetAttribute("updated", "true")
+	fmt.Println(errors.Is(err, helium.ErrReadOnly))
+	// Output:
+	// XML
+	// true
+}
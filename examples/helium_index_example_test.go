@@ -0,0 +1,40 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xpath1"
+)
+
+func Example_helium_index() {
+	doc, err := helium.NewParser().Parse(context.Background(), []byte(`<catalog><item sku="A">Apple</item><item sku="B">Banana</item></catalog>`))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+
+	// With the index enabled, XPath answers //item[@sku='B'] by lookup
+	// instead of walking the whole document.
+	doc.EnableIndex()
+	nodes, err := xpath1.Find(context.Background(), doc, `//item[@sku='B']`)
+	if err != nil {
+		fmt.Printf("failed to evaluate: %s\n", err)
+		return
+	}
+	for _, n := range nodes {
+		fmt.Println(string(n.Content()))
+	}
+
+	// The index follows changes to the document.
+	first := doc.DocumentElement().FirstChild().(*helium.Element)
+	if err := first.SetAttribute("sku", "B"); err != nil {
+		fmt.Printf("failed to edit: %s\n", err)
+		return
+	}
+	fmt.Println(len(doc.Index().ElementsByAttribute("", "sku", "B")))
+	// Output:
+	// Banana
+	// 2
+}
@@ -0,0 +1,39 @@
+package examples_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/helium"
+)
+
+func Example_helium_marshal_binary() {
+	doc, err := helium.NewParser().Parse(context.Background(), []byte(`<!DOCTYPE catalog [
+<!ATTLIST book id ID #REQUIRED>
+]>
+<catalog><book id="b1">Go</book><book id="b2">XML</book></catalog>`))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+
+	// Encode the parsed document, for example to store it in a cache.
+	b, err := helium.MarshalBinary(doc)
+	if err != nil {
+		fmt.Printf("failed to encode: %s\n", err)
+		return
+	}
+
+	// Loading it back skips parsing. The DTD, the ID table and line
+	// numbers come back with the tree.
+	loaded, err := helium.LoadBinary(bytes.NewReader(b))
+	if err != nil {
+		fmt.Printf("failed to load: %s\n", err)
+		return
+	}
+	book := loaded.GetElementByID("b2")
+	fmt.Println(string(book.Content()), book.Line())
+	// Output:
+	// XML 4
+}
@@ -0,0 +1,44 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/helium"
+)
+
+func Example_helium_observe() {
+	doc, err := helium.NewParser().Parse(context.Background(), []byte(`<list><item>one</item></list>`))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+
+	// Observers hear about every change made through the mutation APIs, for
+	// example to invalidate cached query results.
+	cancel := doc.Observe(func(m helium.Mutation) {
+		switch m.Kind {
+		case helium.MutationChildInserted:
+			fmt.Printf("inserted <%s> into <%s>\n", m.Node.Name(), m.Target.Name())
+		case helium.MutationAttributeSet:
+			fmt.Printf("set @%s on <%s>\n", m.Node.Name(), m.Target.Name())
+		case helium.MutationText:
+			fmt.Printf("text changed from %q\n", m.OldValue)
+		}
+	})
+	defer cancel()
+
+	root := doc.DocumentElement()
+	item, err := doc.CreateElement("item")
+	if err != nil {
+		fmt.Printf("failed to create element: %s\n", err)
+		return
+	}
+	_ = root.AddChild(item)
+	_ = item.SetAttribute("id", "2")
+	_ = root.FirstChild().(*helium.Element).AppendText([]byte(" (first)"))
+	// Output:
+	// inserted <item> into <list>
+	// set @id on <item>
+	// text changed from "one"
+}
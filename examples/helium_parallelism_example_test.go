@@ -0,0 +1,39 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+)
+
+func Example_helium_parallelism() {
+	// A large export with many sibling records under one document element.
+	var sb strings.Builder
+	sb.WriteString(`<export xmlns="urn:export">`)
+	for i := range 100000 {
+		fmt.Fprintf(&sb, `<record id="%d"><name>item %d</name></record>`, i, i)
+	}
+	sb.WriteString(`</export>`)
+
+	// Parallelism splits the records across goroutines and stitches the
+	// results into one document. The result is the same as a sequential
+	// parse; small or unsuitable inputs are simply parsed sequentially.
+	doc, err := helium.NewParser().Parallelism(4).Parse(context.Background(), []byte(sb.String()))
+	if err != nil {
+		fmt.Printf("parse failed: %s\n", err)
+		return
+	}
+
+	root := doc.DocumentElement()
+	count := 0
+	for range helium.ChildElements(root) {
+		count++
+	}
+	last := root.LastChild().(*helium.Element)
+	id, _ := last.GetAttribute("id")
+	fmt.Printf("%d records, last %s in %s\n", count, id, last.URI())
+	// Output:
+	// 100000 records, last 99999 in urn:export
+}
@@ -0,0 +1,44 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/helium"
+)
+
+func Example_helium_preserve_lexical() {
+	// PreserveLexical records the source markup of every node, so a targeted
+	// edit leaves the rest of the file byte-for-byte unchanged when written
+	// with a Writer in the same mode.
+	const src = `<config>
+  <server host='a.example'   port = "80" />
+  <server host='b.example'   port = "80" />
+</config>
+`
+	doc, err := helium.NewParser().PreserveLexical(true).Parse(context.Background(), []byte(src))
+	if err != nil {
+		fmt.Printf("parse failed: %s\n", err)
+		return
+	}
+
+	for e := range helium.ChildElements(doc.DocumentElement()) {
+		if host, _ := e.GetAttribute("host"); host == "b.example" {
+			if err := e.SetAttribute("port", "8080"); err != nil {
+				fmt.Printf("set attribute failed: %s\n", err)
+				return
+			}
+		}
+	}
+
+	if err := helium.NewWriter().PreserveLexical(true).WriteTo(os.Stdout, doc); err != nil {
+		fmt.Printf("write failed: %s\n", err)
+		return
+	}
+	// Output:
+	// <config>
+	//   <server host='a.example'   port = "80" />
+	//   <server host="b.example" port="8080"/>
+	// </config>
+}
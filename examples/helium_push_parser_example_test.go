@@ -9,7 +9,8 @@ import (
 )
 
 func Example_helium_push_parser() {
-	// PushParser allows incremental parsing when XML arrives in chunks.
+	// PushParser allows incremental parsing when XML arrives in chunks. Each
+	// Push parses the constructs its chunk completes before it returns.
 	p := helium.NewParser()
 	pp := p.NewPushParser(context.Background())
 
@@ -0,0 +1,44 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+)
+
+func Example_helium_reader() {
+	// Reader walks a document one node at a time without building the whole
+	// tree. Expand materializes the current element's subtree on demand.
+	const src = `<feed><entry id="1"><title>alpha</title></entry><entry id="2"><title>beta</title></entry></feed>`
+
+	r := helium.NewParser().NewReader(context.Background(), strings.NewReader(src))
+	defer r.Close()
+
+	ok, err := r.Read()
+	for ok && err == nil {
+		if r.NodeType() != helium.ReaderElement || r.Name() != "entry" {
+			ok, err = r.Read()
+			continue
+		}
+
+		id, _ := r.GetAttribute("id")
+		entry, xerr := r.Expand()
+		if xerr != nil {
+			fmt.Printf("expand failed: %s\n", xerr)
+			return
+		}
+		fmt.Printf("%s: %s\n", id, entry.Content())
+
+		// Move past the subtree we already have.
+		ok, err = r.Skip()
+	}
+	if err != nil {
+		fmt.Printf("read failed: %s\n", err)
+		return
+	}
+	// Output:
+	// 1: alpha
+	// 2: beta
+}
@@ -0,0 +1,39 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+)
+
+func Example_helium_reparse() {
+	// Reparse applies an edit to the source of a document parsed with
+	// TrackPositions and RetainSource, parsing only the smallest element
+	// around the edit again and leaving the rest of the tree untouched.
+	const src = "<doc>\n  <title>Draft</title>\n  <para>Some text.</para>\n</doc>"
+	p := helium.NewParser().TrackPositions(true).RetainSource(true)
+	doc, err := p.Parse(context.Background(), []byte(src))
+	if err != nil {
+		fmt.Printf("parse failed: %s\n", err)
+		return
+	}
+	title := doc.DocumentElement().FirstChild().NextSibling()
+
+	off := strings.Index(src, "Some")
+	edit := helium.Range{Start: off, End: off + len("Some")}
+	doc, err = p.Reparse(context.Background(), doc, edit, []byte("<em>More</em>"))
+	if err != nil {
+		fmt.Printf("reparse failed: %s\n", err)
+		return
+	}
+
+	para := doc.DocumentElement().LastChild().PrevSibling()
+	pos, _ := helium.PositionOf(para)
+	fmt.Printf("title kept: %t\n", doc.DocumentElement().FirstChild().NextSibling() == title)
+	fmt.Printf("para: line %d, offsets %d-%d\n", pos.Start.Line, pos.Start.Offset, pos.End.Offset)
+	// Output:
+	// title kept: true
+	// para: line 3, offsets 31-63
+}
@@ -0,0 +1,53 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xsd"
+)
+
+func Example_helium_resource_resolver() {
+	ctx := context.Background()
+
+	// One resolver serves every external resource of the pipeline: here from
+	// memory, with no resource allowed past 1 KiB. Anything it does not know
+	// is refused, wherever the reference comes from.
+	resources := helium.LimitResourceResolver(helium.MemoryResourceResolver(map[string][]byte{
+		"book.dtd":   []byte(`<!ENTITY publisher "Example Press">`),
+		"common.xsd": []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="book" type="xs:string"/></xs:schema>`),
+	}), 1024)
+
+	doc, err := helium.NewParser().
+		BlockXXE(false).
+		LoadExternalDTD(true).
+		SubstituteEntities(true).
+		ResourceResolver(resources).
+		Parse(ctx, []byte(`<!DOCTYPE book SYSTEM "book.dtd"><book>&publisher;</book>`))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+	fmt.Println(string(doc.DocumentElement().Content()))
+
+	schemaDoc, err := helium.NewParser().Parse(ctx, []byte(
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:include schemaLocation="common.xsd"/></xs:schema>`))
+	if err != nil {
+		fmt.Printf("failed to parse schema: %s\n", err)
+		return
+	}
+	schema, err := xsd.NewCompiler().BaseDir(".").ResourceResolver(resources).Compile(ctx, schemaDoc)
+	if err != nil {
+		fmt.Printf("failed to compile schema: %s\n", err)
+		return
+	}
+	if err := xsd.NewValidator(schema).Validate(ctx, doc); err != nil {
+		fmt.Printf("invalid: %s\n", err)
+		return
+	}
+	fmt.Println("valid")
+	// Output:
+	// Example Press
+	// valid
+}
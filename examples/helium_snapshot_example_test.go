@@ -0,0 +1,40 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/helium"
+)
+
+func Example_helium_snapshot() {
+	doc, err := helium.NewParser().Parse(context.Background(), []byte(`<article><title>Draft</title></article>`))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+
+	// A snapshot keeps the document as it is now; later edits do not reach it.
+	before, err := doc.Snapshot()
+	if err != nil {
+		fmt.Printf("failed to snapshot: %s\n", err)
+		return
+	}
+
+	title := doc.DocumentElement().FirstChild().(*helium.Element)
+	if err := title.SetAttribute("status", "final"); err != nil {
+		fmt.Printf("failed to edit: %s\n", err)
+		return
+	}
+
+	for _, d := range []*helium.Document{before, doc} {
+		if err := helium.NewWriter().XMLDeclaration(false).WriteTo(os.Stdout, d); err != nil {
+			fmt.Printf("failed to serialize: %s\n", err)
+			return
+		}
+	}
+	// Output:
+	// <article><title>Draft</title></article>
+	// <article><title status="final">Draft</title></article>
+}
@@ -0,0 +1,33 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/helium"
+)
+
+func Example_helium_track_positions() {
+	// TrackPositions records the source extent of every element, attribute,
+	// text node, comment and processing instruction. An attribute's position
+	// also delimits its value, so a tool can point at exactly what is wrong.
+	const src = "<config>\n  <server port='eighty'/>\n</config>"
+	doc, err := helium.NewParser().TrackPositions(true).Parse(context.Background(), []byte(src))
+	if err != nil {
+		fmt.Printf("parse failed: %s\n", err)
+		return
+	}
+
+	server := doc.DocumentElement().FirstChild().NextSibling().(*helium.Element)
+	for _, attr := range server.Attributes() {
+		pos, ok := helium.PositionOf(attr)
+		if !ok {
+			continue
+		}
+		fmt.Printf("%s: line %d, columns %d-%d: %q\n", attr.Name(),
+			pos.ValueStart.Line, pos.ValueStart.Column, pos.ValueEnd.Column-1,
+			src[pos.ValueStart.Offset:pos.ValueEnd.Offset])
+	}
+	// Output:
+	// port: line 2, columns 17-22: "eighty"
+}
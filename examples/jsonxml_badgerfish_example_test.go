@@ -0,0 +1,52 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/jsonxml"
+)
+
+func Example_jsonxml_badgerfish() {
+	ctx := context.Background()
+
+	doc, err := helium.NewParser().Parse(ctx, []byte(`<order id="7"><item>tea</item><item>milk</item><note/></order>`))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+
+	// BadgerFish keeps attributes and text, so the JSON converts back to
+	// the same document.
+	conv := jsonxml.NewConverter()
+	data, err := conv.ToJSON(ctx, doc)
+	if err != nil {
+		fmt.Printf("failed to convert to JSON: %s\n", err)
+		return
+	}
+	fmt.Println(string(data))
+
+	back, err := conv.ToXML(ctx, data)
+	if err != nil {
+		fmt.Printf("failed to convert to XML: %s\n", err)
+		return
+	}
+	if err := helium.NewWriter().XMLDeclaration(false).WriteTo(os.Stdout, back); err != nil {
+		fmt.Printf("failed to write: %s\n", err)
+		return
+	}
+
+	// Parker keeps only the data, with numbers typed.
+	data, err = conv.Convention(jsonxml.ConventionParker).ToJSON(ctx, doc)
+	if err != nil {
+		fmt.Printf("failed to convert to JSON: %s\n", err)
+		return
+	}
+	fmt.Println(string(data))
+	// Output:
+	// {"order":{"@id":"7","item":[{"$":"tea"},{"$":"milk"}],"note":{}}}
+	// <order id="7"><item>tea</item><item>milk</item><note/></order>
+	// {"item":["tea","milk"],"note":null}
+}
@@ -0,0 +1,54 @@
+package examples_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/relaxng"
+	"github.com/lestrrat-go/helium/stream"
+)
+
+func Example_relaxng_validating_writer() {
+	const schemaSrc = `<element name="filing" xmlns="http://relaxng.org/ns/structure/1.0"
+    datatypeLibrary="http://www.w3.org/2001/XMLSchema-datatypes">
+  <element name="header"><text/></element>
+  <oneOrMore>
+    <element name="entry"><data type="decimal"/></element>
+  </oneOrMore>
+</element>`
+
+	ctx := context.Background()
+	schemaDoc, err := helium.NewParser().Parse(ctx, []byte(schemaSrc))
+	if err != nil {
+		fmt.Printf("failed to parse schema: %s\n", err)
+		return
+	}
+	grammar, err := relaxng.NewCompiler().Compile(ctx, schemaDoc)
+	if err != nil {
+		fmt.Printf("failed to compile schema: %s\n", err)
+		return
+	}
+
+	// NewValidatingWriter checks each event against the grammar before
+	// passing it on to out, so a mistake fails at the call that makes it
+	// instead of in a separate validation pass over the finished file.
+	var buf bytes.Buffer
+	out := stream.NewWriter(&buf)
+	w := relaxng.NewValidatingWriter(&out, grammar)
+	_ = w.StartDocument("1.0", "", "")
+	_ = w.StartElement("filing")
+	_ = w.WriteElement("header", "Q3")
+	_ = w.EndElement() // no entry was written
+
+	var ve *relaxng.ValidationError
+	if errors.As(w.Error(), &ve) {
+		fmt.Println(ve.Element, ve.Expected)
+		fmt.Print(ve.Error())
+	}
+	// Output:
+	// filing [entry]
+	// (stream):0: element filing: Relax-NG validity error : Expecting an element entry, got nothing
+}
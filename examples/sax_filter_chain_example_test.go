@@ -0,0 +1,73 @@
+package examples_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/sax"
+	"github.com/lestrrat-go/helium/stream"
+)
+
+func Example_sax_filter_chain() {
+	const src = `<users><user><name>alice</name><password>hunter2</password></user></users>`
+
+	// A sax.Filter forwards every event to the next handler unless a
+	// callback is set for it. This one drops <password> elements with
+	// everything inside them.
+	depth := 0
+	redact := sax.NewFilter(nil)
+	redact.SetOnStartElementNS(sax.StartElementNSFunc(func(ctx context.Context, localname, prefix, uri string, namespaces []sax.Namespace, attrs []sax.Attribute) error {
+		if depth > 0 || localname == "password" {
+			depth++
+			return nil
+		}
+		return redact.Next().StartElementNS(ctx, localname, prefix, uri, namespaces, attrs)
+	}))
+	redact.SetOnEndElementNS(sax.EndElementNSFunc(func(ctx context.Context, localname, prefix, uri string) error {
+		if depth > 0 {
+			depth--
+			return nil
+		}
+		return redact.Next().EndElementNS(ctx, localname, prefix, uri)
+	}))
+	redact.SetOnCharacters(sax.CharactersFunc(func(ctx context.Context, ch []byte) error {
+		if depth > 0 {
+			return nil
+		}
+		return redact.Next().Characters(ctx, ch)
+	}))
+
+	// This one renames <user> to <account>.
+	rename := sax.NewFilter(nil)
+	renamed := func(name string) string {
+		if name == "user" {
+			return "account"
+		}
+		return name
+	}
+	rename.SetOnStartElementNS(sax.StartElementNSFunc(func(ctx context.Context, localname, prefix, uri string, namespaces []sax.Namespace, attrs []sax.Attribute) error {
+		return rename.Next().StartElementNS(ctx, renamed(localname), prefix, uri, namespaces, attrs)
+	}))
+	rename.SetOnEndElementNS(sax.EndElementNSFunc(func(ctx context.Context, localname, prefix, uri string) error {
+		return rename.Next().EndElementNS(ctx, renamed(localname), prefix, uri)
+	}))
+
+	// sax.Chain connects the filters in order and ends the pipeline in a
+	// WriterHandler, which serializes the filtered events through a
+	// stream.Writer as they arrive — no tree is built.
+	var buf bytes.Buffer
+	w := stream.NewWriter(&buf)
+	handler := sax.Chain(sax.NewWriterHandler(&w), redact, rename)
+
+	if _, err := helium.NewParser().SAXHandler(handler).Parse(context.Background(), []byte(src)); err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+	_, _ = os.Stdout.Write(buf.Bytes())
+	// Output:
+	// <?xml version="1.0"?>
+	// <users><account><name>alice</name></account></users>
+}
@@ -0,0 +1,48 @@
+package examples_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/stream"
+)
+
+// writeOrder is emit logic written once against *stream.Writer; the
+// Writer's backend decides where the document goes.
+func writeOrder(w *stream.Writer) error {
+	if err := w.StartElementNS("o", "order", "urn:example:orders"); err != nil {
+		return err
+	}
+	if err := w.WriteAttribute("id", "42"); err != nil {
+		return err
+	}
+	if err := w.WriteElementNS("o", "item", "urn:example:orders", "widget"); err != nil {
+		return err
+	}
+	return w.EndDocument()
+}
+
+func Example_stream_backend() {
+	// NewBackendWriter gives the Writer API a Backend instead of an
+	// io.Writer. helium.DocumentBackend builds a *helium.Document, and Tee
+	// reports each event to several backends, here serializing the same
+	// document at the same time through WriterBackend.
+	var buf bytes.Buffer
+	out := stream.NewWriter(&buf)
+	tree := helium.NewDocumentBackend()
+	w := stream.NewBackendWriter(stream.Tee(stream.WriterBackend(&out), tree))
+
+	if err := writeOrder(&w); err != nil {
+		fmt.Printf("error: %s\n", err)
+		return
+	}
+
+	fmt.Print(buf.String())
+
+	root := tree.Document().DocumentElement()
+	fmt.Println(root.LocalName(), root.URI())
+	// Output:
+	// <o:order id="42" xmlns:o="urn:example:orders"><o:item>widget</o:item></o:order>
+	// order urn:example:orders
+}
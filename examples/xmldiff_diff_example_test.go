@@ -0,0 +1,55 @@
+package examples_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xmldiff"
+)
+
+func Example_xmldiff_diff() {
+	ctx := context.Background()
+
+	// The new version renames one book and drops another. Reindenting or
+	// reordering attributes would not show up in the diff at all.
+	before, err := helium.NewParser().Parse(ctx, []byte(
+		`<catalog><book id="a" year="2015">Go</book><book id="b">XML</book><book id="c">XSLT</book></catalog>`))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+	after, err := helium.NewParser().Parse(ctx, []byte(
+		`<catalog><book year="2015" id="a">The Go Programming Language</book><book id="c">XSLT</book></catalog>`))
+	if err != nil {
+		fmt.Printf("failed to parse: %s\n", err)
+		return
+	}
+
+	// Elements are paired by their id attribute, so each change is reported
+	// against the book it belongs to.
+	patch, err := xmldiff.NewDiffer().Diff(ctx, before, after)
+	if err != nil {
+		fmt.Printf("failed to diff: %s\n", err)
+		return
+	}
+	if err := helium.NewWriter().XMLDeclaration(false).WriteTo(os.Stdout, patch); err != nil {
+		fmt.Printf("failed to write patch: %s\n", err)
+		return
+	}
+
+	// Applying the patch to the old document yields the new one.
+	patched, err := xmldiff.Patch(ctx, before, patch)
+	if err != nil {
+		fmt.Printf("failed to patch: %s\n", err)
+		return
+	}
+	if err := helium.NewWriter().XMLDeclaration(false).WriteTo(os.Stdout, patched); err != nil {
+		fmt.Printf("failed to write document: %s\n", err)
+		return
+	}
+	// Output:
+	// <diff><replace sel="/catalog/book[1]/text()">The Go Programming Language</replace><remove sel="/catalog/book[2]"/></diff>
+	// <catalog><book id="a" year="2015">The Go Programming Language</book><book id="c">XSLT</book></catalog>
+}
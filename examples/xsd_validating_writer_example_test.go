@@ -0,0 +1,56 @@
+package examples_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/stream"
+	"github.com/lestrrat-go/helium/xsd"
+)
+
+func Example_xsd_validating_writer() {
+	const schemaSrc = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="filing">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="header" type="xs:string"/>
+        <xs:element name="entry" type="xs:decimal" maxOccurs="unbounded"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+	ctx := context.Background()
+	schemaDoc, err := helium.NewParser().Parse(ctx, []byte(schemaSrc))
+	if err != nil {
+		fmt.Printf("failed to parse schema: %s\n", err)
+		return
+	}
+	schema, err := xsd.NewCompiler().Compile(ctx, schemaDoc)
+	if err != nil {
+		fmt.Printf("failed to compile schema: %s\n", err)
+		return
+	}
+
+	// NewValidatingWriter checks each event against the schema before
+	// passing it on to out, so a mistake fails at the call that makes it
+	// instead of in a separate validation pass over the finished file.
+	var buf bytes.Buffer
+	out := stream.NewWriter(&buf)
+	w := xsd.NewValidatingWriter(ctx, &out, schema)
+	_ = w.StartDocument("1.0", "", "")
+	_ = w.StartElement("filing")
+	_ = w.WriteElement("entry", "12.50") // header is missing
+
+	var ve *xsd.ValidationError
+	if errors.As(w.Error(), &ve) {
+		fmt.Println(ve.Element, ve.Expected)
+		fmt.Print(ve.Error())
+	}
+	// Output:
+	// entry [header]
+	// (stream):0: Schemas validity error : Element 'entry': This element is not expected. Expected is ( header ).
+}
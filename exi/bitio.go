@@ -0,0 +1,306 @@
+package exi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLength bounds the lengths a decoder accepts for strings, binary values
+// and lists, so that a corrupt length cannot make it allocate or loop
+// without reading input.
+const maxLength = 1 << 28
+
+// bitWriter writes the EXI primitive types. Unaligned, n-bit values are
+// written most significant bit first with no regard to byte boundaries;
+// aligned, each takes whole bytes, least significant first.
+type bitWriter struct {
+	out     io.ByteWriter
+	aligned bool
+	cur     byte
+	n       uint // bits used in cur
+	err     error
+}
+
+func newBitWriter(out io.ByteWriter, aligned bool) *bitWriter {
+	return &bitWriter{out: out, aligned: aligned}
+}
+
+func (w *bitWriter) writeByte(b byte) {
+	if w.err == nil {
+		w.err = w.out.WriteByte(b)
+	}
+}
+
+// bits writes v as an n-bit unsigned integer.
+func (w *bitWriter) bits(n int, v uint64) {
+	if w.aligned {
+		for i := 0; i < (n+7)/8; i++ {
+			w.writeByte(byte(v))
+			v >>= 8
+		}
+		return
+	}
+	for i := n - 1; i >= 0; i-- {
+		w.cur |= byte(v>>uint(i)&1) << (7 - w.n)
+		w.n++
+		if w.n == 8 {
+			w.writeByte(w.cur)
+			w.cur, w.n = 0, 0
+		}
+	}
+}
+
+// align pads the current byte with zero bits.
+func (w *bitWriter) align() {
+	if w.n > 0 {
+		w.writeByte(w.cur)
+		w.cur, w.n = 0, 0
+	}
+}
+
+func (w *bitWriter) boolean(v bool) {
+	var b uint64
+	if v {
+		b = 1
+	}
+	w.bits(1, b)
+}
+
+// unsigned writes v in groups of seven bits, least significant first, with
+// the high bit of each octet set when more follow.
+func (w *bitWriter) unsigned(v uint64) {
+	for v >= 0x80 {
+		w.bits(8, v&0x7f|0x80)
+		v >>= 7
+	}
+	w.bits(8, v)
+}
+
+func (w *bitWriter) unsignedBig(v *big.Int) {
+	if v.IsUint64() {
+		w.unsigned(v.Uint64())
+		return
+	}
+	words := new(big.Int).Set(v)
+	low := new(big.Int)
+	for words.BitLen() > 7 {
+		low.And(words, big.NewInt(0x7f))
+		w.bits(8, low.Uint64()|0x80)
+		words.Rsh(words, 7)
+	}
+	w.bits(8, words.Uint64())
+}
+
+// integer writes a sign bit followed by the magnitude, offset by one for
+// negative values.
+func (w *bitWriter) integer(v int64) {
+	if v < 0 {
+		w.boolean(true)
+		w.unsigned(uint64(-(v + 1)))
+		return
+	}
+	w.boolean(false)
+	w.unsigned(uint64(v))
+}
+
+func (w *bitWriter) integerBig(v *big.Int) {
+	if v.Sign() < 0 {
+		w.boolean(true)
+		m := new(big.Int).Neg(v)
+		w.unsignedBig(m.Sub(m, big.NewInt(1)))
+		return
+	}
+	w.boolean(false)
+	w.unsignedBig(v)
+}
+
+// chars writes the code points of s.
+func (w *bitWriter) chars(s string) {
+	for _, r := range s {
+		w.unsigned(uint64(r))
+	}
+}
+
+// str writes the length of s in code points followed by its characters.
+func (w *bitWriter) str(s string) {
+	w.unsigned(uint64(utf8.RuneCountInString(s)))
+	w.chars(s)
+}
+
+func (w *bitWriter) binary(b []byte) {
+	w.unsigned(uint64(len(b)))
+	for _, c := range b {
+		w.bits(8, uint64(c))
+	}
+}
+
+// bitReader reads what a bitWriter wrote. Errors are sticky: once one
+// occurs every read returns a zero value and err reports it.
+type bitReader struct {
+	in      io.ByteReader
+	aligned bool
+	cur     byte
+	n       uint // bits left in cur
+	err     error
+}
+
+func newBitReader(in io.ByteReader, aligned bool) *bitReader {
+	return &bitReader{in: in, aligned: aligned}
+}
+
+func (r *bitReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *bitReader) readByte() byte {
+	if r.err != nil {
+		return 0
+	}
+	b, err := r.in.ReadByte()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = fmt.Errorf("%w: unexpected end of input", ErrMalformed)
+		}
+		r.fail(err)
+		return 0
+	}
+	return b
+}
+
+func (r *bitReader) bits(n int) uint64 {
+	var v uint64
+	if r.aligned {
+		for i := 0; i < (n+7)/8; i++ {
+			v |= uint64(r.readByte()) << (8 * uint(i))
+		}
+		if n < 64 {
+			v &= 1<<uint(n) - 1
+		}
+		return v
+	}
+	for range n {
+		if r.n == 0 {
+			r.cur = r.readByte()
+			r.n = 8
+		}
+		r.n--
+		v = v<<1 | uint64(r.cur>>r.n&1)
+	}
+	return v
+}
+
+// align skips what is left of the current byte.
+func (r *bitReader) align() {
+	r.n = 0
+}
+
+func (r *bitReader) boolean() bool {
+	return r.bits(1) == 1
+}
+
+func (r *bitReader) unsigned() uint64 {
+	var v uint64
+	for shift := uint(0); ; shift += 7 {
+		b := r.bits(8)
+		if shift > 63 || (shift == 63 && b&0x7f > 1) {
+			r.fail(fmt.Errorf("%w: unsigned integer out of range", ErrMalformed))
+			return 0
+		}
+		v |= (b & 0x7f) << shift
+		if b&0x80 == 0 || r.err != nil {
+			return v
+		}
+	}
+}
+
+func (r *bitReader) unsignedBig() *big.Int {
+	v := new(big.Int)
+	group := new(big.Int)
+	for shift := uint(0); r.err == nil; shift += 7 {
+		if shift > 8*maxLength {
+			r.fail(fmt.Errorf("%w: unsigned integer out of range", ErrMalformed))
+			break
+		}
+		b := r.bits(8)
+		group.SetUint64(b & 0x7f)
+		v.Or(v, group.Lsh(group, shift))
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	return v
+}
+
+func (r *bitReader) integer() int64 {
+	neg := r.boolean()
+	m := r.unsigned()
+	if m > 1<<63-1 {
+		r.fail(fmt.Errorf("%w: integer out of range", ErrMalformed))
+		return 0
+	}
+	if neg {
+		return -int64(m) - 1
+	}
+	return int64(m)
+}
+
+func (r *bitReader) integerBig() *big.Int {
+	neg := r.boolean()
+	m := r.unsignedBig()
+	if neg {
+		m.Neg(m)
+		m.Sub(m, big.NewInt(1))
+	}
+	return m
+}
+
+// length reads an unsigned integer used as a count.
+func (r *bitReader) length() int {
+	n := r.unsigned()
+	if n > maxLength {
+		r.fail(fmt.Errorf("%w: length %d out of range", ErrMalformed, n))
+		return 0
+	}
+	return int(n)
+}
+
+func (r *bitReader) chars(n int) string {
+	var b strings.Builder
+	for i := 0; i < n && r.err == nil; i++ {
+		c := r.unsigned()
+		if c > utf8.MaxRune || !utf8.ValidRune(rune(c)) {
+			r.fail(fmt.Errorf("%w: invalid code point %#x", ErrMalformed, c))
+			return ""
+		}
+		b.WriteRune(rune(c))
+	}
+	return b.String()
+}
+
+func (r *bitReader) str() string {
+	return r.chars(r.length())
+}
+
+func (r *bitReader) binary() []byte {
+	n := r.length()
+	var b []byte
+	for i := 0; i < n && r.err == nil; i++ {
+		b = append(b, byte(r.bits(8)))
+	}
+	return b
+}
+
+// bitsFor returns the number of bits needed to write the values 0 to n-1.
+func bitsFor(n int) int {
+	b := 0
+	for 1<<uint(b) < n {
+		b++
+	}
+	return b
+}
@@ -0,0 +1,257 @@
+package exi
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+// smallChannel is the number of values up to which channels are
+// compressed together rather than each on its own.
+const smallChannel = 100
+
+// pendingValue is a value waiting in its channel for the end of the block.
+type pendingValue struct {
+	s  string
+	wr writer // nil for values that go through the string table
+}
+
+type valueChannel struct {
+	qn     qname
+	values []pendingValue
+}
+
+// blockWriter lays out a body in blocks, for pre-compression and
+// compression: the event codes and the values that decide grammars go to
+// the structure channel; other values go to a channel per element or
+// attribute name, in the order the names first occur in the block. The
+// string table takes values in that order too.
+type blockWriter struct {
+	out       io.Writer
+	compress  bool
+	size      int
+	buf       bytes.Buffer
+	structure *bitWriter
+	channels  []*valueChannel
+	byName    map[qname]*valueChannel
+	count     int
+}
+
+func newBlockWriter(out io.Writer, o *optionsConfig) *blockWriter {
+	b := &blockWriter{out: out, compress: o.alignment == AlignmentCompression, size: o.blockSize, byName: map[qname]*valueChannel{}}
+	b.structure = newBitWriter(&b.buf, true)
+	return b
+}
+
+// add adds a value to the channel of qn and reports whether it completes
+// the block.
+func (b *blockWriter) add(qn qname, s string, wr writer) bool {
+	c := b.byName[qn]
+	if c == nil {
+		c = &valueChannel{qn: qn}
+		b.byName[qn] = c
+		b.channels = append(b.channels, c)
+	}
+	c.values = append(c.values, pendingValue{s: s, wr: wr})
+	b.count++
+	return b.count == b.size
+}
+
+// flush writes out the block and starts the next one.
+func (b *blockWriter) flush(st *stringTable) error {
+	if b.structure.err != nil {
+		return b.structure.err
+	}
+	encoded := make([][]byte, len(b.channels))
+	for i, c := range b.channels {
+		var buf bytes.Buffer
+		w := newBitWriter(&buf, true)
+		for _, v := range c.values {
+			writeValue(w, st, c.qn, v.s, v.wr)
+		}
+		if w.err != nil {
+			return w.err
+		}
+		encoded[i] = buf.Bytes()
+	}
+
+	var err error
+	switch {
+	case !b.compress:
+		err = b.write(false, b.buf.Bytes())
+		for _, e := range encoded {
+			if err == nil {
+				err = b.write(false, e)
+			}
+		}
+	case b.count <= smallChannel:
+		err = b.write(true, append([][]byte{b.buf.Bytes()}, encoded...)...)
+	default:
+		err = b.write(true, b.buf.Bytes())
+		var small [][]byte
+		for i, c := range b.channels {
+			if len(c.values) <= smallChannel {
+				small = append(small, encoded[i])
+			}
+		}
+		if len(small) > 0 && err == nil {
+			err = b.write(true, small...)
+		}
+		for i, c := range b.channels {
+			if len(c.values) > smallChannel && err == nil {
+				err = b.write(true, encoded[i])
+			}
+		}
+	}
+
+	b.buf.Reset()
+	b.channels = b.channels[:0]
+	clear(b.byName)
+	b.count = 0
+	return err
+}
+
+// write writes parts to the output, as one DEFLATE stream when compressed.
+func (b *blockWriter) write(compressed bool, parts ...[]byte) error {
+	if !compressed {
+		for _, p := range parts {
+			if _, err := b.out.Write(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	fw, err := flate.NewWriter(b.out, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if _, err := fw.Write(p); err != nil {
+			return err
+		}
+	}
+	return fw.Close()
+}
+
+// valueSlot is a value the structure of a block refers to, filled in once
+// the block's channels are read.
+type valueSlot struct {
+	qn qname
+	dt *datatype // nil for values that go through the string table
+	s  *string
+}
+
+// blockReader reads a body written by a blockWriter.
+type blockReader struct {
+	in       *bufio.Reader
+	compress bool
+	size     int
+	stream   io.ReadCloser // the DEFLATE stream being read
+	slots    []valueSlot
+}
+
+func newBlockReader(in *bufio.Reader, o *optionsConfig) *blockReader {
+	return &blockReader{in: in, compress: o.alignment == AlignmentCompression, size: o.blockSize}
+}
+
+// begin starts a block and returns the reader of its structure channel.
+func (b *blockReader) begin() *bitReader {
+	b.slots = b.slots[:0]
+	return b.open()
+}
+
+// open returns a reader of the next part of the block.
+func (b *blockReader) open() *bitReader {
+	if !b.compress {
+		return newBitReader(b.in, true)
+	}
+	b.stream = flate.NewReader(b.in)
+	return newBitReader(bufio.NewReader(b.stream), true)
+}
+
+// add records a value of the block and reports whether it completes the
+// block.
+func (b *blockReader) add(slot valueSlot) bool {
+	b.slots = append(b.slots, slot)
+	return len(b.slots) == b.size
+}
+
+// finish reads the value channels of the block, whose structure r has
+// read, and fills in the slots.
+func (b *blockReader) finish(r *bitReader, st *stringTable) error {
+	var order []qname
+	channels := map[qname][]valueSlot{}
+	for _, s := range b.slots {
+		if _, ok := channels[s.qn]; !ok {
+			order = append(order, s.qn)
+		}
+		channels[s.qn] = append(channels[s.qn], s)
+	}
+	read := func(r *bitReader, qn qname) {
+		for _, s := range channels[qn] {
+			*s.s = readValue(r, st, qn, s.dt)
+		}
+	}
+
+	if !b.compress || len(b.slots) <= smallChannel {
+		for _, qn := range order {
+			read(r, qn)
+		}
+		return b.endStream(r)
+	}
+	if err := b.endStream(r); err != nil {
+		return err
+	}
+	var small []qname
+	for _, qn := range order {
+		if len(channels[qn]) <= smallChannel {
+			small = append(small, qn)
+		}
+	}
+	if len(small) > 0 {
+		r := b.open()
+		for _, qn := range small {
+			read(r, qn)
+		}
+		if err := b.endStream(r); err != nil {
+			return err
+		}
+	}
+	for _, qn := range order {
+		if len(channels[qn]) <= smallChannel {
+			continue
+		}
+		r := b.open()
+		read(r, qn)
+		if err := b.endStream(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *blockReader) endStream(r *bitReader) error {
+	if r.err != nil {
+		return r.err
+	}
+	return b.end()
+}
+
+// end finishes the DEFLATE stream being read, leaving the input at the
+// start of the next one.
+func (b *blockReader) end() error {
+	if b.stream == nil {
+		return nil
+	}
+	_, err := io.Copy(io.Discard, b.stream)
+	b.stream = nil
+	return err
+}
+
+func readValue(r *bitReader, st *stringTable, qn qname, dt *datatype) string {
+	if dt == nil || dt.kind == dtString {
+		return st.readValue(r, qn)
+	}
+	return dt.read(r)
+}
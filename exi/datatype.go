@@ -0,0 +1,601 @@
+package exi
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// dtKind is an EXI built-in datatype representation.
+type dtKind uint8
+
+const (
+	dtString dtKind = iota
+	dtBoolean
+	dtBooleanPattern // boolean that keeps 0/1 apart from false/true
+	dtBase64
+	dtHex
+	dtDecimal
+	dtFloat
+	dtInteger
+	dtUnsigned
+	dtNBit // integer in a range of at most 4096 values
+	dtDateTime
+	dtList
+	dtEnum
+)
+
+// dateKind selects the components of a date-time value.
+type dateKind uint8
+
+const (
+	dateGYear dateKind = iota
+	dateGYearMonth
+	dateDate
+	dateDateTime
+	dateGMonth
+	dateGMonthDay
+	dateGDay
+	dateTime
+)
+
+// datatype says how the values of a simple type are represented.
+type datatype struct {
+	kind   dtKind
+	min    *big.Int // dtNBit
+	bits   int      // dtNBit, dtEnum
+	date   dateKind
+	item   *datatype // dtList
+	values []string  // dtEnum
+	float  int       // dtFloat: 32 or 64
+}
+
+var stringType = &datatype{kind: dtString}
+
+// writer writes a prepared value.
+type writer func(w *bitWriter)
+
+// prepare parses s and returns a function writing it, or false when s is
+// not a value of the type. dtString values go through the string table and
+// are written by the caller.
+func (d *datatype) prepare(s string) (writer, bool) {
+	switch d.kind {
+	case dtString:
+		return func(w *bitWriter) { w.str(s) }, true
+	case dtList:
+		return d.prepareList(s)
+	case dtEnum:
+		return d.prepareEnum(s)
+	}
+	s = collapse(s)
+	switch d.kind {
+	case dtBoolean:
+		switch s {
+		case "true", "1":
+			return func(w *bitWriter) { w.boolean(true) }, true
+		case "false", "0":
+			return func(w *bitWriter) { w.boolean(false) }, true
+		}
+	case dtBooleanPattern:
+		if i := booleanPatternIndex(s); i >= 0 {
+			return func(w *bitWriter) { w.bits(2, uint64(i)) }, true
+		}
+	case dtBase64:
+		b, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(s, " ", ""))
+		if err == nil {
+			return func(w *bitWriter) { w.binary(b) }, true
+		}
+	case dtHex:
+		b, err := hex.DecodeString(s)
+		if err == nil {
+			return func(w *bitWriter) { w.binary(b) }, true
+		}
+	case dtDecimal:
+		return prepareDecimal(s)
+	case dtFloat:
+		return d.prepareFloat(s)
+	case dtInteger, dtUnsigned, dtNBit:
+		return d.prepareInteger(s)
+	case dtDateTime:
+		return d.prepareDateTime(s)
+	}
+	return nil, false
+}
+
+// read reads a value and returns it in canonical lexical form.
+func (d *datatype) read(r *bitReader) string {
+	switch d.kind {
+	case dtBoolean:
+		return strconv.FormatBool(r.boolean())
+	case dtBooleanPattern:
+		return booleanPatterns[r.bits(2)]
+	case dtBase64:
+		return base64.StdEncoding.EncodeToString(r.binary())
+	case dtHex:
+		return strings.ToUpper(hex.EncodeToString(r.binary()))
+	case dtDecimal:
+		return readDecimal(r)
+	case dtFloat:
+		return readFloat(r)
+	case dtInteger:
+		return r.integerBig().String()
+	case dtUnsigned:
+		return r.unsignedBig().String()
+	case dtNBit:
+		v := new(big.Int).SetUint64(r.bits(d.bits))
+		return v.Add(v, d.min).String()
+	case dtDateTime:
+		return d.readDateTime(r)
+	case dtList:
+		n := r.length()
+		items := make([]string, 0, min(n, 64))
+		for i := 0; i < n && r.err == nil; i++ {
+			items = append(items, d.item.read(r))
+		}
+		return strings.Join(items, " ")
+	case dtEnum:
+		i := int(r.bits(d.bits))
+		if i >= len(d.values) {
+			r.fail(fmt.Errorf("%w: enumeration value %d out of range", ErrMalformed, i))
+			return ""
+		}
+		return d.values[i]
+	}
+	return r.str()
+}
+
+// collapse applies the collapse whitespace facet.
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var booleanPatterns = [4]string{"false", "0", "true", "1"}
+
+func booleanPatternIndex(s string) int {
+	for i, v := range booleanPatterns {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *datatype) prepareList(s string) (writer, bool) {
+	items := strings.Fields(s)
+	ws := make([]writer, len(items))
+	for i, item := range items {
+		w, ok := d.item.prepare(item)
+		if !ok {
+			return nil, false
+		}
+		ws[i] = w
+	}
+	return func(w *bitWriter) {
+		w.unsigned(uint64(len(ws)))
+		for _, iw := range ws {
+			iw(w)
+		}
+	}, true
+}
+
+func (d *datatype) prepareEnum(s string) (writer, bool) {
+	i := -1
+	for j, v := range d.values {
+		if v == s {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		s = collapse(s)
+		for j, v := range d.values {
+			if collapse(v) == s {
+				i = j
+				break
+			}
+		}
+	}
+	if i < 0 {
+		return nil, false
+	}
+	return func(w *bitWriter) { w.bits(d.bits, uint64(i)) }, true
+}
+
+// digits reports whether s is a non-empty run of ASCII digits.
+func digits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := range len(s) {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// reverseDigits returns the digits of s in reverse order, as EXI writes
+// fractional digits so that trailing zeros do not count.
+func reverseDigits(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+func splitSign(s string) (string, bool) {
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		return rest, true
+	}
+	return strings.TrimPrefix(s, "+"), false
+}
+
+func prepareDecimal(s string) (writer, bool) {
+	s, neg := splitSign(s)
+	ip, fp, _ := strings.Cut(s, ".")
+	if (ip != "" && !digits(ip)) || (fp != "" && !digits(fp)) || ip+fp == "" {
+		return nil, false
+	}
+	i, _ := new(big.Int).SetString("0"+ip, 10)
+	f, _ := new(big.Int).SetString("0"+reverseDigits(fp), 10)
+	return func(w *bitWriter) {
+		w.boolean(neg)
+		w.unsignedBig(i)
+		w.unsignedBig(f)
+	}, true
+}
+
+func readDecimal(r *bitReader) string {
+	neg := r.boolean()
+	i := r.unsignedBig()
+	f := r.unsignedBig()
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	b.WriteString(i.String())
+	if f.Sign() != 0 {
+		b.WriteByte('.')
+		b.WriteString(reverseDigits(f.String()))
+	}
+	return b.String()
+}
+
+// floatSpecial is the exponent that marks INF, -INF and NaN.
+const floatSpecial = -(1 << 14)
+
+func (d *datatype) prepareFloat(s string) (writer, bool) {
+	var m, e int64
+	switch s {
+	case "INF", "+INF":
+		m, e = 1, floatSpecial
+	case "-INF":
+		m, e = -1, floatSpecial
+	case "NaN":
+		m, e = 0, floatSpecial
+	default:
+		if !floatLexical(s) {
+			return nil, false
+		}
+		f, err := strconv.ParseFloat(s, d.float)
+		if err != nil {
+			return nil, false
+		}
+		// The shortest decimal that reads back as the same value has at most
+		// 17 significant digits, which fit the 64-bit mantissa.
+		mant, exp, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, d.float), "e")
+		ip, fp, _ := strings.Cut(mant, ".")
+		fp = strings.TrimRight(fp, "0")
+		m, _ = strconv.ParseInt(ip+fp, 10, 64)
+		x, _ := strconv.ParseInt(exp, 10, 64)
+		e = x - int64(len(fp))
+		if e <= floatSpecial || e >= -floatSpecial {
+			return nil, false
+		}
+	}
+	return func(w *bitWriter) {
+		w.integer(m)
+		w.integer(e)
+	}, true
+}
+
+// floatLexical reports whether s is a decimal number with an optional
+// exponent, the finite part of the xs:float and xs:double lexical space.
+func floatLexical(s string) bool {
+	s, _ = splitSign(s)
+	mant, exp, hasExp := strings.Cut(strings.ToUpper(s), "E")
+	if hasExp {
+		x, _ := splitSign(exp)
+		if !digits(x) {
+			return false
+		}
+	}
+	ip, fp, _ := strings.Cut(mant, ".")
+	return (ip == "" || digits(ip)) && (fp == "" || digits(fp)) && ip+fp != ""
+}
+
+func readFloat(r *bitReader) string {
+	m := r.integer()
+	e := r.integer()
+	if e == floatSpecial {
+		switch m {
+		case 1:
+			return "INF"
+		case -1:
+			return "-INF"
+		}
+		return "NaN"
+	}
+	return strconv.FormatInt(m, 10) + "E" + strconv.FormatInt(e, 10)
+}
+
+func (d *datatype) prepareInteger(s string) (writer, bool) {
+	u, neg := splitSign(s)
+	if !digits(u) {
+		return nil, false
+	}
+	v, _ := new(big.Int).SetString(u, 10)
+	if neg {
+		v.Neg(v)
+	}
+	switch d.kind {
+	case dtUnsigned:
+		if v.Sign() < 0 {
+			return nil, false
+		}
+		return func(w *bitWriter) { w.unsignedBig(v) }, true
+	case dtNBit:
+		v.Sub(v, d.min)
+		if v.Sign() < 0 || v.BitLen() > d.bits {
+			return nil, false
+		}
+		n := v.Uint64()
+		return func(w *bitWriter) { w.bits(d.bits, n) }, true
+	}
+	return func(w *bitWriter) { w.integerBig(v) }, true
+}
+
+// dateValue holds the components of a date-time value. Absent components
+// are zero.
+type dateValue struct {
+	year     int64
+	month    int64
+	day      int64
+	time     int64  // (hour*64+minute)*64+second
+	frac     string // fractional seconds, without the dot
+	hasFrac  bool
+	tz       int64 // hour*64+minute, signed
+	hasTZ    bool
+	hasYear  bool
+	hasMonth bool
+	hasDay   bool
+	hasTime  bool
+}
+
+// tzOffset is added to a time zone to make it non-negative.
+const tzOffset = 896 // 14 hours
+
+func (d *datatype) prepareDateTime(s string) (writer, bool) {
+	v, ok := parseDate(d.date, s)
+	if !ok {
+		return nil, false
+	}
+	return func(w *bitWriter) {
+		if v.hasYear {
+			w.integer(v.year - 2000)
+		}
+		if v.hasMonth || v.hasDay {
+			w.bits(9, uint64(v.month*32+v.day))
+		}
+		if v.hasTime {
+			w.bits(17, uint64(v.time))
+			w.boolean(v.hasFrac)
+			if v.hasFrac {
+				f, _ := new(big.Int).SetString(reverseDigits(v.frac), 10)
+				w.unsignedBig(f)
+			}
+		}
+		w.boolean(v.hasTZ)
+		if v.hasTZ {
+			w.bits(11, uint64(v.tz+tzOffset))
+		}
+	}, true
+}
+
+func (d *datatype) readDateTime(r *bitReader) string {
+	var b strings.Builder
+	var month, day int64
+	hasYear := d.date <= dateDateTime
+	hasMonthDay := d.date != dateGYear && d.date != dateTime
+	if hasYear {
+		y := r.integer() + 2000
+		if y < 0 {
+			fmt.Fprintf(&b, "-%04d", -y)
+		} else {
+			fmt.Fprintf(&b, "%04d", y)
+		}
+	}
+	if hasMonthDay {
+		md := int64(r.bits(9))
+		month, day = md/32, md%32
+	}
+	switch d.date {
+	case dateGYearMonth:
+		fmt.Fprintf(&b, "-%02d", month)
+	case dateDate, dateDateTime:
+		fmt.Fprintf(&b, "-%02d-%02d", month, day)
+	case dateGMonth:
+		fmt.Fprintf(&b, "--%02d", month)
+	case dateGMonthDay:
+		fmt.Fprintf(&b, "--%02d-%02d", month, day)
+	case dateGDay:
+		fmt.Fprintf(&b, "---%02d", day)
+	}
+	if d.date == dateDateTime || d.date == dateTime {
+		if d.date == dateDateTime {
+			b.WriteByte('T')
+		}
+		t := int64(r.bits(17))
+		fmt.Fprintf(&b, "%02d:%02d:%02d", t/4096, t/64%64, t%64)
+		if r.boolean() {
+			b.WriteByte('.')
+			b.WriteString(reverseDigits(r.unsignedBig().String()))
+		}
+	}
+	if r.boolean() {
+		tz := int64(r.bits(11)) - tzOffset
+		switch {
+		case tz == 0:
+			b.WriteByte('Z')
+		case tz < 0:
+			fmt.Fprintf(&b, "-%02d:%02d", -tz/64, -tz%64)
+		default:
+			fmt.Fprintf(&b, "+%02d:%02d", tz/64, tz%64)
+		}
+	}
+	return b.String()
+}
+
+// parseDate parses the lexical form of a date-time type of the given kind.
+func parseDate(kind dateKind, s string) (dateValue, bool) {
+	var v dateValue
+	rest := s
+	num := func(n int) (int64, bool) {
+		if len(rest) < n || !digits(rest[:n]) {
+			return 0, false
+		}
+		x, _ := strconv.ParseInt(rest[:n], 10, 64)
+		rest = rest[n:]
+		return x, true
+	}
+	lit := func(c string) bool {
+		var ok bool
+		rest, ok = strings.CutPrefix(rest, c)
+		return ok
+	}
+
+	var ok bool
+	if kind <= dateDateTime {
+		neg := lit("-")
+		end := 0
+		for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+			end++
+		}
+		if end < 4 || (end > 4 && rest[0] == '0') {
+			return v, false
+		}
+		if v.year, ok = num(end); !ok {
+			return v, false
+		}
+		if neg {
+			v.year = -v.year
+		}
+		v.hasYear = true
+	}
+	switch kind {
+	case dateGYearMonth:
+		if !lit("-") {
+			return v, false
+		}
+		if v.month, ok = num(2); !ok {
+			return v, false
+		}
+		v.hasMonth = true
+	case dateDate, dateDateTime:
+		if !lit("-") {
+			return v, false
+		}
+		if v.month, ok = num(2); !ok || !lit("-") {
+			return v, false
+		}
+		if v.day, ok = num(2); !ok {
+			return v, false
+		}
+		v.hasMonth, v.hasDay = true, true
+		if kind == dateDateTime && !lit("T") {
+			return v, false
+		}
+	case dateGMonth:
+		if !lit("--") {
+			return v, false
+		}
+		if v.month, ok = num(2); !ok {
+			return v, false
+		}
+		v.hasMonth = true
+	case dateGMonthDay:
+		if !lit("--") {
+			return v, false
+		}
+		if v.month, ok = num(2); !ok || !lit("-") {
+			return v, false
+		}
+		if v.day, ok = num(2); !ok {
+			return v, false
+		}
+		v.hasMonth, v.hasDay = true, true
+	case dateGDay:
+		if !lit("---") {
+			return v, false
+		}
+		if v.day, ok = num(2); !ok {
+			return v, false
+		}
+		v.hasDay = true
+	}
+	if v.hasMonth && (v.month < 1 || v.month > 12) {
+		return v, false
+	}
+	if v.hasDay && (v.day < 1 || v.day > 31) {
+		return v, false
+	}
+	if kind == dateDateTime || kind == dateTime {
+		h, ok1 := num(2)
+		ok2 := lit(":")
+		m, ok3 := num(2)
+		ok4 := lit(":")
+		sec, ok5 := num(2)
+		if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || h > 24 || m > 59 || sec > 60 {
+			return v, false
+		}
+		v.time = (h*64+m)*64 + sec
+		v.hasTime = true
+		if lit(".") {
+			end := 0
+			for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+				end++
+			}
+			if end == 0 {
+				return v, false
+			}
+			v.frac, rest = rest[:end], rest[end:]
+			v.hasFrac = true
+		}
+	}
+	switch {
+	case rest == "":
+	case rest == "Z":
+		v.hasTZ = true
+	case len(rest) == 6 && (rest[0] == '+' || rest[0] == '-') && rest[3] == ':':
+		sign := rest[0]
+		rest = rest[1:]
+		h, ok1 := num(2)
+		lit(":")
+		m, ok2 := num(2)
+		if !ok1 || !ok2 || h > 14 || m > 59 || (h == 14 && m != 0) {
+			return v, false
+		}
+		v.tz = h*64 + m
+		if sign == '-' {
+			v.tz = -v.tz
+		}
+		v.hasTZ = true
+	default:
+		return v, false
+	}
+	return v, true
+}
@@ -0,0 +1,423 @@
+package exi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/sax"
+	"github.com/lestrrat-go/helium/xsd"
+)
+
+type decoderConfig struct {
+	options  *Options
+	schema   *xsd.Schema
+	schemaID *string
+}
+
+// Decoder reads EXI streams.
+//
+// It uses clone-on-write semantics: each builder method returns a new
+// Decoder sharing the underlying config until mutation.
+type Decoder struct {
+	cfg *decoderConfig
+}
+
+// NewDecoder creates a Decoder with no schema that takes its options from
+// the stream header.
+func NewDecoder() Decoder {
+	return Decoder{cfg: &decoderConfig{}}
+}
+
+func (d Decoder) clone() Decoder {
+	if d.cfg == nil {
+		return NewDecoder()
+	}
+	cp := *d.cfg
+	return Decoder{cfg: &cp}
+}
+
+func (d Decoder) config() *decoderConfig {
+	if d.cfg == nil {
+		return NewDecoder().cfg
+	}
+	return d.cfg
+}
+
+// Options sets the options of streams whose header does not record them.
+// Options recorded in the header take precedence.
+func (d Decoder) Options(o Options) Decoder {
+	d = d.clone()
+	d.cfg.options = &o
+	return d
+}
+
+// Schema sets the schema of streams encoded with schema-informed grammars.
+func (d Decoder) Schema(s *xsd.Schema) Decoder {
+	d = d.clone()
+	d.cfg.schema = s
+	return d
+}
+
+// SchemaID sets the identifier of the schema given to [Decoder.Schema].
+// A stream whose header names a different schema is rejected with
+// [ErrSchemaMismatch].
+func (d Decoder) SchemaID(id string) Decoder {
+	d = d.clone()
+	d.cfg.schemaID = &id
+	return d
+}
+
+// Decode reads an EXI stream from r and reports the document as SAX events
+// to h. Namespace prefixes are those of the original document when the
+// stream preserves them; otherwise Decode makes up its own and declares
+// them where they are first needed.
+func (d Decoder) Decode(ctx context.Context, r io.Reader, h sax.SAX2Handler) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return d.config().decode(ctx, r, &saxSink{h: h})
+}
+
+// DecodeDocument reads an EXI stream from r and returns the document.
+func (d Decoder) DecodeDocument(ctx context.Context, r io.Reader) (*helium.Document, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s := &domSink{}
+	if err := d.config().decode(ctx, r, s); err != nil {
+		return nil, err
+	}
+	if s.doc == nil {
+		return nil, fmt.Errorf("%w: no document", ErrMalformed)
+	}
+	return s.doc, nil
+}
+
+func (c *decoderConfig) decode(ctx context.Context, r io.Reader, s sink) error {
+	br := bufio.NewReader(r)
+	hr := newBitReader(br, false)
+	h, err := readHeader(ctx, hr)
+	if err != nil {
+		return err
+	}
+	o := h.options
+	if o == nil {
+		if c.options != nil {
+			o = c.options.config()
+		} else {
+			o = NewOptions().cfg
+		}
+		if err := o.check(); err != nil {
+			return err
+		}
+	}
+	schema, err := c.schemaFor(ctx, h.schema)
+	if err != nil {
+		return err
+	}
+
+	var body *bitReader
+	var blocks *blockReader
+	switch o.alignment {
+	case AlignmentBitPacked:
+		body = hr
+	case AlignmentByteAligned:
+		body = newBitReader(br, true)
+	default:
+		blocks = newBlockReader(br, o)
+	}
+	st := newStringTable(o, schema)
+	disp := newDispatcher(s, st)
+	disp.prefixes = o.has(PreservePrefixes)
+	dec := newBodyDecoder(o, newGrammars(o, schema), st, body, blocks, disp)
+	return dec.run(ctx)
+}
+
+// schemaFor returns the schema a stream is decoded with.
+func (c *decoderConfig) schemaFor(ctx context.Context, id schemaID) (*xsd.Schema, error) {
+	switch {
+	case !id.present:
+		return c.schema, nil
+	case id.nil:
+		return nil, nil
+	case id.value == "":
+		return emptySchema(ctx)
+	case c.schema == nil:
+		return nil, fmt.Errorf("%w: the stream needs schema %q", ErrSchemaMismatch, id.value)
+	case c.schemaID != nil && *c.schemaID != id.value:
+		return nil, fmt.Errorf("%w: the stream needs schema %q, not %q", ErrSchemaMismatch, id.value, *c.schemaID)
+	}
+	return c.schema, nil
+}
+
+// bodyDecoder reads the events of a body through the grammars.
+type bodyDecoder struct {
+	opts   *optionsConfig
+	g      *grammars
+	st     *stringTable
+	r      *bitReader
+	blocks *blockReader // nil unless values are channelled
+	disp   *dispatcher
+	doc    *state
+	stack  []*frame
+	queue  []*event // events of the current block
+}
+
+func newBodyDecoder(o *optionsConfig, g *grammars, st *stringTable, r *bitReader, blocks *blockReader, disp *dispatcher) *bodyDecoder {
+	if disp.st == nil {
+		disp.st = st
+	}
+	return &bodyDecoder{opts: o, g: g, st: st, r: r, blocks: blocks, disp: disp, doc: g.doc.document}
+}
+
+func (d *bodyDecoder) top() *frame {
+	if len(d.stack) == 0 {
+		return nil
+	}
+	return d.stack[len(d.stack)-1]
+}
+
+func (d *bodyDecoder) current() *state {
+	if f := d.top(); f != nil {
+		return f.state
+	}
+	return d.doc
+}
+
+func (d *bodyDecoder) advance(p *production) {
+	if f := d.top(); f != nil {
+		f.state = p.next
+		return
+	}
+	d.doc = p.next
+}
+
+// emit passes ev on, or queues it until the values of its block are read.
+func (d *bodyDecoder) emit(ctx context.Context, ev *event) error {
+	if d.blocks != nil {
+		d.queue = append(d.queue, ev)
+		return nil
+	}
+	return d.disp.dispatch(ctx, ev)
+}
+
+// value reads a value into *dst, or arranges for it to be read with the
+// values of its block. It reports whether the value completes the block.
+func (d *bodyDecoder) value(qn qname, dt *datatype, dst *string) bool {
+	if d.blocks == nil {
+		*dst = readValue(d.r, d.st, qn, dt)
+		return false
+	}
+	return d.blocks.add(valueSlot{qn: qn, dt: dt, s: dst})
+}
+
+// endBlock reads the values of the current block and passes its events on.
+func (d *bodyDecoder) endBlock(ctx context.Context) error {
+	if err := d.blocks.finish(d.r, d.st); err != nil {
+		return malformed(err)
+	}
+	for _, ev := range d.queue {
+		if err := d.disp.dispatch(ctx, ev); err != nil {
+			return err
+		}
+	}
+	d.queue = d.queue[:0]
+	return nil
+}
+
+// malformed reports a failure to read the stream as ErrMalformed.
+func malformed(err error) error {
+	if errors.Is(err, ErrMalformed) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrMalformed, err)
+}
+
+func (d *bodyDecoder) run(ctx context.Context) error {
+	if d.blocks != nil {
+		d.r = d.blocks.begin()
+	}
+	for n := 0; ; n++ {
+		if n%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		done, full, err := d.step(ctx)
+		if err != nil {
+			return err
+		}
+		if d.r.err != nil {
+			return malformed(d.r.err)
+		}
+		if d.blocks != nil && (full || done) {
+			if err := d.endBlock(ctx); err != nil {
+				return err
+			}
+			if !done {
+				d.r = d.blocks.begin()
+			}
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// step decodes one event. It reports whether the event ends the document
+// and whether its value completes a block.
+func (d *bodyDecoder) step(ctx context.Context) (bool, bool, error) {
+	s := d.current()
+	f := d.top()
+	p, level := s.readCode(d.r)
+	if p == nil {
+		return false, false, nil
+	}
+	r, st := d.r, d.st
+	ev := &event{kind: p.kind}
+	full := false
+	switch p.kind {
+	case evSD:
+		d.advance(p)
+	case evED:
+		d.advance(p)
+		return true, false, d.emit(ctx, ev)
+	case evSE, evSEURI, evSEAny:
+		var part *uriPartition
+		switch p.kind {
+		case evSE:
+			ev.qn = p.qn
+			part = st.partition(p.qn.uri)
+		case evSEURI:
+			part = st.partition(p.uri)
+			if part == nil {
+				part = st.addURI(p.uri)
+			}
+			ev.qn = qname{uri: p.uri, local: st.readLocal(r, part)}
+		default:
+			ev.qn, part = st.readQName(r)
+		}
+		if part == nil {
+			return false, false, nil
+		}
+		if d.opts.has(PreservePrefixes) {
+			ev.prefix, ev.hasPrefix = st.readPrefix(r, part)
+		}
+		ev.kind = evSE
+		if f != nil && f.builtin != nil && p.kind == evSEAny && level > 0 {
+			s.learn(&production{kind: evSE, qn: ev.qn, next: p.next})
+		}
+		d.advance(p)
+		d.stack = append(d.stack, d.g.elementFrame(ev.qn, p.decl))
+	case evEE:
+		if f == nil {
+			return false, false, fmt.Errorf("%w: end of element with no element open", ErrMalformed)
+		}
+		if f.builtin != nil && level > 0 && s == f.builtin.startTag {
+			s.learn(&production{kind: evEE})
+		}
+		d.stack = d.stack[:len(d.stack)-1]
+	case evNS:
+		part := st.readURI(r)
+		if part == nil {
+			return false, false, nil
+		}
+		ev.value = part.uri
+		ev.prefix = st.readNSPrefix(r, part)
+		ev.local = r.boolean()
+		d.advance(p)
+	case evAT, evATURI, evATAny, evATUntyped:
+		var part *uriPartition
+		switch p.kind {
+		case evAT:
+			ev.qn = p.qn
+			part = st.partition(p.qn.uri)
+		case evATURI:
+			part = st.partition(p.uri)
+			if part == nil {
+				part = st.addURI(p.uri)
+			}
+			ev.qn = qname{uri: p.uri, local: st.readLocal(r, part)}
+		default:
+			ev.qn, part = st.readQName(r)
+		}
+		if part == nil {
+			return false, false, nil
+		}
+		if d.opts.has(PreservePrefixes) {
+			ev.prefix, ev.hasPrefix = st.readPrefix(r, part)
+		}
+		ev.kind = evAT
+		full = d.value(ev.qn, d.valueType(f, p, ev.qn), &ev.value)
+		if f.builtin != nil && p.kind == evATAny && level > 0 {
+			s.learn(&production{kind: evAT, qn: ev.qn, next: p.next})
+		}
+		d.advance(p)
+	case evATType:
+		ev.qn = qnXSIType
+		if d.opts.has(PreservePrefixes) {
+			ev.prefix, ev.hasPrefix = st.readPrefix(r, st.partition(lexicon.NamespaceXSI))
+		}
+		var part *uriPartition
+		ev.typ, part = st.readQName(r)
+		if part == nil {
+			return false, false, nil
+		}
+		if d.opts.has(PreservePrefixes) {
+			ev.typPrefix, ev.hasTypPrefix = st.readPrefix(r, part)
+		}
+		d.advance(p)
+		d.g.typeSwitch(f, ev.typ)
+	case evATNil:
+		ev.kind = evAT
+		ev.qn = qnXSINil
+		if d.opts.has(PreservePrefixes) {
+			ev.prefix, ev.hasPrefix = st.readPrefix(r, st.partition(lexicon.NamespaceXSI))
+		}
+		v := r.boolean()
+		ev.value = "false"
+		d.advance(p)
+		if v {
+			ev.value = "true"
+			f.state = d.g.typeGrammar(f.typ, f.decl != nil && f.decl.nillable, true)
+		}
+	case evCH, evCHUntyped:
+		ev.kind = evCH
+		full = d.value(f.qn, p.dt, &ev.value)
+		if f.builtin != nil && level > 0 {
+			s.learn(&production{kind: evCH, next: p.next})
+		}
+		d.advance(p)
+	case evCM, evER:
+		ev.value = r.str()
+		d.advance(p)
+	case evPI:
+		ev.strs = []string{r.str(), r.str()}
+		d.advance(p)
+	case evDT:
+		ev.strs = []string{r.str(), r.str(), r.str(), r.str()}
+		d.advance(p)
+	default:
+		return false, false, fmt.Errorf("%w: unexpected event", ErrMalformed)
+	}
+	if r.err != nil {
+		return false, false, nil
+	}
+	return false, full, d.emit(ctx, ev)
+}
+
+// valueType is the decoding counterpart of [bodyEncoder.valueType].
+func (d *bodyDecoder) valueType(f *frame, p *production, qn qname) *datatype {
+	if p.kind == evAT {
+		return p.dt
+	}
+	if f.builtin != nil || p.kind == evATUntyped {
+		return nil
+	}
+	return d.g.globalAttrType(qn)
+}
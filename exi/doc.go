@@ -0,0 +1,50 @@
+// Package exi implements the W3C Efficient XML Interchange (EXI) Format 1.0,
+// a compact binary encoding of the XML Information Set.
+//
+// # Encoding
+//
+// Use [NewEncoder] to write a document as an EXI stream:
+//
+//	err := exi.NewEncoder().
+//	    Options(exi.NewOptions().Alignment(exi.AlignmentCompression)).
+//	    Encode(ctx, w, doc)
+//
+// [Encoder.Handler] returns a [sax.SAX2Handler] that encodes the events it
+// receives, so a document can be encoded as it is parsed, without building
+// a tree.
+//
+// # Decoding
+//
+// Use [NewDecoder] to read an EXI stream, either as SAX events delivered to
+// a handler or as a [helium.Document]:
+//
+//	doc, err := exi.NewDecoder().DecodeDocument(ctx, r)
+//
+// # Options
+//
+// [Options] select the alignment of the body (bit-packed, byte-aligned,
+// pre-compression or compression), the parts of the document to preserve
+// (comments, processing instructions, the DTD, namespace prefixes and the
+// lexical form of typed values), strict mode, and the block size and value
+// table limits. The encoder records them in the header unless told not to,
+// in which case the decoder must be given the same options.
+//
+// # Schema-informed Grammars
+//
+// Given a compiled [xsd.Schema], both sides use grammars derived from it:
+// declared elements and attributes are encoded as short event codes, and
+// typed values in their binary representations. In strict mode, only
+// documents valid against the schema can be encoded, and the streams are
+// smaller still. [Encoder.SchemaID] records an identifier for the schema in
+// the header, which [Decoder.SchemaID] checks.
+//
+// Self-contained elements, datatype representation maps and EXI fragments
+// are not supported and are reported as [ErrUnsupported]. Interoperability
+// with other EXI implementations has not been tested yet.
+//
+// # Examples
+//
+// Example code for this package lives in the examples/ directory at the
+// repository root (files prefixed with exi_). Because examples are in
+// a separate test module they do not appear in the generated documentation.
+package exi
@@ -0,0 +1,576 @@
+package exi
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/xsd"
+)
+
+type encoderConfig struct {
+	options        Options
+	schema         *xsd.Schema
+	schemaID       *string
+	includeOptions bool
+	cookie         bool
+}
+
+// Encoder writes XML documents as EXI streams.
+//
+// It uses clone-on-write semantics: each builder method returns a new
+// Encoder sharing the underlying config until mutation.
+type Encoder struct {
+	cfg *encoderConfig
+}
+
+// NewEncoder creates an Encoder with the default options and no schema.
+func NewEncoder() Encoder {
+	return Encoder{cfg: &encoderConfig{options: NewOptions(), includeOptions: true}}
+}
+
+func (e Encoder) clone() Encoder {
+	if e.cfg == nil {
+		return NewEncoder()
+	}
+	cp := *e.cfg
+	return Encoder{cfg: &cp}
+}
+
+func (e Encoder) config() *encoderConfig {
+	if e.cfg == nil {
+		return NewEncoder().cfg
+	}
+	return e.cfg
+}
+
+// Options sets the EXI options of the streams the Encoder writes.
+func (e Encoder) Options(o Options) Encoder {
+	e = e.clone()
+	e.cfg.options = o
+	return e
+}
+
+// Schema makes the Encoder use schema-informed grammars derived from s.
+// Documents that follow the schema encode to smaller streams, and the
+// values of typed elements and attributes are written in binary. A decoder
+// needs the same schema to read them.
+func (e Encoder) Schema(s *xsd.Schema) Encoder {
+	e = e.clone()
+	e.cfg.schema = s
+	return e
+}
+
+// SchemaID sets the schema identifier recorded in the stream header, so
+// that a [Decoder] can check it was given the schema the stream was
+// encoded with. It has no effect without [Encoder.Schema].
+func (e Encoder) SchemaID(id string) Encoder {
+	e = e.clone()
+	e.cfg.schemaID = &id
+	return e
+}
+
+// IncludeOptions controls whether the header records the options. When it
+// does not, the [Decoder] must be configured with the same options.
+// Default: true
+func (e Encoder) IncludeOptions(v bool) Encoder {
+	e = e.clone()
+	e.cfg.includeOptions = v
+	return e
+}
+
+// Cookie controls whether streams start with the "$EXI" signature.
+// Default: false
+func (e Encoder) Cookie(v bool) Encoder {
+	e = e.clone()
+	e.cfg.cookie = v
+	return e
+}
+
+// Encode writes doc to w as an EXI stream.
+func (e Encoder) Encode(ctx context.Context, w io.Writer, doc *helium.Document) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if doc == nil {
+		return fmt.Errorf("exi: %w", helium.ErrNilNode)
+	}
+	h := e.Handler(w)
+	if err := helium.EmitSAX(ctx, doc, h); err != nil {
+		return err
+	}
+	return h.err
+}
+
+// Handler returns a SAX handler that writes the document whose events it
+// receives to w as an EXI stream. The stream is complete once the handler
+// has received EndDocument. The handler can be given to a
+// [helium.Parser] to encode a document as it is parsed, without building a
+// tree.
+func (e Encoder) Handler(w io.Writer) *Handler {
+	cfg := e.config()
+	h := newHandler()
+	h.begin = func(ctx context.Context) (*bodyEncoder, error) {
+		return cfg.start(ctx, w)
+	}
+	return h
+}
+
+// start writes the header to w and returns the encoder of the body.
+func (c *encoderConfig) start(ctx context.Context, w io.Writer) (*bodyEncoder, error) {
+	o := c.options.config()
+	if err := o.check(); err != nil {
+		return nil, err
+	}
+	bw := bufio.NewWriter(w)
+	hw := newBitWriter(bw, false)
+	h := &header{cookie: c.cookie}
+	if c.includeOptions {
+		h.options = o
+		if c.schema != nil && c.schemaID != nil {
+			h.schema = schemaID{present: true, value: *c.schemaID}
+		}
+	}
+	if err := writeHeader(ctx, hw, h); err != nil {
+		return nil, err
+	}
+
+	var body *bitWriter
+	var blocks *blockWriter
+	switch o.alignment {
+	case AlignmentBitPacked:
+		body = hw
+	case AlignmentByteAligned:
+		body = newBitWriter(bw, true)
+	default:
+		blocks = newBlockWriter(bw, o)
+		body = blocks.structure
+	}
+	enc := newBodyEncoder(o, newGrammars(o, c.schema), newStringTable(o, c.schema), body, blocks)
+	enc.out = bw
+	return enc, nil
+}
+
+// encodeDocument writes doc with e, which has no header to write.
+func encodeDocument(ctx context.Context, e *bodyEncoder, doc *helium.Document) error {
+	h := newHandler()
+	h.begin = func(context.Context) (*bodyEncoder, error) { return e, nil }
+	if err := helium.EmitSAX(ctx, doc, h); err != nil {
+		return err
+	}
+	return h.err
+}
+
+// frame is an open element.
+type frame struct {
+	qn      qname
+	state   *state
+	builtin *builtinGrammar // nil for schema-informed grammars
+	typ     *xsd.TypeDef
+	decl    *elementDecl
+}
+
+// bodyEncoder writes the events of a body through the grammars.
+type bodyEncoder struct {
+	opts   *optionsConfig
+	g      *grammars
+	st     *stringTable
+	w      *bitWriter
+	blocks *blockWriter // nil unless values are channelled
+	out    *bufio.Writer
+	doc    *state
+	stack  []*frame
+}
+
+func newBodyEncoder(o *optionsConfig, g *grammars, st *stringTable, w *bitWriter, blocks *blockWriter) *bodyEncoder {
+	return &bodyEncoder{opts: o, g: g, st: st, w: w, blocks: blocks, doc: g.doc.document}
+}
+
+func (e *bodyEncoder) top() *frame {
+	if len(e.stack) == 0 {
+		return nil
+	}
+	return e.stack[len(e.stack)-1]
+}
+
+// current returns the state the next event is matched against.
+func (e *bodyEncoder) current() *state {
+	if f := e.top(); f != nil {
+		return f.state
+	}
+	return e.doc
+}
+
+// advance moves past production p.
+func (e *bodyEncoder) advance(p *production) {
+	if f := e.top(); f != nil {
+		f.state = p.next
+		return
+	}
+	e.doc = p.next
+}
+
+func notEncodable(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrNotEncodable}, args...)...)
+}
+
+// code finds the production match accepts in the current state and writes
+// its event code.
+func (e *bodyEncoder) code(what string, match func(*production) bool) (*production, int, error) {
+	s := e.current()
+	p, level, i := s.find(match)
+	if p == nil {
+		return nil, 0, notEncodable("%s is not allowed here", what)
+	}
+	s.writeCode(e.w, level, i)
+	return p, level, nil
+}
+
+func (e *bodyEncoder) startDocument() error {
+	p, _, err := e.code("start of document", func(p *production) bool { return p.kind == evSD })
+	if err != nil {
+		return err
+	}
+	e.advance(p)
+	return e.w.err
+}
+
+func (e *bodyEncoder) endDocument() error {
+	p, _, err := e.code("end of document", func(p *production) bool { return p.kind == evED })
+	if err != nil {
+		return err
+	}
+	e.advance(p)
+	if e.blocks != nil {
+		if err := e.blocks.flush(e.st); err != nil {
+			return err
+		}
+	}
+	if e.out == nil {
+		// The options document of a header, which the body follows.
+		return e.w.err
+	}
+	e.w.align()
+	if e.w.err != nil {
+		return e.w.err
+	}
+	return e.out.Flush()
+}
+
+func (e *bodyEncoder) doctype(name, publicID, systemID, text string) error {
+	p, _, err := e.code("document type declaration", func(p *production) bool { return p.kind == evDT })
+	if err != nil {
+		return err
+	}
+	e.w.str(name)
+	e.w.str(publicID)
+	e.w.str(systemID)
+	e.w.str(text)
+	e.advance(p)
+	return e.w.err
+}
+
+func (e *bodyEncoder) startElement(qn qname, prefix string) error {
+	f := e.top()
+	p, level, err := e.code("element "+qn.local, func(p *production) bool {
+		switch p.kind {
+		case evSE:
+			return p.qn == qn
+		case evSEURI:
+			return p.uri == qn.uri
+		case evSEAny:
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	var part *uriPartition
+	switch p.kind {
+	case evSE:
+		part = e.st.partition(qn.uri)
+	case evSEURI:
+		part = e.st.partition(qn.uri)
+		e.st.writeLocal(e.w, part, qn.local)
+	default:
+		part = e.st.writeQName(e.w, qn)
+	}
+	if e.opts.has(PreservePrefixes) {
+		e.st.writePrefix(e.w, part, prefix)
+	}
+	if f != nil && f.builtin != nil && p.kind == evSEAny && level > 0 {
+		e.current().learn(&production{kind: evSE, qn: qn, next: p.next})
+	}
+	e.advance(p)
+	e.stack = append(e.stack, e.g.elementFrame(qn, p.decl))
+	return e.w.err
+}
+
+// elementFrame returns the frame of an element started by an SE production
+// of decl, or by a wildcard when decl is nil.
+func (g *grammars) elementFrame(qn qname, decl *elementDecl) *frame {
+	if decl == nil {
+		decl = g.globals[qn]
+	}
+	if decl == nil {
+		b := g.builtinFor(qn)
+		return &frame{qn: qn, state: b.startTag, builtin: b}
+	}
+	return &frame{qn: qn, state: g.typeGrammar(decl.typ, decl.nillable, false), typ: decl.typ, decl: decl}
+}
+
+func (e *bodyEncoder) namespace(uri, prefix string, local bool) error {
+	p, _, err := e.code("namespace declaration", func(p *production) bool { return p.kind == evNS })
+	if err != nil {
+		return err
+	}
+	part := e.st.writeURI(e.w, uri)
+	e.st.writeNSPrefix(e.w, part, prefix)
+	e.w.boolean(local)
+	e.advance(p)
+	return e.w.err
+}
+
+// xsiType writes an xsi:type attribute whose value is the type tqn, and
+// switches the element to the grammar of that type.
+func (e *bodyEncoder) xsiType(tqn qname, prefix string) error {
+	f := e.top()
+	p, _, err := e.code("xsi:type", func(p *production) bool { return p.kind == evATType })
+	if err != nil {
+		return err
+	}
+	if e.opts.has(PreservePrefixes) {
+		e.st.writePrefix(e.w, e.st.partition(lexicon.NamespaceXSI), "xsi")
+	}
+	part := e.st.writeQName(e.w, tqn)
+	if e.opts.has(PreservePrefixes) {
+		e.st.writePrefix(e.w, part, prefix)
+	}
+	e.advance(p)
+	e.g.typeSwitch(f, tqn)
+	return e.w.err
+}
+
+// typeSwitch moves f to the grammar of the type named by an xsi:type
+// attribute. Unknown types leave it where it is.
+func (g *grammars) typeSwitch(f *frame, tqn qname) {
+	td := g.lookupType(tqn)
+	if td == nil {
+		return
+	}
+	f.typ = td
+	f.state = g.typeGrammar(td, f.decl != nil && f.decl.nillable, false)
+}
+
+// xsiNil writes an xsi:nil attribute. A true value leaves the element
+// with the grammar of its type that accepts no content.
+func (e *bodyEncoder) xsiNil(v bool) error {
+	f := e.top()
+	p, _, err := e.code("xsi:nil", func(p *production) bool { return p.kind == evATNil })
+	if err != nil {
+		return err
+	}
+	if e.opts.has(PreservePrefixes) {
+		e.st.writePrefix(e.w, e.st.partition(lexicon.NamespaceXSI), "xsi")
+	}
+	e.w.boolean(v)
+	e.advance(p)
+	if v {
+		f.state = e.g.typeGrammar(f.typ, f.decl != nil && f.decl.nillable, true)
+	}
+	return e.w.err
+}
+
+// valueType returns the type of the value of an attribute matched by a
+// wildcard production: that of the global attribute of the same name in
+// schema-informed grammars, untyped otherwise.
+func (e *bodyEncoder) valueType(f *frame, p *production, qn qname) *datatype {
+	if p.kind == evAT {
+		return p.dt
+	}
+	if f.builtin != nil || p.kind == evATUntyped {
+		return nil
+	}
+	return e.g.globalAttrType(qn)
+}
+
+func (e *bodyEncoder) attribute(qn qname, prefix, value string) error {
+	f := e.top()
+	var wr writer
+	p, level, err := e.code("attribute "+qn.local, func(p *production) bool {
+		switch p.kind {
+		case evAT:
+			if p.qn != qn {
+				return false
+			}
+		case evATURI:
+			if p.uri != qn.uri {
+				return false
+			}
+		case evATAny, evATUntyped:
+		default:
+			return false
+		}
+		var ok bool
+		wr, ok = prepareValue(e.valueType(f, p, qn), value)
+		return ok
+	})
+	if err != nil {
+		return err
+	}
+	var part *uriPartition
+	switch p.kind {
+	case evAT:
+		part = e.st.partition(qn.uri)
+	case evATURI:
+		part = e.st.partition(qn.uri)
+		e.st.writeLocal(e.w, part, qn.local)
+	default:
+		part = e.st.writeQName(e.w, qn)
+	}
+	if e.opts.has(PreservePrefixes) {
+		e.st.writePrefix(e.w, part, prefix)
+	}
+	e.value(qn, value, wr)
+	if f.builtin != nil && p.kind == evATAny && level > 0 {
+		e.current().learn(&production{kind: evAT, qn: qn, next: p.next})
+	}
+	e.advance(p)
+	return e.w.err
+}
+
+// prepareValue returns the writer of a typed value, or nil for a value
+// that goes through the string table; ok is false when value is not a
+// value of dt.
+func prepareValue(dt *datatype, value string) (writer, bool) {
+	if dt == nil || dt.kind == dtString {
+		return nil, true
+	}
+	return dt.prepare(value)
+}
+
+// value writes a value, or adds it to its channel when values are
+// channelled, writing out the block it completes.
+func (e *bodyEncoder) value(qn qname, s string, wr writer) {
+	if e.blocks == nil {
+		writeValue(e.w, e.st, qn, s, wr)
+		return
+	}
+	if e.blocks.add(qn, s, wr) {
+		if err := e.blocks.flush(e.st); err != nil && e.w.err == nil {
+			e.w.err = err
+		}
+	}
+}
+
+func writeValue(w *bitWriter, st *stringTable, qn qname, s string, wr writer) {
+	if wr != nil {
+		wr(w)
+		return
+	}
+	st.writeValue(w, qn, s)
+}
+
+// characters writes character content. Whitespace a schema-informed
+// grammar has no place for is dropped.
+func (e *bodyEncoder) characters(s string) error {
+	f := e.top()
+	if f == nil {
+		return nil
+	}
+	if f.builtin == nil && !hasFirst(f.state, evCH) && strings.TrimLeft(s, " \t\r\n") == "" {
+		return nil
+	}
+	var wr writer
+	p, level, err := e.code("character content", func(p *production) bool {
+		switch p.kind {
+		case evCH, evCHUntyped:
+			var ok bool
+			wr, ok = prepareValue(p.dt, s)
+			return ok
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	e.value(f.qn, s, wr)
+	if f.builtin != nil && level > 0 {
+		e.current().learn(&production{kind: evCH, next: p.next})
+	}
+	e.advance(p)
+	return e.w.err
+}
+
+func (e *bodyEncoder) endElement() error {
+	f := e.top()
+	if f == nil {
+		return notEncodable("end of element with no element open")
+	}
+	if s := f.state; f.builtin == nil && !hasFirst(s, evEE) {
+		// An element of a simple type left empty has an empty value, when
+		// the type allows one, rather than an undeclared end.
+		for i, p := range s.first {
+			if p.kind != evCH || !hasFirst(p.next, evEE) {
+				continue
+			}
+			if wr, ok := prepareValue(p.dt, ""); ok {
+				s.writeCode(e.w, 0, i)
+				e.value(f.qn, "", wr)
+				e.advance(p)
+				break
+			}
+		}
+	}
+	_, level, err := e.code("end of element "+f.qn.local, func(p *production) bool { return p.kind == evEE })
+	if err != nil {
+		return err
+	}
+	if f.builtin != nil && level > 0 && f.state == f.builtin.startTag {
+		f.state.learn(&production{kind: evEE})
+	}
+	e.stack = e.stack[:len(e.stack)-1]
+	return e.w.err
+}
+
+// hasFirst reports whether s has a first-level production of kind.
+func hasFirst(s *state, kind eventKind) bool {
+	return slices.ContainsFunc(s.first, func(p *production) bool { return p.kind == kind })
+}
+
+func (e *bodyEncoder) comment(s string) error {
+	p, _, err := e.code("comment", func(p *production) bool { return p.kind == evCM })
+	if err != nil {
+		return err
+	}
+	e.w.str(s)
+	e.advance(p)
+	return e.w.err
+}
+
+func (e *bodyEncoder) processingInstruction(target, data string) error {
+	p, _, err := e.code("processing instruction", func(p *production) bool { return p.kind == evPI })
+	if err != nil {
+		return err
+	}
+	e.w.str(target)
+	e.w.str(data)
+	e.advance(p)
+	return e.w.err
+}
+
+func (e *bodyEncoder) entityRef(name string) error {
+	p, _, err := e.code("entity reference", func(p *production) bool { return p.kind == evER })
+	if err != nil {
+		return err
+	}
+	e.w.str(name)
+	e.advance(p)
+	return e.w.err
+}
@@ -0,0 +1,31 @@
+package exi
+
+import "errors"
+
+// ErrNotEXI is returned by a [Decoder] when its input does not start with
+// an EXI header.
+var ErrNotEXI = errors.New("exi: not an EXI stream")
+
+// ErrMalformed is returned by a [Decoder] when its input is truncated or
+// does not follow the grammars the stream was encoded with.
+var ErrMalformed = errors.New("exi: malformed EXI stream")
+
+// ErrNotEncodable is returned by an [Encoder] for a document its grammars
+// cannot represent: in strict mode, a document that is not valid against the
+// schema; in any mode, markup the options do not preserve in a form EXI
+// cannot carry, such as an entity reference with no declared replacement
+// text.
+var ErrNotEncodable = errors.New("exi: document cannot be encoded")
+
+// ErrUnsupported is returned for EXI features this package does not
+// implement: self-contained elements, datatype representation maps, EXI
+// fragments, and EXI versions other than 1.
+var ErrUnsupported = errors.New("exi: unsupported EXI feature")
+
+// ErrSchemaMismatch is returned when a stream needs a schema the [Decoder]
+// was not given, or names a schema ID other than the one configured.
+var ErrSchemaMismatch = errors.New("exi: schema does not match the stream")
+
+// ErrInvalidOptions is returned for a combination of options EXI does not
+// allow, such as strict mode while comments are preserved.
+var ErrInvalidOptions = errors.New("exi: invalid options")
@@ -0,0 +1,324 @@
+package exi_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/exi"
+	"github.com/lestrrat-go/helium/sax"
+	"github.com/lestrrat-go/helium/xsd"
+	"github.com/stretchr/testify/require"
+)
+
+var alignments = []exi.Alignment{
+	exi.AlignmentBitPacked,
+	exi.AlignmentByteAligned,
+	exi.AlignmentPreCompression,
+	exi.AlignmentCompression,
+}
+
+const preserveAll = exi.PreserveComments | exi.PreservePIs | exi.PreserveDTD | exi.PreservePrefixes
+
+const orderSchema = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:t" xmlns:t="urn:t" elementFormDefault="qualified">
+  <xs:element name="order" type="t:Order"/>
+  <xs:complexType name="Order">
+    <xs:sequence>
+      <xs:element name="id" type="xs:int"/>
+      <xs:element name="when" type="xs:dateTime"/>
+      <xs:element name="item" maxOccurs="unbounded" type="t:Item"/>
+      <xs:element name="note" type="xs:string" minOccurs="0" nillable="true"/>
+      <xs:element name="total" type="xs:decimal"/>
+    </xs:sequence>
+    <xs:attribute name="status" type="t:Status" use="required"/>
+    <xs:attribute name="urgent" type="xs:boolean"/>
+  </xs:complexType>
+  <xs:complexType name="Item">
+    <xs:simpleContent>
+      <xs:extension base="xs:double">
+        <xs:attribute name="qty" type="t:Small"/>
+        <xs:attribute name="tags" type="t:Tags"/>
+      </xs:extension>
+    </xs:simpleContent>
+  </xs:complexType>
+  <xs:complexType name="SpecialItem">
+    <xs:simpleContent>
+      <xs:extension base="t:Item">
+        <xs:attribute name="code" type="xs:hexBinary"/>
+      </xs:extension>
+    </xs:simpleContent>
+  </xs:complexType>
+  <xs:simpleType name="Status">
+    <xs:restriction base="xs:string">
+      <xs:enumeration value="new"/>
+      <xs:enumeration value="done"/>
+    </xs:restriction>
+  </xs:simpleType>
+  <xs:simpleType name="Small">
+    <xs:restriction base="xs:integer">
+      <xs:minInclusive value="1"/>
+      <xs:maxInclusive value="100"/>
+    </xs:restriction>
+  </xs:simpleType>
+  <xs:simpleType name="Tags">
+    <xs:list itemType="xs:NCName"/>
+  </xs:simpleType>
+</xs:schema>`
+
+const orderInstance = `<order xmlns="urn:t" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:t="urn:t" status="done" urgent="1">` +
+	`<id>42</id>` +
+	`<when>2024-02-29T12:30:15.25+09:00</when>` +
+	`<item qty="3" tags="a b">1.5</item>` +
+	`<item xsi:type="t:SpecialItem" code="0aff">-2E10</item>` +
+	`<note xsi:nil="true"/>` +
+	`<total>12.50</total>` +
+	`</order>`
+
+func parse(t *testing.T, src string) *helium.Document {
+	t.Helper()
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+	return doc
+}
+
+func compileOrderSchema(t *testing.T) *xsd.Schema {
+	t.Helper()
+	schema, err := xsd.NewCompiler().Compile(t.Context(), parse(t, orderSchema))
+	require.NoError(t, err)
+	return schema
+}
+
+func roundTrip(t *testing.T, enc exi.Encoder, dec exi.Decoder, src string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, enc.Encode(t.Context(), &buf, parse(t, src)))
+	doc, err := dec.DecodeDocument(t.Context(), bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	s, err := helium.WriteString(doc)
+	require.NoError(t, err)
+	return s
+}
+
+func TestRoundTrip(t *testing.T) {
+	const src = `<?xml version="1.0"?>
+<!DOCTYPE r [
+<!ENTITY e "ent">
+<!ELEMENT r ANY>
+<!ATTLIST r a CDATA "d">
+]>
+<!--top-->
+<r xmlns="urn:x" xmlns:p="urn:p" p:q="1" z="2"><a>hello</a><a>hello</a><!--c--><?pi data?><b xml:lang="en">&e;</b><p:c/>tail<![CDATA[<x>]]></r>
+`
+	t.Run("preserve all", func(t *testing.T) {
+		want, err := helium.WriteString(parse(t, src))
+		require.NoError(t, err)
+		// EXI has no CDATA sections.
+		want = strings.Replace(want, "<![CDATA[<x>]]>", "&lt;x&gt;", 1)
+		for _, a := range alignments {
+			t.Run(a.String(), func(t *testing.T) {
+				o := exi.NewOptions().Alignment(a).Preserve(preserveAll)
+				got := roundTrip(t, exi.NewEncoder().Options(o), exi.NewDecoder(), src)
+				require.Equal(t, want, got)
+			})
+		}
+	})
+	t.Run("preserve nothing", func(t *testing.T) {
+		// Comments, PIs and the DTD are dropped, entity references are
+		// expanded, CDATA becomes text, and prefixes are made up.
+		const want = `<?xml version="1.0"?>
+<r xmlns="urn:x" xmlns:ns0="urn:p" ns0:q="1" z="2" a="d"><a>hello</a><a>hello</a><b xml:lang="en">ent</b><ns0:c/>tail&lt;x&gt;</r>
+`
+		for _, a := range alignments {
+			t.Run(a.String(), func(t *testing.T) {
+				o := exi.NewOptions().Alignment(a)
+				got := roundTrip(t, exi.NewEncoder().Options(o), exi.NewDecoder(), src)
+				require.Equal(t, want, got)
+			})
+		}
+	})
+}
+
+// TestVectors checks streams worked out by hand from the EXI 1.0
+// specification, bit by bit, rather than against the package's own output.
+// They cover the header, the built-in document and element grammars, the
+// string tables, grammar learning, and both bit-packed and byte-aligned
+// streams. No streams from other EXI implementations are included yet.
+func TestVectors(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		align exi.Alignment
+		want  []byte
+	}{
+		// 10 0 00000 | SE(*) in 0 bits, URI "" as 01, "a" as 00000010
+		// 01100001, EE as 00, ED in 0 bits.
+		{"empty element", `<a/>`, exi.AlignmentBitPacked, []byte{0x80, 0x40, 0x98, 0x40}},
+		// CH as 11, "x" missing from the value tables as 00000011 01111000,
+		// then EE as 0 in ElementContent.
+		{"character data", `<a>x</a>`, exi.AlignmentBitPacked, []byte{0x80, 0x40, 0x98, 0x70, 0x37, 0x80}},
+		{"empty element, byte-aligned", `<a/>`, exi.AlignmentByteAligned, []byte{0x80, 0x01, 0x02, 0x61, 0x00}},
+		{"character data, byte-aligned", `<a>x</a>`, exi.AlignmentByteAligned, []byte{0x80, 0x01, 0x02, 0x61, 0x03, 0x03, 0x78, 0x00}},
+		// AT(*) as 0.1, then EE as 1.0 once AT(b) has been learned.
+		{"attribute, byte-aligned", `<a b="c"/>`, exi.AlignmentByteAligned, []byte{0x80, 0x01, 0x02, 0x61, 0x01, 0x01, 0x02, 0x62, 0x03, 0x63, 0x01, 0x00}},
+		// The second b is a local-name hit (0, then compact ID 1 in one bit),
+		// and EE of a is 1 after SE(b) has been learned.
+		{"repeated element, byte-aligned", `<a><b/><b/></a>`, exi.AlignmentByteAligned, []byte{0x80, 0x01, 0x02, 0x61, 0x02, 0x01, 0x02, 0x62, 0x00, 0x01, 0x00, 0x01, 0x00, 0x01, 0x00, 0x01}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := exi.NewOptions().Alignment(tc.align)
+			var buf bytes.Buffer
+			require.NoError(t, exi.NewEncoder().Options(o).IncludeOptions(false).Encode(t.Context(), &buf, parse(t, tc.src)))
+			require.Equal(t, tc.want, buf.Bytes(), "encoded")
+
+			doc, err := exi.NewDecoder().Options(o).DecodeDocument(t.Context(), bytes.NewReader(tc.want))
+			require.NoError(t, err)
+			got, err := helium.WriteString(doc.DocumentElement())
+			require.NoError(t, err)
+			require.Equal(t, tc.src, got, "decoded")
+		})
+	}
+	t.Run("cookie", func(t *testing.T) {
+		doc, err := exi.NewDecoder().DecodeDocument(t.Context(), bytes.NewReader([]byte("$EXI\x80\x40\x98\x40")))
+		require.NoError(t, err)
+		got, err := helium.WriteString(doc.DocumentElement())
+		require.NoError(t, err)
+		require.Equal(t, `<a/>`, got)
+	})
+}
+
+func TestSchemaInformed(t *testing.T) {
+	schema := compileOrderSchema(t)
+
+	// Typed values come back in their canonical form unless lexical values
+	// are preserved.
+	const canonical = `<?xml version="1.0"?>
+<order xmlns="urn:t" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:t="urn:t" status="done" urgent="true">` +
+		`<id>42</id><when>2024-02-29T12:30:15.25+09:00</when><item qty="3" tags="a b">15E-1</item>` +
+		`<item xsi:type="t:SpecialItem" code="0AFF">-2E10</item><note xsi:nil="true"/><total>12.5</total></order>
+`
+	const lexical = `<?xml version="1.0"?>
+<order xmlns="urn:t" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:t="urn:t" status="done" urgent="1">` +
+		`<id>42</id><when>2024-02-29T12:30:15.25+09:00</when><item qty="3" tags="a b">1.5</item>` +
+		`<item xsi:type="t:SpecialItem" code="0aff">-2E10</item><note xsi:nil="true"/><total>12.50</total></order>
+`
+	for _, strict := range []bool{false, true} {
+		for _, a := range alignments {
+			t.Run(fmt.Sprintf("strict=%t/%s", strict, a), func(t *testing.T) {
+				o := exi.NewOptions().Alignment(a).Strict(strict).Preserve(exi.PreservePrefixes)
+				enc := exi.NewEncoder().Options(o).Schema(schema).SchemaID("order")
+				dec := exi.NewDecoder().Schema(schema)
+				require.Equal(t, canonical, roundTrip(t, enc, dec, orderInstance))
+
+				o = o.Preserve(exi.PreservePrefixes | exi.PreserveLexicalValues)
+				require.Equal(t, lexical, roundTrip(t, enc.Options(o), dec, orderInstance))
+			})
+		}
+	}
+
+	t.Run("smaller than schema-less", func(t *testing.T) {
+		var informed, plain bytes.Buffer
+		require.NoError(t, exi.NewEncoder().Schema(schema).Encode(t.Context(), &informed, parse(t, orderInstance)))
+		require.NoError(t, exi.NewEncoder().Encode(t.Context(), &plain, parse(t, orderInstance)))
+		require.Less(t, informed.Len(), plain.Len())
+	})
+
+	t.Run("undeclared content", func(t *testing.T) {
+		const src = `<order xmlns="urn:t" status="done"><bogus/></order>`
+		strict := exi.NewEncoder().Options(exi.NewOptions().Strict(true)).Schema(schema)
+		err := strict.Encode(t.Context(), &bytes.Buffer{}, parse(t, src))
+		require.ErrorIs(t, err, exi.ErrNotEncodable)
+
+		got := roundTrip(t, exi.NewEncoder().Schema(schema), exi.NewDecoder().Schema(schema), src)
+		require.Equal(t, "<?xml version=\"1.0\"?>\n"+src+"\n", got)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		const src = `<order xmlns="urn:t" status="done"><id>zz</id></order>`
+		strict := exi.NewEncoder().Options(exi.NewOptions().Strict(true)).Schema(schema)
+		err := strict.Encode(t.Context(), &bytes.Buffer{}, parse(t, src))
+		require.ErrorIs(t, err, exi.ErrNotEncodable)
+	})
+}
+
+func TestSchemaID(t *testing.T) {
+	schema := compileOrderSchema(t)
+	var buf bytes.Buffer
+	require.NoError(t, exi.NewEncoder().Schema(schema).SchemaID("order").Encode(t.Context(), &buf, parse(t, orderInstance)))
+
+	_, err := exi.NewDecoder().Schema(schema).SchemaID("other").DecodeDocument(t.Context(), bytes.NewReader(buf.Bytes()))
+	require.ErrorIs(t, err, exi.ErrSchemaMismatch)
+	_, err = exi.NewDecoder().DecodeDocument(t.Context(), bytes.NewReader(buf.Bytes()))
+	require.ErrorIs(t, err, exi.ErrSchemaMismatch)
+	_, err = exi.NewDecoder().Schema(schema).SchemaID("order").DecodeDocument(t.Context(), bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+}
+
+func TestOptionsOutOfBand(t *testing.T) {
+	const src = `<r><a>1</a><!--c--></r>`
+	o := exi.NewOptions().Alignment(exi.AlignmentCompression).Preserve(exi.PreserveComments)
+	var buf bytes.Buffer
+	require.NoError(t, exi.NewEncoder().Options(o).IncludeOptions(false).Cookie(true).Encode(t.Context(), &buf, parse(t, src)))
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("$EXI")))
+
+	doc, err := exi.NewDecoder().Options(o).DecodeDocument(t.Context(), bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	s, err := helium.WriteString(doc.DocumentElement())
+	require.NoError(t, err)
+	require.Equal(t, src, s)
+}
+
+func TestBlocks(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("<r>")
+	for i := range 700 {
+		fmt.Fprintf(&sb, `<a n="%d">v%d</a><b>x</b>`, i%150, i)
+	}
+	sb.WriteString("</r>")
+	src := sb.String()
+
+	for _, size := range []int{50, 1000, exi.DefaultBlockSize} {
+		for _, a := range []exi.Alignment{exi.AlignmentPreCompression, exi.AlignmentCompression} {
+			t.Run(fmt.Sprintf("%d/%s", size, a), func(t *testing.T) {
+				o := exi.NewOptions().Alignment(a).BlockSize(size).ValueMaxLength(3).ValuePartitionCapacity(20)
+				got := roundTrip(t, exi.NewEncoder().Options(o), exi.NewDecoder(), src)
+				require.Equal(t, "<?xml version=\"1.0\"?>\n"+src+"\n", got)
+			})
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	const src = `<r xmlns:p="urn:p"><p:a x="1">text</p:a></r>`
+	o := exi.NewOptions().Preserve(exi.PreservePrefixes)
+	var buf bytes.Buffer
+	h := exi.NewEncoder().Options(o).Handler(&buf)
+	_, err := helium.NewParser().SAXHandler(h).Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+
+	var names []string
+	f := sax.NewFilter(nil)
+	f.SetOnStartElementNS(sax.StartElementNSFunc(func(_ context.Context, localname, prefix, _ string, _ []sax.Namespace, _ []sax.Attribute) error {
+		names = append(names, prefix+":"+localname)
+		return nil
+	}))
+	require.NoError(t, exi.NewDecoder().Decode(t.Context(), bytes.NewReader(buf.Bytes()), f))
+	require.Equal(t, []string{":r", "p:a"}, names)
+}
+
+func TestErrors(t *testing.T) {
+	_, err := exi.NewDecoder().DecodeDocument(t.Context(), strings.NewReader("<x/>"))
+	require.ErrorIs(t, err, exi.ErrNotEXI)
+
+	var buf bytes.Buffer
+	require.NoError(t, exi.NewEncoder().Encode(t.Context(), &buf, parse(t, `<r><a>hello</a><b>world</b></r>`)))
+	_, err = exi.NewDecoder().DecodeDocument(t.Context(), bytes.NewReader(buf.Bytes()[:buf.Len()/2]))
+	require.ErrorIs(t, err, exi.ErrMalformed)
+
+	o := exi.NewOptions().Strict(true).Preserve(exi.PreserveComments)
+	err = exi.NewEncoder().Options(o).Encode(t.Context(), &buf, parse(t, `<r/>`))
+	require.ErrorIs(t, err, exi.ErrInvalidOptions)
+}
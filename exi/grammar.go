@@ -0,0 +1,223 @@
+package exi
+
+import (
+	"fmt"
+	"slices"
+)
+
+// eventKind identifies the terminal symbol of a grammar production.
+type eventKind uint8
+
+const (
+	evSD        eventKind = iota // start document
+	evED                         // end document
+	evSE                         // start element of a given name
+	evSEURI                      // start element in a given namespace
+	evSEAny                      // start element of any name
+	evEE                         // end element
+	evAT                         // attribute of a given name
+	evATURI                      // attribute in a given namespace
+	evATAny                      // attribute of any name
+	evATUntyped                  // attribute of any name, with an untyped value
+	evATType                     // xsi:type
+	evATNil                      // xsi:nil
+	evCH                         // characters
+	evCHUntyped                  // characters with an untyped value
+	evNS                         // namespace declaration
+	evSC                         // self-contained element
+	evCM                         // comment
+	evPI                         // processing instruction
+	evDT                         // document type declaration
+	evER                         // entity reference
+)
+
+// production is one right-hand side of a grammar state: a terminal and the
+// state that follows it.
+type production struct {
+	kind eventKind
+	qn   qname  // evSE, evAT
+	uri  string // evSEURI, evATURI
+	next *state
+	// decl is the declaration whose grammar an evSE production starts.
+	decl *elementDecl
+	// dt is the type of an evAT or evCH value; nil means untyped.
+	dt *datatype
+}
+
+// state is a non-terminal. Its productions are split into the three levels
+// of event codes: a first-level code selects a production in first or
+// escapes to the second level, which escapes to the third in the same way.
+type state struct {
+	first  []*production
+	second []*production
+	third  []*production
+	// tag marks the states of a start tag, which accept attributes.
+	tag bool
+}
+
+// codeWidths returns the number of codes at the first and second level.
+func (s *state) codeWidths() (int, int) {
+	n1, n2 := len(s.first), len(s.second)
+	if len(s.third) > 0 {
+		n2++
+	}
+	if n2 > 0 {
+		n1++
+	}
+	return n1, n2
+}
+
+// writeCode writes the event code of the production at index i of level.
+func (s *state) writeCode(w *bitWriter, level, i int) {
+	n1, n2 := s.codeWidths()
+	if level == 0 {
+		w.bits(bitsFor(n1), uint64(i))
+		return
+	}
+	w.bits(bitsFor(n1), uint64(len(s.first)))
+	if level == 1 {
+		w.bits(bitsFor(n2), uint64(i))
+		return
+	}
+	w.bits(bitsFor(n2), uint64(len(s.second)))
+	w.bits(bitsFor(len(s.third)), uint64(i))
+}
+
+// readCode reads an event code and returns its production and level.
+func (s *state) readCode(r *bitReader) (*production, int) {
+	n1, n2 := s.codeWidths()
+	i := int(r.bits(bitsFor(n1)))
+	if r.err != nil {
+		return nil, 0
+	}
+	if i < len(s.first) {
+		return s.first[i], 0
+	}
+	if i >= n1 {
+		r.fail(fmt.Errorf("%w: event code %d out of range", ErrMalformed, i))
+		return nil, 0
+	}
+	i = int(r.bits(bitsFor(n2)))
+	if r.err != nil {
+		return nil, 0
+	}
+	if i < len(s.second) {
+		return s.second[i], 1
+	}
+	if i >= n2 {
+		r.fail(fmt.Errorf("%w: event code %d out of range", ErrMalformed, i))
+		return nil, 0
+	}
+	i = int(r.bits(bitsFor(len(s.third))))
+	if r.err != nil {
+		return nil, 0
+	}
+	if i >= len(s.third) {
+		r.fail(fmt.Errorf("%w: event code %d out of range", ErrMalformed, i))
+		return nil, 0
+	}
+	return s.third[i], 2
+}
+
+// find returns the level and index of the first production, searching the
+// levels in order, that match accepts.
+func (s *state) find(match func(*production) bool) (*production, int, int) {
+	for level, ps := range [][]*production{s.first, s.second, s.third} {
+		for i, p := range ps {
+			if match(p) {
+				return p, level, i
+			}
+		}
+	}
+	return nil, 0, 0
+}
+
+func (s *state) findKind(kind eventKind) (*production, int, int) {
+	return s.find(func(p *production) bool { return p.kind == kind })
+}
+
+// learn adds p as the first first-level production of a built-in grammar
+// state, where it takes event code 0.
+func (s *state) learn(p *production) {
+	s.first = slices.Insert(s.first, 0, p)
+}
+
+// fidelity returns the third-level productions for comments and processing
+// instructions that the options keep, followed by next.
+func fidelity(o *optionsConfig, next *state) []*production {
+	var ps []*production
+	if o.has(PreserveComments) {
+		ps = append(ps, &production{kind: evCM, next: next})
+	}
+	if o.has(PreservePIs) {
+		ps = append(ps, &production{kind: evPI, next: next})
+	}
+	return ps
+}
+
+// builtinGrammar is the grammar of an element no schema describes. It
+// starts out accepting anything and learns the names and kinds of content
+// it sees, so that later occurrences get shorter event codes.
+type builtinGrammar struct {
+	startTag *state
+	content  *state
+}
+
+func newBuiltinGrammar(o *optionsConfig) *builtinGrammar {
+	g := &builtinGrammar{startTag: &state{tag: true}, content: &state{}}
+	st, ct := g.startTag, g.content
+
+	st.second = append(st.second,
+		&production{kind: evEE},
+		&production{kind: evATAny, next: st},
+	)
+	if o.has(PreservePrefixes) {
+		st.second = append(st.second, &production{kind: evNS, next: st})
+	}
+	st.second = append(st.second, childContent(o, ct)...)
+	st.third = fidelity(o, ct)
+
+	ct.first = []*production{{kind: evEE}}
+	ct.second = childContent(o, ct)
+	ct.third = fidelity(o, ct)
+	return g
+}
+
+// childContent returns the productions of the built-in grammars for the
+// content of an element.
+func childContent(o *optionsConfig, next *state) []*production {
+	ps := []*production{
+		{kind: evSEAny, next: next},
+		{kind: evCH, next: next},
+	}
+	if o.has(PreserveDTD) {
+		ps = append(ps, &production{kind: evER, next: next})
+	}
+	return ps
+}
+
+// documentGrammar is the grammar of the document as a whole: its prolog,
+// document element and epilog.
+type documentGrammar struct {
+	document *state
+	content  *state
+	end      *state
+}
+
+func newDocumentGrammar(o *optionsConfig, globals []*elementDecl) *documentGrammar {
+	g := &documentGrammar{document: &state{}, content: &state{}, end: &state{}}
+	g.document.first = []*production{{kind: evSD, next: g.content}}
+
+	for _, d := range globals {
+		g.content.first = append(g.content.first, &production{kind: evSE, qn: d.qn, decl: d, next: g.end})
+	}
+	g.content.first = append(g.content.first, &production{kind: evSEAny, next: g.end})
+	if o.has(PreserveDTD) {
+		g.content.second = []*production{{kind: evDT, next: g.content}}
+	}
+	g.content.third = fidelity(o, g.content)
+
+	g.end.first = []*production{{kind: evED}}
+	g.end.second = fidelity(o, g.end)
+	return g
+}
@@ -0,0 +1,429 @@
+package exi
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/sax"
+)
+
+// Handler is a SAX2Handler that encodes the document whose events it
+// receives. It is created by [Encoder.Handler].
+//
+// Adjacent character data, including CDATA sections, is encoded as one
+// value. Comments and processing instructions are kept when the options
+// preserve them. The document type declaration and entity references are
+// kept when the DTD is preserved; otherwise references to internal entities
+// whose replacement text has no markup are replaced by that text, and other
+// references cannot be encoded. Attributes defaulted from the DTD are left
+// out when the DTD is preserved, since the declarations restore them.
+//
+// Handler answers GetEntity from the declarations it has seen, so that a
+// parser can resolve references to entities of the internal subset.
+type Handler struct {
+	begin  func(context.Context) (*bodyEncoder, error)
+	enc    *bodyEncoder
+	err    error
+	text   strings.Builder
+	scopes [][]sax.Namespace
+
+	dtdOpen bool
+	dtdDoc  *helium.Document
+	dtd     *helium.DTD
+	doctype [3]string
+	subset  strings.Builder
+}
+
+func newHandler() *Handler {
+	return &Handler{}
+}
+
+// fail records the first error, which every later event returns.
+func (h *Handler) fail(err error) error {
+	if h.err == nil {
+		h.err = err
+	}
+	return h.err
+}
+
+func (h *Handler) ready() bool {
+	return h.err == nil && h.enc != nil
+}
+
+// closeDTD writes the document type declaration if one is open.
+func (h *Handler) closeDTD() error {
+	if !h.dtdOpen {
+		return nil
+	}
+	h.dtdOpen = false
+	if !h.enc.opts.has(PreserveDTD) {
+		return nil
+	}
+	return h.enc.doctype(h.doctype[0], h.doctype[1], h.doctype[2], h.subset.String())
+}
+
+// flushText writes the character data gathered since the last markup.
+func (h *Handler) flushText() error {
+	if h.text.Len() == 0 {
+		return nil
+	}
+	s := h.text.String()
+	h.text.Reset()
+	return h.enc.characters(s)
+}
+
+// markup prepares for an event other than character data.
+func (h *Handler) markup() error {
+	if err := h.closeDTD(); err != nil {
+		return err
+	}
+	return h.flushText()
+}
+
+// lookupNS resolves prefix against the declarations of the open elements.
+func (h *Handler) lookupNS(prefix string) (string, bool) {
+	if prefix == lexicon.PrefixXML {
+		return lexicon.NamespaceXML, true
+	}
+	for i := len(h.scopes) - 1; i >= 0; i-- {
+		for _, ns := range h.scopes[i] {
+			if ns.Prefix() == prefix {
+				return ns.URI(), true
+			}
+		}
+	}
+	return "", prefix == ""
+}
+
+func (h *Handler) SetDocumentLocator(context.Context, sax.DocumentLocator) error {
+	return nil
+}
+
+func (h *Handler) StartDocument(ctx context.Context) error {
+	if h.err != nil {
+		return h.err
+	}
+	enc, err := h.begin(ctx)
+	if err != nil {
+		return h.fail(err)
+	}
+	h.enc = enc
+	return h.fail(enc.startDocument())
+}
+
+func (h *Handler) EndDocument(context.Context) error {
+	if !h.ready() {
+		return h.err
+	}
+	if err := h.markup(); err != nil {
+		return h.fail(err)
+	}
+	if err := h.enc.endDocument(); err != nil {
+		return h.fail(err)
+	}
+	return nil
+}
+
+// InternalSubset opens the document type declaration. It is written when
+// the parser reports the end of the internal subset through
+// ExternalSubset, or at the document element.
+func (h *Handler) InternalSubset(_ context.Context, name, externalID, systemID string) error {
+	if !h.ready() {
+		return h.err
+	}
+	h.dtdOpen = true
+	h.doctype = [3]string{name, externalID, systemID}
+	h.dtdDoc = helium.NewDocument("1.0", "", helium.StandaloneImplicitNo)
+	dtd, err := h.dtdDoc.CreateInternalSubset(name, externalID, systemID)
+	if err != nil {
+		return h.fail(err)
+	}
+	h.dtd = dtd
+	return nil
+}
+
+func (h *Handler) ExternalSubset(context.Context, string, string, string) error {
+	if !h.ready() {
+		return h.err
+	}
+	if err := h.closeDTD(); err != nil {
+		return h.fail(err)
+	}
+	return nil
+}
+
+// declared adds the declaration node to the text of the internal subset.
+func (h *Handler) declared(node helium.Node, err error) error {
+	if err != nil {
+		return h.fail(err)
+	}
+	if !h.dtdOpen {
+		return nil
+	}
+	s, err := helium.WriteString(node)
+	if err != nil {
+		return h.fail(err)
+	}
+	h.subset.WriteString(strings.TrimSpace(s))
+	return nil
+}
+
+func (h *Handler) EntityDecl(_ context.Context, name string, typ enum.EntityType, publicID, systemID, content string) error {
+	if !h.ready() || h.dtd == nil {
+		return h.err
+	}
+	ent, err := h.dtd.AddEntity(name, typ, publicID, systemID, content)
+	return h.declared(ent, err)
+}
+
+func (h *Handler) UnparsedEntityDecl(_ context.Context, name, publicID, systemID, notationName string) error {
+	if !h.ready() || h.dtd == nil {
+		return h.err
+	}
+	ent, err := h.dtd.AddEntity(name, enum.ExternalGeneralUnparsedEntity, publicID, systemID, notationName)
+	return h.declared(ent, err)
+}
+
+func (h *Handler) NotationDecl(_ context.Context, name, publicID, systemID string) error {
+	if !h.ready() || h.dtd == nil {
+		return h.err
+	}
+	n, err := h.dtd.AddNotation(name, publicID, systemID)
+	return h.declared(n, err)
+}
+
+func (h *Handler) ElementDecl(_ context.Context, name string, typ enum.ElementType, content sax.ElementContent) error {
+	if !h.ready() || h.dtd == nil {
+		return h.err
+	}
+	c, _ := content.(*helium.ElementContent)
+	d, err := h.dtd.AddElementDecl(name, typ, c)
+	return h.declared(d, err)
+}
+
+func (h *Handler) AttributeDecl(_ context.Context, elem, fullname string, typ enum.AttributeType, def enum.AttributeDefault, defaultValue string, tree sax.Enumeration) error {
+	if !h.ready() || h.dtd == nil {
+		return h.err
+	}
+	values, _ := tree.(helium.Enumeration)
+	d, err := h.dtd.AddAttributeDecl(elem, fullname, typ, def, defaultValue, values)
+	return h.declared(d, err)
+}
+
+func (h *Handler) StartElementNS(_ context.Context, localname, prefix, uri string, namespaces []sax.Namespace, attrs []sax.Attribute) error {
+	if !h.ready() {
+		return h.err
+	}
+	if err := h.markup(); err != nil {
+		return h.fail(err)
+	}
+	h.scopes = append(h.scopes, namespaces)
+	enc := h.enc
+	if err := enc.startElement(qname{uri: uri, local: localname}, prefix); err != nil {
+		return h.fail(err)
+	}
+	if enc.opts.has(PreservePrefixes) {
+		for _, ns := range namespaces {
+			local := ns.Prefix() == prefix && ns.URI() == uri
+			if err := enc.namespace(ns.URI(), ns.Prefix(), local); err != nil {
+				return h.fail(err)
+			}
+		}
+	}
+
+	type attr struct {
+		qn     qname
+		prefix string
+		value  string
+	}
+	var list []attr
+	var xsiType, xsiNil *attr
+	informed := enc.top().builtin == nil
+	for _, a := range attrs {
+		if a.IsDefault() && enc.opts.has(PreserveDTD) {
+			continue
+		}
+		nsURI := ""
+		if a.Prefix() != "" {
+			nsURI, _ = h.lookupNS(a.Prefix())
+		}
+		at := attr{qn: qname{uri: nsURI, local: a.LocalName()}, prefix: a.Prefix(), value: a.Value()}
+		switch {
+		case informed && at.qn == qnXSIType:
+			xsiType = &at
+			continue
+		case informed && at.qn == qnXSINil:
+			if v := collapse(at.value); v == "true" || v == "1" || v == "false" || v == "0" {
+				xsiNil = &at
+				continue
+			}
+		}
+		list = append(list, at)
+	}
+	if xsiType != nil {
+		tprefix, local, ok := strings.Cut(collapse(xsiType.value), ":")
+		if !ok {
+			tprefix, local = "", tprefix
+		}
+		tns, _ := h.lookupNS(tprefix)
+		if err := enc.xsiType(qname{uri: tns, local: local}, tprefix); err != nil {
+			return h.fail(err)
+		}
+	}
+	if xsiNil != nil {
+		v := collapse(xsiNil.value)
+		if err := enc.xsiNil(v == "true" || v == "1"); err != nil {
+			return h.fail(err)
+		}
+	}
+	if informed {
+		// Schema-informed grammars take attributes in name order.
+		slices.SortStableFunc(list, func(a, b attr) int { return compareQName(a.qn, b.qn) })
+	}
+	for _, a := range list {
+		if err := enc.attribute(a.qn, a.prefix, a.value); err != nil {
+			return h.fail(err)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) EndElementNS(context.Context, string, string, string) error {
+	if !h.ready() {
+		return h.err
+	}
+	if err := h.flushText(); err != nil {
+		return h.fail(err)
+	}
+	if n := len(h.scopes); n > 0 {
+		h.scopes = h.scopes[:n-1]
+	}
+	return h.fail(h.enc.endElement())
+}
+
+func (h *Handler) Characters(_ context.Context, ch []byte) error {
+	if !h.ready() {
+		return h.err
+	}
+	h.text.Write(ch)
+	return nil
+}
+
+func (h *Handler) IgnorableWhitespace(ctx context.Context, ch []byte) error {
+	return h.Characters(ctx, ch)
+}
+
+func (h *Handler) CDataBlock(ctx context.Context, value []byte) error {
+	return h.Characters(ctx, value)
+}
+
+func (h *Handler) Comment(_ context.Context, value []byte) error {
+	if !h.ready() {
+		return h.err
+	}
+	if h.dtdOpen {
+		h.subset.WriteString("<!--")
+		h.subset.Write(value)
+		h.subset.WriteString("-->")
+		return nil
+	}
+	if !h.enc.opts.has(PreserveComments) {
+		return nil
+	}
+	if err := h.markup(); err != nil {
+		return h.fail(err)
+	}
+	return h.fail(h.enc.comment(string(value)))
+}
+
+func (h *Handler) ProcessingInstruction(_ context.Context, target, data string) error {
+	if !h.ready() {
+		return h.err
+	}
+	if h.dtdOpen {
+		h.subset.WriteString("<?")
+		h.subset.WriteString(target)
+		if data != "" {
+			h.subset.WriteByte(' ')
+			h.subset.WriteString(data)
+		}
+		h.subset.WriteString("?>")
+		return nil
+	}
+	if !h.enc.opts.has(PreservePIs) {
+		return nil
+	}
+	if err := h.markup(); err != nil {
+		return h.fail(err)
+	}
+	return h.fail(h.enc.processingInstruction(target, data))
+}
+
+// Reference encodes an entity reference when the DTD is preserved, and
+// otherwise replaces a reference to an internal entity with its text.
+func (h *Handler) Reference(_ context.Context, name string) error {
+	if !h.ready() {
+		return h.err
+	}
+	if h.enc.opts.has(PreserveDTD) {
+		if err := h.markup(); err != nil {
+			return h.fail(err)
+		}
+		return h.fail(h.enc.entityRef(name))
+	}
+	if h.dtd != nil {
+		if ent, ok := h.dtd.LookupEntity(name); ok && ent.EntityType() == enum.InternalGeneralEntity {
+			if text := ent.Content(); !slices.Contains(text, '<') && !slices.Contains(text, '&') {
+				h.text.Write(text)
+				return nil
+			}
+		}
+	}
+	return h.fail(notEncodable("reference to entity %q requires the DTD to be preserved", name))
+}
+
+func (h *Handler) GetEntity(_ context.Context, name string) (sax.Entity, error) {
+	if h.dtd != nil {
+		if ent, ok := h.dtd.LookupEntity(name); ok {
+			return ent, nil
+		}
+	}
+	return nil, nil //nolint:nilnil // an undeclared entity is not an error here
+}
+
+func (h *Handler) GetParameterEntity(_ context.Context, name string) (sax.Entity, error) {
+	if h.dtd != nil {
+		if ent, ok := h.dtd.LookupParameterEntity(name); ok {
+			return ent, nil
+		}
+	}
+	return nil, nil //nolint:nilnil // an undeclared entity is not an error here
+}
+
+func (h *Handler) HasExternalSubset(context.Context) (bool, error) {
+	return false, sax.ErrHandlerUnspecified
+}
+
+func (h *Handler) HasInternalSubset(context.Context) (bool, error) {
+	return false, sax.ErrHandlerUnspecified
+}
+
+func (h *Handler) IsStandalone(context.Context) (bool, error) {
+	return false, sax.ErrHandlerUnspecified
+}
+
+func (h *Handler) ResolveEntity(context.Context, string, string) (sax.ParseInput, error) {
+	return nil, sax.ErrHandlerUnspecified
+}
+
+func (h *Handler) Error(context.Context, error) error {
+	return sax.ErrHandlerUnspecified
+}
+
+func (h *Handler) Warning(context.Context, error) error {
+	return sax.ErrHandlerUnspecified
+}
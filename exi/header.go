@@ -0,0 +1,451 @@
+package exi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/xsd"
+)
+
+// NamespaceEXI is the namespace of the EXI options document.
+const NamespaceEXI = "http://www.w3.org/2009/exi"
+
+// cookie is the optional signature an EXI stream may start with.
+const cookie = "$EXI"
+
+// optionsSchemaSource is the schema of the EXI options document (EXI 1.0,
+// Appendix C).
+const optionsSchemaSource = `<xsd:schema targetNamespace="http://www.w3.org/2009/exi"
+  xmlns:xsd="http://www.w3.org/2001/XMLSchema" elementFormDefault="qualified">
+  <xsd:element name="header">
+    <xsd:complexType>
+      <xsd:sequence>
+        <xsd:element name="lesscommon" minOccurs="0">
+          <xsd:complexType>
+            <xsd:sequence>
+              <xsd:element name="uncommon" minOccurs="0">
+                <xsd:complexType>
+                  <xsd:sequence>
+                    <xsd:any namespace="##other" minOccurs="0" maxOccurs="unbounded" processContents="skip"/>
+                    <xsd:element name="alignment" minOccurs="0">
+                      <xsd:complexType>
+                        <xsd:choice>
+                          <xsd:element name="byte"><xsd:complexType/></xsd:element>
+                          <xsd:element name="pre-compress"><xsd:complexType/></xsd:element>
+                        </xsd:choice>
+                      </xsd:complexType>
+                    </xsd:element>
+                    <xsd:element name="selfContained" minOccurs="0"><xsd:complexType/></xsd:element>
+                    <xsd:element name="valueMaxLength" minOccurs="0">
+                      <xsd:simpleType><xsd:restriction base="xsd:unsignedInt"/></xsd:simpleType>
+                    </xsd:element>
+                    <xsd:element name="valuePartitionCapacity" minOccurs="0">
+                      <xsd:simpleType><xsd:restriction base="xsd:unsignedInt"/></xsd:simpleType>
+                    </xsd:element>
+                    <xsd:element name="datatypeRepresentationMap" minOccurs="0" maxOccurs="unbounded">
+                      <xsd:complexType>
+                        <xsd:sequence>
+                          <xsd:any namespace="##any" processContents="skip"/>
+                          <xsd:any namespace="##any" processContents="skip"/>
+                        </xsd:sequence>
+                      </xsd:complexType>
+                    </xsd:element>
+                  </xsd:sequence>
+                </xsd:complexType>
+              </xsd:element>
+              <xsd:element name="preserve" minOccurs="0">
+                <xsd:complexType>
+                  <xsd:sequence>
+                    <xsd:element name="dtd" minOccurs="0"><xsd:complexType/></xsd:element>
+                    <xsd:element name="prefixes" minOccurs="0"><xsd:complexType/></xsd:element>
+                    <xsd:element name="lexicalValues" minOccurs="0"><xsd:complexType/></xsd:element>
+                    <xsd:element name="comments" minOccurs="0"><xsd:complexType/></xsd:element>
+                    <xsd:element name="pis" minOccurs="0"><xsd:complexType/></xsd:element>
+                  </xsd:sequence>
+                </xsd:complexType>
+              </xsd:element>
+              <xsd:element name="blockSize" minOccurs="0">
+                <xsd:simpleType>
+                  <xsd:restriction base="xsd:unsignedInt"><xsd:minInclusive value="1"/></xsd:restriction>
+                </xsd:simpleType>
+              </xsd:element>
+            </xsd:sequence>
+          </xsd:complexType>
+        </xsd:element>
+        <xsd:element name="common" minOccurs="0">
+          <xsd:complexType>
+            <xsd:sequence>
+              <xsd:element name="compression" minOccurs="0"><xsd:complexType/></xsd:element>
+              <xsd:element name="fragment" minOccurs="0"><xsd:complexType/></xsd:element>
+              <xsd:element name="schemaId" minOccurs="0" nillable="true">
+                <xsd:simpleType><xsd:restriction base="xsd:string"/></xsd:simpleType>
+              </xsd:element>
+            </xsd:sequence>
+          </xsd:complexType>
+        </xsd:element>
+        <xsd:element name="strict" minOccurs="0"><xsd:complexType/></xsd:element>
+      </xsd:sequence>
+    </xsd:complexType>
+  </xsd:element>
+  <xsd:simpleType name="base64Binary"><xsd:restriction base="xsd:base64Binary"/></xsd:simpleType>
+  <xsd:simpleType name="hexBinary"><xsd:restriction base="xsd:hexBinary"/></xsd:simpleType>
+  <xsd:simpleType name="boolean"><xsd:restriction base="xsd:boolean"/></xsd:simpleType>
+  <xsd:simpleType name="decimal"><xsd:restriction base="xsd:decimal"/></xsd:simpleType>
+  <xsd:simpleType name="double"><xsd:restriction base="xsd:double"/></xsd:simpleType>
+  <xsd:simpleType name="integer"><xsd:restriction base="xsd:integer"/></xsd:simpleType>
+  <xsd:simpleType name="string"><xsd:restriction base="xsd:string"/></xsd:simpleType>
+  <xsd:simpleType name="dateTime"><xsd:restriction base="xsd:dateTime"/></xsd:simpleType>
+  <xsd:simpleType name="date"><xsd:restriction base="xsd:date"/></xsd:simpleType>
+  <xsd:simpleType name="time"><xsd:restriction base="xsd:time"/></xsd:simpleType>
+  <xsd:simpleType name="gYearMonth"><xsd:restriction base="xsd:gYearMonth"/></xsd:simpleType>
+  <xsd:simpleType name="gMonthDay"><xsd:restriction base="xsd:gMonthDay"/></xsd:simpleType>
+  <xsd:simpleType name="gYear"><xsd:restriction base="xsd:gYear"/></xsd:simpleType>
+  <xsd:simpleType name="gMonth"><xsd:restriction base="xsd:gMonth"/></xsd:simpleType>
+  <xsd:simpleType name="gDay"><xsd:restriction base="xsd:gDay"/></xsd:simpleType>
+  <xsd:simpleType name="ieeeBinary32"><xsd:restriction base="xsd:float"/></xsd:simpleType>
+  <xsd:simpleType name="ieeeBinary64"><xsd:restriction base="xsd:double"/></xsd:simpleType>
+</xsd:schema>`
+
+var (
+	optionsSchemaOnce sync.Once
+	optionsSchemaVal  *xsd.Schema
+	optionsSchemaErr  error
+
+	emptySchemaOnce sync.Once
+	emptySchemaVal  *xsd.Schema
+	emptySchemaErr  error
+)
+
+// optionsSchema returns the compiled schema of the options document.
+func optionsSchema(ctx context.Context) (*xsd.Schema, error) {
+	optionsSchemaOnce.Do(func() {
+		optionsSchemaVal, optionsSchemaErr = compileSchemaSource(ctx, optionsSchemaSource)
+	})
+	return optionsSchemaVal, optionsSchemaErr
+}
+
+// emptySchema returns a schema with no declarations, which a stream whose
+// schemaId is empty is informed by: only the built-in types are known.
+func emptySchema(ctx context.Context) (*xsd.Schema, error) {
+	emptySchemaOnce.Do(func() {
+		emptySchemaVal, emptySchemaErr = compileSchemaSource(ctx, `<xsd:schema xmlns:xsd="`+lexicon.NamespaceXSD+`"/>`)
+	})
+	return emptySchemaVal, emptySchemaErr
+}
+
+func compileSchemaSource(ctx context.Context, src string) (*xsd.Schema, error) {
+	doc, err := helium.NewParser().Parse(ctx, []byte(src))
+	if err != nil {
+		return nil, err
+	}
+	return xsd.NewCompiler().Compile(ctx, doc)
+}
+
+// headerOptionsConfig is the configuration the options document itself is
+// encoded with: schema-informed by the options schema, strict and
+// bit-packed.
+func headerOptionsConfig() *optionsConfig {
+	o := *NewOptions().cfg
+	o.strict = true
+	return &o
+}
+
+// schemaID is the schemaId of a header: absent, nil (no schema) or a value.
+type schemaID struct {
+	present bool
+	nil     bool
+	value   string
+}
+
+// header is the part of a stream before the body.
+type header struct {
+	cookie  bool
+	options *optionsConfig // nil when the header has no options document
+	schema  schemaID
+}
+
+// writeHeader writes h to w, which must be bit-packed and at the start of
+// the stream.
+func writeHeader(ctx context.Context, w *bitWriter, h *header) error {
+	if h.cookie {
+		for i := range len(cookie) {
+			w.bits(8, uint64(cookie[i]))
+		}
+	}
+	w.bits(2, 2)
+	w.boolean(h.options != nil)
+	w.boolean(false) // final version
+	w.bits(4, 0)     // version 1
+	if h.options != nil {
+		doc, err := optionsDocument(h.options, h.schema)
+		if err != nil {
+			return err
+		}
+		schema, err := optionsSchema(ctx)
+		if err != nil {
+			return err
+		}
+		o := headerOptionsConfig()
+		e := newBodyEncoder(o, newGrammars(o, schema), newStringTable(o, schema), w, nil)
+		if err := encodeDocument(ctx, e, doc); err != nil {
+			return err
+		}
+	}
+	if h.options == nil || h.options.alignment != AlignmentBitPacked {
+		w.align()
+	}
+	return w.err
+}
+
+// optionsDocument builds the options document that records o and id.
+func optionsDocument(o *optionsConfig, id schemaID) (*helium.Document, error) {
+	doc := helium.NewDocument("1.0", "", helium.StandaloneImplicitNo)
+	var b docBuilder
+	b.doc = doc
+	root := b.element(nil, "header")
+	def := NewOptions().cfg
+
+	var lesscommon, common *helium.Element
+	lc := func() *helium.Element {
+		if lesscommon == nil {
+			lesscommon = b.element(root, "lesscommon")
+		}
+		return lesscommon
+	}
+	var uncommon *helium.Element
+	unc := func() *helium.Element {
+		if uncommon == nil {
+			uncommon = b.element(lc(), "uncommon")
+		}
+		return uncommon
+	}
+	switch o.alignment {
+	case AlignmentByteAligned:
+		b.element(b.element(unc(), "alignment"), "byte")
+	case AlignmentPreCompression:
+		b.element(b.element(unc(), "alignment"), "pre-compress")
+	}
+	if o.valueMaxLength >= 0 {
+		b.text(b.element(unc(), "valueMaxLength"), strconv.Itoa(o.valueMaxLength))
+	}
+	if o.valuePartitionCapacity >= 0 {
+		b.text(b.element(unc(), "valuePartitionCapacity"), strconv.Itoa(o.valuePartitionCapacity))
+	}
+	if o.preserve != 0 {
+		preserve := b.element(lc(), "preserve")
+		for _, p := range []struct {
+			flag Preserve
+			name string
+		}{
+			{PreserveDTD, "dtd"},
+			{PreservePrefixes, "prefixes"},
+			{PreserveLexicalValues, "lexicalValues"},
+			{PreserveComments, "comments"},
+			{PreservePIs, "pis"},
+		} {
+			if o.has(p.flag) {
+				b.element(preserve, p.name)
+			}
+		}
+	}
+	if o.blockSize != def.blockSize {
+		b.text(b.element(lc(), "blockSize"), strconv.Itoa(o.blockSize))
+	}
+	if o.alignment == AlignmentCompression {
+		common = b.element(root, "common")
+		b.element(common, "compression")
+	}
+	if id.present {
+		if common == nil {
+			common = b.element(root, "common")
+		}
+		e := b.element(common, "schemaId")
+		if id.nil {
+			ns, err := doc.CreateNamespace("xsi", lexicon.NamespaceXSI)
+			if err != nil {
+				return nil, err
+			}
+			b.check(e.DeclareNamespace("xsi", lexicon.NamespaceXSI))
+			b.check(e.SetAttributeNS("nil", "true", ns))
+		} else {
+			b.text(e, id.value)
+		}
+	}
+	if o.strict {
+		b.element(root, "strict")
+	}
+	return doc, b.err
+}
+
+// docBuilder builds the options document, keeping the first error.
+type docBuilder struct {
+	doc *helium.Document
+	err error
+}
+
+func (b *docBuilder) check(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *docBuilder) element(parent *helium.Element, local string) *helium.Element {
+	e, err := b.doc.CreateElement(local)
+	if err != nil {
+		b.check(err)
+		return nil
+	}
+	if parent == nil {
+		b.check(e.DeclareNamespace("", NamespaceEXI))
+		b.check(e.SetActiveNamespace("", NamespaceEXI))
+		b.check(b.doc.SetDocumentElement(e))
+		return e
+	}
+	b.check(e.SetActiveNamespace("", NamespaceEXI))
+	b.check(parent.AddChild(e))
+	return e
+}
+
+func (b *docBuilder) text(parent *helium.Element, s string) {
+	if parent != nil {
+		b.check(parent.AddChild(b.doc.CreateText([]byte(s))))
+	}
+}
+
+// readHeader reads the header at the start of r, which must be bit-packed.
+func readHeader(ctx context.Context, r *bitReader) (*header, error) {
+	h := &header{}
+	first := r.bits(8)
+	if r.err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotEXI, r.err)
+	}
+	if first == uint64(cookie[0]) {
+		for i := 1; i < len(cookie); i++ {
+			if r.bits(8) != uint64(cookie[i]) {
+				return nil, ErrNotEXI
+			}
+		}
+		h.cookie = true
+		first = r.bits(8)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotEXI, r.err)
+	}
+	if first>>6 != 2 {
+		return nil, ErrNotEXI
+	}
+	hasOptions := first>>5&1 == 1
+	preview := first>>4&1 == 1
+	version := first & 0xf
+	if version == 0xf {
+		return nil, fmt.Errorf("%w: EXI version above 16", ErrUnsupported)
+	}
+	if preview || version != 0 {
+		return nil, fmt.Errorf("%w: EXI version %d", ErrUnsupported, version+1)
+	}
+	if !hasOptions {
+		r.align()
+		return h, nil
+	}
+
+	schema, err := optionsSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	o := headerOptionsConfig()
+	sink := &domSink{}
+	d := newBodyDecoder(o, newGrammars(o, schema), newStringTable(o, schema), r, nil, newDispatcher(sink, nil))
+	if err := d.run(ctx); err != nil {
+		return nil, err
+	}
+	h.options, h.schema, err = parseOptionsDocument(sink.doc)
+	if err != nil {
+		return nil, err
+	}
+	if h.options.alignment != AlignmentBitPacked {
+		r.align()
+	}
+	return h, nil
+}
+
+// parseOptionsDocument reads the options an options document records.
+func parseOptionsDocument(doc *helium.Document) (*optionsConfig, schemaID, error) {
+	o := NewOptions().cfg
+	var id schemaID
+	var err error
+	number := func(e *helium.Element) int {
+		n, perr := strconv.Atoi(string(e.Content()))
+		if perr != nil && err == nil {
+			err = fmt.Errorf("%w: %s %q", ErrMalformed, e.LocalName(), e.Content())
+		}
+		return n
+	}
+	var walk func(helium.Node)
+	walk = func(n helium.Node) {
+		for c := range helium.Children(n) {
+			e, ok := c.(*helium.Element)
+			if !ok {
+				continue
+			}
+			switch e.LocalName() {
+			case "byte":
+				o.alignment = AlignmentByteAligned
+			case "pre-compress":
+				o.alignment = AlignmentPreCompression
+			case "compression":
+				o.alignment = AlignmentCompression
+			case "selfContained":
+				if err == nil {
+					err = fmt.Errorf("%w: self-contained elements", ErrUnsupported)
+				}
+			case "datatypeRepresentationMap":
+				if err == nil {
+					err = fmt.Errorf("%w: datatype representation maps", ErrUnsupported)
+				}
+				continue
+			case "fragment":
+				if err == nil {
+					err = fmt.Errorf("%w: EXI fragments", ErrUnsupported)
+				}
+			case "valueMaxLength":
+				o.valueMaxLength = number(e)
+			case "valuePartitionCapacity":
+				o.valuePartitionCapacity = number(e)
+			case "blockSize":
+				o.blockSize = number(e)
+			case "dtd":
+				o.preserve |= PreserveDTD
+			case "prefixes":
+				o.preserve |= PreservePrefixes
+			case "lexicalValues":
+				o.preserve |= PreserveLexicalValues
+			case "comments":
+				o.preserve |= PreserveComments
+			case "pis":
+				o.preserve |= PreservePIs
+			case "strict":
+				o.strict = true
+			case "schemaId":
+				id.present = true
+				if v, ok := e.GetAttributeNS("nil", lexicon.NamespaceXSI); ok && (v == "true" || v == "1") {
+					id.nil = true
+				} else {
+					id.value = string(e.Content())
+				}
+			}
+			walk(e)
+		}
+	}
+	walk(doc)
+	if err != nil {
+		return nil, id, err
+	}
+	if err := o.check(); err != nil {
+		return nil, id, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	return o, id, nil
+}
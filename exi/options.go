@@ -0,0 +1,177 @@
+package exi
+
+import "fmt"
+
+// DefaultBlockSize is the number of values in a compression block unless
+// [Options.BlockSize] says otherwise.
+const DefaultBlockSize = 1000000
+
+// Alignment selects how an EXI body is laid out.
+type Alignment int
+
+const (
+	// AlignmentBitPacked packs event codes and values without regard to
+	// byte boundaries. It gives the smallest uncompressed streams.
+	AlignmentBitPacked Alignment = iota
+	// AlignmentByteAligned starts every event code and value on a byte
+	// boundary, which is larger but easier to inspect and to compress with a
+	// general-purpose compressor.
+	AlignmentByteAligned
+	// AlignmentPreCompression is byte-aligned and groups the values of each
+	// element and attribute name into channels, as compression does, without
+	// compressing them.
+	AlignmentPreCompression
+	// AlignmentCompression groups values into channels and compresses each
+	// block with DEFLATE.
+	AlignmentCompression
+)
+
+func (a Alignment) String() string {
+	switch a {
+	case AlignmentBitPacked:
+		return "bit-packed"
+	case AlignmentByteAligned:
+		return "byte-aligned"
+	case AlignmentPreCompression:
+		return "pre-compression"
+	case AlignmentCompression:
+		return "compression"
+	}
+	return fmt.Sprintf("Alignment(%d)", int(a))
+}
+
+// Preserve is a set of fidelity options: the parts of a document that are
+// dropped by default and kept when their flag is set.
+type Preserve uint8
+
+const (
+	// PreserveComments keeps comments.
+	PreserveComments Preserve = 1 << iota
+	// PreservePIs keeps processing instructions.
+	PreservePIs
+	// PreserveDTD keeps the document type declaration and entity
+	// references. Without it, references to internal entities are replaced
+	// by their text.
+	PreserveDTD
+	// PreservePrefixes keeps namespace prefixes and declarations. Without
+	// it, a decoder makes up its own.
+	PreservePrefixes
+	// PreserveLexicalValues keeps the exact text of typed values, such as
+	// "+01" for an integer, by encoding every value as a string.
+	PreserveLexicalValues
+)
+
+type optionsConfig struct {
+	alignment              Alignment
+	preserve               Preserve
+	strict                 bool
+	blockSize              int
+	valueMaxLength         int
+	valuePartitionCapacity int
+}
+
+// Options are the EXI options shared by an encoder and a decoder: they
+// decide the grammars and the layout of a stream, so a stream can only be
+// decoded with the options it was encoded with. An [Encoder] records them in
+// the stream header unless told not to, in which case the [Decoder] must be
+// given the same Options.
+//
+// It uses clone-on-write semantics: each builder method returns a new
+// Options sharing the underlying config until mutation.
+type Options struct {
+	cfg *optionsConfig
+}
+
+// NewOptions creates the default EXI options: bit-packed, not strict,
+// preserving nothing, with unbounded string tables.
+func NewOptions() Options {
+	return Options{cfg: &optionsConfig{blockSize: DefaultBlockSize, valueMaxLength: -1, valuePartitionCapacity: -1}}
+}
+
+func (o Options) clone() Options {
+	if o.cfg == nil {
+		return NewOptions()
+	}
+	cp := *o.cfg
+	return Options{cfg: &cp}
+}
+
+func (o Options) config() *optionsConfig {
+	if o.cfg == nil {
+		return NewOptions().cfg
+	}
+	return o.cfg
+}
+
+// Alignment sets the layout of the stream body.
+func (o Options) Alignment(a Alignment) Options {
+	o = o.clone()
+	o.cfg.alignment = a
+	return o
+}
+
+// Preserve sets the fidelity options. Flags not in p are cleared.
+func (o Options) Preserve(p Preserve) Options {
+	o = o.clone()
+	o.cfg.preserve = p
+	return o
+}
+
+// Strict makes schema-informed grammars accept only documents that are
+// valid against the schema, which removes the productions for everything
+// else and shortens event codes. Strict mode cannot be combined with
+// preserving comments, processing instructions or the DTD.
+func (o Options) Strict(v bool) Options {
+	o = o.clone()
+	o.cfg.strict = v
+	return o
+}
+
+// BlockSize sets how many values make up a block under
+// [AlignmentPreCompression] and [AlignmentCompression]. Larger blocks
+// compress better and need more memory on both sides.
+func (o Options) BlockSize(n int) Options {
+	o = o.clone()
+	o.cfg.blockSize = n
+	return o
+}
+
+// ValueMaxLength sets the length of the longest value added to the string
+// table; longer values are written out every time. A negative n removes the
+// limit.
+func (o Options) ValueMaxLength(n int) Options {
+	o = o.clone()
+	o.cfg.valueMaxLength = max(n, -1)
+	return o
+}
+
+// ValuePartitionCapacity sets how many values the string table holds at
+// once. When it is full, the oldest value is replaced. A negative n removes
+// the limit, and zero keeps values out of the table.
+func (o Options) ValuePartitionCapacity(n int) Options {
+	o = o.clone()
+	o.cfg.valuePartitionCapacity = max(n, -1)
+	return o
+}
+
+func (c *optionsConfig) check() error {
+	if c.alignment < AlignmentBitPacked || c.alignment > AlignmentCompression {
+		return fmt.Errorf("%w: unknown alignment %s", ErrInvalidOptions, c.alignment)
+	}
+	if c.blockSize < 1 {
+		return fmt.Errorf("%w: block size %d is not positive", ErrInvalidOptions, c.blockSize)
+	}
+	if c.strict && c.preserve&(PreserveComments|PreservePIs|PreserveDTD) != 0 {
+		return fmt.Errorf("%w: strict mode cannot preserve comments, processing instructions or the DTD", ErrInvalidOptions)
+	}
+	return nil
+}
+
+func (c *optionsConfig) has(p Preserve) bool {
+	return c.preserve&p != 0
+}
+
+// channelled reports whether values are grouped into channels by block.
+func (c *optionsConfig) channelled() bool {
+	return c.alignment == AlignmentPreCompression || c.alignment == AlignmentCompression
+}
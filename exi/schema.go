@@ -0,0 +1,848 @@
+package exi
+
+import (
+	"cmp"
+	"iter"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/xsd"
+)
+
+// elementDecl is an element declaration as the grammars see it.
+type elementDecl struct {
+	qn       qname
+	typ      *xsd.TypeDef
+	nillable bool
+}
+
+// typeKey identifies the grammar of a type. Nillable and empty grammars
+// differ in their undeclared productions and content.
+type typeKey struct {
+	td       *xsd.TypeDef
+	nillable bool
+	empty    bool
+}
+
+// grammars holds every grammar of a stream. Built-in element grammars
+// evolve as they learn, so a grammars value is used for one stream only;
+// schema-informed grammars are built when first needed.
+type grammars struct {
+	opts      *optionsConfig
+	schema    *xsd.Schema
+	doc       *documentGrammar
+	builtin   map[qname]*builtinGrammar
+	globals   map[qname]*elementDecl
+	decls     map[*xsd.ElementDecl]*elementDecl
+	types     map[typeKey]*state
+	datatypes map[*xsd.TypeDef]*datatype
+	subtypes  map[*xsd.TypeDef]bool
+}
+
+func newGrammars(o *optionsConfig, schema *xsd.Schema) *grammars {
+	g := &grammars{
+		opts:      o,
+		schema:    schema,
+		builtin:   map[qname]*builtinGrammar{},
+		globals:   map[qname]*elementDecl{},
+		decls:     map[*xsd.ElementDecl]*elementDecl{},
+		types:     map[typeKey]*state{},
+		datatypes: map[*xsd.TypeDef]*datatype{},
+	}
+	var globals []*elementDecl
+	if schema != nil {
+		for _, d := range schema.Elements() {
+			ed := g.decl(d)
+			g.globals[ed.qn] = ed
+			globals = append(globals, ed)
+		}
+		slices.SortFunc(globals, func(a, b *elementDecl) int { return compareQName(a.qn, b.qn) })
+	}
+	g.doc = newDocumentGrammar(o, globals)
+	return g
+}
+
+func (g *grammars) decl(d *xsd.ElementDecl) *elementDecl {
+	if ed, ok := g.decls[d]; ok {
+		return ed
+	}
+	ed := &elementDecl{qn: qname{uri: d.Name.NS, local: d.Name.Local}, typ: d.Type, nillable: d.Nillable}
+	if ed.typ == nil {
+		ed.typ = g.anyType()
+	}
+	g.decls[d] = ed
+	return ed
+}
+
+func (g *grammars) builtinFor(qn qname) *builtinGrammar {
+	b, ok := g.builtin[qn]
+	if !ok {
+		b = newBuiltinGrammar(g.opts)
+		g.builtin[qn] = b
+	}
+	return b
+}
+
+func (g *grammars) anyType() *xsd.TypeDef {
+	td, _ := g.schema.LookupType("anyType", lexicon.NamespaceXSD)
+	return td
+}
+
+func (g *grammars) lookupType(qn qname) *xsd.TypeDef {
+	if g.schema == nil {
+		return nil
+	}
+	td, _ := g.schema.LookupType(qn.local, qn.uri)
+	return td
+}
+
+// globalAttrType returns the type of the global attribute qn, or nil when
+// there is none and its values are untyped.
+func (g *grammars) globalAttrType(qn qname) *datatype {
+	if g.schema == nil {
+		return nil
+	}
+	au, ok := g.schema.LookupAttribute(qn.local, qn.uri)
+	if !ok {
+		return nil
+	}
+	return g.attrType(au)
+}
+
+func (g *grammars) attrType(au *xsd.AttrUse) *datatype {
+	td := au.Type
+	if td == nil && au.TypeName.Local != "" {
+		td, _ = g.schema.LookupType(au.TypeName.Local, au.TypeName.NS)
+	}
+	return g.datatype(td)
+}
+
+// hasSubtypes reports whether a named type derives from td, or td is a
+// union, which is when strict grammars accept xsi:type.
+func (g *grammars) hasSubtypes(td *xsd.TypeDef) bool {
+	if g.subtypes == nil {
+		g.subtypes = map[*xsd.TypeDef]bool{}
+		for _, n := range g.schema.NamedTypes() {
+			t, _ := g.schema.LookupType(n.Local, n.NS)
+			for b := t.BaseType; b != nil && b != t; b = b.BaseType {
+				g.subtypes[b] = true
+			}
+		}
+	}
+	return g.subtypes[td] || (!td.IsComplex && td.Variety == xsd.TypeVarietyUnion)
+}
+
+// typeGrammar returns the start state of the grammar of elements of type
+// td. An empty grammar accepts the attributes of td and no content, for
+// elements with xsi:nil="true".
+func (g *grammars) typeGrammar(td *xsd.TypeDef, nillable, empty bool) *state {
+	key := typeKey{td: td, nillable: nillable, empty: empty}
+	if s, ok := g.types[key]; ok {
+		return s
+	}
+	b := &nfaBuilder{g: g}
+	start := b.attributes(td)
+	content := b.newState(false)
+	b.epsilon(start.end, content)
+	switch {
+	case empty:
+		content.ee = true
+	case !td.IsComplex || td.ContentType == xsd.ContentTypeSimple:
+		end := b.newState(false)
+		end.ee = true
+		content.edge(&terminal{kind: evCH, dt: g.datatype(simpleContentType(td))}, end)
+	case td.Name == (xsd.QName{Local: "anyType", NS: lexicon.NamespaceXSD}):
+		content.ee = true
+		content.edge(&terminal{kind: evSEAny}, content)
+		content.edge(&terminal{kind: evCH}, content)
+	default:
+		mark := len(b.states)
+		end := content
+		if td.ContentType != xsd.ContentTypeEmpty && td.ContentModel != nil {
+			end = b.group(td.ContentModel, content)
+		}
+		end.ee = true
+		if td.ContentType == xsd.ContentTypeMixed {
+			content.edge(&terminal{kind: evCH}, content)
+			for _, s := range b.states[mark:] {
+				s.edge(&terminal{kind: evCH}, s)
+			}
+		}
+	}
+	s := b.determinize(start.begin, content, td, nillable)
+	g.types[key] = s
+	return s
+}
+
+// simpleContentType returns the simple type of the text of an element of
+// type td.
+func simpleContentType(td *xsd.TypeDef) *xsd.TypeDef {
+	for t := td; t != nil; t = t.BaseType {
+		if !t.IsComplex {
+			return t
+		}
+		if t.ContentSimpleType != nil {
+			return t.ContentSimpleType
+		}
+		if t.BaseType == t {
+			break
+		}
+	}
+	return nil
+}
+
+// terminal is the terminal symbol of a production before normalization.
+type terminal struct {
+	kind  eventKind
+	qn    qname
+	uri   string
+	decl  *elementDecl
+	dt    *datatype
+	order int // position of an SE terminal in the content model
+}
+
+type terminalKey struct {
+	kind eventKind
+	qn   qname
+	uri  string
+}
+
+func (t *terminal) key() terminalKey {
+	return terminalKey{kind: t.kind, qn: t.qn, uri: t.uri}
+}
+
+// rank orders the first-level productions of a schema-informed state.
+func (t *terminal) rank() int {
+	switch t.kind {
+	case evAT:
+		return 0
+	case evATURI:
+		return 1
+	case evATAny:
+		return 2
+	case evSE:
+		return 3
+	case evSEURI:
+		return 4
+	case evSEAny:
+		return 5
+	case evEE:
+		return 6
+	}
+	return 7
+}
+
+func compareTerminals(a, b *terminal) int {
+	if c := cmp.Compare(a.rank(), b.rank()); c != 0 {
+		return c
+	}
+	switch a.kind {
+	case evAT:
+		return compareQName(a.qn, b.qn)
+	case evATURI:
+		return cmp.Compare(a.uri, b.uri)
+	}
+	return cmp.Compare(a.order, b.order)
+}
+
+type nfaEdge struct {
+	t  *terminal
+	to *nfaState
+}
+
+// nfaState is a non-terminal of a proto-grammar, before ε-productions are
+// removed and productions with the same terminal are merged.
+type nfaState struct {
+	id    int
+	attr  bool // part of the attribute uses
+	ee    bool
+	eps   []*nfaState
+	edges []nfaEdge
+}
+
+func (s *nfaState) edge(t *terminal, to *nfaState) {
+	s.edges = append(s.edges, nfaEdge{t: t, to: to})
+}
+
+type nfaBuilder struct {
+	g      *grammars
+	states []*nfaState
+	order  int
+}
+
+type fragment struct {
+	begin, end *nfaState
+}
+
+func (b *nfaBuilder) newState(attr bool) *nfaState {
+	s := &nfaState{id: len(b.states), attr: attr}
+	b.states = append(b.states, s)
+	return s
+}
+
+func (b *nfaBuilder) epsilon(from, to *nfaState) {
+	from.eps = append(from.eps, to)
+}
+
+// attributes builds the grammar of td's attribute uses, sorted by name, with
+// its attribute wildcard allowed between them.
+func (b *nfaBuilder) attributes(td *xsd.TypeDef) fragment {
+	var uses []*xsd.AttrUse
+	for _, au := range td.Attributes {
+		if !au.Prohibited {
+			uses = append(uses, au)
+		}
+	}
+	slices.SortFunc(uses, func(x, y *xsd.AttrUse) int {
+		return compareQName(qname{uri: x.Name.NS, local: x.Name.Local}, qname{uri: y.Name.NS, local: y.Name.Local})
+	})
+	wild := b.wildcard(td.AnyAttribute, evATURI, evATAny)
+
+	start := b.newState(true)
+	cur := start
+	for _, au := range uses {
+		for _, t := range wild {
+			cur.edge(t, cur)
+		}
+		next := b.newState(true)
+		cur.edge(&terminal{kind: evAT, qn: qname{uri: au.Name.NS, local: au.Name.Local}, dt: b.g.attrType(au)}, next)
+		if !au.Required {
+			b.epsilon(cur, next)
+		}
+		cur = next
+	}
+	for _, t := range wild {
+		cur.edge(t, cur)
+	}
+	return fragment{begin: start, end: cur}
+}
+
+// wildcard returns the terminals a wildcard matches: one per namespace it
+// lists, or a single any-name terminal.
+func (b *nfaBuilder) wildcard(w *xsd.Wildcard, byURI, anyName eventKind) []*terminal {
+	if w == nil {
+		return nil
+	}
+	ns := strings.TrimSpace(w.Namespace)
+	if ns == "" || ns == xsd.WildcardNSAny || ns == xsd.WildcardNSOther || ns == xsd.WildcardNSNotAbsent || w.NotNamespace != nil {
+		b.order++
+		return []*terminal{{kind: anyName, order: b.order}}
+	}
+	var ts []*terminal
+	seen := map[string]bool{}
+	for _, tok := range strings.Fields(ns) {
+		uri := tok
+		switch tok {
+		case xsd.WildcardNSLocal:
+			uri = ""
+		case xsd.WildcardNSTargetNamespace:
+			uri = w.TargetNS
+		}
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+		b.order++
+		ts = append(ts, &terminal{kind: byURI, uri: uri, order: b.order})
+	}
+	return ts
+}
+
+// particle builds the grammar of p starting at from and returns its end.
+func (b *nfaBuilder) particle(p *xsd.Particle, from *nfaState) *nfaState {
+	switch term := p.Term.(type) {
+	case *xsd.ModelGroup:
+		return b.group(term, from)
+	case *xsd.ElementDecl:
+		ts := b.element(term)
+		return b.occurs(p.MinOccurs, p.MaxOccurs, from, func(s *nfaState) *nfaState {
+			end := b.newState(false)
+			for _, t := range ts {
+				s.edge(t, end)
+			}
+			return end
+		})
+	case *xsd.Wildcard:
+		ts := b.wildcard(term, evSEURI, evSEAny)
+		return b.occurs(p.MinOccurs, p.MaxOccurs, from, func(s *nfaState) *nfaState {
+			end := b.newState(false)
+			for _, t := range ts {
+				s.edge(t, end)
+			}
+			return end
+		})
+	}
+	return from
+}
+
+// element returns the terminals of an element particle: the element unless
+// it is abstract, and the members of its substitution group, sorted by name.
+func (b *nfaBuilder) element(d *xsd.ElementDecl) []*terminal {
+	decls := []*elementDecl{}
+	if !d.Abstract {
+		decls = append(decls, b.g.decl(d))
+	}
+	if d.IsRef {
+		for _, m := range b.g.schema.SubstGroupMembers(d.Name) {
+			if !m.Abstract {
+				decls = append(decls, b.g.decl(m))
+			}
+		}
+	}
+	slices.SortFunc(decls, func(x, y *elementDecl) int { return compareQName(x.qn, y.qn) })
+	ts := make([]*terminal, 0, len(decls))
+	for _, ed := range decls {
+		b.order++
+		ts = append(ts, &terminal{kind: evSE, qn: ed.qn, decl: ed, order: b.order})
+	}
+	return ts
+}
+
+func (b *nfaBuilder) group(mg *xsd.ModelGroup, from *nfaState) *nfaState {
+	return b.occurs(mg.MinOccurs, mg.MaxOccurs, from, func(s *nfaState) *nfaState {
+		switch mg.Compositor {
+		case xsd.CompositorChoice:
+			if len(mg.Particles) == 0 {
+				return b.newState(false)
+			}
+			end := b.newState(false)
+			for _, p := range mg.Particles {
+				b.epsilon(b.particle(p, s), end)
+			}
+			return end
+		case xsd.CompositorAll:
+			// The members of an all group may come in any order; EXI does
+			// not count them.
+			loop := b.newState(false)
+			b.epsilon(s, loop)
+			for _, p := range mg.Particles {
+				b.epsilon(b.particle(p, loop), loop)
+			}
+			return loop
+		}
+		cur := s
+		for _, p := range mg.Particles {
+			cur = b.particle(p, cur)
+		}
+		return cur
+	})
+}
+
+// occurs repeats the grammar built by once between minOccurs and maxOccurs
+// times.
+func (b *nfaBuilder) occurs(minOccurs, maxOccurs int, from *nfaState, once func(*nfaState) *nfaState) *nfaState {
+	if maxOccurs == 0 {
+		return from
+	}
+	cur := from
+	for range minOccurs {
+		cur = once(cur)
+	}
+	if maxOccurs == xsd.Unbounded {
+		loop := b.newState(false)
+		b.epsilon(cur, loop)
+		b.epsilon(once(loop), loop)
+		return loop
+	}
+	end := b.newState(false)
+	for i := max(minOccurs, 1); i <= maxOccurs; i++ {
+		if i > minOccurs || minOccurs == 0 {
+			b.epsilon(cur, end)
+			cur = once(cur)
+		}
+	}
+	b.epsilon(cur, end)
+	return end
+}
+
+// determinize turns the proto-grammar into a normalized grammar: sets of
+// proto-grammar states reachable on the same events become one state, and
+// the productions of each state are put in event code order. Then it adds
+// the productions for undeclared content and returns the start state.
+func (b *nfaBuilder) determinize(begin, content *nfaState, td *xsd.TypeDef, nillable bool) *state {
+	type dstate struct {
+		members []*nfaState
+		s       *state
+	}
+	closure := func(seed []*nfaState) []*nfaState {
+		seen := map[int]bool{}
+		var out []*nfaState
+		var visit func(*nfaState)
+		visit = func(n *nfaState) {
+			if seen[n.id] {
+				return
+			}
+			seen[n.id] = true
+			out = append(out, n)
+			for _, e := range n.eps {
+				visit(e)
+			}
+		}
+		for _, n := range seed {
+			visit(n)
+		}
+		slices.SortFunc(out, func(x, y *nfaState) int { return cmp.Compare(x.id, y.id) })
+		return out
+	}
+	setKey := func(set []*nfaState) string {
+		var sb strings.Builder
+		for _, n := range set {
+			sb.WriteString(strconv.Itoa(n.id))
+			sb.WriteByte(',')
+		}
+		return sb.String()
+	}
+
+	known := map[string]*dstate{}
+	var work []*dstate
+	get := func(set []*nfaState) *state {
+		k := setKey(set)
+		if d, ok := known[k]; ok {
+			return d.s
+		}
+		d := &dstate{members: set, s: &state{}}
+		known[k] = d
+		work = append(work, d)
+		return d.s
+	}
+
+	start := get(closure([]*nfaState{begin}))
+	content2 := get(closure([]*nfaState{content}))
+	var all []*state
+	for len(work) > 0 {
+		d := work[0]
+		work = work[1:]
+		all = append(all, d.s)
+
+		var order []terminalKey
+		terms := map[terminalKey]*terminal{}
+		targets := map[terminalKey][]*nfaState{}
+		ee := false
+		for _, n := range d.members {
+			d.s.tag = d.s.tag || n.attr
+			ee = ee || n.ee
+			for _, e := range n.edges {
+				k := e.t.key()
+				if _, ok := terms[k]; !ok {
+					terms[k] = e.t
+					order = append(order, k)
+				}
+				targets[k] = append(targets[k], e.to)
+			}
+		}
+		ts := make([]*terminal, 0, len(order)+1)
+		for _, k := range order {
+			ts = append(ts, terms[k])
+		}
+		if ee {
+			ts = append(ts, &terminal{kind: evEE})
+		}
+		slices.SortStableFunc(ts, compareTerminals)
+		for _, t := range ts {
+			p := &production{kind: t.kind, qn: t.qn, uri: t.uri, decl: t.decl, dt: t.dt}
+			if t.kind != evEE {
+				p.next = get(closure(targets[t.key()]))
+			}
+			d.s.first = append(d.s.first, p)
+		}
+	}
+
+	for _, s := range all {
+		b.g.undeclared(s, s == start, content2, td, nillable)
+	}
+	return start
+}
+
+// undeclared adds the second- and third-level productions of a
+// schema-informed state: in strict mode only xsi:type and xsi:nil where the
+// schema allows them, otherwise productions for any content the schema does
+// not declare.
+func (g *grammars) undeclared(s *state, first bool, content2 *state, td *xsd.TypeDef, nillable bool) {
+	o := g.opts
+	if o.strict {
+		if first && g.hasSubtypes(td) {
+			s.second = append(s.second, &production{kind: evATType, next: s})
+		}
+		if first && nillable {
+			s.second = append(s.second, &production{kind: evATNil, next: s})
+		}
+		if first && o.has(PreservePrefixes) {
+			s.second = append(s.second, &production{kind: evNS, next: s})
+		}
+		return
+	}
+	next := s
+	if s.tag {
+		next = content2
+	}
+	if !slices.ContainsFunc(s.first, func(p *production) bool { return p.kind == evEE }) {
+		s.second = append(s.second, &production{kind: evEE})
+	}
+	if first {
+		s.second = append(s.second,
+			&production{kind: evATType, next: s},
+			&production{kind: evATNil, next: s},
+		)
+	}
+	if s.tag {
+		s.second = append(s.second, &production{kind: evATUntyped, next: s})
+	}
+	if first && o.has(PreservePrefixes) {
+		s.second = append(s.second, &production{kind: evNS, next: s})
+	}
+	s.second = append(s.second,
+		&production{kind: evSEAny, next: next},
+		&production{kind: evCHUntyped, next: next},
+	)
+	if o.has(PreserveDTD) {
+		s.second = append(s.second, &production{kind: evER, next: next})
+	}
+	s.third = fidelity(o, next)
+}
+
+// integerBounds are the value ranges of the built-in integer types.
+var integerBounds = map[string][2]string{
+	"nonPositiveInteger": {"", "0"},
+	"negativeInteger":    {"", "-1"},
+	"long":               {"-9223372036854775808", "9223372036854775807"},
+	"int":                {"-2147483648", "2147483647"},
+	"short":              {"-32768", "32767"},
+	"byte":               {"-128", "127"},
+	"nonNegativeInteger": {"0", ""},
+	"unsignedLong":       {"0", "18446744073709551615"},
+	"unsignedInt":        {"0", "4294967295"},
+	"unsignedShort":      {"0", "65535"},
+	"unsignedByte":       {"0", "255"},
+	"positiveInteger":    {"1", ""},
+	"integer":            {"", ""},
+}
+
+var dateKinds = map[string]dateKind{
+	"gYear":         dateGYear,
+	"gYearMonth":    dateGYearMonth,
+	"date":          dateDate,
+	"dateTime":      dateDateTime,
+	"dateTimeStamp": dateDateTime,
+	"gMonth":        dateGMonth,
+	"gMonthDay":     dateGMonthDay,
+	"gDay":          dateGDay,
+	"time":          dateTime,
+}
+
+// datatype returns the representation of the values of the simple type td.
+func (g *grammars) datatype(td *xsd.TypeDef) *datatype {
+	if td == nil || g.opts.has(PreserveLexicalValues) {
+		return stringType
+	}
+	if d, ok := g.datatypes[td]; ok {
+		return d
+	}
+	d := g.classify(td)
+	g.datatypes[td] = d
+	return d
+}
+
+func (g *grammars) classify(td *xsd.TypeDef) *datatype {
+	if td.IsComplex {
+		return stringType
+	}
+	switch td.Variety {
+	case xsd.TypeVarietyList:
+		if td.ItemType == nil {
+			return stringType
+		}
+		return &datatype{kind: dtList, item: g.datatype(td.ItemType)}
+	case xsd.TypeVarietyUnion:
+		return stringType
+	}
+
+	chain := baseChain(td)
+	builtin := ""
+	if last := chain[len(chain)-1]; last.Name.NS == lexicon.NamespaceXSD {
+		builtin = last.Name.Local
+	}
+	for _, t := range chain {
+		if t.Facets != nil && len(t.Facets.Enumeration) > 0 {
+			if builtin == "QName" || builtin == "NOTATION" {
+				break
+			}
+			values := slices.Clone(t.Facets.Enumeration)
+			return &datatype{kind: dtEnum, values: values, bits: bitsFor(len(values))}
+		}
+		if t.Name.NS == lexicon.NamespaceXSD {
+			break
+		}
+	}
+
+	switch builtin {
+	case "boolean":
+		for _, t := range chain {
+			if t.Facets != nil && len(t.Facets.Patterns) > 0 {
+				return &datatype{kind: dtBooleanPattern}
+			}
+		}
+		return &datatype{kind: dtBoolean}
+	case "decimal":
+		return &datatype{kind: dtDecimal}
+	case "float":
+		return &datatype{kind: dtFloat, float: 32}
+	case "double":
+		return &datatype{kind: dtFloat, float: 64}
+	case "base64Binary":
+		return &datatype{kind: dtBase64}
+	case "hexBinary":
+		return &datatype{kind: dtHex}
+	}
+	if k, ok := dateKinds[builtin]; ok {
+		return &datatype{kind: dtDateTime, date: k}
+	}
+	if bounds, ok := integerBounds[builtin]; ok {
+		return integerType(chain, bounds)
+	}
+	return stringType
+}
+
+// baseChain returns td and its ancestors up to the built-in type it is
+// derived from.
+func baseChain(td *xsd.TypeDef) []*xsd.TypeDef {
+	chain := []*xsd.TypeDef{td}
+	for t := td; t.BaseType != nil && !slices.Contains(chain, t.BaseType); t = t.BaseType {
+		if t.Name.NS == lexicon.NamespaceXSD && t.Name.Local != "" {
+			break
+		}
+		chain = append(chain, t.BaseType)
+	}
+	return chain
+}
+
+// integerType picks the representation of an integer type from the bounds
+// of its built-in base and the range facets of its derivation: n bits when
+// it has at most 4096 values, an unsigned integer when it cannot be
+// negative, and an integer otherwise.
+func integerType(chain []*xsd.TypeDef, bounds [2]string) *datatype {
+	parse := func(s string) *big.Int {
+		v, ok := new(big.Int).SetString(strings.TrimPrefix(strings.TrimSpace(s), "+"), 10)
+		if !ok {
+			return nil
+		}
+		return v
+	}
+	var lo, hi *big.Int
+	if bounds[0] != "" {
+		lo = parse(bounds[0])
+	}
+	if bounds[1] != "" {
+		hi = parse(bounds[1])
+	}
+	raise := func(v *big.Int) {
+		if v != nil && (lo == nil || v.Cmp(lo) > 0) {
+			lo = v
+		}
+	}
+	lower := func(v *big.Int) {
+		if v != nil && (hi == nil || v.Cmp(hi) < 0) {
+			hi = v
+		}
+	}
+	one := big.NewInt(1)
+	for _, t := range chain {
+		f := t.Facets
+		if f == nil {
+			continue
+		}
+		if f.MinInclusive != nil {
+			raise(parse(*f.MinInclusive))
+		}
+		if f.MinExclusive != nil {
+			if v := parse(*f.MinExclusive); v != nil {
+				raise(v.Add(v, one))
+			}
+		}
+		if f.MaxInclusive != nil {
+			lower(parse(*f.MaxInclusive))
+		}
+		if f.MaxExclusive != nil {
+			if v := parse(*f.MaxExclusive); v != nil {
+				lower(v.Sub(v, one))
+			}
+		}
+	}
+	if lo != nil && hi != nil && hi.Cmp(lo) >= 0 {
+		span := new(big.Int).Sub(hi, lo)
+		if span.Cmp(big.NewInt(4096)) < 0 {
+			return &datatype{kind: dtNBit, min: lo, bits: bitsFor(int(span.Int64()) + 1)}
+		}
+	}
+	if lo != nil && lo.Sign() >= 0 {
+		return &datatype{kind: dtUnsigned}
+	}
+	return &datatype{kind: dtInteger}
+}
+
+// schemaDecls yields the names of the elements, attributes and named types
+// declared anywhere in schema.
+func schemaDecls(schema *xsd.Schema) iter.Seq[xsd.QName] {
+	return func(yield func(xsd.QName) bool) {
+		types := map[*xsd.TypeDef]bool{}
+		groups := map[*xsd.ModelGroup]bool{}
+		var walkType func(*xsd.TypeDef) bool
+		var walkGroup func(*xsd.ModelGroup) bool
+		walkElem := func(d *xsd.ElementDecl) bool {
+			return yield(d.Name) && walkType(d.Type)
+		}
+		walkType = func(td *xsd.TypeDef) bool {
+			if td == nil || types[td] {
+				return true
+			}
+			types[td] = true
+			for _, au := range td.Attributes {
+				if !yield(au.Name) {
+					return false
+				}
+			}
+			if td.ContentModel != nil {
+				return walkGroup(td.ContentModel)
+			}
+			return true
+		}
+		walkGroup = func(mg *xsd.ModelGroup) bool {
+			if groups[mg] {
+				return true
+			}
+			groups[mg] = true
+			for _, p := range mg.Particles {
+				switch term := p.Term.(type) {
+				case *xsd.ElementDecl:
+					if !walkElem(term) {
+						return false
+					}
+				case *xsd.ModelGroup:
+					if !walkGroup(term) {
+						return false
+					}
+				}
+			}
+			return true
+		}
+
+		for _, d := range schema.Elements() {
+			if !walkElem(d) {
+				return
+			}
+		}
+		for _, au := range schema.Attributes() {
+			if !yield(au.Name) {
+				return
+			}
+		}
+		for _, n := range schema.NamedTypes() {
+			td, _ := schema.LookupType(n.Local, n.NS)
+			if !yield(n) || !walkType(td) {
+				return
+			}
+		}
+	}
+}
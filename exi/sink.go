@@ -0,0 +1,489 @@
+package exi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/sax"
+)
+
+// event is a decoded event on its way to a sink.
+type event struct {
+	kind      eventKind // evSD, evED, evSE, evEE, evNS, evAT, evATType, evCH, evCM, evPI, evDT or evER
+	qn        qname
+	prefix    string
+	hasPrefix bool
+	local     bool     // evNS: the declaration binds the element's prefix
+	value     string   // evAT, evCH, evCM, evER; the namespace of evNS
+	strs      []string // evPI: target and data; evDT: name, public and system ID and text
+	// typ, typPrefix and hasTypPrefix hold the value of an evATType.
+	typ          qname
+	typPrefix    string
+	hasTypPrefix bool
+}
+
+type nsDecl struct {
+	prefix string
+	uri    string
+}
+
+type attrValue struct {
+	qn     qname
+	prefix string
+	value  string
+}
+
+// sink receives a decoded document.
+type sink interface {
+	startDocument(ctx context.Context) error
+	endDocument(ctx context.Context) error
+	doctype(ctx context.Context, name, publicID, systemID, text string) error
+	startElement(ctx context.Context, qn qname, prefix string, decls []nsDecl, attrs []attrValue) error
+	endElement(ctx context.Context) error
+	characters(ctx context.Context, s string) error
+	comment(ctx context.Context, s string) error
+	processingInstruction(ctx context.Context, target, data string) error
+	entityRef(ctx context.Context, name string) error
+}
+
+// startTag is a start tag whose namespace declarations and attributes are
+// still being decoded.
+type startTag struct {
+	ev    *event
+	decls []nsDecl
+	attrs []*event
+}
+
+// dispatcher turns decoded events into calls on a sink. It puts start tags
+// together and chooses prefixes for the names the stream does not give
+// one, declaring them where needed.
+type dispatcher struct {
+	sink     sink
+	st       *stringTable
+	prefixes bool
+	pending  *startTag
+	scopes   [][]nsDecl
+}
+
+func newDispatcher(s sink, st *stringTable) *dispatcher {
+	return &dispatcher{sink: s, st: st}
+}
+
+func (d *dispatcher) dispatch(ctx context.Context, ev *event) error {
+	switch ev.kind {
+	case evNS:
+		if d.pending != nil {
+			d.pending.decls = append(d.pending.decls, nsDecl{prefix: ev.prefix, uri: ev.value})
+			if ev.local {
+				d.pending.ev.prefix, d.pending.ev.hasPrefix = ev.prefix, true
+			}
+		}
+		return nil
+	case evAT, evATType:
+		if d.pending != nil {
+			d.pending.attrs = append(d.pending.attrs, ev)
+		}
+		return nil
+	}
+	if err := d.flushStart(ctx); err != nil {
+		return err
+	}
+	switch ev.kind {
+	case evSD:
+		return d.sink.startDocument(ctx)
+	case evED:
+		return d.sink.endDocument(ctx)
+	case evSE:
+		d.pending = &startTag{ev: ev}
+	case evEE:
+		if n := len(d.scopes); n > 0 {
+			d.scopes = d.scopes[:n-1]
+		}
+		return d.sink.endElement(ctx)
+	case evCH:
+		if ev.value == "" {
+			return nil
+		}
+		return d.sink.characters(ctx, ev.value)
+	case evCM:
+		return d.sink.comment(ctx, ev.value)
+	case evPI:
+		return d.sink.processingInstruction(ctx, ev.strs[0], ev.strs[1])
+	case evDT:
+		return d.sink.doctype(ctx, ev.strs[0], ev.strs[1], ev.strs[2], ev.strs[3])
+	case evER:
+		return d.sink.entityRef(ctx, ev.value)
+	}
+	return nil
+}
+
+// lookup returns the namespace prefix is bound to in the scope of the
+// pending start tag.
+func (d *dispatcher) lookup(decls []nsDecl, prefix string) (string, bool) {
+	if prefix == lexicon.PrefixXML {
+		return lexicon.NamespaceXML, true
+	}
+	for i := len(decls) - 1; i >= 0; i-- {
+		if decls[i].prefix == prefix {
+			return decls[i].uri, true
+		}
+	}
+	for i := len(d.scopes) - 1; i >= 0; i-- {
+		for _, ns := range d.scopes[i] {
+			if ns.prefix == prefix {
+				return ns.uri, true
+			}
+		}
+	}
+	return "", prefix == ""
+}
+
+// boundPrefix returns a prefix bound to uri in the scope of decls. The
+// empty prefix is only considered when allowDefault is set.
+func (d *dispatcher) boundPrefix(decls []nsDecl, uri string, allowDefault bool) (string, bool) {
+	if uri == lexicon.NamespaceXML {
+		return lexicon.PrefixXML, true
+	}
+	if allowDefault {
+		if u, _ := d.lookup(decls, ""); u == uri {
+			return "", true
+		}
+	}
+	bound := func(p string) bool {
+		u, ok := d.lookup(decls, p)
+		return p != "" && ok && u == uri
+	}
+	for i := len(decls) - 1; i >= 0; i-- {
+		if p := decls[i].prefix; bound(p) {
+			return p, true
+		}
+	}
+	for i := len(d.scopes) - 1; i >= 0; i-- {
+		for _, ns := range d.scopes[i] {
+			if bound(ns.prefix) {
+				return ns.prefix, true
+			}
+		}
+	}
+	return "", false
+}
+
+// prefixFor returns a prefix bound to uri in the scope of decls, declaring
+// a new one when there is none. The empty prefix is only used when
+// allowDefault is set.
+func (d *dispatcher) prefixFor(decls *[]nsDecl, uri string, allowDefault bool) string {
+	if p, ok := d.boundPrefix(*decls, uri, allowDefault); ok {
+		return p
+	}
+	// Prefer a prefix the string table knows for the namespace, such as xsi.
+	var p string
+	if part := d.st.partition(uri); part != nil {
+		for _, c := range part.prefixes {
+			if _, taken := d.lookup(*decls, c); c != "" && !taken {
+				p = c
+				break
+			}
+		}
+	}
+	for i := 0; p == ""; i++ {
+		c := "ns" + strconv.Itoa(i)
+		if _, taken := d.lookup(*decls, c); !taken {
+			p = c
+		}
+	}
+	*decls = append(*decls, nsDecl{prefix: p, uri: uri})
+	return p
+}
+
+// flushStart hands the pending start tag to the sink.
+func (d *dispatcher) flushStart(ctx context.Context) error {
+	t := d.pending
+	if t == nil {
+		return nil
+	}
+	d.pending = nil
+	decls := t.decls
+	ev := t.ev
+
+	prefix := ev.prefix
+	switch {
+	case ev.hasPrefix && d.prefixes:
+	case ev.qn.uri == "":
+		prefix = ""
+		if u, _ := d.lookup(decls, ""); u != "" {
+			decls = append(decls, nsDecl{})
+		}
+	case d.prefixes:
+		prefix = d.prefixFor(&decls, ev.qn.uri, true)
+	default:
+		var ok bool
+		if prefix, ok = d.boundPrefix(decls, ev.qn.uri, true); !ok {
+			prefix = ""
+			decls = append(decls, nsDecl{uri: ev.qn.uri})
+		}
+	}
+
+	attrs := make([]attrValue, 0, len(t.attrs))
+	for _, a := range t.attrs {
+		av := attrValue{qn: a.qn, prefix: a.prefix, value: a.value}
+		if a.qn.uri == "" {
+			av.prefix = ""
+		} else if !a.hasPrefix || !d.prefixes {
+			av.prefix = d.prefixFor(&decls, a.qn.uri, false)
+		}
+		if a.kind == evATType {
+			tp := a.typPrefix
+			if !a.hasTypPrefix || !d.prefixes {
+				tp = ""
+				if a.typ.uri != "" {
+					tp = d.prefixFor(&decls, a.typ.uri, true)
+				}
+			}
+			av.value = a.typ.local
+			if tp != "" {
+				av.value = tp + ":" + a.typ.local
+			}
+		}
+		attrs = append(attrs, av)
+	}
+	d.scopes = append(d.scopes, decls)
+	return d.sink.startElement(ctx, ev.qn, prefix, decls, attrs)
+}
+
+// parseDoctype parses the document type declaration of a DT event.
+func parseDoctype(ctx context.Context, name, publicID, systemID, text string) (*helium.Document, error) {
+	src := "<!DOCTYPE " + name
+	switch {
+	case publicID != "":
+		src += " PUBLIC " + quote(publicID) + " " + quote(systemID)
+	case systemID != "":
+		src += " SYSTEM " + quote(systemID)
+	}
+	if text != "" {
+		src += " [" + text + "]"
+	}
+	src += "><doc/>"
+	doc, err := helium.NewParser().Parse(ctx, []byte(src))
+	if err != nil {
+		return nil, fmt.Errorf("%w: document type declaration: %w", ErrMalformed, err)
+	}
+	return doc, nil
+}
+
+// quote quotes s with whichever quote it does not contain.
+func quote(s string) string {
+	for _, c := range s {
+		if c == '"' {
+			return "'" + s + "'"
+		}
+	}
+	return `"` + s + `"`
+}
+
+// saxSink passes a decoded document to a SAX handler.
+type saxSink struct {
+	h     sax.SAX2Handler
+	names []qname
+	pfx   []string
+}
+
+// saxResult maps the result of a SAX callback: an unhandled event is not an
+// error.
+func saxResult(err error) error {
+	if err == sax.ErrHandlerUnspecified { //nolint:errorlint // sentinel returned as is
+		return nil
+	}
+	return err
+}
+
+func (s *saxSink) startDocument(ctx context.Context) error {
+	return saxResult(s.h.StartDocument(ctx))
+}
+
+func (s *saxSink) endDocument(ctx context.Context) error {
+	return saxResult(s.h.EndDocument(ctx))
+}
+
+// doctype reports the declarations of the document type declaration, as a
+// parser would, by parsing it.
+func (s *saxSink) doctype(ctx context.Context, name, publicID, systemID, text string) error {
+	doc, err := parseDoctype(ctx, name, publicID, systemID, text)
+	if err != nil {
+		return err
+	}
+	dtd := doc.IntSubset()
+	if dtd == nil {
+		return nil
+	}
+	f := sax.NewFilter(s.h)
+	f.SetOnSetDocumentLocator(sax.SetDocumentLocatorFunc(func(context.Context, sax.DocumentLocator) error { return nil }))
+	f.SetOnStartDocument(sax.StartDocumentFunc(func(context.Context) error { return nil }))
+	f.SetOnEndDocument(sax.EndDocumentFunc(func(context.Context) error { return nil }))
+	return helium.EmitSAX(ctx, dtd, f)
+}
+
+type saxNamespace nsDecl
+
+func (n saxNamespace) Prefix() string { return n.prefix }
+func (n saxNamespace) URI() string    { return n.uri }
+
+type saxAttribute attrValue
+
+func (a saxAttribute) LocalName() string { return a.qn.local }
+func (a saxAttribute) Prefix() string    { return a.prefix }
+func (a saxAttribute) Value() string     { return a.value }
+func (a saxAttribute) IsDefault() bool   { return false }
+
+func (a saxAttribute) Name() string {
+	if a.prefix == "" {
+		return a.qn.local
+	}
+	return a.prefix + ":" + a.qn.local
+}
+
+func (s *saxSink) startElement(ctx context.Context, qn qname, prefix string, decls []nsDecl, attrs []attrValue) error {
+	var nss []sax.Namespace
+	for _, d := range decls {
+		nss = append(nss, saxNamespace(d))
+	}
+	var as []sax.Attribute
+	for _, a := range attrs {
+		as = append(as, saxAttribute(a))
+	}
+	s.names = append(s.names, qn)
+	s.pfx = append(s.pfx, prefix)
+	return saxResult(s.h.StartElementNS(ctx, qn.local, prefix, qn.uri, nss, as))
+}
+
+func (s *saxSink) endElement(ctx context.Context) error {
+	n := len(s.names) - 1
+	if n < 0 {
+		return nil
+	}
+	qn, prefix := s.names[n], s.pfx[n]
+	s.names, s.pfx = s.names[:n], s.pfx[:n]
+	return saxResult(s.h.EndElementNS(ctx, qn.local, prefix, qn.uri))
+}
+
+func (s *saxSink) characters(ctx context.Context, v string) error {
+	return saxResult(s.h.Characters(ctx, []byte(v)))
+}
+
+func (s *saxSink) comment(ctx context.Context, v string) error {
+	return saxResult(s.h.Comment(ctx, []byte(v)))
+}
+
+func (s *saxSink) processingInstruction(ctx context.Context, target, data string) error {
+	return saxResult(s.h.ProcessingInstruction(ctx, target, data))
+}
+
+func (s *saxSink) entityRef(ctx context.Context, name string) error {
+	return saxResult(s.h.Reference(ctx, name))
+}
+
+// domSink builds a document from a decoded one.
+type domSink struct {
+	doc   *helium.Document
+	stack []*helium.Element
+}
+
+func (s *domSink) startDocument(context.Context) error {
+	s.doc = helium.NewDocument("1.0", "", helium.StandaloneImplicitNo)
+	return nil
+}
+
+func (s *domSink) endDocument(context.Context) error {
+	return nil
+}
+
+func (s *domSink) doctype(ctx context.Context, name, publicID, systemID, text string) error {
+	doc, err := parseDoctype(ctx, name, publicID, systemID, text)
+	if err != nil {
+		return err
+	}
+	return helium.CopyDTDInfo(doc, s.doc)
+}
+
+// add adds n to the open element, or to the document.
+func (s *domSink) add(n helium.Node) error {
+	if k := len(s.stack); k > 0 {
+		return s.stack[k-1].AddChild(n)
+	}
+	return s.doc.AddChild(n)
+}
+
+func (s *domSink) startElement(_ context.Context, qn qname, prefix string, decls []nsDecl, attrs []attrValue) error {
+	e, err := s.doc.CreateElement(qn.local)
+	if err != nil {
+		return err
+	}
+	for _, d := range decls {
+		if err := e.DeclareNamespace(d.prefix, d.uri); err != nil {
+			return err
+		}
+	}
+	if qn.uri != "" {
+		ns, err := s.doc.CreateNamespace(prefix, qn.uri)
+		if err != nil {
+			return err
+		}
+		e.SetNamespace(ns)
+	}
+	for _, a := range attrs {
+		if a.qn.uri == "" {
+			if err := e.SetAttribute(a.qn.local, a.value); err != nil {
+				return err
+			}
+			continue
+		}
+		ns, err := s.doc.CreateNamespace(a.prefix, a.qn.uri)
+		if err != nil {
+			return err
+		}
+		if err := e.SetAttributeNS(a.qn.local, a.value, ns); err != nil {
+			return err
+		}
+	}
+	if len(s.stack) == 0 {
+		if err := s.doc.SetDocumentElement(e); err != nil {
+			return err
+		}
+	} else if err := s.add(e); err != nil {
+		return err
+	}
+	s.stack = append(s.stack, e)
+	return nil
+}
+
+func (s *domSink) endElement(context.Context) error {
+	if n := len(s.stack); n > 0 {
+		s.stack = s.stack[:n-1]
+	}
+	return nil
+}
+
+func (s *domSink) characters(_ context.Context, v string) error {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	return s.add(s.doc.CreateText([]byte(v)))
+}
+
+func (s *domSink) comment(_ context.Context, v string) error {
+	return s.add(s.doc.CreateComment([]byte(v)))
+}
+
+func (s *domSink) processingInstruction(_ context.Context, target, data string) error {
+	return s.add(s.doc.CreatePI(target, data))
+}
+
+func (s *domSink) entityRef(_ context.Context, name string) error {
+	ref, err := s.doc.CreateReference(name)
+	if err != nil {
+		return err
+	}
+	return s.add(ref)
+}
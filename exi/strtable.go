@@ -0,0 +1,395 @@
+package exi
+
+import (
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/xsd"
+)
+
+// qname is an expanded name: a namespace URI and a local name.
+type qname struct {
+	uri   string
+	local string
+}
+
+func compareQName(a, b qname) int {
+	if a.local != b.local {
+		if a.local < b.local {
+			return -1
+		}
+		return 1
+	}
+	if a.uri != b.uri {
+		if a.uri < b.uri {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+var (
+	qnXSIType = qname{uri: lexicon.NamespaceXSI, local: "type"}
+	qnXSINil  = qname{uri: lexicon.NamespaceXSI, local: "nil"}
+)
+
+// xsdTypeNames are the local names the string table starts with in the XML
+// Schema namespace when grammars are schema-informed (EXI Appendix D.3).
+var xsdTypeNames = []string{
+	"ENTITIES", "ENTITY", "ID", "IDREF", "IDREFS", "NCName", "NMTOKEN",
+	"NMTOKENS", "NOTATION", "Name", "QName", "anySimpleType", "anyType",
+	"anyURI", "base64Binary", "boolean", "byte", "date", "dateTime",
+	"decimal", "double", "duration", "float", "gDay", "gMonth", "gMonthDay",
+	"gYear", "gYearMonth", "hexBinary", "int", "integer", "language", "long",
+	"negativeInteger", "nonNegativeInteger", "nonPositiveInteger",
+	"normalizedString", "positiveInteger", "short", "string", "time", "token",
+	"unsignedByte", "unsignedInt", "unsignedLong", "unsignedShort",
+}
+
+// uriPartition holds the prefixes and local names known for one namespace.
+type uriPartition struct {
+	id       int
+	uri      string
+	prefixes []string
+	locals   []string
+	localIDs map[string]int
+}
+
+func (p *uriPartition) addLocal(local string) {
+	if _, ok := p.localIDs[local]; ok {
+		return
+	}
+	p.localIDs[local] = len(p.locals)
+	p.locals = append(p.locals, local)
+}
+
+// valueEntry is one string in the global value partition.
+type valueEntry struct {
+	value   string
+	qn      qname
+	localID int
+}
+
+// localValues is the local value partition of one element or attribute name.
+type localValues struct {
+	values []string
+	ids    map[string]int
+}
+
+// stringTable is the EXI string table: the URI, prefix and local-name
+// partitions and the global and local value partitions. Encoder and decoder
+// grow it the same way, so an entry can be sent once and referred to by its
+// compact identifier afterwards.
+type stringTable struct {
+	uris     []*uriPartition
+	uriIDs   map[string]int
+	global   []valueEntry
+	globalID map[string]int
+	next     int // slot the next value replaces once global is full
+	locals   map[qname]*localValues
+	maxLen   int
+	capacity int
+}
+
+func newStringTable(o *optionsConfig, schema *xsd.Schema) *stringTable {
+	t := &stringTable{
+		uriIDs:   map[string]int{},
+		globalID: map[string]int{},
+		locals:   map[qname]*localValues{},
+		maxLen:   o.valueMaxLength,
+		capacity: o.valuePartitionCapacity,
+	}
+	t.addURI("").prefixes = []string{""}
+	xml := t.addURI(lexicon.NamespaceXML)
+	xml.prefixes = []string{lexicon.PrefixXML}
+	for _, l := range []string{"base", "id", "lang", "space"} {
+		xml.addLocal(l)
+	}
+	xsi := t.addURI(lexicon.NamespaceXSI)
+	xsi.prefixes = []string{"xsi"}
+	xsi.addLocal("nil")
+	xsi.addLocal("type")
+	if schema == nil {
+		return t
+	}
+	xs := t.addURI(lexicon.NamespaceXSD)
+	for _, l := range xsdTypeNames {
+		xs.addLocal(l)
+	}
+	names := schemaNames(schema)
+	uris := make([]string, 0, len(names))
+	for uri := range names {
+		uris = append(uris, uri)
+	}
+	slices.Sort(uris)
+	for _, uri := range uris {
+		p := t.partition(uri)
+		if p == nil {
+			p = t.addURI(uri)
+		}
+		locals := names[uri]
+		slices.Sort(locals)
+		for _, l := range locals {
+			p.addLocal(l)
+		}
+	}
+	return t
+}
+
+// schemaNames collects the names of every element, attribute and named type
+// the schema declares, by namespace. Names in the XML Schema namespace are
+// left out: its built-in types are already in the table.
+func schemaNames(schema *xsd.Schema) map[string][]string {
+	names := map[string][]string{}
+	seen := map[qname]bool{}
+	add := func(n xsd.QName) {
+		qn := qname{uri: n.NS, local: n.Local}
+		if n.Local == "" || n.NS == lexicon.NamespaceXSD || seen[qn] {
+			return
+		}
+		seen[qn] = true
+		names[n.NS] = append(names[n.NS], n.Local)
+	}
+	for n := range schemaDecls(schema) {
+		add(n)
+	}
+	return names
+}
+
+func (t *stringTable) addURI(uri string) *uriPartition {
+	p := &uriPartition{id: len(t.uris), uri: uri, localIDs: map[string]int{}}
+	t.uriIDs[uri] = p.id
+	t.uris = append(t.uris, p)
+	return p
+}
+
+func (t *stringTable) partition(uri string) *uriPartition {
+	id, ok := t.uriIDs[uri]
+	if !ok {
+		return nil
+	}
+	return t.uris[id]
+}
+
+func (t *stringTable) writeURI(w *bitWriter, uri string) *uriPartition {
+	n := bitsFor(len(t.uris) + 1)
+	if p := t.partition(uri); p != nil {
+		w.bits(n, uint64(p.id+1))
+		return p
+	}
+	w.bits(n, 0)
+	w.str(uri)
+	return t.addURI(uri)
+}
+
+func (t *stringTable) readURI(r *bitReader) *uriPartition {
+	id := int(r.bits(bitsFor(len(t.uris) + 1)))
+	if r.err != nil {
+		return nil
+	}
+	if id == 0 {
+		uri := r.str()
+		if r.err != nil {
+			return nil
+		}
+		if p := t.partition(uri); p != nil {
+			return p
+		}
+		return t.addURI(uri)
+	}
+	if id > len(t.uris) {
+		r.fail(fmt.Errorf("%w: URI %d out of range", ErrMalformed, id))
+		return nil
+	}
+	return t.uris[id-1]
+}
+
+func (t *stringTable) writeLocal(w *bitWriter, p *uriPartition, local string) {
+	if id, ok := p.localIDs[local]; ok {
+		w.unsigned(0)
+		w.bits(bitsFor(len(p.locals)), uint64(id))
+		return
+	}
+	w.unsigned(uint64(utf8.RuneCountInString(local)) + 1)
+	w.chars(local)
+	p.addLocal(local)
+}
+
+func (t *stringTable) readLocal(r *bitReader, p *uriPartition) string {
+	n := r.length()
+	if r.err != nil {
+		return ""
+	}
+	if n == 0 {
+		id := int(r.bits(bitsFor(len(p.locals))))
+		if id >= len(p.locals) {
+			r.fail(fmt.Errorf("%w: local name %d out of range", ErrMalformed, id))
+			return ""
+		}
+		return p.locals[id]
+	}
+	local := r.chars(n - 1)
+	if r.err == nil {
+		p.addLocal(local)
+	}
+	return local
+}
+
+func (t *stringTable) writeQName(w *bitWriter, qn qname) *uriPartition {
+	p := t.writeURI(w, qn.uri)
+	t.writeLocal(w, p, qn.local)
+	return p
+}
+
+func (t *stringTable) readQName(r *bitReader) (qname, *uriPartition) {
+	p := t.readURI(r)
+	if p == nil {
+		return qname{}, nil
+	}
+	return qname{uri: p.uri, local: t.readLocal(r, p)}, p
+}
+
+// writePrefix writes the prefix of a qualified name as an index into the
+// prefixes known for its namespace. When none is known yet, it takes no
+// bits and the decoder learns it from the element's namespace declarations.
+func (t *stringTable) writePrefix(w *bitWriter, p *uriPartition, prefix string) {
+	if len(p.prefixes) == 0 {
+		return
+	}
+	w.bits(bitsFor(len(p.prefixes)), uint64(max(slices.Index(p.prefixes, prefix), 0)))
+}
+
+// readPrefix reads the prefix of a qualified name. ok is false when the
+// namespace has no prefixes yet.
+func (t *stringTable) readPrefix(r *bitReader, p *uriPartition) (string, bool) {
+	if len(p.prefixes) == 0 {
+		return "", false
+	}
+	id := int(r.bits(bitsFor(len(p.prefixes))))
+	if id >= len(p.prefixes) {
+		r.fail(fmt.Errorf("%w: prefix %d out of range", ErrMalformed, id))
+		return "", false
+	}
+	return p.prefixes[id], true
+}
+
+// writeNSPrefix writes the prefix of a namespace declaration, adding it to
+// the table when it is new.
+func (t *stringTable) writeNSPrefix(w *bitWriter, p *uriPartition, prefix string) {
+	n := bitsFor(len(p.prefixes) + 1)
+	if id := slices.Index(p.prefixes, prefix); id >= 0 {
+		w.bits(n, uint64(id+1))
+		return
+	}
+	w.bits(n, 0)
+	w.str(prefix)
+	p.prefixes = append(p.prefixes, prefix)
+}
+
+func (t *stringTable) readNSPrefix(r *bitReader, p *uriPartition) string {
+	id := int(r.bits(bitsFor(len(p.prefixes) + 1)))
+	if r.err != nil {
+		return ""
+	}
+	if id == 0 {
+		prefix := r.str()
+		if r.err == nil && !slices.Contains(p.prefixes, prefix) {
+			p.prefixes = append(p.prefixes, prefix)
+		}
+		return prefix
+	}
+	if id > len(p.prefixes) {
+		r.fail(fmt.Errorf("%w: prefix %d out of range", ErrMalformed, id))
+		return ""
+	}
+	return p.prefixes[id-1]
+}
+
+// writeValue writes an untyped or string value of the element or attribute
+// qn: as a hit in qn's local value partition, a hit in the global one, or a
+// literal that is then added to both.
+func (t *stringTable) writeValue(w *bitWriter, qn qname, s string) {
+	if loc := t.locals[qn]; loc != nil {
+		if id, ok := loc.ids[s]; ok {
+			w.unsigned(0)
+			w.bits(bitsFor(len(loc.values)), uint64(id))
+			return
+		}
+	}
+	if id, ok := t.globalID[s]; ok {
+		w.unsigned(1)
+		w.bits(bitsFor(len(t.global)), uint64(id))
+		return
+	}
+	n := utf8.RuneCountInString(s)
+	w.unsigned(uint64(n) + 2)
+	w.chars(s)
+	t.addValue(qn, s, n)
+}
+
+func (t *stringTable) readValue(r *bitReader, qn qname) string {
+	n := r.length()
+	if r.err != nil {
+		return ""
+	}
+	switch n {
+	case 0:
+		loc := t.locals[qn]
+		if loc == nil {
+			r.fail(fmt.Errorf("%w: no local values for %s", ErrMalformed, qn.local))
+			return ""
+		}
+		id := int(r.bits(bitsFor(len(loc.values))))
+		if id >= len(loc.values) {
+			r.fail(fmt.Errorf("%w: local value %d out of range", ErrMalformed, id))
+			return ""
+		}
+		return loc.values[id]
+	case 1:
+		id := int(r.bits(bitsFor(len(t.global))))
+		if id >= len(t.global) {
+			r.fail(fmt.Errorf("%w: global value %d out of range", ErrMalformed, id))
+			return ""
+		}
+		return t.global[id].value
+	}
+	s := r.chars(n - 2)
+	if r.err == nil {
+		t.addValue(qn, s, n-2)
+	}
+	return s
+}
+
+// addValue adds s, of n characters, to the value partitions. Empty values
+// and values longer than the maximum are not added. Once the global
+// partition is at capacity each new value replaces the oldest one, which
+// also leaves its local partition.
+func (t *stringTable) addValue(qn qname, s string, n int) {
+	if n == 0 || (t.maxLen >= 0 && n > t.maxLen) || t.capacity == 0 {
+		return
+	}
+	loc := t.locals[qn]
+	if loc == nil {
+		loc = &localValues{ids: map[string]int{}}
+		t.locals[qn] = loc
+	}
+	e := valueEntry{value: s, qn: qn, localID: len(loc.values)}
+	loc.ids[s] = e.localID
+	loc.values = append(loc.values, s)
+	if t.capacity < 0 || len(t.global) < t.capacity {
+		t.globalID[s] = len(t.global)
+		t.global = append(t.global, e)
+		return
+	}
+	old := t.global[t.next]
+	delete(t.globalID, old.value)
+	if oldLoc := t.locals[old.qn]; oldLoc != nil && oldLoc.ids[old.value] == old.localID {
+		delete(oldLoc.ids, old.value)
+	}
+	t.global[t.next] = e
+	t.globalID[s] = t.next
+	t.next = (t.next + 1) % t.capacity
+}
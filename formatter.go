@@ -0,0 +1,694 @@
+package helium
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+)
+
+// DefaultFormatWidth is the line width a [Formatter] fits start tags and
+// comments into unless [Formatter.Width] says otherwise.
+const DefaultFormatWidth = 100
+
+// AttributeWrap selects how a [Formatter] breaks a start tag that does not
+// fit in the line width.
+type AttributeWrap int
+
+const (
+	// AttributeWrapOnePerLine puts each attribute on a line of its own,
+	// indented one level deeper than the element.
+	AttributeWrapOnePerLine AttributeWrap = iota
+	// AttributeWrapAligned keeps the first attribute on the element's line
+	// and aligns the others under it.
+	AttributeWrapAligned
+	// AttributeWrapNever keeps every start tag on one line.
+	AttributeWrapNever
+)
+
+// AttributeOrder selects the order in which a [Formatter] writes the
+// attributes of a start tag. Namespace declarations always come first.
+type AttributeOrder int
+
+const (
+	// AttributeOrderSource keeps attributes and namespace declarations in
+	// document order.
+	AttributeOrderSource AttributeOrder = iota
+	// AttributeOrderName sorts namespace declarations by prefix and
+	// attributes by qualified name.
+	AttributeOrderName
+	// AttributeOrderCanonical sorts namespace declarations by prefix and
+	// attributes by namespace URI, then local name, as Canonical XML does.
+	AttributeOrderCanonical
+)
+
+type formatterConfig struct {
+	width     int
+	indent    string
+	wrap      AttributeWrap
+	order     AttributeOrder
+	maxBlank  int
+	reflow    bool
+	parser    Parser
+	hasParser bool
+}
+
+// Formatter lays XML documents out for reading, in the manner of gofmt:
+// element content is indented, start tags that are too long are wrapped,
+// and runs of blank lines are collapsed. Formatting never changes what a
+// document means. Text is left exactly as written, including its
+// character and entity references, so mixed content and elements under
+// xml:space="preserve" keep their layout; only whitespace between the
+// children of elements whose content is all elements, comments and
+// processing instructions is rewritten, and elements without content are
+// written as empty-element tags. An element the document's DTD declares with
+// mixed or ANY content, or gives a default xml:space="preserve", keeps its
+// whitespace too. The output of a Formatter is
+// stable: formatting it again changes nothing.
+//
+// It uses clone-on-write semantics: each builder method returns a new
+// Formatter sharing the underlying config until mutation.
+type Formatter struct {
+	cfg *formatterConfig
+}
+
+// NewFormatter creates a Formatter that indents by two spaces, wraps start
+// tags wider than [DefaultFormatWidth] one attribute per line, keeps
+// attributes in document order and keeps at most one blank line between
+// nodes.
+func NewFormatter() Formatter {
+	return Formatter{cfg: &formatterConfig{width: DefaultFormatWidth, indent: "  ", maxBlank: 1}}
+}
+
+func (f Formatter) clone() Formatter {
+	if f.cfg == nil {
+		return NewFormatter()
+	}
+	cp := *f.cfg
+	return Formatter{cfg: &cp}
+}
+
+// Width sets the line width start tags and reflowed comments are fitted
+// into. Columns count characters, with a tab advancing to the next multiple
+// of eight. Zero or a negative value disables wrapping.
+func (f Formatter) Width(n int) Formatter {
+	f = f.clone()
+	f.cfg.width = n
+	return f
+}
+
+// Indent sets the string written once per level of nesting. It should
+// hold only spaces and tabs: anything else becomes element content.
+func (f Formatter) Indent(s string) Formatter {
+	f = f.clone()
+	f.cfg.indent = s
+	return f
+}
+
+// AttributeWrap sets how start tags wider than the line width are broken.
+func (f Formatter) AttributeWrap(mode AttributeWrap) Formatter {
+	f = f.clone()
+	f.cfg.wrap = mode
+	return f
+}
+
+// AttributeOrder sets the order in which attributes are written.
+func (f Formatter) AttributeOrder(order AttributeOrder) Formatter {
+	f = f.clone()
+	f.cfg.order = order
+	return f
+}
+
+// MaxBlankLines sets how many consecutive blank lines are kept between two
+// nodes that were separated by blank lines in the source. Zero removes
+// them all. Blank lines before the first and after the last child of an
+// element are always removed.
+func (f Formatter) MaxBlankLines(n int) Formatter {
+	f = f.clone()
+	f.cfg.maxBlank = max(n, 0)
+	return f
+}
+
+// ReflowComments controls whether comments that do not fit in the line
+// width are rewrapped. A reflowed comment puts its text on lines of their
+// own between the <!-- and --> delimiters, one level deeper than the
+// comment, keeping blank lines between paragraphs. Other comments, and all
+// comments when reflowing is off, keep their text as written.
+func (f Formatter) ReflowComments(v bool) Formatter {
+	f = f.clone()
+	f.cfg.reflow = v
+	return f
+}
+
+// Parser sets the parser [Formatter.Format] reads documents with.
+// [Parser.PreserveLexical] is always enabled on it.
+func (f Formatter) Parser(p Parser) Formatter {
+	f = f.clone()
+	f.cfg.parser = p
+	f.cfg.hasParser = true
+	return f
+}
+
+// Format parses src and returns it formatted. It returns the parse error
+// when src is not well-formed.
+func (f Formatter) Format(ctx context.Context, src []byte) ([]byte, error) {
+	if f.cfg == nil {
+		f = NewFormatter()
+	}
+	p := NewParser()
+	if f.cfg.hasParser {
+		p = f.cfg.parser
+	}
+	doc, err := p.PreserveLexical(true).Parse(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := f.WriteTo(&buf, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo writes doc to out formatted. A document parsed with
+// [Parser.PreserveLexical] keeps the spelling of its text, XML declaration
+// and DOCTYPE, and the blank lines around its top-level nodes; other
+// documents are written as [Writer] would write those parts. Output is
+// encoded in the document's encoding.
+func (f Formatter) WriteTo(out io.Writer, doc *Document) error {
+	if doc == nil {
+		return ErrNilNode
+	}
+	if f.cfg == nil {
+		f = NewFormatter()
+	}
+	return Writer{layout: f.cfg}.WriteTo(out, doc)
+}
+
+// layoutWriter is the state of one formatting pass. It writes through the
+// session, so names, escaping and namespace bookkeeping are exactly those
+// of a plain [Writer].
+type layoutWriter struct {
+	s   *writeSession
+	cfg *formatterConfig
+	out io.Writer
+	col int // column of the next character on the current line
+	// subsets are the document's DTDs, which decide whether whitespace in
+	// an element they declare is content.
+	subsets []*DTD
+}
+
+// startToken is one attribute or namespace declaration of a start tag, as
+// written.
+type startToken struct {
+	text  string
+	ns    bool
+	key   string // prefix, or qualified name
+	uri   string
+	local string
+}
+
+func (s *writeSession) writeLayoutDoc(out io.Writer, doc *Document) error {
+	l := &layoutWriter{s: s, cfg: s.layout, out: out}
+	for _, dtd := range []*DTD{doc.intSubset, doc.extSubset} {
+		if dtd != nil {
+			l.subsets = append(l.subsets, dtd)
+		}
+	}
+	li := s.lexical
+
+	newline := false
+	switch {
+	case li != nil:
+		l.writeBytes(li.bom)
+		if li.decl != nil {
+			if li.declSig == lexicalSig(doc) {
+				l.writeBytes(li.decl)
+			} else if err := s.dumpDocContent(l, doc); err != nil {
+				return err
+			}
+			newline = true
+		}
+	default:
+		if err := s.dumpDocContent(l, doc); err != nil {
+			return err
+		}
+	}
+
+	for child := range Children(doc) {
+		if newline || l.col > 0 {
+			l.newline()
+		}
+		if li != nil && newline {
+			if ln, ok := li.nodes[child]; ok {
+				l.blankLines(bytes.Count(ln.lead, []byte{'\n'}) - 1)
+			}
+		}
+		newline = true
+		if err := l.node(child, 0, false); err != nil {
+			return err
+		}
+	}
+	l.newline()
+	return s.err
+}
+
+// Write implements io.Writer so that session methods can write through l
+// while it tracks the column.
+func (l *layoutWriter) Write(p []byte) (int, error) {
+	if i := bytes.LastIndexByte(p, '\n'); i >= 0 {
+		l.col = columns(0, p[i+1:])
+	} else {
+		l.col = columns(l.col, p)
+	}
+	return l.out.Write(p)
+}
+
+func (l *layoutWriter) writeBytes(b []byte) {
+	if len(b) > 0 {
+		l.s.writeBytes(l, b)
+	}
+}
+
+func (l *layoutWriter) writeString(s string) {
+	if s != "" {
+		l.s.writeString(l, s)
+	}
+}
+
+func (l *layoutWriter) newline() {
+	l.writeString("\n")
+}
+
+func (l *layoutWriter) blankLines(n int) {
+	for range min(n, l.cfg.maxBlank) {
+		l.newline()
+	}
+}
+
+func (l *layoutWriter) indent(depth int) {
+	l.writeString(strings.Repeat(l.cfg.indent, depth))
+}
+
+// columns returns the column reached by writing b from column col.
+func columns(col int, b []byte) int {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == '\t' {
+			col += 8 - col%8
+		} else {
+			col++
+		}
+		b = b[size:]
+	}
+	return col
+}
+
+// fits reports whether s, written from the current column, stays within the
+// line width.
+func (l *layoutWriter) fits(s string) bool {
+	return l.cfg.width <= 0 || columns(l.col, []byte(s)) <= l.cfg.width
+}
+
+// node writes n, which starts at the current position on a line indented to
+// depth.
+func (l *layoutWriter) node(n Node, depth int, preserve bool) error {
+	switch v := n.(type) {
+	case *Element:
+		return l.element(v, depth, preserve)
+	case *Comment:
+		return l.comment(v, depth)
+	}
+	return l.s.writeNode(l, n)
+}
+
+func (l *layoutWriter) element(e *Element, depth int, preserve bool) error {
+	v, ok := e.GetAttributeNS(lexicon.AttrSpace, lexicon.NamespaceXML)
+	if !ok {
+		v, ok = l.declaredSpace(e)
+	}
+	if ok {
+		switch v {
+		case lexicon.SpacePreserve:
+			preserve = true
+		case lexicon.SpaceDefault:
+			preserve = false
+		}
+	}
+
+	name, saved, err := l.startTag(e, depth, e.FirstChild() == nil, true)
+	if saved != nil {
+		defer l.s.nsScopeRestore(saved)
+	}
+	if err != nil || e.FirstChild() == nil {
+		return err
+	}
+
+	if preserve || !l.declaredElementContent(e) || !hasElementContent(e) {
+		for child := range Children(e) {
+			if err := l.inline(child); err != nil {
+				return err
+			}
+		}
+	} else {
+		first := true
+		blank := 0
+		for child := range Children(e) {
+			if child.Type() == TextNode {
+				blank += bytes.Count(rawContent(child), []byte{'\n'})
+				continue
+			}
+			l.newline()
+			if !first {
+				l.blankLines(blank - 1)
+			}
+			first = false
+			blank = 0
+			l.indent(depth + 1)
+			if err := l.node(child, depth+1, false); err != nil {
+				return err
+			}
+		}
+		l.newline()
+		l.indent(depth)
+	}
+	l.writeString("</" + name + ">")
+	return l.s.err
+}
+
+// inline writes n exactly as its content requires: in mixed content and
+// under xml:space="preserve" whitespace is text, so nothing is added.
+func (l *layoutWriter) inline(n Node) error {
+	e, ok := n.(*Element)
+	if !ok {
+		return l.s.writeNode(l, n)
+	}
+	name, saved, err := l.startTag(e, 0, e.FirstChild() == nil, false)
+	if saved != nil {
+		defer l.s.nsScopeRestore(saved)
+	}
+	if err != nil || e.FirstChild() == nil {
+		return err
+	}
+	for child := range Children(e) {
+		if err := l.inline(child); err != nil {
+			return err
+		}
+	}
+	l.writeString("</" + name + ">")
+	return l.s.err
+}
+
+// declaredSpace returns the xml:space value the DTD gives e when the
+// attribute is not written on it.
+func (l *layoutWriter) declaredSpace(e *Element) (string, bool) {
+	for _, dtd := range l.subsets {
+		decl, ok := dtd.LookupAttribute(lexicon.AttrSpace, lexicon.PrefixXML, e.Name())
+		if !ok {
+			continue
+		}
+		if decl.Default() == enum.AttrDefaultNone || decl.Default() == enum.AttrDefaultFixed {
+			return decl.DefaultValue(), true
+		}
+		return "", false
+	}
+	return "", false
+}
+
+// declaredElementContent reports whether whitespace between the children of
+// e may be rewritten as far as the DTD is concerned: e is undeclared, or
+// declared to hold elements only. Whitespace in mixed or ANY content is
+// character data to a processor that reads the DTD.
+func (l *layoutWriter) declaredElementContent(e *Element) bool {
+	for _, dtd := range l.subsets {
+		if decl, ok := dtd.LookupElement(e.LocalName(), e.Prefix()); ok {
+			return decl.DeclType() == enum.ElementElementType
+		}
+	}
+	return true
+}
+
+// hasElementContent reports whether e holds child nodes other than text and
+// all of its text is whitespace that only separates them. Whitespace written
+// as a character reference is deliberate, and counts as text.
+func hasElementContent(e *Element) bool {
+	other := false
+	for child := range Children(e) {
+		switch child.Type() {
+		case TextNode:
+			if !isBlankText(child) {
+				return false
+			}
+		case CDATASectionNode, EntityRefNode:
+			return false
+		default:
+			other = true
+		}
+	}
+	return other
+}
+
+func isBlankText(n Node) bool {
+	c := rawContent(n)
+	for _, b := range c {
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			return false
+		}
+	}
+	if doc := n.OwnerDocument(); doc != nil && doc.lexical != nil {
+		if ln, ok := doc.lexical.lookup(n); ok && !bytes.Equal(ln.raw, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// startTag writes the start tag of e, or its empty-element tag when empty is
+// set, and returns the qualified name and the namespace bindings to restore
+// once the element is done. When wrap is set, a tag too wide for the line is
+// broken between attributes.
+func (l *layoutWriter) startTag(e *Element, depth int, empty, wrap bool) (string, []nsSaved, error) {
+	s := l.s
+	name := e.LocalName()
+	if prefix := e.Prefix(); prefix != "" {
+		name = prefix + ":" + name
+	}
+	if !s.checkElementName(name) || !s.checkNamespaceBinding("element name", name, e.Prefix(), e.URI()) {
+		return name, nil, s.err
+	}
+
+	tokens, saved := l.startTokens(e)
+	if s.err != nil {
+		return name, saved, s.err
+	}
+	l.sortTokens(tokens)
+
+	closing := ">"
+	if empty {
+		closing = "/>"
+	}
+	var line strings.Builder
+	line.WriteString("<" + name)
+	for _, t := range tokens {
+		line.WriteString(" " + t.text)
+	}
+	line.WriteString(closing)
+
+	if !wrap || len(tokens) == 0 || l.cfg.wrap == AttributeWrapNever || l.fits(line.String()) {
+		l.writeString(line.String())
+		return name, saved, s.err
+	}
+
+	l.writeString("<" + name)
+	switch l.cfg.wrap {
+	case AttributeWrapAligned:
+		align := strings.Repeat(l.cfg.indent, depth) + strings.Repeat(" ", utf8.RuneCountInString(name)+2)
+		for i, t := range tokens {
+			if i == 0 {
+				l.writeString(" ")
+			} else {
+				l.newline()
+				l.writeString(align)
+			}
+			l.writeString(t.text)
+		}
+	default:
+		for _, t := range tokens {
+			l.newline()
+			l.indent(depth + 1)
+			l.writeString(t.text)
+		}
+	}
+	l.writeString(closing)
+	return name, saved, s.err
+}
+
+// startTokens renders the namespace declarations and attributes of e, one
+// token each. It follows writeNode and reconcileNamespaces, declaring any
+// namespace e uses that is not in scope.
+func (l *layoutWriter) startTokens(e *Element) ([]startToken, []nsSaved) {
+	s := l.s
+	var tokens []startToken
+	var saved []nsSaved
+	var buf bytes.Buffer
+	emitted := make(map[string]struct{})
+
+	nslist := e.Namespaces()
+	if active := e.Namespace(); active != nil && active.href != "" {
+		nslist = dropConflictingActiveNS(nslist, active.prefix, active.href)
+	}
+	seen := make(map[string]struct{}, len(nslist))
+	for _, ns := range nslist {
+		if _, dup := seen[ns.prefix]; dup {
+			continue
+		}
+		seen[ns.prefix] = struct{}{}
+		buf.Reset()
+		if err := s.dumpNs(&buf, ns); err != nil {
+			s.check(err)
+			return nil, saved
+		}
+		if buf.Len() > 0 {
+			tokens = append(tokens, startToken{text: strings.TrimPrefix(buf.String(), " "), ns: true, key: ns.prefix})
+		}
+		if ns.prefix == lexicon.PrefixXML || ns.prefix == lexicon.PrefixXMLNS {
+			continue
+		}
+		saved = s.nsScopePush(ns.prefix, ns.href, saved)
+		if ns.href != "" {
+			emitted[ns.prefix] = struct{}{}
+		}
+	}
+
+	reconcile := func(prefix, href string, isElement bool) {
+		buf.Reset()
+		saved = s.reconcileOne(&buf, prefix, href, isElement, emitted, saved)
+		if buf.Len() > 0 {
+			tokens = append(tokens, startToken{text: strings.TrimPrefix(buf.String(), " "), ns: true, key: prefix})
+		}
+	}
+	if ns := e.Namespace(); ns != nil {
+		reconcile(ns.prefix, ns.href, true)
+	}
+	for attr := e.properties; attr != nil; attr = attr.NextAttribute() {
+		if ans := attr.ns; ans != nil {
+			reconcile(ans.prefix, ans.href, false)
+		}
+	}
+
+	for attr := e.properties; attr != nil; attr = attr.NextAttribute() {
+		if attr.IsDefault() {
+			// Supplied from the DTD, not written in the document.
+			continue
+		}
+		buf.Reset()
+		if err := s.writeAttribute(&buf, attr); err != nil {
+			return nil, saved
+		}
+		tokens = append(tokens, startToken{text: buf.String(), key: attr.Name(), uri: attr.URI(), local: attr.LocalName()})
+	}
+	return tokens, saved
+}
+
+func (l *layoutWriter) sortTokens(tokens []startToken) {
+	if l.cfg.order == AttributeOrderSource {
+		return
+	}
+	slices.SortStableFunc(tokens, func(a, b startToken) int {
+		switch {
+		case a.ns != b.ns:
+			if a.ns {
+				return -1
+			}
+			return 1
+		case a.ns || l.cfg.order == AttributeOrderName:
+			return strings.Compare(a.key, b.key)
+		}
+		return cmp.Or(strings.Compare(a.uri, b.uri), strings.Compare(a.local, b.local))
+	})
+}
+
+// comment writes c, reflowing its text when that is enabled and the
+// comment as written does not fit.
+func (l *layoutWriter) comment(c *Comment, depth int) error {
+	content := string(rawContent(c))
+	words := strings.Fields(content)
+	if !l.cfg.reflow || l.cfg.width <= 0 || len(words) == 0 || l.commentFits(content) {
+		return l.s.writeNode(l, c)
+	}
+
+	one := " " + strings.Join(words, " ") + " "
+	if l.fits("<!--" + one + "-->") {
+		return l.s.writeNode(l, newComment([]byte(one)))
+	}
+
+	inner := strings.Repeat(l.cfg.indent, depth+1)
+	avail := l.cfg.width - columns(0, []byte(inner))
+	var sb strings.Builder
+	for i, para := range commentParagraphs(content) {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		line := ""
+		for _, w := range para {
+			if line != "" && columns(0, []byte(line+" "+w)) > avail {
+				sb.WriteString("\n" + inner + line)
+				line = ""
+			}
+			if line == "" {
+				line = w
+			} else {
+				line += " " + w
+			}
+		}
+		sb.WriteString("\n" + inner + line)
+	}
+	sb.WriteString("\n" + strings.Repeat(l.cfg.indent, depth))
+
+	// Rewrapping only moves whitespace, but go through the session so the
+	// text is checked exactly as any other comment.
+	return l.s.writeNode(l, newComment([]byte(sb.String())))
+}
+
+// commentFits reports whether every line of a comment holding content stays
+// within the line width, the first starting at the current column.
+func (l *layoutWriter) commentFits(content string) bool {
+	col := l.col
+	for i, line := range strings.Split("<!--"+content+"-->", "\n") {
+		if i > 0 {
+			col = 0
+		}
+		if columns(col, []byte(line)) > l.cfg.width {
+			return false
+		}
+	}
+	return true
+}
+
+// commentParagraphs splits comment text into paragraphs at blank lines and
+// each paragraph into words.
+func commentParagraphs(content string) [][]string {
+	var paras [][]string
+	var cur []string
+	for line := range strings.Lines(content) {
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			if len(cur) > 0 {
+				paras = append(paras, cur)
+				cur = nil
+			}
+			continue
+		}
+		cur = append(cur, words...)
+	}
+	if len(cur) > 0 {
+		paras = append(paras, cur)
+	}
+	return paras
+}
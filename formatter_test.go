@@ -0,0 +1,182 @@
+package helium_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/c14n"
+	"github.com/stretchr/testify/require"
+)
+
+func format(t *testing.T, f helium.Formatter, src string) string {
+	t.Helper()
+	out, err := f.Format(t.Context(), []byte(src))
+	require.NoError(t, err)
+	again, err := f.Format(t.Context(), out)
+	require.NoError(t, err)
+	require.Equal(t, string(out), string(again), "formatting is not idempotent")
+	return string(out)
+}
+
+// canonical returns the canonical form of src with whitespace-only text
+// between elements removed, which is all a formatter may change.
+func canonical(t *testing.T, src string) string {
+	t.Helper()
+	doc, err := helium.NewParser().StripBlanks(true).Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, c14n.NewCanonicalizer(c14n.C14N10).Comments().Canonicalize(doc, &buf))
+	return buf.String()
+}
+
+func TestFormatter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("element content", func(t *testing.T) {
+		t.Parallel()
+		src := "<?xml version=\"1.0\"?>\n<!-- lead -->\n\n\n\n<root><a/>\n\n\n<b>x</b><c>\n  <d/>  </c><e></e></root>\n<?done?>"
+		got := format(t, helium.NewFormatter(), src)
+		require.Equal(t, `<?xml version="1.0"?>
+<!-- lead -->
+
+<root>
+  <a/>
+
+  <b>x</b>
+  <c>
+    <d/>
+  </c>
+  <e/>
+</root>
+<?done?>
+`, got)
+		require.Equal(t, canonical(t, src), canonical(t, got))
+	})
+
+	t.Run("mixed content and xml:space", func(t *testing.T) {
+		t.Parallel()
+		src := `<doc><p>Some <em>mixed</em>  text</p><pre xml:space="preserve">
+  <x/> </pre><raw>&#32;<y/></raw><plain xml:space="preserve"><q xml:space="default"><r/></q></plain></doc>`
+		got := format(t, helium.NewFormatter(), src)
+		require.Equal(t, `<doc>
+  <p>Some <em>mixed</em>  text</p>
+  <pre xml:space="preserve">
+  <x/> </pre>
+  <raw>&#32;<y/></raw>
+  <plain xml:space="preserve"><q xml:space="default"><r/></q></plain>
+</doc>
+`, got)
+		require.Equal(t, canonical(t, src), canonical(t, got))
+	})
+
+	t.Run("declared content", func(t *testing.T) {
+		t.Parallel()
+		src := `<!DOCTYPE doc [
+<!ELEMENT doc (pre, any, list)>
+<!ELEMENT pre (x)>
+<!ATTLIST pre xml:space (default|preserve) "preserve">
+<!ELEMENT any ANY>
+<!ELEMENT list (x*)>
+<!ELEMENT x EMPTY>
+]><doc><pre> <x/></pre><any> <x/></any><list> <x/></list></doc>`
+		got := format(t, helium.NewFormatter(), src)
+		require.Contains(t, got, "<doc>\n  <pre> <x/></pre>\n  <any> <x/></any>\n  <list>\n    <x/>\n  </list>\n</doc>\n")
+	})
+
+	t.Run("attribute wrapping", func(t *testing.T) {
+		t.Parallel()
+		src := `<root><item name="first" value="something long" kind="k"/><short a="1"/></root>`
+		f := helium.NewFormatter().Width(30)
+
+		require.Equal(t, `<root>
+  <item
+    name="first"
+    value="something long"
+    kind="k"/>
+  <short a="1"/>
+</root>
+`, format(t, f, src))
+
+		require.Equal(t, `<root>
+  <item name="first"
+        value="something long"
+        kind="k"/>
+  <short a="1"/>
+</root>
+`, format(t, f.AttributeWrap(helium.AttributeWrapAligned), src))
+
+		require.Equal(t, `<root>
+  <item name="first" value="something long" kind="k"/>
+  <short a="1"/>
+</root>
+`, format(t, f.AttributeWrap(helium.AttributeWrapNever), src))
+
+		require.Equal(t, format(t, f.Width(0), src), format(t, f.AttributeWrap(helium.AttributeWrapNever), src))
+	})
+
+	t.Run("attribute order", func(t *testing.T) {
+		t.Parallel()
+		src := `<r xmlns:z="urn:a" b="1" z:a="2" a="3" xmlns:y="urn:b" y:c="4"/>`
+		require.Equal(t, `<r xmlns:z="urn:a" xmlns:y="urn:b" b="1" z:a="2" a="3" y:c="4"/>`+"\n", format(t, helium.NewFormatter(), src))
+		require.Equal(t, `<r xmlns:y="urn:b" xmlns:z="urn:a" a="3" b="1" y:c="4" z:a="2"/>`+"\n", format(t, helium.NewFormatter().AttributeOrder(helium.AttributeOrderName), src))
+		require.Equal(t, `<r xmlns:y="urn:b" xmlns:z="urn:a" a="3" b="1" z:a="2" y:c="4"/>`+"\n", format(t, helium.NewFormatter().AttributeOrder(helium.AttributeOrderCanonical), src))
+	})
+
+	t.Run("comment reflow", func(t *testing.T) {
+		t.Parallel()
+		src := "<r><!-- short --><!-- one two three four five six seven\n\n eight nine ten --></r>"
+		f := helium.NewFormatter().Width(24)
+		require.Equal(t, "<r>\n  <!-- short -->\n  <!-- one two three four five six seven\n\n eight nine ten -->\n</r>\n", format(t, f, src))
+		require.Equal(t, `<r>
+  <!-- short -->
+  <!--
+    one two three four
+    five six seven
+
+    eight nine ten
+  -->
+</r>
+`, format(t, f.ReflowComments(true), src))
+	})
+
+	t.Run("blank lines", func(t *testing.T) {
+		t.Parallel()
+		src := "<r><a/>\n\n\n\n<b/></r>"
+		require.Equal(t, "<r>\n  <a/>\n  <b/>\n</r>\n", format(t, helium.NewFormatter().MaxBlankLines(0), src))
+		require.Equal(t, "<r>\n  <a/>\n\n\n  <b/>\n</r>\n", format(t, helium.NewFormatter().MaxBlankLines(2), src))
+		require.Equal(t, "<r>\n\t<a/>\n\n\t<b/>\n</r>\n", format(t, helium.NewFormatter().Indent("\t"), src))
+	})
+
+	t.Run("keeps references and DOCTYPE", func(t *testing.T) {
+		t.Parallel()
+		src := `<!DOCTYPE r [ <!ENTITY e "ent"> ]><r a="x&amp;y&#10;"><t>&e;&lt;</t><![CDATA[<x>]]></r>`
+		got := format(t, helium.NewFormatter(), src)
+		require.Equal(t, "<!DOCTYPE r [ <!ENTITY e \"ent\"> ]>\n<r a=\"x&amp;y&#10;\"><t>&e;&lt;</t><![CDATA[<x>]]></r>\n", got)
+	})
+
+	t.Run("tree without source markup", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(`<r><a b="1"/>text</r>`))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, helium.NewFormatter().WriteTo(&buf, doc))
+		require.Equal(t, "<?xml version=\"1.0\"?>\n<r><a b=\"1\"/>text</r>\n", buf.String())
+
+		require.ErrorIs(t, helium.NewFormatter().WriteTo(&buf, nil), helium.ErrNilNode)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+		_, err := helium.NewFormatter().Format(t.Context(), []byte(`<r>`))
+		require.Error(t, err)
+	})
+
+	t.Run("clone on write", func(t *testing.T) {
+		t.Parallel()
+		base := helium.NewFormatter()
+		_ = base.Width(10).Indent("\t")
+		require.False(t, strings.Contains(format(t, base, `<r><a/></r>`), "\t"))
+	})
+}
@@ -0,0 +1,476 @@
+package helium
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Freeze returns a read-only copy of doc for documents that are kept resident
+// only to be queried. Each kind of node is stored in one array sized to the
+// document, the character data of all text, CDATA and comment nodes and
+// attribute values shares a single buffer, names are interned, and equal
+// namespace bindings share one declaration, so the copy is built in a handful
+// of allocations instead of one per node. Every node is still a full node
+// struct with its links, so the copy takes about as much memory as doc and
+// costs the garbage collector about as much to scan.
+//
+// The frozen document is an ordinary *Document made of the usual node types,
+// so XPath, XSLT, the validators and the serializer accept it as they would any
+// other. Every mutation of it that returns an error — AddChild, AddSibling,
+// Replace, AppendText, the attribute and namespace setters — fails with
+// [ErrReadOnly]. The mutations that have no error to return, such as
+// UnlinkNode, SetLine, SetNamespace, SetEncoding, SetURL, RemoveAttribute or
+// DTD.RemoveElement, panic with it instead. Its nodes cannot be moved into
+// another document either; copy them with [CopyNode] instead. Source positions
+// and preserved lexical markup are not carried over.
+//
+// doc itself is left untouched and may be freed or discarded afterwards.
+func Freeze(doc *Document) (*Document, error) {
+	if doc == nil {
+		return nil, ErrNilNode
+	}
+	return freeze(doc, false)
+}
+
+// freeze builds the frozen copy of doc. With shareText, character data is
+// not copied but referenced from doc, which must then keep it alive.
+func freeze(doc *Document, shareText bool) (*Document, error) {
+	dst := NewDocument(doc.version, doc.encoding, doc.standalone)
+	dst.etype = doc.etype
+	dst.url = doc.url
+	dst.properties = doc.properties
+	dst.idsSkip = doc.idsSkip
+	dst.standaloneNormAttrs = doc.standaloneNormAttrs
+	if dtd := doc.intSubset; dtd != nil {
+		if err := copyDTD(dtd, dst); err != nil {
+			return nil, err
+		}
+		UnlinkNode(dst.intSubset)
+	}
+	CopyExtSubset(doc, dst)
+
+	var c freezeCounts
+	c.count(doc)
+	if shareText {
+		c.content = 0
+	}
+	f := newFreezer(dst, &c)
+	f.shareText = shareText
+	if len(doc.ids) > 0 {
+		f.elems = make(map[*Element]*Element, len(doc.ids))
+	}
+	if err := f.children(doc, dst); err != nil {
+		return nil, err
+	}
+	for id, e := range doc.ids {
+		if cp := f.elems[e]; cp != nil {
+			dst.registerID(id, cp)
+		}
+	}
+	dst.readOnly = true
+	return dst, nil
+}
+
+// IsReadOnly reports whether d was produced by [Freeze] and rejects
+// mutation.
+func (d *Document) IsReadOnly() bool {
+	return d.readOnly
+}
+
+// beginMutation is called by every operation that changes a document, before
+// it changes the trees nodes belong to. It returns ErrReadOnly when any of them
+// belongs to a frozen document, and otherwise records that their documents
+// are changing (see Document.Snapshot).
+func beginMutation(nodes ...Node) error {
+	for _, n := range nodes {
+		if err := documentOf(n).beginMutation(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mustBeginMutation is beginMutation for the operations that have no error
+// result to report ErrReadOnly through: they panic with it instead of
+// silently leaving a frozen document unchanged.
+func mustBeginMutation(nodes ...Node) {
+	if err := beginMutation(nodes...); err != nil {
+		panic(err)
+	}
+}
+
+// mustBeginMutation is the document form of the package-level
+// mustBeginMutation.
+func (d *Document) mustBeginMutation() {
+	if err := d.beginMutation(); err != nil {
+		panic(err)
+	}
+}
+
+// beginMutation is the document form of the package-level beginMutation. A
+// nil d stands for nodes that belong to no document.
+func (d *Document) beginMutation() error {
+	if d == nil {
+		return nil
+	}
+	if d.readOnly {
+		return fmt.Errorf("%w: cannot modify a frozen document", ErrReadOnly)
+	}
+	d.generation++
+	return nil
+}
+
+// freezeCounts sizes the arrays a frozen document is built in.
+type freezeCounts struct {
+	elems, texts, attrs, comments, cdata, pis int
+	nsDecls                                   int
+	namespaces                                int // distinct bindings, when known; at least nsDecls
+	content                                   int
+}
+
+// count tallies the nodes below n. It descends through the same owned
+// children the copy visits, so entity content shared from the DTD is not
+// counted.
+func (c *freezeCounts) count(n Node) {
+	for child := range Children(n) {
+		switch child := child.(type) {
+		case *Element:
+			c.elems++
+			c.nsDecls += len(child.nsDefs)
+			for a := child.properties; a != nil; a = a.NextAttribute() {
+				c.attrs++
+				c.count(a)
+			}
+			c.count(child)
+		case *Text:
+			c.texts++
+			c.content += len(child.content)
+		case *CDATASection:
+			c.cdata++
+			c.content += len(child.content)
+		case *Comment:
+			c.comments++
+			c.content += len(child.content)
+		case *ProcessingInstruction:
+			c.pis++
+		}
+	}
+}
+
+// size estimates the bytes a frozen document with these counts holds.
+func (c *freezeCounts) size() int64 {
+	return int64(c.elems)*int64(unsafe.Sizeof(Element{})) +
+		int64(c.texts)*int64(unsafe.Sizeof(Text{})) +
+		int64(c.attrs)*int64(unsafe.Sizeof(Attribute{})) +
+		int64(c.comments)*int64(unsafe.Sizeof(Comment{})) +
+		int64(c.cdata)*int64(unsafe.Sizeof(CDATASection{})) +
+		int64(c.pis)*int64(unsafe.Sizeof(ProcessingInstruction{})) +
+		int64(c.nsDecls)*int64(unsafe.Sizeof(Namespace{})) +
+		int64(c.content)
+}
+
+// freezer builds a frozen document. Nodes are handed out from arrays sized by
+// freezeCounts; running past one (which a consistent tree never does) falls
+// back to the document's regular allocators.
+type freezer struct {
+	dst *Document
+
+	elemArena    []Element
+	textArena    []Text
+	attrArena    []Attribute
+	commentArena []Comment
+	cdataArena   []CDATASection
+	piArena      []ProcessingInstruction
+	nsArena      []Namespace
+	nsDefsArena  []*Namespace
+	content      []byte
+	shareText    bool // reference the source's character data instead of copying it
+
+	names      map[string]string
+	namespaces map[*Namespace]*Namespace
+	elems      map[*Element]*Element // source to copy, for the ID table
+}
+
+func newFreezer(dst *Document, c *freezeCounts) *freezer {
+	return &freezer{
+		dst:          dst,
+		elemArena:    make([]Element, c.elems),
+		textArena:    make([]Text, c.texts),
+		attrArena:    make([]Attribute, c.attrs),
+		commentArena: make([]Comment, c.comments),
+		cdataArena:   make([]CDATASection, c.cdata),
+		piArena:      make([]ProcessingInstruction, c.pis),
+		nsArena:      make([]Namespace, 0, max(c.nsDecls, c.namespaces)),
+		nsDefsArena:  make([]*Namespace, c.nsDecls),
+		content:      make([]byte, 0, c.content),
+		names:        make(map[string]string),
+		namespaces:   make(map[*Namespace]*Namespace),
+	}
+}
+
+// children copies the owned children of src under parent, in order.
+func (f *freezer) children(src Node, parent MutableNode) error {
+	for c := range Children(src) {
+		var cp Node
+		switch c := c.(type) {
+		case *DTD:
+			// The internal subset was copied before the walk; link it back
+			// in at its place among the document's children.
+			if f.dst.intSubset == nil || f.dst.intSubset.parent != nil {
+				continue
+			}
+			cp = f.dst.intSubset
+		case *Element:
+			e, err := f.element(c)
+			if err != nil {
+				return err
+			}
+			cp = e
+		default:
+			var err error
+			cp, err = f.leaf(c)
+			if err != nil {
+				return err
+			}
+		}
+		if err := appendFastChild(parent, cp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *freezer) element(src *Element) (*Element, error) {
+	e := f.allocElement()
+	f.base(&e.docnode, &src.docnode)
+	e.contentHasReference = src.contentHasReference
+	e.ns = f.namespace(src.ns)
+	if n := len(src.nsDefs); n > 0 {
+		defs := f.allocNSDefs(n)
+		for i, ns := range src.nsDefs {
+			defs[i] = f.namespace(ns)
+		}
+		e.nsDefs = defs
+	}
+	if f.elems != nil {
+		f.elems[src] = e
+	}
+
+	var last *Attribute
+	for a := src.properties; a != nil; a = a.NextAttribute() {
+		cp, err := f.attribute(a)
+		if err != nil {
+			return nil, err
+		}
+		last = linkAttribute(e, last, cp)
+	}
+	if err := f.children(src, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (f *freezer) attribute(src *Attribute) (*Attribute, error) {
+	a := f.allocAttribute()
+	f.base(&a.docnode, &src.docnode)
+	a.atype = src.atype
+	a.defaultAttr = src.defaultAttr
+	a.syntheticBase = src.syntheticBase
+	a.ns = f.namespace(src.ns)
+	if err := f.children(src, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// leaf copies a node that is neither an element nor the DTD.
+func (f *freezer) leaf(src Node) (Node, error) {
+	switch src := src.(type) {
+	case *Text:
+		t := f.allocText()
+		f.base(&t.docnode, &src.docnode)
+		t.content = f.text(src.content)
+		t.fromCharRef = src.fromCharRef
+		return t, nil
+	case *CDATASection:
+		c := f.allocCDATA()
+		f.base(&c.docnode, &src.docnode)
+		c.content = f.text(src.content)
+		return c, nil
+	case *Comment:
+		c := f.allocComment()
+		f.base(&c.docnode, &src.docnode)
+		c.content = f.text(src.content)
+		return c, nil
+	case *ProcessingInstruction:
+		pi := f.allocPI()
+		f.base(&pi.docnode, &src.docnode)
+		pi.target = f.intern(src.target)
+		pi.data = src.data
+		return pi, nil
+	case *EntityRef:
+		ref, err := f.dst.CreateReference(src.Name())
+		if err != nil {
+			return nil, err
+		}
+		ref.line = src.line
+		ref.entityBaseURI = src.entityBaseURI
+		return ref, nil
+	default:
+		cp, err := CopyNode(src, f.dst)
+		if err != nil {
+			return nil, err
+		}
+		copyLine(src, cp)
+		return cp, nil
+	}
+}
+
+// base fills in the fields every node kind shares. Links are set as the node
+// is attached.
+func (f *freezer) base(dn, src *docnode) {
+	dn.name = f.intern(src.name)
+	dn.etype = src.etype
+	dn.doc = f.dst
+	dn.line = src.line
+	dn.entityBaseURI = src.entityBaseURI
+}
+
+// text copies b into the shared content buffer. The result is capped so an
+// append can never spill into the next node's bytes. When sharing, b itself is
+// returned, capped the same way: character data is only ever appended to, so
+// later appends on the source node leave these bytes as they are.
+func (f *freezer) text(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	if f.shareText {
+		return b[:len(b):len(b)]
+	}
+	if cap(f.content)-len(f.content) < len(b) {
+		return append([]byte(nil), b...)
+	}
+	start := len(f.content)
+	f.content = append(f.content, b...)
+	return f.content[start:len(f.content):len(f.content)]
+}
+
+func (f *freezer) intern(s string) string {
+	if couldBeGlobalNameString(s) {
+		if interned, ok := globalNames[s]; ok {
+			return interned
+		}
+	}
+	if interned, ok := f.names[s]; ok {
+		return interned
+	}
+	f.names[s] = s
+	return s
+}
+
+// namespace returns the copy of ns. A declaration and every reference to it
+// keep sharing one Namespace, as they do in a parsed tree.
+func (f *freezer) namespace(ns *Namespace) *Namespace {
+	if ns == nil {
+		return nil
+	}
+	if cp, ok := f.namespaces[ns]; ok {
+		return cp
+	}
+	cp := f.allocNamespace(ns.etype, ns.prefix, ns.href)
+	f.namespaces[ns] = cp
+	return cp
+}
+
+// linkAttribute appends a to the attribute list of e, after last, and
+// returns a as the new last attribute.
+func linkAttribute(e *Element, last, a *Attribute) *Attribute {
+	a.parent = e
+	if last == nil {
+		e.properties = a
+	} else {
+		last.next = a
+		a.prev = last
+	}
+	return a
+}
+
+// The alloc methods hand out the next node of each kind from its arena.
+
+func (f *freezer) allocElement() *Element {
+	if len(f.elemArena) == 0 {
+		return newElement("")
+	}
+	e := &f.elemArena[0]
+	f.elemArena = f.elemArena[1:]
+	return e
+}
+
+func (f *freezer) allocAttribute() *Attribute {
+	if len(f.attrArena) == 0 {
+		return &Attribute{}
+	}
+	a := &f.attrArena[0]
+	f.attrArena = f.attrArena[1:]
+	return a
+}
+
+func (f *freezer) allocText() *Text {
+	if len(f.textArena) == 0 {
+		return &Text{}
+	}
+	t := &f.textArena[0]
+	f.textArena = f.textArena[1:]
+	return t
+}
+
+func (f *freezer) allocCDATA() *CDATASection {
+	if len(f.cdataArena) == 0 {
+		return &CDATASection{}
+	}
+	c := &f.cdataArena[0]
+	f.cdataArena = f.cdataArena[1:]
+	return c
+}
+
+func (f *freezer) allocComment() *Comment {
+	if len(f.commentArena) == 0 {
+		return &Comment{}
+	}
+	c := &f.commentArena[0]
+	f.commentArena = f.commentArena[1:]
+	return c
+}
+
+func (f *freezer) allocPI() *ProcessingInstruction {
+	if len(f.piArena) == 0 {
+		return &ProcessingInstruction{}
+	}
+	pi := &f.piArena[0]
+	f.piArena = f.piArena[1:]
+	return pi
+}
+
+func (f *freezer) allocNSDefs(n int) []*Namespace {
+	if len(f.nsDefsArena) < n {
+		return make([]*Namespace, n)
+	}
+	defs := f.nsDefsArena[:n:n]
+	f.nsDefsArena = f.nsDefsArena[n:]
+	return defs
+}
+
+func (f *freezer) allocNamespace(etype ElementType, prefix, href string) *Namespace {
+	var ns *Namespace
+	if len(f.nsArena) < cap(f.nsArena) {
+		f.nsArena = f.nsArena[:len(f.nsArena)+1]
+		ns = &f.nsArena[len(f.nsArena)-1]
+	} else {
+		ns = &Namespace{}
+	}
+	ns.etype = etype
+	ns.prefix = f.intern(prefix)
+	ns.href = f.intern(href)
+	ns.context = f.dst
+	return ns
+}
@@ -0,0 +1,126 @@
+package helium_test
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeze(t *testing.T) {
+	t.Parallel()
+
+	const src = `<?xml version="1.0"?>
+<!-- lead -->
+<!DOCTYPE catalog [
+  <!ENTITY pub "ACME">
+  <!ATTLIST book id ID #IMPLIED>
+  <!ELEMENT title (#PCDATA)>
+]>
+<catalog xmlns="urn:c" xmlns:x="urn:x">
+  <book id="b1" x:lang="en"><title>Go &amp; XML</title><![CDATA[<raw>]]></book>
+  <?pi data?>
+  <book id="b2"><title>&pub;</title></book>
+</catalog>
+`
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+	want, err := helium.WriteString(doc)
+	require.NoError(t, err)
+
+	frozen, err := helium.Freeze(doc)
+	require.NoError(t, err)
+	require.True(t, frozen.IsReadOnly())
+	require.False(t, doc.IsReadOnly())
+
+	t.Run("same document", func(t *testing.T) {
+		t.Parallel()
+		got, err := helium.WriteString(frozen)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+
+		b2 := frozen.GetElementByID("b2")
+		require.NotNil(t, b2)
+		require.Same(t, frozen, b2.OwnerDocument())
+		require.Equal(t, "urn:c", b2.URI())
+		require.Equal(t, 11, b2.Line())
+
+		b1 := frozen.GetElementByID("b1")
+		v, ok := b1.GetAttributeNS("lang", "urn:x")
+		require.True(t, ok)
+		require.Equal(t, "en", v)
+		require.Equal(t, "Go & XML", string(b1.FirstChild().Content()))
+	})
+
+	t.Run("rejects mutation", func(t *testing.T) {
+		t.Parallel()
+		root := frozen.DocumentElement()
+		book := root.FirstChild().NextSibling().(*helium.Element)
+		title := book.FirstChild().(*helium.Element)
+		text := title.FirstChild().(*helium.Text)
+
+		e, err := frozen.CreateElement("new")
+		require.NoError(t, err)
+		require.ErrorIs(t, root.AddChild(e), helium.ErrReadOnly)
+		require.ErrorIs(t, book.AddSibling(e), helium.ErrReadOnly)
+		require.ErrorIs(t, book.Replace(e), helium.ErrReadOnly)
+		require.ErrorIs(t, text.AppendText([]byte("!")), helium.ErrReadOnly)
+		require.ErrorIs(t, book.SetAttribute("id", "b3"), helium.ErrReadOnly)
+		require.ErrorIs(t, book.DeclareNamespace("y", "urn:y"), helium.ErrReadOnly)
+		readOnly := func(name string, f func()) {
+			t.Helper()
+			defer func() {
+				err, _ := recover().(error)
+				require.ErrorIs(t, err, helium.ErrReadOnly, name)
+			}()
+			f()
+		}
+		readOnly("RemoveAttribute", func() { book.RemoveAttribute("id") })
+		readOnly("UnlinkNode", func() { helium.UnlinkNode(book) })
+		readOnly("SetLine", func() { book.SetLine(1) })
+		readOnly("SetNamespace", func() { book.SetNamespace(nil) })
+		readOnly("SetEncoding", func() { frozen.SetEncoding("UTF-16") })
+		readOnly("SetURL", func() { frozen.SetURL("other.xml") })
+		readOnly("SetProperties", func() { frozen.SetProperties(0) })
+		ent, ok := frozen.GetEntity("pub")
+		require.True(t, ok)
+		readOnly("SetOrig", func() { ent.SetOrig("x") })
+		readOnly("RemoveElement", func() { frozen.IntSubset().RemoveElement("title", "") })
+
+		other := helium.NewDefaultDocument()
+		require.ErrorIs(t, other.AddChild(book), helium.ErrReadOnly)
+
+		got, err := helium.WriteString(frozen)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	})
+
+	t.Run("copies stay writable", func(t *testing.T) {
+		t.Parallel()
+		cp, err := helium.CopyDoc(frozen)
+		require.NoError(t, err)
+		require.False(t, cp.IsReadOnly())
+		require.NoError(t, cp.DocumentElement().SetAttribute("n", "1"))
+	})
+
+	t.Run("source is independent", func(t *testing.T) {
+		t.Parallel()
+		src, err := helium.NewParser().Parse(t.Context(), []byte(`<r><a>text</a></r>`))
+		require.NoError(t, err)
+		frozen, err := helium.Freeze(src)
+		require.NoError(t, err)
+		src.Free()
+
+		_, err = helium.NewParser().Parse(t.Context(), []byte(`<s><b>other</b></s>`))
+		require.NoError(t, err)
+		got, err := helium.WriteString(frozen)
+		require.NoError(t, err)
+		require.Equal(t, "<?xml version=\"1.0\"?>\n<r><a>text</a></r>\n", got)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+		_, err := helium.Freeze(nil)
+		require.ErrorIs(t, err, helium.ErrNilNode)
+	})
+}
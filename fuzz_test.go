@@ -2,6 +2,8 @@ package helium_test
 
 import (
 	"bytes"
+	"encoding/binary"
+	"hash/crc32"
 	"testing"
 
 	"github.com/lestrrat-go/helium"
@@ -164,3 +166,34 @@ func FuzzParseRoundtrip(f *testing.F) {
 		require.NoError(t, err)
 	})
 }
+
+// FuzzLoadBinary feeds damaged binary documents to LoadBinary, which must
+// reject or load them without panicking. The checksum is recomputed, so that
+// the input reaches the decoder.
+func FuzzLoadBinary(f *testing.F) {
+	for _, src := range []string{
+		`<root/>`,
+		`<!DOCTYPE r [<!ENTITY e "x"><!ATTLIST r id ID #IMPLIED>]><r id="a" xmlns:p="urn:p"><p:c p:a="1">&e;</p:c><!--c--><?pi d?><![CDATA[x]]></r>`,
+	} {
+		doc, err := helium.NewParser().Parse(f.Context(), []byte(src))
+		require.NoError(f, err)
+		b, err := helium.MarshalBinary(doc)
+		require.NoError(f, err)
+		f.Add(b[:len(b)-4])
+	}
+
+	table := crc32.MakeTable(crc32.Castagnoli)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > 1<<20 {
+			return
+		}
+		data = binary.LittleEndian.AppendUint32(bytes.Clone(data), crc32.Checksum(data, table))
+		doc, err := helium.LoadBinary(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		// The names and values in a damaged document need not be valid
+		// XML, so the writer may reject it; it must not panic.
+		_, _ = helium.WriteString(doc)
+	})
+}
@@ -0,0 +1,169 @@
+package helium
+
+import (
+	"strings"
+	"sync"
+)
+
+// Index answers name and value lookups over a document without walking it.
+// It is obtained from [Document.Index] once [Document.EnableIndex] has been
+// called, and the XPath evaluators consult it on their own for descendant
+// steps such as //ns:item[@sku='X'] that start from the document node.
+//
+// The index covers the nodes the XPath descendant axis reaches from the
+// document: elements, and the text and CDATA sections among their children.
+// The content of unexpanded entity references is not included. Each kind of
+// lookup builds its table with one walk of the document the first time it
+// is used, and every change to the document, whether through AddChild,
+// AddSibling, Replace, UnlinkNode, AppendText or a setter such as
+// SetAttribute or SetNamespace, discards the tables, which are then rebuilt
+// by the next lookup.
+//
+// Lookups may run from any number of goroutines as long as the document is
+// not being changed at the same time. The slices they return are shared
+// with the index and must not be modified.
+type Index struct {
+	doc *Document
+
+	mu       sync.Mutex
+	gen      uint64
+	elements map[indexName][]*Element
+	attrs    map[indexAttr][]*Element
+	texts    map[string][]Node
+}
+
+// indexName is the expanded name of an element.
+type indexName struct {
+	uri, local string
+}
+
+// indexAttr is the expanded name and value of an attribute.
+type indexAttr struct {
+	uri, local, value string
+}
+
+// EnableIndex turns on the lookup index of d (see [Index]). The tables are
+// built lazily, so enabling the index costs nothing until it is used.
+// Enabling it again keeps the existing index.
+func (d *Document) EnableIndex() {
+	if d.index == nil {
+		d.index = &Index{doc: d}
+	}
+}
+
+// DisableIndex turns off the lookup index of d and releases its tables.
+func (d *Document) DisableIndex() {
+	d.index = nil
+}
+
+// Index returns the lookup index of d, or nil when it has not been enabled
+// with [Document.EnableIndex].
+func (d *Document) Index() *Index {
+	return d.index
+}
+
+// Elements returns the elements with namespace URI uri and local name local,
+// in document order.
+func (x *Index) Elements(uri, local string) []*Element {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.sync()
+	if x.elements == nil {
+		x.elements = make(map[indexName][]*Element)
+		x.walk(func(n Node) {
+			if e, ok := n.(*Element); ok {
+				key := indexName{uri: e.URI(), local: e.LocalName()}
+				x.elements[key] = append(x.elements[key], e)
+			}
+		})
+	}
+	return x.elements[indexName{uri: uri, local: local}]
+}
+
+// ElementsByAttribute returns the elements that have an attribute with
+// namespace URI uri, local name local and value value, in document order.
+func (x *Index) ElementsByAttribute(uri, local, value string) []*Element {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.sync()
+	if x.attrs == nil {
+		x.attrs = make(map[indexAttr][]*Element)
+		x.walk(func(n Node) {
+			e, ok := n.(*Element)
+			if !ok {
+				return
+			}
+			e.ForEachAttribute(func(a *Attribute) bool {
+				key := indexAttr{uri: a.URI(), local: attributeLocalName(a), value: a.Value()}
+				x.attrs[key] = append(x.attrs[key], e)
+				return true
+			})
+		})
+	}
+	return x.attrs[indexAttr{uri: uri, local: local, value: value}]
+}
+
+// TextNodes returns the text and CDATA section nodes whose content is value,
+// in document order.
+func (x *Index) TextNodes(value string) []Node {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.sync()
+	if x.texts == nil {
+		x.texts = make(map[string][]Node)
+		x.walk(func(n Node) {
+			switch n.(type) {
+			case *Text, *CDATASection:
+				key := string(n.Content())
+				x.texts[key] = append(x.texts[key], n)
+			}
+		})
+	}
+	return x.texts[value]
+}
+
+// sync discards the tables when the document changed since they were built.
+func (x *Index) sync() {
+	if x.gen == x.doc.generation {
+		return
+	}
+	x.gen = x.doc.generation
+	x.elements = nil
+	x.attrs = nil
+	x.texts = nil
+}
+
+// walk calls fn for each element, text and CDATA section node below the
+// document, in document order. Like the XPath child axis it does not enter
+// entity references. The walk keeps its own stack so that deep trees built
+// in code do not exhaust the goroutine stack.
+func (x *Index) walk(fn func(Node)) {
+	var stack []Node
+	push := func(n Node) {
+		for c := n.LastChild(); c != nil; c = c.PrevSibling() {
+			stack = append(stack, c)
+		}
+	}
+	push(x.doc)
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch n.(type) {
+		case *Element:
+			fn(n)
+			push(n)
+		case *Text, *CDATASection:
+			fn(n)
+		}
+	}
+}
+
+// attributeLocalName returns the local name of a as XPath name tests see it:
+// the part after the colon of a name whose prefix was not resolved.
+func attributeLocalName(a *Attribute) string {
+	local := a.LocalName()
+	if _, after, ok := strings.Cut(local, ":"); ok {
+		return after
+	}
+	return local
+}
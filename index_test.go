@@ -0,0 +1,133 @@
+package helium_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	const src = `<?xml version="1.0"?>
+<!DOCTYPE doc [<!ENTITY e "<item sku='E'/>">]>
+<doc xmlns:p="urn:p">
+  <item sku="A">first</item>
+  <p:item p:sku="A">second</p:item>
+  <group><item sku="B"><![CDATA[first]]></item></group>
+  <ref>&e;</ref>
+</doc>`
+
+	names := func(elems []*helium.Element) []string {
+		out := make([]string, 0, len(elems))
+		for _, e := range elems {
+			out = append(out, string(e.Content()))
+		}
+		return out
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		require.Nil(t, doc.Index())
+		doc.EnableIndex()
+		idx := doc.Index()
+		require.NotNil(t, idx)
+		doc.EnableIndex()
+		require.Same(t, idx, doc.Index())
+		doc.DisableIndex()
+		require.Nil(t, doc.Index())
+	})
+
+	t.Run("lookups", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		doc.EnableIndex()
+		idx := doc.Index()
+
+		// The element inside the unexpanded entity reference is not indexed.
+		require.Equal(t, []string{"first", "first"}, names(idx.Elements("", "item")))
+		require.Equal(t, []string{"second"}, names(idx.Elements("urn:p", "item")))
+		require.Empty(t, idx.Elements("", "missing"))
+
+		require.Equal(t, []string{"first"}, names(idx.ElementsByAttribute("", "sku", "A")))
+		require.Equal(t, []string{"second"}, names(idx.ElementsByAttribute("urn:p", "sku", "A")))
+		require.Empty(t, idx.ElementsByAttribute("", "sku", "E"))
+
+		texts := idx.TextNodes("first")
+		require.Len(t, texts, 2)
+		require.Equal(t, helium.TextNode, texts[0].Type())
+		require.Equal(t, helium.CDATASectionNode, texts[1].Type())
+	})
+
+	t.Run("follows mutations", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(`<doc><item sku="A">a</item></doc>`))
+		require.NoError(t, err)
+		doc.EnableIndex()
+		idx := doc.Index()
+		root := doc.DocumentElement()
+		first := root.FirstChild().(*helium.Element)
+		require.Len(t, idx.ElementsByAttribute("", "sku", "A"), 1)
+
+		e, err := doc.CreateElement("item")
+		require.NoError(t, err)
+		require.NoError(t, e.SetAttribute("sku", "B"))
+		require.NoError(t, root.AddChild(e))
+		require.Len(t, idx.Elements("", "item"), 2)
+		require.Equal(t, []*helium.Element{e}, idx.ElementsByAttribute("", "sku", "B"))
+
+		require.NoError(t, first.SetAttribute("sku", "C"))
+		require.Empty(t, idx.ElementsByAttribute("", "sku", "A"))
+		require.Equal(t, []*helium.Element{first}, idx.ElementsByAttribute("", "sku", "C"))
+
+		require.True(t, e.RemoveAttribute("sku"))
+		require.Empty(t, idx.ElementsByAttribute("", "sku", "B"))
+
+		require.Len(t, idx.TextNodes("a"), 1)
+		require.NoError(t, first.FirstChild().(*helium.Text).AppendText([]byte("b")))
+		require.Empty(t, idx.TextNodes("a"))
+		require.Len(t, idx.TextNodes("ab"), 1)
+
+		helium.UnlinkNode(first)
+		require.Equal(t, []*helium.Element{e}, idx.Elements("", "item"))
+		require.Empty(t, idx.TextNodes("ab"))
+
+		ns, err := doc.CreateNamespace("a", "urn:a")
+		require.NoError(t, err)
+		e.SetNamespace(ns)
+		require.Empty(t, idx.Elements("", "item"))
+		require.Equal(t, []*helium.Element{e}, idx.Elements("urn:a", "item"))
+	})
+
+	t.Run("frozen document", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		frozen, err := helium.Freeze(doc)
+		require.NoError(t, err)
+		frozen.EnableIndex()
+		require.Equal(t, []string{"first", "first"}, names(frozen.Index().Elements("", "item")))
+	})
+
+	t.Run("concurrent lookups", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		doc.EnableIndex()
+		idx := doc.Index()
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Go(func() {
+				if len(idx.Elements("", "item")) != 2 || len(idx.ElementsByAttribute("", "sku", "B")) != 1 || len(idx.TextNodes("second")) != 1 {
+					t.Error("unexpected index contents")
+				}
+			})
+		}
+		wg.Wait()
+	})
+}
@@ -119,8 +119,18 @@ func Execute(ctx context.Context, args []string) int { //nolint:contextcheck
 	}
 
 	switch args[0] {
+	case "convert":
+		return newConvertCommandWithIO("helium convert", stdin, stdout, stderr, stdinTTY).runContext(ctx, args[1:])
+	case "convert-schema":
+		return newConvertSchemaCommandWithIO("helium convert-schema", stdin, stdout, stderr, stdinTTY).runContext(ctx, args[1:])
+	case "diff":
+		return newDiffCommandWithIO("helium diff", stdin, stdout, stderr, stdinTTY).runContext(ctx, args[1:])
+	case "fmt":
+		return newFmtCommandWithIO("helium fmt", stdin, stdout, stderr, stdinTTY).runContext(ctx, args[1:])
 	case "lint":
 		return newCommandWithIO("helium lint", stdin, stdout, stderr, stdinTTY).runContext(ctx, args[1:])
+	case "patch":
+		return newPatchCommandWithIO("helium patch", stdin, stdout, stderr, stdinTTY).runContext(ctx, args[1:])
 	case "relaxng":
 		return runRelaxNG(ctx, stderr, stdin, stdinTTY, args[1:])
 	case "schematron":
@@ -190,7 +200,12 @@ func showUsage(w io.Writer) {
 	_, _ = fmt.Fprintln(w, `Usage: helium <command> [options]
 
 Available commands:
+  convert Convert XML to JSON, or JSON to XML
+  convert-schema Convert a DTD to XML Schema or RELAX NG
+  diff    Compute an XML patch between two documents
+  fmt     Format XML documents
   lint    Parse and lint XML documents
+  patch   Apply an XML patch to a document
   relaxng RELAX NG operations
   schematron Schematron operations
   xpath   Evaluate XPath expressions
@@ -1,13 +1,18 @@
 package heliumcmd_test
 
 const (
-	cmdXPath      = "xpath"
-	cmdRelaxNG    = "relaxng"
-	cmdSchematron = "schematron"
-	cmdXSD        = "xsd"
-	cmdValidate   = "validate"
-	flagVersion   = "--version"
-	flagMaxInput  = "--max-input-bytes"
-	flagMaxDepth  = "--max-depth"
-	xpathBook     = "//book"
+	cmdXPath       = "xpath"
+	cmdConvert     = "convert-schema"
+	cmdConvertJSON = "convert"
+	cmdDiff        = "diff"
+	cmdFmt         = "fmt"
+	cmdPatch       = "patch"
+	cmdRelaxNG     = "relaxng"
+	cmdSchematron  = "schematron"
+	cmdXSD         = "xsd"
+	cmdValidate    = "validate"
+	flagVersion    = "--version"
+	flagMaxInput   = "--max-input-bytes"
+	flagMaxDepth   = "--max-depth"
+	xpathBook      = "//book"
 )
@@ -0,0 +1,308 @@
+package heliumcmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/jsonxml"
+	"github.com/lestrrat-go/helium/xsd"
+)
+
+var conventionNames = map[string]jsonxml.Convention{
+	"badgerfish": jsonxml.ConventionBadgerFish,
+	"parker":     jsonxml.ConventionParker,
+	"gdata":      jsonxml.ConventionGData,
+	"jsonml":     jsonxml.ConventionJsonML,
+	"xpath":      jsonxml.ConventionXPath,
+}
+
+type convertConfig struct {
+	to            string
+	convention    jsonxml.Convention
+	attrPrefix    string
+	hasAttrPrefix bool
+	textKey       string
+	hasTextKey    bool
+	arrays        []string
+	schemaFile    string
+	strip         bool
+	inferTypes    bool
+	hasInferTypes bool
+	root          string
+	indent        string
+	version       bool
+	maxInputBytes int64
+}
+
+type convertCommand struct {
+	prog     string
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	stdinTTY bool
+}
+
+func newConvertCommandWithIO(prog string, stdin io.Reader, stdout, stderr io.Writer, stdinTTY bool) *convertCommand {
+	return &convertCommand{
+		prog:     prog,
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		stdinTTY: stdinTTY,
+	}
+}
+
+func (c *convertCommand) runContext(ctx context.Context, args []string) int {
+	cfg, file := c.parseArgs(args)
+	if cfg == nil {
+		c.showUsage()
+		return ExitErr
+	}
+
+	if cfg.version {
+		c.showVersion()
+		return ExitOK
+	}
+
+	var buf []byte
+	var err error
+	if file == "-" {
+		buf, err = readInput(c.stdin, "-", cfg.maxInputBytes)
+	} else {
+		buf, err = readInputFile(file, cfg.maxInputBytes)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitReadFile
+	}
+
+	conv := jsonxml.NewConverter().Convention(cfg.convention).Indent(cfg.indent).ArrayElements(cfg.arrays...)
+	if cfg.hasAttrPrefix {
+		conv = conv.AttributePrefix(cfg.attrPrefix)
+	}
+	if cfg.hasTextKey {
+		conv = conv.TextKey(cfg.textKey)
+	}
+	if cfg.strip {
+		conv = conv.Namespaces(jsonxml.NamespaceModeStrip)
+	}
+	if cfg.hasInferTypes {
+		conv = conv.InferTypes(cfg.inferTypes)
+	}
+	if cfg.root != "" {
+		conv = conv.RootName(cfg.root)
+	}
+	if cfg.schemaFile != "" {
+		// The CLI is a trusted local tool, so the schema's includes and
+		// imports may load from the host filesystem.
+		schema, err := xsd.NewCompiler().
+			Label(cfg.schemaFile).
+			FS(iofsPermissiveRoot()).
+			CompileFile(ctx, cfg.schemaFile)
+		if err != nil {
+			_, _ = fmt.Fprintf(c.stderr, "%s: failed to compile schema: %s\n", c.prog, err)
+			return ExitSchemaComp
+		}
+		conv = conv.ArraysFromSchema(schema)
+	}
+
+	to := cfg.to
+	if to == "" {
+		to = "xml"
+		if looksLikeXML(buf) {
+			to = "json"
+		}
+	}
+	if to == "xml" {
+		doc, err := conv.ToXML(ctx, buf)
+		if err != nil {
+			_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+			return ExitErr
+		}
+		if err := helium.NewWriter().Format(true).WriteTo(c.stdout, doc); err != nil {
+			_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+			return ExitErr
+		}
+		return ExitOK
+	}
+
+	p := helium.NewParser()
+	if file != "-" {
+		p = p.BaseURI(file)
+	}
+	doc, err := p.Parse(ctx, buf)
+	if err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitErr
+	}
+	out, err := conv.ToJSON(ctx, doc)
+	if err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitErr
+	}
+	if _, err := fmt.Fprintf(c.stdout, "%s\n", out); err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitErr
+	}
+	return ExitOK
+}
+
+// looksLikeXML reports whether buf starts with markup rather than JSON.
+func looksLikeXML(buf []byte) bool {
+	buf = bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf"))
+	buf = bytes.TrimLeft(buf, " \t\r\n")
+	return len(buf) > 0 && buf[0] == '<'
+}
+
+func (c *convertCommand) showVersion() {
+	_, _ = fmt.Fprintf(c.stderr, "%s: using helium (%s)\n", c.prog, commitID())
+}
+
+func (c *convertCommand) showUsage() {
+	_, _ = fmt.Fprintf(c.stderr, `Usage : %s [options] FILE
+	Convert XML to JSON, or JSON to XML ("-" reads stdin)
+	--to json|xml : output format (default: JSON for XML input, XML otherwise)
+	--convention badgerfish|parker|gdata|jsonml|xpath : mapping (default badgerfish)
+	--attribute-prefix S : prefix of attribute keys
+	--text-key S : key of element text
+	--array NAME : always write elements NAME or {URI}NAME as arrays (repeatable)
+	--schema XSD : always write elements XSD lets repeat as arrays
+	--strip-namespaces : drop prefixes and namespace declarations
+	--infer-types | --no-infer-types : type numbers and booleans in JSON output, or not
+	--root NAME : document element for Parker input (default root)
+	--indent S : JSON indentation (default two spaces; "" for compact)
+	--max-input-bytes N : cap bytes read from FILE (0 = unlimited)
+	--version : display the version of the XML library used
+`, c.prog)
+}
+
+func (c *convertCommand) parseArgs(args []string) (*convertConfig, string) {
+	cfg := &convertConfig{indent: "  ", maxInputBytes: DefaultMaxInputBytes}
+	var positional []string
+
+	// value returns the argument of the option at args[i].
+	value := func(i int) (string, bool) {
+		if i+1 >= len(args) {
+			_, _ = fmt.Fprintf(c.stderr, "%s: %s requires an argument\n", c.prog, args[i])
+			return "", false
+		}
+		return args[i+1], true
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case flagVersion:
+			cfg.version = true
+		case "--to":
+			v, ok := value(i)
+			if !ok {
+				return nil, ""
+			}
+			i++
+			if v != "json" && v != "xml" {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --to: invalid argument %q\n", c.prog, v)
+				return nil, ""
+			}
+			cfg.to = v
+		case "--convention":
+			v, ok := value(i)
+			if !ok {
+				return nil, ""
+			}
+			i++
+			conv, found := conventionNames[strings.ToLower(v)]
+			if !found {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --convention: invalid argument %q\n", c.prog, v)
+				return nil, ""
+			}
+			cfg.convention = conv
+		case "--attribute-prefix":
+			v, ok := value(i)
+			if !ok {
+				return nil, ""
+			}
+			i++
+			cfg.attrPrefix = v
+			cfg.hasAttrPrefix = true
+		case "--text-key":
+			v, ok := value(i)
+			if !ok {
+				return nil, ""
+			}
+			i++
+			cfg.textKey = v
+			cfg.hasTextKey = true
+		case "--array":
+			v, ok := value(i)
+			if !ok {
+				return nil, ""
+			}
+			i++
+			cfg.arrays = append(cfg.arrays, v)
+		case "--schema":
+			v, ok := value(i)
+			if !ok {
+				return nil, ""
+			}
+			i++
+			cfg.schemaFile = v
+		case "--strip-namespaces":
+			cfg.strip = true
+		case "--infer-types", "--no-infer-types":
+			cfg.inferTypes = arg == "--infer-types"
+			cfg.hasInferTypes = true
+		case "--root":
+			v, ok := value(i)
+			if !ok {
+				return nil, ""
+			}
+			i++
+			cfg.root = v
+		case "--indent":
+			v, ok := value(i)
+			if !ok {
+				return nil, ""
+			}
+			i++
+			cfg.indent = v
+		case flagMaxInputBytes:
+			v, ok := value(i)
+			if !ok {
+				return nil, ""
+			}
+			i++
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-input-bytes: invalid argument %q\n", c.prog, v)
+				return nil, ""
+			}
+			cfg.maxInputBytes = n
+		default:
+			if arg != "-" && strings.HasPrefix(arg, "-") {
+				_, _ = fmt.Fprintf(c.stderr, "%s: unrecognized option %s\n", c.prog, arg)
+				return nil, ""
+			}
+			positional = append(positional, arg)
+		}
+	}
+
+	if cfg.version {
+		return cfg, ""
+	}
+
+	if len(positional) != 1 {
+		_, _ = fmt.Fprintf(c.stderr, "%s: exactly one input is required\n", c.prog)
+		return nil, ""
+	}
+	if positional[0] == "-" && c.stdinTTY {
+		_, _ = fmt.Fprintf(c.stderr, "%s: stdin is a terminal\n", c.prog)
+		return nil, ""
+	}
+	return cfg, positional[0]
+}
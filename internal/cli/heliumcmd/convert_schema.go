@@ -0,0 +1,187 @@
+package heliumcmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/dtdconv"
+)
+
+type convertSchemaConfig struct {
+	to              string
+	targetNamespace string
+	hasTargetNS     bool
+	roots           []string
+	version         bool
+	maxInputBytes   int64
+}
+
+type convertSchemaCommand struct {
+	prog     string
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	stdinTTY bool
+}
+
+func newConvertSchemaCommandWithIO(prog string, stdin io.Reader, stdout, stderr io.Writer, stdinTTY bool) *convertSchemaCommand {
+	return &convertSchemaCommand{
+		prog:     prog,
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		stdinTTY: stdinTTY,
+	}
+}
+
+func (c *convertSchemaCommand) runContext(ctx context.Context, args []string) int {
+	cfg, file := c.parseArgs(args)
+	if cfg == nil {
+		c.showUsage()
+		return ExitErr
+	}
+
+	if cfg.version {
+		c.showVersion()
+		return ExitOK
+	}
+
+	var buf []byte
+	var err error
+	if file == "-" {
+		buf, err = readInput(c.stdin, "-", cfg.maxInputBytes)
+	} else {
+		buf, err = readInputFile(file, cfg.maxInputBytes)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitReadFile
+	}
+
+	// Parameter entities the DTD references are loaded relative to it.
+	p := helium.NewParser().BlockXXE(false).FS(iofsPermissiveRoot())
+	if file != "-" {
+		p = p.BaseURI(file)
+	}
+	dtd, err := p.ParseDTD(ctx, bytes.NewReader(buf))
+	if err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitDTD
+	}
+
+	conv := dtdconv.NewConverter()
+	if cfg.hasTargetNS {
+		conv = conv.TargetNamespace(cfg.targetNamespace)
+	}
+	if len(cfg.roots) > 0 {
+		conv = conv.Roots(cfg.roots...)
+	}
+	var schema *helium.Document
+	if cfg.to == "rng" {
+		schema, err = conv.ToRelaxNG(ctx, dtd)
+	} else {
+		schema, err = conv.ToXSD(ctx, dtd)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitErr
+	}
+	if err := helium.NewWriter().Format(true).WriteTo(c.stdout, schema); err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitErr
+	}
+	return ExitOK
+}
+
+func (c *convertSchemaCommand) showVersion() {
+	_, _ = fmt.Fprintf(c.stderr, "%s: using helium (%s)\n", c.prog, commitID())
+}
+
+func (c *convertSchemaCommand) showUsage() {
+	_, _ = fmt.Fprintf(c.stderr, `Usage : %s [options] DTD
+	Print an XML Schema or RELAX NG grammar equivalent to DTD ("-" reads stdin)
+	--to xsd|rng : output schema language (default xsd)
+	--target-namespace URI : namespace of unprefixed element names
+	--root NAME : document element for RELAX NG output (repeatable)
+	--max-input-bytes N : cap bytes read from DTD (0 = unlimited)
+	--version : display the version of the XML library used
+`, c.prog)
+}
+
+func (c *convertSchemaCommand) parseArgs(args []string) (*convertSchemaConfig, string) {
+	cfg := &convertSchemaConfig{to: "xsd", maxInputBytes: DefaultMaxInputBytes}
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case flagVersion:
+			cfg.version = true
+		case "--to":
+			i++
+			if i >= len(args) {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --to requires an argument\n", c.prog)
+				return nil, ""
+			}
+			switch args[i] { //nolint:gosec // bounds checked above
+			case "xsd", "rng":
+				cfg.to = args[i] //nolint:gosec // bounds checked above
+			default:
+				_, _ = fmt.Fprintf(c.stderr, "%s: --to: invalid argument %q\n", c.prog, args[i]) //nolint:gosec // bounds checked above
+				return nil, ""
+			}
+		case "--target-namespace":
+			i++
+			if i >= len(args) {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --target-namespace requires an argument\n", c.prog)
+				return nil, ""
+			}
+			cfg.targetNamespace = args[i] //nolint:gosec // bounds checked above
+			cfg.hasTargetNS = true
+		case "--root":
+			i++
+			if i >= len(args) {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --root requires an argument\n", c.prog)
+				return nil, ""
+			}
+			cfg.roots = append(cfg.roots, args[i]) //nolint:gosec // bounds checked above
+		case flagMaxInputBytes:
+			i++
+			if i >= len(args) {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-input-bytes requires an argument\n", c.prog)
+				return nil, ""
+			}
+			n, err := strconv.ParseInt(args[i], 10, 64) //nolint:gosec // bounds checked above
+			if err != nil || n < 0 {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-input-bytes: invalid argument %q\n", c.prog, args[i]) //nolint:gosec // bounds checked above
+				return nil, ""
+			}
+			cfg.maxInputBytes = n
+		default:
+			if arg != "-" && strings.HasPrefix(arg, "-") {
+				_, _ = fmt.Fprintf(c.stderr, "%s: unrecognized option %s\n", c.prog, arg)
+				return nil, ""
+			}
+			positional = append(positional, arg)
+		}
+	}
+
+	if cfg.version {
+		return cfg, ""
+	}
+
+	if len(positional) != 1 {
+		_, _ = fmt.Fprintf(c.stderr, "%s: exactly one DTD is required\n", c.prog)
+		return nil, ""
+	}
+	if positional[0] == "-" && c.stdinTTY {
+		_, _ = fmt.Fprintf(c.stderr, "%s: stdin is a terminal\n", c.prog)
+		return nil, ""
+	}
+	return cfg, positional[0]
+}
@@ -0,0 +1,73 @@
+package heliumcmd_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium/internal/cli/heliumcmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertSchemaVersion(t *testing.T) {
+	var stderr bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(""), io.Discard, &stderr)
+	ctx = heliumcmd.WithStdinTTY(ctx, true)
+
+	code := heliumcmd.Execute(ctx, []string{cmdConvert, flagVersion})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Contains(t, stderr.String(), "using helium")
+}
+
+func TestConvertSchemaFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "item.ent", `<!ELEMENT item (#PCDATA)><!ATTLIST item id ID #REQUIRED>`)
+	dtdFile := writeFile(t, dir, "list.dtd", `<!ENTITY % item SYSTEM "item.ent">%item;<!ELEMENT list (item+)>`)
+
+	var stdout bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(""), &stdout, io.Discard)
+	ctx = heliumcmd.WithStdinTTY(ctx, true)
+
+	code := heliumcmd.Execute(ctx, []string{cmdConvert, "--target-namespace", "urn:list", dtdFile})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Contains(t, stdout.String(), `targetNamespace="urn:list"`)
+	require.Contains(t, stdout.String(), `<xs:attribute name="id" type="xs:ID" use="required"/>`)
+	require.Contains(t, stdout.String(), `<xs:element ref="item" maxOccurs="unbounded"/>`)
+}
+
+func TestConvertSchemaRelaxNGStdin(t *testing.T) {
+	var stdout bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(`<!ELEMENT a (b?)><!ELEMENT b EMPTY>`), &stdout, io.Discard)
+
+	code := heliumcmd.Execute(ctx, []string{cmdConvert, "--to", "rng", "--root", "b", "-"})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Contains(t, stdout.String(), `<grammar xmlns="http://relaxng.org/ns/structure/1.0"`)
+	require.Contains(t, stdout.String(), "<start>\n    <ref name=\"b\"/>\n  </start>")
+}
+
+func TestConvertSchemaArguments(t *testing.T) {
+	dir := t.TempDir()
+	dtdFile := writeFile(t, dir, "doc.dtd", `<!ELEMENT a EMPTY>`)
+	badFile := writeFile(t, dir, "bad.dtd", `<!ELEMENT a (b`)
+	undeclared := writeFile(t, dir, "undeclared.dtd", `<!ELEMENT a (b)>`)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "missing DTD", args: []string{cmdConvert}, want: heliumcmd.ExitErr},
+		{name: "two DTDs", args: []string{cmdConvert, dtdFile, dtdFile}, want: heliumcmd.ExitErr},
+		{name: "invalid target", args: []string{cmdConvert, "--to", "dtd", dtdFile}, want: heliumcmd.ExitErr},
+		{name: "stdin is a terminal", args: []string{cmdConvert, "-"}, want: heliumcmd.ExitErr},
+		{name: "missing file", args: []string{cmdConvert, "/missing.dtd"}, want: heliumcmd.ExitReadFile},
+		{name: "malformed DTD", args: []string{cmdConvert, badFile}, want: heliumcmd.ExitDTD},
+		{name: "unconvertible DTD", args: []string{cmdConvert, undeclared}, want: heliumcmd.ExitErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, executeDiscard(t, tt.args))
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package heliumcmd_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium/internal/cli/heliumcmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertVersion(t *testing.T) {
+	var stderr bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(""), io.Discard, &stderr)
+	ctx = heliumcmd.WithStdinTTY(ctx, true)
+
+	code := heliumcmd.Execute(ctx, []string{cmdConvertJSON, flagVersion})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Contains(t, stderr.String(), "using helium")
+}
+
+func TestConvertToJSON(t *testing.T) {
+	dir := t.TempDir()
+	xmlFile := writeFile(t, dir, "order.xml", `<order id="7"><item>tea</item><total>2.5</total></order>`)
+
+	var stdout bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(""), &stdout, io.Discard)
+	ctx = heliumcmd.WithStdinTTY(ctx, true)
+
+	code := heliumcmd.Execute(ctx, []string{cmdConvertJSON, xmlFile})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Equal(t, `{
+  "order": {
+    "@id": "7",
+    "item": {
+      "$": "tea"
+    },
+    "total": {
+      "$": "2.5"
+    }
+  }
+}
+`, stdout.String())
+
+	stdout.Reset()
+	code = heliumcmd.Execute(ctx, []string{cmdConvertJSON, "--convention", "parker", "--array", "item", "--indent", "", xmlFile})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Equal(t, `{"item":["tea"],"total":2.5}`+"\n", stdout.String())
+}
+
+func TestConvertSchemaArrays(t *testing.T) {
+	dir := t.TempDir()
+	xsdFile := writeFile(t, dir, "list.xsd", `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="list">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="item" type="xs:string" maxOccurs="unbounded"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`)
+
+	var stdout bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(`<list><item>a</item></list>`), &stdout, io.Discard)
+
+	code := heliumcmd.Execute(ctx, []string{cmdConvertJSON, "--convention", "parker", "--schema", xsdFile, "--indent", "", "-"})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Equal(t, `{"item":["a"]}`+"\n", stdout.String())
+}
+
+func TestConvertToXML(t *testing.T) {
+	var stdout bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(`{"a":[1,true],"b":null}`), &stdout, io.Discard)
+
+	code := heliumcmd.Execute(ctx, []string{cmdConvertJSON, "--convention", "xpath", "-"})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Equal(t, `<?xml version="1.0"?>
+<map xmlns="http://www.w3.org/2005/xpath-functions">
+  <array key="a">
+    <number>1</number>
+    <boolean>true</boolean>
+  </array>
+  <null key="b"/>
+</map>
+`, stdout.String())
+
+	stdout.Reset()
+	ctx = heliumcmd.WithIO(t.Context(), strings.NewReader(`<r><a>1</a></r>`), &stdout, io.Discard)
+	code = heliumcmd.Execute(ctx, []string{cmdConvertJSON, "--convention", "parker", "--to", "xml", "-"})
+	require.Equal(t, heliumcmd.ExitErr, code)
+}
+
+func TestConvertArguments(t *testing.T) {
+	dir := t.TempDir()
+	xmlFile := writeFile(t, dir, "doc.xml", `<a/>`)
+	badFile := writeFile(t, dir, "bad.xml", `<a>`)
+	badSchema := writeFile(t, dir, "bad.xsd", `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element/></xs:schema>`)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "missing input", args: []string{cmdConvertJSON}, want: heliumcmd.ExitErr},
+		{name: "two inputs", args: []string{cmdConvertJSON, xmlFile, xmlFile}, want: heliumcmd.ExitErr},
+		{name: "bad convention", args: []string{cmdConvertJSON, "--convention", "yaml", xmlFile}, want: heliumcmd.ExitErr},
+		{name: "bad to", args: []string{cmdConvertJSON, "--to", "yaml", xmlFile}, want: heliumcmd.ExitErr},
+		{name: "missing argument", args: []string{cmdConvertJSON, xmlFile, "--root"}, want: heliumcmd.ExitErr},
+		{name: "unknown option", args: []string{cmdConvertJSON, "--pretty", xmlFile}, want: heliumcmd.ExitErr},
+		{name: "missing file", args: []string{cmdConvertJSON, dir + "/missing.xml"}, want: heliumcmd.ExitReadFile},
+		{name: "malformed XML", args: []string{cmdConvertJSON, badFile}, want: heliumcmd.ExitErr},
+		{name: "bad schema", args: []string{cmdConvertJSON, "--schema", badSchema, xmlFile}, want: heliumcmd.ExitSchemaComp},
+		{name: "stdin terminal", args: []string{cmdConvertJSON, "-"}, want: heliumcmd.ExitErr},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(""), io.Discard, io.Discard)
+			ctx = heliumcmd.WithStdinTTY(ctx, true)
+			require.Equal(t, tc.want, heliumcmd.Execute(ctx, tc.args))
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package heliumcmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xmldiff"
+)
+
+type diffConfig struct {
+	idAttrs       []string
+	threshold     float64
+	hasThreshold  bool
+	version       bool
+	maxInputBytes int64
+	maxDepth      int
+}
+
+type diffCommand struct {
+	prog     string
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	stdinTTY bool
+}
+
+func newDiffCommandWithIO(prog string, stdin io.Reader, stdout, stderr io.Writer, stdinTTY bool) *diffCommand {
+	return &diffCommand{
+		prog:     prog,
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		stdinTTY: stdinTTY,
+	}
+}
+
+func (c *diffCommand) runContext(ctx context.Context, args []string) int {
+	cfg, files := c.parseArgs(args)
+	if cfg == nil {
+		c.showUsage()
+		return ExitErr
+	}
+
+	if cfg.version {
+		c.showVersion()
+		return ExitOK
+	}
+
+	var docs [2]*helium.Document
+	for i, name := range files {
+		doc, code := parseNamedInput(ctx, c.prog, c.stdin, c.stderr, namedInput{name: name, stdin: name == "-"}, cfg.maxInputBytes, cfg.maxDepth)
+		if code != ExitOK {
+			return code
+		}
+		docs[i] = doc
+	}
+
+	d := xmldiff.NewDiffer()
+	if len(cfg.idAttrs) > 0 {
+		d = d.IDAttributes(cfg.idAttrs...)
+	}
+	if cfg.hasThreshold {
+		d = d.Threshold(cfg.threshold)
+	}
+	patch, err := d.Diff(ctx, docs[0], docs[1])
+	if err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitErr
+	}
+	if err := helium.NewWriter().WriteTo(c.stdout, patch); err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitErr
+	}
+	return ExitOK
+}
+
+func (c *diffCommand) showVersion() {
+	_, _ = fmt.Fprintf(c.stderr, "%s: using helium (%s)\n", c.prog, commitID())
+}
+
+func (c *diffCommand) showUsage() {
+	_, _ = fmt.Fprintf(c.stderr, `Usage : %s [options] OLD NEW
+	Print an RFC 5261 XML patch that turns OLD into NEW ("-" reads stdin)
+	--id-attr NAME : match elements by the attribute NAME (repeatable, default "id")
+	--threshold F : minimum similarity, from 0 to 1, for pairing elements (default 0.5)
+	--max-input-bytes N : cap bytes read per input (0 = unlimited)
+	--max-depth N : cap element nesting depth (default 256, 0 = unlimited)
+	--version : display the version of the XML library used
+`, c.prog)
+}
+
+func (c *diffCommand) parseArgs(args []string) (*diffConfig, []string) {
+	cfg := &diffConfig{maxInputBytes: DefaultMaxInputBytes, maxDepth: -1}
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case flagVersion:
+			cfg.version = true
+		case "--id-attr":
+			i++
+			if i >= len(args) {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --id-attr requires an argument\n", c.prog)
+				return nil, nil
+			}
+			cfg.idAttrs = append(cfg.idAttrs, args[i]) //nolint:gosec // bounds checked above
+		case "--threshold":
+			i++
+			if i >= len(args) {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --threshold requires an argument\n", c.prog)
+				return nil, nil
+			}
+			f, err := strconv.ParseFloat(args[i], 64) //nolint:gosec // bounds checked above
+			if err != nil || f < 0 || f > 1 {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --threshold: invalid argument %q\n", c.prog, args[i]) //nolint:gosec // bounds checked above
+				return nil, nil
+			}
+			cfg.threshold = f
+			cfg.hasThreshold = true
+		case flagMaxInputBytes:
+			i++
+			if i >= len(args) {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-input-bytes requires an argument\n", c.prog)
+				return nil, nil
+			}
+			n, err := strconv.ParseInt(args[i], 10, 64) //nolint:gosec // bounds checked above
+			if err != nil || n < 0 {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-input-bytes: invalid argument %q\n", c.prog, args[i]) //nolint:gosec // bounds checked above
+				return nil, nil
+			}
+			cfg.maxInputBytes = n
+		case flagMaxDepth:
+			i++
+			if i >= len(args) {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-depth requires an argument\n", c.prog)
+				return nil, nil
+			}
+			n, err := strconv.Atoi(args[i]) //nolint:gosec // bounds checked above
+			if err != nil || n < 0 {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-depth: invalid argument %q\n", c.prog, args[i]) //nolint:gosec // bounds checked above
+				return nil, nil
+			}
+			cfg.maxDepth = n
+		default:
+			if arg != "-" && strings.HasPrefix(arg, "-") {
+				_, _ = fmt.Fprintf(c.stderr, "%s: unrecognized option %s\n", c.prog, arg)
+				return nil, nil
+			}
+			positional = append(positional, arg)
+		}
+	}
+
+	if cfg.version {
+		return cfg, positional
+	}
+
+	if !checkInputPair(c.prog, c.stderr, c.stdinTTY, positional, "OLD and NEW") {
+		return nil, nil
+	}
+	return cfg, positional
+}
+
+// checkInputPair reports whether positional names exactly the two inputs a
+// command compares, at most one of them stdin, and stdin only when it is not
+// a terminal.
+func checkInputPair(prog string, stderr io.Writer, stdinTTY bool, positional []string, what string) bool {
+	if len(positional) != 2 {
+		_, _ = fmt.Fprintf(stderr, "%s: %s are required\n", prog, what)
+		return false
+	}
+	if positional[0] == "-" && positional[1] == "-" {
+		_, _ = fmt.Fprintf(stderr, "%s: only one input can be read from stdin\n", prog)
+		return false
+	}
+	if stdinTTY && (positional[0] == "-" || positional[1] == "-") {
+		_, _ = fmt.Fprintf(stderr, "%s: stdin is a terminal\n", prog)
+		return false
+	}
+	return true
+}
+
+// parseNamedInput reads and parses input, reporting failures to stderr. It
+// returns ExitReadFile when input cannot be read and ExitErr when it does not
+// parse.
+func parseNamedInput(ctx context.Context, prog string, stdin io.Reader, stderr io.Writer, input namedInput, maxInputBytes int64, maxDepth int) (*helium.Document, int) {
+	var buf []byte
+	var err error
+	if input.stdin {
+		buf, err = readInput(stdin, "-", maxInputBytes)
+	} else {
+		buf, err = readInputFile(input.name, maxInputBytes)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "%s: %s\n", prog, err)
+		return nil, ExitReadFile
+	}
+
+	p := applyMaxDepth(helium.NewParser(), maxDepth)
+	if !input.stdin {
+		p = p.BaseURI(input.name)
+	}
+	doc, err := p.Parse(ctx, buf)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "%s: %s\n", prog, err)
+		return nil, ExitErr
+	}
+	return doc, ExitOK
+}
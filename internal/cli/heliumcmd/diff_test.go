@@ -0,0 +1,77 @@
+package heliumcmd_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium/internal/cli/heliumcmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffVersion(t *testing.T) {
+	var stderr bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(""), io.Discard, &stderr)
+	ctx = heliumcmd.WithStdinTTY(ctx, true)
+
+	code := heliumcmd.Execute(ctx, []string{cmdDiff, flagVersion})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Contains(t, stderr.String(), "using helium")
+}
+
+func TestDiffFiles(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := writeFile(t, dir, "old.xml", `<root><item id="a">one</item><item id="b">two</item></root>`)
+	newFile := writeFile(t, dir, "new.xml", `<root><item id="a">uno</item><item id="b">two</item></root>`)
+
+	var stdout bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(""), &stdout, io.Discard)
+	ctx = heliumcmd.WithStdinTTY(ctx, true)
+
+	code := heliumcmd.Execute(ctx, []string{cmdDiff, oldFile, newFile})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Contains(t, stdout.String(), `<replace sel="/root/item[1]/text()">uno</replace>`)
+}
+
+func TestDiffIDAttrAndStdin(t *testing.T) {
+	dir := t.TempDir()
+	newFile := writeFile(t, dir, "new.xml", `<root><item key="b">one two</item></root>`)
+
+	// Without --id-attr the items are similar enough to be edited in place.
+	var stdout bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(`<root><item key="a">one two</item></root>`), &stdout, io.Discard)
+	code := heliumcmd.Execute(ctx, []string{cmdDiff, "-", newFile})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Contains(t, stdout.String(), `<replace sel="/root/item/@key">b</replace>`)
+
+	// With it, items with different keys never correspond.
+	stdout.Reset()
+	ctx = heliumcmd.WithIO(t.Context(), strings.NewReader(`<root><item key="a">one two</item></root>`), &stdout, io.Discard)
+	code = heliumcmd.Execute(ctx, []string{cmdDiff, "--id-attr", "key", "-", newFile})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Contains(t, stdout.String(), `<remove sel="/root/item"/>`)
+}
+
+func TestDiffArguments(t *testing.T) {
+	dir := t.TempDir()
+	xmlFile := writeFile(t, dir, "doc.xml", `<root/>`)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "missing inputs", args: []string{cmdDiff, xmlFile}, want: heliumcmd.ExitErr},
+		{name: "too many inputs", args: []string{cmdDiff, xmlFile, xmlFile, xmlFile}, want: heliumcmd.ExitErr},
+		{name: "stdin twice", args: []string{cmdDiff, "-", "-"}, want: heliumcmd.ExitErr},
+		{name: "stdin is a terminal", args: []string{cmdDiff, "-", xmlFile}, want: heliumcmd.ExitErr},
+		{name: "invalid threshold", args: []string{cmdDiff, "--threshold", "2", xmlFile, xmlFile}, want: heliumcmd.ExitErr},
+		{name: "missing file", args: []string{cmdDiff, xmlFile, "/missing.xml"}, want: heliumcmd.ExitReadFile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, executeDiscard(t, tt.args))
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package heliumcmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+)
+
+// ExitUnformatted is returned by fmt --check when a file is not formatted.
+const ExitUnformatted = 13
+
+type fmtConfig struct {
+	formatter     helium.Formatter
+	write         bool
+	check         bool
+	version       bool
+	maxInputBytes int64
+}
+
+type fmtCommand struct {
+	prog     string
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	stdinTTY bool
+}
+
+func newFmtCommandWithIO(prog string, stdin io.Reader, stdout, stderr io.Writer, stdinTTY bool) *fmtCommand {
+	return &fmtCommand{
+		prog:     prog,
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		stdinTTY: stdinTTY,
+	}
+}
+
+func (c *fmtCommand) runContext(ctx context.Context, args []string) int {
+	cfg, files := c.parseArgs(args)
+	if cfg == nil {
+		c.showUsage()
+		return ExitErr
+	}
+
+	if cfg.version {
+		c.showVersion()
+		return ExitOK
+	}
+
+	exitCode := ExitOK
+	for _, file := range files {
+		exitCode = mergeExitCode(exitCode, c.formatFile(ctx, cfg, file))
+	}
+	return exitCode
+}
+
+// formatFile formats one file and reports the exit code for it.
+func (c *fmtCommand) formatFile(ctx context.Context, cfg *fmtConfig, file string) int {
+	var src []byte
+	var err error
+	if file == "-" {
+		src, err = readInput(c.stdin, "-", cfg.maxInputBytes)
+	} else {
+		src, err = readInputFile(file, cfg.maxInputBytes)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitReadFile
+	}
+
+	// External DTDs and entities are never loaded: formatting only needs the
+	// markup as written.
+	p := helium.NewParser()
+	if file != "-" {
+		p = p.BaseURI(file)
+	}
+	out, err := cfg.formatter.Parser(p).Format(ctx, src)
+	if err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s: %s\n", c.prog, file, err)
+		return ExitErr
+	}
+
+	switch {
+	case cfg.check:
+		if bytes.Equal(src, out) {
+			return ExitOK
+		}
+		name := file
+		if file == "-" {
+			name = "<stdin>"
+		}
+		_, _ = fmt.Fprintln(c.stdout, name)
+		return ExitUnformatted
+	case cfg.write:
+		if bytes.Equal(src, out) {
+			return ExitOK
+		}
+		if err := c.writeFile(file, out); err != nil {
+			_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+			return ExitErr
+		}
+		return ExitOK
+	}
+	if _, err := c.stdout.Write(out); err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitErr
+	}
+	return ExitOK
+}
+
+// writeFile replaces file with data, leaving it untouched on failure.
+func (c *fmtCommand) writeFile(file string, data []byte) error {
+	p, err := newPendingOutput(file)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+	if _, err := p.File().Write(data); err != nil {
+		return err //nolint:wrapcheck // caller reports raw error
+	}
+	return p.Commit()
+}
+
+func (c *fmtCommand) showVersion() {
+	_, _ = fmt.Fprintf(c.stderr, "%s: using helium (%s)\n", c.prog, commitID())
+}
+
+func (c *fmtCommand) showUsage() {
+	_, _ = fmt.Fprintf(c.stderr, `Usage : %s [options] XMLfiles ...
+	Print the XML files laid out in a canonical style ("-" reads stdin)
+	--width N : line width for start tags and comments (0 = unlimited, default %d)
+	--indent S : string written per level of nesting (default two spaces)
+	--wrap one-per-line|aligned|never : how start tags wider than the line break
+	--sort source|name|canonical : attribute order (default source)
+	--max-blank-lines N : blank lines kept between nodes (default 1)
+	--reflow-comments : rewrap comments wider than the line
+	-w, --write : rewrite files in place instead of printing them
+	--check : list files that are not formatted and exit with %d
+	--max-input-bytes N : cap bytes read from each file (0 = unlimited)
+	--version : display the version of the XML library used
+`, c.prog, helium.DefaultFormatWidth, ExitUnformatted)
+}
+
+func (c *fmtCommand) parseArgs(args []string) (*fmtConfig, []string) {
+	cfg := &fmtConfig{formatter: helium.NewFormatter(), maxInputBytes: DefaultMaxInputBytes}
+	var files []string
+
+	// value returns the argument of the option at args[i].
+	value := func(i int) (string, bool) {
+		if i+1 >= len(args) {
+			_, _ = fmt.Fprintf(c.stderr, "%s: %s requires an argument\n", c.prog, args[i])
+			return "", false
+		}
+		return args[i+1], true
+	}
+	count := func(name, s string) (int, bool) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			_, _ = fmt.Fprintf(c.stderr, "%s: %s: invalid argument %q\n", c.prog, name, s)
+			return 0, false
+		}
+		return n, true
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case flagVersion:
+			cfg.version = true
+		case "-w", "--write":
+			cfg.write = true
+		case "--check":
+			cfg.check = true
+		case "--reflow-comments":
+			cfg.formatter = cfg.formatter.ReflowComments(true)
+		case "--width", "--max-blank-lines":
+			v, ok := value(i)
+			if !ok {
+				return nil, nil
+			}
+			i++
+			n, ok := count(arg, v)
+			if !ok {
+				return nil, nil
+			}
+			if arg == "--width" {
+				cfg.formatter = cfg.formatter.Width(n)
+			} else {
+				cfg.formatter = cfg.formatter.MaxBlankLines(n)
+			}
+		case "--indent":
+			v, ok := value(i)
+			if !ok {
+				return nil, nil
+			}
+			i++
+			if strings.Trim(v, " \t") != "" {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --indent: invalid argument %q\n", c.prog, v)
+				return nil, nil
+			}
+			cfg.formatter = cfg.formatter.Indent(v)
+		case "--wrap":
+			v, ok := value(i)
+			if !ok {
+				return nil, nil
+			}
+			i++
+			switch v {
+			case "one-per-line":
+				cfg.formatter = cfg.formatter.AttributeWrap(helium.AttributeWrapOnePerLine)
+			case "aligned":
+				cfg.formatter = cfg.formatter.AttributeWrap(helium.AttributeWrapAligned)
+			case "never":
+				cfg.formatter = cfg.formatter.AttributeWrap(helium.AttributeWrapNever)
+			default:
+				_, _ = fmt.Fprintf(c.stderr, "%s: --wrap: invalid argument %q\n", c.prog, v)
+				return nil, nil
+			}
+		case "--sort":
+			v, ok := value(i)
+			if !ok {
+				return nil, nil
+			}
+			i++
+			switch v {
+			case "source":
+				cfg.formatter = cfg.formatter.AttributeOrder(helium.AttributeOrderSource)
+			case "name":
+				cfg.formatter = cfg.formatter.AttributeOrder(helium.AttributeOrderName)
+			case "canonical":
+				cfg.formatter = cfg.formatter.AttributeOrder(helium.AttributeOrderCanonical)
+			default:
+				_, _ = fmt.Fprintf(c.stderr, "%s: --sort: invalid argument %q\n", c.prog, v)
+				return nil, nil
+			}
+		case flagMaxInputBytes:
+			v, ok := value(i)
+			if !ok {
+				return nil, nil
+			}
+			i++
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-input-bytes: invalid argument %q\n", c.prog, v)
+				return nil, nil
+			}
+			cfg.maxInputBytes = n
+		default:
+			if arg != "-" && strings.HasPrefix(arg, "-") {
+				_, _ = fmt.Fprintf(c.stderr, "%s: unrecognized option %s\n", c.prog, arg)
+				return nil, nil
+			}
+			files = append(files, arg)
+		}
+	}
+
+	if cfg.version {
+		return cfg, nil
+	}
+
+	if len(files) == 0 {
+		_, _ = fmt.Fprintf(c.stderr, "%s: no input files\n", c.prog)
+		return nil, nil
+	}
+	if cfg.write && cfg.check {
+		_, _ = fmt.Fprintf(c.stderr, "%s: --write and --check are mutually exclusive\n", c.prog)
+		return nil, nil
+	}
+	for _, f := range files {
+		if f == "-" && cfg.write {
+			_, _ = fmt.Fprintf(c.stderr, "%s: cannot use --write with stdin\n", c.prog)
+			return nil, nil
+		}
+		if f == "-" && c.stdinTTY {
+			_, _ = fmt.Fprintf(c.stderr, "%s: stdin is a terminal\n", c.prog)
+			return nil, nil
+		}
+	}
+	return cfg, files
+}
@@ -0,0 +1,108 @@
+package heliumcmd_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium/internal/cli/heliumcmd"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fmtUnformatted = `<root><a x="1"/><b>text</b></root>`
+	fmtFormatted   = "<root>\n  <a x=\"1\"/>\n  <b>text</b>\n</root>\n"
+)
+
+func executeFmt(t *testing.T, stdin string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	var outBuf, errBuf bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(stdin), &outBuf, &errBuf)
+	code = heliumcmd.Execute(ctx, append([]string{cmdFmt}, args...))
+	return outBuf.String(), errBuf.String(), code
+}
+
+func TestFmtVersion(t *testing.T) {
+	_, stderr, code := executeFmt(t, "", flagVersion)
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Contains(t, stderr, "using helium")
+}
+
+func TestFmtStdout(t *testing.T) {
+	stdout, _, code := executeFmt(t, fmtUnformatted, "-")
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Equal(t, fmtFormatted, stdout)
+
+	stdout, _, code = executeFmt(t, `<r><item first="1" second="2"/></r>`, "--width", "20", "--wrap", "aligned", "--indent", "\t", "--sort", "name", "-")
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Equal(t, "<r>\n\t<item first=\"1\"\n\t      second=\"2\"/>\n</r>\n", stdout)
+}
+
+func TestFmtCheck(t *testing.T) {
+	dir := t.TempDir()
+	good := writeFile(t, dir, "good.xml", fmtFormatted)
+	bad := writeFile(t, dir, "bad.xml", fmtUnformatted)
+
+	stdout, _, code := executeFmt(t, "", "--check", good)
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Empty(t, stdout)
+
+	stdout, _, code = executeFmt(t, "", "--check", good, bad)
+	require.Equal(t, heliumcmd.ExitUnformatted, code)
+	require.Equal(t, bad+"\n", stdout)
+
+	// --check never modifies files.
+	got, err := os.ReadFile(bad)
+	require.NoError(t, err)
+	require.Equal(t, fmtUnformatted, string(got))
+}
+
+func TestFmtWrite(t *testing.T) {
+	dir := t.TempDir()
+	bad := writeFile(t, dir, "bad.xml", fmtUnformatted)
+
+	stdout, _, code := executeFmt(t, "", "-w", bad)
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Empty(t, stdout)
+	got, err := os.ReadFile(bad)
+	require.NoError(t, err)
+	require.Equal(t, fmtFormatted, string(got))
+
+	_, _, code = executeFmt(t, "", "--check", bad)
+	require.Equal(t, heliumcmd.ExitOK, code)
+}
+
+func TestFmtArguments(t *testing.T) {
+	dir := t.TempDir()
+	good := writeFile(t, dir, "good.xml", fmtFormatted)
+	broken := writeFile(t, dir, "broken.xml", `<root>`)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "no files", args: []string{cmdFmt}, want: heliumcmd.ExitErr},
+		{name: "unknown option", args: []string{cmdFmt, "--bogus", good}, want: heliumcmd.ExitErr},
+		{name: "bad width", args: []string{cmdFmt, "--width", "-1", good}, want: heliumcmd.ExitErr},
+		{name: "missing width", args: []string{cmdFmt, "--width"}, want: heliumcmd.ExitErr},
+		{name: "bad wrap", args: []string{cmdFmt, "--wrap", "sometimes", good}, want: heliumcmd.ExitErr},
+		{name: "bad sort", args: []string{cmdFmt, "--sort", "random", good}, want: heliumcmd.ExitErr},
+		{name: "bad indent", args: []string{cmdFmt, "--indent", "--", good}, want: heliumcmd.ExitErr},
+		{name: "write and check", args: []string{cmdFmt, "-w", "--check", good}, want: heliumcmd.ExitErr},
+		{name: "write stdin", args: []string{cmdFmt, "-w", "-"}, want: heliumcmd.ExitErr},
+		{name: "stdin terminal", args: []string{cmdFmt, "-"}, want: heliumcmd.ExitErr},
+		{name: "not well-formed", args: []string{cmdFmt, broken}, want: heliumcmd.ExitErr},
+		{name: "missing file", args: []string{cmdFmt, dir + "/missing.xml"}, want: heliumcmd.ExitReadFile},
+		{name: "too large", args: []string{cmdFmt, flagMaxInput, "4", good}, want: heliumcmd.ExitReadFile},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, executeDiscard(t, tc.args))
+		})
+	}
+
+	_, _, code := executeFmt(t, "", "--check", broken, good)
+	require.Equal(t, heliumcmd.ExitErr, code)
+}
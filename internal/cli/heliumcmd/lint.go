@@ -23,6 +23,7 @@ import (
 const (
 	ExitOK         = 0
 	ExitErr        = 1
+	ExitDTD        = 2
 	ExitValidation = 3
 	ExitReadFile   = 4
 	ExitSchemaComp = 5
@@ -36,6 +37,7 @@ type config struct {
 	noXIncNode  bool
 	noBaseFixup bool
 	dtdValid    bool
+	dtdFile     string
 	c14nMode    int
 	schemaFile  string
 	xpathExpr   string
@@ -155,6 +157,16 @@ func (c *command) runContext(ctx context.Context, args []string) int {
 		}
 	}
 
+	var dtd *helium.DTD
+	if cfg.dtdFile != "" {
+		var err error
+		dtd, err = c.loadDTD(ctx, cfg)
+		if err != nil {
+			_, _ = fmt.Fprintf(c.stderr, "%s: could not parse DTD %s: %s\n", c.prog, cfg.dtdFile, err)
+			return ExitDTD
+		}
+	}
+
 	out := c.stdout
 	var pending *pendingOutput
 	if cfg.outputFile != "" {
@@ -181,7 +193,7 @@ func (c *command) runContext(ctx context.Context, args []string) int {
 
 	exitCode := ExitOK
 	for _, input := range inputs {
-		code := c.processInput(ctx, cfg, input, cat, schema, out)
+		code := c.processInput(ctx, cfg, input, cat, schema, dtd, out)
 		exitCode = mergeExitCode(exitCode, code)
 	}
 
@@ -205,7 +217,7 @@ func (c *command) runContext(ctx context.Context, args []string) int {
 }
 
 // checkOutputCollision reports a non-OK exit code if the configured output
-// file refers to the same file as any XML input or the schema.
+// file refers to the same file as any XML input, the schema, or the DTD.
 func (c *command) checkOutputCollision(cfg *config, inputs []namedInput) int {
 	for _, input := range inputs {
 		if input.stdin {
@@ -220,6 +232,10 @@ func (c *command) checkOutputCollision(cfg *config, inputs []namedInput) int {
 		_, _ = fmt.Fprintf(c.stderr, "%s: --output %q would overwrite schema %q\n", c.prog, cfg.outputFile, cfg.schemaFile)
 		return ExitErr
 	}
+	if cfg.dtdFile != "" && samePath(cfg.outputFile, cfg.dtdFile) {
+		_, _ = fmt.Fprintf(c.stderr, "%s: --output %q would overwrite DTD %q\n", c.prog, cfg.outputFile, cfg.dtdFile)
+		return ExitErr
+	}
 	return ExitOK
 }
 
@@ -236,6 +252,7 @@ func (c *command) showUsage() {
 	--loaddtd : fetch external DTD
 	--dtdattr : loaddtd + populate tree with inherited attributes
 	--valid : validate the document with the DTD
+	--dtdvalid FILE : validate the document against the given DTD
 	--nowarning : do not emit warnings from parser/validator
 	--pedantic : enable pedantic error reporting
 	--noblanks : drop (ignorable?) blanks spaces
@@ -303,6 +320,13 @@ func (c *command) parseArgs(args []string) (*config, []string) {
 			cfg.parser = cfg.parser.ValidateDTD(true)
 			cfg.dtdValid = true
 			cfg.loadExternal = true
+		case "--dtdvalid":
+			i++
+			if i >= len(args) {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --dtdvalid requires an argument\n", c.prog)
+				return nil, nil
+			}
+			cfg.dtdFile = args[i] //nolint:gosec // bounds checked above
 		case "--nowarning":
 			cfg.parser = cfg.parser.SuppressWarnings(true)
 		case "--pedantic":
@@ -623,7 +647,27 @@ func (c *command) compileSchema(ctx context.Context, cfg *config) (*xsd.Schema,
 	return schema, nil
 }
 
-func (c *command) processInput(ctx context.Context, cfg *config, input namedInput, cat helium.CatalogResolver, schema *xsd.Schema, out io.Writer) int {
+// loadDTD parses the --dtdvalid DTD. Parameter entities it references
+// resolve relative to it, through the --path search path when one is given.
+func (c *command) loadDTD(ctx context.Context, cfg *config) (*helium.DTD, error) {
+	f, err := os.Open(cfg.dtdFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fsys := iofsPermissiveRoot()
+	if dirs := c.pathDirs(cfg); len(dirs) > 0 {
+		fsys = pathSearchFS{base: iofsPermissiveRoot(), dirs: dirs}
+	}
+	return helium.NewParser().
+		BlockXXE(false).
+		FS(fsys).
+		BaseURI(cfg.dtdFile).
+		ParseDTD(ctx, f)
+}
+
+func (c *command) processInput(ctx context.Context, cfg *config, input namedInput, cat helium.CatalogResolver, schema *xsd.Schema, dtd *helium.DTD, out io.Writer) int {
 	var buf []byte
 	var err error
 	if input.stdin {
@@ -727,6 +771,23 @@ func (c *command) processInput(ctx context.Context, cfg *config, input namedInpu
 		return ExitValidation
 	}
 
+	if dtd != nil {
+		var t0 time.Time
+		if cfg.timing {
+			t0 = time.Now()
+		}
+		err := helium.NewDTDValidator(dtd).
+			ErrorHandler(&lineErrorHandler{w: c.stderr}).
+			Validate(ctx, doc)
+		if cfg.timing {
+			_, _ = fmt.Fprintf(c.stderr, "Validating took %s\n", time.Since(t0))
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(c.stderr, "%s fails to validate\n", input.name)
+			return ExitValidation
+		}
+	}
+
 	if cfg.xpathExpr != "" {
 		return c.evalXPath(ctx, cfg, doc, out)
 	}
@@ -122,7 +122,7 @@ func TestLintEncodeWithXPathRejected(t *testing.T) {
 }
 
 func TestParseArgsMissingValues(t *testing.T) {
-	flags := []string{"--schema", "--xpath", "--output", "--encode", "--pretty", "--path", "--repeat"}
+	flags := []string{"--schema", "--dtdvalid", "--xpath", "--output", "--encode", "--pretty", "--path", "--repeat"}
 	for _, flag := range flags {
 		t.Run(flag, func(t *testing.T) {
 			_, _, code := executeLint(t, strings.NewReader(""), flag)
@@ -518,6 +518,48 @@ func TestSchemaValidation(t *testing.T) {
 	}
 }
 
+func TestDTDValid(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile(t, dir, "items.ent", `<!ELEMENT item (#PCDATA)>`)
+	dtdFile := writeFile(t, dir, "list.dtd", `<!ENTITY % items SYSTEM "items.ent">
+%items;
+<!ELEMENT list (item+)>`)
+
+	tests := []struct {
+		name     string
+		xml      string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "valid", xml: `<list><item>a</item></list>`, wantCode: heliumcmd.ExitOK},
+		{name: "own DOCTYPE ignored", xml: `<!DOCTYPE list [<!ELEMENT list EMPTY>]><list><item>a</item></list>`, wantCode: heliumcmd.ExitOK},
+		{name: "invalid", xml: `<list><other/></list>`, wantCode: heliumcmd.ExitValidation, wantErr: "element other: no declaration found\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			xmlFile := writeFile(t, t.TempDir(), "test.xml", tc.xml)
+			_, errOut, code := executeLintFile(t, xmlFile, "--dtdvalid", dtdFile, "--noout")
+			require.Equal(t, tc.wantCode, code, errOut)
+			if tc.wantErr != "" {
+				require.Contains(t, errOut, tc.wantErr)
+				require.Contains(t, errOut, "fails to validate")
+			}
+		})
+	}
+
+	t.Run("malformed DTD", func(t *testing.T) {
+		t.Parallel()
+		bad := writeFile(t, t.TempDir(), "bad.dtd", `<!ELEMENT list (item`)
+		xmlFile := writeFile(t, t.TempDir(), "test.xml", `<list/>`)
+		_, errOut, code := executeLintFile(t, xmlFile, "--dtdvalid", bad, "--noout")
+		require.Equal(t, heliumcmd.ExitDTD, code)
+		require.Contains(t, errOut, "could not parse DTD")
+	})
+}
+
 func TestSchemaCompileDiagnosticReachesStderr(t *testing.T) {
 	// A duplicate global element is a fatal schema compile error. lint must
 	// compile with an ErrorHandler + Label so the diagnostic detail reaches
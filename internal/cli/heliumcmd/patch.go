@@ -0,0 +1,137 @@
+package heliumcmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xmldiff"
+)
+
+// ExitPatch is returned when a patch does not apply to the document.
+const ExitPatch = 12
+
+type patchConfig struct {
+	version       bool
+	maxInputBytes int64
+	maxDepth      int
+}
+
+type patchCommand struct {
+	prog     string
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	stdinTTY bool
+}
+
+func newPatchCommandWithIO(prog string, stdin io.Reader, stdout, stderr io.Writer, stdinTTY bool) *patchCommand {
+	return &patchCommand{
+		prog:     prog,
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		stdinTTY: stdinTTY,
+	}
+}
+
+func (c *patchCommand) runContext(ctx context.Context, args []string) int {
+	cfg, files := c.parseArgs(args)
+	if cfg == nil {
+		c.showUsage()
+		return ExitErr
+	}
+
+	if cfg.version {
+		c.showVersion()
+		return ExitOK
+	}
+
+	var docs [2]*helium.Document
+	for i, name := range files {
+		doc, code := parseNamedInput(ctx, c.prog, c.stdin, c.stderr, namedInput{name: name, stdin: name == "-"}, cfg.maxInputBytes, cfg.maxDepth)
+		if code != ExitOK {
+			return code
+		}
+		docs[i] = doc
+	}
+
+	out, err := xmldiff.Patch(ctx, docs[0], docs[1])
+	if err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitPatch
+	}
+	if err := helium.NewWriter().WriteTo(c.stdout, out); err != nil {
+		_, _ = fmt.Fprintf(c.stderr, "%s: %s\n", c.prog, err)
+		return ExitErr
+	}
+	return ExitOK
+}
+
+func (c *patchCommand) showVersion() {
+	_, _ = fmt.Fprintf(c.stderr, "%s: using helium (%s)\n", c.prog, commitID())
+}
+
+func (c *patchCommand) showUsage() {
+	_, _ = fmt.Fprintf(c.stderr, `Usage : %s [options] XMLfile PATCH
+	Apply an RFC 5261 XML patch and print the result ("-" reads stdin)
+	--max-input-bytes N : cap bytes read per input (0 = unlimited)
+	--max-depth N : cap element nesting depth (default 256, 0 = unlimited)
+	--version : display the version of the XML library used
+`, c.prog)
+}
+
+func (c *patchCommand) parseArgs(args []string) (*patchConfig, []string) {
+	cfg := &patchConfig{maxInputBytes: DefaultMaxInputBytes, maxDepth: -1}
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case flagVersion:
+			cfg.version = true
+		case flagMaxInputBytes:
+			i++
+			if i >= len(args) {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-input-bytes requires an argument\n", c.prog)
+				return nil, nil
+			}
+			n, err := strconv.ParseInt(args[i], 10, 64) //nolint:gosec // bounds checked above
+			if err != nil || n < 0 {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-input-bytes: invalid argument %q\n", c.prog, args[i]) //nolint:gosec // bounds checked above
+				return nil, nil
+			}
+			cfg.maxInputBytes = n
+		case flagMaxDepth:
+			i++
+			if i >= len(args) {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-depth requires an argument\n", c.prog)
+				return nil, nil
+			}
+			n, err := strconv.Atoi(args[i]) //nolint:gosec // bounds checked above
+			if err != nil || n < 0 {
+				_, _ = fmt.Fprintf(c.stderr, "%s: --max-depth: invalid argument %q\n", c.prog, args[i]) //nolint:gosec // bounds checked above
+				return nil, nil
+			}
+			cfg.maxDepth = n
+		default:
+			if arg != "-" && strings.HasPrefix(arg, "-") {
+				_, _ = fmt.Fprintf(c.stderr, "%s: unrecognized option %s\n", c.prog, arg)
+				return nil, nil
+			}
+			positional = append(positional, arg)
+		}
+	}
+
+	if cfg.version {
+		return cfg, positional
+	}
+
+	if !checkInputPair(c.prog, c.stderr, c.stdinTTY, positional, "XMLfile and PATCH") {
+		return nil, nil
+	}
+	return cfg, positional
+}
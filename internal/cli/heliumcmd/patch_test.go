@@ -0,0 +1,87 @@
+package heliumcmd_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium/internal/cli/heliumcmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchVersion(t *testing.T) {
+	var stderr bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(""), io.Discard, &stderr)
+	ctx = heliumcmd.WithStdinTTY(ctx, true)
+
+	code := heliumcmd.Execute(ctx, []string{cmdPatch, flagVersion})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Contains(t, stderr.String(), "using helium")
+}
+
+func TestPatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	xmlFile := writeFile(t, dir, "doc.xml", `<root><a>one</a></root>`)
+	patchFile := writeFile(t, dir, "patch.xml", `<diff><replace sel="/root/a/text()">two</replace><add sel="/root"><b/></add></diff>`)
+
+	var stdout bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(""), &stdout, io.Discard)
+	ctx = heliumcmd.WithStdinTTY(ctx, true)
+
+	code := heliumcmd.Execute(ctx, []string{cmdPatch, xmlFile, patchFile})
+	require.Equal(t, heliumcmd.ExitOK, code)
+	require.Contains(t, stdout.String(), `<root><a>two</a><b/></root>`)
+}
+
+func TestPatchDiffRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := writeFile(t, dir, "old.xml", `<root><a x="1">one</a><!--c--><b/></root>`)
+	newFile := writeFile(t, dir, "new.xml", `<root><b y="2"/><a>one</a><?pi?></root>`)
+
+	var patch bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(""), &patch, io.Discard)
+	ctx = heliumcmd.WithStdinTTY(ctx, true)
+	require.Equal(t, heliumcmd.ExitOK, heliumcmd.Execute(ctx, []string{cmdDiff, oldFile, newFile}))
+
+	var stdout bytes.Buffer
+	ctx = heliumcmd.WithIO(t.Context(), &patch, &stdout, io.Discard)
+	require.Equal(t, heliumcmd.ExitOK, heliumcmd.Execute(ctx, []string{cmdPatch, oldFile, "-"}))
+	require.Contains(t, stdout.String(), `<root><b y="2"/><a>one</a><?pi?></root>`)
+}
+
+func TestPatchDoesNotApply(t *testing.T) {
+	dir := t.TempDir()
+	xmlFile := writeFile(t, dir, "doc.xml", `<root/>`)
+	patchFile := writeFile(t, dir, "patch.xml", `<diff><remove sel="/root/missing"/></diff>`)
+
+	var stderr bytes.Buffer
+	ctx := heliumcmd.WithIO(t.Context(), strings.NewReader(""), io.Discard, &stderr)
+	ctx = heliumcmd.WithStdinTTY(ctx, true)
+
+	code := heliumcmd.Execute(ctx, []string{cmdPatch, xmlFile, patchFile})
+	require.Equal(t, heliumcmd.ExitPatch, code)
+	require.Contains(t, stderr.String(), "matched 0 nodes")
+}
+
+func TestPatchArguments(t *testing.T) {
+	dir := t.TempDir()
+	xmlFile := writeFile(t, dir, "doc.xml", `<root/>`)
+	badFile := writeFile(t, dir, "bad.xml", `<diff>`)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "missing patch", args: []string{cmdPatch, xmlFile}, want: heliumcmd.ExitErr},
+		{name: "unknown option", args: []string{cmdPatch, "--id-attr", "x", xmlFile, xmlFile}, want: heliumcmd.ExitErr},
+		{name: "missing file", args: []string{cmdPatch, "/missing.xml", xmlFile}, want: heliumcmd.ExitReadFile},
+		{name: "malformed patch", args: []string{cmdPatch, xmlFile, badFile}, want: heliumcmd.ExitErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, executeDiscard(t, tt.args))
+		})
+	}
+}
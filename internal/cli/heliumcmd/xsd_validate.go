@@ -21,6 +21,17 @@ func (h *writerErrorHandler) Handle(_ context.Context, err error) {
 	_, _ = fmt.Fprint(h.w, err)
 }
 
+// lineErrorHandler writes each error to an io.Writer on a line of its own,
+// for diagnostics such as helium.DTDValidationError whose messages carry no
+// trailing newline.
+type lineErrorHandler struct {
+	w io.Writer
+}
+
+func (h *lineErrorHandler) Handle(_ context.Context, err error) {
+	_, _ = fmt.Fprintf(h.w, "%s\n", err)
+}
+
 // errSchemaCompilation is the CLI-side sentinel for a schema that produced
 // fatal compilation diagnostics. The xsd compiler may still return a non-nil
 // schema with a nil error in that case (the terminal failure contract lives in
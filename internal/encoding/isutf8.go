@@ -1,6 +1,11 @@
 package encoding
 
-import "golang.org/x/text/encoding/unicode"
+import (
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/unicode"
+)
 
 // UnicodeBOMFamily classifies a declared encoding name into the canonical
 // Unicode family relevant to byte-order-mark conflict detection, deriving the
@@ -58,3 +63,27 @@ func IsASCII(name string) bool {
 	_, ok := asciiEncodingNames[normalizeEncodingName(name)]
 	return ok
 }
+
+// IsASCIITransparent reports whether the named encoding writes every ASCII
+// character as that single byte and never uses a byte below 0x80 inside a
+// multibyte sequence, so markup delimiters can be found in the raw bytes
+// without decoding them. UTF-8, US-ASCII, the ISO-8859 and Windows code pages
+// and EUC-JP/EUC-KR qualify; Shift_JIS, Big5 and GBK (whose trail bytes
+// include '[' and ']'), the stateful ISO-2022-JP and HZ, EBCDIC and the
+// UTF-16/UCS-4 forms do not.
+func IsASCIITransparent(name string) bool {
+	switch e := Load(name).(type) {
+	case nil:
+		return false
+	case asciiEncoding, *c1Encoding:
+		return true
+	default:
+		switch e {
+		case unicode.UTF8, japanese.EUCJP, korean.EUCKR,
+			charmap.CodePage437, charmap.CodePage866, charmap.KOI8R, charmap.KOI8U,
+			charmap.Macintosh, charmap.MacintoshCyrillic, charmap.XUserDefined:
+			return true
+		}
+	}
+	return false
+}
@@ -52,3 +52,43 @@ func TestUnicodeBOMFamily(t *testing.T) {
 		})
 	}
 }
+
+// TestIsASCIITransparent verifies which encodings the push parser may split
+// at markup without decoding first.
+func TestIsASCIITransparent(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		want bool
+	}{
+		{"UTF-8", true},
+		{"US-ASCII", true},
+		{"ISO-8859-1", true},
+		{"ISO-8859-2", true},
+		{"ISO-8859-15", true},
+		{"windows-1252", true},
+		{"windows-1251", true},
+		{"KOI8-R", true},
+		{"EUC-JP", true},
+		{"EUC-KR", true},
+
+		{"Shift_JIS", false},
+		{"Big5", false},
+		{"GBK", false},
+		{"GB18030", false},
+		{"ISO-2022-JP", false},
+		{"HZ-GB-2312", false},
+		{"UTF-16", false},
+		{"UTF-16LE", false},
+		{"UCS-4", false},
+		{"IBM037", false},
+		{"no-such-encoding", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.want, xmlenc.IsASCIITransparent(tc.name))
+		})
+	}
+}
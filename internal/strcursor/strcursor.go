@@ -628,6 +628,9 @@ type ByteCursor struct {
 	buf     []byte
 	buflen  int
 	bufpos  int
+	base    int // input offset of buf[0]
+	keep    int // input offset from which consumed bytes stay buffered
+	retain  bool
 	column  int
 	in      io.Reader
 	line    []byte
@@ -645,6 +648,7 @@ func NewByteCursor(r io.Reader, nn ...int) *ByteCursor {
 		buf:    buf,
 		buflen: n,
 		bufpos: n, // force fill on first read
+		base:   -n,
 		column: 1,
 		in:     r,
 		line:   make([]byte, 0, 256),
@@ -657,17 +661,22 @@ func (c *ByteCursor) fillBuffer(n int) error {
 		return nil
 	}
 
-	// Compact remaining bytes to front.
-	remaining := c.buflen - c.bufpos
-	if c.bufpos > 0 && remaining > 0 {
-		copy(c.buf, c.buf[c.bufpos:c.buflen])
+	// Compact remaining (and retained) bytes to front.
+	drop := c.bufpos
+	if c.retain {
+		drop = min(drop, max(c.keep-c.base, 0))
 	}
-	c.bufpos = 0
+	remaining := c.buflen - drop
+	if drop > 0 && remaining > 0 {
+		copy(c.buf, c.buf[drop:c.buflen])
+	}
+	c.bufpos -= drop
 	c.buflen = remaining
+	c.base += drop
 
 	// Grow buffer if needed.
-	if n > len(c.buf) {
-		newBuf := make([]byte, n*2)
+	if c.bufpos+n > len(c.buf) {
+		newBuf := make([]byte, (c.bufpos+n)*2)
 		copy(newBuf, c.buf[:remaining])
 		c.buf = newBuf
 	}
@@ -684,7 +693,7 @@ func (c *ByteCursor) fillBuffer(n int) error {
 	// slow producer that splits a token (e.g. the XML declaration) across
 	// pushes from being mistaken for end-of-input, matching UTF8Cursor.
 	zeroProgress := 0
-	for c.buflen < n {
+	for c.buflen-c.bufpos < n {
 		nread, err := c.in.Read(c.buf[c.buflen:])
 		c.buflen += nread
 		// A single (0, nil) read is not fatal: io.Reader permits a reader to
@@ -714,7 +723,7 @@ func (c *ByteCursor) fillBuffer(n int) error {
 			if err != io.EOF && c.readErr == nil {
 				c.readErr = err
 			}
-			if c.buflen >= n {
+			if c.buflen-c.bufpos >= n {
 				return nil
 			}
 			if c.readErr != nil {
@@ -898,6 +907,28 @@ func (c *ByteCursor) LineNumber() int {
 	return c.lineno
 }
 
+// Offset returns the number of bytes consumed from the input.
+func (c *ByteCursor) Offset() int {
+	return c.base + c.bufpos
+}
+
+// Retain keeps the input from offset off on buffered once it is consumed,
+// so that Slice can return it. Retain(-1) releases it.
+func (c *ByteCursor) Retain(off int) {
+	c.retain = off >= 0
+	c.keep = off
+}
+
+// Slice returns the consumed input between offsets from and to, which must
+// have been retained. The result aliases the cursor's buffer and is valid
+// only until the cursor next reads.
+func (c *ByteCursor) Slice(from, to int) []byte {
+	if from < c.base || from > to || to > c.base+c.bufpos {
+		return nil
+	}
+	return c.buf[from-c.base : to-c.base]
+}
+
 func (c *ByteCursor) Column() int {
 	return c.column
 }
@@ -116,6 +116,9 @@ type UTF8Cursor struct {
 	buf     []byte
 	buflen  int
 	bufpos  int
+	base    int // input offset of buf[0]
+	keep    int // input offset from which consumed bytes stay buffered
+	retain  bool
 	column  int
 	in      io.Reader
 	lineno  int
@@ -141,18 +144,21 @@ func (c *UTF8Cursor) fillBuffer(minBytes int) error {
 		return nil
 	}
 
-	// Compact: move unconsumed bytes to front.
-	if c.bufpos > 0 {
-		if avail > 0 {
-			copy(c.buf, c.buf[c.bufpos:c.buflen])
-		}
-		c.buflen = avail
-		c.bufpos = 0
+	// Compact: move unconsumed (and retained) bytes to front.
+	drop := c.bufpos
+	if c.retain {
+		drop = min(drop, max(c.keep-c.base, 0))
+	}
+	if drop > 0 {
+		copy(c.buf, c.buf[drop:c.buflen])
+		c.buflen -= drop
+		c.bufpos -= drop
+		c.base += drop
 	}
 
 	// Grow buffer if needed.
-	if minBytes > len(c.buf) {
-		newBuf := make([]byte, minBytes*2)
+	if c.bufpos+minBytes > len(c.buf) {
+		newBuf := make([]byte, (c.bufpos+minBytes)*2)
 		copy(newBuf, c.buf[:c.buflen])
 		c.buf = newBuf
 	}
@@ -490,6 +496,28 @@ func (c *UTF8Cursor) LineNumber() int {
 	return c.lineno
 }
 
+// Offset returns the number of bytes consumed from the input.
+func (c *UTF8Cursor) Offset() int {
+	return c.base + c.bufpos
+}
+
+// Retain keeps the input from offset off on buffered once it is consumed,
+// so that Slice can return it. Retain(-1) releases it.
+func (c *UTF8Cursor) Retain(off int) {
+	c.retain = off >= 0
+	c.keep = off
+}
+
+// Slice returns the consumed input between offsets from and to, which must
+// have been retained. The result aliases the cursor's buffer and is valid
+// only until the cursor next reads.
+func (c *UTF8Cursor) Slice(from, to int) []byte {
+	if from < c.base || from > to || to > c.base+c.bufpos {
+		return nil
+	}
+	return c.buf[from-c.base : to-c.base]
+}
+
 func (c *UTF8Cursor) Column() int {
 	return c.column
 }
@@ -740,10 +768,22 @@ func (c *UTF8Cursor) ScanCharDataSlice(dst []byte, maxBytes int) ([]byte, int) {
 	data := c.buf[c.bufpos:c.buflen]
 	dlen := len(data)
 
-	for off < dlen {
+	for {
 		if maxBytes > 0 && off >= maxBytes {
 			break
 		}
+		if off >= dlen {
+			// A line break or a multi-byte character can end exactly at the
+			// end of the buffer; the run goes on in the next read.
+			if c.fillBuffer(off+1) != nil {
+				break
+			}
+			data = c.buf[c.bufpos:c.buflen]
+			dlen = len(data)
+			if off >= dlen {
+				break
+			}
+		}
 		runLen := scanSafeCharDataASCII(data[off:dlen])
 		if maxBytes > 0 && off+runLen > maxBytes {
 			// Cap the ASCII run at the byte budget. ASCII bytes are single-byte,
@@ -91,6 +91,17 @@ func TestUTF8CursorScanCharDataSliceConsumesCRLFAcrossBufferEdge(t *testing.T) {
 	require.Equal(t, "\n", string(data))
 }
 
+func TestUTF8CursorScanCharDataSliceContinuesPastCRLFAtBufferEdge(t *testing.T) {
+	cur := strcursor.NewUTF8Cursor(&chunkedReader{
+		data:  []byte("\r\n\t\t<"),
+		chunk: 2,
+	})
+
+	data, n := cur.ScanCharDataSlice(nil, 0)
+	require.Equal(t, 4, n)
+	require.Equal(t, "\n\t\t", string(data))
+}
+
 func TestUTF8CursorScanCharDataSlicePreservesWhitespaceRunAcrossBufferEdge(t *testing.T) {
 	cur := strcursor.NewUTF8Cursor(&chunkedReader{
 		data:  []byte(strings.Repeat(" ", 7) + "<"),
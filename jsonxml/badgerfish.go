@@ -0,0 +1,176 @@
+package jsonxml
+
+import (
+	"fmt"
+
+	helium "github.com/lestrrat-go/helium"
+)
+
+// badgerFishDefault is the key of the default namespace under "@xmlns".
+const badgerFishDefault = "$"
+
+func (w *jsonBuilder) badgerFishDocument(root *helium.Element) (any, error) {
+	v, err := w.badgerFish(root)
+	if err != nil {
+		return nil, err
+	}
+	o := &object{}
+	o.add(w.elementKey(root, ":"), v)
+	return o, nil
+}
+
+func (w *jsonBuilder) badgerFish(e *helium.Element) (any, error) {
+	if err := w.ctx.Err(); err != nil {
+		return nil, err
+	}
+	o := &object{}
+	if !w.s.strip {
+		if nss := inScopeNamespaces(e); len(nss) > 0 {
+			decls := &object{}
+			for _, ns := range nss {
+				key := ns.Prefix()
+				if key == "" {
+					key = badgerFishDefault
+				}
+				decls.add(key, ns.URI())
+			}
+			o.add(w.s.attrPrefix+"xmlns", decls)
+		}
+	}
+	for _, a := range e.Attributes() {
+		key := w.attributeKey(a, ":")
+		if o.has(key) {
+			return nil, fmt.Errorf("%w: attribute key %q is already in use", ErrNotRepresentable, key)
+		}
+		o.add(key, scalar(a.Value(), w.s.infer))
+	}
+	elems := childElements(e)
+	text, err := elementText(e, len(elems) > 0)
+	if err != nil {
+		return nil, err
+	}
+	if text != "" {
+		o.add(w.s.textKey, scalar(text, w.s.infer))
+	}
+	if err := w.addChildren(o, elems, ":", w.badgerFish); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// inScopeNamespaces returns the namespaces in scope on e, outermost
+// declaration first, without the xml namespace.
+func inScopeNamespaces(e *helium.Element) []*helium.Namespace {
+	var chain []*helium.Element
+	for n := helium.Node(e); n != nil; n = n.Parent() {
+		if pe, ok := helium.AsNode[*helium.Element](n); ok {
+			chain = append(chain, pe)
+		}
+	}
+	var order []string
+	byPrefix := map[string]*helium.Namespace{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, ns := range chain[i].Namespaces() {
+			if _, ok := byPrefix[ns.Prefix()]; !ok {
+				order = append(order, ns.Prefix())
+			}
+			byPrefix[ns.Prefix()] = ns
+		}
+	}
+	var nss []*helium.Namespace
+	for _, prefix := range order {
+		if ns := byPrefix[prefix]; ns.URI() != "" {
+			nss = append(nss, ns)
+		}
+	}
+	return nss
+}
+
+func (b *xmlBuilder) badgerFishDocument(v any) error {
+	o, ok := v.(*object)
+	if !ok || len(o.members) != 1 {
+		return fmt.Errorf("%w: a BadgerFish document is an object with one member", ErrUnexpectedJSON)
+	}
+	root, err := b.badgerFish(o.members[0].key, o.members[0].value, nil)
+	if err != nil {
+		return err
+	}
+	return b.setRoot(root)
+}
+
+func (b *xmlBuilder) badgerFish(name string, v any, sc scope) (*helium.Element, error) {
+	o, ok := v.(*object)
+	if !ok {
+		return b.textElement(name, ":", v, sc)
+	}
+
+	xmlnsKey := b.s.attrPrefix + "xmlns"
+	var decls []nsDecl
+	for _, m := range o.members {
+		if m.key != xmlnsKey {
+			continue
+		}
+		nss, ok := m.value.(*object)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not an object", ErrUnexpectedJSON, xmlnsKey)
+		}
+		for _, ns := range nss.members {
+			uri, ok := ns.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: namespace %q is not a string", ErrUnexpectedJSON, ns.key)
+			}
+			prefix := ns.key
+			if prefix == badgerFishDefault {
+				prefix = ""
+			}
+			decls = append(decls, nsDecl{prefix: prefix, uri: uri})
+		}
+	}
+
+	e, sc, err := b.element(name, ":", decls, sc)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range o.members {
+		if m.key == xmlnsKey {
+			continue
+		}
+		if m.key == b.s.textKey {
+			text, ok := scalarText(m.value)
+			if !ok {
+				return nil, fmt.Errorf("%w: text of %q is not a scalar", ErrUnexpectedJSON, name)
+			}
+			if err := b.addText(e, text); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if attr, ok := b.attributeName(m.key, m.value); ok {
+			text, _ := scalarText(m.value)
+			if err := b.setAttribute(e, attr, ":", text, sc); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := b.addChildren(e, m.key, m.value, sc, b.badgerFish); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// textElement creates an element named name whose content is the scalar v.
+func (b *xmlBuilder) textElement(name, sep string, v any, sc scope) (*helium.Element, error) {
+	text, ok := scalarText(v)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected value of %q", ErrUnexpectedJSON, name)
+	}
+	e, _, err := b.element(name, sep, nil, sc)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.addText(e, text); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,337 @@
+package jsonxml
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xsd"
+)
+
+// Convention selects how XML is laid out as JSON.
+type Convention int
+
+const (
+	// ConventionBadgerFish keeps everything: text goes under "$",
+	// attributes under "@" followed by their name, and the namespaces in
+	// scope under "@xmlns". All values are strings.
+	ConventionBadgerFish Convention = iota
+	// ConventionParker keeps only element structure: the document element
+	// and attributes are dropped, text becomes the element's value, and
+	// empty elements become null.
+	ConventionParker
+	// ConventionGData follows the Google Data JSON format: text goes under
+	// "$t", attributes take plain keys, and a prefix is joined to a local
+	// name with "$".
+	ConventionGData
+	// ConventionJsonML writes each element as an array of its name, an
+	// optional object of attributes, and its children.
+	ConventionJsonML
+	// ConventionXPath uses the XML vocabulary of the XPath 3.1 functions
+	// fn:json-to-xml and fn:xml-to-json. Names are not taken from the
+	// document: the XML side is always map, array, string, number, boolean
+	// and null elements in the http://www.w3.org/2005/xpath-functions
+	// namespace.
+	ConventionXPath
+)
+
+// String returns the name the helium convert command uses for c.
+func (c Convention) String() string {
+	switch c {
+	case ConventionBadgerFish:
+		return "badgerfish"
+	case ConventionParker:
+		return "parker"
+	case ConventionGData:
+		return "gdata"
+	case ConventionJsonML:
+		return "jsonml"
+	case ConventionXPath:
+		return "xpath"
+	default:
+		return fmt.Sprintf("Convention(%d)", int(c))
+	}
+}
+
+// NamespaceMode selects what a conversion does with namespaces.
+type NamespaceMode int
+
+const (
+	// NamespaceModePreserve writes names with their prefixes and carries
+	// namespace declarations over in the convention's own form.
+	NamespaceModePreserve NamespaceMode = iota
+	// NamespaceModeStrip drops prefixes and namespace declarations, so
+	// names are local names and the result is in no namespace.
+	NamespaceModeStrip
+)
+
+type converterConfig struct {
+	convention    Convention
+	attrPrefix    string
+	hasAttrPrefix bool
+	textKey       string
+	hasTextKey    bool
+	arrays        []string
+	schemas       []*xsd.Schema
+	namespaces    NamespaceMode
+	inferTypes    bool
+	hasInferTypes bool
+	rootName      string
+	indent        string
+}
+
+// Converter converts between XML documents and JSON text under a
+// [Convention]. It uses clone-on-write semantics: each builder method
+// returns a new Converter sharing the underlying config until mutation.
+type Converter struct {
+	cfg *converterConfig
+}
+
+// NewConverter creates a Converter for the BadgerFish convention that keeps
+// namespaces and writes compact JSON.
+func NewConverter() Converter {
+	return Converter{cfg: &converterConfig{}}
+}
+
+func (c Converter) clone() Converter {
+	if c.cfg == nil {
+		return NewConverter()
+	}
+	cp := *c.cfg
+	cp.arrays = slices.Clone(cp.arrays)
+	cp.schemas = slices.Clone(cp.schemas)
+	return Converter{cfg: &cp}
+}
+
+// Convention sets the convention of both directions of conversion.
+func (c Converter) Convention(conv Convention) Converter {
+	c = c.clone()
+	c.cfg.convention = conv
+	return c
+}
+
+// AttributePrefix sets the string put before an attribute name to make its
+// key. The default is "@" for BadgerFish and none for GData; the other
+// conventions do not use it. Reading GData with a prefix set, a member with
+// a scalar value is an attribute only when its key carries the prefix.
+func (c Converter) AttributePrefix(prefix string) Converter {
+	c = c.clone()
+	c.cfg.attrPrefix = prefix
+	c.cfg.hasAttrPrefix = true
+	return c
+}
+
+// TextKey sets the key of an element's text. The default is "$" for
+// BadgerFish and "$t" for GData; the other conventions do not use it.
+func (c Converter) TextKey(key string) Converter {
+	c = c.clone()
+	c.cfg.textKey = key
+	c.cfg.hasTextKey = true
+	return c
+}
+
+// ArrayElements names elements that are always written as a JSON array,
+// even when only one of them occurs, so the shape of the JSON does not
+// depend on how many there are. A name is either a local name, matching in
+// any namespace, or {uri}local. Calls add to the names already given.
+// JsonML and XPath do not use it: JsonML always writes children as a list,
+// and the XPath vocabulary says itself what is an array.
+func (c Converter) ArrayElements(names ...string) Converter {
+	c = c.clone()
+	c.cfg.arrays = append(c.cfg.arrays, names...)
+	return c
+}
+
+// ArraysFromSchema makes the elements that schema allows to repeat behave
+// as if given to [Converter.ArrayElements]: every element that can occur
+// more than once in some content model of the schema, whether through its
+// own maxOccurs or that of an enclosing group, is written as an array.
+// Calls add to the schemas already given.
+func (c Converter) ArraysFromSchema(schema *xsd.Schema) Converter {
+	c = c.clone()
+	if schema != nil {
+		c.cfg.schemas = append(c.cfg.schemas, schema)
+	}
+	return c
+}
+
+// Namespaces sets what happens to namespaces. The XPath convention ignores
+// it, as its vocabulary is always in the same namespace.
+func (c Converter) Namespaces(mode NamespaceMode) Converter {
+	c = c.clone()
+	c.cfg.namespaces = mode
+	return c
+}
+
+// InferTypes sets whether [Converter.ToJSON] writes text and attribute
+// values that read as JSON numbers or booleans as such rather than as
+// strings. It is on by default for Parker and off for BadgerFish and GData;
+// JsonML and XPath do not use it.
+func (c Converter) InferTypes(v bool) Converter {
+	c = c.clone()
+	c.cfg.inferTypes = v
+	c.cfg.hasInferTypes = true
+	return c
+}
+
+// RootName sets the name of the document element [Converter.ToXML] creates
+// for Parker, which does not record it. The default is "root".
+func (c Converter) RootName(name string) Converter {
+	c = c.clone()
+	c.cfg.rootName = name
+	return c
+}
+
+// Indent sets the string [Converter.ToJSON] indents each nesting level
+// with. The default, an empty string, writes compact JSON.
+func (c Converter) Indent(indent string) Converter {
+	c = c.clone()
+	c.cfg.indent = indent
+	return c
+}
+
+// settings is the configuration of one conversion, with the defaults of
+// the convention filled in.
+type settings struct {
+	convention Convention
+	attrPrefix string
+	textKey    string
+	arrays     map[string]struct{}
+	strip      bool
+	infer      bool
+	rootName   string
+}
+
+func (c Converter) settings() *settings {
+	cfg := c.cfg
+	if cfg == nil {
+		cfg = &converterConfig{}
+	}
+	s := &settings{
+		convention: cfg.convention,
+		strip:      cfg.namespaces == NamespaceModeStrip,
+		rootName:   cfg.rootName,
+		arrays:     map[string]struct{}{},
+	}
+	switch cfg.convention {
+	case ConventionBadgerFish:
+		s.attrPrefix = "@"
+		s.textKey = "$"
+	case ConventionGData:
+		s.textKey = "$t"
+	case ConventionParker:
+		s.infer = true
+	}
+	if cfg.hasAttrPrefix {
+		s.attrPrefix = cfg.attrPrefix
+	}
+	if cfg.hasTextKey {
+		s.textKey = cfg.textKey
+	}
+	if cfg.hasInferTypes {
+		s.infer = cfg.inferTypes
+	}
+	if s.rootName == "" {
+		s.rootName = "root"
+	}
+	for _, name := range cfg.arrays {
+		s.arrays[name] = struct{}{}
+	}
+	for _, schema := range cfg.schemas {
+		schemaArrays(schema, s.arrays)
+	}
+	return s
+}
+
+// isArray reports whether e is always written as an array.
+func (s *settings) isArray(e *helium.Element) bool {
+	if len(s.arrays) == 0 {
+		return false
+	}
+	if _, ok := s.arrays[e.LocalName()]; ok {
+		return true
+	}
+	_, ok := s.arrays["{"+e.URI()+"}"+e.LocalName()]
+	return ok
+}
+
+// ToJSON returns node, an element or a document, as JSON text.
+func (c Converter) ToJSON(ctx context.Context, node helium.Node) ([]byte, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var doc *helium.Document
+	var root *helium.Element
+	switch n := node.(type) {
+	case *helium.Document:
+		doc = n
+		root = n.DocumentElement()
+	case *helium.Element:
+		root = n
+	}
+	if root == nil {
+		return nil, ErrNoElement
+	}
+
+	w := &jsonBuilder{ctx: ctx, s: c.settings()}
+	var v any
+	var err error
+	switch w.s.convention {
+	case ConventionParker:
+		v, err = w.parker(root)
+	case ConventionGData:
+		v, err = w.gdataDocument(doc, root)
+	case ConventionJsonML:
+		v, err = w.jsonml(root)
+	case ConventionXPath:
+		v, err = w.xpath(root)
+	default:
+		v, err = w.badgerFishDocument(root)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeJSON(&buf, v)
+	if c.cfg == nil || c.cfg.indent == "" {
+		return buf.Bytes(), nil
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", c.cfg.indent); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// ToXML returns the document data, JSON text, describes.
+func (c Converter) ToXML(ctx context.Context, data []byte) (*helium.Document, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	v, err := parseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &xmlBuilder{ctx: ctx, s: c.settings(), doc: helium.NewDefaultDocument()}
+	switch b.s.convention {
+	case ConventionParker:
+		err = b.parker(v)
+	case ConventionGData:
+		err = b.gdataDocument(v)
+	case ConventionJsonML:
+		err = b.jsonmlDocument(v)
+	case ConventionXPath:
+		err = b.xpathDocument(v)
+	default:
+		err = b.badgerFishDocument(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.doc, nil
+}
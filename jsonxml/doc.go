@@ -0,0 +1,65 @@
+// Package jsonxml converts between XML documents and JSON under the common
+// conventions for laying one out as the other.
+//
+// Build a [Converter] with [NewConverter], choose a [Convention], and call
+// [Converter.ToJSON] with a document or element, or [Converter.ToXML] with
+// JSON text:
+//
+//	conv := jsonxml.NewConverter().Convention(jsonxml.ConventionParker)
+//	data, err := conv.ToJSON(ctx, doc)
+//	...
+//	doc, err = conv.ToXML(ctx, data)
+//
+// # Conventions
+//
+// BadgerFish ([ConventionBadgerFish]) keeps all of a document's
+// information: each element is an object, with its text under "$", its
+// attributes under "@name", and the namespaces in scope under "@xmlns" ("$"
+// standing for the default namespace).
+//
+//	<a xmlns="urn:a" id="1">hi</a>  {"a":{"@xmlns":{"$":"urn:a"},"@id":"1","$":"hi"}}
+//
+// Parker ([ConventionParker]) keeps only the shape of the data: the
+// document element and attributes are dropped, an element with only text
+// is that text, an empty element is null, and numbers and booleans are
+// typed. Reading Parker back, the document element is named by
+// [Converter.RootName].
+//
+//	<r><n>1</n><ok>true</ok></r>  {"n":1,"ok":true}
+//
+// GData ([ConventionGData]) is the format of the Google Data APIs: text is
+// under "$t", attributes take plain keys, a prefix is joined to its local
+// name with "$", namespaces are declared by "xmlns" and "xmlns$prefix"
+// members, and a document records its version and encoding.
+//
+// JsonML ([ConventionJsonML]) writes each element as an array of its name,
+// an optional object of attributes, and its content, so mixed content keeps
+// its order.
+//
+//	<p>Hello <b>world</b></p>  ["p","Hello ",["b","world"]]
+//
+// XPath ([ConventionXPath]) is the XML vocabulary of the XPath 3.1
+// functions fn:json-to-xml and fn:xml-to-json. It represents any JSON, but
+// only XML in that vocabulary can be written as JSON.
+//
+// In the conventions that group children by name, repeated elements become
+// an array. An element that occurs once is written as a plain member unless
+// [Converter.ArrayElements] names it or [Converter.ArraysFromSchema] finds
+// it repeatable in an XML Schema, so that JSON consumers see the same shape
+// whatever the number of elements. Comments and processing instructions are
+// not converted, and whitespace between child elements is dropped, except in
+// JsonML.
+//
+// Reading JSON, the members of an object become attributes, text and child
+// elements in their order, and a value that does not fit the convention
+// fails with [ErrUnexpectedJSON]. XML that a convention cannot express,
+// such as an attribute and a child element with the same key, or mixed
+// content in BadgerFish and GData, which keep text apart from the child
+// elements, fails with [ErrNotRepresentable].
+//
+// # Examples
+//
+// Example code for this package lives in the examples/ directory at the
+// repository root (files prefixed with jsonxml_). Because examples are in a
+// separate test module they do not appear in the generated documentation.
+package jsonxml
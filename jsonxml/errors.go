@@ -0,0 +1,28 @@
+package jsonxml
+
+import "errors"
+
+// ErrInvalidJSON is returned by [Converter.ToXML] when its input is not a
+// single well-formed JSON value.
+var ErrInvalidJSON = errors.New("jsonxml: invalid JSON")
+
+// ErrUnexpectedJSON is returned by [Converter.ToXML] when the JSON is
+// well-formed but does not have the shape the convention describes, such as
+// an object with several top-level members for BadgerFish or an array whose
+// first item is not an element name for JsonML.
+var ErrUnexpectedJSON = errors.New("jsonxml: JSON does not follow the convention")
+
+// ErrNotRepresentable is returned by [Converter.ToJSON] when the XML cannot
+// be written in the convention: an attribute and a child element mapping to
+// the same key, text mixed with child elements in BadgerFish or GData, or,
+// for the XPath convention, a document outside the vocabulary of
+// fn:xml-to-json.
+var ErrNotRepresentable = errors.New("jsonxml: XML cannot be represented in the convention")
+
+// ErrUnboundPrefix is returned by [Converter.ToXML] when a name uses a
+// prefix that no namespace declaration in scope binds.
+var ErrUnboundPrefix = errors.New("jsonxml: prefix is not bound to a namespace")
+
+// ErrNoElement is returned by [Converter.ToJSON] when the node is neither an
+// element nor a document with a document element.
+var ErrNoElement = errors.New("jsonxml: no element to convert")
@@ -0,0 +1,158 @@
+package jsonxml
+
+import (
+	"fmt"
+	"strings"
+
+	helium "github.com/lestrrat-go/helium"
+)
+
+// gdataSep joins a prefix to a local name in GData keys.
+const gdataSep = "$"
+
+func (w *jsonBuilder) gdataDocument(doc *helium.Document, root *helium.Element) (any, error) {
+	v, err := w.gdata(root)
+	if err != nil {
+		return nil, err
+	}
+	o := &object{}
+	if doc != nil {
+		version := doc.Version()
+		if version == "" {
+			version = "1.0"
+		}
+		encoding := doc.RawEncoding()
+		if encoding == "" {
+			encoding = "UTF-8"
+		}
+		o.add("version", version)
+		o.add("encoding", encoding)
+	}
+	o.add(w.elementKey(root, gdataSep), v)
+	return o, nil
+}
+
+func (w *jsonBuilder) gdata(e *helium.Element) (any, error) {
+	if err := w.ctx.Err(); err != nil {
+		return nil, err
+	}
+	o := &object{}
+	if !w.s.strip {
+		for _, ns := range e.Namespaces() {
+			key := "xmlns"
+			if p := ns.Prefix(); p != "" {
+				key += gdataSep + p
+			}
+			o.add(key, ns.URI())
+		}
+	}
+	for _, a := range e.Attributes() {
+		key := w.attributeKey(a, gdataSep)
+		if o.has(key) {
+			return nil, fmt.Errorf("%w: attribute key %q is already in use", ErrNotRepresentable, key)
+		}
+		o.add(key, scalar(a.Value(), w.s.infer))
+	}
+	elems := childElements(e)
+	text, err := elementText(e, len(elems) > 0)
+	if err != nil {
+		return nil, err
+	}
+	if text != "" {
+		if o.has(w.s.textKey) {
+			return nil, fmt.Errorf("%w: text key %q is already in use", ErrNotRepresentable, w.s.textKey)
+		}
+		o.add(w.s.textKey, scalar(text, w.s.infer))
+	}
+	if err := w.addChildren(o, elems, gdataSep, w.gdata); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (b *xmlBuilder) gdataDocument(v any) error {
+	o, ok := v.(*object)
+	if !ok {
+		return fmt.Errorf("%w: a GData document is an object", ErrUnexpectedJSON)
+	}
+	// The document element is the one member whose value is an object;
+	// the others, such as version and encoding, describe the document.
+	var root *member
+	for i, m := range o.members {
+		if _, ok := m.value.(*object); !ok {
+			continue
+		}
+		if root != nil {
+			return fmt.Errorf("%w: a GData document has one element", ErrUnexpectedJSON)
+		}
+		root = &o.members[i]
+	}
+	if root == nil {
+		return fmt.Errorf("%w: a GData document has one element", ErrUnexpectedJSON)
+	}
+	e, err := b.gdata(root.key, root.value, nil)
+	if err != nil {
+		return err
+	}
+	return b.setRoot(e)
+}
+
+func (b *xmlBuilder) gdata(name string, v any, sc scope) (*helium.Element, error) {
+	o, ok := v.(*object)
+	if !ok {
+		return b.textElement(name, gdataSep, v, sc)
+	}
+
+	var decls []nsDecl
+	for _, m := range o.members {
+		prefix, ok := gdataXMLNS(m.key)
+		if !ok {
+			continue
+		}
+		uri, ok := m.value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: namespace %q is not a string", ErrUnexpectedJSON, m.key)
+		}
+		decls = append(decls, nsDecl{prefix: prefix, uri: uri})
+	}
+
+	e, sc, err := b.element(name, gdataSep, decls, sc)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range o.members {
+		if _, ok := gdataXMLNS(m.key); ok {
+			continue
+		}
+		if m.key == b.s.textKey {
+			text, ok := scalarText(m.value)
+			if !ok {
+				return nil, fmt.Errorf("%w: text of %q is not a scalar", ErrUnexpectedJSON, name)
+			}
+			if err := b.addText(e, text); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if attr, ok := b.attributeName(m.key, m.value); ok {
+			text, _ := scalarText(m.value)
+			if err := b.setAttribute(e, attr, gdataSep, text, sc); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := b.addChildren(e, m.key, m.value, sc, b.gdata); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// gdataXMLNS returns the prefix a namespace declaration key declares: none
+// for "xmlns" and p for "xmlns$p".
+func gdataXMLNS(key string) (string, bool) {
+	if key == "xmlns" {
+		return "", true
+	}
+	return strings.CutPrefix(key, "xmlns"+gdataSep)
+}
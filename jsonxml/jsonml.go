@@ -0,0 +1,151 @@
+package jsonxml
+
+import (
+	"fmt"
+	"strings"
+
+	helium "github.com/lestrrat-go/helium"
+)
+
+func (w *jsonBuilder) jsonml(e *helium.Element) (any, error) {
+	if err := w.ctx.Err(); err != nil {
+		return nil, err
+	}
+	a := []any{w.elementKey(e, ":")}
+
+	attrs := &object{}
+	if !w.s.strip {
+		for _, ns := range e.Namespaces() {
+			key := "xmlns"
+			if p := ns.Prefix(); p != "" {
+				key += ":" + p
+			}
+			attrs.add(key, ns.URI())
+		}
+	}
+	for _, attr := range e.Attributes() {
+		key := w.qname(attr.Prefix(), attr.LocalName(), ":")
+		if attrs.has(key) {
+			return nil, fmt.Errorf("%w: attribute key %q is already in use", ErrNotRepresentable, key)
+		}
+		attrs.add(key, attr.Value())
+	}
+	if len(attrs.members) > 0 {
+		a = append(a, attrs)
+	}
+
+	// Adjacent text, CDATA sections and entity references make one string;
+	// comments and processing instructions are dropped.
+	var text strings.Builder
+	for child := range helium.Children(e) {
+		switch child.Type() {
+		case helium.TextNode, helium.CDATASectionNode, helium.EntityRefNode:
+			text.Write(child.Content())
+		case helium.ElementNode:
+			ce, _ := helium.AsNode[*helium.Element](child)
+			v, err := w.jsonml(ce)
+			if err != nil {
+				return nil, err
+			}
+			if text.Len() > 0 {
+				a = append(a, text.String())
+				text.Reset()
+			}
+			a = append(a, v)
+		}
+	}
+	if text.Len() > 0 {
+		a = append(a, text.String())
+	}
+	return a, nil
+}
+
+func (b *xmlBuilder) jsonmlDocument(v any) error {
+	root, err := b.jsonml(v, nil)
+	if err != nil {
+		return err
+	}
+	return b.setRoot(root)
+}
+
+func (b *xmlBuilder) jsonml(v any, sc scope) (*helium.Element, error) {
+	a, ok := v.([]any)
+	if !ok || len(a) == 0 {
+		return nil, fmt.Errorf("%w: a JsonML element is a non-empty array", ErrUnexpectedJSON)
+	}
+	name, ok := a[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: a JsonML element starts with its name", ErrUnexpectedJSON)
+	}
+	content := a[1:]
+	var attrs *object
+	if len(content) > 0 {
+		if o, ok := content[0].(*object); ok {
+			attrs = o
+			content = content[1:]
+		}
+	}
+
+	var decls []nsDecl
+	if attrs != nil {
+		for _, m := range attrs.members {
+			prefix, ok := jsonmlXMLNS(m.key)
+			if !ok {
+				continue
+			}
+			uri, ok := m.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: namespace %q is not a string", ErrUnexpectedJSON, m.key)
+			}
+			decls = append(decls, nsDecl{prefix: prefix, uri: uri})
+		}
+	}
+
+	e, sc, err := b.element(name, ":", decls, sc)
+	if err != nil {
+		return nil, err
+	}
+	if attrs != nil {
+		for _, m := range attrs.members {
+			if _, ok := jsonmlXMLNS(m.key); ok {
+				continue
+			}
+			text, ok := scalarText(m.value)
+			if !ok {
+				return nil, fmt.Errorf("%w: attribute %q is not a scalar", ErrUnexpectedJSON, m.key)
+			}
+			if err := b.setAttribute(e, m.key, ":", text, sc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, item := range content {
+		if _, ok := item.([]any); ok {
+			child, err := b.jsonml(item, sc)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.AddChild(child); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		text, ok := scalarText(item)
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected object in the content of %q", ErrUnexpectedJSON, name)
+		}
+		if err := b.addText(e, text); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// jsonmlXMLNS returns the prefix a namespace declaration attribute
+// declares: none for "xmlns" and p for "xmlns:p".
+func jsonmlXMLNS(key string) (string, bool) {
+	if key == "xmlns" {
+		return "", true
+	}
+	return strings.CutPrefix(key, "xmlns:")
+}
@@ -0,0 +1,291 @@
+package jsonxml_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/jsonxml"
+	"github.com/lestrrat-go/helium/xsd"
+	"github.com/stretchr/testify/require"
+)
+
+const feedXML = `<feed xmlns="urn:feed" xmlns:g="urn:g" xml:lang="en"><title type="text">Tom &amp; Jerry</title><entry g:id="1"><n>1</n></entry><entry g:id="2"><n>2.5</n><done>true</done><empty/></entry></feed>`
+
+func parse(t *testing.T, src string) *helium.Document {
+	t.Helper()
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+	return doc
+}
+
+// toXML converts src and serializes the result without an XML declaration.
+func toXML(t *testing.T, conv jsonxml.Converter, src string) string {
+	t.Helper()
+	doc, err := conv.ToXML(t.Context(), []byte(src))
+	require.NoError(t, err)
+	var sb strings.Builder
+	require.NoError(t, helium.NewWriter().XMLDeclaration(false).WriteTo(&sb, doc))
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func TestToJSON(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		conv jsonxml.Converter
+		src  string
+		want string
+	}{
+		{
+			name: "BadgerFish",
+			conv: jsonxml.NewConverter(),
+			src:  "<a x=\"1\"><b>text</b><b/><c>\n  <d/>\n</c></a>",
+			want: `{"a":{"@x":"1","b":[{"$":"text"},{}],"c":{"d":{}}}}`,
+		},
+		{
+			name: "BadgerFish namespaces",
+			conv: jsonxml.NewConverter(),
+			src:  `<a xmlns="urn:a"><p:b xmlns:p="urn:p" p:x="y"/></a>`,
+			want: `{"a":{"@xmlns":{"$":"urn:a"},"p:b":{"@xmlns":{"$":"urn:a","p":"urn:p"},"@p:x":"y"}}}`,
+		},
+		{
+			name: "Parker",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionParker),
+			src:  feedXML,
+			want: `{"title":"Tom & Jerry","entry":[{"n":1},{"n":2.5,"done":true,"empty":null}]}`,
+		},
+		{
+			name: "Parker without type inference",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionParker).InferTypes(false),
+			src:  `<r><n>007</n><m>1e3</m></r>`,
+			want: `{"n":"007","m":"1e3"}`,
+		},
+		{
+			name: "GData",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionGData),
+			src:  feedXML,
+			want: `{"version":"1.0","encoding":"UTF-8","feed":{"xmlns":"urn:feed","xmlns$g":"urn:g","xml$lang":"en","title":{"type":"text","$t":"Tom & Jerry"},"entry":[{"g$id":"1","n":{"$t":"1"}},{"g$id":"2","n":{"$t":"2.5"},"done":{"$t":"true"},"empty":{}}]}}`,
+		},
+		{
+			name: "JsonML",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionJsonML),
+			src:  `<p class="x">Hello <b>big</b><!-- c --> <![CDATA[world]]></p>`,
+			want: `["p",{"class":"x"},"Hello ",["b","big"]," world"]`,
+		},
+		{
+			name: "XPath",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionXPath),
+			src: `<map xmlns="http://www.w3.org/2005/xpath-functions">
+  <array key="a"><number>1</number><number>.5</number><boolean>1</boolean><null/></array>
+  <string key="s" escaped="true">line\nbreak 😀</string>
+  <map key="k\/e" escaped-key="true"/>
+</map>`,
+			want: `{"a":[1,0.5,true,null],"s":"line\nbreak 😀","k/e":{}}`,
+		},
+		{
+			name: "strip namespaces",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionGData).Namespaces(jsonxml.NamespaceModeStrip),
+			src:  `<p:a xmlns:p="urn:p" p:x="1"><p:b>t</p:b></p:a>`,
+			want: `{"version":"1.0","encoding":"UTF-8","a":{"x":"1","b":{"$t":"t"}}}`,
+		},
+		{
+			name: "array elements",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionParker).ArrayElements("item", "{urn:x}y"),
+			src:  `<r xmlns:x="urn:x"><item>1</item><x:y/><y/></r>`,
+			want: `{"item":[1],"x:y":[null],"y":null}`,
+		},
+		{
+			name: "attribute prefix and text key",
+			conv: jsonxml.NewConverter().AttributePrefix("-").TextKey("#text").InferTypes(true),
+			src:  `<a n="1">true</a>`,
+			want: `{"a":{"-n":1,"#text":true}}`,
+		},
+		{
+			name: "escaping",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionParker),
+			src:  "<r><s>\"q\" &lt;tag&gt;\t\\ &#x2028;</s></r>",
+			want: `{"s":"\"q\" <tag>\t\\ \u2028"}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			out, err := tc.conv.ToJSON(t.Context(), parse(t, tc.src))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(out))
+		})
+	}
+
+	t.Run("element", func(t *testing.T) {
+		t.Parallel()
+		doc := parse(t, `<r><a>1</a></r>`)
+		out, err := jsonxml.NewConverter().Convention(jsonxml.ConventionGData).ToJSON(t.Context(), doc.DocumentElement().FirstChild())
+		require.NoError(t, err)
+		require.Equal(t, `{"a":{"$t":"1"}}`, string(out))
+	})
+
+	t.Run("indent", func(t *testing.T) {
+		t.Parallel()
+		out, err := jsonxml.NewConverter().Convention(jsonxml.ConventionParker).Indent("  ").ToJSON(t.Context(), parse(t, `<r><a>1</a></r>`))
+		require.NoError(t, err)
+		require.Equal(t, "{\n  \"a\": 1\n}", string(out))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+		_, err := jsonxml.NewConverter().Convention(jsonxml.ConventionGData).ToJSON(t.Context(), parse(t, `<a b="1"><b/></a>`))
+		require.ErrorIs(t, err, jsonxml.ErrNotRepresentable)
+
+		for _, c := range []jsonxml.Convention{jsonxml.ConventionBadgerFish, jsonxml.ConventionGData} {
+			_, err = jsonxml.NewConverter().Convention(c).ToJSON(t.Context(), parse(t, `<p>Hello <b>world</b> again</p>`))
+			require.ErrorIs(t, err, jsonxml.ErrNotRepresentable)
+		}
+
+		_, err = jsonxml.NewConverter().Convention(jsonxml.ConventionXPath).ToJSON(t.Context(), parse(t, `<map/>`))
+		require.ErrorIs(t, err, jsonxml.ErrNotRepresentable)
+
+		_, err = jsonxml.NewConverter().ToJSON(t.Context(), helium.NewDefaultDocument())
+		require.ErrorIs(t, err, jsonxml.ErrNoElement)
+	})
+}
+
+func TestToXML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		conv jsonxml.Converter
+		src  string
+		want string
+	}{
+		{
+			name: "BadgerFish",
+			conv: jsonxml.NewConverter(),
+			src:  `{"a":{"@xmlns":{"$":"urn:a","p":"urn:p"},"@x":1,"p:b":[{"$":"one"},"two"],"c":{"$":"t","@xml:lang":"en"}}}`,
+			want: `<a xmlns="urn:a" xmlns:p="urn:p" x="1"><p:b>one</p:b><p:b>two</p:b><c xml:lang="en">t</c></a>`,
+		},
+		{
+			name: "Parker",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionParker).RootName("doc"),
+			src:  `{"a":[1,{"b":true}],"c":null,"d":"x & y"}`,
+			want: `<doc><a>1</a><a><b>true</b></a><c/><d>x &amp; y</d></doc>`,
+		},
+		{
+			name: "GData",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionGData),
+			src:  `{"version":"1.0","encoding":"UTF-8","feed":{"xmlns":"urn:feed","xmlns$g":"urn:g","g$id":"1","title":{"type":"text","$t":"T"},"g$x":[{},{}]}}`,
+			want: `<feed xmlns="urn:feed" xmlns:g="urn:g" g:id="1"><title type="text">T</title><g:x/><g:x/></feed>`,
+		},
+		{
+			name: "GData attribute prefix",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionGData).AttributePrefix("@"),
+			src:  `{"a":{"@id":"1","title":"T"}}`,
+			want: `<a id="1"><title>T</title></a>`,
+		},
+		{
+			name: "JsonML",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionJsonML),
+			src:  `["p",{"xmlns:h":"urn:h","class":"x"},"Hello ",["h:b","big"],1,null]`,
+			want: `<p xmlns:h="urn:h" class="x">Hello <h:b>big</h:b>1</p>`,
+		},
+		{
+			name: "XPath",
+			conv: jsonxml.NewConverter().Convention(jsonxml.ConventionXPath),
+			src:  "{\"a\":[1.50,false,null],\"s\":\"x\\u0000\"}",
+			want: `<map xmlns="http://www.w3.org/2005/xpath-functions"><array key="a"><number>1.50</number><boolean>false</boolean><null/></array><string key="s">x&#xFFFD;</string></map>`,
+		},
+		{
+			name: "strip namespaces",
+			conv: jsonxml.NewConverter().Namespaces(jsonxml.NamespaceModeStrip),
+			src:  `{"p:a":{"@xmlns":{"p":"urn:p"},"@p:x":"1"}}`,
+			want: `<a x="1"/>`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.want, toXML(t, tc.conv, tc.src))
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+		errorTests := []struct {
+			conv jsonxml.Converter
+			src  string
+			want error
+		}{
+			{conv: jsonxml.NewConverter(), src: `{"a":`, want: jsonxml.ErrInvalidJSON},
+			{conv: jsonxml.NewConverter(), src: `{"a":{}} {}`, want: jsonxml.ErrInvalidJSON},
+			{conv: jsonxml.NewConverter(), src: `{"a":{},"b":{}}`, want: jsonxml.ErrUnexpectedJSON},
+			{conv: jsonxml.NewConverter(), src: `{"a b":{}}`, want: jsonxml.ErrUnexpectedJSON},
+			{conv: jsonxml.NewConverter(), src: `{"p:a":{}}`, want: jsonxml.ErrUnboundPrefix},
+			{conv: jsonxml.NewConverter().Convention(jsonxml.ConventionParker), src: `[1]`, want: jsonxml.ErrUnexpectedJSON},
+			{conv: jsonxml.NewConverter().Convention(jsonxml.ConventionParker), src: `{"a":[[1]]}`, want: jsonxml.ErrUnexpectedJSON},
+			{conv: jsonxml.NewConverter().Convention(jsonxml.ConventionJsonML), src: `[{"a":"b"}]`, want: jsonxml.ErrUnexpectedJSON},
+			{conv: jsonxml.NewConverter().Convention(jsonxml.ConventionGData), src: `{"version":"1.0"}`, want: jsonxml.ErrUnexpectedJSON},
+		}
+		for _, tc := range errorTests {
+			_, err := tc.conv.ToXML(t.Context(), []byte(tc.src))
+			require.ErrorIs(t, err, tc.want, tc.src)
+		}
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, conv := range []jsonxml.Convention{jsonxml.ConventionBadgerFish, jsonxml.ConventionGData, jsonxml.ConventionJsonML} {
+		t.Run(conv.String(), func(t *testing.T) {
+			t.Parallel()
+			c := jsonxml.NewConverter().Convention(conv)
+			out, err := c.ToJSON(t.Context(), parse(t, feedXML))
+			require.NoError(t, err)
+			require.Equal(t, feedXML, toXML(t, c, string(out)))
+		})
+	}
+
+	t.Run("xpath", func(t *testing.T) {
+		t.Parallel()
+		const src = `{"a":[1,-2.5e3,true,null,{}],"b":{"c":"d\"e"},"":[]}`
+		c := jsonxml.NewConverter().Convention(jsonxml.ConventionXPath)
+		doc, err := c.ToXML(t.Context(), []byte(src))
+		require.NoError(t, err)
+		out, err := c.ToJSON(t.Context(), doc)
+		require.NoError(t, err)
+		require.Equal(t, src, string(out))
+	})
+}
+
+func TestArraysFromSchema(t *testing.T) {
+	t.Parallel()
+
+	const schemaSrc = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:o" xmlns="urn:o" elementFormDefault="qualified">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="customer" type="xs:string"/>
+        <xs:element name="line" type="lineType" maxOccurs="unbounded"/>
+        <xs:sequence maxOccurs="3">
+          <xs:element name="note" type="xs:string"/>
+        </xs:sequence>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+  <xs:complexType name="lineType">
+    <xs:sequence>
+      <xs:element name="sku" type="xs:string"/>
+      <xs:element name="tag" type="xs:string" minOccurs="0" maxOccurs="2"/>
+    </xs:sequence>
+  </xs:complexType>
+</xs:schema>`
+	schema, err := xsd.NewCompiler().Compile(t.Context(), parse(t, schemaSrc))
+	require.NoError(t, err)
+
+	conv := jsonxml.NewConverter().Convention(jsonxml.ConventionParker).ArraysFromSchema(schema)
+	out, err := conv.ToJSON(t.Context(), parse(t, `<order xmlns="urn:o"><customer>c</customer><line><sku>s</sku><tag>t</tag></line><note>n</note></order>`))
+	require.NoError(t, err)
+	require.Equal(t, `{"customer":"c","line":[{"sku":"s","tag":["t"]}],"note":["n"]}`, string(out))
+}
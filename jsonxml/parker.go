@@ -0,0 +1,54 @@
+package jsonxml
+
+import (
+	"fmt"
+
+	helium "github.com/lestrrat-go/helium"
+)
+
+func (w *jsonBuilder) parker(e *helium.Element) (any, error) {
+	if err := w.ctx.Err(); err != nil {
+		return nil, err
+	}
+	elems := childElements(e)
+	if len(elems) == 0 {
+		text := directText(e)
+		if text == "" {
+			return nil, nil //nolint:nilnil // an empty element is null
+		}
+		return scalar(text, w.s.infer), nil
+	}
+	o := &object{}
+	if err := w.addChildren(o, elems, ":", w.parker); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (b *xmlBuilder) parker(v any) error {
+	if _, ok := v.([]any); ok {
+		return fmt.Errorf("%w: a Parker document is not an array", ErrUnexpectedJSON)
+	}
+	root, err := b.parkerElement(b.s.rootName, v, nil)
+	if err != nil {
+		return err
+	}
+	return b.setRoot(root)
+}
+
+func (b *xmlBuilder) parkerElement(name string, v any, sc scope) (*helium.Element, error) {
+	o, ok := v.(*object)
+	if !ok {
+		return b.textElement(name, ":", v, sc)
+	}
+	e, sc, err := b.element(name, ":", nil, sc)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range o.members {
+		if err := b.addChildren(e, m.key, m.value, sc, b.parkerElement); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
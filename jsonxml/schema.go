@@ -0,0 +1,61 @@
+package jsonxml
+
+import (
+	"github.com/lestrrat-go/helium/xsd"
+)
+
+// schemaArrays adds to arrays the {uri}local names of the elements schema
+// allows to occur more than once in a row within some content model.
+func schemaArrays(schema *xsd.Schema, arrays map[string]struct{}) {
+	seen := map[*xsd.TypeDef]struct{}{}
+	add := func(decl *xsd.ElementDecl) {
+		arrays["{"+decl.Name.NS+"}"+decl.Name.Local] = struct{}{}
+		for _, member := range schema.SubstGroupMembers(decl.Name) {
+			arrays["{"+member.Name.NS+"}"+member.Name.Local] = struct{}{}
+		}
+	}
+
+	var walkType func(*xsd.TypeDef)
+	var walkGroup func(*xsd.ModelGroup, bool)
+	walkGroup = func(g *xsd.ModelGroup, repeats bool) {
+		repeats = repeats || occursMany(g.MaxOccurs)
+		for _, p := range g.Particles {
+			r := repeats || occursMany(p.MaxOccurs)
+			switch term := p.Term.(type) {
+			case *xsd.ElementDecl:
+				if r {
+					add(term)
+				}
+				walkType(term.Type)
+			case *xsd.ModelGroup:
+				walkGroup(term, r)
+			}
+		}
+	}
+	walkType = func(t *xsd.TypeDef) {
+		if t == nil {
+			return
+		}
+		if _, ok := seen[t]; ok {
+			return
+		}
+		seen[t] = struct{}{}
+		if t.ContentModel != nil {
+			walkGroup(t.ContentModel, false)
+		}
+		walkType(t.BaseType)
+	}
+
+	for _, decl := range schema.Elements() {
+		walkType(decl.Type)
+	}
+	for _, name := range schema.NamedTypes() {
+		if t, ok := schema.LookupType(name.Local, name.NS); ok {
+			walkType(t)
+		}
+	}
+}
+
+func occursMany(maxOccurs int) bool {
+	return maxOccurs == xsd.Unbounded || maxOccurs > 1
+}
@@ -0,0 +1,130 @@
+package jsonxml
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	helium "github.com/lestrrat-go/helium"
+)
+
+// jsonBuilder builds the JSON value of an XML tree.
+type jsonBuilder struct {
+	ctx context.Context
+	s   *settings
+}
+
+// qname returns prefix and local joined by sep, or local alone when the
+// prefix is empty or namespaces are stripped.
+func (w *jsonBuilder) qname(prefix, local, sep string) string {
+	if w.s.strip || prefix == "" {
+		return local
+	}
+	return prefix + sep + local
+}
+
+// elementKey returns the key of e, its prefix joined to its local name by
+// sep.
+func (w *jsonBuilder) elementKey(e *helium.Element, sep string) string {
+	return w.qname(e.Prefix(), e.LocalName(), sep)
+}
+
+// attributeKey returns the key of a, its prefix joined to its local name by
+// sep.
+func (w *jsonBuilder) attributeKey(a *helium.Attribute, sep string) string {
+	return w.s.attrPrefix + w.qname(a.Prefix(), a.LocalName(), sep)
+}
+
+// childElements returns the elements among the children of e.
+func childElements(e *helium.Element) []*helium.Element {
+	var elems []*helium.Element
+	for child := range helium.Children(e) {
+		if ce, ok := helium.AsNode[*helium.Element](child); ok {
+			elems = append(elems, ce)
+		}
+	}
+	return elems
+}
+
+// directText returns the text among the children of e, with entity
+// references expanded.
+func directText(e *helium.Element) string {
+	var sb strings.Builder
+	for child := range helium.Children(e) {
+		switch child.Type() {
+		case helium.TextNode, helium.CDATASectionNode, helium.EntityRefNode:
+			sb.Write(child.Content())
+		}
+	}
+	return sb.String()
+}
+
+// elementText returns the text of e worth keeping: all of it for an element
+// with no child elements, and nothing for one whose text is only the
+// whitespace that indents its children. The conventions that use it write
+// text under one key apart from the child elements, so they cannot keep
+// other text in its place among the children and fail with
+// ErrNotRepresentable.
+func elementText(e *helium.Element, hasChildren bool) (string, error) {
+	text := directText(e)
+	if !hasChildren {
+		return text, nil
+	}
+	if strings.TrimSpace(text) != "" {
+		return "", fmt.Errorf("%w: %q has both text and child elements", ErrNotRepresentable, e.Name())
+	}
+	return "", nil
+}
+
+// elementGroup is the children of an element that share a key.
+type elementGroup struct {
+	key   string
+	elems []*helium.Element
+}
+
+// groupElements groups elems by key, in the order the keys first occur.
+func groupElements(elems []*helium.Element, key func(*helium.Element) string) []*elementGroup {
+	var groups []*elementGroup
+	byKey := map[string]*elementGroup{}
+	for _, e := range elems {
+		k := key(e)
+		g := byKey[k]
+		if g == nil {
+			g = &elementGroup{key: k}
+			byKey[k] = g
+			groups = append(groups, g)
+		}
+		g.elems = append(g.elems, e)
+	}
+	return groups
+}
+
+// addChildren adds the child elements of an element to o, one member per
+// key; a key that occurs more than once, or that names an element always
+// written as an array, has an array value. value returns the value of one
+// child.
+func (w *jsonBuilder) addChildren(o *object, elems []*helium.Element, sep string, value func(*helium.Element) (any, error)) error {
+	for _, g := range groupElements(elems, func(e *helium.Element) string { return w.elementKey(e, sep) }) {
+		if o.has(g.key) {
+			return fmt.Errorf("%w: key %q is used by an attribute and an element", ErrNotRepresentable, g.key)
+		}
+		if len(g.elems) == 1 && !w.s.isArray(g.elems[0]) {
+			v, err := value(g.elems[0])
+			if err != nil {
+				return err
+			}
+			o.add(g.key, v)
+			continue
+		}
+		items := make([]any, 0, len(g.elems))
+		for _, e := range g.elems {
+			v, err := value(e)
+			if err != nil {
+				return err
+			}
+			items = append(items, v)
+		}
+		o.add(g.key, items)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package jsonxml
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/internal/xmlchar"
+)
+
+// xmlBuilder builds an XML tree from a JSON value.
+type xmlBuilder struct {
+	ctx context.Context
+	s   *settings
+	doc *helium.Document
+}
+
+// scope maps the prefixes in scope to their namespaces; the empty prefix is
+// the default namespace.
+type scope map[string]*helium.Namespace
+
+// nsDecl is a namespace declaration taken from the JSON.
+type nsDecl struct {
+	prefix string
+	uri    string
+}
+
+// element creates an element named name, a prefixed name with sep between
+// prefix and local name, carrying decls, and returns it with the scope of
+// its content. sc is the scope of the parent.
+func (b *xmlBuilder) element(name, sep string, decls []nsDecl, sc scope) (*helium.Element, scope, error) {
+	if err := b.ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	prefix, local, err := b.splitName(name, sep)
+	if err != nil {
+		return nil, nil, err
+	}
+	e, err := b.doc.CreateElement(local)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !b.s.strip {
+		cloned := false
+		for _, d := range decls {
+			// Declarations already in effect, and undeclarations of
+			// namespaces not in scope, are left out.
+			if cur, ok := sc[d.prefix]; (ok && cur.URI() == d.uri) || (!ok && d.uri == "") {
+				continue
+			}
+			if d.prefix != "" && (!xmlchar.IsValidNCName(d.prefix) || d.uri == "") {
+				return nil, nil, fmt.Errorf("%w: invalid declaration of prefix %q", ErrUnexpectedJSON, d.prefix)
+			}
+			ns, err := b.doc.CreateNamespace(d.prefix, d.uri)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := e.AddNamespaceDecl(ns); err != nil {
+				return nil, nil, err
+			}
+			if !cloned {
+				sc = maps.Clone(sc)
+				if sc == nil {
+					sc = scope{}
+				}
+				cloned = true
+			}
+			sc[d.prefix] = ns
+		}
+	}
+	ns, ok := sc[prefix]
+	if !ok && prefix != "" {
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnboundPrefix, name)
+	}
+	if ns != nil && ns.URI() != "" {
+		e.SetNamespace(ns)
+	}
+	return e, sc, nil
+}
+
+// setAttribute sets the attribute named name, a prefixed name with sep
+// between prefix and local name, on e.
+func (b *xmlBuilder) setAttribute(e *helium.Element, name, sep, value string, sc scope) error {
+	prefix, local, err := b.splitName(name, sep)
+	if err != nil {
+		return err
+	}
+	value = xmlText(value)
+	if prefix == "" {
+		return e.SetAttribute(local, value)
+	}
+	ns, ok := sc[prefix]
+	if !ok {
+		if prefix != lexicon.PrefixXML {
+			return fmt.Errorf("%w: %q", ErrUnboundPrefix, name)
+		}
+		if ns, err = b.doc.CreateNamespace(lexicon.PrefixXML, lexicon.NamespaceXML); err != nil {
+			return err
+		}
+	}
+	return e.SetAttributeNS(local, value, ns)
+}
+
+// splitName splits a name written with sep between prefix and local name.
+// With namespaces stripped the prefix is dropped.
+func (b *xmlBuilder) splitName(name, sep string) (string, string, error) {
+	prefix, local, ok := strings.Cut(name, sep)
+	if !ok {
+		prefix, local = "", name
+	}
+	if !xmlchar.IsValidNCName(local) || (ok && !xmlchar.IsValidNCName(prefix)) {
+		return "", "", fmt.Errorf("%w: %q is not an XML name", ErrUnexpectedJSON, name)
+	}
+	if b.s.strip {
+		prefix = ""
+	}
+	return prefix, local, nil
+}
+
+// addText adds s to e as text.
+func (b *xmlBuilder) addText(e *helium.Element, s string) error {
+	if s == "" {
+		return nil
+	}
+	return e.AddChild(b.doc.CreateText([]byte(xmlText(s))))
+}
+
+// setRoot makes e the document element.
+func (b *xmlBuilder) setRoot(e *helium.Element) error {
+	return b.doc.SetDocumentElement(e)
+}
+
+// xmlText returns s with the characters XML cannot hold, such as NUL, which
+// JSON strings can, replaced by U+FFFD.
+func xmlText(s string) string {
+	if strings.IndexFunc(s, isNotChar) < 0 {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if isNotChar(r) {
+			return '\uFFFD'
+		}
+		return r
+	}, s)
+}
+
+func isNotChar(r rune) bool {
+	return !xmlchar.IsChar(r)
+}
+
+// attributeName returns the name of the attribute a member stands for, and
+// false when the member is not an attribute. Only scalars are attributes;
+// with an attribute prefix set, the key must also carry it.
+func (b *xmlBuilder) attributeName(key string, v any) (string, bool) {
+	if _, ok := scalarText(v); !ok {
+		return "", false
+	}
+	if b.s.attrPrefix == "" {
+		return key, true
+	}
+	return strings.CutPrefix(key, b.s.attrPrefix)
+}
+
+// addChildren adds to e the elements named name that v, a value or an array
+// of values, stands for. build creates one element from its value.
+func (b *xmlBuilder) addChildren(e *helium.Element, name string, v any, sc scope, build func(string, any, scope) (*helium.Element, error)) error {
+	items, ok := v.([]any)
+	if !ok {
+		items = []any{v}
+	}
+	for _, item := range items {
+		if _, nested := item.([]any); nested {
+			return fmt.Errorf("%w: array of arrays under %q", ErrUnexpectedJSON, name)
+		}
+		child, err := build(name, item, sc)
+		if err != nil {
+			return err
+		}
+		if err := e.AddChild(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
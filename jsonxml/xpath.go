@@ -0,0 +1,257 @@
+package jsonxml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+)
+
+// The element names of the fn:json-to-xml vocabulary.
+const (
+	xpathMap     = "map"
+	xpathArray   = "array"
+	xpathString  = "string"
+	xpathNumber  = "number"
+	xpathBoolean = "boolean"
+	xpathNull    = "null"
+)
+
+func (w *jsonBuilder) xpath(e *helium.Element) (any, error) {
+	if err := w.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if e.URI() != lexicon.NamespaceFn {
+		return nil, fmt.Errorf("%w: %q is not in the %s namespace", ErrNotRepresentable, e.Name(), lexicon.NamespaceFn)
+	}
+	elems := childElements(e)
+	switch name := e.LocalName(); name {
+	case xpathMap, xpathArray:
+		if strings.TrimSpace(directText(e)) != "" {
+			return nil, fmt.Errorf("%w: text in %s", ErrNotRepresentable, name)
+		}
+		if name == xpathArray {
+			items := make([]any, 0, len(elems))
+			for _, ce := range elems {
+				v, err := w.xpath(ce)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, v)
+			}
+			return items, nil
+		}
+		o := &object{}
+		for _, ce := range elems {
+			key, ok := ce.GetAttribute("key")
+			if !ok {
+				return nil, fmt.Errorf("%w: map entry %q has no key", ErrNotRepresentable, ce.Name())
+			}
+			if xpathFlag(ce, "escaped-key") {
+				var err error
+				if key, err = unescapeJSON(key); err != nil {
+					return nil, err
+				}
+			}
+			if o.has(key) {
+				return nil, fmt.Errorf("%w: duplicate map key %q", ErrNotRepresentable, key)
+			}
+			v, err := w.xpath(ce)
+			if err != nil {
+				return nil, err
+			}
+			o.add(key, v)
+		}
+		return o, nil
+	}
+
+	if len(elems) > 0 {
+		return nil, fmt.Errorf("%w: element in %s", ErrNotRepresentable, e.LocalName())
+	}
+	text := directText(e)
+	switch e.LocalName() {
+	case xpathString:
+		if xpathFlag(e, "escaped") {
+			return unescapeJSON(text)
+		}
+		return text, nil
+	case xpathNumber:
+		s := strings.TrimSpace(text)
+		if isNumber(s) {
+			return json.Number(s), nil
+		}
+		// Other xs:double literals, such as ".5" or "+1", are written
+		// the way JSON allows.
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("%w: %q is not a JSON number", ErrNotRepresentable, s)
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+	case xpathBoolean:
+		switch strings.TrimSpace(text) {
+		case "true", "1":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%w: %q is not a boolean", ErrNotRepresentable, text)
+	case xpathNull:
+		if text != "" {
+			return nil, fmt.Errorf("%w: text in null", ErrNotRepresentable)
+		}
+		return nil, nil //nolint:nilnil // the JSON null
+	}
+	return nil, fmt.Errorf("%w: unknown element %q", ErrNotRepresentable, e.LocalName())
+}
+
+// xpathFlag reports whether the boolean attribute name of e is true.
+func xpathFlag(e *helium.Element, name string) bool {
+	v, _ := e.GetAttribute(name)
+	switch strings.TrimSpace(v) {
+	case "true", "1":
+		return true
+	}
+	return false
+}
+
+// unescapeJSON resolves the JSON escape sequences in s, the content of a
+// string or key marked as escaped.
+func unescapeJSON(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(s) {
+			return "", fmt.Errorf("%w: incomplete escape in %q", ErrNotRepresentable, s)
+		}
+		switch s[i] {
+		case '"', '\\', '/':
+			sb.WriteByte(s[i])
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'u':
+			r, ok := hex4(s, i+1)
+			if !ok {
+				return "", fmt.Errorf("%w: invalid escape in %q", ErrNotRepresentable, s)
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				if r2, ok := hex4(s, i+3); ok && i+2 < len(s) && s[i+1] == '\\' && s[i+2] == 'u' {
+					if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+						r = dec
+						i += 6
+					}
+				}
+			}
+			sb.WriteRune(r)
+		default:
+			return "", fmt.Errorf("%w: invalid escape in %q", ErrNotRepresentable, s)
+		}
+	}
+	return sb.String(), nil
+}
+
+// hex4 reads the four hexadecimal digits at s[i:].
+func hex4(s string, i int) (rune, bool) {
+	if i+4 > len(s) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s[i:i+4], 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	return rune(n), true
+}
+
+func (b *xmlBuilder) xpathDocument(v any) error {
+	ns, err := b.doc.CreateNamespace("", lexicon.NamespaceFn)
+	if err != nil {
+		return err
+	}
+	root, err := b.xpathElement(v, ns)
+	if err != nil {
+		return err
+	}
+	if err := root.AddNamespaceDecl(ns); err != nil {
+		return err
+	}
+	return b.setRoot(root)
+}
+
+func (b *xmlBuilder) xpathElement(v any, ns *helium.Namespace) (*helium.Element, error) {
+	if err := b.ctx.Err(); err != nil {
+		return nil, err
+	}
+	var name string
+	switch v.(type) {
+	case *object:
+		name = xpathMap
+	case []any:
+		name = xpathArray
+	case string:
+		name = xpathString
+	case json.Number:
+		name = xpathNumber
+	case bool:
+		name = xpathBoolean
+	default:
+		name = xpathNull
+	}
+	e, err := b.doc.CreateElementNS(name, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case *object:
+		for _, m := range v.members {
+			child, err := b.xpathElement(m.value, ns)
+			if err != nil {
+				return nil, err
+			}
+			if err := child.SetAttribute("key", xmlText(m.key)); err != nil {
+				return nil, err
+			}
+			if err := e.AddChild(child); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			child, err := b.xpathElement(item, ns)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.AddChild(child); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		text, _ := scalarText(v)
+		if err := b.addText(e, text); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
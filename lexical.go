@@ -0,0 +1,154 @@
+package helium
+
+import (
+	"strconv"
+	"strings"
+)
+
+// lexicalInfo holds the source markup recorded for a document parsed with
+// [Parser.PreserveLexical]. The writer consults it (see
+// [Writer.PreserveLexical]) to reproduce unedited nodes exactly as they were
+// written.
+//
+// Every recorded node carries a signature of its state at parse time. A node
+// whose current signature differs has been edited since, and is serialized
+// from the DOM as usual; this keeps the record correct no matter which
+// mutation API touched the tree.
+type lexicalInfo struct {
+	bom     []byte // UTF-8 byte order mark the source started with, if any
+	decl    []byte // XML declaration as written; nil when the source had none
+	declSig string
+	tail    []byte // whitespace after the last top-level node
+	nodes   map[Node]*lexicalNode
+}
+
+// lexicalNode is the source markup of a single node, or of a run of sibling
+// nodes that the source only spells out together.
+type lexicalNode struct {
+	// lead is the whitespace preceding a top-level node. The DOM has no node
+	// for whitespace outside the document element.
+	lead []byte
+	// raw is the start tag of an element, or the complete markup of any other
+	// node (including character and entity references in text).
+	raw []byte
+	// end is the end tag of an element; nil when the element was written as
+	// an empty-element tag.
+	end []byte
+	// rest holds the siblings after the node that raw also produces, as when
+	// an entity reference expands to several nodes or text runs on into one.
+	rest []Node
+	// whole marks that raw is the markup of the node and rest together,
+	// subtrees included, rather than of the node alone.
+	whole bool
+	sig   string
+}
+
+// lookup returns the record for n if n, and for a whole run the nodes after
+// it, are unchanged since they were parsed.
+func (li *lexicalInfo) lookup(n Node) (*lexicalNode, bool) {
+	ln, ok := li.nodes[n]
+	if !ok {
+		return nil, false
+	}
+	if !ln.whole {
+		if ln.sig != lexicalSig(n) {
+			return nil, false
+		}
+		return ln, true
+	}
+	next := n
+	for _, r := range ln.rest {
+		if next = next.NextSibling(); next != r {
+			return nil, false
+		}
+	}
+	if ln.sig != lexicalRunSig(n, ln.rest) {
+		return nil, false
+	}
+	return ln, true
+}
+
+// seal stores the signature of every recorded node once the tree is
+// complete.
+func (li *lexicalInfo) seal() {
+	for n, ln := range li.nodes {
+		if ln.whole {
+			ln.sig = lexicalRunSig(n, ln.rest)
+		} else {
+			ln.sig = lexicalSig(n)
+		}
+	}
+}
+
+// lexicalRunSig summarizes n, the nodes in rest and their subtrees.
+func lexicalRunSig(n Node, rest []Node) string {
+	var sb strings.Builder
+	var add func(Node)
+	add = func(n Node) {
+		sb.WriteString(strconv.Itoa(int(n.Type())))
+		sb.WriteByte('(')
+		sb.WriteString(lexicalSig(n))
+		if n.Type() != EntityRefNode {
+			// An entity reference shares its children with the entity.
+			for c := range Children(n) {
+				add(c)
+			}
+		}
+		sb.WriteByte(')')
+	}
+	add(n)
+	for _, r := range rest {
+		add(r)
+	}
+	return sb.String()
+}
+
+// lexicalSig summarizes the parts of n that its recorded markup encodes.
+func lexicalSig(n Node) string {
+	var sb strings.Builder
+	switch v := n.(type) {
+	case *Element:
+		sb.WriteString(v.Name())
+		for _, ns := range v.Namespaces() {
+			sb.WriteString("\x00xmlns:")
+			sb.WriteString(ns.Prefix())
+			sb.WriteByte('=')
+			sb.WriteString(ns.URI())
+		}
+		for attr := v.properties; attr != nil; attr = attr.NextAttribute() {
+			sb.WriteByte(0)
+			sb.WriteString(attr.Name())
+			sb.WriteByte('=')
+			sb.WriteString(attr.Value())
+		}
+	case *ProcessingInstruction:
+		sb.WriteString(v.target)
+		sb.WriteByte(0)
+		sb.WriteString(v.data)
+	case *DTD:
+		sb.WriteString(v.Name())
+		sb.WriteByte(0)
+		sb.WriteString(v.ExternalID())
+		sb.WriteByte(0)
+		sb.WriteString(v.SystemID())
+		count := 0
+		for range Children(v) {
+			count++
+		}
+		sb.WriteByte(0)
+		sb.WriteString(strconv.Itoa(count))
+	case *Document:
+		sb.WriteString(v.Version())
+		sb.WriteByte(0)
+		sb.WriteString(v.RawEncoding())
+		sb.WriteByte(0)
+		sb.WriteString(strconv.Itoa(int(v.Standalone())))
+	case *EntityRef:
+		sb.WriteString(v.Name())
+	default:
+		sb.Write(rawContent(n))
+	}
+	return sb.String()
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
@@ -0,0 +1,177 @@
+package helium_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLexical(t *testing.T, n helium.Node) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, helium.NewWriter().PreserveLexical(true).WriteTo(&buf, n))
+	return buf.String()
+}
+
+func TestPreserveLexical(t *testing.T) {
+	t.Parallel()
+
+	p := helium.NewParser().PreserveLexical(true)
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+		inputs := map[string]string{
+			"attributes": `<config   b='2'  a="1"
+	c = "x&amp;y" ><item id='x'/><item id="y" ></item></config>`,
+			"references":     `<r>a&#65;&#x42;&lt;&gt;b &quot;q&apos; > ]]</r>`,
+			"prolog":         "<?xml version='1.0'  encoding=\"UTF-8\" ?>\r\n<!-- head -->\n\n<?pi  data ?>\n<root/>\n<!-- tail -->\n\n",
+			"no declaration": "  <root>\n  <child/>\n</root>",
+			"doctype": `<?xml version="1.0"?>
+<!DOCTYPE root [
+  <!ENTITY who "world">
+  <!-- a ] comment > -->
+  <!ATTLIST root x CDATA "d">
+]>
+<root>hello &who;!<![CDATA[ <raw> ]]></root>
+`,
+			"namespaces":   `<a:root xmlns:a="urn:a"   xmlns="urn:d"><a:x a:attr = "1"/><y/></a:root>`,
+			"line endings": "<root>\r\n  <a>x\ry</a>\r\n</root>\r\n",
+		}
+		for name, src := range inputs {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				doc, err := p.Parse(t.Context(), []byte(src))
+				require.NoError(t, err)
+				require.Equal(t, src, writeLexical(t, doc))
+			})
+		}
+	})
+
+	t.Run("substituted entities", func(t *testing.T) {
+		t.Parallel()
+		const src = `<!DOCTYPE r [
+  <!ENTITY e "<b  k='v'>x</b>">
+  <!ENTITY t "plain">
+]>
+<r><a/>&e;<c  x="1"/>&t; and &e;&t;<d/></r>
+`
+		q := p.SubstituteEntities(true)
+		doc, err := q.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		require.Equal(t, src, writeLexical(t, doc))
+
+		doc, err = q.ParseReader(t.Context(), strings.NewReader(src))
+		require.NoError(t, err)
+		require.Equal(t, src, writeLexical(t, doc))
+
+		pp := q.NewPushParser(t.Context())
+		for i := range len(src) {
+			require.NoError(t, pp.Push([]byte(src[i:i+1])))
+		}
+		doc, err = pp.Close()
+		require.NoError(t, err)
+		require.Equal(t, src, writeLexical(t, doc))
+	})
+
+	t.Run("ParseReader", func(t *testing.T) {
+		t.Parallel()
+		const src = "<?xml version='1.0'?>\n<root  a='1'>&#x20;</root>\n"
+		doc, err := p.ParseReader(t.Context(), strings.NewReader(src))
+		require.NoError(t, err)
+		require.Equal(t, src, writeLexical(t, doc))
+	})
+
+	t.Run("non UTF-8 input", func(t *testing.T) {
+		t.Parallel()
+		src := []byte("<?xml version='1.0' encoding='ISO-8859-1'?>\n<r  a='caf\xe9'>na\xefve</r>\n")
+		doc, err := p.Parse(t.Context(), src)
+		require.NoError(t, err)
+		require.Equal(t, string(src), writeLexical(t, doc))
+	})
+
+	t.Run("edits only touch edited nodes", func(t *testing.T) {
+		t.Parallel()
+		const src = `<?xml version="1.0"?>
+<config>
+  <server host='a.example'   port = "80" />
+  <server host='b.example'   port = "80" />
+  <!-- keep   me -->
+  <name>x&#x20;y</name>
+</config>
+`
+		doc, err := p.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+
+		root := doc.DocumentElement()
+		var servers []*helium.Element
+		for e := range helium.ChildElements(root) {
+			if e.LocalName() == "server" {
+				servers = append(servers, e)
+			}
+		}
+		require.Len(t, servers, 2)
+		require.NoError(t, servers[1].SetAttribute("port", "8080"))
+
+		want := strings.Replace(src, `<server host='b.example'   port = "80" />`, `<server host="b.example" port="8080"/>`, 1)
+		require.Equal(t, want, writeLexical(t, doc))
+	})
+
+	t.Run("added content and new nodes", func(t *testing.T) {
+		t.Parallel()
+		const src = "<root>\n  <empty  />\n  <text>a&amp;b</text>\n</root>"
+		doc, err := p.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+
+		root := doc.DocumentElement()
+		var empty, text *helium.Element
+		for e := range helium.ChildElements(root) {
+			switch e.LocalName() {
+			case "empty":
+				empty = e
+			case "text":
+				text = e
+			}
+		}
+		child, err := doc.CreateElement("new")
+		require.NoError(t, err)
+		require.NoError(t, empty.AddChild(child))
+		require.NoError(t, text.FirstChild().(*helium.Text).AppendText([]byte("!")))
+
+		require.Equal(t, "<root>\n  <empty><new/></empty>\n  <text>a&amp;b!</text>\n</root>", writeLexical(t, doc))
+	})
+
+	t.Run("fragment keeps namespace declarations", func(t *testing.T) {
+		t.Parallel()
+		doc, err := p.Parse(t.Context(), []byte(`<a:root xmlns:a="urn:a"><a:x  k='v'><a:y/></a:x></a:root>`))
+		require.NoError(t, err)
+
+		x := doc.DocumentElement().FirstChild()
+		require.Equal(t, `<a:x xmlns:a="urn:a" k="v"><a:y/></a:x>`, writeLexical(t, x))
+	})
+
+	t.Run("stripped blanks", func(t *testing.T) {
+		t.Parallel()
+		doc, err := p.StripBlanks(true).Parse(t.Context(), []byte("<r>\n  <a  x='1'/>\n</r>"))
+		require.NoError(t, err)
+		require.Equal(t, "<r><a  x='1'/></r>", writeLexical(t, doc))
+	})
+
+	t.Run("writer mode off", func(t *testing.T) {
+		t.Parallel()
+		doc, err := p.Parse(t.Context(), []byte(`<r  a='1'/>`))
+		require.NoError(t, err)
+		s, err := helium.WriteString(doc)
+		require.NoError(t, err)
+		require.Equal(t, "<?xml version=\"1.0\"?>\n<r a=\"1\"/>\n", s)
+	})
+
+	t.Run("parser mode off", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(`<r  a='1'/>`))
+		require.NoError(t, err)
+		require.Equal(t, "<?xml version=\"1.0\"?>\n<r a=\"1\"/>\n", writeLexical(t, doc))
+	})
+}
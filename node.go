@@ -294,6 +294,9 @@ func rawContent(n Node) []byte {
 }
 
 func appendText(n MutableNode, b []byte) error {
+	if err := beginMutation(n); err != nil {
+		return err
+	}
 	// Fast path: if last child is already a text node, append directly
 	// without allocating a new Text node.
 	if last := n.LastChild(); last != nil {
@@ -431,6 +434,7 @@ func (n docnode) Line() int {
 }
 
 func (n *docnode) SetLine(line int) {
+	n.doc.mustBeginMutation()
 	n.line = line
 }
 
@@ -575,6 +579,12 @@ func noteCrossDocumentNamespaceEscape(dest *Document, ns *Namespace) {
 func addChildPreflight(n MutableNode, cur Node) error {
 	cdn := cur.baseDocNode()
 
+	// Neither the receiving tree nor the one cur is detached from may be
+	// frozen.
+	if err := beginMutation(n, cur); err != nil {
+		return err
+	}
+
 	// A node linked into a different document keeps its slab-backed storage in its
 	// original document, so guard that document's Free against recycling it. Mark
 	// BEFORE any unlink, while cur still reports its original owner.
@@ -643,6 +653,7 @@ func addChild(n MutableNode, cur Node) error {
 		pdn.firstChild = cur
 		pdn.lastChild = cur
 		cdn.parent = n
+		notifyInserted(n, cur)
 		return nil
 	}
 
@@ -655,6 +666,7 @@ func addChild(n MutableNode, cur Node) error {
 		cdn.prev = l
 		cdn.parent = n
 		pdn.lastChild = cur
+		notifyInserted(n, cur)
 		return nil
 	}
 
@@ -690,6 +702,10 @@ func (n docnode) PrevSibling() Node {
 func addSiblingPreflight(n MutableNode, cur Node) error {
 	cdn := cur.baseDocNode()
 
+	if err := beginMutation(n, cur); err != nil {
+		return err
+	}
+
 	// A sibling of n shares n's document; if cur comes from elsewhere, guard its
 	// original document's Free against recycling its slab storage. Mark BEFORE any
 	// unlink, while cur still reports its original owner. See noteCrossDocumentEscape.
@@ -759,6 +775,7 @@ func addSibling(n MutableNode, cur Node) error {
 			idn.next = cur
 			cdn.prev = iter
 			cdn.parent = ownerElem
+			notifyInserted(ownerElem, cur)
 			return nil
 		}
 	}
@@ -778,6 +795,7 @@ func addSibling(n MutableNode, cur Node) error {
 			if parent != nil {
 				parent.baseDocNode().lastChild = cur
 			}
+			notifyInserted(parent, cur)
 			return nil
 		}
 		iter = iter.NextSibling()
@@ -813,10 +831,13 @@ func UnsafeSetNextSibling(n Node, next Node) {
 //
 // A nil or typed-nil node (e.g. the *Element that Document.DocumentElement
 // returns for a rootless document) is a no-op — there is nothing to detach.
+// A node of a document produced by Freeze cannot change; UnlinkNode panics
+// with ErrReadOnly for one.
 func UnlinkNode(n MutableNode) {
 	if isNilNode(n) {
 		return
 	}
+	mustBeginMutation(n)
 	unlinkNode(n)
 }
 
@@ -848,7 +869,9 @@ func unlinkNode(n Node) {
 		}
 	}
 
-	if parent := ndn.parent; parent != nil {
+	parent := ndn.parent
+	linked := parent != nil || ndn.prev != nil || ndn.next != nil
+	if parent != nil {
 		pdn := parent.baseDocNode()
 		if pdn.firstChild != nil && pdn.firstChild.baseDocNode() == ndn {
 			pdn.firstChild = ndn.next
@@ -868,6 +891,16 @@ func unlinkNode(n Node) {
 	ndn.parent = nil
 	ndn.prev = nil
 	ndn.next = nil
+
+	if linked {
+		d := documentOf(n)
+		if parent != nil {
+			d = documentOf(parent)
+		}
+		if d.observed() {
+			d.notify(Mutation{Kind: MutationChildRemoved, Target: parent, Node: n})
+		}
+	}
 }
 
 func replaceNode(n MutableNode, nodes ...Node) error {
@@ -885,6 +918,12 @@ func replaceNode(n MutableNode, nodes ...Node) error {
 	if slices.ContainsFunc(nodes, isNilNode) {
 		return ErrNilNode
 	}
+	if err := beginMutation(n); err != nil {
+		return err
+	}
+	if err := beginMutation(nodes...); err != nil {
+		return err
+	}
 
 	cur := nodes[0]
 	cdn := cur.baseDocNode()
@@ -1047,6 +1086,23 @@ func replaceNode(n MutableNode, nodes ...Node) error {
 		ndn.next = nil
 	}
 
+	if d := replDoc; d.observed() {
+		if !replacedIsInserted {
+			kind := MutationChildRemoved
+			old := ""
+			if attrList {
+				kind = MutationAttributeRemoved
+				old = nAttr.Value()
+			}
+			d.notify(Mutation{Kind: kind, Target: parent, Node: n, OldValue: old})
+		}
+		for _, nn := range nodes {
+			if nn.baseDocNode() != ndn {
+				notifyInserted(parent, nn)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -1068,6 +1124,7 @@ func (n node) Namespaces() []*Namespace {
 // remains. A caller that rebinds an in-use prefix must also reassign the active
 // namespace (SetActiveNamespace/SetNamespace) and any prefixed attribute.
 func (n *node) RemoveNamespaceByPrefix(prefix string) bool {
+	n.doc.mustBeginMutation()
 	for i, ns := range n.nsDefs {
 		if ns.Prefix() == prefix {
 			n.nsDefs = append(n.nsDefs[:i], n.nsDefs[i+1:]...)
@@ -1155,6 +1212,9 @@ func (n *node) prefixConflictsInUse(prefix, uri string) bool {
 // most one xmlns:prefix per element across all mutators is a serializer-level
 // concern, outside this method's scope.
 func (n *node) DeclareNamespace(prefix, uri string) error {
+	if err := n.doc.beginMutation(); err != nil {
+		return err
+	}
 	if n.prefixConflictsInUse(prefix, uri) {
 		return fmt.Errorf("cannot rebind namespace prefix %q while it is in use on this element: %w", prefix, ErrInvalidOperation)
 	}
@@ -1223,6 +1283,9 @@ func (n *node) AddNamespaceDecl(ns *Namespace) error {
 	if ns == nil {
 		return ErrNilNode
 	}
+	if err := n.doc.beginMutation(); err != nil {
+		return err
+	}
 	prefix := ns.Prefix()
 	if n.prefixConflictsInUse(prefix, ns.URI()) {
 		return fmt.Errorf("cannot rebind namespace prefix %q while it is in use on this element: %w", prefix, ErrInvalidOperation)
@@ -1255,6 +1318,9 @@ func (n *node) AddNamespaceDecl(ns *Namespace) error {
 // SetActiveNamespace declares a namespace and sets it as this node's active
 // namespace.
 func (n *node) SetActiveNamespace(prefix, uri string) error {
+	if err := n.doc.beginMutation(); err != nil {
+		return err
+	}
 	ns, err := n.doc.CreateNamespace(prefix, uri)
 	if err != nil {
 		return err
@@ -1273,6 +1339,7 @@ func (n *node) SetActiveNamespace(prefix, uri string) error {
 // a cross-document node move apply (see noteCrossDocumentNamespaceEscape). A nil,
 // heap-allocated, or same-document ns marks nothing.
 func (n *node) SetNamespace(ns *Namespace) {
+	n.doc.mustBeginMutation()
 	noteCrossDocumentNamespaceEscape(n.doc, ns)
 	n.ns = ns
 	n.invalidateQName()
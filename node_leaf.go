@@ -34,7 +34,12 @@ func (n *CDATASection) AddChild(cur Node) error {
 }
 
 func (n *CDATASection) AppendText(b []byte) error {
+	if err := beginMutation(n); err != nil {
+		return err
+	}
+	old := n.content
 	n.content = append(n.content, b...)
+	notifyText(n, old)
 	return nil
 }
 
@@ -100,7 +105,12 @@ func (n *Comment) AddChild(cur Node) error {
 }
 
 func (n *Comment) AppendText(b []byte) error {
+	if err := beginMutation(n); err != nil {
+		return err
+	}
+	old := n.content
 	n.content = append(n.content, b...)
+	notifyText(n, old)
 	return nil
 }
 
@@ -180,8 +190,7 @@ func (p *ProcessingInstruction) AddChild(cur Node) error {
 		if err := addChildPreflight(p, cur); err != nil {
 			return err
 		}
-		p.data += string(cur.Content())
-		return nil
+		return p.AppendText(cur.Content())
 	default:
 		// A self-add (pi.AddChild(pi)) reaches here because a PI is not a text
 		// node; detect it by direct pointer identity so it matches every other
@@ -208,7 +217,14 @@ func (p *ProcessingInstruction) AddChild(cur Node) error {
 // AppendText appends text to the PI's data string, creating no child
 // text node. See AddChild for rationale.
 func (p *ProcessingInstruction) AppendText(b []byte) error {
+	if err := beginMutation(p); err != nil {
+		return err
+	}
+	old := p.data
 	p.data += string(b)
+	if d := p.doc; d.observed() {
+		d.notify(Mutation{Kind: MutationText, Target: p, OldValue: old})
+	}
 	return nil
 }
 
@@ -309,10 +325,15 @@ func (n *Text) AddChild(cur Node) error {
 }
 
 func (n *Text) AppendText(b []byte) error {
+	if err := beginMutation(n); err != nil {
+		return err
+	}
+	old := n.content
 	if doc := n.doc; doc != nil {
 		n.content = doc.growOwnedTextContent(n.content, len(b))
 	}
 	n.content = append(n.content, b...)
+	notifyText(n, old)
 	return nil
 }
 
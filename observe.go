@@ -0,0 +1,184 @@
+package helium
+
+import "slices"
+
+// MutationKind identifies the kind of change a [Mutation] describes.
+type MutationKind int
+
+const (
+	// MutationChildInserted reports that Node was linked in as a child of
+	// Target.
+	MutationChildInserted MutationKind = iota + 1
+	// MutationChildRemoved reports that Node was detached from Target, its
+	// former parent, either to be discarded or to be moved elsewhere.
+	MutationChildRemoved
+	// MutationAttributeSet reports that the attribute Node was added to the
+	// element Target, or replaced an attribute of the same name there, in
+	// which case OldValue holds the previous value.
+	MutationAttributeSet
+	// MutationAttributeRemoved reports that the attribute Node was removed
+	// from the element Target. OldValue holds its value.
+	MutationAttributeRemoved
+	// MutationText reports that character data was appended to Target, a
+	// text, CDATA section, comment or processing instruction node. OldValue
+	// holds its previous content.
+	MutationText
+	// MutationNamespace reports that the namespace declarations or the
+	// namespace of the element Target changed.
+	MutationNamespace
+)
+
+// Mutation describes one change to a document, as delivered to the functions
+// registered with [Document.Observe].
+type Mutation struct {
+	Kind MutationKind
+	// Target is the node that changed: the parent a child was inserted into
+	// or removed from, the element whose attributes or namespaces changed,
+	// or the node whose character data changed. It is nil when a node
+	// without a parent gained or lost a sibling.
+	Target Node
+	// Node is the child inserted or removed, or the attribute set or
+	// removed. It is nil for text and namespace changes.
+	Node Node
+	// OldValue is the previous value of a replaced or removed attribute, or
+	// the previous content of a node whose character data changed.
+	OldValue string
+}
+
+// mutationObserver wraps a registered function so that it can be told apart
+// from others when it is unregistered.
+type mutationObserver struct {
+	fn func(Mutation)
+}
+
+// Observe registers fn to be called after each change made to d through the
+// public mutation operations: AddChild, AddSibling, Replace, UnlinkNode,
+// AppendText, the attribute setters and RemoveAttribute, and the namespace
+// setters of [Element]. Moving a node reports its removal to the document it
+// leaves and its insertion to the document it joins. Merging text into an
+// adjacent text node reports a [MutationText] in place of an insertion.
+// Changes made while parsing, and through other setters such as SetLine or
+// SetAType, are not reported.
+//
+// fn runs synchronously on the goroutine making the change, once the tree is
+// consistent again. It may read the document; changes it makes are reported
+// to the observers in turn. Observe returns a function that unregisters fn.
+func (d *Document) Observe(fn func(Mutation)) (cancel func()) {
+	o := &mutationObserver{fn: fn}
+	d.observers = append(slices.Clip(d.observers), o)
+	return func() {
+		// Rebuild rather than edit in place, so a notification in progress
+		// keeps iterating the list it started with.
+		d.observers = slices.DeleteFunc(slices.Clone(d.observers), func(x *mutationObserver) bool {
+			return x == o
+		})
+	}
+}
+
+// observed reports whether any observer is registered on d, so that callers
+// can skip work done only to describe a mutation.
+func (d *Document) observed() bool {
+	return d != nil && len(d.observers) > 0
+}
+
+// notify delivers m to the observers of d.
+func (d *Document) notify(m Mutation) {
+	if d == nil {
+		return
+	}
+	for _, o := range d.observers {
+		o.fn(m)
+	}
+}
+
+// documentOf returns the document n belongs to: n itself for a document,
+// and its owner otherwise.
+func documentOf(n Node) *Document {
+	if d, ok := n.(*Document); ok {
+		return d
+	}
+	return n.OwnerDocument()
+}
+
+// notifyInserted reports cur as inserted under parent, which is nil when cur
+// became a sibling of a node without one. Attributes inserted into an
+// element's property list are reported as set.
+func notifyInserted(parent, cur Node) {
+	d := documentOf(cur)
+	if parent != nil {
+		d = documentOf(parent)
+	}
+	if !d.observed() {
+		return
+	}
+	kind := MutationChildInserted
+	if attr, ok := cur.(*Attribute); ok {
+		if elem, ok := parent.(*Element); ok && elem.hasAttributeInProperties(attr) {
+			kind = MutationAttributeSet
+		}
+	}
+	d.notify(Mutation{Kind: kind, Target: parent, Node: cur})
+}
+
+// notifyText reports that the character data of n changed from old.
+func notifyText(n Node, old []byte) {
+	if d := documentOf(n); d.observed() {
+		d.notify(Mutation{Kind: MutationText, Target: n, OldValue: string(old)})
+	}
+}
+
+// notifyNamespace reports a change to the namespaces of e.
+func notifyNamespace(e *Element) {
+	if d := e.doc; d.observed() {
+		d.notify(Mutation{Kind: MutationNamespace, Target: e})
+	}
+}
+
+// DeclareNamespace declares prefix as bound to uri on the element. See
+// the method of the same name that Element inherits; this one also notifies
+// the document's observers.
+func (n *Element) DeclareNamespace(prefix, uri string) error {
+	if err := n.node.DeclareNamespace(prefix, uri); err != nil {
+		return err
+	}
+	notifyNamespace(n)
+	return nil
+}
+
+// AddNamespaceDecl adds ns to the element's namespace declarations and
+// notifies the document's observers.
+func (n *Element) AddNamespaceDecl(ns *Namespace) error {
+	if err := n.node.AddNamespaceDecl(ns); err != nil {
+		return err
+	}
+	notifyNamespace(n)
+	return nil
+}
+
+// SetActiveNamespace declares a namespace, sets it as the element's active
+// namespace and notifies the document's observers.
+func (n *Element) SetActiveNamespace(prefix, uri string) error {
+	if err := n.node.SetActiveNamespace(prefix, uri); err != nil {
+		return err
+	}
+	notifyNamespace(n)
+	return nil
+}
+
+// SetNamespace sets the element's active namespace to ns without declaring
+// it, and notifies the document's observers.
+func (n *Element) SetNamespace(ns *Namespace) {
+	n.node.SetNamespace(ns)
+	notifyNamespace(n)
+}
+
+// RemoveNamespaceByPrefix removes the element's declaration of prefix and
+// notifies the document's observers. It reports whether a declaration was
+// removed.
+func (n *Element) RemoveNamespaceByPrefix(prefix string) bool {
+	if !n.node.RemoveNamespaceByPrefix(prefix) {
+		return false
+	}
+	notifyNamespace(n)
+	return true
+}
@@ -0,0 +1,129 @@
+package helium_test
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/stretchr/testify/require"
+)
+
+// mutationLog records mutations as comparable summaries.
+type mutationLog []string
+
+func (l *mutationLog) record(m helium.Mutation) {
+	s := map[helium.MutationKind]string{
+		helium.MutationChildInserted:    "insert",
+		helium.MutationChildRemoved:     "remove",
+		helium.MutationAttributeSet:     "attr-set",
+		helium.MutationAttributeRemoved: "attr-remove",
+		helium.MutationText:             "text",
+		helium.MutationNamespace:        "ns",
+	}[m.Kind]
+	if m.Target != nil {
+		s += " " + m.Target.Name()
+	}
+	if m.Node != nil {
+		s += " " + m.Node.Name()
+	}
+	if m.OldValue != "" {
+		s += " (" + m.OldValue + ")"
+	}
+	*l = append(*l, s)
+}
+
+func TestObserve(t *testing.T) {
+	t.Parallel()
+
+	parse := func(t *testing.T) (*helium.Document, *mutationLog) {
+		t.Helper()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(`<root id="r"><a>one</a><b/></root>`))
+		require.NoError(t, err)
+		var log mutationLog
+		doc.Observe(log.record)
+		return doc, &log
+	}
+
+	t.Run("children", func(t *testing.T) {
+		t.Parallel()
+		doc, log := parse(t)
+		root := doc.DocumentElement()
+		a := root.FirstChild().(*helium.Element)
+		b := a.NextSibling().(*helium.Element)
+
+		c, err := doc.CreateElement("c")
+		require.NoError(t, err)
+		require.NoError(t, root.AddChild(c))
+		d, err := doc.CreateElement("d")
+		require.NoError(t, err)
+		require.NoError(t, c.AddSibling(d))
+		e, err := doc.CreateElement("e")
+		require.NoError(t, err)
+		require.NoError(t, b.Replace(e))
+		require.NoError(t, a.AddChild(d))
+		helium.UnlinkNode(c)
+
+		require.Equal(t, mutationLog{
+			"insert root c",
+			"insert root d",
+			"remove root b",
+			"insert root e",
+			"remove root d",
+			"insert a d",
+			"remove root c",
+		}, *log)
+	})
+
+	t.Run("attributes", func(t *testing.T) {
+		t.Parallel()
+		doc, log := parse(t)
+		root := doc.DocumentElement()
+
+		require.NoError(t, root.SetAttribute("n", "1"))
+		require.NoError(t, root.SetAttribute("id", "r2"))
+		require.True(t, root.RemoveAttribute("n"))
+		require.False(t, root.RemoveAttribute("missing"))
+
+		require.Equal(t, mutationLog{
+			"attr-set root n",
+			"attr-set root id (r)",
+			"attr-remove root n (1)",
+		}, *log)
+	})
+
+	t.Run("text and namespaces", func(t *testing.T) {
+		t.Parallel()
+		doc, log := parse(t)
+		a := doc.DocumentElement().FirstChild().(*helium.Element)
+
+		require.NoError(t, a.AppendText([]byte(" two")))
+		require.NoError(t, a.DeclareNamespace("p", "urn:p"))
+		require.NoError(t, a.SetActiveNamespace("p", "urn:p"))
+		require.True(t, a.RemoveNamespaceByPrefix("p"))
+
+		require.Equal(t, mutationLog{
+			"text (text) (one)",
+			"ns a",
+			"ns p:a",
+			"ns p:a",
+		}, *log)
+	})
+
+	t.Run("moves across documents and cancel", func(t *testing.T) {
+		t.Parallel()
+		src, srcLog := parse(t)
+		dst, dstLog := parse(t)
+
+		a := src.DocumentElement().FirstChild()
+		require.NoError(t, dst.DocumentElement().AddChild(a))
+		require.Equal(t, mutationLog{"remove root a"}, *srcLog)
+		require.Equal(t, mutationLog{"insert root a"}, *dstLog)
+
+		var calls int
+		cancel := dst.Observe(func(helium.Mutation) { calls++ })
+		require.NoError(t, dst.DocumentElement().SetAttribute("x", "1"))
+		cancel()
+		require.NoError(t, dst.DocumentElement().SetAttribute("y", "1"))
+		require.Equal(t, 1, calls)
+		require.Len(t, *dstLog, 3)
+	})
+}
@@ -11,7 +11,7 @@ import (
 	"path/filepath"
 
 	"github.com/lestrrat-go/helium/internal/iofs"
-	"github.com/lestrrat-go/helium/push"
+	"github.com/lestrrat-go/helium/internal/strcursor"
 	"github.com/lestrrat-go/helium/sax"
 )
 
@@ -50,14 +50,21 @@ type parserConfig struct {
 	baseURI        string
 	catalog        CatalogResolver
 	fsys           fs.FS
+	resolver       ResourceResolver
+	cache          *ResourceCache
 	maxDepth       int
 	maxExtDTDSize  int
 	maxNameLength  int
 	maxEntityAmpl  int
 	maxCMDepth     int
 	maxNodeContent int
+	maxPushBuffer  int
 	errorHandler   ErrorHandler
 	xincludeProc   XIncludeProcessor
+	preserveLex    bool
+	trackPos       bool
+	retainSource   bool
+	parallelism    int
 }
 
 // XIncludeProcessor performs XInclude substitution on a parsed document,
@@ -261,6 +268,9 @@ func (p Parser) DefaultDTDAttributes(v bool) Parser {
 // want "validate against a DTD only when the document carries one" should check
 // for [ErrNoDTDFound] and treat it as success.
 //
+// To validate against a DTD the document does not reference, or a document
+// that was not parsed, use [DTDValidator].
+//
 // libxml2: XML_PARSE_DTDVALID
 // Default: false
 func (p Parser) ValidateDTD(v bool) Parser {
@@ -427,6 +437,9 @@ const (
 	// oversized one is a memory-amplification vector on untrusted input. The
 	// 10 MiB value mirrors the intent of libxml2's XML_MAX_TEXT_LENGTH.
 	DefaultMaxNodeContentSize = 10 << 20
+	// DefaultMaxPushBufferSize is the default cap, in bytes, on the input a
+	// PushParser holds while it waits for the end of a construct.
+	DefaultMaxPushBufferSize = 10 << 20
 )
 
 // MaxNameLength sets the maximum length, in bytes, of a single element,
@@ -489,6 +502,19 @@ func (p Parser) MaxNodeContentSize(n int) Parser {
 	return p
 }
 
+// MaxPushBufferBytes sets the maximum number of bytes a [PushParser] holds
+// while it waits for the end of a construct — a tag, a comment, a reference
+// — that a chunk starts but does not finish. A Push that leaves more than n
+// bytes held fails with [ErrPushBufferTooLarge]. A value of zero (the
+// default) uses [DefaultMaxPushBufferSize] (10 MiB); a negative value
+// removes the limit. A document held until Close because of its encoding
+// (see [PushParser]) is subject to the same limit.
+func (p Parser) MaxPushBufferBytes(n int) Parser {
+	p = p.clone()
+	p.cfg.maxPushBuffer = n
+	return p
+}
+
 // IgnoreEncoding controls whether the parser ignores the encoding
 // declaration inside the document and uses the transport-level encoding
 // instead.
@@ -679,6 +705,33 @@ func (p Parser) FS(fsys fs.FS) Parser {
 	return p
 }
 
+// ResourceResolver sets the [ResourceResolver] used to load external DTDs and
+// external entities. When set, it takes precedence over [Parser.FS]: every
+// load goes through it, under the ctx given to Parse, and the confined-FS
+// retry described there does not apply. Catalog mapping ([Parser.Catalog]) and
+// the network guard ([Parser.AllowNetwork]) still happen first, and loading
+// must still be enabled, e.g. with [Parser.LoadExternalDTD]. A nil value
+// restores loading through the FS.
+func (p Parser) ResourceResolver(r ResourceResolver) Parser {
+	p = p.clone()
+	p.cfg.resolver = r
+	return p
+}
+
+// ResourceCache sets a [ResourceCache] that memoizes external DTD subsets and
+// external entities across parses. A resource the cache holds under the same
+// [Parser.FS] or [Parser.ResourceResolver] is served without consulting it
+// again; one it does not is loaded through it and cached. An external subset
+// that no internal subset precedes is cached parsed, and served to parsers
+// with the same configuration whose SAX handler is a [TreeBuilder]. Catalog
+// mapping and the network guard still happen first. A nil value disables
+// caching.
+func (p Parser) ResourceCache(c *ResourceCache) Parser {
+	p = p.clone()
+	p.cfg.cache = c
+	return p
+}
+
 // ErrorHandler sets the handler that receives individual errors produced
 // during DTD validation ([ValidateDTD]); the returned error from Parse is
 // [ErrDTDValidationFailed] on failure. The handler is not consulted for
@@ -721,6 +774,72 @@ func (p Parser) XInclude(proc XIncludeProcessor) Parser {
 	return p
 }
 
+// PreserveLexical records the source markup of each parsed node that the DOM
+// does not keep: attribute quoting, order and spacing inside tags, character
+// and entity references, empty-element vs start/end tag form, and whitespace
+// outside the document element. A [Writer] with [Writer.PreserveLexical]
+// enabled reproduces any node that was not edited since parsing byte for byte,
+// so a targeted edit only changes the markup of the nodes it touches.
+//
+// The markup is recorded as each node is parsed; the document keeps only the
+// records, not the input. Recording requires the default [TreeBuilder]; with
+// a custom SAX handler nothing is recorded.
+// Default: false
+func (p Parser) PreserveLexical(v bool) Parser {
+	p = p.clone()
+	p.cfg.preserveLex = v
+	return p
+}
+
+// TrackPositions records where each element, attribute, text node, CDATA
+// section, comment and processing instruction starts and ends in the source:
+// line, column and byte offset. Retrieve them with [PositionOf]. Unlike
+// [Node.Line], which is the line the parser was on when it created the node,
+// a position spans the node's complete markup, and an attribute's position
+// also delimits its value.
+//
+// Positions are recorded as each node is parsed, as offsets relative to the
+// node's parent; the document keeps no copy of the input unless
+// [Parser.RetainSource] is also set. Like [Parser.PreserveLexical], this
+// requires the default [TreeBuilder].
+// Default: false
+func (p Parser) TrackPositions(v bool) Parser {
+	p = p.clone()
+	p.cfg.trackPos = v
+	return p
+}
+
+// RetainSource keeps the input, decoded to UTF-8, with the positions
+// recorded by [Parser.TrackPositions], so that [Parser.Reparse] can apply
+// edits to it. It has no effect without TrackPositions.
+// Default: false
+func (p Parser) RetainSource(v bool) Parser {
+	p = p.clone()
+	p.cfg.retainSource = v
+	return p
+}
+
+// Parallelism parses the content of large documents on up to n goroutines.
+// A quick pre-scan splits the children of the document element into chunks
+// at top-level boundaries; each chunk is parsed on its own, with the
+// namespaces declared on the document element in scope, and the resulting
+// subtrees are joined under the document element. The resulting document is
+// the same as that of a sequential parse.
+//
+// Only [Parser.Parse] and [Parser.ParseFile] parse in parallel, and only
+// when it is safe: the input is UTF-8 and at least a few megabytes, has no
+// document type declaration, and is parsed with the default [TreeBuilder]
+// without [Parser.RecoverOnError], [Parser.PreserveLexical] or
+// [Parser.TrackPositions]. Anything else, and any input that
+// produces a warning or error, is parsed sequentially, so diagnostics are
+// reported exactly as without this option. n <= 1 disables parallel parsing.
+// Default: 0 (sequential)
+func (p Parser) Parallelism(n int) Parser {
+	p = p.clone()
+	p.cfg.parallelism = n
+	return p
+}
+
 func (p Parser) closeHandler() {
 	if p.cfg != nil && p.cfg.errorHandler != nil {
 		if cl, ok := p.cfg.errorHandler.(io.Closer); ok {
@@ -790,10 +909,17 @@ func (p Parser) Parse(ctx context.Context, b []byte) (*Document, error) { //noli
 
 	p = p.normalized()
 
+	if p.cfg.parallelism > 1 {
+		if doc, ok, err := p.parseParallel(ctx, b); ok {
+			return doc, err
+		}
+	}
+
 	pctx := &parserCtx{rawInput: b, baseURI: p.cfg.baseURI}
 	if err := pctx.init(p.cfg, bytes.NewReader(b)); err != nil {
 		return nil, err
 	}
+	pctx.startRecording(p.cfg)
 	defer func() {
 		// Release the parser context; any error is intentionally ignored so it
 		// does not override the main return error.
@@ -816,6 +942,8 @@ func (p Parser) Parse(ctx context.Context, b []byte) (*Document, error) { //noli
 		return nil, err
 	}
 
+	pctx.finishRecording()
+
 	// Post-parse steps (XInclude substitution, DTD validation) on the built tree.
 	return p.finalize(ctx, pctx.doc)
 }
@@ -1024,6 +1152,7 @@ func (p Parser) parseReader(ctx context.Context, r io.Reader, srcSize int64) (*D
 	if err := pctx.init(p.cfg, stream); err != nil {
 		return nil, err
 	}
+	pctx.startRecording(p.cfg)
 	// init seeds inputSize from rawInput (nil here, so 0). When the caller
 	// knows the source size, set it so the amplification guard isn't tripped
 	// for a large internal entity referenced only once.
@@ -1051,6 +1180,8 @@ func (p Parser) parseReader(ctx context.Context, r io.Reader, srcSize int64) (*D
 		return nil, err
 	}
 
+	pctx.finishRecording()
+
 	return p.finalize(ctx, pctx.doc)
 }
 
@@ -1068,6 +1199,19 @@ func (p Parser) ParseFile(ctx context.Context, path string) (*Document, error) {
 	}
 	defer f.Close()
 
+	// A parallel parse needs the whole input up front.
+	if p.cfg != nil && p.cfg.parallelism > 1 {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("helium: failed to read %q: %w", path, err)
+		}
+		doc, err := p.BaseURI(abs).Parse(ctx, b)
+		if doc != nil {
+			doc.SetURL(abs)
+		}
+		return doc, err
+	}
+
 	// Pass the file size so the entity-amplification guard uses the real input
 	// size, matching Parse([]byte). Stat failure falls back to unknown (-1).
 	srcSize := int64(-1)
@@ -1097,6 +1241,12 @@ func (p Parser) ParseInNodeContext(ctx context.Context, node Node, data []byte)
 	}
 
 	p = p.normalized()
+	return p.parseInNodeContext(ctx, node, data, nil)
+}
+
+// parseInNodeContext is ParseInNodeContext, recording the fragment's source
+// with rec when it is not nil.
+func (p Parser) parseInNodeContext(ctx context.Context, node Node, data []byte, rec *sourceRecorder) (Node, error) {
 
 	// Reject both a literal nil interface and a typed-nil pointer (e.g. the
 	// *Element that Document.DocumentElement returns for a rootless document)
@@ -1174,6 +1324,10 @@ found:
 	if err := newctx.switchEncoding(); err != nil {
 		return nil, err
 	}
+	if u8, ok := newctx.getCursor().(*strcursor.UTF8Cursor); ok && rec != nil && newctx.treeBuilder != nil {
+		newctx.rec = rec
+		rec.attach(u8, nil)
+	}
 	innerCtx := withParserCtx(ctx, newctx)
 	innerCtx = sax.WithDocumentLocator(innerCtx, newctx)
 	innerCtx = context.WithValue(innerCtx, stopFuncKey{}, newctx.stop)
@@ -1218,17 +1372,3 @@ func collectInScopeNamespaces(elem *Element) []*Namespace {
 	}
 	return result
 }
-
-// PushParser provides an incremental XML parsing interface
-// (libxml2: xmlParserCtxt in push mode).
-// Data is pushed via Push or Write, and the parser processes tokens as
-// they become available in a background goroutine. Call [PushParser.Close]
-// to signal end-of-input and retrieve the parsed Document.
-type PushParser = push.Parser[*Document]
-
-// NewPushParser creates a PushParser using the given Parser's configuration.
-// The parser runs in a background goroutine, reading from the internal
-// stream as data is pushed.
-func (p Parser) NewPushParser(ctx context.Context) *PushParser {
-	return push.New[*Document](ctx, p)
-}
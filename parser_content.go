@@ -120,6 +120,8 @@ func (pctx *parserCtx) parsePI(ctx context.Context) error {
 	if cur == nil {
 		return pctx.error(ctx, errNoCursor)
 	}
+	m := pctx.recordMark()
+	defer pctx.recordNodes(m, false)
 	if !cur.ConsumeString("<?") {
 		return pctx.error(ctx, ErrInvalidProcessingInstruction)
 	}
@@ -317,6 +319,8 @@ func (pctx *parserCtx) parseCDSect(ctx context.Context) error {
 	if cur == nil {
 		return pctx.error(ctx, errNoCursor)
 	}
+	m := pctx.recordMark()
+	defer pctx.recordNodes(m, false)
 	if !cur.ConsumeString("<![CDATA[") {
 		return pctx.error(ctx, ErrInvalidCDSect)
 	}
@@ -364,6 +368,8 @@ func (pctx *parserCtx) parseComment(ctx context.Context) error {
 	if cur == nil {
 		return pctx.error(ctx, errNoCursor)
 	}
+	m := pctx.recordMark()
+	defer pctx.recordNodes(m, false)
 	if !cur.ConsumeString("<!--") {
 		return pctx.error(ctx, ErrInvalidComment)
 	}
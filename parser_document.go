@@ -11,13 +11,26 @@ import (
 )
 
 func (pctx *parserCtx) parseDocument(ctx context.Context) error {
-	// Store pctx in the context so SAX callbacks (e.g. TreeBuilder) can
-	// retrieve it via getParserCtx. Also store the document locator and
-	// stop function so helium.StopParser works.
+	ctx = pctx.documentContext(ctx)
+	if err := pctx.parseDocumentStart(ctx); err != nil {
+		return err
+	}
+	return pctx.parseDocumentBody(ctx)
+}
+
+// documentContext returns ctx carrying pctx, so SAX callbacks (e.g.
+// TreeBuilder) can retrieve it via getParserCtx, along with the document
+// locator and the stop function that helium.StopParser uses.
+func (pctx *parserCtx) documentContext(ctx context.Context) context.Context {
 	ctx = withParserCtx(ctx, pctx)
 	ctx = sax.WithDocumentLocator(ctx, pctx)
-	ctx = context.WithValue(ctx, stopFuncKey{}, pctx.stop)
+	return context.WithValue(ctx, stopFuncKey{}, pctx.stop)
+}
 
+// parseDocumentStart detects the encoding, parses the XML declaration,
+// switches the input to the document's encoding and reports the start of
+// the document.
+func (pctx *parserCtx) parseDocumentStart(ctx context.Context) error {
 	// Honor a context that is already cancelled before any parsing work
 	// (or blocking reads) begins.
 	if err := ctx.Err(); err != nil {
@@ -49,8 +62,8 @@ func (pctx *parserCtx) parseDocument(ctx context.Context) error {
 
 	// nothing left? eek
 	if bcur.Done() {
-		// An apparently empty document may instead be a cancelled read: the
-		// push parser's stream returns the context error from its blocking
+		// An apparently empty document may instead be a cancelled read: a
+		// context-aware reader returns the context error from its blocking
 		// wait, which the byte cursor records and Done() then masks. Surface
 		// cancellation (and any other sticky read error) instead of the
 		// misleading "empty document".
@@ -132,6 +145,10 @@ func (pctx *parserCtx) parseDocument(ctx context.Context) error {
 		}
 	}
 
+	if pctx.rec != nil {
+		pctx.attachRecorder()
+	}
+
 	// A leading byte-order mark asserts the entity's encoding; a contradicting
 	// encoding declaration is a fatal error (XML §4.3.3).
 	if err := pctx.checkBOMEncodingConflict(); err != nil {
@@ -148,11 +165,17 @@ func (pctx *parserCtx) parseDocument(ctx context.Context) error {
 			return pctx.error(ctx, err)
 		}
 	}
+	pctx.recordProlog()
 
 	if pctx.stopped {
 		return errParserStopped
 	}
+	return nil
+}
 
+// parseDocumentBody parses the rest of the document after
+// parseDocumentStart: the prolog, the document element and the epilogue.
+func (pctx *parserCtx) parseDocumentBody(ctx context.Context) error {
 	// Misc part of the prolog
 	if err := pctx.parseMisc(ctx); err != nil {
 		return pctx.error(ctx, err)
@@ -164,54 +187,9 @@ func (pctx *parserCtx) parseDocument(ctx context.Context) error {
 	}
 	// Doctype declarations and more misc
 	if cur.HasPrefixString("<!DOCTYPE") {
-		pctx.inSubset = inInternalSubset
-		if err := pctx.parseDocTypeDecl(ctx); err != nil {
-			return pctx.error(ctx, err)
-		}
-
-		if cur.HasPrefixString("[") {
-			pctx.instate = psDTD
-			if err := pctx.parseInternalSubset(ctx); err != nil {
-				return pctx.error(ctx, err)
-			}
-		}
-
-		// Query SAX callbacks for subset/standalone status.
-		// These mirror libxml2's calls after internal subset parsing.
-		if s := pctx.sax; s != nil {
-			if has, err := s.HasInternalSubset(ctx); err == nil {
-				_ = has // informational; handler may use for validation decisions
-			}
-			if has, err := s.HasExternalSubset(ctx); err == nil {
-				_ = has
-			}
-		}
-
-		pctx.inSubset = inExternalSubset
-		if s := pctx.sax; s != nil {
-			switch err := s.ExternalSubset(ctx, pctx.intSubName, pctx.extSubSystem, pctx.extSubURI); err {
-			case nil, sax.ErrHandlerUnspecified:
-				// no op
-			default:
-				return pctx.error(ctx, err)
-			}
-		}
-		if pctx.instate == psEOF {
-			return pctx.error(ctx, errors.New("unexpected EOF"))
-		}
-		pctx.inSubset = notInSubset
-
-		// The whole DTD is parsed now, so the entity tables are complete. Re-check
-		// every attribute default value's WFCs to catch a nested external/unparsed
-		// (or '<') entity reached through a FORWARD-referenced entity that was not
-		// yet declared when the default was parsed (SubstituteEntities(false)).
-		if err := pctx.validateAttributeDefaultsWFC(ctx); err != nil {
+		if err := pctx.parseDoctype(ctx); err != nil {
 			return err
 		}
-
-		pctx.cleanSpecialAttributes()
-
-		pctx.instate = psPrologue
 		if err := pctx.parseMisc(ctx); err != nil {
 			return pctx.error(ctx, err)
 		}
@@ -251,6 +229,12 @@ func (pctx *parserCtx) parseDocument(ctx context.Context) error {
 	if !cur.Done() {
 		return pctx.error(ctx, ErrDocumentEnd)
 	}
+	return pctx.parseDocumentEnd(ctx)
+}
+
+// parseDocumentEnd checks that the input ended cleanly and reports the end of
+// the document.
+func (pctx *parserCtx) parseDocumentEnd(ctx context.Context) error {
 	// A clean Done() may mask a transcoding/decode error (e.g. an unpaired
 	// UTF-16 surrogate the decoder replaced with U+FFFD). Surface it as a fatal
 	// error, so truncated or malformed encoded input is never accepted.
@@ -274,6 +258,66 @@ func (pctx *parserCtx) parseDocument(ctx context.Context) error {
 	return nil
 }
 
+// parseDoctype parses the document type declaration, with its internal and
+// external subsets, at the cursor.
+func (pctx *parserCtx) parseDoctype(ctx context.Context) error {
+	cur := pctx.getCursor()
+	if cur == nil {
+		return pctx.error(ctx, errNoCursor)
+	}
+	m := pctx.recordMark()
+	defer pctx.recordNodes(m, false)
+	pctx.inSubset = inInternalSubset
+	if err := pctx.parseDocTypeDecl(ctx); err != nil {
+		return pctx.error(ctx, err)
+	}
+
+	if cur.HasPrefixString("[") {
+		pctx.instate = psDTD
+		if err := pctx.parseInternalSubset(ctx); err != nil {
+			return pctx.error(ctx, err)
+		}
+	}
+
+	// Query SAX callbacks for subset/standalone status.
+	// These mirror libxml2's calls after internal subset parsing.
+	if s := pctx.sax; s != nil {
+		if has, err := s.HasInternalSubset(ctx); err == nil {
+			_ = has // informational; handler may use for validation decisions
+		}
+		if has, err := s.HasExternalSubset(ctx); err == nil {
+			_ = has
+		}
+	}
+
+	pctx.inSubset = inExternalSubset
+	if s := pctx.sax; s != nil {
+		switch err := s.ExternalSubset(ctx, pctx.intSubName, pctx.extSubSystem, pctx.extSubURI); err {
+		case nil, sax.ErrHandlerUnspecified:
+			// no op
+		default:
+			return pctx.error(ctx, err)
+		}
+	}
+	if pctx.instate == psEOF {
+		return pctx.error(ctx, errors.New("unexpected EOF"))
+	}
+	pctx.inSubset = notInSubset
+
+	// The whole DTD is parsed now, so the entity tables are complete. Re-check
+	// every attribute default value's WFCs to catch a nested external/unparsed
+	// (or '<') entity reached through a FORWARD-referenced entity that was not
+	// yet declared when the default was parsed (SubstituteEntities(false)).
+	if err := pctx.validateAttributeDefaultsWFC(ctx); err != nil {
+		return err
+	}
+
+	pctx.cleanSpecialAttributes()
+
+	pctx.instate = psPrologue
+	return nil
+}
+
 func (pctx *parserCtx) parseContent(ctx context.Context) error {
 	pctx.instate = psContent
 
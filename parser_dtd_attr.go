@@ -541,9 +541,9 @@ func (ctx *parserCtx) addAttributeDefault(elemName, attrName, defaultValue strin
 		return
 	}
 
-	attr.SetDefault(true)
+	attr.defaultAttr = true
 	if decl := lookupAttributeDecl(ctx.doc, local, prefix, elemName); decl != nil {
-		attr.SetAType(decl.AType())
+		attr.atype = decl.AType()
 	}
 	ctx.attsDefault[elemName] = append(existing, attr)
 }
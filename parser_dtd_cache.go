@@ -0,0 +1,149 @@
+package helium
+
+import (
+	"io/fs"
+	"maps"
+	"strings"
+
+	"github.com/lestrrat-go/helium/enum"
+)
+
+// parserCachePolicy is the policy a parser's [ResourceCache] entries are
+// scoped to: what loads external resources, and for an fs.FS the directory of
+// the document base its confined-FS retry relativizes against.
+type parserCachePolicy struct {
+	resolver ResourceResolver
+	fsys     fs.FS
+	base     string
+}
+
+func (ctx *parserCtx) cachePolicy() parserCachePolicy {
+	if ctx.resolver != nil {
+		return parserCachePolicy{resolver: ctx.resolver}
+	}
+	base := ctx.documentBaseURI
+	if i := strings.LastIndexAny(base, `/\`); i >= 0 {
+		base = base[:i+1]
+	}
+	return parserCachePolicy{fsys: ctx.fsys, base: base}
+}
+
+// extSubsetCachePolicy scopes a parsed external subset: besides the loader,
+// everything that changes how its declarations parse.
+type extSubsetCachePolicy struct {
+	loader         parserCachePolicy
+	catalog        CatalogResolver
+	options        parseOption
+	loadsubset     LoadSubsetOption
+	version        string
+	standalone     DocumentStandaloneType
+	maxAmpl        int
+	maxNameLength  int
+	maxCMDepth     int
+	maxExtDTDSize  int
+	maxNodeContent int
+}
+
+// parsedExtSubset is the cached form of an external subset: its declarations,
+// in a document of their own, and what parsing them recorded in the parser.
+type parsedExtSubset struct {
+	doc              *Document
+	hasPERefs        bool
+	hasExternalPERef bool
+	defaults         []attrDefault
+	special          map[specialAttrKey]enum.AttributeType
+	specialExternal  map[specialAttrKey]struct{}
+}
+
+// attrDefault is a default attribute value an ATTLIST declaration recorded.
+type attrDefault struct {
+	elem, name, value string
+}
+
+// extSubsetCacheable reports whether the external subset about to be loaded
+// parses the same for every document that refers to it under the same
+// policy: the tree builder t receives the declarations, so no other handler
+// observes them, and no internal subset has declared anything they could
+// override.
+func (ctx *parserCtx) extSubsetCacheable(t *TreeBuilder) bool {
+	if ctx.cache == nil || ctx.treeBuilder != t || ctx.doc == nil {
+		return false
+	}
+	if dtd := ctx.doc.intSubset; dtd != nil && dtd.FirstChild() != nil {
+		return false
+	}
+	return !ctx.hasPERefs && len(ctx.attsDefault) == 0 && len(ctx.attsSpecial) == 0
+}
+
+func (ctx *parserCtx) extSubsetCachePolicy() extSubsetCachePolicy {
+	return extSubsetCachePolicy{
+		loader:         ctx.cachePolicy(),
+		catalog:        ctx.catalog,
+		options:        ctx.options,
+		loadsubset:     ctx.loadsubset,
+		version:        ctx.version,
+		standalone:     ctx.standalone,
+		maxAmpl:        ctx.maxAmpl,
+		maxNameLength:  ctx.maxNameLength,
+		maxCMDepth:     ctx.maxCMDepth,
+		maxExtDTDSize:  ctx.maxExtDTDSize,
+		maxNodeContent: ctx.maxNodeContent,
+	}
+}
+
+// loadCachedExtSubset installs the external subset cached for name as the
+// document's, as parsing it would have. It reports false when none is cached.
+func (ctx *parserCtx) loadCachedExtSubset(policy extSubsetCachePolicy, name, dtdName, eid, uri string) bool {
+	key, ok := policyKey(policy)
+	if !ok {
+		return false
+	}
+	v, ok := ctx.cache.getParsed(cacheKey{policy: key, name: name, parsed: true})
+	if !ok {
+		return false
+	}
+	parsed, ok := v.(*parsedExtSubset)
+	if !ok {
+		return false
+	}
+
+	CopyExtSubset(parsed.doc, ctx.doc)
+	dtd := ctx.doc.extSubset
+	dtd.name = dtdName
+	dtd.externalID = eid
+	dtd.systemID = uri
+
+	ctx.hasPERefs = ctx.hasPERefs || parsed.hasPERefs
+	ctx.hasExternalPERef = ctx.hasExternalPERef || parsed.hasExternalPERef
+	// Defaults first: addAttributeDefault skips attributes already recorded as
+	// special, which the parse recorded after their defaults.
+	for _, d := range parsed.defaults {
+		ctx.addAttributeDefault(d.elem, d.name, d.value)
+	}
+	maps.Copy(ctx.attsSpecial, parsed.special)
+	maps.Copy(ctx.attsSpecialExternal, parsed.specialExternal)
+	return true
+}
+
+// storeExtSubset caches the external subset the parser has just parsed from
+// name, of size bytes.
+func (ctx *parserCtx) storeExtSubset(policy extSubsetCachePolicy, name string, size int) {
+	key, ok := policyKey(policy)
+	if !ok {
+		return
+	}
+	parsed := &parsedExtSubset{
+		doc:              NewDocument(ctx.doc.version, "", StandaloneImplicitNo),
+		hasPERefs:        ctx.hasPERefs,
+		hasExternalPERef: ctx.hasExternalPERef,
+		special:          maps.Clone(ctx.attsSpecial),
+		specialExternal:  maps.Clone(ctx.attsSpecialExternal),
+	}
+	CopyExtSubset(ctx.doc, parsed.doc)
+	for elem, attrs := range ctx.attsDefault {
+		for _, a := range attrs {
+			parsed.defaults = append(parsed.defaults, attrDefault{elem: elem, name: a.Name(), value: a.Value()})
+		}
+	}
+	ctx.cache.putParsed(cacheKey{policy: key, name: name, parsed: true}, policy, parsed, int64(size))
+}
@@ -0,0 +1,95 @@
+package helium
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/lestrrat-go/helium/internal/iolimit"
+	"github.com/lestrrat-go/helium/sax"
+)
+
+// ParseDTD parses a standalone DTD — the content of an external subset, such
+// as a .dtd file — with a default [Parser]. See [Parser.ParseDTD].
+func ParseDTD(ctx context.Context, r io.Reader) (*DTD, error) {
+	return NewParser().ParseDTD(ctx, r)
+}
+
+// ParseDTD parses a standalone DTD — the content of an external subset, such
+// as a .dtd file — read from r, and returns it detached from any document
+// (libxml2: xmlIOParseDTD). The result can be passed to [NewDTDValidator] to
+// validate documents that do not reference it themselves.
+//
+// The DTD is parsed as an external subset: it may begin with a text
+// declaration, and parameter-entity references and conditional sections are
+// expanded. External parameter entities are resolved against [Parser.BaseURI]
+// through the parser's [Parser.FS], [Parser.ResourceResolver] and
+// [Parser.Catalog], under the same limits as an external subset loaded during
+// a parse, including [Parser.MaxExternalDTDBytes] for r itself. A SAX handler
+// set with [Parser.SAXHandler] is not used: the declarations always build a
+// [DTD].
+func (p Parser) ParseDTD(ctx context.Context, r io.Reader) (*DTD, error) { //nolint:contextcheck
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	p = p.normalized()
+
+	limit := int64(resolveLimit(p.cfg.maxExtDTDSize, MaxExternalDTDSize))
+	if limit <= 0 {
+		limit = math.MaxInt64
+	}
+	data, exceeded, err := iolimit.ReadAll(r, limit)
+	if exceeded {
+		return nil, ErrExternalDTDTooLarge
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	pctx := &parserCtx{baseURI: p.cfg.baseURI}
+	if err := pctx.init(p.cfg, bytes.NewReader(nil)); err != nil {
+		return nil, err
+	}
+	defer func() {
+		// Release the parser context; any error is intentionally ignored so it
+		// does not override the main return error.
+		_ = pctx.release()
+	}()
+	tb := NewTreeBuilder()
+	pctx.sax = tb
+	pctx.treeBuilder = tb
+
+	// Declarations are recorded into the external subset of a scratch
+	// document, exactly as during a parse, and detached from it afterwards.
+	doc := NewDocument("1.0", "", StandaloneImplicitNo)
+	dtd := newDTD()
+	dtd.systemID = p.cfg.baseURI
+	dtd.doc = doc
+	doc.extSubset = dtd
+	pctx.doc = doc
+	pctx.inSubset = inExternalSubset
+	pctx.instate = psDTD
+
+	innerCtx := withParserCtx(ctx, pctx)
+	innerCtx = sax.WithDocumentLocator(innerCtx, pctx)
+	innerCtx = context.WithValue(innerCtx, stopFuncKey{}, pctx.stop)
+	if err := innerCtx.Err(); err != nil {
+		return nil, err
+	}
+	if err := pctx.parseExternalSubsetContent(innerCtx, p.cfg.baseURI, data); err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, pctx.error(innerCtx, err)
+	}
+	if err := pctx.validateAttributeDefaultsWFC(innerCtx); err != nil {
+		return nil, err
+	}
+
+	doc.extSubset = nil
+	dtd.doc = nil
+	return dtd, nil
+}
@@ -409,6 +409,78 @@ func (pctx *parserCtx) checkCondSectionEntityBoundary(ctx context.Context, secti
 		fmt.Errorf("%w: all markup of the conditional section is not in the same entity", ErrEntityBoundary))
 }
 
+// parseExternalSubsetContent parses data, the full content of an external DTD
+// subset read from resolved, into the document's external subset, which the
+// caller has already created. It is shared by TreeBuilder.ExternalSubset and
+// Parser.ParseDTD.
+func (pctx *parserCtx) parseExternalSubsetContent(ctx context.Context, resolved string, data []byte) error {
+	// An external subset may begin with a TextDecl
+	// ('<?xml' VersionInfo? EncodingDecl S? '?>'). Consume it (and honor any
+	// declared encoding) before the declaration loop, which would otherwise
+	// reject the '<?xml' as a processing instruction whose target may not be
+	// "xml". This is the same treatment external parameter/general entities get.
+	data, textDeclVersion, err := pctx.decodeExternalPEContentVersion(ctx, resolved, data)
+	if err != nil {
+		return err
+	}
+
+	// Parse markup declarations from the DTD content.
+	// Push content onto the input stack and loop until exhausted.
+	savedExternal := pctx.external
+	savedBaseURI := pctx.baseURI
+	savedDTDInputFloor := pctx.dtdInputFloor
+	pctx.external = true
+	pctx.baseURI = resolved
+
+	baseLen := pctx.inputTab.Len()
+	pctx.pushInputWithVersion(strcursor.NewByteCursor(bytes.NewReader(data)), textDeclVersion)
+	// The DTD cursor we just pushed is the enclosing content cursor for the
+	// shared declaration step: it lives one level above baseLen.
+	dtdFloor := pctx.inputTab.Len()
+	// skipBlanksPE expands parameter-entity references inside/adjacent to markup
+	// declarations by pushing their padded replacement text ABOVE this cursor and
+	// crossing back when the PE input is spent; it must never pop below this base
+	// (into the main document input), so record its depth as the floor.
+	pctx.dtdInputFloor = dtdFloor
+
+	// Restore parser state on every exit path, including the error returns
+	// below, and ensure our pushed input is always removed from the stack.
+	defer func() {
+		for pctx.inputTab.Len() > baseLen {
+			pctx.popInput()
+		}
+		pctx.external = savedExternal
+		pctx.baseURI = savedBaseURI
+		pctx.dtdInputFloor = savedDTDInputFloor
+	}()
+
+	// Parse the external subset declaration-by-declaration through the SHARED
+	// step used for INCLUDE-section bodies (parseExternalSubsetDeclStep), so a
+	// parameter-entity reference expands identically in both contexts: a
+	// blank-only skip (NOT skipBlanks, whose handlePEReference would consume a
+	// "%pe;" reference without expanding it), explicit parsePEReference
+	// expansion, spent-cursor cleanup, and a forward-progress guard that surfaces
+	// a malformed "<!BOGUS" while the external DTD cursor/baseURI are still
+	// active (so its location, not the main doctype's, is reported). A malformed
+	// or unterminated conditional section propagates as a fatal error.
+	for pctx.inputTab.Len() > baseLen {
+		top := pctx.adaptCursor(pctx.inputTab.PeekOne())
+		if top == nil || top.Done() {
+			break
+		}
+
+		stop, err := pctx.parseExternalSubsetDeclStep(ctx, dtdFloor)
+		if err != nil {
+			return err
+		}
+		if stop {
+			break
+		}
+	}
+
+	return nil
+}
+
 // popSpentExternalSubsetInputs pops any exhausted (Done) parameter-entity or
 // conditional-section cursors that sit above baseLen on the input stack, so the
 // next declaration resumes in the parent DTD where the expanded content left
@@ -29,6 +29,8 @@ func (pctx *parserCtx) parseCharData(ctx context.Context, cdata bool) error {
 		_, err := pctx.parseCDataContent()
 		return err
 	}
+	m := pctx.recordMark()
+	defer pctx.recordNodes(m, false)
 	return pctx.parseCharDataContent(ctx)
 }
 
@@ -81,7 +83,7 @@ func (pctx *parserCtx) parseCharDataContent(ctx context.Context) error {
 		// Keep the grown buffer for next call.
 		pctx.charBuf = data
 
-		if pctx.areBlanksBytes(data, false) {
+		if !pctx.textContinues && pctx.areBlanksBytes(data, false) {
 			if pctx.treeBuilder != nil && !pctx.disableSAX {
 				if err := pctx.fastIgnorableWhitespace(data); err != nil {
 					return err
@@ -128,7 +130,7 @@ func (pctx *parserCtx) parseCharDataContent(ctx context.Context) error {
 	}
 
 	data := buf.Bytes()
-	if pctx.areBlanksBytes(data, false) {
+	if !pctx.textContinues && pctx.areBlanksBytes(data, false) {
 		if pctx.treeBuilder != nil && !pctx.disableSAX {
 			if err := pctx.fastIgnorableWhitespace(data); err != nil {
 				return err
@@ -197,7 +199,7 @@ func (pctx *parserCtx) parseCharDataChunkedSAX(ctx context.Context, u8 *strcurso
 	// blank tracks whether the run could still be ignorable whitespace. When the
 	// context makes whitespace non-ignorable, it starts false so the first chunk
 	// commits to Characters immediately (no blank-prefix accumulation).
-	blank := pctx.whitespaceContextIgnorable()
+	blank := !pctx.textContinues && pctx.whitespaceContextIgnorable()
 
 	acc := pctx.charBuf[:0]
 	first := true
@@ -432,6 +434,7 @@ func (pctx *parserCtx) parseStartTag(ctx context.Context) error {
 	if cur.Peek() != '<' {
 		return pctx.error(ctx, ErrStartTagRequired)
 	}
+	m := pctx.recordMark()
 	if err := cur.Advance(1); err != nil {
 		return err
 	}
@@ -481,6 +484,7 @@ func (pctx *parserCtx) parseStartTag(ctx context.Context) error {
 		if cur.Peek() == '/' && cur.PeekAt(1) == '>' {
 			break
 		}
+		attrStart := pctx.recordOffset()
 		attname, aprefix, attvalue, err := pctx.parseAttribute(ctx, elemQName)
 		if err != nil {
 			return pctx.error(ctx, err)
@@ -597,6 +601,7 @@ func (pctx *parserCtx) parseStartTag(ctx context.Context) error {
 		}
 
 		attrs = append(attrs, attr)
+		pctx.recordAttribute(attrStart)
 
 		// XML §3.1 P40/P44: attributes in a start/empty-element tag must be
 		// separated by whitespace (STag/EmptyElemTag: '(S Attribute)*'). After
@@ -742,6 +747,7 @@ func (pctx *parserCtx) parseStartTag(ctx context.Context) error {
 		return pctx.namespaceError(ctx, errors.New("namespace '"+prefix+"' not found"))
 	}
 
+	pctx.startTagEmpty = cur.Peek() == '/' && cur.PeekAt(1) == '>'
 	if pctx.treeBuilder != nil && !pctx.disableSAX {
 		if err := pctx.fastStartElement(local, prefix, nsuri, attrs, nbNs); err != nil {
 			return pctx.error(ctx, err)
@@ -768,6 +774,7 @@ func (pctx *parserCtx) parseStartTag(ctx context.Context) error {
 			return pctx.error(ctx, err)
 		}
 	}
+	pctx.recordStartTag(m)
 	qname := local
 	if prefix != "" {
 		qname = prefix + ":" + local
@@ -794,6 +801,7 @@ func (pctx *parserCtx) parseEndTag(ctx context.Context) error {
 	if cur == nil {
 		return pctx.error(ctx, errNoCursor)
 	}
+	m := pctx.recordMark()
 	if cur.Peek() == '/' && cur.PeekAt(1) == '>' {
 		if err := cur.Advance(2); err != nil {
 			return err
@@ -845,6 +853,7 @@ func (pctx *parserCtx) parseEndTag(ctx context.Context) error {
 		}
 	}
 
+	pctx.recordEndTag(m)
 	return nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"unicode/utf8"
 
 	"github.com/lestrrat-go/helium/internal/encoding"
@@ -207,20 +208,27 @@ func decodeRuneAt(cur strcursor.Cursor, offset int) (rune, int, bool) {
 	return r, w, true
 }
 
-func (ctx *parserCtx) switchEncoding() error {
-	encName := ctx.encoding
-	if encName == "" {
-		encName = ctx.detectedEncoding
-		if encName == "" {
-			encName = "utf8"
-		}
+// inputEncodingName returns the encoding the document entity is decoded with:
+// the declared encoding, else the detected one, else UTF-8.
+func (ctx *parserCtx) inputEncodingName() string {
+	if ctx.encoding != "" {
+		return ctx.encoding
 	}
+	if ctx.detectedEncoding != "" {
+		return ctx.detectedEncoding
+	}
+	return "utf8"
+}
+
+func (ctx *parserCtx) switchEncoding() error {
+	encName := ctx.inputEncodingName()
 
 	if encoding.IsUTF8(encName) {
 		cur := ctx.getByteCursor()
 		if cur == nil {
 			return ErrByteCursorRequired
 		}
+		ctx.notePrefix(cur)
 		ctx.popInput()
 		ctx.pushInput(strcursor.NewUTF8Cursor(cur))
 		return nil
@@ -236,7 +244,13 @@ func (ctx *parserCtx) switchEncoding() error {
 		return ErrByteCursorRequired
 	}
 
-	b := enc.NewDecoder().Reader(cur)
+	ctx.notePrefix(cur)
+	var b io.Reader
+	if ctx.pushIn != nil {
+		b = newPushDecoder(enc.NewDecoder(), cur, ctx.pushIn)
+	} else {
+		b = enc.NewDecoder().Reader(cur)
+	}
 	ctx.popInput()
 	ctx.pushInput(strcursor.NewUTF8Cursor(b))
 
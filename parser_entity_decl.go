@@ -837,6 +837,8 @@ func (pctx *parserCtx) inheritNestedParserState(newctx *parserCtx) {
 	// catalog, and base URI as the top-level parse, with no fallback to
 	// the permissive os.Open root.
 	newctx.fsys = pctx.fsys
+	newctx.resolver = pctx.resolver
+	newctx.cache = pctx.cache
 	newctx.catalog = pctx.catalog
 	newctx.baseURI = pctx.baseURI
 	// Carry the fixed top-level document base so a confined-FS retry inside a
@@ -19,6 +19,8 @@ func (pctx *parserCtx) parseReference(ctx context.Context) error {
 	if cur == nil {
 		return pctx.error(ctx, errNoCursor)
 	}
+	m := pctx.recordMark()
+	defer pctx.recordNodes(m, true)
 	if cur.Peek() != '&' {
 		return pctx.error(ctx, ErrAmpersandRequired)
 	}
@@ -225,6 +227,7 @@ func (pctx *parserCtx) replayEntityNode(ctx context.Context, n Node) error {
 			attrs = append(attrs, attr)
 		}
 
+		pctx.startTagEmpty = false
 		switch err := pctx.sax.StartElementNS(ctx, v.LocalName(), v.Prefix(), v.URI(), namespaces, attrs); err {
 		case nil, sax.ErrHandlerUnspecified:
 		default:
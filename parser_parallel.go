@@ -0,0 +1,411 @@
+package helium
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/lestrrat-go/helium/internal/encoding"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/sax"
+)
+
+// parallelMinChunk is the smallest amount of document element content, in
+// bytes, worth handing to a goroutine of its own. Inputs too small to yield
+// two such chunks are parsed sequentially.
+const parallelMinChunk = 1 << 20
+
+// parallelChunksPerWorker oversubscribes the workers so that a chunk holding
+// unusually dense markup does not leave the others idle.
+const parallelChunksPerWorker = 4
+
+// parallelPlan is the result of pre-scanning a document for a parallel
+// parse.
+type parallelPlan struct {
+	contentStart int   // first byte after the document element's start tag
+	contentEnd   int   // first byte of the document element's end tag
+	cuts         []int // chunk boundaries within the content, in order
+}
+
+// planParallel finds the document element of src and the points between its
+// top-level children at which the content can be split into chunks of at
+// least chunkSize bytes. It reports false for input the pre-scan does not
+// handle: a document type declaration, or anything that is not well-formed
+// enough to scan. Such input is parsed sequentially, which also produces the
+// appropriate errors.
+func planParallel(src []byte, chunkSize int) (parallelPlan, bool) {
+	var plan parallelPlan
+	i := 0
+	if bytes.HasPrefix(src, utf8BOM) {
+		i = len(utf8BOM)
+	}
+
+	// Prolog: only the XML declaration, comments and processing instructions.
+prolog:
+	for {
+		for i < len(src) && isBlankByte(src[i]) {
+			i++
+		}
+		rest := src[i:]
+		switch {
+		case len(rest) == 0 || rest[0] != '<':
+			return plan, false
+		case bytes.HasPrefix(rest, []byte("<?")):
+			end := bytes.Index(rest, []byte("?>"))
+			if end < 0 {
+				return plan, false
+			}
+			i += end + 2
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end := bytes.Index(rest[4:], []byte("-->"))
+			if end < 0 {
+				return plan, false
+			}
+			i += end + 7
+		case bytes.HasPrefix(rest, []byte("<!")):
+			return plan, false
+		default:
+			break prolog
+		}
+	}
+
+	end, ok := scanTagEnd(src[i:])
+	if !ok || src[i+end-2] == '/' {
+		return plan, false
+	}
+	i += end
+	plan.contentStart = i
+
+	depth := 0
+	last := i
+	for {
+		lt := bytes.IndexByte(src[i:], '<')
+		if lt < 0 {
+			return plan, false
+		}
+		i += lt
+		rest := src[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end := bytes.Index(rest[4:], []byte("-->"))
+			if end < 0 {
+				return plan, false
+			}
+			i += end + 7
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			end := bytes.Index(rest, []byte("]]>"))
+			if end < 0 {
+				return plan, false
+			}
+			i += end + 3
+		case bytes.HasPrefix(rest, []byte("<?")):
+			end := bytes.Index(rest, []byte("?>"))
+			if end < 0 {
+				return plan, false
+			}
+			i += end + 2
+		case bytes.HasPrefix(rest, []byte("<!")):
+			return plan, false
+		case bytes.HasPrefix(rest, []byte("</")):
+			if depth == 0 {
+				plan.contentEnd = i
+				return plan, true
+			}
+			end := bytes.IndexByte(rest, '>')
+			if end < 0 {
+				return plan, false
+			}
+			i += end + 1
+			depth--
+		default:
+			end, ok := scanTagEnd(rest)
+			if !ok {
+				return plan, false
+			}
+			i += end
+			if rest[end-2] != '/' {
+				depth++
+			}
+		}
+		if depth == 0 && i-last >= chunkSize {
+			plan.cuts = append(plan.cuts, i)
+			last = i
+		}
+	}
+}
+
+// parseParallel parses b by splitting the content of its document element
+// across goroutines (see [Parser.Parallelism]). It reports false, having
+// done nothing observable, when the input or the configuration does not
+// allow a parallel parse, or when any part of the input produced a
+// diagnostic; the caller then parses sequentially so that diagnostics are
+// reported exactly as usual.
+func (p Parser) parseParallel(ctx context.Context, b []byte) (*Document, bool, error) {
+	return p.parseChunked(ctx, b, parallelMinChunk)
+}
+
+// parseChunked is parseParallel with chunks of at least minChunk bytes.
+func (p Parser) parseChunked(ctx context.Context, b []byte, minChunk int) (*Document, bool, error) {
+	workers := p.cfg.parallelism
+	if _, ok := p.cfg.sax.(*TreeBuilder); !ok || p.cfg.options.IsSet(parseRecover) {
+		return nil, false, nil
+	}
+	if p.cfg.preserveLex || p.cfg.trackPos {
+		// Source records are taken while a single parser reads the document.
+		return nil, false, nil
+	}
+	if len(b) < 2*minChunk {
+		return nil, false, nil
+	}
+	plan, ok := planParallel(b, max(len(b)/(workers*parallelChunksPerWorker), minChunk))
+	if !ok || len(plan.cuts) == 0 {
+		return nil, false, nil
+	}
+	if last := plan.cuts[len(plan.cuts)-1]; last == plan.contentEnd {
+		plan.cuts = plan.cuts[:len(plan.cuts)-1]
+	}
+
+	// The skeleton is the document with the content of its document element
+	// removed. It is parsed first so that the prolog, the start tag and any
+	// namespace declarations on it are checked as usual.
+	q := p.clone()
+	q.cfg.parallelism = 0
+	q.cfg.xincludeProc = nil
+	q.cfg.options.Clear(parseDTDValid)
+	acc := &errorAccumulator{}
+	q.cfg.errorHandler = acc
+
+	skeleton := make([]byte, 0, plan.contentStart+len(b)-plan.contentEnd)
+	skeleton = append(skeleton, b[:plan.contentStart]...)
+	skeleton = append(skeleton, b[plan.contentEnd:]...)
+	doc, err := q.Parse(ctx, skeleton)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, true, err
+		}
+		return nil, false, nil
+	}
+	if len(acc.collectErrors()) > 0 || doc.intSubset != nil || doc.extSubset != nil {
+		return nil, false, nil
+	}
+	if enc := doc.Encoding(); enc != "" && !encoding.IsUTF8(enc) {
+		return nil, false, nil
+	}
+	root := doc.DocumentElement()
+	scope := collectInScopeNamespaces(root)
+	space := -1
+	if v, ok := root.GetAttributeNS("space", lexicon.NamespaceXML); ok {
+		switch v {
+		case "preserve":
+			space = 1
+		case "default":
+			space = 0
+		}
+	}
+
+	bounds := make([]int, 0, len(plan.cuts)+2)
+	bounds = append(bounds, plan.contentStart)
+	bounds = append(bounds, plan.cuts...)
+	bounds = append(bounds, plan.contentEnd)
+	chunks := make([]parallelChunk, len(bounds)-1)
+	line := bytes.Count(b[:plan.contentStart], []byte{'\n'})
+	for i := range chunks {
+		chunks[i].data = b[bounds[i]:bounds[i+1]]
+		chunks[i].lines = line
+		line += bytes.Count(chunks[i].data, []byte{'\n'})
+	}
+	// The last chunk keeps the "</" of the end tag of the document element,
+	// where its parse stops, so that whitespace before it is classified
+	// with the end tag in view.
+	last := &chunks[len(chunks)-1]
+	last.data = b[bounds[len(bounds)-2] : plan.contentEnd+2]
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for range min(workers, len(chunks)) {
+		wg.Go(func() {
+			for i := range next {
+				chunks[i].parse(ctx, q, doc, scope, space, i > 0)
+			}
+		})
+	}
+	for i := range chunks {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for i := range chunks {
+		if err := chunks[i].err; err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil, true, err
+			}
+			return nil, false, nil
+		}
+	}
+
+	if q.cfg.options.IsSet(parseNoBlanks) && space != 1 {
+		// Once the document element starts with text, no whitespace in its
+		// content is ignorable; the other chunks cannot know that.
+		if _, ok := chunks[0].first.(*Text); ok {
+			return nil, false, nil
+		}
+	}
+
+	for i := range chunks {
+		c := &chunks[i]
+		for n := c.first; n != nil; {
+			next := n.NextSibling()
+			if err := root.AddChild(n); err != nil {
+				return nil, true, err
+			}
+			n = next
+		}
+		for id, e := range c.doc.ids {
+			doc.registerID(id, e)
+		}
+	}
+	// Nodes after the document element were parsed without its content.
+	contentLines := line - bytes.Count(b[:plan.contentStart], []byte{'\n'})
+	for n := root.NextSibling(); n != nil; n = n.NextSibling() {
+		shiftLines(n, contentLines)
+	}
+
+	doc, err = p.finalize(ctx, doc)
+	return doc, true, err
+}
+
+// parallelChunk is a run of top-level children of the document element,
+// parsed on its own.
+type parallelChunk struct {
+	data  []byte
+	lines int // newlines in the input before data
+
+	doc   *Document // the document the chunk was parsed into
+	first Node      // first parsed node; siblings follow
+	err   error
+}
+
+// parse parses the chunk into a document of its own, as content of an
+// element declaring the namespaces in scope and standing in for the
+// document element at depth 1 with its xml:space setting, then moves the
+// result to owner. A chunk that follows another starts after a placeholder
+// child, so that whitespace is classified as it is after the nodes of the
+// previous chunk. Any diagnostic fails the chunk.
+func (c *parallelChunk) parse(ctx context.Context, p Parser, owner *Document, scope []*Namespace, space int, follows bool) {
+	doc := NewDocument("1.0", "", StandaloneImplicitNo)
+	doc.idsSkip = owner.idsSkip
+	c.doc = doc
+	acc := &errorAccumulator{}
+	cfg := *p.cfg
+	cfg.errorHandler = acc
+
+	pctx := &parserCtx{rawInput: c.data}
+	if err := pctx.init(&cfg, bytes.NewReader(c.data)); err != nil {
+		c.err = err
+		return
+	}
+	defer func() {
+		_ = pctx.release()
+	}()
+	pctx.doc = doc
+
+	holder, err := doc.CreateElement(pseudoRootName)
+	if err != nil {
+		c.err = err
+		return
+	}
+	for _, ns := range scope {
+		if err := holder.DeclareNamespace(ns.Prefix(), ns.URI()); err != nil {
+			c.err = err
+			return
+		}
+		pctx.pushNS(ns.Prefix(), ns.URI())
+	}
+	if err := doc.AddChild(holder); err != nil {
+		c.err = err
+		return
+	}
+	content, err := doc.CreateElement(pseudoRootName)
+	if err != nil {
+		c.err = err
+		return
+	}
+	if err := holder.AddChild(content); err != nil {
+		c.err = err
+		return
+	}
+	var placeholder Node
+	if follows {
+		placeholder = doc.CreateComment(nil)
+		if err := content.AddChild(placeholder); err != nil {
+			c.err = err
+			return
+		}
+	}
+	pctx.pushNodeEntry(nodeEntry{local: pseudoRootName, qname: pseudoRootName, synthetic: true})
+	pctx.elem = content
+	pctx.elemDepth = 1
+	pctx.spaceTab = append(pctx.spaceTab, space)
+
+	if err := pctx.switchEncoding(); err != nil {
+		c.err = err
+		return
+	}
+	innerCtx := withParserCtx(ctx, pctx)
+	innerCtx = sax.WithDocumentLocator(innerCtx, pctx)
+	innerCtx = context.WithValue(innerCtx, stopFuncKey{}, pctx.stop)
+	if err := pctx.parseContent(innerCtx); err != nil {
+		c.err = err
+		return
+	}
+	if len(acc.collectErrors()) > 0 {
+		c.err = errParallelDiagnostic
+		return
+	}
+
+	c.first = content.FirstChild()
+	if placeholder != nil {
+		c.first = placeholder.NextSibling()
+	}
+	for n := c.first; n != nil; n = n.NextSibling() {
+		shiftLines(n, c.lines)
+		n.(MutableNode).SetTreeDoc(owner) //nolint:forcetypeassert
+	}
+}
+
+// errParallelDiagnostic fails a chunk that produced a warning or error, so
+// that the document is parsed again sequentially and reports it in context.
+var errParallelDiagnostic = errors.New("chunk produced a diagnostic")
+
+// shiftLines adds delta to the line numbers of n and its descendants.
+func shiftLines(n Node, delta int) {
+	dn := n.baseDocNode()
+	if dn.line > 0 {
+		dn.line += delta
+	}
+	for c := range Children(n) {
+		shiftLines(c, delta)
+	}
+}
+
+// scanTagEnd returns the length of the start tag at the start of b, skipping
+// over quoted attribute values.
+func scanTagEnd(b []byte) (int, bool) {
+	var quote byte
+	for i := 1; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
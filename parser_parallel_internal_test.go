@@ -0,0 +1,116 @@
+package helium
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanParallel(t *testing.T) {
+	t.Run("cuts between top-level children", func(t *testing.T) {
+		const src = `<?xml version="1.0"?><!-- c --><r a='>'><x y="/>"><z/></x><!-- </r> --><w/><![CDATA[</r>]]><v>t</v></r>`
+		plan, ok := planParallel([]byte(src), 1)
+		require.True(t, ok)
+		require.Equal(t, `<x y="/>"><z/></x>`, src[plan.contentStart:plan.cuts[0]])
+		require.Equal(t, "</r>", src[plan.contentEnd:])
+		var parts []string
+		prev := plan.contentStart
+		for _, cut := range plan.cuts {
+			parts = append(parts, src[prev:cut])
+			prev = cut
+		}
+		require.Equal(t, []string{`<x y="/>"><z/></x>`, `<!-- </r> -->`, `<w/>`, `<![CDATA[</r>]]>`, `<v>t</v>`}, parts)
+	})
+
+	t.Run("unsupported input", func(t *testing.T) {
+		for _, src := range []string{
+			`<!DOCTYPE r><r><a/></r>`,
+			`<r/>`,
+			`<r><a/>`,
+			"\xff\xfe<\x00r\x00/\x00>\x00",
+		} {
+			_, ok := planParallel([]byte(src), 1)
+			require.False(t, ok, src)
+		}
+	})
+}
+
+func TestParseParallelUsed(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString(`<r xmlns:p="urn:p">`)
+	for sb.Len() < 3*parallelMinChunk {
+		sb.WriteString(`<p:a b="c">text</p:a>`)
+	}
+	sb.WriteString(`</r>`)
+
+	doc, ok, err := NewParser().Parallelism(4).normalized().parseParallel(t.Context(), []byte(sb.String()))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "urn:p", doc.DocumentElement().FirstChild().(*Element).URI())
+}
+
+// TestParseChunkedCorpus parses the libxml2 test documents and a few crafted
+// ones in chunks as small as the content allows, and checks that every
+// chunked parse that goes ahead builds the document a sequential parse
+// builds.
+func TestParseChunkedCorpus(t *testing.T) {
+	t.Parallel()
+
+	inputs := map[string][]byte{
+		"root xml:space":      []byte("<r xml:space='preserve'>\n  <a/>\n  <b/>\n</r>"),
+		"trailing whitespace": []byte("<r>\n  <a/>\n  <b> </b>\n  <c/>\n</r>"),
+		"whitespace chunk":    []byte("<r><a/><b/>\n\n</r>"),
+		"mixed root":          []byte("<r>text\n<a/>\n<b/>\n</r>"),
+		"depth":               []byte("<r><a><b/></a><a><b/></a></r>"),
+	}
+	files, err := filepath.Glob("testdata/libxml2-compat/*")
+	require.NoError(t, err)
+	for _, file := range files {
+		if filepath.Ext(file) != "" && filepath.Ext(file) != ".xml" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		inputs[file] = data
+	}
+
+	configs := map[string]Parser{
+		"default":      NewParser(),
+		"strip blanks": NewParser().StripBlanks(true),
+		"max depth":    NewParser().MaxDepth(2),
+	}
+	var n int
+	for name, data := range inputs {
+		for cname, p := range configs {
+			want, wantErr := p.Parse(t.Context(), data)
+			got, ok, err := p.Parallelism(4).normalized().parseChunked(t.Context(), data, 1)
+			if !ok {
+				continue
+			}
+			n++
+			require.NoError(t, wantErr, "%s (%s)", name, cname)
+			require.NoError(t, err, "%s (%s)", name, cname)
+			ws, err := WriteString(want)
+			require.NoError(t, err)
+			gs, err := WriteString(got)
+			require.NoError(t, err)
+			require.Equal(t, ws, gs, "%s (%s)", name, cname)
+
+			var lines func(w, g Node)
+			lines = func(w, g Node) {
+				require.Equal(t, w.Line(), g.Line(), "%s (%s): %s", name, cname, w.Name())
+				wc, gc := w.FirstChild(), g.FirstChild()
+				for ; wc != nil && gc != nil; wc, gc = wc.NextSibling(), gc.NextSibling() {
+					lines(wc, gc)
+				}
+			}
+			lines(want, got)
+		}
+	}
+	require.NotZero(t, n)
+}
@@ -0,0 +1,96 @@
+package helium_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/stretchr/testify/require"
+)
+
+// largeDocument builds a document big enough to be parsed in parallel.
+func largeDocument(records int) string {
+	var sb strings.Builder
+	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!-- export -->\n")
+	sb.WriteString("<export xmlns=\"urn:export\" xmlns:x=\"urn:x\">\n")
+	for i := range records {
+		fmt.Fprintf(&sb, "  <record xml:id=\"r%d\" x:n='%d'>\n    <name>item &amp; %d</name>\n", i, i, i)
+		if i%7 == 0 {
+			fmt.Fprintf(&sb, "    <!-- note %d --><?pi %d?><![CDATA[<raw>]]>\n", i, i)
+		}
+		sb.WriteString("    <empty/>\n  </record>\n")
+	}
+	sb.WriteString("</export>\n<!-- end -->\n")
+	return sb.String()
+}
+
+func TestParallelism(t *testing.T) {
+	t.Parallel()
+
+	src := largeDocument(50000)
+	require.Greater(t, len(src), 4<<20)
+
+	want, err := helium.NewParser().Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+
+	t.Run("same document as a sequential parse", func(t *testing.T) {
+		t.Parallel()
+		got, err := helium.NewParser().Parallelism(4).Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+
+		ws, err := helium.WriteString(want)
+		require.NoError(t, err)
+		gs, err := helium.WriteString(got)
+		require.NoError(t, err)
+		require.Equal(t, ws, gs)
+
+		var lines func(w, g helium.Node)
+		lines = func(w, g helium.Node) {
+			require.Equal(t, w.Line(), g.Line(), "%s", w.Name())
+			wc, gc := w.FirstChild(), g.FirstChild()
+			for ; wc != nil && gc != nil; wc, gc = wc.NextSibling(), gc.NextSibling() {
+				lines(wc, gc)
+			}
+		}
+		lines(want, got)
+
+		e := got.GetElementByID("r49999")
+		require.NotNil(t, e)
+		require.Same(t, got, e.OwnerDocument())
+		v, ok := e.GetAttributeNS("n", "urn:x")
+		require.True(t, ok)
+		require.Equal(t, "49999", v)
+		require.Equal(t, "urn:export", e.URI())
+	})
+
+	t.Run("positions", func(t *testing.T) {
+		t.Parallel()
+		got, err := helium.NewParser().Parallelism(4).TrackPositions(true).Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		e := got.GetElementByID("r20000")
+		pos, ok := helium.PositionOf(e)
+		require.True(t, ok)
+		require.True(t, strings.HasPrefix(src[pos.Start.Offset:], `<record xml:id="r20000"`))
+	})
+
+	t.Run("errors match a sequential parse", func(t *testing.T) {
+		t.Parallel()
+		bad := strings.Replace(src, "<name>item &amp; 30000</name>", "<name>item & 30000</name>", 1)
+		_, wantErr := helium.NewParser().Parse(t.Context(), []byte(bad))
+		require.Error(t, wantErr)
+		_, gotErr := helium.NewParser().Parallelism(4).Parse(t.Context(), []byte(bad))
+		require.Error(t, gotErr)
+		require.Equal(t, wantErr.Error(), gotErr.Error())
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		doc, err := helium.NewParser().Parallelism(4).Parse(ctx, []byte(src))
+		require.ErrorIs(t, err, context.Canceled)
+		require.Nil(t, doc)
+	})
+}
@@ -0,0 +1,984 @@
+package helium
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/lestrrat-go/helium/internal/encoding"
+	"golang.org/x/text/transform"
+)
+
+// pushTextChunk is the size a pending character-data run reaches before a
+// PushParser delivers the part it has instead of waiting for the run's end.
+const pushTextChunk = 4096
+
+// PushParser parses XML that arrives in chunks (libxml2: xmlParserCtxt in
+// push mode, driven by xmlParseChunk). Each [PushParser.Push] parses as far
+// as the bytes pushed so far allow and returns once it has, so SAX events
+// fire, and errors surface, during the call that completes the construct
+// that causes them. The parser keeps its place between calls; no goroutine
+// runs in the background.
+//
+// The only input a PushParser holds is the start of a construct — a tag, a
+// comment, a reference — whose end has not arrived yet; a long run of
+// character data is delivered in pieces. [PushParser.Buffered] reports how
+// many bytes that is, and [Parser.MaxPushBufferBytes] caps it. Because Push
+// does the parsing work before it returns, a caller feeding many streams
+// from one event loop is paced by the parser and never queues input behind
+// it.
+//
+// A document in an encoding whose multibyte sequences can reuse ASCII bytes
+// (EBCDIC, Shift_JIS, Big5, GBK, ISO-2022-JP, HZ) cannot be split at markup
+// without decoding it first; such a document is held until
+// [PushParser.Close] and parsed then.
+//
+// A PushParser is not safe for concurrent use.
+type PushParser struct {
+	p Parser
+	// ctx is stored because the parse spans calls to Push and Close, which
+	// take no context of their own.
+	ctx    context.Context //nolint:containedctx
+	pctx   *parserCtx
+	in     *pushInput
+	state  pushState
+	layout pushLayout
+	mark   int      // document offset of the next construct to parse
+	scan   pushScan // progress of the search for the end of that construct
+	depth  int      // elements open
+	// textChunks allows a long character-data run to be delivered before its
+	// end has arrived, which is only safe in UTF-8.
+	textChunks bool
+	// inText marks that the last construct parsed was part of a
+	// character-data run that has not ended.
+	inText  bool
+	doctype bool
+	limit   int
+	err     error
+	closed  bool
+	doc     *Document
+}
+
+type pushState int
+
+const (
+	pushStart    pushState = iota // before the XML declaration
+	pushProlog                    // before the document element
+	pushContent                   // inside the document element
+	pushEpilogue                  // after the document element
+	pushBuffered                  // held for Close
+)
+
+// pushToken is the kind of construct at the start of the unparsed input.
+type pushToken int
+
+const (
+	pushTokenBlanks pushToken = iota + 1
+	pushTokenText
+	pushTokenTextPart // the first part of a character-data run not yet ended
+	pushTokenReference
+	pushTokenStartTag
+	pushTokenEndTag
+	pushTokenComment
+	pushTokenPI
+	pushTokenCDATA
+	pushTokenDoctype
+)
+
+// pushScan records how far the search for the end of the next construct has
+// got, so each Push scans only the bytes it added.
+type pushScan struct {
+	from  int    // code units scanned
+	quote byte   // the quote of the literal being scanned, or 0
+	depth int    // '[' nesting in a DOCTYPE
+	skip  string // terminator of the comment or PI skipped in a DOCTYPE
+}
+
+// pushLayout describes how the document encodes ASCII: in code units of
+// width bytes, the character's byte at index pos and the others zero.
+type pushLayout struct {
+	width int
+	pos   int
+}
+
+// pushInput is the reader under a PushParser's cursor. It holds the bytes
+// pushed but not yet read by the cursor, and hands out only those up to
+// avail, the end of the last complete construct, reporting io.EOF past it.
+// The cursors do not treat io.EOF as sticky, so they read on once avail
+// moves.
+type pushInput struct {
+	buf    []byte // the bytes from document offset base on
+	base   int
+	read   int  // document offset the cursor has read up to
+	avail  int  // document offset the cursor may read up to
+	closed bool // no more bytes will be pushed
+}
+
+func (in *pushInput) Read(p []byte) (int, error) {
+	if in.read >= in.avail {
+		return 0, io.EOF
+	}
+	n := copy(p, in.buf[in.read-in.base:in.avail-in.base])
+	in.read += n
+	return n, nil
+}
+
+// end returns the document offset after the last byte pushed.
+func (in *pushInput) end() int {
+	return in.base + len(in.buf)
+}
+
+// from returns the bytes pushed from document offset off on.
+func (in *pushInput) from(off int) []byte {
+	return in.buf[off-in.base:]
+}
+
+// release lets the cursor read up to document offset off.
+func (in *pushInput) release(off int) {
+	in.avail = max(in.avail, off)
+}
+
+// discard drops the bytes before document offset off.
+func (in *pushInput) discard(off int) {
+	n := off - in.base
+	if n <= 0 {
+		return
+	}
+	in.buf = in.buf[:copy(in.buf, in.buf[n:])]
+	in.base = off
+}
+
+// pushView presents the unparsed input as one byte per code unit: the ASCII
+// character the unit encodes, or 0x80 for any other character.
+type pushView struct {
+	b []byte
+	pushLayout
+}
+
+func (v pushView) len() int {
+	return len(v.b) / v.width
+}
+
+func (v pushView) at(i int) byte {
+	if v.width == 1 {
+		return v.b[i]
+	}
+	u := v.b[i*v.width : (i+1)*v.width]
+	for j, c := range u {
+		if j != v.pos && c != 0 {
+			return 0x80
+		}
+	}
+	if c := u[v.pos]; c < 0x80 {
+		return c
+	}
+	return 0x80
+}
+
+// index returns the index of the first unit from from on that is one of
+// chars, or -1.
+func (v pushView) index(from int, chars string) int {
+	if v.width == 1 {
+		if i := bytes.IndexAny(v.b[from:], chars); i >= 0 {
+			return from + i
+		}
+		return -1
+	}
+	for i := from; i < v.len(); i++ {
+		for j := range len(chars) {
+			if v.at(i) == chars[j] {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// indexString returns the index of the first occurrence of s from from on,
+// or -1.
+func (v pushView) indexString(from int, s string) int {
+	for i := from; i+len(s) <= v.len(); i++ {
+		if v.hasPrefixAt(i, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (v pushView) hasPrefixAt(i int, s string) bool {
+	if i+len(s) > v.len() {
+		return false
+	}
+	for j := range len(s) {
+		if v.at(i+j) != s[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// startsLike reports whether v starts with s, or with a part of s that
+// more input may complete.
+func (v pushView) startsLike(s string) bool {
+	n := min(v.len(), len(s))
+	for j := range n {
+		if v.at(j) != s[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewPushParser creates a PushParser using the given Parser's configuration.
+// See [PushParser] for how it consumes its input.
+func (p Parser) NewPushParser(ctx context.Context) *PushParser { //nolint:contextcheck
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p = p.normalized()
+
+	in := &pushInput{}
+	pctx := &parserCtx{baseURI: p.cfg.baseURI, pushIn: in}
+	pp := &PushParser{
+		p:      p,
+		pctx:   pctx,
+		in:     in,
+		layout: pushLayout{width: 1},
+		limit:  resolveLimit(p.cfg.maxPushBuffer, DefaultMaxPushBufferSize),
+	}
+	if err := pctx.init(p.cfg, in); err != nil {
+		pp.err = err
+	}
+	pctx.startRecording(p.cfg)
+	pp.ctx = pctx.documentContext(ctx)
+	return pp
+}
+
+// Push parses the next chunk of the document. It returns once every
+// construct the chunk completes has been parsed, with the first error the
+// document has caused; after an error, Push returns that error again.
+// Pushing after [PushParser.Close] returns [io.ErrClosedPipe].
+func (pp *PushParser) Push(chunk []byte) error {
+	if pp.closed {
+		return io.ErrClosedPipe
+	}
+	if pp.err != nil {
+		if errors.Is(pp.err, errParserStopped) {
+			// StopParser ends the parse; the rest of the input is ignored.
+			return nil
+		}
+		return pp.err
+	}
+	if err := pp.ctx.Err(); err != nil {
+		pp.err = err
+		return err
+	}
+
+	pp.in.buf = append(pp.in.buf, chunk...)
+	if err := pp.parse(false); err != nil {
+		pp.err = err
+		if errors.Is(err, errParserStopped) {
+			return nil
+		}
+		return err
+	}
+	if pp.limit > 0 && pp.Buffered() > pp.limit {
+		pp.err = pp.pctx.error(pp.ctx, ErrPushBufferTooLarge)
+		return pp.err
+	}
+	return nil
+}
+
+// Write implements io.Writer, allowing use with io.Copy and similar
+// functions. It is Push, reporting the whole of p as written on success.
+func (pp *PushParser) Write(p []byte) (int, error) {
+	if err := pp.Push(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Buffered returns the number of bytes pushed that the parser holds because
+// the construct they start has not ended yet.
+func (pp *PushParser) Buffered() int {
+	return pp.in.end() - pp.mark
+}
+
+// Close signals end-of-input, parses what remains, and returns the parsed
+// Document. Its results follow those of [Parser.Parse]. It is idempotent:
+// subsequent calls return the same result.
+func (pp *PushParser) Close() (*Document, error) {
+	if pp.closed {
+		return pp.doc, pp.err
+	}
+	pp.closed = true
+	pp.in.closed = true
+	defer func() {
+		_ = pp.pctx.release()
+	}()
+
+	err := pp.err
+	if err == nil {
+		err = pp.ctx.Err()
+	}
+	if err == nil {
+		err = pp.parse(true)
+	}
+	pp.doc, pp.err = pp.result(err)
+	return pp.doc, pp.err
+}
+
+// result turns the outcome of the parse into the document and error Close
+// returns, as Parser.ParseReader does.
+func (pp *PushParser) result(err error) (*Document, error) {
+	pctx := pp.pctx
+	if err != nil {
+		if errors.Is(err, errParserStopped) {
+			return pctx.doc, nil
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		if pp.p.cfg.options.IsSet(parseRecover) {
+			return pctx.doc, err
+		}
+		return nil, err
+	}
+	pctx.finishRecording()
+	return pp.p.finalize(pp.ctx, pctx.doc)
+}
+
+// parse parses the complete constructs in the input. With final set the
+// input is complete, and parse finishes the document.
+func (pp *PushParser) parse(final bool) error {
+	pctx := pp.pctx
+	defer func() {
+		if pp.state != pushStart && pp.state != pushBuffered {
+			pp.in.discard(min(pp.in.read, pp.mark))
+		}
+	}()
+
+	for {
+		if err := pp.ctx.Err(); err != nil {
+			return err
+		}
+		if pctx.stopped {
+			return errParserStopped
+		}
+
+		switch pp.state {
+		case pushStart:
+			ok, err := pp.parseStart(final)
+			if err != nil || !ok {
+				return err
+			}
+			continue
+		case pushBuffered:
+			if !final {
+				return nil
+			}
+			return pp.parseBuffered()
+		}
+
+		v := pushView{b: pp.in.from(pp.mark), pushLayout: pp.layout}
+		tok, n, ok := pp.next(v, final)
+		if !ok {
+			if final {
+				return pp.finish()
+			}
+			return nil
+		}
+
+		end := pp.mark + n*pp.layout.width
+		avail := end
+		if (tok == pushTokenText || tok == pushTokenBlanks) && n < v.len() {
+			// Character data is classified by the character after it, so
+			// let the cursor see that too.
+			avail += pp.layout.width
+		}
+		pp.in.release(avail)
+		pctx.inputSize = int64(pp.in.avail)
+		pp.mark = end
+		pp.scan = pushScan{}
+
+		var err error
+		switch pp.state {
+		case pushProlog:
+			err = pp.parseProlog(tok)
+		case pushContent:
+			err = pp.parseContent(tok)
+		case pushEpilogue:
+			err = pp.parseEpilogue(tok)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseStart runs parseDocumentStart once the input holds the XML
+// declaration, or shows there is none. It reports whether it ran.
+func (pp *PushParser) parseStart(final bool) (bool, error) {
+	pctx := pp.pctx
+	b := pp.in.from(pp.mark)
+	if len(b) < 4 && !final {
+		return false, nil
+	}
+	if bytes.HasPrefix(b, patEBCDIC) {
+		pp.state = pushBuffered
+		return true, nil
+	}
+
+	var bom int
+	pp.layout, bom = sniffPushLayout(b)
+	v := pushView{b: b[bom:], pushLayout: pp.layout}
+	end := bom + min(v.len(), 4)*pp.layout.width
+	decl := false
+	switch {
+	case v.len() >= 6 && v.hasPrefixAt(0, "<?xml") && isBlankByte(v.at(5)):
+		i := v.indexString(max(pp.scan.from, 6), "?>")
+		if i < 0 {
+			if !final {
+				pp.scan.from = max(6, v.len()-1)
+				return false, nil
+			}
+			end = len(b)
+		} else {
+			end = bom + (i+2)*pp.layout.width
+		}
+		decl = true
+	case v.len() < 6 && v.startsLike("<?xml") && !final:
+		return false, nil
+	}
+	pp.in.release(end)
+	pp.scan = pushScan{}
+
+	if err := pctx.parseDocumentStart(pp.ctx); err != nil {
+		return false, err
+	}
+
+	pp.mark = bom
+	if decl {
+		pp.mark = end
+	}
+	name := pctx.inputEncodingName()
+	if pp.layout.width == 1 && !encoding.IsASCIITransparent(name) {
+		pp.state = pushBuffered
+		return true, nil
+	}
+	pp.textChunks = pp.layout.width == 1 && encoding.IsUTF8(name)
+	pp.state = pushProlog
+	return true, nil
+}
+
+// sniffPushLayout returns how the document starting with b encodes ASCII,
+// and the length of its byte-order mark, as detectEncoding finds them.
+func sniffPushLayout(b []byte) (pushLayout, int) {
+	switch fixedWidthUnicodeEncoding(b) {
+	case encUCS4BE:
+		return pushLayout{width: 4, pos: 3}, 0
+	case encUCS4LE:
+		return pushLayout{width: 4, pos: 0}, 0
+	case encUCS42143:
+		return pushLayout{width: 4, pos: 2}, 0
+	case encUCS43412:
+		return pushLayout{width: 4, pos: 1}, 0
+	case encUTF16LE:
+		if bytes.HasPrefix(b, patUTF16LE2B) {
+			return pushLayout{width: 2, pos: 0}, len(patUTF16LE2B)
+		}
+		return pushLayout{width: 2, pos: 0}, 0
+	case encUTF16BE:
+		if bytes.HasPrefix(b, patUTF16BE2B) {
+			return pushLayout{width: 2, pos: 1}, len(patUTF16BE2B)
+		}
+		return pushLayout{width: 2, pos: 1}, 0
+	}
+	if bytes.HasPrefix(b, patUTF8) {
+		return pushLayout{width: 1}, len(patUTF8)
+	}
+	return pushLayout{width: 1}, 0
+}
+
+// parseBuffered parses a document held for Close.
+func (pp *PushParser) parseBuffered() error {
+	pctx := pp.pctx
+	pp.in.release(pp.in.end())
+	pctx.inputSize = int64(pp.in.avail)
+	if pctx.doc == nil && pctx.instate == psStart {
+		// The encoding was not ASCII-compatible, so nothing has been parsed:
+		// parse the whole document as Parser.Parse does.
+		pctx.rawInput = pp.in.buf
+		return pctx.parseDocument(pp.ctx)
+	}
+	return pctx.parseDocumentBody(pp.ctx)
+}
+
+// next finds the construct at the start of v. It returns its kind and its
+// length in code units, or false when its end has not been pushed yet.
+// With final set, a construct that runs to the end of the input ends there.
+func (pp *PushParser) next(v pushView, final bool) (pushToken, int, bool) {
+	n := v.len()
+	if n == 0 {
+		return 0, 0, false
+	}
+	st := &pp.scan
+
+	switch c := v.at(0); {
+	case c == '<':
+		if n < 2 {
+			return pp.incomplete(pushTokenStartTag, n, final)
+		}
+		switch v.at(1) {
+		case '?':
+			return pp.until(v, pushTokenPI, "?>", 2, final)
+		case '/':
+			return pp.until(v, pushTokenEndTag, ">", 2, final)
+		case '!':
+			switch {
+			case v.hasPrefixAt(0, "<!--"):
+				return pp.until(v, pushTokenComment, "-->", 4, final)
+			case v.hasPrefixAt(0, "<![CDATA["):
+				return pp.until(v, pushTokenCDATA, "]]>", 9, final)
+			case v.hasPrefixAt(0, "<!DOCTYPE"):
+				return pp.doctypeEnd(v, final)
+			case v.startsLike("<!--"), v.startsLike("<![CDATA["), v.startsLike("<!DOCTYPE"):
+				return pp.incomplete(pushTokenStartTag, n, final)
+			}
+		}
+		return pp.tagEnd(v, final)
+	case c == '&':
+		i := v.index(max(st.from, 1), ";<& \t\r\n")
+		switch {
+		case i >= 0 && v.at(i) == ';':
+			return pushTokenReference, i + 1, true
+		case i >= 0:
+			// Not a reference; the parser reports it.
+			return pushTokenReference, i, true
+		}
+		st.from = n
+		return pp.incomplete(pushTokenReference, n, final)
+	case isBlankByte(c) && pp.state != pushContent:
+		i := 1
+		for i < n && isBlankByte(v.at(i)) {
+			i++
+		}
+		return pushTokenBlanks, i, true
+	}
+
+	i := v.index(st.from, "<&")
+	if i >= 0 {
+		return pushTokenText, i, true
+	}
+	st.from = n
+	if final {
+		return pushTokenText, n, true
+	}
+	if end := pp.textPrefix(v.b); end > 0 {
+		return pushTokenTextPart, end, true
+	}
+	return 0, 0, false
+}
+
+// incomplete returns a construct of kind running to the end of the input,
+// if the input is complete.
+func (pp *PushParser) incomplete(kind pushToken, n int, final bool) (pushToken, int, bool) {
+	if final {
+		return kind, n, true
+	}
+	return 0, 0, false
+}
+
+// until finds a construct of kind that ends with term, searching from unit
+// start on.
+func (pp *PushParser) until(v pushView, kind pushToken, term string, start int, final bool) (pushToken, int, bool) {
+	from := max(pp.scan.from, start)
+	if i := v.indexString(from, term); i >= 0 {
+		return kind, i + len(term), true
+	}
+	pp.scan.from = max(start, v.len()-len(term)+1)
+	return pp.incomplete(kind, v.len(), final)
+}
+
+// tagEnd finds the '>' that ends a start tag, outside its attribute values.
+func (pp *PushParser) tagEnd(v pushView, final bool) (pushToken, int, bool) {
+	st := &pp.scan
+	for i := max(st.from, 1); i < v.len(); i++ {
+		c := v.at(i)
+		switch {
+		case st.quote != 0:
+			if c == st.quote {
+				st.quote = 0
+			}
+		case c == '"' || c == '\'':
+			st.quote = c
+		case c == '>':
+			return pushTokenStartTag, i + 1, true
+		}
+	}
+	st.from = v.len()
+	return pp.incomplete(pushTokenStartTag, v.len(), final)
+}
+
+// doctypeEnd finds the '>' that ends a document type declaration, outside
+// its literals, internal subset, comments and processing instructions.
+func (pp *PushParser) doctypeEnd(v pushView, final bool) (pushToken, int, bool) {
+	st := &pp.scan
+	i := max(st.from, len("<!DOCTYPE"))
+	for i < v.len() {
+		c := v.at(i)
+		switch {
+		case st.skip != "":
+			if v.hasPrefixAt(i, st.skip) {
+				i += len(st.skip)
+				st.skip = ""
+				continue
+			}
+			if i+len(st.skip) > v.len() {
+				st.from = i
+				return pp.incomplete(pushTokenDoctype, v.len(), final)
+			}
+		case st.quote != 0:
+			if c == st.quote {
+				st.quote = 0
+			}
+		case c == '"' || c == '\'':
+			st.quote = c
+		case c == '[':
+			st.depth++
+		case c == ']':
+			st.depth--
+		case c == '<' && st.depth > 0:
+			if i+4 > v.len() {
+				st.from = i
+				return pp.incomplete(pushTokenDoctype, v.len(), final)
+			}
+			switch {
+			case v.hasPrefixAt(i, "<!--"):
+				st.skip = "-->"
+				i += 4
+				continue
+			case v.hasPrefixAt(i, "<?"):
+				st.skip = "?>"
+				i += 2
+				continue
+			}
+		case c == '>' && st.depth <= 0:
+			return pushTokenDoctype, i + 1, true
+		}
+		i++
+	}
+	st.from = i
+	return pp.incomplete(pushTokenDoctype, v.len(), final)
+}
+
+// textPrefix returns how much of the UTF-8 character data b, whose end has
+// not been pushed yet, can be delivered now: once the run is long enough,
+// everything up to its last non-blank character. A carriage return, a ']'
+// and a split character wait for what follows them, which may change them.
+func (pp *PushParser) textPrefix(b []byte) int {
+	if !pp.textChunks || len(b) < pushTextChunk {
+		return 0
+	}
+	end := len(b)
+	for end > 0 {
+		c := b[end-1]
+		if c == ']' || isBlankByte(c) {
+			end--
+			continue
+		}
+		if c >= utf8.RuneSelf {
+			i := end - 1
+			for i > 0 && end-i < utf8.UTFMax && !utf8.RuneStart(b[i]) {
+				i--
+			}
+			if !utf8.FullRune(b[i:end]) {
+				end = i
+				continue
+			}
+		}
+		break
+	}
+	return end
+}
+
+// parseProlog parses a construct before the document element.
+func (pp *PushParser) parseProlog(tok pushToken) error {
+	pctx := pp.pctx
+	ctx := pp.ctx
+	switch tok {
+	case pushTokenBlanks, pushTokenComment, pushTokenPI:
+		return pp.parseMisc(tok)
+	case pushTokenDoctype:
+		if !pp.doctype {
+			pp.doctype = true
+			return pctx.parseDoctype(ctx)
+		}
+	case pushTokenText, pushTokenTextPart, pushTokenReference:
+		if err := pctx.cursorDecodeErr(); err != nil {
+			return pctx.error(ctx, err)
+		}
+		return pctx.error(ctx, ErrEmptyDocument)
+	}
+	// Anything else that starts with '<' is the document element, and
+	// parseStartTag rejects what is not a start tag.
+	pctx.instate = psContent
+	pp.state = pushContent
+	return pp.parseContent(pushTokenStartTag)
+}
+
+// parseEpilogue parses a construct after the document element.
+func (pp *PushParser) parseEpilogue(tok pushToken) error {
+	switch tok {
+	case pushTokenBlanks, pushTokenComment, pushTokenPI:
+		return pp.parseMisc(tok)
+	}
+	return pp.pctx.error(pp.ctx, ErrDocumentEnd)
+}
+
+// parseMisc parses whitespace, a comment or a processing instruction
+// outside the document element, as parseMisc does.
+func (pp *PushParser) parseMisc(tok pushToken) error {
+	pctx := pp.pctx
+	ctx := pp.ctx
+	var err error
+	switch tok {
+	case pushTokenComment:
+		err = pctx.parseComment(ctx)
+	case pushTokenPI:
+		err = pctx.parsePI(ctx)
+	default:
+		pctx.skipBlanks(ctx)
+		err = pctx.blankRunErr
+	}
+	if err != nil {
+		return pctx.error(ctx, err)
+	}
+	return nil
+}
+
+// parseContent parses a construct inside the document element, as
+// parseContent does, recovering from an error when the parser is set to.
+func (pp *PushParser) parseContent(tok pushToken) error {
+	pctx := pp.pctx
+	ctx := pp.ctx
+
+	var err error
+	switch tok {
+	case pushTokenText, pushTokenTextPart:
+		err = pp.charData()
+		pp.inText = tok == pushTokenTextPart
+	case pushTokenReference:
+		err = pctx.parseReference(ctx)
+	case pushTokenEndTag:
+		err = pp.endElement()
+	case pushTokenComment:
+		err = pctx.parseComment(ctx)
+	case pushTokenPI:
+		err = pctx.parsePI(ctx)
+	case pushTokenCDATA:
+		err = pctx.parseCDSect(ctx)
+	default:
+		err = pp.startElement()
+	}
+	if tok != pushTokenText && tok != pushTokenTextPart {
+		pp.inText = false
+	}
+	if err == nil {
+		return nil
+	}
+
+	if !pctx.options.IsSet(parseRecover) || isParseAbort(err) {
+		if tok == pushTokenText || tok == pushTokenTextPart {
+			return err
+		}
+		return pctx.error(ctx, err)
+	}
+	if pctx.recoverErr == nil {
+		pctx.recoverErr = err
+	}
+	pctx.disableSAX = true
+	pctx.wellFormed = false
+
+	cur := pctx.getCursor()
+	prevLine, prevCol := cur.LineNumber(), cur.Column()
+	if err := pctx.skipToRecoverPoint(ctx); err != nil {
+		return err
+	}
+	if !cur.Done() && cur.LineNumber() == prevLine && cur.Column() == prevCol {
+		_ = cur.Advance(1)
+	}
+	if pp.depth == 0 {
+		return pctx.recoverErr
+	}
+	return nil
+}
+
+// charData parses a character-data run. parseCharData may return before the
+// end of the run, as it does in parseContent, so it is called until the
+// cursor reaches the delimiter or the end of the input released.
+func (pp *PushParser) charData() error {
+	pctx := pp.pctx
+	pctx.textContinues = pp.inText
+	defer func() {
+		pctx.textContinues = false
+	}()
+
+	cur := pctx.getCursor()
+	for {
+		if err := pctx.parseCharData(pp.ctx, false); err != nil {
+			return err
+		}
+		if cur.Done() || pctx.stopped {
+			return nil
+		}
+		if c := cur.Peek(); c == '<' || c == '&' {
+			return nil
+		}
+	}
+}
+
+// startElement parses a start tag, and the end of an empty element, as
+// parseElement does.
+func (pp *PushParser) startElement() error {
+	pctx := pp.pctx
+	ctx := pp.ctx
+
+	pctx.elemDepth++
+	if pctx.maxElemDepth > 0 && pctx.elemDepth > pctx.maxElemDepth {
+		pctx.elemDepth--
+		return pctx.error(ctx, fmt.Errorf("xml: exceeded max depth"))
+	}
+	if err := pctx.parseStartTag(ctx); err != nil {
+		pctx.elemDepth--
+		return err
+	}
+	pp.depth++
+
+	cur := pctx.getCursor()
+	if cur.Peek() == '/' && cur.PeekAt(1) == '>' {
+		return pp.endElement()
+	}
+	return nil
+}
+
+// endElement parses an end tag, or the end of an empty element, and leaves
+// the content once it closes the document element.
+func (pp *PushParser) endElement() error {
+	pctx := pp.pctx
+	err := pctx.parseEndTag(pp.ctx)
+	// An element whose end tag is in error is closed anyway, as it is when
+	// the error unwinds parseElement.
+	pctx.elemDepth--
+	pp.depth--
+	if pp.depth > 0 {
+		return err
+	}
+	pctx.instate = psEpilogue
+	pp.state = pushEpilogue
+	if err == nil && pctx.recoverErr != nil {
+		return pctx.recoverErr
+	}
+	return err
+}
+
+// finish ends the document once the input is complete.
+func (pp *PushParser) finish() error {
+	pctx := pp.pctx
+	ctx := pp.ctx
+	switch pp.state {
+	case pushProlog:
+		if err := pctx.cursorDecodeErr(); err != nil {
+			return pctx.error(ctx, err)
+		}
+		return pctx.error(ctx, ErrEmptyDocument)
+	case pushContent:
+		if pctx.recoverErr != nil {
+			return pctx.recoverErr
+		}
+		return pctx.error(ctx, ErrLtSlashRequired)
+	}
+	return pctx.parseDocumentEnd(ctx)
+}
+
+// pushDecoder decodes the input of a PushParser. Unlike transform.Reader it
+// does not take io.EOF from its source as the end of the input until the
+// PushParser is closed, and decodes on once more input arrives.
+type pushDecoder struct {
+	t    transform.Transformer
+	r    io.Reader
+	in   *pushInput
+	src  []byte // bytes read from r and not yet decoded
+	dst  []byte // decoded bytes not yet returned
+	buf  []byte // backing store for dst
+	tmp  []byte
+	err  error
+	done bool
+}
+
+func newPushDecoder(t transform.Transformer, r io.Reader, in *pushInput) *pushDecoder {
+	return &pushDecoder{
+		t:   t,
+		r:   r,
+		in:  in,
+		buf: make([]byte, 4096),
+		tmp: make([]byte, 4096),
+	}
+}
+
+func (d *pushDecoder) Read(p []byte) (int, error) {
+	for {
+		if len(d.dst) > 0 {
+			n := copy(p, d.dst)
+			d.dst = d.dst[n:]
+			return n, nil
+		}
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.done {
+			return 0, io.EOF
+		}
+		if len(d.src) > 0 && d.decode(false) {
+			continue
+		}
+
+		n, err := d.r.Read(d.tmp)
+		d.src = append(d.src, d.tmp[:n]...)
+		switch {
+		case n > 0:
+			continue
+		case err == nil:
+			return 0, nil
+		case err != io.EOF:
+			return 0, err
+		case !d.in.closed:
+			return 0, io.EOF
+		}
+
+		// The input is complete: flush what the transformer holds.
+		if !d.decode(true) {
+			d.done = true
+		}
+	}
+}
+
+// decode decodes what it can of src into dst, and reports whether it made
+// progress. Its buffer has room for the longest sequence a decoder emits
+// at once, so a short destination never stalls it.
+func (d *pushDecoder) decode(atEOF bool) bool {
+	nDst, nSrc, err := d.t.Transform(d.buf, d.src, atEOF)
+	d.src = d.src[nSrc:]
+	d.dst = d.buf[:nDst]
+	switch {
+	case err == nil:
+		d.done = atEOF
+	case !errors.Is(err, transform.ErrShortSrc) && !errors.Is(err, transform.ErrShortDst):
+		d.err = err
+		return true
+	}
+	return nDst > 0 || nSrc > 0
+}
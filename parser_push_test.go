@@ -5,11 +5,15 @@ import (
 	"context"
 	"io"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/sax"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
 )
 
 const testXML = `<?xml version="1.0"?>
@@ -153,9 +157,11 @@ func TestPushParser(t *testing.T) {
 
 		p := helium.NewParser()
 		pp := p.NewPushParser(t.Context())
-		require.NoError(t, pp.Push(input))
+		// The mismatched end tag is complete, so Push reports it.
+		pushErr := pp.Push(input)
+		require.Error(t, pushErr)
 		_, err := pp.Close()
-		require.Error(t, err)
+		require.ErrorIs(t, err, pushErr)
 	})
 
 	t.Run("push after error", func(t *testing.T) {
@@ -255,10 +261,9 @@ func TestPushParser(t *testing.T) {
 	t.Run("context cancel while waiting for data", func(t *testing.T) {
 		t.Parallel()
 
-		// Push a partial document and never push the rest. The background
-		// parser will block in the stream's Read waiting for more data. The
-		// stream wait is context-aware, so cancelling must unblock it and let
-		// Close return promptly with the context error.
+		// Push a partial document and never push the rest. Cancelling must
+		// make Close return promptly with the context error instead of
+		// finishing the document.
 		ctx, cancel := context.WithCancel(t.Context())
 
 		p := helium.NewParser()
@@ -285,23 +290,15 @@ func TestPushParser(t *testing.T) {
 		t.Parallel()
 
 		// Push only "<?xml " (the declaration hint plus a single space) and never
-		// push the rest. The background parser consumes "<?xml", skips the one
-		// buffered space, then blocks in the stream's Read scanning for more
-		// whitespace after "<?xml". Cancelling unblocks that Read with
-		// context.Canceled, which the cursor records as a sticky Err() while
-		// PeekAt reports 0. The blank scanner must surface that read error so the
-		// cancellation propagates as context.Canceled. A synthesized syntax
-		// error ("blank needed after '<?xml'") would mask it.
+		// push the rest, leaving the parser waiting for the end of the
+		// declaration. The cancellation must surface as context.Canceled; a
+		// synthesized syntax error ("blank needed after '<?xml'") would mask it.
 		ctx, cancel := context.WithCancel(t.Context())
 
 		p := helium.NewParser()
 		pp := p.NewPushParser(ctx)
 		require.NoError(t, pp.Push([]byte("<?xml ")))
 
-		// Give the background parser time to consume "<?xml ", advance over the
-		// buffered space, and block in the whitespace Read before cancelling so
-		// the cancellation lands squarely in the declaration whitespace scan.
-		time.Sleep(200 * time.Millisecond)
 		cancel()
 
 		done := make(chan struct {
@@ -349,4 +346,108 @@ func TestPushParser(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, dumpDoc(t, want), dumpDoc(t, got))
 	})
+
+	t.Run("push parses before it returns", func(t *testing.T) {
+		t.Parallel()
+
+		var started []string
+		h := sax.New()
+		h.SetOnStartElementNS(sax.StartElementNSFunc(func(_ context.Context, localname, _, _ string, _ []sax.Namespace, _ []sax.Attribute) error {
+			started = append(started, localname)
+			return nil
+		}))
+
+		pp := helium.NewParser().SAXHandler(h).NewPushParser(t.Context())
+		require.NoError(t, pp.Push([]byte(`<root><a/><b x="1`)))
+		require.Equal(t, []string{"root", "a"}, started)
+		require.Equal(t, len(`<b x="1`), pp.Buffered(), "only the unfinished tag is held")
+
+		require.NoError(t, pp.Push([]byte(`"/></root>`)))
+		require.Equal(t, []string{"root", "a", "b"}, started)
+		require.Zero(t, pp.Buffered())
+
+		_, err := pp.Close()
+		require.NoError(t, err)
+	})
+
+	t.Run("error surfaces from the push that completes it", func(t *testing.T) {
+		t.Parallel()
+
+		pp := helium.NewParser().NewPushParser(t.Context())
+		require.NoError(t, pp.Push([]byte(`<root><a></`)))
+		require.Error(t, pp.Push([]byte(`b>`)))
+	})
+
+	t.Run("long text is delivered in pieces", func(t *testing.T) {
+		t.Parallel()
+
+		// Multibyte characters and blanks fall on the chunk boundaries.
+		text := bytes.Repeat([]byte("0123 5678 é 日本 \r\n]] "), 1<<12)
+		var got bytes.Buffer
+		h := sax.New()
+		h.SetOnCharacters(sax.CharactersFunc(func(_ context.Context, ch []byte) error {
+			got.Write(ch)
+			return nil
+		}))
+
+		pp := helium.NewParser().SAXHandler(h).NewPushParser(t.Context())
+		require.NoError(t, pp.Push([]byte(`<root>`)))
+		for i := 0; i < len(text); i += 1000 {
+			require.NoError(t, pp.Push(text[i:min(i+1000, len(text))]))
+			require.Less(t, pp.Buffered(), 8192)
+		}
+		require.NoError(t, pp.Push([]byte(`</root>`)))
+		_, err := pp.Close()
+		require.NoError(t, err)
+		require.Equal(t, strings.ReplaceAll(string(text), "\r\n", "\n"), got.String())
+	})
+
+	t.Run("buffer limit", func(t *testing.T) {
+		t.Parallel()
+
+		pp := helium.NewParser().MaxPushBufferBytes(64).NewPushParser(t.Context())
+		require.NoError(t, pp.Push([]byte(`<root attr="`)))
+		err := pp.Push(bytes.Repeat([]byte("x"), 100))
+		require.ErrorIs(t, err, helium.ErrPushBufferTooLarge)
+		require.ErrorIs(t, pp.Push([]byte(`"/>`)), helium.ErrPushBufferTooLarge)
+	})
+
+	t.Run("UTF-16 byte at a time", func(t *testing.T) {
+		t.Parallel()
+
+		input, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes(
+			[]byte(`<?xml version="1.0" encoding="UTF-16"?><root a="é">𝄞 text &amp; more<x/></root>`))
+		require.NoError(t, err)
+
+		p := helium.NewParser()
+		want, err := p.Parse(t.Context(), input)
+		require.NoError(t, err)
+
+		pp := p.NewPushParser(t.Context())
+		for i := range input {
+			require.NoError(t, pp.Push(input[i:i+1]))
+		}
+		got, err := pp.Close()
+		require.NoError(t, err)
+		require.Equal(t, dumpDoc(t, want), dumpDoc(t, got))
+	})
+
+	t.Run("encoding that is not ASCII-transparent is parsed at close", func(t *testing.T) {
+		t.Parallel()
+
+		const decl = `<?xml version="1.0" encoding="Shift_JIS"?>`
+		input, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(decl + `<root>表示</root>`))
+		require.NoError(t, err)
+
+		p := helium.NewParser()
+		want, err := p.Parse(t.Context(), input)
+		require.NoError(t, err)
+
+		pp := p.NewPushParser(t.Context())
+		require.NoError(t, pp.Push(input))
+		require.Equal(t, len(input)-len(decl), pp.Buffered(), "the document is held after its declaration")
+		got, err := pp.Close()
+		require.NoError(t, err)
+		require.Equal(t, dumpDoc(t, want), dumpDoc(t, got))
+	})
 }
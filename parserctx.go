@@ -109,9 +109,17 @@ type parserCtx struct {
 	// internal entity is referenced once is not falsely rejected. nil on every
 	// non-EBCDIC path (where inputSize already reflects the real/known size).
 	ebcdicConsumed *countingReader
-	nbread         int
-	instate        parserState
-	keepBlanks     bool
+	// pushIn is the input of a PushParser, nil on every other path.
+	// switchEncoding decodes it with a pushDecoder, which reads on after an
+	// io.EOF that only marks the end of the bytes pushed so far.
+	pushIn *pushInput
+	// textContinues marks the later pieces of a character-data run that a
+	// PushParser delivers in pieces. The first piece held non-blank text, so
+	// the rest is character data, never ignorable whitespace.
+	textContinues bool
+	nbread        int
+	instate       parserState
+	keepBlanks    bool
 	// charDataFromCharRef marks that the character data currently being delivered
 	// to the SAX Characters sink originated from a character reference (&#N;/&#xN;),
 	// as opposed to literal source text. TreeBuilder.Characters stamps the resulting
@@ -120,6 +128,12 @@ type parserCtx struct {
 	// for the duration of a char-ref delivery (and a cached-entity Text replay of
 	// one); false otherwise.
 	charDataFromCharRef bool
+	// startTagEmpty reports whether the start tag currently being delivered to
+	// StartElementNS was written as an empty-element tag (<a/>). It is set by
+	// parseStartTag just before the callback and is false for elements replayed
+	// from a cached entity subtree, whose source form is no longer known. Read
+	// by the Reader's handler for IsEmptyElement.
+	startTagEmpty bool
 	// remain            int
 	replaceEntities   bool
 	sax               sax.SAX2Handler
@@ -166,12 +180,15 @@ type parserCtx struct {
 	depth            int
 	loadsubset       LoadSubsetOption
 	charBufferSize   int
-	baseURI          string          // document base URI for resolving external references
-	documentBaseURI  string          // fixed top-level document base URI, captured once at parse start; unlike baseURI it is not moved while an external subset/entity is parsed, so the confined-FS retry always relativizes against the document root
-	extRefRelative   bool            // whether the external reference currently being resolved through TreeBuilder.ResolveEntity declared a RELATIVE SYSTEM id (before URI resolution / catalog mapping) — the gate for the confined-FS base-relative retry (openExternalResource); an originally-absolute or file-URI SYSTEM id is never retried. Set by the entity loaders before each ResolveEntity call; the ExternalSubset path passes its own eligibility directly and does not use this field.
-	catalog          CatalogResolver // XML catalog for entity resolution
-	fsys             fs.FS           // filesystem for loading external DTDs and entities
-	elem             *Element        // current context element
+	baseURI          string           // document base URI for resolving external references
+	documentBaseURI  string           // fixed top-level document base URI, captured once at parse start; unlike baseURI it is not moved while an external subset/entity is parsed, so the confined-FS retry always relativizes against the document root
+	extRefRelative   bool             // whether the external reference currently being resolved through TreeBuilder.ResolveEntity declared a RELATIVE SYSTEM id (before URI resolution / catalog mapping) — the gate for the confined-FS base-relative retry (openExternalResource); an originally-absolute or file-URI SYSTEM id is never retried. Set by the entity loaders before each ResolveEntity call; the ExternalSubset path passes its own eligibility directly and does not use this field.
+	catalog          CatalogResolver  // XML catalog for entity resolution
+	fsys             fs.FS            // filesystem for loading external DTDs and entities
+	resolver         ResourceResolver // when set, loads external DTDs and entities in place of fsys
+	cache            *ResourceCache   // when set, memoizes external DTDs and entities across parses
+	elem             *Element         // current context element
+	rec              *sourceRecorder  // records source positions and markup; nil unless requested
 
 	nsTab       nsStack
 	nsNrTab     []int // number of ns bindings pushed per element (parallel to nodeTab)
@@ -701,6 +718,8 @@ func (ctx *parserCtx) init(p *parserConfig, in io.Reader) error {
 		ctx.options = p.options
 		ctx.catalog = p.catalog
 		ctx.fsys = p.fsys
+		ctx.resolver = p.resolver
+		ctx.cache = p.cache
 		if ctx.options.IsSet(parseNoBlanks) {
 			ctx.keepBlanks = false
 		}
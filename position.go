@@ -0,0 +1,183 @@
+package helium
+
+import "sort"
+
+// Location is a point in the source of a parsed document.
+type Location struct {
+	// Line is the 1-based line number, counted like [Node.Line].
+	Line int
+	// Column is the 1-based byte column within the line, counted like the
+	// parser's ColumnNumber.
+	Column int
+	// Offset is the 0-based byte offset from the start of the input. For
+	// input in an encoding other than UTF-8 it is an offset into the input
+	// as decoded to UTF-8.
+	Offset int
+}
+
+// Position is the source extent of a node: Start is its first byte and End
+// the byte just past its last one.
+//
+// For an element the extent runs from the '<' of its start tag to the '>'
+// of its end tag. For an attribute it covers name="value" as written inside
+// the start tag, and ValueStart/ValueEnd narrow it to the characters between
+// the quotes. Text, CDATA sections, comments, processing instructions and
+// entity references span their complete markup, including any character or
+// entity references folded into a text node.
+type Position struct {
+	Start Location
+	End   Location
+
+	// ValueStart and ValueEnd delimit an attribute's value, excluding the
+	// quotes. They are zero for every other kind of node.
+	ValueStart Location
+	ValueEnd   Location
+}
+
+// PositionOf returns the source position of n, recorded while parsing with
+// [Parser.TrackPositions]. It reports false when positions were not tracked
+// or n has none: nodes created after parsing, attributes defaulted from the
+// DTD, and nodes an entity reference expanded to, other than a single text
+// node, which spans the reference.
+//
+// Positions describe the source the document was parsed from, as updated by
+// [Parser.Reparse]; other edits to the tree do not update them.
+func PositionOf(n Node) (Position, bool) {
+	if n == nil {
+		return Position{}, false
+	}
+	doc := n.OwnerDocument()
+	if doc == nil || doc.source == nil {
+		return Position{}, false
+	}
+	return doc.source.position(n)
+}
+
+// sourceMap holds the extent of each node of a document parsed with
+// Parser.TrackPositions. A node's extent is stored relative to the start of
+// its parent, so that Parser.Reparse moves everything after an edit by
+// updating only the ancestors of the reparsed element.
+type sourceMap struct {
+	root  *nodeSpan // the document; its children are the top-level nodes
+	nodes map[Node]*nodeSpan
+	// lines holds the start of every line an extent begins or ends on, in
+	// order. It is not kept up to date by Parser.Reparse, which requires
+	// text.
+	lines []lineStart
+	// text is the source, decoded to UTF-8, kept with Parser.RetainSource;
+	// nil otherwise. Once present it is the authority for lines.
+	text       *rope
+	base       int  // length of the byte order mark; line 1 starts after it
+	transcoded bool // the input was in an encoding other than UTF-8
+}
+
+// lineStart is the offset of the first byte of a line.
+type lineStart struct {
+	line int
+	off  int
+}
+
+// nodeSpan is the extent of a node.
+type nodeSpan struct {
+	node   Node
+	parent *nodeSpan
+	index  int // position in parent.kids; -1 for an attribute
+	off    int // start, relative to the start of parent
+	size   int
+	// open and close are the lengths of an element's start and end tags.
+	// close is 0 for an empty-element tag, which open covers.
+	open, close int
+	// valOff and valSize delimit an attribute's value, relative to the start
+	// of the attribute.
+	valOff, valSize int
+	attrs           []*nodeSpan
+	kids            []*nodeSpan
+	// shift is a Fenwick tree over kids of the distance each has moved
+	// since parsing; nil until one has.
+	shift []int
+}
+
+func (sm *sourceMap) position(n Node) (Position, bool) {
+	s, ok := sm.nodes[n]
+	if !ok {
+		return Position{}, false
+	}
+	start := s.start()
+	pos := Position{Start: sm.location(start), End: sm.location(start + s.size)}
+	if s.index < 0 {
+		pos.ValueStart = sm.location(start + s.valOff)
+		pos.ValueEnd = sm.location(start + s.valOff + s.valSize)
+	}
+	return pos, true
+}
+
+func (sm *sourceMap) location(off int) Location {
+	var line, start int
+	if sm.text != nil {
+		line = sm.text.newlinesBefore(off) + 1
+		start = sm.base
+		if line > 1 {
+			start = sm.text.newlineAt(line-1) + 1
+		}
+	} else {
+		i := sort.Search(len(sm.lines), func(i int) bool { return sm.lines[i].off > off })
+		ls := sm.lines[max(i-1, 0)]
+		line, start = ls.line, ls.off
+	}
+	return Location{Line: line, Column: off - start + 1, Offset: off}
+}
+
+// start returns the offset of the first byte of s.
+func (s *nodeSpan) start() int {
+	off := s.off
+	for c, p := s, s.parent; p != nil; c, p = p, p.parent {
+		off += p.off
+		if c.index >= 0 {
+			off += p.shiftAt(c.index)
+		}
+	}
+	return off
+}
+
+// add appends a child extent starting at off, relative to the start of s.
+func (s *nodeSpan) add(n Node, off int) *nodeSpan {
+	kid := &nodeSpan{node: n, parent: s, index: len(s.kids), off: off}
+	s.kids = append(s.kids, kid)
+	return kid
+}
+
+// shiftAt returns how far the kid at index i has moved.
+func (s *nodeSpan) shiftAt(i int) int {
+	if s.shift == nil {
+		return 0
+	}
+	sum := 0
+	for i++; i > 0; i -= i & -i {
+		sum += s.shift[i]
+	}
+	return sum
+}
+
+// addShift moves the kids from index i on by d.
+func (s *nodeSpan) addShift(i, d int) {
+	if i >= len(s.kids) || d == 0 {
+		return
+	}
+	if s.shift == nil {
+		s.shift = make([]int, len(s.kids)+1)
+	}
+	for i++; i < len(s.shift); i += i & -i {
+		s.shift[i] += d
+	}
+}
+
+// walk calls fn for s, its attributes and its descendants.
+func (s *nodeSpan) walk(fn func(*nodeSpan)) {
+	fn(s)
+	for _, attr := range s.attrs {
+		fn(attr)
+	}
+	for _, kid := range s.kids {
+		kid.walk(fn)
+	}
+}
@@ -0,0 +1,153 @@
+package helium_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/stretchr/testify/require"
+)
+
+// sourceOf returns the text of src that pos spans.
+func sourceOf(src string, start, end helium.Location) string {
+	return src[start.Offset:end.Offset]
+}
+
+func TestTrackPositions(t *testing.T) {
+	t.Parallel()
+
+	p := helium.NewParser().TrackPositions(true)
+
+	t.Run("nodes and attributes", func(t *testing.T) {
+		t.Parallel()
+		const src = "<?xml version=\"1.0\"?>\n<root>\n  <item id = 'x'  n=\"1\">a&amp;b</item>\n  <!-- note -->\n  <?pi data?><![CDATA[<c>]]>\n</root>\n"
+		doc, err := p.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+
+		root := doc.DocumentElement()
+		pos, ok := helium.PositionOf(root)
+		require.True(t, ok)
+		require.Equal(t, helium.Location{Line: 2, Column: 1, Offset: 22}, pos.Start)
+		require.Equal(t, helium.Location{Line: 6, Column: 8, Offset: len(src) - 1}, pos.End)
+
+		item := root.FirstChild().NextSibling().(*helium.Element)
+		pos, ok = helium.PositionOf(item)
+		require.True(t, ok)
+		require.Equal(t, `<item id = 'x'  n="1">a&amp;b</item>`, sourceOf(src, pos.Start, pos.End))
+		require.Equal(t, 3, pos.Start.Line)
+		require.Equal(t, 3, pos.Start.Column)
+		require.Equal(t, item.Line(), pos.Start.Line)
+
+		attrs := item.Attributes()
+		require.Len(t, attrs, 2)
+		pos, ok = helium.PositionOf(attrs[0])
+		require.True(t, ok)
+		require.Equal(t, `id = 'x'`, sourceOf(src, pos.Start, pos.End))
+		require.Equal(t, `x`, sourceOf(src, pos.ValueStart, pos.ValueEnd))
+		require.Equal(t, 15, pos.ValueStart.Column)
+		pos, ok = helium.PositionOf(attrs[1])
+		require.True(t, ok)
+		require.Equal(t, `n="1"`, sourceOf(src, pos.Start, pos.End))
+		require.Equal(t, `1`, sourceOf(src, pos.ValueStart, pos.ValueEnd))
+
+		pos, ok = helium.PositionOf(item.FirstChild())
+		require.True(t, ok)
+		require.Equal(t, `a&amp;b`, sourceOf(src, pos.Start, pos.End))
+
+		want := map[helium.ElementType]string{
+			helium.CommentNode:               `<!-- note -->`,
+			helium.ProcessingInstructionNode: `<?pi data?>`,
+			helium.CDATASectionNode:          `<![CDATA[<c>]]>`,
+		}
+		for c := range helium.Children(root) {
+			s, found := want[c.Type()]
+			if !found {
+				continue
+			}
+			pos, ok := helium.PositionOf(c)
+			require.True(t, ok, "%s", c.Type())
+			require.Equal(t, s, sourceOf(src, pos.Start, pos.End))
+			delete(want, c.Type())
+		}
+		require.Empty(t, want)
+	})
+
+	t.Run("byte order mark and CRLF", func(t *testing.T) {
+		t.Parallel()
+		const src = "\xef\xbb\xbf<r>\r\n<é a='1'/></r>"
+		doc, err := p.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+
+		e := doc.DocumentElement().LastChild()
+		pos, ok := helium.PositionOf(e)
+		require.True(t, ok)
+		require.Equal(t, helium.Location{Line: 2, Column: 1, Offset: 8}, pos.Start)
+		require.Equal(t, `<é a='1'/>`, sourceOf(src, pos.Start, pos.End))
+	})
+
+	t.Run("entity references", func(t *testing.T) {
+		t.Parallel()
+		const src = "<!DOCTYPE r [<!ENTITY e '<b>x</b>'>]><r><a/>&e;<c x=\"1\"/>text</r>"
+		at := func(t *testing.T, n helium.Node) string {
+			t.Helper()
+			pos, ok := helium.PositionOf(n)
+			require.True(t, ok, "%s", n.Name())
+			return sourceOf(src, pos.Start, pos.End)
+		}
+
+		doc, err := p.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		ref := doc.DocumentElement().FirstChild().NextSibling()
+		require.Equal(t, helium.EntityRefNode, ref.Type())
+		require.Equal(t, "&e;", at(t, ref))
+		require.Equal(t, `<c x="1"/>`, at(t, ref.NextSibling()))
+
+		doc, err = p.SubstituteEntities(true).Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		// The element the reference expanded to has no markup of its own;
+		// the nodes after it keep theirs.
+		var got []string
+		for c := range helium.Children(doc.DocumentElement()) {
+			if _, ok := helium.PositionOf(c); !ok {
+				require.Equal(t, "b", c.Name())
+				continue
+			}
+			got = append(got, at(t, c))
+		}
+		require.Equal(t, []string{"<a/>", `<c x="1"/>`, "text"}, got)
+		c := doc.DocumentElement().LastChild().PrevSibling().(*helium.Element)
+		pos, ok := helium.PositionOf(c.Attributes()[0])
+		require.True(t, ok)
+		require.Equal(t, "1", sourceOf(src, pos.ValueStart, pos.ValueEnd))
+	})
+
+	t.Run("ParseReader", func(t *testing.T) {
+		t.Parallel()
+		const src = "<r>\n  <a b='c'/>\n</r>"
+		doc, err := p.ParseReader(t.Context(), strings.NewReader(src))
+		require.NoError(t, err)
+
+		a := doc.DocumentElement().FirstChild().NextSibling().(*helium.Element)
+		pos, ok := helium.PositionOf(a.Attributes()[0])
+		require.True(t, ok)
+		require.Equal(t, helium.Location{Line: 2, Column: 6, Offset: 9}, pos.Start)
+	})
+
+	t.Run("untracked nodes", func(t *testing.T) {
+		t.Parallel()
+		doc, err := p.Parse(t.Context(), []byte(`<r/>`))
+		require.NoError(t, err)
+
+		e, err := doc.CreateElement("new")
+		require.NoError(t, err)
+		_, ok := helium.PositionOf(e)
+		require.False(t, ok)
+		_, ok = helium.PositionOf(doc)
+		require.False(t, ok)
+
+		doc, err = helium.NewParser().Parse(t.Context(), []byte(`<r/>`))
+		require.NoError(t, err)
+		_, ok = helium.PositionOf(doc.DocumentElement())
+		require.False(t, ok)
+	})
+}
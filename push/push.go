@@ -1,6 +1,7 @@
 // Package push provides a generic push parser that accepts data in
-// chunks and parses it in a background goroutine. It is used by both the
-// XML ([helium.Parser]) and HTML ([html.Parser]) push-parser APIs.
+// chunks and parses it in a background goroutine. It backs the HTML
+// ([html.Parser]) push-parser API; the XML push parser, helium.PushParser,
+// parses each chunk synchronously and does not use it.
 //
 // The [ReaderParser] interface abstracts the underlying parser; any type
 // with a ParseReader(ctx, io.Reader) method can be used.
@@ -0,0 +1,652 @@
+package helium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	"github.com/lestrrat-go/helium/sax"
+)
+
+// ReaderNodeType identifies the kind of node a [Reader] is positioned on
+// (libxml2: xmlReaderTypes). Unlike [ElementType] it distinguishes the end of
+// an element and whitespace-only text, which a pull consumer observes as
+// separate steps.
+type ReaderNodeType int
+
+const (
+	ReaderNone ReaderNodeType = iota
+	ReaderElement
+	ReaderEndElement
+	ReaderText
+	ReaderCDATA
+	ReaderEntityReference
+	ReaderProcessingInstruction
+	ReaderComment
+	ReaderDocumentType
+	// ReaderWhitespace is a whitespace-only text node outside the scope of
+	// xml:space="preserve".
+	ReaderWhitespace
+	// ReaderSignificantWhitespace is a whitespace-only text node in the scope
+	// of xml:space="preserve".
+	ReaderSignificantWhitespace
+)
+
+func (t ReaderNodeType) String() string {
+	switch t {
+	case ReaderNone:
+		return "None"
+	case ReaderElement:
+		return "Element"
+	case ReaderEndElement:
+		return "EndElement"
+	case ReaderText:
+		return "Text"
+	case ReaderCDATA:
+		return "CDATA"
+	case ReaderEntityReference:
+		return "EntityReference"
+	case ReaderProcessingInstruction:
+		return "ProcessingInstruction"
+	case ReaderComment:
+		return "Comment"
+	case ReaderDocumentType:
+		return "DocumentType"
+	case ReaderWhitespace:
+		return "Whitespace"
+	case ReaderSignificantWhitespace:
+		return "SignificantWhitespace"
+	default:
+		return fmt.Sprintf("ReaderNodeType(%d)", int(t))
+	}
+}
+
+// ErrReaderClosed is returned by [Reader] methods called after
+// [Reader.Close].
+var ErrReaderClosed = errors.New("reader closed")
+
+// readerEvent is one step of the pull stream: the node the parser just
+// produced, its depth, and — for elements — whether it was written as an
+// empty-element tag.
+type readerEvent struct {
+	typ   ReaderNodeType
+	node  Node
+	depth int
+	empty bool
+}
+
+// Reader is a forward-only, pull-style cursor over an XML document
+// (libxml2: xmlTextReader). Each call to [Reader.Read] advances to the next
+// node in document order; accessors such as [Reader.NodeType], [Reader.Name]
+// and [Reader.Value] describe the node the reader is positioned on.
+//
+//	r := helium.NewParser().NewReader(ctx, f)
+//	defer r.Close()
+//	for {
+//		ok, err := r.Read()
+//		if err != nil {
+//			return err
+//		}
+//		if !ok {
+//			break
+//		}
+//		if r.NodeType() == helium.ReaderElement && r.LocalName() == "record" {
+//			rec, err := r.Expand()
+//			...
+//		}
+//	}
+//
+// The Reader feeds the source to a [PushParser] in chunks as nodes are asked
+// for, so every [Parser] option (limits, entity handling, FS sandboxing)
+// applies unchanged, and the parse runs on the caller's goroutine: nothing is
+// parsed between calls. Nodes are built into a partial tree as the parser
+// produces them and are unlinked again as soon as the reader has moved past
+// them, so resident memory is bounded by the depth of the document, the nodes
+// of one input chunk, and the subtree most recently passed to
+// [Reader.Expand], not by the size of the document. The DTD is retained for
+// the whole read. IDs are not interned, as if [Parser.SkipIDs] were set: the
+// ID table would otherwise hold every element with an ID attribute until the
+// end of the read. The post-parse steps [Parser.XInclude] and
+// [Parser.ValidateDTD] operate on a complete tree and are not applied.
+//
+// A Reader is not safe for concurrent use. [Reader.Close] releases the parser
+// when the Reader is abandoned before the end of the document.
+type Reader struct {
+	p   Parser
+	ctx context.Context //nolint:containedctx // the parse outlives NewReader; see push.Parser for the same trade-off
+	src io.Reader
+
+	pp       *PushParser
+	buf      []byte
+	finished bool // the push parser has been closed
+	closed   bool
+
+	pending  []readerEvent // events the parser produced that pull has not returned
+	queue    []readerEvent // events pulled ahead of the cursor
+	cur      readerEvent
+	err      error
+	expanded int // depth of the outermost expanded element, or -1
+}
+
+// NewReader returns a [Reader] that pulls nodes from r using this Parser's
+// configuration. Parsing starts on the first call to [Reader.Read]. The
+// configured SAX handler is ignored: the Reader installs its own
+// [TreeBuilder]-based handler.
+func (p Parser) NewReader(ctx context.Context, r io.Reader) *Reader {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p = p.XInclude(nil).ValidateDTD(false).SkipIDs(true)
+	return &Reader{
+		p:        p,
+		ctx:      ctx,
+		src:      r,
+		expanded: -1,
+	}
+}
+
+// readerChunkSize is the number of bytes the Reader reads from its source
+// and pushes to the parser at a time.
+const readerChunkSize = 4096
+
+// pull parses until the parser has produced an event and returns the first
+// one not yet queued. It returns false once the parse has finished,
+// recording any parse error in r.err.
+func (r *Reader) pull() (readerEvent, bool) {
+	if r.pp == nil && !r.finished {
+		h := &readerHandler{TreeBuilder: NewTreeBuilder(), r: r}
+		r.pp = r.p.SAXHandler(h).NewPushParser(r.ctx)
+		r.buf = make([]byte, readerChunkSize)
+	}
+	for len(r.pending) == 0 {
+		if r.finished {
+			return readerEvent{}, false
+		}
+		if err := r.feed(); err != nil {
+			r.finish(err)
+		}
+	}
+	ev := r.pending[0]
+	r.pending = r.pending[1:]
+	return ev, true
+}
+
+// feed reads the next chunk of the source and pushes it to the parser,
+// closing the parser at the end of the source.
+func (r *Reader) feed() error {
+	n, err := r.src.Read(r.buf)
+	if n > 0 {
+		if perr := r.pp.Push(r.buf[:n]); perr != nil {
+			return perr
+		}
+	}
+	switch {
+	case errors.Is(err, io.EOF):
+		_, cerr := r.pp.Close()
+		r.finish(cerr)
+		return nil
+	case err != nil:
+		return err
+	}
+	return nil
+}
+
+// finish closes the parser, recording err as the outcome of the read.
+func (r *Reader) finish(err error) {
+	if !r.finished {
+		r.finished = true
+		StopParser(r.pp.ctx)
+		_, _ = r.pp.Close()
+	}
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// peek returns the next event without consuming it.
+func (r *Reader) peek() (readerEvent, bool) {
+	if len(r.queue) > 0 {
+		return r.queue[0], true
+	}
+	ev, ok := r.pull()
+	if !ok {
+		return readerEvent{}, false
+	}
+	r.queue = append(r.queue, ev)
+	return ev, true
+}
+
+// next consumes the next event, folding consecutive deliveries into the same
+// text node (character data may arrive in several SAX callbacks) so a text
+// node is reported once, with its complete content.
+func (r *Reader) next() (readerEvent, bool) {
+	var ev readerEvent
+	if len(r.queue) > 0 {
+		ev = r.queue[0]
+		r.queue = r.queue[1:]
+	} else {
+		var ok bool
+		if ev, ok = r.pull(); !ok {
+			return readerEvent{}, false
+		}
+	}
+	if ev.typ != ReaderText {
+		return ev, true
+	}
+	for {
+		nx, ok := r.peek()
+		if !ok || nx.node != ev.node {
+			break
+		}
+		r.queue = r.queue[1:]
+	}
+	ev.typ = textReaderType(ev.node)
+	return ev, true
+}
+
+// textReaderType classifies a completed text node: whitespace-only text is
+// reported as (significant) whitespace, mirroring xmlTextReaderNodeType.
+func textReaderType(n Node) ReaderNodeType {
+	for _, c := range rawContent(n) {
+		if !isBlankByte(c) {
+			return ReaderText
+		}
+	}
+	if xmlSpacePreserved(n.Parent()) {
+		return ReaderSignificantWhitespace
+	}
+	return ReaderWhitespace
+}
+
+// xmlSpacePreserved reports whether n is in the scope of xml:space="preserve".
+func xmlSpacePreserved(n Node) bool {
+	for ; n != nil; n = n.Parent() {
+		e, ok := n.(*Element)
+		if !ok {
+			continue
+		}
+		if v, ok := e.GetAttributeNS("space", lexicon.NamespaceXML); ok {
+			return v == "preserve"
+		}
+	}
+	return false
+}
+
+// release unlinks a node the reader has moved past, keeping the partial tree
+// bounded. It must only be called once a later event has been received: by
+// then the parser is done with the node (an empty element, for instance, is
+// reported before its EndElementNS has run). Nodes inside an expanded subtree
+// are kept until the expanded element itself is passed, so the *Element
+// returned by Expand stays complete.
+func (r *Reader) release(ev readerEvent) {
+	if ev.node == nil {
+		return
+	}
+	switch ev.typ {
+	case ReaderElement:
+		if !ev.empty {
+			return
+		}
+	case ReaderDocumentType, ReaderNone:
+		return
+	}
+	if r.expanded >= 0 {
+		if ev.depth > r.expanded {
+			return
+		}
+		r.expanded = -1
+	}
+	unlinkNode(ev.node)
+}
+
+// Read advances the reader to the next node in document order. It returns
+// false with a nil error at the end of the document, and false with the parse
+// error if the document is malformed or ctx was cancelled.
+func (r *Reader) Read() (bool, error) {
+	if r.closed {
+		return false, ErrReaderClosed
+	}
+	if err := r.ctx.Err(); err != nil && r.pp != nil {
+		r.finish(err)
+		r.pending, r.queue = nil, nil
+	}
+	prev := r.cur
+	ev, ok := r.next()
+	r.release(prev)
+	if !ok {
+		r.cur = readerEvent{}
+		return false, r.err
+	}
+	r.cur = ev
+	return true, nil
+}
+
+// Skip advances the reader to the node following the current node's subtree,
+// without reporting its descendants (libxml2: xmlTextReaderNext). On any node
+// other than a non-empty element it behaves like [Reader.Read].
+func (r *Reader) Skip() (bool, error) {
+	if r.closed {
+		return false, ErrReaderClosed
+	}
+	if r.cur.typ == ReaderElement && !r.cur.empty {
+		start := r.cur
+		for {
+			ok, err := r.Read()
+			if !ok || err != nil {
+				return ok, err
+			}
+			if r.cur.typ == ReaderEndElement && r.cur.node == start.node {
+				break
+			}
+		}
+	}
+	return r.Read()
+}
+
+// Expand reads ahead to the end of the current element and returns it with
+// its complete subtree (libxml2: xmlTextReaderExpand). The reader stays
+// positioned on the element: a following [Reader.Read] visits its
+// descendants, and [Reader.Skip] moves past them. The returned element is
+// detached from the document once the reader moves past its end, but its
+// subtree remains intact and may be retained by the caller.
+//
+// Expand returns [ErrInvalidOperation] when the reader is not positioned on an
+// element start.
+func (r *Reader) Expand() (*Element, error) {
+	if r.closed {
+		return nil, ErrReaderClosed
+	}
+	e, ok := r.cur.node.(*Element)
+	if !ok || r.cur.typ != ReaderElement {
+		return nil, fmt.Errorf("%w: reader is not positioned on an element", ErrInvalidOperation)
+	}
+	if r.expanded < 0 {
+		r.expanded = r.cur.depth
+	}
+	if r.cur.empty {
+		return e, nil
+	}
+	// The end event may already be queued by an earlier Expand of an
+	// ancestor.
+	for _, ev := range r.queue {
+		if ev.typ == ReaderEndElement && ev.node == r.cur.node {
+			return e, nil
+		}
+	}
+	for {
+		ev, ok := r.pull()
+		if !ok {
+			if r.err != nil {
+				return nil, r.err
+			}
+			return nil, fmt.Errorf("%w: unexpected end of document", ErrInvalidOperation)
+		}
+		r.queue = append(r.queue, ev)
+		if ev.typ == ReaderEndElement && ev.node == r.cur.node {
+			return e, nil
+		}
+	}
+}
+
+// Close stops the parse and releases the parser. Close is idempotent.
+func (r *Reader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if r.pp != nil {
+		r.finish(nil)
+	}
+	r.pending = nil
+	r.queue = nil
+	r.cur = readerEvent{}
+	return nil
+}
+
+// Node returns the node the reader is positioned on, or nil before the first
+// Read and after the end of the document. On a [ReaderEndElement] it is the
+// element being closed.
+func (r *Reader) Node() Node {
+	return r.cur.node
+}
+
+// NodeType returns the kind of the current node.
+func (r *Reader) NodeType() ReaderNodeType {
+	return r.cur.typ
+}
+
+// Depth returns the nesting depth of the current node; the document element
+// is at depth 0 and its children at depth 1.
+func (r *Reader) Depth() int {
+	return r.cur.depth
+}
+
+// IsEmptyElement reports whether the current node is an element written as
+// an empty-element tag (<a/>). No [ReaderEndElement] is reported for such an
+// element.
+func (r *Reader) IsEmptyElement() bool {
+	return r.cur.typ == ReaderElement && r.cur.empty
+}
+
+// Name returns the qualified name of the current node: the QName of an
+// element, the target of a processing instruction, the name of an entity
+// reference or document type, and "#text", "#cdata-section" or "#comment"
+// for character data and comments.
+func (r *Reader) Name() string {
+	switch r.cur.typ {
+	case ReaderNone:
+		return ""
+	case ReaderText, ReaderWhitespace, ReaderSignificantWhitespace:
+		return "#text"
+	case ReaderCDATA:
+		return "#cdata-section"
+	case ReaderComment:
+		return "#comment"
+	default:
+		return r.cur.node.Name()
+	}
+}
+
+// LocalName returns the local part of the current node's name. For nodes
+// without a namespace-qualified name it is the same as [Reader.Name].
+func (r *Reader) LocalName() string {
+	if e, ok := r.cur.node.(*Element); ok {
+		return e.LocalName()
+	}
+	return r.Name()
+}
+
+// Prefix returns the namespace prefix of the current element, or "".
+func (r *Reader) Prefix() string {
+	if e, ok := r.cur.node.(*Element); ok {
+		return e.Prefix()
+	}
+	return ""
+}
+
+// NamespaceURI returns the namespace URI of the current element, or "".
+func (r *Reader) NamespaceURI() string {
+	if e, ok := r.cur.node.(*Element); ok {
+		return e.URI()
+	}
+	return ""
+}
+
+// HasValue reports whether the current node carries a value: text, CDATA,
+// whitespace, comments, and processing instructions do.
+func (r *Reader) HasValue() bool {
+	switch r.cur.typ {
+	case ReaderText, ReaderCDATA, ReaderComment, ReaderProcessingInstruction,
+		ReaderWhitespace, ReaderSignificantWhitespace:
+		return true
+	default:
+		return false
+	}
+}
+
+// Value returns the value of the current node (see [Reader.HasValue]), or ""
+// for nodes without one.
+func (r *Reader) Value() string {
+	if !r.HasValue() {
+		return ""
+	}
+	return string(rawContent(r.cur.node))
+}
+
+// AttributeCount returns the number of attributes on the current element.
+func (r *Reader) AttributeCount() int {
+	e, ok := r.cur.node.(*Element)
+	if !ok || r.cur.typ != ReaderElement {
+		return 0
+	}
+	n := 0
+	e.ForEachAttribute(func(*Attribute) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// GetAttribute returns the value of the current element's attribute with the
+// given qualified name.
+func (r *Reader) GetAttribute(name string) (string, bool) {
+	e, ok := r.cur.node.(*Element)
+	if !ok || r.cur.typ != ReaderElement {
+		return "", false
+	}
+	return e.GetAttribute(name)
+}
+
+// GetAttributeNS returns the value of the current element's attribute with
+// the given local name and namespace URI.
+func (r *Reader) GetAttributeNS(localName, nsURI string) (string, bool) {
+	e, ok := r.cur.node.(*Element)
+	if !ok || r.cur.typ != ReaderElement {
+		return "", false
+	}
+	return e.GetAttributeNS(localName, nsURI)
+}
+
+// readerHandler is the SAX handler behind a Reader. It delegates tree
+// construction to the embedded TreeBuilder and, after each node-producing
+// callback, queues the new node for the Reader.
+type readerHandler struct {
+	*TreeBuilder
+	r       *Reader
+	depth   int
+	empty   []bool
+	doctype *DTD
+}
+
+// emit queues ev for the Reader, preceded by the document type if it is
+// still to be reported.
+func (h *readerHandler) emit(_ context.Context, ev readerEvent) error {
+	if h.doctype != nil {
+		h.r.pending = append(h.r.pending, readerEvent{typ: ReaderDocumentType, node: h.doctype})
+		h.doctype = nil
+	}
+	h.r.pending = append(h.r.pending, ev)
+	return nil
+}
+
+// emitLeaf reports the node the TreeBuilder just appended under the current
+// parent. Nodes that went into a DTD subset are not part of the reader
+// stream.
+func (h *readerHandler) emitLeaf(ctx context.Context, typ ReaderNodeType) error {
+	pctx := h.pctx(ctx)
+	if pctx.inSubset != notInSubset {
+		return nil
+	}
+	var parent Node = pctx.doc
+	if pctx.elem != nil {
+		parent = pctx.elem
+	}
+	n := parent.LastChild()
+	if n == nil {
+		return nil
+	}
+	return h.emit(ctx, readerEvent{typ: typ, node: n, depth: h.depth})
+}
+
+func (h *readerHandler) StartElementNS(ctx context.Context, localname, prefix, uri string, namespaces []sax.Namespace, attrs []sax.Attribute) error {
+	if err := h.TreeBuilder.StartElementNS(ctx, localname, prefix, uri, namespaces, attrs); err != nil {
+		return err
+	}
+	pctx := h.pctx(ctx)
+	empty := pctx.startTagEmpty
+	h.empty = append(h.empty, empty)
+	depth := h.depth
+	h.depth++
+	return h.emit(ctx, readerEvent{typ: ReaderElement, node: pctx.elem, depth: depth, empty: empty})
+}
+
+func (h *readerHandler) EndElementNS(ctx context.Context, localname, prefix, uri string) error {
+	e := h.pctx(ctx).elem
+	if err := h.TreeBuilder.EndElementNS(ctx, localname, prefix, uri); err != nil {
+		return err
+	}
+	h.depth--
+	empty := false
+	if n := len(h.empty); n > 0 {
+		empty = h.empty[n-1]
+		h.empty = h.empty[:n-1]
+	}
+	if empty || e == nil {
+		return nil
+	}
+	return h.emit(ctx, readerEvent{typ: ReaderEndElement, node: e, depth: h.depth})
+}
+
+func (h *readerHandler) Characters(ctx context.Context, data []byte) error {
+	if err := h.TreeBuilder.Characters(ctx, data); err != nil {
+		return err
+	}
+	return h.emitLeaf(ctx, ReaderText)
+}
+
+func (h *readerHandler) IgnorableWhitespace(ctx context.Context, data []byte) error {
+	if !h.pctx(ctx).keepBlanks {
+		return nil
+	}
+	return h.Characters(ctx, data)
+}
+
+func (h *readerHandler) CDataBlock(ctx context.Context, data []byte) error {
+	if err := h.TreeBuilder.CDataBlock(ctx, data); err != nil {
+		return err
+	}
+	return h.emitLeaf(ctx, ReaderCDATA)
+}
+
+func (h *readerHandler) Comment(ctx context.Context, data []byte) error {
+	if err := h.TreeBuilder.Comment(ctx, data); err != nil {
+		return err
+	}
+	return h.emitLeaf(ctx, ReaderComment)
+}
+
+func (h *readerHandler) ProcessingInstruction(ctx context.Context, target, data string) error {
+	if err := h.TreeBuilder.ProcessingInstruction(ctx, target, data); err != nil {
+		return err
+	}
+	return h.emitLeaf(ctx, ReaderProcessingInstruction)
+}
+
+func (h *readerHandler) Reference(ctx context.Context, name string) error {
+	if err := h.TreeBuilder.Reference(ctx, name); err != nil {
+		return err
+	}
+	return h.emitLeaf(ctx, ReaderEntityReference)
+}
+
+// InternalSubset defers reporting the document type until its declarations
+// have been parsed: it is emitted just ahead of the next reported node.
+func (h *readerHandler) InternalSubset(ctx context.Context, name, eid, uri string) error {
+	if err := h.TreeBuilder.InternalSubset(ctx, name, eid, uri); err != nil {
+		return err
+	}
+	h.doctype = h.pctx(ctx).doc.IntSubset()
+	return nil
+}
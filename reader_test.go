@@ -0,0 +1,312 @@
+package helium_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/stretchr/testify/require"
+)
+
+type readerStep struct {
+	typ   helium.ReaderNodeType
+	name  string
+	depth int
+	value string
+	empty bool
+}
+
+func collectReader(t *testing.T, r *helium.Reader) []readerStep {
+	t.Helper()
+	var steps []readerStep
+	for {
+		ok, err := r.Read()
+		require.NoError(t, err)
+		if !ok {
+			return steps
+		}
+		steps = append(steps, readerStep{
+			typ:   r.NodeType(),
+			name:  r.Name(),
+			depth: r.Depth(),
+			value: r.Value(),
+			empty: r.IsEmptyElement(),
+		})
+	}
+}
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("node sequence", func(t *testing.T) {
+		t.Parallel()
+		const src = `<?xml version="1.0"?>
+<!DOCTYPE root [<!ELEMENT root ANY>]>
+<?pi data?>
+<root a="1"><!--c--><e/><f>t<![CDATA[x]]></f>  <g xml:space="preserve"> </g></root>`
+
+		r := helium.NewParser().NewReader(t.Context(), strings.NewReader(src))
+		defer r.Close()
+
+		require.Equal(t, []readerStep{
+			{typ: helium.ReaderDocumentType, name: "root"},
+			{typ: helium.ReaderProcessingInstruction, name: "pi", value: "data"},
+			{typ: helium.ReaderElement, name: "root"},
+			{typ: helium.ReaderComment, name: "#comment", depth: 1, value: "c"},
+			{typ: helium.ReaderElement, name: "e", depth: 1, empty: true},
+			{typ: helium.ReaderElement, name: "f", depth: 1},
+			{typ: helium.ReaderText, name: "#text", depth: 2, value: "t"},
+			{typ: helium.ReaderCDATA, name: "#cdata-section", depth: 2, value: "x"},
+			{typ: helium.ReaderEndElement, name: "f", depth: 1},
+			{typ: helium.ReaderWhitespace, name: "#text", depth: 1, value: "  "},
+			{typ: helium.ReaderElement, name: "g", depth: 1},
+			{typ: helium.ReaderSignificantWhitespace, name: "#text", depth: 2, value: " "},
+			{typ: helium.ReaderEndElement, name: "g", depth: 1},
+			{typ: helium.ReaderEndElement, name: "root"},
+		}, collectReader(t, r))
+	})
+
+	t.Run("namespaces and attributes", func(t *testing.T) {
+		t.Parallel()
+		const src = `<p:root xmlns:p="urn:p" xmlns:q="urn:q" q:id="7" plain="v"/>`
+
+		r := helium.NewParser().NewReader(t.Context(), strings.NewReader(src))
+		defer r.Close()
+
+		ok, err := r.Read()
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "p:root", r.Name())
+		require.Equal(t, "root", r.LocalName())
+		require.Equal(t, "p", r.Prefix())
+		require.Equal(t, "urn:p", r.NamespaceURI())
+		require.Equal(t, 2, r.AttributeCount())
+
+		v, ok := r.GetAttributeNS("id", "urn:q")
+		require.True(t, ok)
+		require.Equal(t, "7", v)
+		v, ok = r.GetAttribute("plain")
+		require.True(t, ok)
+		require.Equal(t, "v", v)
+
+		ok, err = r.Read()
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("text split across callbacks is reported once", func(t *testing.T) {
+		t.Parallel()
+		text := strings.Repeat("abcdefgh", 64)
+		src := "<root>" + text + "&amp;" + text + "</root>"
+
+		r := helium.NewParser().CharBufferSize(7).NewReader(t.Context(), strings.NewReader(src))
+		defer r.Close()
+
+		steps := collectReader(t, r)
+		require.Len(t, steps, 3)
+		require.Equal(t, helium.ReaderText, steps[1].typ)
+		require.Equal(t, text+"&"+text, steps[1].value)
+	})
+
+	t.Run("expand and skip", func(t *testing.T) {
+		t.Parallel()
+		var b strings.Builder
+		b.WriteString("<feed>")
+		for i := range 5 {
+			fmt.Fprintf(&b, `<record id="%d"><name>n%d</name><tags><t/><t/></tags></record>`, i, i)
+		}
+		b.WriteString("</feed>")
+
+		r := helium.NewParser().NewReader(t.Context(), strings.NewReader(b.String()))
+		defer r.Close()
+
+		var records []*helium.Element
+		ok, err := r.Read()
+		for ok && err == nil {
+			if r.NodeType() == helium.ReaderElement && r.Name() == "record" {
+				e, xerr := r.Expand()
+				require.NoError(t, xerr)
+				records = append(records, e)
+				ok, err = r.Skip()
+				continue
+			}
+			ok, err = r.Read()
+		}
+		require.NoError(t, err)
+		require.Len(t, records, 5)
+
+		for i, e := range records {
+			// Passed records are detached from the document but keep their
+			// complete subtree.
+			require.Nil(t, e.Parent())
+			s, werr := helium.WriteString(e)
+			require.NoError(t, werr)
+			require.Equal(t, fmt.Sprintf(`<record id="%d"><name>n%d</name><tags><t/><t/></tags></record>`, i, i), s)
+		}
+	})
+
+	t.Run("read after expand visits descendants", func(t *testing.T) {
+		t.Parallel()
+		r := helium.NewParser().NewReader(t.Context(), strings.NewReader(`<a><b>x</b><c/></a>`))
+		defer r.Close()
+
+		ok, err := r.Read()
+		require.NoError(t, err)
+		require.True(t, ok)
+		e, err := r.Expand()
+		require.NoError(t, err)
+		require.Equal(t, "a", e.Name())
+
+		var names []string
+		for _, s := range collectReader(t, r) {
+			names = append(names, s.typ.String()+":"+s.name)
+		}
+		require.Equal(t, []string{
+			"Element:b", "Text:#text", "EndElement:b", "Element:c", "EndElement:a",
+		}, names)
+	})
+
+	t.Run("expand requires an element", func(t *testing.T) {
+		t.Parallel()
+		r := helium.NewParser().NewReader(t.Context(), strings.NewReader(`<a>x</a>`))
+		defer r.Close()
+
+		_, err := r.Expand()
+		require.ErrorIs(t, err, helium.ErrInvalidOperation)
+
+		for range 2 {
+			_, err = r.Read()
+			require.NoError(t, err)
+		}
+		require.Equal(t, helium.ReaderText, r.NodeType())
+		_, err = r.Expand()
+		require.ErrorIs(t, err, helium.ErrInvalidOperation)
+	})
+
+	t.Run("passed nodes are released", func(t *testing.T) {
+		t.Parallel()
+		r := helium.NewParser().NewReader(t.Context(), strings.NewReader(`<root><a>1</a><b>2</b></root>`))
+		defer r.Close()
+
+		var root helium.Node
+		for {
+			ok, err := r.Read()
+			require.NoError(t, err)
+			if !ok {
+				break
+			}
+			if r.Name() == "root" && r.NodeType() == helium.ReaderElement {
+				root = r.Node()
+			}
+			if r.Name() == "b" && r.NodeType() == helium.ReaderElement {
+				// <a> has been passed and unlinked; <b> is the only child.
+				require.Equal(t, r.Node(), root.FirstChild())
+				require.Nil(t, r.Node().PrevSibling())
+			}
+		}
+	})
+
+	t.Run("ids are not interned", func(t *testing.T) {
+		t.Parallel()
+		const src = `<!DOCTYPE root [<!ATTLIST e id ID #IMPLIED>]><root><e id="x"/></root>`
+		r := helium.NewParser().NewReader(t.Context(), strings.NewReader(src))
+		defer r.Close()
+
+		for {
+			ok, err := r.Read()
+			require.NoError(t, err)
+			require.True(t, ok)
+			if r.Name() == "e" {
+				break
+			}
+		}
+		doc := r.Node().OwnerDocument()
+		require.True(t, doc.SkipIDs())
+		require.Nil(t, doc.GetElementByID("x"))
+	})
+
+	t.Run("reads the source as nodes are asked for", func(t *testing.T) {
+		t.Parallel()
+		var sb strings.Builder
+		sb.WriteString("<root>")
+		for range 10000 {
+			sb.WriteString("<item>text</item>")
+		}
+		sb.WriteString("</root>")
+		src := &countingReader{r: strings.NewReader(sb.String())}
+		r := helium.NewParser().NewReader(t.Context(), src)
+		defer r.Close()
+
+		for range 3 {
+			ok, err := r.Read()
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		require.Less(t, src.n, sb.Len()/2)
+	})
+
+	t.Run("malformed input", func(t *testing.T) {
+		t.Parallel()
+		r := helium.NewParser().NewReader(t.Context(), strings.NewReader(`<root><a></b></root>`))
+		defer r.Close()
+
+		var err error
+		ok := true
+		for ok && err == nil {
+			ok, err = r.Read()
+		}
+		require.Error(t, err)
+		var perr helium.ErrParseError
+		require.True(t, errors.As(err, &perr))
+
+		// The error is sticky.
+		_, err2 := r.Read()
+		require.Equal(t, err, err2)
+	})
+
+	t.Run("close mid-document", func(t *testing.T) {
+		t.Parallel()
+		r := helium.NewParser().NewReader(t.Context(), strings.NewReader(`<root><a/><b/><c/></root>`))
+		ok, err := r.Read()
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.NoError(t, r.Close())
+		require.NoError(t, r.Close())
+
+		_, err = r.Read()
+		require.ErrorIs(t, err, helium.ErrReaderClosed)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(t.Context())
+		r := helium.NewParser().NewReader(ctx, strings.NewReader(`<root><a/><b/><c/></root>`))
+		defer r.Close()
+
+		ok, err := r.Read()
+		require.NoError(t, err)
+		require.True(t, ok)
+		cancel()
+
+		for ok && err == nil {
+			ok, err = r.Read()
+		}
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
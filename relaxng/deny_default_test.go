@@ -1,6 +1,8 @@
 package relaxng_test
 
 import (
+	"context"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -140,3 +142,74 @@ func TestCompile_MaxResourceBytes(t *testing.T) {
 		require.Empty(t, got, "a resource at or under the cap must load")
 	})
 }
+
+// A ResourceResolver loads include/externalRef targets in place of the FS,
+// under the ctx of the Compile call.
+func TestCompile_ResourceResolver(t *testing.T) {
+	t.Parallel()
+
+	type ctxKey struct{}
+	mem := helium.MemoryResourceResolver(map[string][]byte{"target.rng": []byte(validTargetRNG)})
+	var seen []any
+	r := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+		seen = append(seen, ctx.Value(ctxKey{}))
+		return mem.ResolveResource(ctx, uri)
+	})
+
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(`<?xml version="1.0"?>
+<grammar xmlns="http://relaxng.org/ns/structure/1.0">
+  <start><externalRef href="target.rng"/></start>
+</grammar>`))
+	require.NoError(t, err)
+	grammar, err := relaxng.NewCompiler().
+		FS(fstest.MapFS{}).
+		ResourceResolver(r).
+		Compile(context.WithValue(t.Context(), ctxKey{}, "v"), doc)
+	require.NoError(t, err)
+	require.Equal(t, []any{"v"}, seen)
+
+	inst, err := helium.NewParser().Parse(t.Context(), []byte(`<root>ok</root>`))
+	require.NoError(t, err)
+	require.NoError(t, relaxng.NewValidator(grammar).Validate(t.Context(), inst))
+}
+
+// A ResourceCache serves an include/externalRef target loaded once to later
+// compiles; the deny-all default is never served from it.
+func TestCompile_ResourceCache(t *testing.T) {
+	t.Parallel()
+
+	const schema = `<?xml version="1.0"?>
+<grammar xmlns="http://relaxng.org/ns/structure/1.0">
+  <start><externalRef href="target.rng"/></start>
+</grammar>`
+	fsys := &countingFS{fsys: fstest.MapFS{"target.rng": &fstest.MapFile{Data: []byte(validTargetRNG)}}}
+	cache := helium.NewResourceCache(0, 0)
+
+	compile := func(t *testing.T, c relaxng.Compiler) string {
+		t.Helper()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(schema))
+		require.NoError(t, err)
+		collector := helium.NewErrorCollector(t.Context(), helium.ErrorLevelNone)
+		_, err = c.ErrorHandler(collector).Compile(t.Context(), doc)
+		require.NoError(t, err)
+		_ = collector.Close()
+		_, compileErrors := partitionCompileErrors(collector.Errors())
+		return compileErrors
+	}
+
+	for range 3 {
+		require.Empty(t, compile(t, relaxng.NewCompiler().FS(fsys).ResourceCache(cache)))
+	}
+	require.Equal(t, 1, fsys.opens)
+	require.Contains(t, compile(t, relaxng.NewCompiler().ResourceCache(cache)), "could not load")
+}
+
+type countingFS struct {
+	fsys  fs.FS
+	opens int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.opens++
+	return c.fsys.Open(name)
+}
@@ -19,6 +19,10 @@
 // On failure, the returned error is [ErrValidationFailed]. Individual
 // validation errors are delivered to the configured [helium.ErrorHandler].
 //
+// [NewValidatingWriter] validates a document while a stream.Writer produces
+// it, failing the call that writes an invalid element or attribute. This is
+// a helium extension not present in libxml2.
+//
 // # Error Handling
 //
 // Both [Compiler] and [Validator] accept an [helium.ErrorHandler] via the
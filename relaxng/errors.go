@@ -8,11 +8,22 @@ import (
 )
 
 // ValidationError represents a single validation error with structured fields.
+// The errors passed to a [helium.ErrorHandler] during validation unwrap to it
+// via [errors.As].
+//
+// Position is the source extent of the offending attribute or element. It is
+// only set when the instance document was parsed with
+// [helium.Parser.TrackPositions].
+//
+// Expected lists the elements that would have been accepted where an element
+// was not expected or was missing. It is set by [StreamValidator].
 type ValidationError struct {
-	Filename string // source filename
-	Line     int    // line number in the source document
-	Element  string // element name
-	Message  string // human-readable error description
+	Filename string          // source filename
+	Line     int             // line number in the source document
+	Element  string          // element name
+	Message  string          // human-readable error description
+	Position helium.Position // source position (optional)
+	Expected []string        // expected elements (optional)
 }
 
 func (e *ValidationError) Error() string {
@@ -22,6 +33,17 @@ func (e *ValidationError) Error() string {
 	return validityError(e.Filename, e.Line, e.Element, e.Message)
 }
 
+// leveledValidationError carries a *ValidationError through the
+// helium.ErrorHandler pipeline with its ErrorLevel.
+type leveledValidationError struct {
+	*ValidationError
+	level helium.ErrorLevel
+}
+
+func (e *leveledValidationError) ErrorLevel() helium.ErrorLevel { return e.level }
+
+func (e *leveledValidationError) Unwrap() error { return e.ValidationError }
+
 // validityError formats a validation error in libxml2 format:
 //
 //	{file}:{line}: element {name}: Relax-NG validity error : {msg}\n
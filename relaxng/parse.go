@@ -120,6 +120,14 @@ func compileSchema(ctx context.Context, doc *helium.Document, baseDir string, cf
 	if cfg.fsys != nil {
 		fsys = cfg.fsys
 	}
+	switch {
+	case cfg.resolver != nil && cfg.cache != nil:
+		fsys = helium.ResourceFS(ctx, cfg.cache.Resolver(cfg.resolver))
+	case cfg.resolver != nil:
+		fsys = helium.ResourceFS(ctx, cfg.resolver)
+	case cfg.cache != nil:
+		fsys = cfg.cache.FS(ctx, fsys)
+	}
 	c := &compiler{
 		grammar: &Grammar{
 			defines: make(map[string]*pattern),
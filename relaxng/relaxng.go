@@ -15,8 +15,10 @@ import (
 type compileConfig struct {
 	label        string // label for error messages (e.g. source filename)
 	baseDir      string
-	fsys         fs.FS          // filesystem for loading include/externalRef targets
-	parser       *helium.Parser // parser governing schema-document parse policy
+	fsys         fs.FS                   // filesystem for loading include/externalRef targets
+	resolver     helium.ResourceResolver // replaces fsys when set
+	cache        *helium.ResourceCache   // memoizes include/externalRef targets across compiles
+	parser       *helium.Parser          // parser governing schema-document parse policy
 	errorHandler helium.ErrorHandler
 	// maxResourceBytes caps the bytes read from a single include/externalRef
 	// target. Zero means the package default (defaultMaxResourceBytes).
@@ -94,6 +96,26 @@ func (c Compiler) FS(fsys fs.FS) Compiler {
 	return c
 }
 
+// ResourceResolver sets a [helium.ResourceResolver] that loads include and
+// externalRef targets in place of [Compiler.FS], under the ctx of the Compile
+// call. It receives the same names the FS would and takes precedence over it.
+// [Compiler.MaxResourceBytes] still caps each resource.
+func (c Compiler) ResourceResolver(r helium.ResourceResolver) Compiler {
+	c = c.clone()
+	c.cfg.resolver = r
+	return c
+}
+
+// ResourceCache sets a [helium.ResourceCache] that memoizes include and
+// externalRef targets across compiles. A target the cache holds under the same
+// [Compiler.FS] or [Compiler.ResourceResolver] is served without consulting
+// it again. [Compiler.MaxResourceBytes] applies to cached targets too.
+func (c Compiler) ResourceCache(rc *helium.ResourceCache) Compiler {
+	c = c.clone()
+	c.cfg.cache = rc
+	return c
+}
+
 // MaxResourceBytes sets the maximum number of bytes read from a single schema
 // resource pulled in via include or externalRef. A resource larger than the cap
 // fails to load with a compile error. A value <= 0 restores the package default
@@ -0,0 +1,245 @@
+package relaxng
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/helium/stream"
+)
+
+// StreamValidator is a [stream.Backend] that validates a document against a
+// grammar while a [stream.Writer] produces it, so a generator learns about an
+// invalid element or attribute at the call that writes it instead of after
+// the whole document exists. Put it before the backend that stores the
+// output, so that [stream.Tee] stops before invalid content is written:
+//
+//	var buf bytes.Buffer
+//	out := stream.NewWriter(&buf)
+//	w := stream.NewBackendWriter(stream.Tee(relaxng.NewStreamValidator(grammar), stream.WriterBackend(&out)))
+//
+// [NewValidatingWriter] builds that pipeline. Validation follows the
+// derivative algorithm of the RELAX NG authors, so only the remaining content
+// of the open elements is kept and memory does not grow with the size of the
+// document.
+//
+// A Writer reports a start tag once its attributes are complete, so a bad
+// element or attribute fails the first call after the start tag's last
+// attribute. Character data where none is allowed fails at once; a data
+// value is checked when the tag that ends it is written. The error is a [*ValidationError]; when an element is not allowed, or an
+// element ends while required children are missing, its Expected field lists
+// the elements that would have been accepted. A reference to an entity other
+// than the predefined ones cannot be validated without its replacement text
+// and fails with an error wrapping [errors.ErrUnsupported].
+type StreamValidator struct {
+	grammar  *Grammar
+	derivs   *derivs
+	state    *deriv
+	stack    []*streamFrame
+	rootSeen bool
+}
+
+// streamLabel is the Filename of the errors a StreamValidator reports.
+const streamLabel = "(stream)"
+
+// streamFrame is an open element.
+type streamFrame struct {
+	name     string
+	children bool // a child element has started
+	text     strings.Builder
+	checked  bool // text has been checked against the content since the last tag
+}
+
+// NewStreamValidator creates a StreamValidator for grammar.
+func NewStreamValidator(grammar *Grammar) *StreamValidator {
+	return &StreamValidator{grammar: grammar}
+}
+
+// NewValidatingWriter returns a [stream.Writer] that validates the document
+// against grammar as it is written and passes valid events on to out. An
+// invalid event is not written; the error it causes is returned and kept by
+// the Writer, as with any backend error.
+func NewValidatingWriter(out *stream.Writer, grammar *Grammar) stream.Writer {
+	return stream.NewBackendWriter(stream.Tee(NewStreamValidator(grammar), stream.WriterBackend(out)))
+}
+
+func (s *StreamValidator) StartDocument(string, string, string) error {
+	if s.grammar == nil || s.grammar.start == nil {
+		return fmt.Errorf("relaxng: no grammar to validate against: %w", ErrValidationFailed)
+	}
+	if s.derivs == nil {
+		s.derivs = newDerivs(s.grammar)
+	}
+	s.state = s.derivs.convert(s.grammar.start)
+	s.stack = s.stack[:0]
+	s.rootSeen = false
+	return nil
+}
+
+func (s *StreamValidator) EndDocument() error {
+	if !s.rootSeen {
+		return fmt.Errorf("relaxng: the document has no document element: %w", ErrValidationFailed)
+	}
+	return nil
+}
+
+func (s *StreamValidator) DocumentType(string, string, string, string) error {
+	return nil
+}
+
+func (s *StreamValidator) StartElement(name stream.Name, _ []stream.Namespace, attrs []stream.Attribute) error {
+	if s.state == nil {
+		return fmt.Errorf("relaxng: no grammar to validate against: %w", ErrValidationFailed)
+	}
+	d := s.derivs
+	defer d.checkTables()
+
+	state := s.state
+	owner := name.LocalName
+	if n := len(s.stack); n > 0 {
+		parent := s.stack[n-1]
+		owner = parent.name
+		var err error
+		if state, err = s.flushText(parent, state); err != nil {
+			return err
+		}
+		parent.children = true
+	} else if s.rootSeen {
+		return fmt.Errorf("relaxng: second document element %q: %w", name.QName(), ErrValidationFailed)
+	}
+
+	p := d.startTagOpen(state, name.LocalName, name.NamespaceURI)
+	if p.kind == derivNotAllowed {
+		expected := d.expected(state)
+		got := clarkName(name.NamespaceURI, name.LocalName)
+		msg := fmt.Sprintf("Did not expect element %s there", got)
+		if len(expected) == 1 {
+			msg = fmt.Sprintf("Expecting element %s, got %s", expected[0], got)
+		}
+		return s.report(owner, msg, expected)
+	}
+	for _, a := range attrs {
+		next := d.attribute(p, a.LocalName, a.NamespaceURI, a.Value)
+		if next.kind == derivNotAllowed {
+			return s.report(name.LocalName, fmt.Sprintf("Invalid attribute %s for element %s", a.LocalName, name.LocalName), nil)
+		}
+		p = next
+	}
+	p = d.startTagClose(p)
+	if p.kind == derivNotAllowed {
+		return s.report(name.LocalName, fmt.Sprintf("Element %s failed to validate attributes", name.LocalName), nil)
+	}
+
+	s.state = p
+	s.stack = append(s.stack, &streamFrame{name: name.LocalName})
+	s.rootSeen = true
+	return nil
+}
+
+// flushText applies the character data written since the last tag of f.
+// Whitespace between elements is insignificant.
+func (s *StreamValidator) flushText(f *streamFrame, state *deriv) (*deriv, error) {
+	text := f.text.String()
+	f.text.Reset()
+	f.checked = false
+	if isXMLSpaceOnly(text) {
+		return state, nil
+	}
+	p := s.derivs.textDeriv(state, text)
+	if p.kind == derivNotAllowed {
+		return nil, s.report(f.name, fmt.Sprintf("Element %s failed to validate content", f.name), nil)
+	}
+	return p, nil
+}
+
+func (s *StreamValidator) EndElement(stream.Name) error {
+	n := len(s.stack)
+	if n == 0 {
+		return nil
+	}
+	d := s.derivs
+	defer d.checkTables()
+
+	f := s.stack[n-1]
+	p := s.state
+	if f.children {
+		var err error
+		if p, err = s.flushText(f, p); err != nil {
+			return err
+		}
+	} else {
+		// Without child elements the content is a single, possibly empty,
+		// text node, which a data pattern must match in full.
+		text := f.text.String()
+		f.text.Reset()
+		next := d.textDeriv(p, text)
+		if isXMLSpaceOnly(text) {
+			next = d.choice(p, next)
+		}
+		if next.kind == derivNotAllowed {
+			return s.report(f.name, fmt.Sprintf("Element %s failed to validate content", f.name), nil)
+		}
+		p = next
+	}
+
+	end := d.endTag(p)
+	if end.kind == derivNotAllowed {
+		expected := d.expected(p)
+		msg := fmt.Sprintf("Element %s failed to validate content", f.name)
+		if len(expected) == 1 {
+			msg = fmt.Sprintf("Expecting an element %s, got nothing", expected[0])
+		}
+		return s.report(f.name, msg, expected)
+	}
+	s.state = end
+	s.stack = s.stack[:n-1]
+	return nil
+}
+
+func (s *StreamValidator) Text(text string) error {
+	n := len(s.stack)
+	if n == 0 {
+		return nil
+	}
+	f := s.stack[n-1]
+	if !f.checked && !isXMLSpaceOnly(text) {
+		// Fail at the call that writes text where none is allowed rather
+		// than at the next tag.
+		if !s.derivs.acceptsText(s.state) {
+			return s.report(f.name, fmt.Sprintf("Element %s failed to validate content", f.name), nil)
+		}
+		f.checked = true
+	}
+	f.text.WriteString(text)
+	return nil
+}
+
+func (s *StreamValidator) CDATA(text string) error {
+	return s.Text(text)
+}
+
+func (s *StreamValidator) Comment(string) error {
+	return nil
+}
+
+func (s *StreamValidator) ProcessingInstruction(string, string) error {
+	return nil
+}
+
+func (s *StreamValidator) EntityReference(name string) error {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	return fmt.Errorf("relaxng: entity reference &%s; cannot be validated while streaming: %w", name, errors.ErrUnsupported)
+}
+
+// report builds the error for an invalid event reported against element
+// elemName, with the elements that would have been accepted.
+func (s *StreamValidator) report(elemName, msg string, expected []string) error {
+	return &ValidationError{
+		Filename: streamLabel,
+		Element:  elemName,
+		Message:  msg,
+		Expected: expected,
+	}
+}
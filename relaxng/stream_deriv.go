@@ -0,0 +1,515 @@
+package relaxng
+
+import (
+	"cmp"
+	"slices"
+)
+
+// This file implements James Clark's derivative algorithm for RELAX NG
+// ("An algorithm for RELAX NG validation") over the compiled pattern tree.
+// The StreamValidator keeps a single derivative pattern for the whole
+// document: the remaining content of every open element is kept in nested
+// After nodes, so an event only ever looks at the pattern of the innermost
+// open element.
+//
+// Patterns are hash-consed so that equal derivatives are the same node, which
+// keeps choices small and lets derivatives be memoized. The tables are
+// dropped when they grow past derivTableLimit; nodes already in use stay
+// valid, they are only no longer shared.
+
+// derivTableLimit bounds the number of interned nodes and memoized
+// derivatives before the tables are reset.
+const derivTableLimit = 1 << 16
+
+// derivKind enumerates derivative pattern types.
+type derivKind int
+
+const (
+	derivNotAllowed derivKind = iota
+	derivEmpty
+	derivText
+	derivChoice
+	derivInterleave
+	derivGroup
+	derivOneOrMore
+	derivAfter     // p1 is the rest of the current element, p2 what follows its end tag
+	derivElement   // src is a patternElement; its content is converted on entry
+	derivAttribute // src is a patternAttribute
+	derivData      // src is a patternData, patternValue or patternList
+)
+
+// deriv is a node of a derivative pattern.
+type deriv struct {
+	kind     derivKind
+	id       int // creation order, used to order choice alternatives
+	p1, p2   *deriv
+	src      *pattern
+	nullable bool
+}
+
+type derivKey struct {
+	kind   derivKind
+	p1, p2 *deriv
+	src    *pattern
+}
+
+type derivOpenKey struct {
+	p         *deriv
+	local, ns string
+}
+
+// derivs builds and differentiates derivative patterns for one grammar.
+type derivs struct {
+	v          *validator
+	nextID     int
+	nodes      map[derivKey]*deriv
+	refs       map[*pattern]*deriv // converted ref targets
+	contents   map[*pattern]*deriv // converted element contents
+	opens      map[derivOpenKey]*deriv
+	closes     map[*deriv]*deriv
+	notAllowed *deriv
+	empty      *deriv
+	text       *deriv
+}
+
+func newDerivs(grammar *Grammar) *derivs {
+	d := &derivs{
+		// Errors of the value checks are never wanted: the stream validator
+		// reports its own.
+		v: &validator{grammar: grammar, valid: true, suppressDepth: 1},
+	}
+	d.resetTables()
+	return d
+}
+
+func (d *derivs) resetTables() {
+	d.nodes = make(map[derivKey]*deriv)
+	d.refs = make(map[*pattern]*deriv)
+	d.contents = make(map[*pattern]*deriv)
+	d.opens = make(map[derivOpenKey]*deriv)
+	d.closes = make(map[*deriv]*deriv)
+	d.notAllowed = d.intern(derivNotAllowed, nil, nil, nil)
+	d.empty = d.intern(derivEmpty, nil, nil, nil)
+	d.text = d.intern(derivText, nil, nil, nil)
+}
+
+func (d *derivs) intern(kind derivKind, p1, p2 *deriv, src *pattern) *deriv {
+	key := derivKey{kind: kind, p1: p1, p2: p2, src: src}
+	if n, ok := d.nodes[key]; ok {
+		return n
+	}
+	n := &deriv{kind: kind, id: d.nextID, p1: p1, p2: p2, src: src}
+	d.nextID++
+	switch kind {
+	case derivEmpty, derivText:
+		n.nullable = true
+	case derivChoice:
+		n.nullable = p1.nullable || p2.nullable
+	case derivGroup, derivInterleave:
+		n.nullable = p1.nullable && p2.nullable
+	case derivOneOrMore:
+		n.nullable = p1.nullable
+	}
+	d.nodes[key] = n
+	return n
+}
+
+// checkTables resets the tables once they have grown too large. It is only
+// called between events, when no derivative is being built.
+func (d *derivs) checkTables() {
+	if len(d.nodes)+len(d.opens)+len(d.closes) > derivTableLimit {
+		d.resetTables()
+	}
+}
+
+func (d *derivs) choice(p1, p2 *deriv) *deriv {
+	switch {
+	case p1.kind == derivNotAllowed:
+		return p2
+	case p2.kind == derivNotAllowed, p1 == p2:
+		return p1
+	}
+	// Keep choices flat, free of duplicates and in a canonical order so that
+	// equal sets of alternatives intern to the same node.
+	alts := appendAlternatives(nil, p1)
+	alts = appendAlternatives(alts, p2)
+	slices.SortFunc(alts, func(a, b *deriv) int { return cmp.Compare(a.id, b.id) })
+	alts = slices.Compact(alts)
+	n := alts[len(alts)-1]
+	for i := len(alts) - 2; i >= 0; i-- {
+		n = d.intern(derivChoice, alts[i], n, nil)
+	}
+	return n
+}
+
+func appendAlternatives(alts []*deriv, p *deriv) []*deriv {
+	for p.kind == derivChoice {
+		alts = append(alts, p.p1)
+		p = p.p2
+	}
+	return append(alts, p)
+}
+
+func (d *derivs) group(p1, p2 *deriv) *deriv {
+	switch {
+	case p1.kind == derivNotAllowed || p2.kind == derivNotAllowed:
+		return d.notAllowed
+	case p1.kind == derivEmpty:
+		return p2
+	case p2.kind == derivEmpty:
+		return p1
+	}
+	return d.intern(derivGroup, p1, p2, nil)
+}
+
+func (d *derivs) interleave(p1, p2 *deriv) *deriv {
+	switch {
+	case p1.kind == derivNotAllowed || p2.kind == derivNotAllowed:
+		return d.notAllowed
+	case p1.kind == derivEmpty:
+		return p2
+	case p2.kind == derivEmpty:
+		return p1
+	}
+	return d.intern(derivInterleave, p1, p2, nil)
+}
+
+func (d *derivs) after(p1, p2 *deriv) *deriv {
+	if p1.kind == derivNotAllowed || p2.kind == derivNotAllowed {
+		return d.notAllowed
+	}
+	return d.intern(derivAfter, p1, p2, nil)
+}
+
+func (d *derivs) oneOrMore(p *deriv) *deriv {
+	if p.kind == derivNotAllowed || p.kind == derivEmpty {
+		return p
+	}
+	return d.intern(derivOneOrMore, p, nil, nil)
+}
+
+// convert turns a compiled pattern into a derivative pattern. Element content
+// is left to content, so recursive grammars are converted lazily.
+func (d *derivs) convert(pat *pattern) *deriv {
+	if pat == nil {
+		return d.notAllowed
+	}
+	switch pat.kind {
+	case patternEmpty:
+		return d.empty
+	case patternText:
+		return d.text
+	case patternElement:
+		return d.intern(derivElement, nil, nil, pat)
+	case patternAttribute:
+		return d.intern(derivAttribute, nil, nil, pat)
+	case patternData, patternValue, patternList:
+		return d.intern(derivData, nil, nil, pat)
+	case patternGroup:
+		return d.convertAll(pat.children, d.group)
+	case patternInterleave:
+		return d.convertAll(pat.children, d.interleave)
+	case patternMixed:
+		return d.interleave(d.text, d.convertAll(pat.children, d.group))
+	case patternChoice:
+		if len(pat.children) == 0 {
+			return d.notAllowed
+		}
+		return d.convertAll(pat.children, d.choice)
+	case patternOptional:
+		return d.choice(d.convertAll(pat.children, d.group), d.empty)
+	case patternZeroOrMore:
+		return d.choice(d.oneOrMore(d.convertAll(pat.children, d.group)), d.empty)
+	case patternOneOrMore:
+		return d.oneOrMore(d.convertAll(pat.children, d.group))
+	case patternRef, patternParentRef:
+		def := pat.resolved
+		if def == nil {
+			return d.notAllowed
+		}
+		if n, ok := d.refs[def]; ok {
+			return n
+		}
+		// A reference cycle that does not pass through an element is not
+		// allowed by the grammar; it matches nothing here.
+		d.refs[def] = d.notAllowed
+		n := d.convert(def)
+		d.refs[def] = n
+		return n
+	}
+	return d.notAllowed
+}
+
+func (d *derivs) convertAll(pats []*pattern, combine func(p1, p2 *deriv) *deriv) *deriv {
+	if len(pats) == 0 {
+		return d.empty
+	}
+	n := d.convert(pats[len(pats)-1])
+	for i := len(pats) - 2; i >= 0; i-- {
+		n = combine(d.convert(pats[i]), n)
+	}
+	return n
+}
+
+// content returns the attributes and content of element pattern pat.
+func (d *derivs) content(pat *pattern) *deriv {
+	if n, ok := d.contents[pat]; ok {
+		return n
+	}
+	n := d.group(d.convertAll(pat.attrs, d.group), d.convertAll(pat.children, d.group))
+	d.contents[pat] = n
+	return n
+}
+
+// startTagOpen is the derivative of p for the start of an element.
+func (d *derivs) startTagOpen(p *deriv, local, ns string) *deriv {
+	key := derivOpenKey{p: p, local: local, ns: ns}
+	if n, ok := d.opens[key]; ok {
+		return n
+	}
+	n := d.startTagOpenNode(p, local, ns)
+	d.opens[key] = n
+	return n
+}
+
+func (d *derivs) startTagOpenNode(p *deriv, local, ns string) *deriv {
+	switch p.kind {
+	case derivChoice:
+		return d.choice(d.startTagOpen(p.p1, local, ns), d.startTagOpen(p.p2, local, ns))
+	case derivElement:
+		if !elementNameMatches(p.src, local, ns) {
+			return d.notAllowed
+		}
+		return d.after(d.content(p.src), d.empty)
+	case derivInterleave:
+		p1, p2 := p.p1, p.p2
+		return d.choice(
+			d.applyAfter(func(x *deriv) *deriv { return d.interleave(x, p2) }, d.startTagOpen(p1, local, ns)),
+			d.applyAfter(func(x *deriv) *deriv { return d.interleave(p1, x) }, d.startTagOpen(p2, local, ns)),
+		)
+	case derivOneOrMore:
+		rest := d.choice(p, d.empty)
+		return d.applyAfter(func(x *deriv) *deriv { return d.group(x, rest) }, d.startTagOpen(p.p1, local, ns))
+	case derivGroup:
+		p2 := p.p2
+		n := d.applyAfter(func(x *deriv) *deriv { return d.group(x, p2) }, d.startTagOpen(p.p1, local, ns))
+		if p.p1.nullable {
+			n = d.choice(n, d.startTagOpen(p2, local, ns))
+		}
+		return n
+	case derivAfter:
+		p2 := p.p2
+		return d.applyAfter(func(x *deriv) *deriv { return d.after(x, p2) }, d.startTagOpen(p.p1, local, ns))
+	}
+	return d.notAllowed
+}
+
+// applyAfter applies f to what follows the end tag in every alternative of p.
+func (d *derivs) applyAfter(f func(*deriv) *deriv, p *deriv) *deriv {
+	switch p.kind {
+	case derivAfter:
+		return d.after(p.p1, f(p.p2))
+	case derivChoice:
+		return d.choice(d.applyAfter(f, p.p1), d.applyAfter(f, p.p2))
+	}
+	return d.notAllowed
+}
+
+// attribute is the derivative of p for an attribute of the open start tag.
+func (d *derivs) attribute(p *deriv, local, ns, value string) *deriv {
+	switch p.kind {
+	case derivAfter:
+		return d.after(d.attribute(p.p1, local, ns, value), p.p2)
+	case derivChoice:
+		return d.choice(d.attribute(p.p1, local, ns, value), d.attribute(p.p2, local, ns, value))
+	case derivGroup:
+		return d.choice(
+			d.group(d.attribute(p.p1, local, ns, value), p.p2),
+			d.group(p.p1, d.attribute(p.p2, local, ns, value)),
+		)
+	case derivInterleave:
+		return d.choice(
+			d.interleave(d.attribute(p.p1, local, ns, value), p.p2),
+			d.interleave(p.p1, d.attribute(p.p2, local, ns, value)),
+		)
+	case derivOneOrMore:
+		return d.group(d.attribute(p.p1, local, ns, value), d.choice(p, d.empty))
+	case derivAttribute:
+		if !attrNameMatches(p.src, local, ns) {
+			return d.notAllowed
+		}
+		if len(p.src.children) > 0 && d.v.matchAttrContent(p.src.children[0], value, nil) != 0 {
+			return d.notAllowed
+		}
+		return d.empty
+	}
+	return d.notAllowed
+}
+
+// startTagClose is the derivative of p for the end of a start tag: attribute
+// patterns that were not matched are no longer satisfiable.
+func (d *derivs) startTagClose(p *deriv) *deriv {
+	if n, ok := d.closes[p]; ok {
+		return n
+	}
+	var n *deriv
+	switch p.kind {
+	case derivAfter:
+		n = d.after(d.startTagClose(p.p1), p.p2)
+	case derivChoice:
+		n = d.choice(d.startTagClose(p.p1), d.startTagClose(p.p2))
+	case derivGroup:
+		n = d.group(d.startTagClose(p.p1), d.startTagClose(p.p2))
+	case derivInterleave:
+		n = d.interleave(d.startTagClose(p.p1), d.startTagClose(p.p2))
+	case derivOneOrMore:
+		n = d.oneOrMore(d.startTagClose(p.p1))
+	case derivAttribute:
+		n = d.notAllowed
+	default:
+		n = p
+	}
+	d.closes[p] = n
+	return n
+}
+
+// textDeriv is the derivative of p for a run of character data.
+func (d *derivs) textDeriv(p *deriv, s string) *deriv {
+	switch p.kind {
+	case derivChoice:
+		return d.choice(d.textDeriv(p.p1, s), d.textDeriv(p.p2, s))
+	case derivInterleave:
+		return d.choice(d.interleave(d.textDeriv(p.p1, s), p.p2), d.interleave(p.p1, d.textDeriv(p.p2, s)))
+	case derivGroup:
+		n := d.group(d.textDeriv(p.p1, s), p.p2)
+		if p.p1.nullable {
+			n = d.choice(n, d.textDeriv(p.p2, s))
+		}
+		return n
+	case derivAfter:
+		return d.after(d.textDeriv(p.p1, s), p.p2)
+	case derivOneOrMore:
+		return d.group(d.textDeriv(p.p1, s), d.choice(p, d.empty))
+	case derivText:
+		return p
+	case derivData:
+		if d.dataMatches(p.src, s) {
+			return d.empty
+		}
+	}
+	return d.notAllowed
+}
+
+func (d *derivs) dataMatches(pat *pattern, s string) bool {
+	switch pat.kind {
+	case patternData:
+		return d.v.matchData(pat, s) == 0
+	case patternValue:
+		return d.v.matchValue(pat, s) == 0
+	case patternList:
+		return d.v.matchListContent(pat, s, nil) == 0
+	}
+	return false
+}
+
+// acceptsText reports whether character data can come next in p.
+func (d *derivs) acceptsText(p *deriv) bool {
+	switch p.kind {
+	case derivChoice, derivInterleave:
+		return d.acceptsText(p.p1) || d.acceptsText(p.p2)
+	case derivGroup:
+		return d.acceptsText(p.p1) || p.p1.nullable && d.acceptsText(p.p2)
+	case derivOneOrMore, derivAfter:
+		return d.acceptsText(p.p1)
+	case derivText, derivData:
+		return true
+	}
+	return false
+}
+
+// endTag is the derivative of p for the end tag of the current element.
+func (d *derivs) endTag(p *deriv) *deriv {
+	switch p.kind {
+	case derivChoice:
+		return d.choice(d.endTag(p.p1), d.endTag(p.p2))
+	case derivAfter:
+		if p.p1.nullable {
+			return p.p2
+		}
+	}
+	return d.notAllowed
+}
+
+// expected lists the names of the elements p admits next, in the notation of
+// [helium.ClarkName]; "*" stands for any name and "{ns}*" for any name in ns.
+func (d *derivs) expected(p *deriv) []string {
+	var names []string
+	seen := make(map[*deriv]struct{})
+	var walk func(*deriv)
+	walk = func(p *deriv) {
+		if _, ok := seen[p]; ok {
+			return
+		}
+		seen[p] = struct{}{}
+		switch p.kind {
+		case derivChoice, derivInterleave:
+			walk(p.p1)
+			walk(p.p2)
+		case derivGroup:
+			walk(p.p1)
+			if p.p1.nullable {
+				walk(p.p2)
+			}
+		case derivOneOrMore, derivAfter:
+			walk(p.p1)
+		case derivElement:
+			names = appendNameClassNames(names, p.src)
+		}
+	}
+	walk(p)
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+func appendNameClassNames(names []string, pat *pattern) []string {
+	if pat.nameClass == nil {
+		if pat.name == "" {
+			return names
+		}
+		return append(names, clarkName(pat.ns, pat.name))
+	}
+	var walk func(*nameClass)
+	walk = func(nc *nameClass) {
+		if nc == nil {
+			return
+		}
+		switch nc.kind {
+		case ncName:
+			names = append(names, clarkName(nc.ns, nc.name))
+		case ncAnyName:
+			names = append(names, "*")
+		case ncNsName:
+			names = append(names, clarkName(nc.ns, "*"))
+		case ncChoice:
+			walk(nc.left)
+			walk(nc.right)
+		}
+	}
+	walk(pat.nameClass)
+	return names
+}
+
+func clarkName(ns, local string) string {
+	if ns == "" {
+		return local
+	}
+	return "{" + ns + "}" + local
+}
+
+// elementNameMatches reports whether element pattern pat admits the name.
+func elementNameMatches(pat *pattern, local, ns string) bool {
+	if pat.nameClass != nil {
+		return nameClassMatches(pat.nameClass, local, ns)
+	}
+	return pat.name != "" && pat.name == local && pat.ns == ns
+}
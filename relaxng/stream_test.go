@@ -0,0 +1,244 @@
+package relaxng_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/relaxng"
+	"github.com/lestrrat-go/helium/sax"
+	"github.com/lestrrat-go/helium/stream"
+	"github.com/stretchr/testify/require"
+)
+
+const streamGrammar = `<element name="filing" xmlns="http://relaxng.org/ns/structure/1.0"
+    datatypeLibrary="http://www.w3.org/2001/XMLSchema-datatypes">
+  <element name="header"><text/></element>
+  <oneOrMore>
+    <element name="entry">
+      <attribute name="code"><data type="NCName"/></attribute>
+      <data type="decimal"/>
+    </element>
+  </oneOrMore>
+  <optional>
+    <choice>
+      <element name="footer"><text/></element>
+      <element name="signature"><empty/></element>
+    </choice>
+  </optional>
+</element>`
+
+func compileStreamGrammar(t *testing.T) *relaxng.Grammar {
+	t.Helper()
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(streamGrammar))
+	require.NoError(t, err)
+	grammar, err := relaxng.NewCompiler().Compile(t.Context(), doc)
+	require.NoError(t, err)
+	return grammar
+}
+
+func TestValidatingWriter(t *testing.T) {
+	t.Parallel()
+	grammar := compileStreamGrammar(t)
+
+	newWriter := func(t *testing.T) *stream.Writer {
+		t.Helper()
+		var buf bytes.Buffer
+		out := stream.NewWriter(&buf)
+		w := relaxng.NewValidatingWriter(&out, grammar)
+		require.NoError(t, w.StartDocument("", "", ""))
+		require.NoError(t, w.StartElement("filing"))
+		return &w
+	}
+
+	t.Run("valid document", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		out := stream.NewWriter(&buf)
+		w := relaxng.NewValidatingWriter(&out, grammar)
+		require.NoError(t, w.StartDocument("", "", ""))
+		require.NoError(t, w.StartElement("filing"))
+		require.NoError(t, w.WriteElement("header", "Q3"))
+		for _, code := range []string{"a1", "b2"} {
+			require.NoError(t, w.StartElement("entry"))
+			require.NoError(t, w.WriteAttribute("code", code))
+			require.NoError(t, w.WriteString(" 12.50 "))
+			require.NoError(t, w.EndElement())
+		}
+		require.NoError(t, w.StartElement("signature"))
+		require.NoError(t, w.EndElement())
+		require.NoError(t, w.EndElement())
+		require.NoError(t, w.EndDocument())
+		require.NoError(t, w.Flush())
+		require.Equal(t, `<?xml version="1.0"?>`+"\n"+`<filing><header>Q3</header><entry code="a1"> 12.50 </entry><entry code="b2"> 12.50 </entry><signature/></filing>`+"\n", buf.String())
+	})
+
+	t.Run("unexpected element fails at once", func(t *testing.T) {
+		t.Parallel()
+		w := newWriter(t)
+		require.NoError(t, w.StartElement("entry"))
+		// The start tag is reported once its attributes are complete.
+		err := w.WriteString("1")
+		var ve *relaxng.ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Equal(t, "filing", ve.Element)
+		require.Equal(t, "Expecting element header, got entry", ve.Message)
+		require.Equal(t, []string{"header"}, ve.Expected)
+		require.Equal(t, err, w.Error(), "the error is sticky")
+	})
+
+	t.Run("several expected elements", func(t *testing.T) {
+		t.Parallel()
+		w := newWriter(t)
+		require.NoError(t, w.WriteElement("header", "Q3"))
+		require.NoError(t, w.StartElement("entry"))
+		require.NoError(t, w.WriteAttribute("code", "a1"))
+		require.NoError(t, w.WriteString("1"))
+		require.NoError(t, w.EndElement())
+		require.NoError(t, w.StartElement("header"))
+		var ve *relaxng.ValidationError
+		require.ErrorAs(t, w.EndElement(), &ve)
+		require.Equal(t, "Did not expect element header there", ve.Message)
+		require.Equal(t, []string{"entry", "footer", "signature"}, ve.Expected)
+	})
+
+	t.Run("missing child", func(t *testing.T) {
+		t.Parallel()
+		w := newWriter(t)
+		require.NoError(t, w.WriteElement("header", "Q3"))
+		var ve *relaxng.ValidationError
+		require.ErrorAs(t, w.EndElement(), &ve)
+		require.Equal(t, "filing", ve.Element)
+		require.Equal(t, "Expecting an element entry, got nothing", ve.Message)
+		require.Equal(t, []string{"entry"}, ve.Expected)
+	})
+
+	t.Run("invalid attribute value", func(t *testing.T) {
+		t.Parallel()
+		w := newWriter(t)
+		require.NoError(t, w.WriteElement("header", "Q3"))
+		require.NoError(t, w.StartElement("entry"))
+		require.NoError(t, w.WriteAttribute("code", "1a"))
+		var ve *relaxng.ValidationError
+		require.ErrorAs(t, w.WriteString("1"), &ve)
+		require.Equal(t, "Invalid attribute code for element entry", ve.Message)
+	})
+
+	t.Run("missing attribute", func(t *testing.T) {
+		t.Parallel()
+		w := newWriter(t)
+		require.NoError(t, w.WriteElement("header", "Q3"))
+		require.NoError(t, w.StartElement("entry"))
+		var ve *relaxng.ValidationError
+		require.ErrorAs(t, w.WriteString("1"), &ve)
+		require.Equal(t, "Element entry failed to validate attributes", ve.Message)
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		t.Parallel()
+		w := newWriter(t)
+		require.NoError(t, w.WriteElement("header", "Q3"))
+		require.NoError(t, w.StartElement("entry"))
+		require.NoError(t, w.WriteAttribute("code", "a1"))
+		require.NoError(t, w.WriteString("twelve"))
+		var ve *relaxng.ValidationError
+		require.ErrorAs(t, w.EndElement(), &ve)
+		require.Equal(t, "Element entry failed to validate content", ve.Message)
+	})
+
+	t.Run("text in element-only content", func(t *testing.T) {
+		t.Parallel()
+		w := newWriter(t)
+		require.NoError(t, w.WriteString("\n  "))
+		var ve *relaxng.ValidationError
+		require.ErrorAs(t, w.WriteString("stray"), &ve)
+		require.Equal(t, "filing", ve.Element)
+	})
+
+	t.Run("entity reference", func(t *testing.T) {
+		t.Parallel()
+		w := newWriter(t)
+		require.NoError(t, w.StartElement("header"))
+		require.ErrorIs(t, w.WriteEntityRef("company"), errors.ErrUnsupported)
+	})
+}
+
+func TestValidatingWriterInterleave(t *testing.T) {
+	t.Parallel()
+	const schema = `<element name="card" xmlns="http://relaxng.org/ns/structure/1.0">
+  <interleave>
+    <element name="name"><text/></element>
+    <zeroOrMore><element name="email"><text/></element></zeroOrMore>
+  </interleave>
+</element>`
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(schema))
+	require.NoError(t, err)
+	grammar, err := relaxng.NewCompiler().Compile(t.Context(), doc)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	out := stream.NewWriter(&buf)
+	w := relaxng.NewValidatingWriter(&out, grammar)
+	require.NoError(t, w.StartDocument("", "", ""))
+	require.NoError(t, w.StartElement("card"))
+	require.NoError(t, w.WriteElement("email", "a@example.com"))
+	require.NoError(t, w.WriteElement("name", "A"))
+	require.NoError(t, w.WriteElement("email", "b@example.com"))
+	require.NoError(t, w.StartElement("name"))
+	var ve *relaxng.ValidationError
+	require.ErrorAs(t, w.EndElement(), &ve)
+	require.Equal(t, []string{"email"}, ve.Expected)
+}
+
+func TestValidatingWriterNilGrammar(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	out := stream.NewWriter(&buf)
+	w := relaxng.NewValidatingWriter(&out, nil)
+	require.ErrorIs(t, w.StartDocument("", "", ""), relaxng.ErrValidationFailed)
+}
+
+// TestStreamValidatorGoldenAgreement replays every golden instance through a
+// validating Writer and checks that it accepts exactly the documents the
+// tree validator accepts.
+func TestStreamValidatorGoldenAgreement(t *testing.T) {
+	t.Parallel()
+	for _, tc := range discoverTests(t) {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if reason := shouldSkip(tc.name); reason != "" {
+				t.Skipf("skipping: %s", reason)
+			}
+			collector := helium.NewErrorCollector(t.Context(), helium.ErrorLevelNone)
+			grammar, err := relaxng.NewCompiler().FS(helium.PermissiveFS()).ErrorHandler(collector).CompileFile(t.Context(), tc.rngPath)
+			require.NoError(t, err)
+			_ = collector.Close()
+			if _, compileErrors := partitionCompileErrors(collector.Errors()); compileErrors != "" {
+				t.Skip("schema does not compile")
+			}
+			data, err := os.ReadFile(tc.xmlPath)
+			require.NoError(t, err)
+			doc, err := helium.NewParser().Parse(t.Context(), data)
+			require.NoError(t, err)
+			domErr := relaxng.NewValidator(grammar).Validate(t.Context(), doc)
+
+			var buf bytes.Buffer
+			out := stream.NewWriter(&buf)
+			w := relaxng.NewValidatingWriter(&out, grammar)
+			err = helium.EmitSAX(t.Context(), doc, sax.NewWriterHandler(&w))
+			if err == nil {
+				err = w.Error()
+			}
+			if errors.Is(err, errors.ErrUnsupported) {
+				t.Skip("instance needs an unsupported stream check")
+			}
+			if domErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+		})
+	}
+}
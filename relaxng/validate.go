@@ -319,7 +319,7 @@ func (v *validator) validateElement(pat *pattern, state *validState) int {
 	// Check all attrs consumed
 	for i, attr := range instanceAttrs {
 		if !attrUsed[i] {
-			v.addError(elem, fmt.Sprintf("Invalid attribute %s for element %s", attr.LocalName(), elem.LocalName()))
+			v.addAttrError(elem, attr, fmt.Sprintf("Invalid attribute %s for element %s", attr.LocalName(), elem.LocalName()))
 			v.suppressDepth = savedSuppress
 			return -1
 		}
@@ -1150,8 +1150,11 @@ func (v *validator) matchOneAttr(pat *pattern, attrs []*helium.Attribute, used [
 }
 
 func (v *validator) attributeMatches(pat *pattern, attr *helium.Attribute) bool {
-	localName := attr.LocalName()
-	uri := attr.URI()
+	return attrNameMatches(pat, attr.LocalName(), attr.URI())
+}
+
+// attrNameMatches reports whether attribute pattern pat admits the name.
+func attrNameMatches(pat *pattern, localName, uri string) bool {
 	if pat.nameClass != nil {
 		return nameClassMatches(pat.nameClass, localName, uri)
 	}
@@ -2435,12 +2438,28 @@ func isXMLSpaceOnly(s string) bool {
 
 // addError adds a validation error (suppressed when inside choice branches).
 func (v *validator) addError(elem *helium.Element, msg string) {
+	v.addNodeError(elem, elem, msg)
+}
+
+// addAttrError adds a validation error about attribute attr of elem.
+func (v *validator) addAttrError(elem *helium.Element, attr *helium.Attribute, msg string) {
+	v.addNodeError(elem, attr, msg)
+}
+
+// addNodeError adds a validation error reported against elem whose position
+// is that of at.
+func (v *validator) addNodeError(elem *helium.Element, at helium.Node, msg string) {
 	if v.suppressDepth > 0 {
 		return
 	}
-	line := elem.Line()
-	errStr := validityError(v.filename, line, elem.LocalName(), msg)
-	v.pendingErrors = append(v.pendingErrors, helium.NewLeveledError(errStr, helium.ErrorLevelError))
+	ve := &ValidationError{
+		Filename: v.filename,
+		Line:     elem.Line(),
+		Element:  elem.LocalName(),
+		Message:  msg,
+	}
+	ve.Position, _ = helium.PositionOf(at)
+	v.pendingErrors = append(v.pendingErrors, &leveledValidationError{ValidationError: ve, level: helium.ErrorLevelError})
 	v.valid = false
 }
 
@@ -0,0 +1,46 @@
+package relaxng_test
+
+import (
+	"errors"
+	"testing"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/relaxng"
+	"github.com/stretchr/testify/require"
+)
+
+// Validation errors surface as *relaxng.ValidationError via errors.As, and
+// carry the source position of the offending node when the instance was
+// parsed with helium.Parser.TrackPositions.
+func TestValidationError_Position(t *testing.T) {
+	t.Parallel()
+	const schemaXML = `<element name="root" xmlns="http://relaxng.org/ns/structure/1.0">
+  <element name="item"><empty/></element>
+</element>`
+	const instanceXML = "<root>\n  <item  bad='1'/>\n</root>"
+
+	schemaDoc, err := helium.NewParser().Parse(t.Context(), []byte(schemaXML))
+	require.NoError(t, err)
+	grammar, err := relaxng.NewCompiler().Compile(t.Context(), schemaDoc)
+	require.NoError(t, err)
+
+	doc, err := helium.NewParser().TrackPositions(true).Parse(t.Context(), []byte(instanceXML))
+	require.NoError(t, err)
+
+	collector := helium.NewErrorCollector(t.Context(), helium.ErrorLevelNone)
+	_ = relaxng.NewValidator(grammar).Label("doc.xml").ErrorHandler(collector).Validate(t.Context(), doc)
+	require.NoError(t, collector.Close())
+
+	var ve *relaxng.ValidationError
+	for _, e := range collector.Errors() {
+		if errors.As(e, &ve) && ve.Element == "item" {
+			break
+		}
+		ve = nil
+	}
+	require.NotNil(t, ve)
+	require.Equal(t, "doc.xml", ve.Filename)
+	require.Equal(t, 2, ve.Line)
+	require.Equal(t, "bad='1'", instanceXML[ve.Position.Start.Offset:ve.Position.End.Offset])
+	require.Equal(t, helium.Location{Line: 2, Column: 10, Offset: 16}, ve.Position.Start)
+}
@@ -0,0 +1,270 @@
+package helium
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+)
+
+// Range is a span of bytes in the source a document was parsed from: Start
+// is the offset of its first byte and End the offset just past its last one.
+// Offsets are those reported by [Location.Offset].
+type Range struct {
+	Start int
+	End   int
+}
+
+// Reparse applies an edit to the source of doc, replacing the bytes in old
+// with repl, and brings doc up to date with the edited source. doc must have
+// been parsed with [Parser.TrackPositions] and [Parser.RetainSource], and p
+// should be configured like the parser that produced it.
+//
+// When the edit lies within the content of an element, only the smallest
+// such element is parsed again: its replacement is parsed in the context of
+// the element's parent and spliced in with [Element.Replace], and doc is
+// returned with the positions of all other nodes adjusted and its ID table
+// updated. Nodes outside that element are kept as they are. The cost of the
+// update depends on the size of that element and the depth of the tree, not
+// on the size of the document. Otherwise, when the edit touches the tags of
+// every enclosing element, the prolog, or markup that is no longer
+// well-balanced, the whole edited source is parsed and a new document is
+// returned; the error of that parse, if any, is returned as well.
+//
+// Reparse returns [ErrNoSourcePositions] when doc carries no source.
+func (p Parser) Reparse(ctx context.Context, doc *Document, old Range, repl []byte) (*Document, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p = p.normalized()
+	if doc == nil {
+		return nil, ErrNilNode
+	}
+	sm := doc.source
+	if sm == nil || sm.text == nil {
+		return nil, ErrNoSourcePositions
+	}
+	size := sm.text.len()
+	if old.Start < 0 || old.Start > old.End || old.End > size {
+		return nil, fmt.Errorf("helium: reparse range [%d, %d) outside source of %d bytes: %w", old.Start, old.End, size, ErrInvalidArgument)
+	}
+
+	if target := sm.enclosingElement(old); target != nil {
+		ok, err := p.reparseElement(ctx, doc, target, old, repl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return doc, nil
+		}
+	}
+
+	text := make([]byte, 0, size-(old.End-old.Start)+len(repl))
+	text = sm.text.appendSlice(text, 0, old.Start)
+	text = append(text, repl...)
+	text = sm.text.appendSlice(text, old.End, size)
+	return p.reparseFull(ctx, doc, text)
+}
+
+// reparseElement parses the element at span again with the edit applied,
+// and splices the result in. It reports false when the edited markup no
+// longer forms a single element of the same name.
+func (p Parser) reparseElement(ctx context.Context, doc *Document, span *nodeSpan, old Range, repl []byte) (bool, error) {
+	sm := doc.source
+	target := span.node.(*Element) //nolint:forcetypeassert // enclosingElement returns elements
+	start := span.start()
+	end := start + span.size
+	frag := make([]byte, 0, span.size-(old.End-old.Start)+len(repl))
+	frag = sm.text.appendSlice(frag, start, old.Start)
+	frag = append(frag, repl...)
+	frag = sm.text.appendSlice(frag, old.End, end)
+
+	// IDs the fragment declares are collected apart from the document's, so
+	// that a failed parse leaves the table as it was.
+	ids := doc.ids
+	doc.ids = nil
+	rec := newSourceRecorder(true, doc.lexical != nil, false, nil)
+	// Diagnostics are held back until the fragment is known to stand on its
+	// own; otherwise the full parse reports them.
+	acc := &errorAccumulator{}
+	first, err := p.ErrorHandler(acc).parseInNodeContext(ctx, target.Parent(), frag, rec)
+	fragIDs := doc.ids
+	doc.ids = ids
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	replacement, ok := first.(*Element)
+	if !ok || replacement.NextSibling() != nil || replacement.Name() != target.Name() {
+		return false, nil
+	}
+	fs := rec.fragment()
+	if fs == nil || fs.node != Node(replacement) {
+		return false, nil
+	}
+
+	li := doc.lexical
+	var lead []byte
+	if li != nil {
+		if ln, found := li.nodes[target]; found {
+			lead = ln.lead
+		}
+	}
+	if err := target.Replace(replacement); err != nil {
+		return false, err
+	}
+	forgetIDs(doc, target)
+	forgetSubtree(doc, target)
+
+	// The replacement starts where the target did, so it takes the target's
+	// place among its parent's kids as is; everything after it moves by
+	// delta.
+	delta := fs.size - span.size
+	parent := span.parent
+	fs.parent = parent
+	fs.index = span.index
+	fs.off = span.off
+	parent.kids[span.index] = fs
+	for n, s := range rec.spans {
+		sm.nodes[n] = s
+	}
+	for c, a := fs, parent; a != nil; c, a = a, a.parent {
+		a.addShift(c.index+1, delta)
+		a.size += delta
+	}
+	sm.text.replace(old.Start, old.End, repl)
+
+	if li != nil {
+		for n, ln := range rec.info.nodes {
+			li.nodes[n] = ln
+		}
+		if ln, found := li.nodes[replacement]; found {
+			ln.lead = lead
+		}
+	}
+	if len(fragIDs) > 0 {
+		if doc.ids == nil {
+			doc.ids = make(map[string]*Element, len(fragIDs))
+		}
+		maps.Copy(doc.ids, fragIDs)
+	}
+
+	if h := p.cfg.errorHandler; h != nil {
+		for _, e := range acc.collectErrors() {
+			h.Handle(ctx, e)
+		}
+	}
+	return true, nil
+}
+
+// reparseFull parses the complete edited source into a new document.
+func (p Parser) reparseFull(ctx context.Context, doc *Document, text []byte) (*Document, error) {
+	q := p.TrackPositions(true).RetainSource(true)
+	if doc.lexical != nil {
+		q = q.PreserveLexical(true)
+	}
+	if doc.source.transcoded {
+		// text is UTF-8 whatever the declaration says.
+		q = q.IgnoreEncoding(true)
+	}
+	newDoc, err := q.Parse(ctx, text)
+	if newDoc != nil && doc.URL() != "" {
+		newDoc.SetURL(doc.URL())
+	}
+	return newDoc, err
+}
+
+// enclosingElement returns the extent of the innermost element whose
+// content contains r entirely, or nil when r reaches into the tags of the
+// document element or lies outside it.
+func (sm *sourceMap) enclosingElement(r Range) *nodeSpan {
+	var found *nodeSpan
+	s, start := sm.root, 0
+	for {
+		i := sort.Search(len(s.kids), func(i int) bool {
+			return start+s.kids[i].off+s.shiftAt(i) > r.Start
+		}) - 1
+		if i < 0 {
+			return found
+		}
+		kid := s.kids[i]
+		if _, ok := kid.node.(*Element); !ok || kid.node.Parent() != s.node || kid.close == 0 {
+			// Not an element, an element moved since it was parsed, or an
+			// empty-element tag, which has no content.
+			return found
+		}
+		kidStart := start + kid.off + s.shiftAt(i)
+		if r.Start < kidStart+kid.open || r.End > kidStart+kid.size-kid.close {
+			return found
+		}
+		found = kid
+		s, start = kid, kidStart
+	}
+}
+
+// fragment returns the extent of the single top-level node of a fragment
+// recorded by parseInNodeContext, or nil.
+func (r *sourceRecorder) fragment() *nodeSpan {
+	if r.input == nil || r.failed || len(r.open) > 0 || len(r.root.kids) != 1 {
+		return nil
+	}
+	r.closeRun()
+	if r.lexical {
+		r.info.seal()
+	}
+	r.input.Retain(-1)
+	kid := r.root.kids[0]
+	r.root.relativize(r.spans, 0)
+	if len(r.root.kids) != 1 {
+		return nil
+	}
+	return kid
+}
+
+// forgetIDs drops the entries of the document's ID table that point into
+// the subtree of e.
+func forgetIDs(doc *Document, e *Element) {
+	if len(doc.ids) == 0 {
+		return
+	}
+	forget := func(e *Element) {
+		for attr := e.properties; attr != nil; attr = attr.NextAttribute() {
+			if attr.AType() != enum.AttrID && attr.Name() != lexicon.QNameXMLID {
+				continue
+			}
+			if id := strings.TrimSpace(attr.Value()); doc.ids[id] == e {
+				delete(doc.ids, id)
+			}
+		}
+	}
+	forget(e)
+	for n := range Descendants(e) {
+		if c, ok := n.(*Element); ok {
+			forget(c)
+		}
+	}
+}
+
+// forgetSubtree drops the source records of n and everything below it.
+func forgetSubtree(doc *Document, n Node) {
+	if doc.source != nil {
+		delete(doc.source.nodes, n)
+	}
+	if doc.lexical != nil {
+		delete(doc.lexical.nodes, n)
+	}
+	if e, ok := n.(*Element); ok {
+		for attr := e.properties; attr != nil; attr = attr.NextAttribute() {
+			forgetSubtree(doc, attr)
+		}
+	}
+	for c := range Children(n) {
+		forgetSubtree(doc, c)
+	}
+}
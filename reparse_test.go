@@ -0,0 +1,189 @@
+package helium_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/stretchr/testify/require"
+)
+
+// edit applies an edit to src the way Reparse applies it to a document's
+// source.
+func edit(src string, r helium.Range, repl string) string {
+	return src[:r.Start] + repl + src[r.End:]
+}
+
+// requireSamePositions checks every tracked position in got against a
+// document freshly parsed from src.
+func requireSamePositions(t *testing.T, src string, got *helium.Document) {
+	t.Helper()
+	want, err := helium.NewParser().TrackPositions(true).Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+
+	var walk func(w, g helium.Node)
+	walk = func(w, g helium.Node) {
+		wp, wok := helium.PositionOf(w)
+		gp, gok := helium.PositionOf(g)
+		require.Equal(t, wok, gok, "%s", w.Name())
+		require.Equal(t, wp, gp, "%s", w.Name())
+		if we, ok := w.(*helium.Element); ok {
+			ge := g.(*helium.Element)
+			wa, ga := we.Attributes(), ge.Attributes()
+			require.Len(t, ga, len(wa))
+			for i := range wa {
+				walk(wa[i], ga[i])
+			}
+		}
+		wc, gc := w.FirstChild(), g.FirstChild()
+		for ; wc != nil && gc != nil; wc, gc = wc.NextSibling(), gc.NextSibling() {
+			walk(wc, gc)
+		}
+		require.Nil(t, wc)
+		require.Nil(t, gc)
+	}
+	walk(want, got)
+}
+
+func TestReparse(t *testing.T) {
+	t.Parallel()
+
+	p := helium.NewParser().TrackPositions(true).RetainSource(true)
+	const src = "<?xml version=\"1.0\"?>\n<book>\n  <title lang='en'>Old</title>\n  <chapter>\n    <para>one</para>\n    <para>two</para>\n  </chapter>\n  <appendix/>\n</book>\n"
+
+	t.Run("edit inside an element", func(t *testing.T) {
+		t.Parallel()
+		doc, err := p.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		chapter := doc.DocumentElement().FirstChild().NextSibling().NextSibling().NextSibling().(*helium.Element)
+		title := doc.DocumentElement().FirstChild().NextSibling()
+
+		off := strings.Index(src, "two")
+		r := helium.Range{Start: off, End: off + len("two")}
+		got, err := p.Reparse(t.Context(), doc, r, []byte("two\nand <b>three</b>"))
+		require.NoError(t, err)
+		require.Same(t, doc, got, "the document is updated in place")
+		require.Same(t, title, got.DocumentElement().FirstChild().NextSibling(), "nodes outside the edit are kept")
+		require.Same(t, chapter, got.DocumentElement().FirstChild().NextSibling().NextSibling().NextSibling(), "enclosing elements are kept")
+
+		newSrc := edit(src, r, "two\nand <b>three</b>")
+		s, err := helium.WriteString(got)
+		require.NoError(t, err)
+		require.Contains(t, s, "<para>two\nand <b>three</b></para>")
+		requireSamePositions(t, newSrc, got)
+
+		// Positions are in terms of the edited source, so edits can be chained.
+		off = strings.Index(newSrc, "one")
+		r = helium.Range{Start: off, End: off + len("one")}
+		got, err = p.Reparse(t.Context(), got, r, []byte("1"))
+		require.NoError(t, err)
+		require.Same(t, doc, got)
+		requireSamePositions(t, edit(newSrc, r, "1"), got)
+	})
+
+	t.Run("edit touching tags reparses the enclosing element", func(t *testing.T) {
+		t.Parallel()
+		doc, err := p.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+
+		off := strings.Index(src, "'en'")
+		r := helium.Range{Start: off, End: off + len("'en'")}
+		got, err := p.Reparse(t.Context(), doc, r, []byte("'fr'"))
+		require.NoError(t, err)
+		require.Same(t, doc, got)
+		v, _ := got.DocumentElement().FirstChild().NextSibling().(*helium.Element).GetAttribute("lang")
+		require.Equal(t, "fr", v)
+		requireSamePositions(t, edit(src, r, "'fr'"), got)
+	})
+
+	t.Run("edit outside the document element parses the whole source", func(t *testing.T) {
+		t.Parallel()
+		doc, err := p.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+
+		off := strings.Index(src, "<book>")
+		r := helium.Range{Start: off, End: off + len("<book>")}
+		got, err := p.Reparse(t.Context(), doc, r, []byte("<book id='b'>"))
+		require.NoError(t, err)
+		require.NotSame(t, doc, got)
+		requireSamePositions(t, edit(src, r, "<book id='b'>"), got)
+
+		newSrc := edit(src, r, "<book id='b'>")
+		got, err = p.Reparse(t.Context(), got, helium.Range{Start: off, End: off}, []byte("<!-- new -->"))
+		require.NoError(t, err)
+		requireSamePositions(t, edit(newSrc, helium.Range{Start: off, End: off}, "<!-- new -->"), got)
+	})
+
+	t.Run("unbalanced edit reports the parse error", func(t *testing.T) {
+		t.Parallel()
+		doc, err := p.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+
+		off := strings.Index(src, "one")
+		got, err := p.Reparse(t.Context(), doc, helium.Range{Start: off, End: off}, []byte("<b>"))
+		require.Error(t, err)
+		require.Nil(t, got)
+	})
+
+	t.Run("namespaces and lexical markup", func(t *testing.T) {
+		t.Parallel()
+		const nsSrc = "<r xmlns:a='urn:a'>\n  <a:x  k='v'>text</a:x>\n</r>"
+		q := p.PreserveLexical(true)
+		doc, err := q.Parse(t.Context(), []byte(nsSrc))
+		require.NoError(t, err)
+
+		off := strings.Index(nsSrc, "text")
+		r := helium.Range{Start: off, End: off + len("text")}
+		got, err := q.Reparse(t.Context(), doc, r, []byte("<a:y/>"))
+		require.NoError(t, err)
+		require.Same(t, doc, got)
+
+		x := got.DocumentElement().FirstChild().NextSibling().(*helium.Element)
+		require.Equal(t, "urn:a", x.FirstChild().(*helium.Element).URI())
+		require.Equal(t, edit(nsSrc, r, "<a:y/>"), writeLexical(t, got))
+	})
+
+	t.Run("edit of an id attribute", func(t *testing.T) {
+		t.Parallel()
+		const idSrc = "<!DOCTYPE r [<!ATTLIST s id ID #IMPLIED>]>\n<r>\n  <g><s id='a'><t xml:id='in'/></s></g>\n  <s id='b'/>\n</r>"
+		doc, err := p.Parse(t.Context(), []byte(idSrc))
+		require.NoError(t, err)
+		require.NotNil(t, doc.GetElementByID("a"))
+		require.NotNil(t, doc.GetElementByID("in"))
+		b := doc.GetElementByID("b")
+		require.NotNil(t, b)
+
+		off := strings.Index(idSrc, "id='a'")
+		r := helium.Range{Start: off, End: off + len("id='a'><t xml:id='in'")}
+		got, err := p.Reparse(t.Context(), doc, r, []byte("id='c'><t xml:id='in2'"))
+		require.NoError(t, err)
+		require.Same(t, doc, got)
+
+		require.Nil(t, got.GetElementByID("a"))
+		require.Nil(t, got.GetElementByID("in"))
+		c := got.GetElementByID("c")
+		require.NotNil(t, c)
+		require.Equal(t, "g", c.Parent().(*helium.Element).Name())
+		require.Equal(t, "t", got.GetElementByID("in2").Name())
+		require.Same(t, b, got.GetElementByID("b"))
+		requireSamePositions(t, edit(idSrc, r, "id='c'><t xml:id='in2'"), got)
+	})
+
+	t.Run("requires positions", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		_, err = p.Reparse(t.Context(), doc, helium.Range{}, []byte("x"))
+		require.ErrorIs(t, err, helium.ErrNoSourcePositions)
+
+		doc, err = helium.NewParser().TrackPositions(true).Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		_, err = p.Reparse(t.Context(), doc, helium.Range{}, []byte("x"))
+		require.ErrorIs(t, err, helium.ErrNoSourcePositions)
+
+		doc, err = p.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		_, err = p.Reparse(t.Context(), doc, helium.Range{Start: 5, End: len(src) + 1}, []byte("x"))
+		require.ErrorIs(t, err, helium.ErrInvalidArgument)
+	})
+}
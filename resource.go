@@ -0,0 +1,270 @@
+package helium
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"maps"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+// Resource is an external resource opened by a [ResourceResolver]: its
+// content, which the caller must close, and what is known about it.
+type Resource struct {
+	io.ReadCloser
+
+	// URI is the absolute URI the content was actually loaded from, after any
+	// catalog mapping or redirect. References inside the resource resolve
+	// against it. Empty means the URI that was asked for.
+	URI string
+
+	// MediaType is the media type of the content, such as "application/xml",
+	// or "" when unknown.
+	MediaType string
+
+	// Size is the length of the content in bytes, or -1 when unknown. It is
+	// advisory: consumers still bound what they read.
+	Size int64
+}
+
+// ResourceResolver opens the external resources that documents, schemas and
+// stylesheets refer to: external DTDs and entities, XInclude targets, schema
+// includes and imports, stylesheet modules, and the documents and text read
+// by XPath functions such as fn:doc. Every package that loads such resources
+// accepts one, so a single resolver can sandbox and bound a whole pipeline,
+// and the ctx of the operation in progress reaches every load.
+//
+// uri has already been resolved against the applicable base URI. A resolver
+// that cannot find a resource returns an error wrapping [fs.ErrNotExist];
+// one that refuses to load it returns any other error. ResolveResource must
+// be safe for concurrent use and should honor ctx cancellation.
+//
+// The package provides [FSResourceResolver], [CatalogResourceResolver],
+// [MemoryResourceResolver] and [LimitResourceResolver], which compose.
+type ResourceResolver interface {
+	ResolveResource(ctx context.Context, uri string) (*Resource, error)
+}
+
+// ResourceResolverFunc adapts an ordinary function to a [ResourceResolver].
+type ResourceResolverFunc func(ctx context.Context, uri string) (*Resource, error)
+
+// ResolveResource calls f(ctx, uri).
+func (f ResourceResolverFunc) ResolveResource(ctx context.Context, uri string) (*Resource, error) {
+	return f(ctx, uri)
+}
+
+type fsResourceResolver struct {
+	fsys fs.FS
+}
+
+// FSResourceResolver returns a [ResourceResolver] that opens resources from
+// fsys. A "file:" URI is converted to a local path first; any other name is
+// handed to fsys as is, so the names it must accept are those the parser
+// hands to [Parser.FS]. The usual sandboxes apply: [DirFS] confines loads to a
+// directory, and [PermissiveFS] opens any local path.
+func FSResourceResolver(fsys fs.FS) ResourceResolver {
+	return fsResourceResolver{fsys: fsys}
+}
+
+func (r fsResourceResolver) ResolveResource(ctx context.Context, uri string) (*Resource, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name := catalogOpenName(uri)
+	f, err := r.fsys.Open(name)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // fs errors already carry the name
+	}
+	size := int64(-1)
+	if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+		size = fi.Size()
+	}
+	return &Resource{ReadCloser: f, MediaType: mediaTypeOf(name), Size: size}, nil
+}
+
+type catalogResourceResolver struct {
+	catalog CatalogResolver
+	next    ResourceResolver
+}
+
+// CatalogResourceResolver returns a [ResourceResolver] that maps each URI
+// through catalog, as a URI and then as a system identifier, and opens the
+// result with next. A URI the catalog does not map is opened unchanged. The
+// mapped URI becomes the resource's URI unless next reports another.
+func CatalogResourceResolver(catalog CatalogResolver, next ResourceResolver) ResourceResolver {
+	return catalogResourceResolver{catalog: catalog, next: next}
+}
+
+func (r catalogResourceResolver) ResolveResource(ctx context.Context, uri string) (*Resource, error) {
+	mapped := r.catalog.ResolveURI(ctx, uri)
+	if mapped == "" {
+		mapped = r.catalog.Resolve(ctx, "", uri)
+	}
+	if mapped == "" {
+		return r.next.ResolveResource(ctx, uri)
+	}
+	res, err := r.next.ResolveResource(ctx, mapped)
+	if err != nil {
+		return nil, err
+	}
+	if res.URI == "" {
+		res.URI = mapped
+	}
+	return res, nil
+}
+
+type memoryResourceResolver struct {
+	resources map[string][]byte
+}
+
+// MemoryResourceResolver returns a [ResourceResolver] that serves the given
+// contents, keyed by exact URI, and nothing else. The map is copied; the
+// byte slices are not and must not be modified afterwards.
+func MemoryResourceResolver(resources map[string][]byte) ResourceResolver {
+	return memoryResourceResolver{resources: maps.Clone(resources)}
+}
+
+func (r memoryResourceResolver) ResolveResource(ctx context.Context, uri string) (*Resource, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	data, ok := r.resources[uri]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: uri, Err: fs.ErrNotExist}
+	}
+	return &Resource{
+		ReadCloser: io.NopCloser(bytes.NewReader(data)),
+		MediaType:  mediaTypeOf(uri),
+		Size:       int64(len(data)),
+	}, nil
+}
+
+type limitResourceResolver struct {
+	next     ResourceResolver
+	maxBytes int64
+}
+
+// LimitResourceResolver returns a [ResourceResolver] that opens resources
+// with next but refuses any larger than maxBytes with [ErrResourceTooLarge]:
+// up front when the reported size exceeds the limit, and otherwise as soon as
+// a read goes past it. A non-positive maxBytes refuses every non-empty
+// resource.
+func LimitResourceResolver(next ResourceResolver, maxBytes int64) ResourceResolver {
+	return limitResourceResolver{next: next, maxBytes: max(maxBytes, 0)}
+}
+
+func (r limitResourceResolver) ResolveResource(ctx context.Context, uri string) (*Resource, error) {
+	res, err := r.next.ResolveResource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if res.Size > r.maxBytes {
+		_ = res.Close()
+		return nil, ErrResourceTooLarge
+	}
+	res.ReadCloser = &limitedReadCloser{ReadCloser: res.ReadCloser, remaining: r.maxBytes}
+	return res, nil
+}
+
+// limitedReadCloser fails with ErrResourceTooLarge once more than remaining
+// bytes have been read. It reads one byte past the limit to tell a resource
+// of exactly the limit from a larger one.
+type limitedReadCloser struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrResourceTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), ErrResourceTooLarge
+	}
+	return n, err //nolint:wrapcheck // pass the underlying reader's error through
+}
+
+// ResourceFS returns an [fs.FS] that opens every name by resolving it with r
+// under ctx. It lets a [ResourceResolver] stand in wherever an fs.FS is
+// expected. Names are passed to r unchanged, so they need not satisfy
+// [fs.ValidPath]. Errors from r are reported as *[fs.PathError]s.
+func ResourceFS(ctx context.Context, r ResourceResolver) fs.FS {
+	return resourceFS{ctx: ctx, r: r}
+}
+
+type resourceFS struct {
+	ctx context.Context //nolint:containedctx // the FS serves a single operation
+	r   ResourceResolver
+}
+
+func (f resourceFS) Open(name string) (fs.File, error) {
+	res, err := f.r.ResolveResource(f.ctx, name)
+	if err != nil {
+		if _, ok := err.(*fs.PathError); ok { //nolint:errorlint // only a top-level PathError is reused as is
+			return nil, err
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return resourceFile{res: res, name: name}, nil
+}
+
+// resourceFile presents a Resource as an fs.File.
+type resourceFile struct {
+	res  *Resource
+	name string
+}
+
+func (f resourceFile) Read(p []byte) (int, error) { return f.res.Read(p) }
+func (f resourceFile) Close() error               { return f.res.Close() }
+
+func (f resourceFile) Stat() (fs.FileInfo, error) {
+	return resourceFileInfo{name: path.Base(f.name), size: f.res.Size}, nil
+}
+
+type resourceFileInfo struct {
+	name string
+	size int64
+}
+
+func (fi resourceFileInfo) Name() string { return fi.name }
+
+// Size reports the resource size, or 0 when it is unknown, as fs.FileInfo
+// has no way to say so.
+func (fi resourceFileInfo) Size() int64        { return max(fi.size, 0) }
+func (fi resourceFileInfo) Mode() fs.FileMode  { return 0o444 }
+func (fi resourceFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi resourceFileInfo) IsDir() bool        { return false }
+func (fi resourceFileInfo) Sys() any           { return nil }
+
+// mediaTypeOf guesses the media type of a resource from the extension of its
+// name. The formats this module reads are known; anything else is left to
+// the platform's MIME table.
+func mediaTypeOf(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	switch ext {
+	case ".xml", ".xsd", ".rng", ".sch", ".ent":
+		return "application/xml"
+	case ".xsl", ".xslt":
+		return "application/xslt+xml"
+	case ".dtd":
+		return "application/xml-dtd"
+	case ".html", ".htm":
+		return "text/html"
+	case ".json":
+		return "application/json"
+	case ".txt":
+		return "text/plain"
+	case "":
+		return ""
+	}
+	return mime.TypeByExtension(ext)
+}
@@ -0,0 +1,391 @@
+package helium
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"io/fs"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lestrrat-go/helium/internal/iolimit"
+)
+
+const (
+	// DefaultResourceCacheEntries is the number of resources a
+	// [ResourceCache] holds when NewResourceCache is given no positive count.
+	DefaultResourceCacheEntries = 1024
+
+	// DefaultResourceCacheBytes is the total size of the resources a
+	// [ResourceCache] holds when NewResourceCache is given no positive size.
+	DefaultResourceCacheBytes = 64 << 20
+)
+
+// ResourceCache memoizes external resources across parses and compiles:
+// external DTD subsets and entities, XInclude targets, schema includes and
+// imports, and stylesheet modules, keyed by the resolved URI they were loaded
+// from. Attach one to [Parser.ResourceCache] and the ResourceCache method of
+// xinclude.Processor, xsd.Compiler, relaxng.Compiler and xslt3.Compiler, and
+// a DTD or schema module that every document refers to is loaded once.
+//
+// Where the parsed form of a resource does not depend on what refers to it,
+// the cache holds that too: the parser keeps an external DTD subset that no
+// internal subset precedes as parsed declarations, and xsd.Compiler keeps the
+// parsed documents of included and imported schemas. Other resources are held
+// as loaded content, which each consumer parses afresh: an external subset
+// that an internal one may override, or a stylesheet module whose import
+// precedence depends on the importer. Failed loads are not cached.
+//
+// Entries are scoped to the policy they were loaded under: the resolver or
+// fs.FS that loaded them, and for parsed forms the parser configuration too.
+// A hit is served without consulting that resolver or FS again, but only to a
+// consumer loading under the same one, so a cache may be shared between
+// consumers with different sandboxes. A policy is identified by its value, and
+// by the identity of what it refers to (pointers, maps, slices); a resolver
+// that is a function, such as a [ResourceResolverFunc], has no identity, and
+// what it loads is passed through uncached.
+//
+// The least recently used entries are evicted once the cache holds more than
+// its entry count or total size. A resource larger than the total size is
+// passed through uncached. A ResourceCache is safe for concurrent use.
+type ResourceCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	size       int64
+	lru        *list.List // of *cachedResource, most recently used first
+	entries    map[cacheKey]*list.Element
+}
+
+// cacheKey identifies an entry: the name it was loaded by, under the policy
+// identified by policyKey, and whether it holds loaded content or a parsed
+// form.
+type cacheKey struct {
+	policy string
+	name   string
+	parsed bool
+}
+
+type cachedResource struct {
+	key       cacheKey
+	policy    any // keeps what policy.key refers to alive, so no other value takes its address
+	uri       string
+	mediaType string
+	data      []byte
+	parsed    any   // the parsed form, when one is cached instead of data
+	size      int64 // len(data), or the estimated size of parsed
+}
+
+// NewResourceCache creates a ResourceCache that holds at most maxEntries
+// resources totalling at most maxBytes bytes. A non-positive value selects
+// [DefaultResourceCacheEntries] or [DefaultResourceCacheBytes] respectively.
+func NewResourceCache(maxEntries int, maxBytes int64) *ResourceCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultResourceCacheEntries
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultResourceCacheBytes
+	}
+	return &ResourceCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		lru:        list.New(),
+		entries:    make(map[cacheKey]*list.Element),
+	}
+}
+
+// Len reports the number of resources in the cache.
+func (c *ResourceCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Size reports the total size in bytes of the resources in the cache.
+func (c *ResourceCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Remove drops what is cached for uri under any policy, so the next consumer
+// to ask for it loads it afresh.
+func (c *ResourceCache) Remove(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if key.name == uri {
+			c.evict(e)
+		}
+	}
+}
+
+// Purge drops every cached resource.
+func (c *ResourceCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	clear(c.entries)
+	c.size = 0
+}
+
+// Resolver returns a [ResourceResolver] that serves resources from the cache
+// and loads the ones it does not hold with next, caching them under next.
+func (c *ResourceCache) Resolver(next ResourceResolver) ResourceResolver {
+	return c.ResolverFor(next, next)
+}
+
+// ResolverFor is [ResourceCache.Resolver] with the entries scoped to policy
+// instead of next. It serves a consumer that wraps a longer-lived loader in a
+// resolver built per load: policy is the loader, which next must consult for
+// every resource it loads. When policy has no identity, next is returned.
+func (c *ResourceCache) ResolverFor(policy any, next ResourceResolver) ResourceResolver {
+	key, ok := policyKey(policy)
+	if !ok {
+		return next
+	}
+	return cachingResourceResolver{cache: c, key: key, policy: policy, next: next}
+}
+
+// FS returns an [fs.FS] that serves files from the cache, keyed by name, and
+// opens the ones it does not hold with fsys, caching them under fsys. Opens
+// are made under ctx.
+func (c *ResourceCache) FS(ctx context.Context, fsys fs.FS) fs.FS {
+	return ResourceFS(ctx, c.ResolverFor(fsys, fsOpenResolver{fsys: fsys}))
+}
+
+// Document returns the document cached for name under policy, or the one
+// parse returns, caching a frozen copy of it (see [Freeze]). A cached document
+// is shared by every caller, which must not modify it; a caller that needs to
+// copies it with [CopyDoc]. policy identifies everything the parsed document
+// depends on besides name, such as the loader and the parser configuration.
+// When policy has no identity, parse's result is returned uncached. A failed
+// parse is not cached.
+func (c *ResourceCache) Document(policy any, name string, parse func() (*Document, error)) (*Document, error) {
+	key, ok := policyKey(policy)
+	if !ok {
+		return parse()
+	}
+	ck := cacheKey{policy: key, name: name, parsed: true}
+	if v, ok := c.getParsed(ck); ok {
+		if doc, ok := v.(*Document); ok {
+			return doc, nil
+		}
+	}
+	doc, err := parse()
+	if err != nil || doc == nil {
+		return doc, err
+	}
+	frozen, err := Freeze(doc)
+	if err != nil {
+		return doc, nil //nolint:nilerr // a document that cannot be frozen is served uncached
+	}
+	var counts freezeCounts
+	counts.count(frozen)
+	c.putParsed(ck, policy, frozen, counts.size())
+	return frozen, nil
+}
+
+type cachingResourceResolver struct {
+	cache  *ResourceCache
+	key    string
+	policy any
+	next   ResourceResolver
+}
+
+func (r cachingResourceResolver) ResolveResource(ctx context.Context, uri string) (*Resource, error) {
+	key := cacheKey{policy: r.key, name: uri}
+	if res, ok := r.cache.get(key); ok {
+		return res, nil
+	}
+	res, err := r.next.ResolveResource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return r.cache.fill(key, r.policy, res)
+}
+
+// fsOpenResolver opens names with an fs.FS exactly as given, unlike
+// FSResourceResolver, which converts "file:" URIs to paths first.
+type fsOpenResolver struct {
+	fsys fs.FS
+}
+
+func (r fsOpenResolver) ResolveResource(ctx context.Context, name string) (*Resource, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := r.fsys.Open(name)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // fs errors already carry the name
+	}
+	size := int64(-1)
+	if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+		size = fi.Size()
+	}
+	return &Resource{ReadCloser: f, Size: size}, nil
+}
+
+func (c *ResourceCache) get(key cacheKey) (*Resource, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*cachedResource).resource(), true
+}
+
+// getParsed returns the parsed form cached under key.
+func (c *ResourceCache) getParsed(key cacheKey) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*cachedResource).parsed, true
+}
+
+// putParsed caches v, of roughly size bytes, under key. policy is what key
+// identifies.
+func (c *ResourceCache) putParsed(key cacheKey, policy, v any, size int64) {
+	if size > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.insert(&cachedResource{key: key, policy: policy, parsed: v, size: size})
+}
+
+// fill reads res into the cache under key and returns a Resource serving the
+// same content. A resource larger than the cache is returned still streaming,
+// with what was read so far in front of the rest of it.
+func (c *ResourceCache) fill(key cacheKey, policy any, res *Resource) (*Resource, error) {
+	if res.Size > c.maxBytes {
+		return res, nil
+	}
+	data, exceeded, err := iolimit.ReadAll(res, c.maxBytes)
+	if exceeded {
+		return &Resource{
+			ReadCloser: readCloser{Reader: io.MultiReader(bytes.NewReader(data), res), Closer: res},
+			URI:        res.URI,
+			MediaType:  res.MediaType,
+			Size:       res.Size,
+		}, nil
+	}
+	_ = res.Close()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the resource's own read error
+	}
+
+	entry := &cachedResource{key: key, policy: policy, uri: res.URI, mediaType: res.MediaType, data: data, size: int64(len(data))}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.insert(entry)
+	return entry.resource(), nil
+}
+
+// insert adds entry, replacing any entry under its key, and evicts the least
+// recently used entries past the cache's bounds. The caller holds c.mu.
+func (c *ResourceCache) insert(entry *cachedResource) {
+	if e, ok := c.entries[entry.key]; ok {
+		// Another consumer loaded it concurrently; keep the newer copy.
+		c.evict(e)
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.size += entry.size
+	for c.lru.Len() > c.maxEntries || c.size > c.maxBytes {
+		c.evict(c.lru.Back())
+	}
+}
+
+// evict removes e. The caller holds c.mu.
+func (c *ResourceCache) evict(e *list.Element) {
+	entry := c.lru.Remove(e).(*cachedResource)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+func (e *cachedResource) resource() *Resource {
+	return &Resource{
+		ReadCloser: io.NopCloser(bytes.NewReader(e.data)),
+		URI:        e.uri,
+		MediaType:  e.mediaType,
+		Size:       int64(len(e.data)),
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// policyKey identifies policy for cacheKey: by value for scalars, strings and
+// the fields of structs and arrays, and by address for what pointers, maps,
+// channels and slices refer to. The cache entries keep policy alive, so no
+// other value can take one of those addresses while they exist. It reports
+// false when policy holds a function, which has no identity.
+func policyKey(policy any) (string, bool) {
+	var b strings.Builder
+	if !writePolicyKey(&b, reflect.ValueOf(policy)) {
+		return "", false
+	}
+	return b.String(), true
+}
+
+func writePolicyKey(b *strings.Builder, v reflect.Value) bool {
+	if !v.IsValid() {
+		b.WriteString("nil")
+		return true
+	}
+	b.WriteString(v.Type().String())
+	b.WriteByte('(')
+	switch v.Kind() {
+	case reflect.Func:
+		return false
+	case reflect.Interface:
+		if !writePolicyKey(b, v.Elem()) {
+			return false
+		}
+	case reflect.Pointer, reflect.Map, reflect.Chan, reflect.UnsafePointer:
+		b.WriteString(strconv.FormatUint(uint64(v.Pointer()), 16))
+	case reflect.Slice:
+		b.WriteString(strconv.FormatUint(uint64(v.Pointer()), 16))
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(v.Len()))
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if !writePolicyKey(b, v.Field(i)) {
+				return false
+			}
+		}
+	case reflect.Array:
+		for i := range v.Len() {
+			if !writePolicyKey(b, v.Index(i)) {
+				return false
+			}
+		}
+	case reflect.String:
+		b.WriteString(strconv.Quote(v.String()))
+	case reflect.Bool:
+		b.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		b.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		b.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.Complex64, reflect.Complex128:
+		b.WriteString(strconv.FormatComplex(v.Complex(), 'g', -1, 128))
+	default:
+		return false
+	}
+	b.WriteByte(')')
+	return true
+}
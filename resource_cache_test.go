@@ -0,0 +1,295 @@
+package helium_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/stretchr/testify/require"
+)
+
+// countingResolver serves from a MemoryResourceResolver and counts loads.
+type countingResolver struct {
+	next  helium.ResourceResolver
+	loads atomic.Int32
+}
+
+func (r *countingResolver) ResolveResource(ctx context.Context, uri string) (*helium.Resource, error) {
+	r.loads.Add(1)
+	return r.next.ResolveResource(ctx, uri)
+}
+
+func newCountingResolver(resources map[string][]byte) *countingResolver {
+	return &countingResolver{next: helium.MemoryResourceResolver(resources)}
+}
+
+func TestResourceCache(t *testing.T) {
+	t.Parallel()
+
+	resources := map[string][]byte{
+		"a": []byte("aaaa"),
+		"b": []byte("bbbb"),
+		"c": []byte("cccc"),
+		"d": []byte("dddddddddddd"),
+	}
+
+	t.Run("serves hits without loading", func(t *testing.T) {
+		t.Parallel()
+		next := newCountingResolver(resources)
+		r := helium.NewResourceCache(0, 0).Resolver(next)
+		for range 3 {
+			_, data := readResource(t, r, "a")
+			require.Equal(t, "aaaa", data)
+		}
+		require.Equal(t, int32(1), next.loads.Load())
+	})
+
+	t.Run("evicts by count", func(t *testing.T) {
+		t.Parallel()
+		next := newCountingResolver(resources)
+		cache := helium.NewResourceCache(2, 0)
+		r := cache.Resolver(next)
+		readResource(t, r, "a")
+		readResource(t, r, "b")
+		readResource(t, r, "a") // a is now more recently used than b
+		readResource(t, r, "c") // evicts b
+		require.Equal(t, 2, cache.Len())
+		require.Equal(t, int32(3), next.loads.Load())
+
+		readResource(t, r, "a")
+		require.Equal(t, int32(3), next.loads.Load())
+		readResource(t, r, "b")
+		require.Equal(t, int32(4), next.loads.Load())
+	})
+
+	t.Run("evicts by size", func(t *testing.T) {
+		t.Parallel()
+		cache := helium.NewResourceCache(0, 10)
+		r := cache.Resolver(newCountingResolver(resources))
+		readResource(t, r, "a")
+		readResource(t, r, "b")
+		readResource(t, r, "c")
+		require.Equal(t, 2, cache.Len())
+		require.Equal(t, int64(8), cache.Size())
+	})
+
+	t.Run("passes oversized resources through", func(t *testing.T) {
+		t.Parallel()
+		next := newCountingResolver(resources)
+		cache := helium.NewResourceCache(0, 10)
+		unsized := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+			res, err := next.ResolveResource(ctx, uri)
+			if err != nil {
+				return nil, err
+			}
+			res.Size = -1
+			return res, nil
+		})
+		for _, r := range []helium.ResourceResolver{cache.Resolver(next), cache.Resolver(unsized)} {
+			_, data := readResource(t, r, "d")
+			require.Equal(t, "dddddddddddd", data)
+		}
+		require.Zero(t, cache.Len())
+	})
+
+	t.Run("does not cache failures", func(t *testing.T) {
+		t.Parallel()
+		next := newCountingResolver(resources)
+		cache := helium.NewResourceCache(0, 0)
+		r := cache.Resolver(next)
+		for range 2 {
+			_, err := r.ResolveResource(t.Context(), "missing")
+			require.Error(t, err)
+		}
+		require.Equal(t, int32(2), next.loads.Load())
+		require.Zero(t, cache.Len())
+	})
+
+	t.Run("Remove and Purge", func(t *testing.T) {
+		t.Parallel()
+		cache := helium.NewResourceCache(0, 0)
+		r := cache.Resolver(newCountingResolver(resources))
+		readResource(t, r, "a")
+		readResource(t, r, "b")
+		cache.Remove("a")
+		require.Equal(t, 1, cache.Len())
+		require.Equal(t, int64(4), cache.Size())
+		cache.Purge()
+		require.Zero(t, cache.Len())
+		require.Zero(t, cache.Size())
+	})
+
+	t.Run("FS", func(t *testing.T) {
+		t.Parallel()
+		cache := helium.NewResourceCache(0, 0)
+		fsys := cache.FS(t.Context(), fstest.MapFS{"x.dtd": &fstest.MapFile{Data: []byte("x")}})
+		f, err := fsys.Open("x.dtd")
+		require.NoError(t, err)
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		require.Equal(t, "x", string(data))
+		require.Equal(t, 1, cache.Len())
+	})
+
+	t.Run("FS keeps the context", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		fsys := helium.NewResourceCache(0, 0).FS(ctx, fstest.MapFS{"x.dtd": &fstest.MapFile{Data: []byte("x")}})
+		_, err := fsys.Open("x.dtd")
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("scopes entries to the loader", func(t *testing.T) {
+		t.Parallel()
+		cache := helium.NewResourceCache(0, 0)
+		readResource(t, cache.Resolver(newCountingResolver(resources)), "a")
+
+		// A resolver that may not load a is not served the cached copy.
+		sandboxed := newCountingResolver(map[string][]byte{"b": []byte("bbbb")})
+		_, err := cache.Resolver(sandboxed).ResolveResource(t.Context(), "a")
+		require.Error(t, err)
+		require.Equal(t, int32(1), sandboxed.loads.Load())
+
+		// An fs.FS and a map-backed resolver are scoped by identity too.
+		mapfs := fstest.MapFS{"x.dtd": &fstest.MapFile{Data: []byte("x")}}
+		_, err = cache.FS(t.Context(), mapfs).Open("x.dtd")
+		require.NoError(t, err)
+		_, err = cache.FS(t.Context(), fstest.MapFS{}).Open("x.dtd")
+		require.Error(t, err)
+		_, err = cache.FS(t.Context(), mapfs).Open("x.dtd")
+		require.NoError(t, err)
+		require.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("passes function resolvers through", func(t *testing.T) {
+		t.Parallel()
+		next := newCountingResolver(resources)
+		cache := helium.NewResourceCache(0, 0)
+		r := cache.Resolver(helium.ResourceResolverFunc(next.ResolveResource))
+		readResource(t, r, "a")
+		readResource(t, r, "a")
+		require.Equal(t, int32(2), next.loads.Load())
+		require.Zero(t, cache.Len())
+	})
+
+	t.Run("Document", func(t *testing.T) {
+		t.Parallel()
+		cache := helium.NewResourceCache(0, 0)
+		var parses int
+		parse := func() (*helium.Document, error) {
+			parses++
+			return helium.NewParser().Parse(t.Context(), []byte(`<r><a/></r>`))
+		}
+		first, err := cache.Document("policy", "r.xml", parse)
+		require.NoError(t, err)
+		require.True(t, first.IsReadOnly())
+		second, err := cache.Document("policy", "r.xml", parse)
+		require.NoError(t, err)
+		require.Same(t, first, second)
+		require.Equal(t, 1, parses)
+
+		other, err := cache.Document("other policy", "r.xml", parse)
+		require.NoError(t, err)
+		require.NotSame(t, first, other)
+		require.Equal(t, 2, parses)
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		t.Parallel()
+		cache := helium.NewResourceCache(2, 0)
+		r := cache.Resolver(newCountingResolver(resources))
+		var wg sync.WaitGroup
+		for i := range 16 {
+			wg.Go(func() {
+				for j := range 50 {
+					uri := string(rune('a' + (i+j)%3))
+					res, err := r.ResolveResource(context.Background(), uri)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					data, _ := io.ReadAll(res)
+					_ = res.Close()
+					if string(data) != strings.Repeat(uri, 4) {
+						t.Errorf("got %q for %q", data, uri)
+					}
+				}
+			})
+		}
+		wg.Wait()
+		require.LessOrEqual(t, cache.Len(), 2)
+	})
+}
+
+func TestParserResourceCache(t *testing.T) {
+	t.Parallel()
+
+	next := newCountingResolver(map[string][]byte{
+		"r.dtd":   []byte(`<!ENTITY ext SYSTEM "ext.xml">`),
+		"ext.xml": []byte(`<e>cached</e>`),
+	})
+	cache := helium.NewResourceCache(0, 0)
+	p := helium.NewParser().
+		BlockXXE(false).
+		LoadExternalDTD(true).
+		SubstituteEntities(true).
+		ResourceResolver(next).
+		ResourceCache(cache)
+
+	for range 3 {
+		doc, err := p.Parse(t.Context(), []byte(`<!DOCTYPE r SYSTEM "r.dtd"><r>&ext;</r>`))
+		require.NoError(t, err)
+		out, err := helium.WriteString(doc.DocumentElement())
+		require.NoError(t, err)
+		require.Contains(t, out, `>cached</e></r>`)
+	}
+	require.Equal(t, int32(2), next.loads.Load())
+	// The DTD is held both loaded and parsed.
+	require.Equal(t, 3, cache.Len())
+}
+
+func TestParserResourceCacheParsedDTD(t *testing.T) {
+	t.Parallel()
+
+	next := newCountingResolver(map[string][]byte{
+		"r.dtd":     []byte(`<!ENTITY % decls SYSTEM "decls.ent"> %decls; <!ATTLIST r b CDATA "from-dtd">`),
+		"decls.ent": []byte(`<!ATTLIST r a NMTOKEN "  x  ">`),
+	})
+	cache := helium.NewResourceCache(0, 0)
+	p := helium.NewParser().
+		BlockXXE(false).
+		DefaultDTDAttributes(true).
+		ResourceResolver(next).
+		ResourceCache(cache)
+
+	parse := func(src string) string {
+		t.Helper()
+		doc, err := p.Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		require.NotNil(t, doc.ExtSubset())
+		_, ok := doc.ExtSubset().LookupAttribute("a", "", "r")
+		require.True(t, ok)
+		out, err := helium.WriteString(doc.DocumentElement())
+		require.NoError(t, err)
+		return out
+	}
+
+	for range 3 {
+		require.Equal(t, `<r a="x" b="from-dtd"/>`, parse(`<!DOCTYPE r SYSTEM "r.dtd"><r/>`))
+	}
+	// Parsed once, and held parsed beside the two loaded resources.
+	require.Equal(t, int32(2), next.loads.Load())
+	require.Equal(t, 3, cache.Len())
+
+	// An internal subset takes precedence over the external one, so the
+	// parsed form is not used.
+	require.Equal(t, `<r b="internal" a="x"/>`, parse(`<!DOCTYPE r SYSTEM "r.dtd" [<!ATTLIST r b CDATA "internal">]><r/>`))
+	require.Equal(t, int32(2), next.loads.Load())
+}
@@ -0,0 +1,246 @@
+package helium_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey struct{}
+
+// mapCatalog is a CatalogResolver that maps URIs by exact match.
+type mapCatalog map[string]string
+
+func (c mapCatalog) Resolve(_ context.Context, _, sysID string) string { return c[sysID] }
+func (c mapCatalog) ResolveURI(_ context.Context, uri string) string   { return c[uri] }
+
+func readResource(t *testing.T, r helium.ResourceResolver, uri string) (*helium.Resource, string) {
+	t.Helper()
+	res, err := r.ResolveResource(t.Context(), uri)
+	require.NoError(t, err)
+	defer res.Close()
+	data, err := io.ReadAll(res)
+	require.NoError(t, err)
+	return res, string(data)
+}
+
+func TestFSResourceResolver(t *testing.T) {
+	t.Parallel()
+
+	r := helium.FSResourceResolver(fstest.MapFS{
+		"schemas/a.xsd": &fstest.MapFile{Data: []byte("<schema/>")},
+	})
+
+	res, data := readResource(t, r, "schemas/a.xsd")
+	require.Equal(t, "<schema/>", data)
+	require.Equal(t, "application/xml", res.MediaType)
+	require.Equal(t, int64(len(data)), res.Size)
+
+	_, err := r.ResolveResource(t.Context(), "missing.xml")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = r.ResolveResource(ctx, "schemas/a.xsd")
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMemoryResourceResolver(t *testing.T) {
+	t.Parallel()
+
+	resources := map[string][]byte{"urn:x:a.dtd": []byte("<!ELEMENT a EMPTY>")}
+	r := helium.MemoryResourceResolver(resources)
+	delete(resources, "urn:x:a.dtd")
+
+	res, data := readResource(t, r, "urn:x:a.dtd")
+	require.Equal(t, "<!ELEMENT a EMPTY>", data)
+	require.Equal(t, "application/xml-dtd", res.MediaType)
+
+	_, err := r.ResolveResource(t.Context(), "urn:x:b.dtd")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestCatalogResourceResolver(t *testing.T) {
+	t.Parallel()
+
+	catalog := mapCatalog{"http://example.com/a.xsd": "file:///local/a.xsd"}
+	next := helium.MemoryResourceResolver(map[string][]byte{
+		"file:///local/a.xsd": []byte("mapped"),
+		"plain.xml":           []byte("plain"),
+	})
+	r := helium.CatalogResourceResolver(catalog, next)
+
+	res, data := readResource(t, r, "http://example.com/a.xsd")
+	require.Equal(t, "mapped", data)
+	require.Equal(t, "file:///local/a.xsd", res.URI)
+
+	res, data = readResource(t, r, "plain.xml")
+	require.Equal(t, "plain", data)
+	require.Empty(t, res.URI)
+}
+
+func TestLimitResourceResolver(t *testing.T) {
+	t.Parallel()
+
+	mem := helium.MemoryResourceResolver(map[string][]byte{"big": []byte("0123456789")})
+	streamed := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+		res, err := mem.ResolveResource(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		res.Size = -1
+		return res, nil
+	})
+
+	t.Run("refused by size", func(t *testing.T) {
+		t.Parallel()
+		_, err := helium.LimitResourceResolver(mem, 5).ResolveResource(t.Context(), "big")
+		require.ErrorIs(t, err, helium.ErrResourceTooLarge)
+	})
+
+	t.Run("refused while reading", func(t *testing.T) {
+		t.Parallel()
+		res, err := helium.LimitResourceResolver(streamed, 5).ResolveResource(t.Context(), "big")
+		require.NoError(t, err)
+		defer res.Close()
+		_, err = io.ReadAll(res)
+		require.ErrorIs(t, err, helium.ErrResourceTooLarge)
+	})
+
+	t.Run("exactly the limit", func(t *testing.T) {
+		t.Parallel()
+		_, data := readResource(t, helium.LimitResourceResolver(streamed, 10), "big")
+		require.Equal(t, "0123456789", data)
+	})
+}
+
+func TestResourceFS(t *testing.T) {
+	t.Parallel()
+
+	var seen context.Context
+	r := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+		seen = ctx
+		if uri != "/abs/a.txt" {
+			return nil, errors.New("refused")
+		}
+		return &helium.Resource{ReadCloser: io.NopCloser(strings.NewReader("abc")), Size: 3}, nil
+	})
+	ctx := context.WithValue(t.Context(), ctxKey{}, "v")
+	fsys := helium.ResourceFS(ctx, r)
+
+	data, err := fs.ReadFile(fsys, "/abs/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "abc", string(data))
+	require.Equal(t, "v", seen.Value(ctxKey{}))
+
+	_, err = fsys.Open("other")
+	var pe *fs.PathError
+	require.ErrorAs(t, err, &pe)
+	require.Equal(t, "other", pe.Path)
+}
+
+func TestParserResourceResolver(t *testing.T) {
+	t.Parallel()
+
+	const src = `<!DOCTYPE r SYSTEM "r.dtd"><r>&ext;</r>`
+	mem := helium.MemoryResourceResolver(map[string][]byte{
+		"r.dtd":   []byte(`<!ENTITY ext SYSTEM "ext.xml">`),
+		"ext.xml": []byte(`<e>from resolver</e>`),
+	})
+
+	t.Run("loads external subset and entities", func(t *testing.T) {
+		t.Parallel()
+		var uris []string
+		var values []any
+		r := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+			uris = append(uris, uri)
+			values = append(values, ctx.Value(ctxKey{}))
+			return mem.ResolveResource(ctx, uri)
+		})
+		ctx := context.WithValue(t.Context(), ctxKey{}, "v")
+		doc, err := helium.NewParser().
+			BlockXXE(false).
+			LoadExternalDTD(true).
+			SubstituteEntities(true).
+			ResourceResolver(r).
+			Parse(ctx, []byte(src))
+		require.NoError(t, err)
+		out, err := helium.WriteString(doc.DocumentElement())
+		require.NoError(t, err)
+		require.Contains(t, out, `>from resolver</e></r>`)
+		require.Equal(t, []string{"r.dtd", "ext.xml"}, uris)
+		require.Equal(t, []any{"v", "v"}, values)
+	})
+
+	t.Run("takes precedence over FS", func(t *testing.T) {
+		t.Parallel()
+		_, err := helium.NewParser().
+			BlockXXE(false).
+			LoadExternalDTD(true).
+			SubstituteEntities(true).
+			FS(fstest.MapFS{}).
+			ResourceResolver(mem).
+			Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+	})
+
+	t.Run("catalog results reach it as URIs", func(t *testing.T) {
+		t.Parallel()
+		files := helium.MemoryResourceResolver(map[string][]byte{
+			"file:///dtds/r.dtd":   []byte(`<!ENTITY ext SYSTEM "file:///dtds/ext.xml">`),
+			"file:///dtds/ext.xml": []byte(`<e>from resolver</e>`),
+		})
+		var uris []string
+		r := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+			uris = append(uris, uri)
+			return files.ResolveResource(ctx, uri)
+		})
+		doc, err := helium.NewParser().
+			BlockXXE(false).
+			LoadExternalDTD(true).
+			SubstituteEntities(true).
+			Catalog(mapCatalog{"r.dtd": "file:///dtds/r.dtd"}).
+			ResourceResolver(r).
+			Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		out, err := helium.WriteString(doc.DocumentElement())
+		require.NoError(t, err)
+		require.Contains(t, out, `>from resolver</e></r>`)
+		require.Equal(t, []string{"file:///dtds/r.dtd", "file:///dtds/ext.xml"}, uris)
+	})
+
+	t.Run("reported URI becomes the base", func(t *testing.T) {
+		t.Parallel()
+		r := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+			if uri == "r.dtd" {
+				res, err := mem.ResolveResource(ctx, uri)
+				if err != nil {
+					return nil, err
+				}
+				res.URI = "moved/r.dtd"
+				return res, nil
+			}
+			return mem.ResolveResource(ctx, strings.TrimPrefix(uri, "moved/"))
+		})
+		var uris []string
+		rec := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+			uris = append(uris, uri)
+			return r.ResolveResource(ctx, uri)
+		})
+		_, err := helium.NewParser().
+			BlockXXE(false).
+			LoadExternalDTD(true).
+			SubstituteEntities(true).
+			ResourceResolver(rec).
+			Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		require.Equal(t, []string{"r.dtd", "moved/ext.xml"}, uris)
+	})
+}
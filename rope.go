@@ -0,0 +1,189 @@
+package helium
+
+import (
+	"bytes"
+	"math/rand/v2"
+)
+
+// ropeChunk is the size of the chunks newRope splits its text into.
+const ropeChunk = 4096
+
+// rope is the source of a document parsed with Parser.RetainSource. It is a
+// treap of chunks ordered by position, so replacing a range and finding a
+// line both take time logarithmic in the number of chunks.
+type rope struct {
+	root *ropeNode
+}
+
+type ropeNode struct {
+	left, right *ropeNode
+	prio        uint32
+	chunk       []byte
+	nl          int // newlines in chunk
+	size        int // bytes in the subtree
+	lines       int // newlines in the subtree
+}
+
+// newRope returns a rope holding b, which it takes ownership of.
+func newRope(b []byte) *rope {
+	return &rope{root: ropeBuild(b)}
+}
+
+func ropeBuild(b []byte) *ropeNode {
+	var root *ropeNode
+	for len(b) > 0 {
+		n := min(len(b), ropeChunk)
+		c := &ropeNode{prio: rand.Uint32(), chunk: b[:n:n]} //nolint:gosec // treap priorities need no cryptographic randomness
+		c.nl = bytes.Count(c.chunk, []byte{'\n'})
+		c.update()
+		root = ropeMerge(root, c)
+		b = b[n:]
+	}
+	return root
+}
+
+func (n *ropeNode) sizeOf() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *ropeNode) linesOf() int {
+	if n == nil {
+		return 0
+	}
+	return n.lines
+}
+
+func (n *ropeNode) update() {
+	n.size = n.left.sizeOf() + len(n.chunk) + n.right.sizeOf()
+	n.lines = n.left.linesOf() + n.nl + n.right.linesOf()
+}
+
+// ropeSplit splits n into its first k bytes and the rest.
+func ropeSplit(n *ropeNode, k int) (*ropeNode, *ropeNode) {
+	if n == nil {
+		return nil, nil
+	}
+	ls := n.left.sizeOf()
+	switch {
+	case k <= ls:
+		l, r := ropeSplit(n.left, k)
+		n.left = r
+		n.update()
+		return l, n
+	case k >= ls+len(n.chunk):
+		l, r := ropeSplit(n.right, k-ls-len(n.chunk))
+		n.right = l
+		n.update()
+		return n, r
+	}
+	i := k - ls
+	r := &ropeNode{right: n.right, prio: n.prio, chunk: n.chunk[i:]}
+	r.nl = bytes.Count(r.chunk, []byte{'\n'})
+	r.update()
+	n.chunk = n.chunk[:i:i]
+	n.nl -= r.nl
+	n.right = nil
+	n.update()
+	return n, r
+}
+
+func ropeMerge(a, b *ropeNode) *ropeNode {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.prio > b.prio:
+		a.right = ropeMerge(a.right, b)
+		a.update()
+		return a
+	}
+	b.left = ropeMerge(a, b.left)
+	b.update()
+	return b
+}
+
+func (r *rope) len() int {
+	return r.root.sizeOf()
+}
+
+// replace replaces the bytes from from to to with b.
+func (r *rope) replace(from, to int, b []byte) {
+	l, rest := ropeSplit(r.root, from)
+	_, tail := ropeSplit(rest, to-from)
+	r.root = ropeMerge(ropeMerge(l, ropeBuild(bytes.Clone(b))), tail)
+}
+
+// appendSlice appends the bytes from from to to.
+func (r *rope) appendSlice(dst []byte, from, to int) []byte {
+	return r.root.appendRange(dst, from, to)
+}
+
+func (n *ropeNode) appendRange(dst []byte, from, to int) []byte {
+	if n == nil || from >= to {
+		return dst
+	}
+	ls := n.left.sizeOf()
+	if from < ls {
+		dst = n.left.appendRange(dst, from, min(to, ls))
+	}
+	if cs, ce := max(from-ls, 0), min(to-ls, len(n.chunk)); cs < ce {
+		dst = append(dst, n.chunk[cs:ce]...)
+	}
+	if rs := ls + len(n.chunk); to > rs {
+		dst = n.right.appendRange(dst, max(from-rs, 0), to-rs)
+	}
+	return dst
+}
+
+// newlinesBefore returns the number of newlines before offset off.
+func (r *rope) newlinesBefore(off int) int {
+	count := 0
+	for n := r.root; n != nil; {
+		ls := n.left.sizeOf()
+		if off <= ls {
+			n = n.left
+			continue
+		}
+		count += n.left.linesOf()
+		off -= ls
+		if off <= len(n.chunk) {
+			return count + bytes.Count(n.chunk[:off], []byte{'\n'})
+		}
+		count += n.nl
+		off -= len(n.chunk)
+		n = n.right
+	}
+	return count
+}
+
+// newlineAt returns the offset of the k-th newline, counting from 1.
+func (r *rope) newlineAt(k int) int {
+	off := 0
+	for n := r.root; n != nil; {
+		ll := n.left.linesOf()
+		if k <= ll {
+			n = n.left
+			continue
+		}
+		k -= ll
+		off += n.left.sizeOf()
+		if k <= n.nl {
+			i := 0
+			for {
+				j := bytes.IndexByte(n.chunk[i:], '\n')
+				if k--; k == 0 {
+					return off + i + j
+				}
+				i += j + 1
+			}
+		}
+		k -= n.nl
+		off += len(n.chunk)
+		n = n.right
+	}
+	return -1
+}
@@ -12,6 +12,13 @@
 // Pass a SAX2Handler to [helium.Parser.SAXHandler] to receive events during
 // XML parsing without building a DOM tree.
 //
+// [Filter] wraps a downstream handler and forwards every event to it except
+// the ones it has a callback for, which can modify, drop, or inject events.
+// [Chain] connects filters into a pipeline, [Tee] delivers one event stream
+// to several handlers, and [WriterHandler] serializes events through a
+// stream.Writer, so a pipeline can rewrite a large document as it is parsed.
+// [helium.EmitSAX] replays an existing tree through the same handlers.
+//
 // # Examples
 //
 // Example code for this package lives in the examples/ directory at the
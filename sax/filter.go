@@ -0,0 +1,38 @@
+package sax
+
+// XMLFilter is a SAX2Handler that sits in front of a downstream handler and
+// forwards events to it, possibly modified, dropped, or with further events
+// injected (cf. org.xml.sax.XMLFilter). [Filter] is the callback-based
+// implementation; a type that embeds *Filter and overrides some of its
+// methods is an XMLFilter too.
+type XMLFilter interface {
+	SAX2Handler
+	SetNext(SAX2Handler)
+}
+
+// Chain connects filters into a pipeline that ends in h and returns the
+// handler to pass to the parser. Events flow through the filters in the
+// order given: filters[0] sees them first and h sees them last. With no
+// filters, Chain returns h.
+func Chain(h SAX2Handler, filters ...XMLFilter) SAX2Handler {
+	for i := len(filters) - 1; i >= 0; i-- {
+		filters[i].SetNext(h)
+		h = filters[i]
+	}
+	return h
+}
+
+// Tee returns a SAX2Handler that delivers every event to each of handlers in
+// order, for example to build a tree with helium.TreeBuilder while a
+// validator or a [WriterHandler] sees the same events.
+//
+// A notification is delivered to every handler; the first error other than
+// ErrHandlerUnspecified stops delivery and is returned. ErrHandlerUnspecified
+// is returned only when no handler handled the event. A query such as
+// GetEntity or ResolveEntity is answered by the first handler that does not
+// return ErrHandlerUnspecified; the handlers after it are not asked.
+func Tee(handlers ...SAX2Handler) SAX2Handler {
+	return tee(handlers)
+}
+
+type tee []SAX2Handler
@@ -0,0 +1,173 @@
+package sax_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/sax"
+	"github.com/lestrrat-go/helium/stream"
+	"github.com/stretchr/testify/require"
+)
+
+// pipe parses src through filters into a WriterHandler and returns the
+// serialized output.
+func pipe(t *testing.T, src string, filters ...sax.XMLFilter) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := stream.NewWriter(&buf)
+	h := sax.Chain(sax.NewWriterHandler(&w), filters...)
+	_, err := helium.NewParser().SubstituteEntities(true).SAXHandler(h).Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+	return buf.String()
+}
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("passes events through", func(t *testing.T) {
+		t.Parallel()
+		const src = `<?xml version="1.0"?>
+<!DOCTYPE r [<!ENTITY e "x &amp; y"><!NOTATION n SYSTEM "n.bin"><!-- in subset -->]>
+<r xmlns="urn:a" xmlns:p="urn:p" p:at="1" xml:lang="en"><p:c a="&lt;"/>t &amp; u<![CDATA[<x>]]><?pi d?><!-- c --><d xmlns="">z</d></r>`
+		require.Equal(t, `<?xml version="1.0"?>
+<!DOCTYPE r [<!ENTITY e "x &amp; y"><!NOTATION n SYSTEM "n.bin"><!-- in subset -->]><r p:at="1" xml:lang="en" xmlns="urn:a" xmlns:p="urn:p"><p:c a="&lt;"/>t &amp; u<![CDATA[<x>]]><?pi d?><!-- c --><d xmlns="">z</d></r>
+`, pipe(t, src, sax.NewFilter(nil)))
+	})
+
+	t.Run("modifies, drops and injects events", func(t *testing.T) {
+		t.Parallel()
+		rename := sax.NewFilter(nil)
+		rename.SetOnStartElementNS(sax.StartElementNSFunc(func(ctx context.Context, localname, prefix, uri string, namespaces []sax.Namespace, attrs []sax.Attribute) error {
+			return rename.Next().StartElementNS(ctx, strings.ToUpper(localname), prefix, uri, namespaces, attrs)
+		}))
+		rename.SetOnEndElementNS(sax.EndElementNSFunc(func(ctx context.Context, localname, prefix, uri string) error {
+			return rename.Next().EndElementNS(ctx, strings.ToUpper(localname), prefix, uri)
+		}))
+
+		redact := sax.NewFilter(nil)
+		redact.SetOnComment(sax.CommentFunc(func(context.Context, []byte) error { return nil }))
+		redact.SetOnCharacters(sax.CharactersFunc(func(ctx context.Context, ch []byte) error {
+			return redact.Next().Characters(ctx, bytes.ReplaceAll(ch, []byte("secret"), []byte("XXX")))
+		}))
+		redact.SetOnEndElementNS(sax.EndElementNSFunc(func(ctx context.Context, localname, prefix, uri string) error {
+			if localname == "r" {
+				if err := redact.Next().StartElementNS(ctx, "end", "", "", nil, nil); err != nil {
+					return err
+				}
+				if err := redact.Next().EndElementNS(ctx, "end", "", ""); err != nil {
+					return err
+				}
+			}
+			return redact.Next().EndElementNS(ctx, localname, prefix, uri)
+		}))
+
+		// redact sees the events first, so the element it injects is
+		// renamed too.
+		out := pipe(t, `<r><!-- drop --><a>a secret</a></r>`, redact, rename)
+		require.Equal(t, "<?xml version=\"1.0\"?>\n<R><A>a XXX</A><END/></R>\n", out)
+	})
+
+	t.Run("rewrites namespaces", func(t *testing.T) {
+		t.Parallel()
+		f := sax.NewFilter(nil)
+		f.SetOnStartElementNS(sax.StartElementNSFunc(func(ctx context.Context, localname, prefix, uri string, namespaces []sax.Namespace, attrs []sax.Attribute) error {
+			if uri == "urn:old" {
+				uri = "urn:new"
+			}
+			var kept []sax.Namespace
+			for _, ns := range namespaces {
+				if ns.URI() != "urn:old" {
+					kept = append(kept, ns)
+				}
+			}
+			return f.Next().StartElementNS(ctx, localname, prefix, uri, kept, attrs)
+		}))
+		out := pipe(t, `<o:r xmlns:o="urn:old"><o:c/></o:r>`, f)
+		require.Equal(t, "<?xml version=\"1.0\"?>\n<o:r xmlns:o=\"urn:new\"><o:c/></o:r>\n", out)
+	})
+
+	t.Run("without a downstream handler", func(t *testing.T) {
+		t.Parallel()
+		f := sax.NewFilter(nil)
+		require.ErrorIs(t, f.StartDocument(t.Context()), sax.ErrHandlerUnspecified)
+		_, err := f.GetEntity(t.Context(), "e")
+		require.ErrorIs(t, err, sax.ErrHandlerUnspecified)
+	})
+}
+
+// embeddingFilter overrides a method of an embedded *sax.Filter.
+type embeddingFilter struct {
+	*sax.Filter
+}
+
+func (f embeddingFilter) Characters(ctx context.Context, ch []byte) error {
+	return f.Next().Characters(ctx, bytes.ToUpper(ch))
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "<?xml version=\"1.0\"?>\n<r>TEXT</r>\n",
+		pipe(t, `<r>text</r>`, embeddingFilter{sax.NewFilter(nil)}))
+
+	h := sax.New()
+	require.Same(t, h, sax.Chain(h))
+}
+
+func TestTee(t *testing.T) {
+	t.Parallel()
+
+	t.Run("builds a tree while serializing", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		w := stream.NewWriter(&buf)
+		h := sax.Tee(helium.NewTreeBuilder(), sax.NewWriterHandler(&w))
+		doc, err := helium.NewParser().SubstituteEntities(true).SAXHandler(h).Parse(t.Context(), []byte(`<!DOCTYPE r [<!ENTITY e "ent">]><r a="&e;">text</r>`))
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+		require.Equal(t, "r", doc.DocumentElement().LocalName())
+		require.Equal(t, "<?xml version=\"1.0\"?>\n<!DOCTYPE r [<!ENTITY e \"ent\">]><r a=\"ent\">text</r>\n", buf.String())
+	})
+
+	t.Run("notifications", func(t *testing.T) {
+		t.Parallel()
+		var got []string
+		record := func(name string, err error) sax.SAX2Handler {
+			h := sax.New()
+			h.SetOnComment(sax.CommentFunc(func(_ context.Context, value []byte) error {
+				got = append(got, name+":"+string(value))
+				return err
+			}))
+			return h
+		}
+		boom := errors.New("boom")
+
+		require.NoError(t, sax.Tee(record("a", nil), sax.New(), record("b", nil)).Comment(t.Context(), []byte("x")))
+		require.Equal(t, []string{"a:x", "b:x"}, got)
+
+		got = nil
+		require.ErrorIs(t, sax.Tee(record("a", boom), record("b", nil)).Comment(t.Context(), []byte("y")), boom)
+		require.Equal(t, []string{"a:y"}, got)
+
+		require.ErrorIs(t, sax.Tee(sax.New(), sax.New()).Comment(t.Context(), nil), sax.ErrHandlerUnspecified)
+	})
+
+	t.Run("queries", func(t *testing.T) {
+		t.Parallel()
+		answer := func(v bool) sax.SAX2Handler {
+			h := sax.New()
+			h.SetOnIsStandalone(sax.IsStandaloneFunc(func(context.Context) (bool, error) { return v, nil }))
+			return h
+		}
+		v, err := sax.Tee(sax.New(), answer(true), answer(false)).IsStandalone(t.Context())
+		require.NoError(t, err)
+		require.True(t, v)
+
+		_, err = sax.Tee(sax.New()).IsStandalone(t.Context())
+		require.ErrorIs(t, err, sax.ErrHandlerUnspecified)
+	})
+}
@@ -0,0 +1,316 @@
+package sax
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/lestrrat-go/helium/stream"
+)
+
+// WriterHandler is a SAX2Handler that serializes the events it receives
+// through a [stream.Writer], so the end of a filter pipeline can write XML
+// as it is parsed, without building a tree.
+//
+// The DOCTYPE declaration is written with the entity and notation
+// declarations, comments and processing instructions of the internal
+// subset; element and attribute-list declarations are not reproduced, and
+// defaulted attributes are written like specified ones. Entity references
+// reported through Reference (when the parser does not substitute entities)
+// are written as references.
+//
+// WriterHandler does not record declarations, so it answers no queries:
+// the parser resolves an entity reference by asking its handler's
+// GetEntity, and a document that references entities declared in its DTD
+// needs a handler that answers it, such as helium.TreeBuilder, ahead of the
+// WriterHandler in a [Tee].
+type WriterHandler struct {
+	w       *stream.Writer
+	dtd     dtdState
+	doctype [3]string       // name, public and system ID of the open DOCTYPE
+	subset  strings.Builder // internal subset of the open DOCTYPE
+	scopes  [][]Namespace   // in-scope declarations per open element
+}
+
+type dtdState int
+
+const (
+	dtdNone dtdState = iota
+	dtdOpen
+	dtdClosed
+)
+
+// NewWriterHandler creates a WriterHandler that writes to w.
+func NewWriterHandler(w *stream.Writer) *WriterHandler {
+	return &WriterHandler{w: w}
+}
+
+// closeDTD writes the DOCTYPE declaration if one is still open.
+func (h *WriterHandler) closeDTD() error {
+	if h.dtd != dtdOpen {
+		return nil
+	}
+	h.dtd = dtdClosed
+	return h.w.WriteDTD(h.doctype[0], h.doctype[1], h.doctype[2], h.subset.String())
+}
+
+// lookupNS resolves prefix against the declarations of the open elements.
+func (h *WriterHandler) lookupNS(prefix string) (string, bool) {
+	for i := len(h.scopes) - 1; i >= 0; i-- {
+		for _, ns := range h.scopes[i] {
+			if ns.Prefix() == prefix {
+				return ns.URI(), true
+			}
+		}
+	}
+	return "", false
+}
+
+func (h *WriterHandler) SetDocumentLocator(context.Context, DocumentLocator) error {
+	return nil
+}
+
+func (h *WriterHandler) StartDocument(context.Context) error {
+	return h.w.StartDocument("", "", "")
+}
+
+func (h *WriterHandler) EndDocument(context.Context) error {
+	if err := h.closeDTD(); err != nil {
+		return err
+	}
+	return h.w.EndDocument()
+}
+
+// InternalSubset opens the DOCTYPE declaration. It is written once the
+// parser reports the end of the internal subset through ExternalSubset.
+func (h *WriterHandler) InternalSubset(_ context.Context, name, externalID, systemID string) error {
+	if h.dtd != dtdNone {
+		return nil
+	}
+	h.dtd = dtdOpen
+	h.doctype = [3]string{name, externalID, systemID}
+	return nil
+}
+
+// ExternalSubset marks the end of the internal subset: the parser reports
+// it once the internal subset has been read, whether or not the document
+// has an external subset. Declarations read from the external subset are
+// not written.
+func (h *WriterHandler) ExternalSubset(context.Context, string, string, string) error {
+	return h.closeDTD()
+}
+
+func (h *WriterHandler) EntityDecl(_ context.Context, name string, typ enum.EntityType, publicID, systemID, content string) error {
+	if h.dtd != dtdOpen {
+		return nil
+	}
+	switch typ {
+	case enum.InternalGeneralEntity, enum.InternalParameterEntity:
+		h.subset.WriteString("<!ENTITY ")
+		if typ == enum.InternalParameterEntity {
+			h.subset.WriteString("% ")
+		}
+		h.subset.WriteString(name)
+		h.subset.WriteString(` "`)
+		writeEntityValue(&h.subset, content)
+		h.subset.WriteString(`">`)
+	case enum.ExternalGeneralParsedEntity, enum.ExternalParameterEntity:
+		h.subset.WriteString("<!ENTITY ")
+		if typ == enum.ExternalParameterEntity {
+			h.subset.WriteString("% ")
+		}
+		h.subset.WriteString(name)
+		writeExternalID(&h.subset, publicID, systemID)
+		h.subset.WriteByte('>')
+	}
+	return nil
+}
+
+func (h *WriterHandler) UnparsedEntityDecl(_ context.Context, name, publicID, systemID, notationName string) error {
+	if h.dtd != dtdOpen {
+		return nil
+	}
+	h.subset.WriteString("<!ENTITY ")
+	h.subset.WriteString(name)
+	writeExternalID(&h.subset, publicID, systemID)
+	h.subset.WriteString(" NDATA ")
+	h.subset.WriteString(notationName)
+	h.subset.WriteByte('>')
+	return nil
+}
+
+func (h *WriterHandler) NotationDecl(_ context.Context, name, publicID, systemID string) error {
+	if h.dtd != dtdOpen {
+		return nil
+	}
+	h.subset.WriteString("<!NOTATION ")
+	h.subset.WriteString(name)
+	if publicID != "" && systemID == "" {
+		h.subset.WriteString(" PUBLIC ")
+		writeQuoted(&h.subset, publicID)
+	} else {
+		writeExternalID(&h.subset, publicID, systemID)
+	}
+	h.subset.WriteByte('>')
+	return nil
+}
+
+// writeEntityValue writes the replacement text of an internal entity as a
+// double-quoted EntityValue that parses back to the same text. Entity
+// references in it are left alone, since they are not expanded when the
+// declaration is parsed; character references, parameter-entity references
+// and the quote are escaped, since they would be.
+func writeEntityValue(b *strings.Builder, s string) {
+	for i := range len(s) {
+		switch c := s[i]; {
+		case c == '"':
+			b.WriteString("&#34;")
+		case c == '%':
+			b.WriteString("&#37;")
+		case c == '&' && i+1 < len(s) && s[i+1] == '#':
+			b.WriteString("&#38;")
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func writeExternalID(b *strings.Builder, publicID, systemID string) {
+	if publicID != "" {
+		b.WriteString(" PUBLIC ")
+		writeQuoted(b, publicID)
+		b.WriteByte(' ')
+	} else {
+		b.WriteString(" SYSTEM ")
+	}
+	writeQuoted(b, systemID)
+}
+
+// writeQuoted writes a literal in whichever quote it does not contain.
+func writeQuoted(b *strings.Builder, s string) {
+	q := byte('"')
+	if strings.IndexByte(s, '"') >= 0 {
+		q = '\''
+	}
+	b.WriteByte(q)
+	b.WriteString(s)
+	b.WriteByte(q)
+}
+
+func (h *WriterHandler) ElementDecl(context.Context, string, enum.ElementType, ElementContent) error {
+	return nil
+}
+
+func (h *WriterHandler) AttributeDecl(context.Context, string, string, enum.AttributeType, enum.AttributeDefault, string, Enumeration) error {
+	return nil
+}
+
+func (h *WriterHandler) StartElementNS(_ context.Context, localname, prefix, uri string, namespaces []Namespace, attrs []Attribute) error {
+	if err := h.closeDTD(); err != nil {
+		return err
+	}
+	h.scopes = append(h.scopes, namespaces)
+	if err := h.w.StartElementNS(prefix, localname, uri); err != nil {
+		return err
+	}
+	for _, ns := range namespaces {
+		if err := h.w.DeclareNamespace(ns.Prefix(), ns.URI()); err != nil {
+			return err
+		}
+	}
+	for _, attr := range attrs {
+		p := attr.Prefix()
+		if p == "" {
+			if err := h.w.WriteAttribute(attr.LocalName(), attr.Value()); err != nil {
+				return err
+			}
+			continue
+		}
+		// The xml prefix is bound implicitly, and stream.Writer accepts it
+		// with an empty namespace name.
+		nsURI, _ := h.lookupNS(p)
+		if err := h.w.WriteAttributeNS(p, attr.LocalName(), nsURI, attr.Value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *WriterHandler) EndElementNS(context.Context, string, string, string) error {
+	if n := len(h.scopes); n > 0 {
+		h.scopes = h.scopes[:n-1]
+	}
+	return h.w.EndElement()
+}
+
+func (h *WriterHandler) Characters(_ context.Context, ch []byte) error {
+	return h.w.WriteString(string(ch))
+}
+
+func (h *WriterHandler) IgnorableWhitespace(_ context.Context, ch []byte) error {
+	return h.w.WriteString(string(ch))
+}
+
+func (h *WriterHandler) CDataBlock(_ context.Context, value []byte) error {
+	return h.w.WriteCDATA(string(value))
+}
+
+func (h *WriterHandler) Comment(_ context.Context, value []byte) error {
+	if h.dtd == dtdOpen {
+		h.subset.WriteString("<!--")
+		h.subset.Write(value)
+		h.subset.WriteString("-->")
+		return nil
+	}
+	return h.w.WriteComment(string(value))
+}
+
+func (h *WriterHandler) ProcessingInstruction(_ context.Context, target, data string) error {
+	if h.dtd == dtdOpen {
+		h.subset.WriteString("<?")
+		h.subset.WriteString(target)
+		if data != "" {
+			h.subset.WriteByte(' ')
+			h.subset.WriteString(data)
+		}
+		h.subset.WriteString("?>")
+		return nil
+	}
+	return h.w.WritePI(target, data)
+}
+
+func (h *WriterHandler) Reference(_ context.Context, name string) error {
+	return h.w.WriteEntityRef(name)
+}
+
+func (h *WriterHandler) GetEntity(context.Context, string) (Entity, error) {
+	return nil, ErrHandlerUnspecified
+}
+
+func (h *WriterHandler) GetParameterEntity(context.Context, string) (Entity, error) {
+	return nil, ErrHandlerUnspecified
+}
+
+func (h *WriterHandler) HasExternalSubset(context.Context) (bool, error) {
+	return false, ErrHandlerUnspecified
+}
+
+func (h *WriterHandler) HasInternalSubset(context.Context) (bool, error) {
+	return false, ErrHandlerUnspecified
+}
+
+func (h *WriterHandler) IsStandalone(context.Context) (bool, error) {
+	return false, ErrHandlerUnspecified
+}
+
+func (h *WriterHandler) ResolveEntity(context.Context, string, string) (ParseInput, error) {
+	return nil, ErrHandlerUnspecified
+}
+
+func (h *WriterHandler) Error(context.Context, error) error {
+	return ErrHandlerUnspecified
+}
+
+func (h *WriterHandler) Warning(context.Context, error) error {
+	return ErrHandlerUnspecified
+}
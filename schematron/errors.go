@@ -1,16 +1,25 @@
 package schematron
 
-import "fmt"
+import (
+	"fmt"
+
+	helium "github.com/lestrrat-go/helium"
+)
 
 // ValidationError represents a single schematron validation error.
 // It implements the error interface so it can be passed to
 // helium.ErrorHandler.Handle and extracted via errors.As.
+//
+// Position is the source extent of the node the failing assertion or report
+// fired on. It is only set when the instance document was parsed with
+// [helium.Parser.TrackPositions].
 type ValidationError struct {
 	Filename string
 	Line     int
 	Element  string
 	Path     string
 	Message  string
+	Position helium.Position
 }
 
 // Error implements the error interface, producing libxml2-compatible output.
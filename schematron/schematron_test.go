@@ -486,6 +486,33 @@ func hasXPathError(msgs []string) bool {
 
 // validationErrorCollector implements helium.ErrorHandler and extracts
 // *schematron.ValidationError values via errors.As.
+func TestValidationErrorPosition(t *testing.T) {
+	t.Parallel()
+	const sct = `<schema xmlns="http://www.ascc.net/xml/schematron">
+  <pattern name="test">
+    <rule context="BBB">
+      <assert test="@id">BBB needs an id.</assert>
+    </rule>
+  </pattern>
+</schema>`
+	const src = "<AAA>\n  <BBB/>\n</AAA>"
+
+	sDoc, err := helium.NewParser().Parse(t.Context(), []byte(sct))
+	require.NoError(t, err)
+	schema, err := schematron.NewCompiler().Compile(t.Context(), sDoc)
+	require.NoError(t, err)
+
+	doc, err := helium.NewParser().TrackPositions(true).Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+
+	var collected []*schematron.ValidationError
+	err = schematron.NewValidator(schema).ErrorHandler(validationErrorCollector{errors: &collected}).Validate(t.Context(), doc)
+	require.ErrorIs(t, err, schematron.ErrValidationFailed)
+	require.Len(t, collected, 1)
+	require.Equal(t, helium.Location{Line: 2, Column: 3, Offset: 8}, collected[0].Position.Start)
+	require.Equal(t, helium.Location{Line: 2, Column: 9, Offset: 14}, collected[0].Position.End)
+}
+
 type validationErrorCollector struct {
 	errors *[]*schematron.ValidationError
 }
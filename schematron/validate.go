@@ -125,6 +125,7 @@ func validateDocument(ctx context.Context, doc *helium.Document, schema *Schema,
 								Path:     getNodePath(node),
 								Message:  msg,
 							}
+							ve.Position, _ = helium.PositionOf(node)
 							handler.Handle(ctx, &ve)
 						}
 					}
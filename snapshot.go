@@ -0,0 +1,41 @@
+package helium
+
+// Snapshot returns a read-only copy of d as it is now. Later changes to d do
+// not show in the snapshot, and the snapshot itself rejects mutation like a
+// document returned by [Freeze].
+//
+// Snapshot is not a persistent, structurally shared version of d. A tree
+// node links to its parent, its siblings and its owner, so two versions of a
+// document cannot share nodes, and every snapshot copies all of d's nodes
+// (see Freeze): it takes time and memory proportional to the size of the
+// tree, like [CopyDoc]. Only the character data of text, CDATA and comment
+// nodes and attribute values is shared with d rather than copied, since it
+// is only ever appended to. What Snapshot adds over CopyDoc is that repeated
+// snapshots with no change to d in between return the same snapshot; every
+// change made through the API counts, including those made through setters
+// such as SetNamespace, SetAType or SetEncoding, while the bookkeeping the
+// parser does as it builds d does not.
+//
+// Snapshot reads d, so it must not run concurrently with changes to d; the
+// snapshot it returns can be read from any number of goroutines while d
+// goes on changing. Because d's character data stays referenced, d.Free does
+// not recycle its storage once a snapshot has been taken. Calling Snapshot
+// on a read-only document returns the document itself. To go on editing
+// from a snapshot, as when undoing, copy it with [CopyDoc].
+func (d *Document) Snapshot() (*Document, error) {
+	if d.readOnly {
+		return d, nil
+	}
+	if d.snapshot != nil && d.snapshotGen == d.generation {
+		return d.snapshot, nil
+	}
+	s, err := freeze(d, true)
+	if err != nil {
+		return nil, err
+	}
+	// The snapshot references d's text storage; keep Free from recycling it.
+	d.slabEscaped = true
+	d.snapshot = s
+	d.snapshotGen = d.generation
+	return s, nil
+}
@@ -0,0 +1,21 @@
+package helium
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseBookkeepingIsNotAChange checks that the line numbers, attribute
+// types and ID table the parser fills in do not count as changes to the
+// document.
+func TestParseBookkeepingIsNotAChange(t *testing.T) {
+	doc, err := NewParser().Parse(t.Context(), []byte(`<r><a xml:id="a1"/><b xml:id="b1"/></r>`))
+	require.NoError(t, err)
+	require.NotNil(t, doc.GetElementByID("a1"))
+	require.Equal(t, 1, doc.DocumentElement().Line())
+	require.Zero(t, doc.generation)
+
+	doc.RegisterID("c1", doc.DocumentElement())
+	require.NotZero(t, doc.generation)
+}
@@ -0,0 +1,160 @@
+package helium_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	const src = `<doc><title lang="en">Draft</title><body>text</body></doc>`
+	const before = "<?xml version=\"1.0\"?>\n" + src + "\n"
+
+	t.Run("unaffected by later mutations", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+
+		snap, err := doc.Snapshot()
+		require.NoError(t, err)
+		require.True(t, snap.IsReadOnly())
+
+		root := doc.DocumentElement()
+		title := root.FirstChild().(*helium.Element)
+		body := title.NextSibling().(*helium.Element)
+		require.NoError(t, title.FirstChild().(*helium.Text).AppendText([]byte(" v2")))
+		require.NoError(t, title.SetAttribute("lang", "fr"))
+		e, err := doc.CreateElement("footer")
+		require.NoError(t, err)
+		require.NoError(t, root.AddChild(e))
+		p, err := doc.CreateElement("p")
+		require.NoError(t, err)
+		require.NoError(t, body.Replace(p))
+
+		got, err := helium.WriteString(snap)
+		require.NoError(t, err)
+		require.Equal(t, before, got)
+
+		after, err := helium.WriteString(doc)
+		require.NoError(t, err)
+		require.Equal(t, "<?xml version=\"1.0\"?>\n<doc><title lang=\"fr\">Draft v2</title><p/><footer/></doc>\n", after)
+
+		snap2, err := doc.Snapshot()
+		require.NoError(t, err)
+		require.NotSame(t, snap, snap2)
+		got, err = helium.WriteString(snap2)
+		require.NoError(t, err)
+		require.Equal(t, after, got)
+	})
+
+	t.Run("reused while unchanged", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+
+		s1, err := doc.Snapshot()
+		require.NoError(t, err)
+		s2, err := doc.Snapshot()
+		require.NoError(t, err)
+		require.Same(t, s1, s2)
+
+		s3, err := s1.Snapshot()
+		require.NoError(t, err)
+		require.Same(t, s1, s3)
+
+		helium.UnlinkNode(doc.DocumentElement().LastChild().(helium.MutableNode))
+		s4, err := doc.Snapshot()
+		require.NoError(t, err)
+		require.NotSame(t, s1, s4)
+	})
+
+	t.Run("not reused after setters", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(`<r xmlns:a="urn:a"><item id="x"/></r>`))
+		require.NoError(t, err)
+		item := doc.DocumentElement().FirstChild().(*helium.Element)
+		ns := doc.DocumentElement().Namespaces()[0]
+		attr := item.Attributes()[0]
+
+		for _, change := range []func(){
+			func() { item.SetNamespace(ns) },
+			func() { attr.SetAType(enum.AttrID) },
+			func() { doc.SetEncoding("UTF-16") },
+			func() { doc.SetVersion("1.1") },
+			func() { doc.SetURL("file:///r.xml") },
+			func() { doc.RegisterID("x", item) },
+		} {
+			before, err := doc.Snapshot()
+			require.NoError(t, err)
+			change()
+			after, err := doc.Snapshot()
+			require.NoError(t, err)
+			require.NotSame(t, before, after)
+		}
+
+		snap, err := doc.Snapshot()
+		require.NoError(t, err)
+		require.Equal(t, "urn:a", snap.DocumentElement().FirstChild().(*helium.Element).URI())
+		require.Equal(t, "UTF-16", snap.RawEncoding())
+		require.Same(t, snap.DocumentElement().FirstChild(), snap.GetElementByID("x"))
+
+		// Setters panic on the read-only snapshot and leave it as it is.
+		snapItem := snap.DocumentElement().FirstChild().(*helium.Element)
+		require.Panics(t, func() { snapItem.SetNamespace(nil) })
+		require.Panics(t, func() { snap.SetEncoding("") })
+		require.Equal(t, "urn:a", snapItem.URI())
+		require.Equal(t, "UTF-16", snap.RawEncoding())
+	})
+
+	t.Run("undo", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+
+		var history []*helium.Document
+		for _, v := range []string{"a", "b", "c"} {
+			s, err := doc.Snapshot()
+			require.NoError(t, err)
+			history = append(history, s)
+			require.NoError(t, doc.DocumentElement().SetAttribute("rev", v))
+		}
+
+		restored, err := helium.CopyDoc(history[1])
+		require.NoError(t, err)
+		require.False(t, restored.IsReadOnly())
+		v, ok := restored.DocumentElement().GetAttribute("rev")
+		require.True(t, ok)
+		require.Equal(t, "a", v)
+	})
+
+	t.Run("concurrent readers", func(t *testing.T) {
+		t.Parallel()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		snap, err := doc.Snapshot()
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Go(func() {
+				for range 100 {
+					got, err := helium.WriteString(snap)
+					if err != nil || got != before {
+						t.Errorf("snapshot changed: %q, %v", got, err)
+						return
+					}
+				}
+			})
+		}
+		text := doc.DocumentElement().LastChild().FirstChild().(*helium.Text)
+		for range 100 {
+			require.NoError(t, text.AppendText([]byte("more")))
+		}
+		wg.Wait()
+	})
+}
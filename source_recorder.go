@@ -0,0 +1,600 @@
+package helium
+
+import (
+	"bytes"
+
+	"github.com/lestrrat-go/helium/internal/encoding"
+	"github.com/lestrrat-go/helium/internal/strcursor"
+)
+
+// sourceRecorder records, while the parser builds the tree, where each node's
+// markup lies in the document entity (Parser.TrackPositions) and what that
+// markup was (Parser.PreserveLexical). The construct parsers call the
+// record* hooks of parserCtx around the nodes they create; offsets come from
+// the decoded input cursor, which holds on to the bytes of the construct
+// being parsed for as long as a record may still need them.
+//
+// Offsets count bytes of the input decoded to UTF-8, from its very start:
+// prefix holds what the byte cursor consumed before the decoding cursor took
+// over (the byte order mark and, for an ASCII-compatible encoding, the XML
+// declaration).
+type sourceRecorder struct {
+	positions bool
+	lexical   bool
+	retainAll bool // keep the whole source for Parser.Reparse
+	lineTable bool // collect sourceMap.lines
+
+	input  *strcursor.UTF8Cursor // nil until attach
+	prefix []byte
+	bomLen int
+	// line0 and col0 are the line and column the decoding cursor starts at.
+	line0, col0 int
+	utf8        bool
+	transcoded  bool
+	// failed stops recording once the tree no longer follows the source, as
+	// after a recovered error.
+	failed bool
+
+	lines []lineStart
+	root  *nodeSpan
+	spans map[Node]*nodeSpan
+	open  []openElement
+	attrs []attrSpan // literal attributes of the start tag being parsed
+
+	info *lexicalInfo
+	// run is the record of the sibling nodes the last constructs produced:
+	// character data and references folded into one text node, or expanded
+	// to several nodes. It spans runStart to runEnd; runLast is its last
+	// node.
+	run       *lexicalNode
+	runLast   Node
+	runStart  int
+	runEnd    int
+	leadStart int // start of the whitespace before the next top-level node
+}
+
+// openElement is an element whose start tag has been recorded and whose end
+// tag has not.
+type openElement struct {
+	elem  *Element
+	span  *nodeSpan
+	ln    *lexicalNode
+	start int
+	empty bool
+}
+
+// attrSpan is the extent of a literal attribute, with the offset of its
+// opening quote.
+type attrSpan struct {
+	start, end, quote int
+}
+
+// sourceMark is the state of the tree and the input before a construct is
+// parsed. The zero value records nothing.
+type sourceMark struct {
+	ok      bool
+	start   int
+	parent  Node
+	last    Node
+	lastLen int
+}
+
+func newSourceRecorder(positions, lexical, retainAll bool, doc Node) *sourceRecorder {
+	r := &sourceRecorder{
+		positions: positions,
+		lexical:   lexical,
+		retainAll: positions && retainAll,
+		lineTable: positions && !retainAll,
+		root:      &nodeSpan{node: doc, index: -1},
+		spans:     make(map[Node]*nodeSpan),
+	}
+	if lexical {
+		r.info = &lexicalInfo{nodes: make(map[Node]*lexicalNode)}
+	}
+	return r
+}
+
+// startRecording sets up a recorder for a parse configured to record
+// positions or lexical markup. Only the default TreeBuilder builds the tree
+// the hooks observe.
+func (pctx *parserCtx) startRecording(cfg *parserConfig) {
+	if (!cfg.preserveLex && !cfg.trackPos) || pctx.treeBuilder == nil {
+		return
+	}
+	pctx.rec = newSourceRecorder(cfg.trackPos, cfg.preserveLex, cfg.retainSource, nil)
+	if bcur := pctx.getByteCursor(); bcur != nil {
+		// Keep the BOM and XML declaration until the decoding cursor is
+		// attached.
+		bcur.Retain(0)
+	}
+}
+
+// notePrefix keeps what bcur has consumed, before switchEncoding hands the
+// rest of the input to a decoding cursor that reads ahead of the parser.
+func (pctx *parserCtx) notePrefix(bcur *strcursor.ByteCursor) {
+	r := pctx.rec
+	if r == nil || r.input != nil {
+		return
+	}
+	r.prefix = bytes.Clone(bcur.Slice(0, bcur.Offset()))
+	bcur.Retain(-1)
+}
+
+// attachRecorder starts recording from the decoding cursor switchEncoding
+// installed.
+func (pctx *parserCtx) attachRecorder() {
+	r := pctx.rec
+	u8, ok := pctx.getCursor().(*strcursor.UTF8Cursor)
+	if !ok {
+		r.failed = true
+		return
+	}
+	name := pctx.inputEncodingName()
+	r.utf8 = encoding.IsUTF8(name)
+	r.transcoded = !r.utf8
+
+	prefix := r.prefix
+	switch pctx.detectedEncoding {
+	case encUTF16LE, encUTF16BE, encUCS4BE, encUCS4LE, encUCS42143, encUCS43412, encEBCDIC:
+		// The decoding cursor starts at the document start; a byte order
+		// mark counts as the one it decodes to.
+		prefix = nil
+		if pctx.autoEncoding != "" {
+			prefix = utf8BOM
+		}
+	}
+	r.attach(u8, prefix)
+}
+
+func (r *sourceRecorder) attach(cur *strcursor.UTF8Cursor, prefix []byte) {
+	r.input = cur
+	r.prefix = prefix
+	if bytes.HasPrefix(prefix, utf8BOM) {
+		r.bomLen = len(utf8BOM)
+	}
+	text := prefix[r.bomLen:]
+	r.line0 = 1 + bytes.Count(text, []byte{'\n'})
+	r.col0 = len(text) - bytes.LastIndexByte(text, '\n')
+	if r.lineTable {
+		r.lines = append(r.lines, lineStart{line: 1, off: r.bomLen})
+		if r.line0 > 1 {
+			r.lines = append(r.lines, lineStart{line: r.line0, off: len(prefix) - r.col0 + 1})
+		}
+	}
+	r.leadStart = len(prefix)
+	cur.Retain(0)
+}
+
+// here returns the offset the cursor is at, noting the line it is on.
+func (r *sourceRecorder) here() int {
+	off := len(r.prefix) + r.input.Offset()
+	if r.lineTable {
+		line := r.line0 + r.input.LineNumber() - 1
+		if line > r.lines[len(r.lines)-1].line {
+			col := r.input.Column()
+			if line == r.line0 {
+				col += r.col0 - 1
+			}
+			r.lines = append(r.lines, lineStart{line: line, off: off - col + 1})
+		}
+	}
+	return off
+}
+
+// slice returns the source from from to to, which may alias the cursor's
+// buffer, or nil when it is no longer held.
+func (r *sourceRecorder) slice(from, to int) []byte {
+	p := len(r.prefix)
+	if from >= p {
+		return r.input.Slice(from-p, to-p)
+	}
+	b := bytes.Clone(r.prefix[from:min(to, p)])
+	if to > p {
+		rest := r.input.Slice(0, to-p)
+		if rest == nil {
+			return nil
+		}
+		b = append(b, rest...)
+	}
+	return b
+}
+
+// text is slice, copied.
+func (r *sourceRecorder) text(from, to int) []byte {
+	if from == to {
+		return []byte{}
+	}
+	return bytes.Clone(r.slice(from, to))
+}
+
+// retain tells the cursor which bytes the recorder may still ask for.
+func (r *sourceRecorder) retain(start int) {
+	keep := start
+	if r.retainAll {
+		keep = 0
+	}
+	if r.run != nil {
+		keep = min(keep, r.runStart)
+	}
+	if len(r.open) == 0 {
+		keep = min(keep, r.leadStart)
+	}
+	r.input.Retain(max(keep-len(r.prefix), 0))
+}
+
+// recording reports whether the parser is reading the document entity
+// itself into the tree, as opposed to a DTD, an entity's replacement text,
+// or a stretch it recovers from.
+func (pctx *parserCtx) recording() bool {
+	r := pctx.rec
+	if r == nil || r.input == nil || r.failed {
+		return false
+	}
+	if pctx.disableSAX {
+		r.failed = true
+		return false
+	}
+	return pctx.inSubset == 0 && pctx.inputTab.PeekOne() == any(r.input)
+}
+
+// recordOffset returns the current offset, or -1 when not recording.
+func (pctx *parserCtx) recordOffset() int {
+	if !pctx.recording() {
+		return -1
+	}
+	return pctx.rec.here()
+}
+
+// recordMark notes the state before a construct is parsed.
+func (pctx *parserCtx) recordMark() sourceMark {
+	if !pctx.recording() {
+		return sourceMark{}
+	}
+	r := pctx.rec
+	var parent Node
+	switch {
+	case pctx.elem != nil:
+		parent = pctx.elem
+	case pctx.doc != nil:
+		parent = pctx.doc
+	default:
+		return sourceMark{}
+	}
+	m := sourceMark{ok: true, start: r.here(), parent: parent, last: parent.LastChild()}
+	if t, ok := m.last.(*Text); ok {
+		m.lastLen = len(rawContent(t))
+	}
+	r.retain(m.start)
+	return m
+}
+
+// recordProlog records the XML declaration, which parseDocumentStart has
+// just consumed.
+func (pctx *parserCtx) recordProlog() {
+	if !pctx.recording() {
+		return
+	}
+	r := pctx.rec
+	end := r.here()
+	if r.lexical {
+		if r.utf8 && r.bomLen > 0 {
+			r.info.bom = utf8BOM
+		}
+		if end > r.bomLen {
+			r.info.decl = r.text(r.bomLen, end)
+		}
+	}
+	r.leadStart = end
+	r.retain(end)
+}
+
+// recordAttribute records the literal attribute that parseStartTag has just
+// appended, which started at start.
+func (pctx *parserCtx) recordAttribute(start int) {
+	if start < 0 || !pctx.recording() {
+		return
+	}
+	r := pctx.rec
+	end := len(r.prefix) + r.input.Offset()
+	raw := r.slice(start, end)
+	quote := bytes.IndexAny(raw, `"'`)
+	if quote < 0 {
+		r.failed = true
+		return
+	}
+	if r.lineTable {
+		// A value may span lines, and its end is never a mark.
+		line := r.lines[len(r.lines)-1].line
+		for i, c := range raw {
+			if c == '\n' {
+				line++
+				r.lines = append(r.lines, lineStart{line: line, off: start + i + 1})
+			}
+		}
+	}
+	r.attrs = append(r.attrs, attrSpan{start: start, end: end, quote: start + quote})
+}
+
+// recordStartTag records the element parseStartTag created from the start
+// tag at m. The cursor is at the "/>" of an empty-element tag, which
+// parseEndTag consumes.
+func (pctx *parserCtx) recordStartTag(m sourceMark) {
+	r := pctx.rec
+	if r == nil {
+		return
+	}
+	attrs := r.attrs
+	r.attrs = r.attrs[:0]
+	if !m.ok || !pctx.recording() {
+		return
+	}
+	e := pctx.elem
+	if e == nil || Node(e) == m.parent {
+		return
+	}
+	end := r.here()
+	r.closeRun()
+
+	var span *nodeSpan
+	if r.positions {
+		span = r.parentSpan().add(e, m.start)
+		span.open = end - m.start
+		attr := e.properties
+		for _, a := range attrs {
+			for attr != nil && attr.IsDefault() {
+				attr = attr.NextAttribute()
+			}
+			if attr == nil {
+				break
+			}
+			as := &nodeSpan{
+				node:    attr,
+				parent:  span,
+				index:   -1,
+				off:     a.start,
+				size:    a.end - a.start,
+				valOff:  a.quote + 1 - a.start,
+				valSize: a.end - 1 - (a.quote + 1),
+			}
+			span.attrs = append(span.attrs, as)
+			r.spans[attr] = as
+			attr = attr.NextAttribute()
+		}
+		r.spans[e] = span
+	}
+
+	var ln *lexicalNode
+	if r.lexical {
+		ln = &lexicalNode{raw: r.text(m.start, end)}
+		if len(r.open) == 0 {
+			ln.lead = r.text(r.leadStart, m.start)
+		}
+		r.info.nodes[e] = ln
+	}
+	r.open = append(r.open, openElement{elem: e, span: span, ln: ln, start: m.start, empty: pctx.startTagEmpty})
+}
+
+// recordEndTag completes the record of the element whose end tag, or the
+// "/>" of whose empty-element tag, parseEndTag has just consumed.
+func (pctx *parserCtx) recordEndTag(m sourceMark) {
+	if !m.ok || !pctx.recording() {
+		return
+	}
+	r := pctx.rec
+	n := len(r.open)
+	if n == 0 || Node(r.open[n-1].elem) != m.parent {
+		r.failed = true
+		return
+	}
+	top := r.open[n-1]
+	r.open = r.open[:n-1]
+	end := r.here()
+	r.closeRun()
+
+	if s := top.span; s != nil {
+		s.size = end - top.start
+		if top.empty {
+			s.open = s.size
+		} else {
+			s.close = end - m.start
+		}
+	}
+	if ln := top.ln; ln != nil {
+		if top.empty {
+			ln.raw = append(ln.raw, "/>"...)
+		} else {
+			ln.end = r.text(m.start, end)
+		}
+	}
+	if len(r.open) == 0 {
+		r.leadStart = end
+	}
+}
+
+// recordNodes records the nodes the construct at m created, and the growth
+// of the text node it appended to. ref marks a reference, which continues
+// the run of nodes before it.
+func (pctx *parserCtx) recordNodes(m sourceMark, ref bool) {
+	if !m.ok || !pctx.recording() {
+		return
+	}
+	r := pctx.rec
+	end := r.here()
+
+	var buf [2]Node
+	nodes := buf[:0]
+	next := m.parent.FirstChild()
+	if m.last != nil {
+		next = m.last.NextSibling()
+	}
+	for ; next != nil; next = next.NextSibling() {
+		nodes = append(nodes, next)
+	}
+	extended := false
+	if t, ok := m.last.(*Text); ok && len(rawContent(t)) != m.lastLen {
+		extended = true
+	}
+
+	if r.positions {
+		r.recordSpans(m, nodes, extended, ref, end)
+	}
+	if r.lexical {
+		r.recordRun(m, nodes, extended, ref, end)
+	}
+	if len(r.open) == 0 {
+		r.leadStart = end
+	}
+}
+
+func (r *sourceRecorder) parentSpan() *nodeSpan {
+	if n := len(r.open); n > 0 {
+		return r.open[n-1].span
+	}
+	return r.root
+}
+
+func (r *sourceRecorder) recordSpans(m sourceMark, nodes []Node, extended, ref bool, end int) {
+	if extended {
+		s, ok := r.spans[m.last]
+		switch {
+		case !ok:
+		case len(nodes) == 0:
+			s.size = end - s.off
+		default:
+			// The text ends where the expansion of the reference starts
+			// producing other nodes, which the source cannot show.
+			delete(r.spans, m.last)
+		}
+	}
+	if len(nodes) == 0 {
+		return
+	}
+	n := nodes[0]
+	if ref {
+		// Only a reference that produced a single node of its own is that
+		// node's markup.
+		if extended || len(nodes) > 1 {
+			return
+		}
+		if t := n.Type(); t != TextNode && t != EntityRefNode {
+			return
+		}
+	}
+	s := r.parentSpan().add(n, m.start)
+	s.size = end - m.start
+	r.spans[n] = s
+}
+
+func (r *sourceRecorder) recordRun(m sourceMark, nodes []Node, extended, ref bool, end int) {
+	if r.run != nil && r.runLast == m.last && (ref || extended) {
+		if len(nodes) > 0 {
+			r.run.rest = append(r.run.rest, nodes...)
+			r.run.whole = true
+			r.runLast = nodes[len(nodes)-1]
+		}
+		r.runEnd = end
+		return
+	}
+	r.closeRun()
+	if extended {
+		// The text grew past the run it was recorded with.
+		delete(r.info.nodes, m.last)
+	}
+	if len(nodes) == 0 {
+		return
+	}
+	ln := &lexicalNode{}
+	if len(nodes) > 1 {
+		ln.rest = append([]Node(nil), nodes[1:]...)
+	}
+	_, elem := nodes[0].(*Element)
+	ln.whole = len(ln.rest) > 0 || elem
+	if len(r.open) == 0 {
+		ln.lead = r.text(r.leadStart, m.start)
+	}
+	r.info.nodes[nodes[0]] = ln
+	r.run = ln
+	r.runLast = nodes[len(nodes)-1]
+	r.runStart = m.start
+	r.runEnd = end
+}
+
+// closeRun stores the markup of the current run.
+func (r *sourceRecorder) closeRun() {
+	ln := r.run
+	if ln == nil {
+		return
+	}
+	r.run = nil
+	ln.raw = r.text(r.runStart, r.runEnd)
+	if ln.raw == nil {
+		r.failed = true
+	}
+}
+
+// finishRecording stores what was recorded on the document once the parse
+// has succeeded.
+func (pctx *parserCtx) finishRecording() {
+	r := pctx.rec
+	if r == nil {
+		return
+	}
+	pctx.rec = nil
+	if r.input == nil || r.failed || pctx.doc == nil || len(r.open) > 0 {
+		return
+	}
+	defer r.input.Retain(-1)
+	doc := pctx.doc
+	end := r.here()
+	r.closeRun()
+	if r.lexical {
+		li := r.info
+		li.tail = r.text(r.leadStart, end)
+		li.declSig = lexicalSig(doc)
+		li.seal()
+		doc.lexical = li
+	}
+	if r.positions {
+		r.root.node = doc
+		sm := r.sourceMap(end)
+		if r.retainAll {
+			sm.text = newRope(r.text(0, end))
+			sm.lines = nil
+		}
+		doc.source = sm
+	}
+}
+
+// sourceMap returns the recorded extents, made relative to their parents.
+func (r *sourceRecorder) sourceMap(end int) *sourceMap {
+	r.root.size = end
+	r.root.relativize(r.spans, 0)
+	return &sourceMap{
+		root:       r.root,
+		nodes:      r.spans,
+		lines:      r.lines,
+		base:       r.bomLen,
+		transcoded: r.transcoded,
+	}
+}
+
+// relativize turns the offsets of the kids of s, which starts at start,
+// from offsets into the source into offsets from start, dropping extents
+// that were discarded.
+func (s *nodeSpan) relativize(spans map[Node]*nodeSpan, start int) {
+	for _, attr := range s.attrs {
+		attr.off -= start
+	}
+	kids := s.kids[:0]
+	for _, kid := range s.kids {
+		if spans[kid.node] != kid {
+			continue
+		}
+		kid.relativize(spans, kid.off)
+		kid.off -= start
+		kid.index = len(kids)
+		kids = append(kids, kid)
+	}
+	clear(s.kids[len(kids):])
+	s.kids = kids
+}
@@ -0,0 +1,238 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Backend receives the document a Writer produces as a sequence of events
+// instead of serialized bytes. A Writer created with [NewBackendWriter]
+// checks every call exactly as a Writer created with [NewWriter] does, and
+// reports each construct to its Backend once the construct is complete: an
+// element's start once its start tag is (when content follows it, or the
+// element ends), and a comment, processing instruction or CDATA section
+// when it ends.
+//
+// Names reach a Backend resolved: StartElement receives the namespace URI
+// of the element and of each attribute, from the declarations in scope, and
+// the declarations the start tag makes, including those written as xmlns
+// attributes. Text, attribute values, comments, processing instructions
+// and CDATA sections are passed as written, without escaping.
+//
+// A Backend error is sticky, like an I/O error on the output of a
+// serializing Writer: the call that caused it returns it, as do
+// [Writer.Error], [Writer.Flush] and every later call. If the Backend has a
+// Flush() error method, [Writer.Flush] calls it.
+//
+// helium.DocumentBackend builds a document and helium.SAXBackend fires SAX2
+// events at any sax.SAX2Handler. [WriterBackend] serializes through a
+// Writer, and [Tee] reports to several Backends at once, so one sequence of
+// calls can, say, write a document to disk and build it in memory.
+type Backend interface {
+	// StartDocument begins the document. version is empty when the Writer
+	// reports the start of a document that has no XML declaration, which it
+	// does before its first event unless StartDocument was called.
+	StartDocument(version, encoding, standalone string) error
+	EndDocument() error
+	// DocumentType reports a DOCTYPE declaration. internalSubset holds the
+	// declarations of its internal subset, serialized.
+	DocumentType(name, publicID, systemID, internalSubset string) error
+	StartElement(name Name, namespaces []Namespace, attrs []Attribute) error
+	EndElement(name Name) error
+	Text(text string) error
+	CDATA(text string) error
+	Comment(text string) error
+	ProcessingInstruction(target, data string) error
+	// EntityReference reports a reference to the general entity name in
+	// content, written with [Writer.WriteEntityRef].
+	EntityReference(name string) error
+}
+
+// Name is the name of an element or attribute, as reported to a Backend.
+// NamespaceURI is empty for a name in no namespace.
+type Name struct {
+	Prefix       string
+	LocalName    string
+	NamespaceURI string
+}
+
+// QName returns prefix:localName, or localName when there is no prefix.
+func (n Name) QName() string {
+	return qualifiedName(n.Prefix, n.LocalName)
+}
+
+// Namespace is a namespace declaration made by a start tag. An empty
+// Prefix declares the default namespace, and an empty URI with it
+// undeclares an inherited one.
+type Namespace struct {
+	Prefix string
+	URI    string
+}
+
+// Attribute is an attribute of a start tag, with its value as written.
+type Attribute struct {
+	Name
+	Value string
+}
+
+// pendingAttr is an attribute of the open start tag of a Writer with a
+// Backend, as written.
+type pendingAttr struct {
+	name  string
+	value string
+}
+
+// NewBackendWriter creates a Writer that reports what it is given to b
+// instead of writing XML. Indentation and the quote character do not apply;
+// [Writer.WriteRaw] fails with an error wrapping [errors.ErrUnsupported],
+// since raw markup cannot be turned into events, and so does
+// [Writer.WriteEntityRef] in an attribute value for an entity other than
+// the predefined ones.
+func NewBackendWriter(b Backend) Writer {
+	return Writer{
+		backend:   b,
+		quoteChar: '"',
+		state:     stateNone,
+	}
+}
+
+// errRawUnsupported is returned by WriteRaw on a Writer with a Backend.
+var errRawUnsupported = fmt.Errorf("stream: raw content cannot be written to a Backend: %w", errors.ErrUnsupported)
+
+// WriterBackend returns a Backend that writes the events it receives
+// through w, so a serializing Writer can be one of the Backends of a [Tee].
+// The start of a document without an XML declaration writes nothing.
+func WriterBackend(w *Writer) Backend {
+	return writerBackend{w: w}
+}
+
+type writerBackend struct {
+	w *Writer
+}
+
+func (b writerBackend) StartDocument(version, encoding, standalone string) error {
+	if version == "" {
+		return b.w.Error()
+	}
+	return b.w.StartDocument(version, encoding, standalone)
+}
+
+func (b writerBackend) EndDocument() error {
+	return b.w.EndDocument()
+}
+
+func (b writerBackend) DocumentType(name, publicID, systemID, internalSubset string) error {
+	return b.w.WriteDTD(name, publicID, systemID, internalSubset)
+}
+
+func (b writerBackend) StartElement(name Name, namespaces []Namespace, attrs []Attribute) error {
+	if err := b.w.StartElement(name.QName()); err != nil {
+		return err
+	}
+	for _, ns := range namespaces {
+		if err := b.w.DeclareNamespace(ns.Prefix, ns.URI); err != nil {
+			return err
+		}
+	}
+	for _, attr := range attrs {
+		if err := b.w.WriteAttribute(attr.QName(), attr.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b writerBackend) EndElement(Name) error {
+	return b.w.EndElement()
+}
+
+func (b writerBackend) Text(text string) error {
+	return b.w.WriteString(text)
+}
+
+func (b writerBackend) CDATA(text string) error {
+	return b.w.WriteCDATA(text)
+}
+
+func (b writerBackend) Comment(text string) error {
+	return b.w.WriteComment(text)
+}
+
+func (b writerBackend) ProcessingInstruction(target, data string) error {
+	return b.w.WritePI(target, data)
+}
+
+func (b writerBackend) EntityReference(name string) error {
+	return b.w.WriteEntityRef(name)
+}
+
+func (b writerBackend) Flush() error {
+	return b.w.Flush()
+}
+
+// Tee returns a Backend that reports each event to every one of backends
+// in turn. The first error stops delivery of the event and is returned.
+// Its Flush calls the Flush method of each backend that has one.
+func Tee(backends ...Backend) Backend {
+	return tee(backends)
+}
+
+type tee []Backend
+
+func (t tee) each(f func(Backend) error) error {
+	for _, b := range t {
+		if err := f(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t tee) StartDocument(version, encoding, standalone string) error {
+	return t.each(func(b Backend) error { return b.StartDocument(version, encoding, standalone) })
+}
+
+func (t tee) EndDocument() error {
+	return t.each(func(b Backend) error { return b.EndDocument() })
+}
+
+func (t tee) DocumentType(name, publicID, systemID, internalSubset string) error {
+	return t.each(func(b Backend) error { return b.DocumentType(name, publicID, systemID, internalSubset) })
+}
+
+func (t tee) StartElement(name Name, namespaces []Namespace, attrs []Attribute) error {
+	return t.each(func(b Backend) error { return b.StartElement(name, namespaces, attrs) })
+}
+
+func (t tee) EndElement(name Name) error {
+	return t.each(func(b Backend) error { return b.EndElement(name) })
+}
+
+func (t tee) Text(text string) error {
+	return t.each(func(b Backend) error { return b.Text(text) })
+}
+
+func (t tee) CDATA(text string) error {
+	return t.each(func(b Backend) error { return b.CDATA(text) })
+}
+
+func (t tee) Comment(text string) error {
+	return t.each(func(b Backend) error { return b.Comment(text) })
+}
+
+func (t tee) ProcessingInstruction(target, data string) error {
+	return t.each(func(b Backend) error { return b.ProcessingInstruction(target, data) })
+}
+
+func (t tee) EntityReference(name string) error {
+	return t.each(func(b Backend) error { return b.EntityReference(name) })
+}
+
+func (t tee) Flush() error {
+	return t.each(func(b Backend) error {
+		if f, ok := b.(interface{ Flush() error }); ok {
+			return f.Flush()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,225 @@
+package stream_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium/stream"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a Backend that records the events it receives.
+type recorder struct {
+	events []string
+	fail   string // event name that returns errBackend
+}
+
+var errBackend = errors.New("backend failed")
+
+func (r *recorder) add(name, format string, args ...any) error {
+	r.events = append(r.events, name+" "+fmt.Sprintf(format, args...))
+	if name == r.fail {
+		return errBackend
+	}
+	return nil
+}
+
+func (r *recorder) StartDocument(version, encoding, standalone string) error {
+	return r.add("start-document", "%q %q %q", version, encoding, standalone)
+}
+
+func (r *recorder) EndDocument() error {
+	return r.add("end-document", "")
+}
+
+func (r *recorder) DocumentType(name, publicID, systemID, internalSubset string) error {
+	return r.add("doctype", "%s %q %q %s", name, publicID, systemID, internalSubset)
+}
+
+func (r *recorder) StartElement(name stream.Name, namespaces []stream.Namespace, attrs []stream.Attribute) error {
+	var b strings.Builder
+	b.WriteString(formatName(name))
+	for _, ns := range namespaces {
+		fmt.Fprintf(&b, " xmlns(%s=%s)", ns.Prefix, ns.URI)
+	}
+	for _, a := range attrs {
+		fmt.Fprintf(&b, " %s=%q", formatName(a.Name), a.Value)
+	}
+	return r.add("start", "%s", b.String())
+}
+
+func (r *recorder) EndElement(name stream.Name) error {
+	return r.add("end", "%s", formatName(name))
+}
+
+func (r *recorder) Text(text string) error {
+	return r.add("text", "%q", text)
+}
+
+func (r *recorder) CDATA(text string) error {
+	return r.add("cdata", "%q", text)
+}
+
+func (r *recorder) Comment(text string) error {
+	return r.add("comment", "%q", text)
+}
+
+func (r *recorder) ProcessingInstruction(target, data string) error {
+	return r.add("pi", "%s %q", target, data)
+}
+
+func (r *recorder) EntityReference(name string) error {
+	return r.add("ref", "%s", name)
+}
+
+func formatName(n stream.Name) string {
+	if n.NamespaceURI == "" {
+		return n.QName()
+	}
+	return "{" + n.NamespaceURI + "}" + n.QName()
+}
+
+func TestBackendWriter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reports complete constructs", func(t *testing.T) {
+		t.Parallel()
+		var r recorder
+		w := stream.NewBackendWriter(&r)
+		require.NoError(t, w.StartDocument("1.0", "UTF-8", "yes"))
+		require.NoError(t, w.StartDTD("a:root", "", "root.dtd"))
+		require.NoError(t, w.WriteDTDEntity(false, "e", "ent"))
+		require.NoError(t, w.EndDTD())
+		require.NoError(t, w.StartElementNS("a", "root", "urn:a"))
+		require.NoError(t, w.WriteAttribute("xmlns", "urn:d"))
+		require.NoError(t, w.WriteAttributeNS("b", "at", "urn:b", "1 < 2"))
+		require.NoError(t, w.StartAttribute("plain"))
+		require.NoError(t, w.WriteString("x"))
+		require.NoError(t, w.WriteEntityRef("amp"))
+		require.NoError(t, w.WriteString("y"))
+		require.NoError(t, w.EndAttribute())
+		require.NoError(t, w.WriteAttribute("xml:lang", "en"))
+		require.NoError(t, w.WriteString("one"))
+		require.NoError(t, w.WriteString(" & two"))
+		require.NoError(t, w.WriteEntityRef("e"))
+		require.NoError(t, w.StartElement("child"))
+		require.NoError(t, w.EndElement())
+		require.NoError(t, w.StartComment())
+		require.NoError(t, w.WriteString("a "))
+		require.NoError(t, w.WriteString("comment"))
+		require.NoError(t, w.EndComment())
+		require.NoError(t, w.WritePI("pi", "data"))
+		require.NoError(t, w.WriteCDATA("x]]>y"))
+		require.NoError(t, w.EndDocument())
+
+		require.Equal(t, []string{
+			`start-document "1.0" "UTF-8" "yes"`,
+			`doctype a:root "" "root.dtd" <!ENTITY e "ent">`,
+			`start {urn:a}a:root xmlns(a=urn:a) xmlns(b=urn:b) xmlns(=urn:d) {urn:b}b:at="1 < 2" plain="x&y" {http://www.w3.org/XML/1998/namespace}xml:lang="en"`,
+			`text "one"`,
+			`text " & two"`,
+			`ref e`,
+			`start {urn:d}child`,
+			`end {urn:d}child`,
+			`comment "a comment"`,
+			`pi pi "data"`,
+			`cdata "x]]"`,
+			`cdata ">y"`,
+			`end {urn:a}a:root`,
+			`end-document `,
+		}, r.events)
+	})
+
+	t.Run("reports an implicit document start", func(t *testing.T) {
+		t.Parallel()
+		var r recorder
+		w := stream.NewBackendWriter(&r)
+		require.NoError(t, w.WriteElement("r", "v"))
+		require.NoError(t, w.EndDocument())
+		require.Equal(t, []string{
+			`start-document "" "" ""`,
+			`start r`,
+			`text "v"`,
+			`end r`,
+			`end-document `,
+		}, r.events)
+	})
+
+	t.Run("rejects what cannot be reported", func(t *testing.T) {
+		t.Parallel()
+		var r recorder
+		w := stream.NewBackendWriter(&r)
+		require.NoError(t, w.StartElement("r"))
+		require.ErrorIs(t, w.WriteRaw("<x/>"), errors.ErrUnsupported)
+		require.NoError(t, w.StartAttribute("a"))
+		require.ErrorIs(t, w.WriteEntityRef("e"), errors.ErrUnsupported)
+		require.NoError(t, w.EndAttribute())
+		require.NoError(t, w.EndElement())
+		require.NoError(t, w.Error())
+	})
+
+	t.Run("unbound prefix is sticky", func(t *testing.T) {
+		t.Parallel()
+		var r recorder
+		w := stream.NewBackendWriter(&r)
+		require.NoError(t, w.StartElement("p:r"))
+		err := w.WriteString("text")
+		require.ErrorContains(t, err, `namespace prefix "p" is not declared`)
+		require.Equal(t, err, w.Error())
+	})
+
+	t.Run("backend error is sticky", func(t *testing.T) {
+		t.Parallel()
+		r := recorder{fail: "comment"}
+		w := stream.NewBackendWriter(&r)
+		require.NoError(t, w.StartElement("r"))
+		require.ErrorIs(t, w.WriteComment("c"), errBackend)
+		require.ErrorIs(t, w.WriteString("text"), errBackend)
+		require.ErrorIs(t, w.Flush(), errBackend)
+		require.Equal(t, []string{`start-document "" "" ""`, `start r`, `comment "c"`}, r.events)
+	})
+}
+
+func TestTee(t *testing.T) {
+	t.Parallel()
+
+	emit := func(w *stream.Writer) error {
+		if err := w.StartDocument("1.0", "", ""); err != nil {
+			return err
+		}
+		if err := w.WriteDTD("r", "", "", `<!ENTITY e "ent">`); err != nil {
+			return err
+		}
+		if err := w.StartElementNS("", "r", "urn:r"); err != nil {
+			return err
+		}
+		if err := w.WriteAttributeNS("x", "a", "urn:x", `"v"`); err != nil {
+			return err
+		}
+		if err := w.WriteElement("c", "a<b"); err != nil {
+			return err
+		}
+		if err := w.WriteEntityRef("e"); err != nil {
+			return err
+		}
+		if err := w.WriteComment("c"); err != nil {
+			return err
+		}
+		return w.EndDocument()
+	}
+
+	var want bytes.Buffer
+	direct := stream.NewWriter(&want)
+	require.NoError(t, emit(&direct))
+
+	var got bytes.Buffer
+	out := stream.NewWriter(&got)
+	var r recorder
+	w := stream.NewBackendWriter(stream.Tee(stream.WriterBackend(&out), &r))
+	require.NoError(t, emit(&w))
+	require.Equal(t, want.String(), got.String())
+	require.Len(t, r.events, 10)
+}
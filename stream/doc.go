@@ -16,6 +16,16 @@
 // The writer tracks open elements and namespace scopes, and uses sticky
 // error handling — check [Writer.Error] after a sequence of calls.
 //
+// [NewBackendWriter] gives the same API a [Backend] in place of an
+// [io.Writer]: the Writer checks every call as usual and reports the
+// document to the Backend as events, with names resolved to namespace URIs.
+// helium.DocumentBackend builds a helium.Document and helium.SAXBackend
+// fires SAX2 events, so emit logic written once against *Writer can stream
+// to disk, build a tree or feed a SAX pipeline; [Tee] does several at once.
+// xsd.NewValidatingWriter and relaxng.NewValidatingWriter put a schema
+// validator in front of the output, so invalid content fails the call that
+// writes it.
+//
 // # Examples
 //
 // Example code for this package lives in the examples/ directory at the
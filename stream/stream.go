@@ -46,6 +46,7 @@ type elementEntry struct {
 	empty    bool   // true until content is written; enables self-close
 	hasText  bool   // true if text content was written (disables indent for end tag)
 	hasChild bool   // true if child elements were written
+	resolved Name   // name with its namespace URI, once reported to a Backend
 }
 
 // nsEntry tracks a namespace declaration for the current element scope.
@@ -60,12 +61,13 @@ type nsScope struct {
 	emitted int // number of decls already emitted (indices < emitted are done)
 }
 
-// Writer writes XML incrementally to an io.Writer.
+// Writer writes XML incrementally to an io.Writer, or reports it to a
+// [Backend].
 //
 // Writer is not safe for concurrent use by multiple goroutines.
 //
 // The zero value of Writer is not ready to use because it has no output
-// destination. Construct a Writer with NewWriter.
+// destination. Construct a Writer with NewWriter or NewBackendWriter.
 //
 // (libxml2: xmlTextWriter)
 type Writer struct {
@@ -85,6 +87,17 @@ type Writer struct {
 	piQuestion    bool          // true if the current PI body ends with '?' (would form '?>' across writes)
 	cdataBrackets int           // count (0,1,2) of trailing ']' in the current CDATA body, to detect ']]>' across writes
 	xml11         bool          // true when serializing XML 1.1: restricted control chars are emitted as decimal character references instead of being rejected
+
+	// Backend mode (NewBackendWriter): out is nil, so nothing is serialized,
+	// except the internal subset of a DTD, which is collected in subset.
+	backend  Backend
+	started  bool          // true once StartDocument was reported to backend
+	capture  []byte        // content of the open attribute, comment, PI or CDATA section
+	attrs    []pendingAttr // attributes of the open start tag
+	attrName string        // name of the open attribute
+	piTarget string        // target of the open PI
+	dtd      [3]string     // name, public and system ID of the open DTD
+	subset   *strings.Builder
 }
 
 // NewWriter creates a Writer that writes to w. Configure the Writer
@@ -190,12 +203,119 @@ func (w *Writer) ensureWritable() bool {
 		return false
 	}
 	if w.out == nil {
-		w.err = errNilOutputWriter
+		if w.backend == nil {
+			w.err = errNilOutputWriter
+		}
 		return false
 	}
 	return true
 }
 
+// backendReady reports whether the Writer has a Backend to report an event
+// to, reporting the start of the document first if that has not happened.
+func (w *Writer) backendReady() bool {
+	if w.backend == nil || w.err != nil {
+		return false
+	}
+	if !w.started {
+		w.started = true
+		w.err = w.backend.StartDocument("", "", "")
+	}
+	return w.err == nil
+}
+
+// lookupPrefix returns the namespace URI prefix is bound to in the current
+// namespace stack.
+func (w *Writer) lookupPrefix(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return xmlNamespaceURI, true
+	}
+	for _, v := range slices.Backward(w.nsStack) {
+		for _, ns := range v.decls {
+			if ns.prefix == prefix {
+				return ns.uri, true
+			}
+		}
+	}
+	return "", false
+}
+
+// resolveName splits qname and looks up the namespace URI of its prefix. An
+// unprefixed name is in the default namespace if isElement is set, and in no
+// namespace otherwise.
+func (w *Writer) resolveName(qname string, isElement bool) (Name, error) {
+	prefix, local, ok := strings.Cut(qname, ":")
+	if !ok {
+		prefix, local = "", qname
+	}
+	name := Name{Prefix: prefix, LocalName: local}
+	if prefix == "" && !isElement {
+		return name, nil
+	}
+	uri, found := w.lookupPrefix(prefix)
+	if !found && prefix != "" {
+		return name, fmt.Errorf("stream: namespace prefix %q is not declared", prefix)
+	}
+	name.NamespaceURI = uri
+	return name, nil
+}
+
+// reportStartTag reports the start tag of e, the innermost open element, to
+// the Backend. Attributes written as xmlns declarations join the namespace
+// declarations of the element's scope, against which the element and
+// attribute names are then resolved.
+func (w *Writer) reportStartTag(e *elementEntry) {
+	if !w.backendReady() {
+		return
+	}
+	attrs := make([]Attribute, 0, len(w.attrs))
+	pending := w.attrs
+	w.attrs = w.attrs[:0]
+	for _, a := range pending {
+		prefix, ok := strings.CutPrefix(a.name, "xmlns:")
+		if !ok {
+			if a.name != "xmlns" {
+				continue
+			}
+			prefix = ""
+		}
+		if prefix == "xml" {
+			continue
+		}
+		if w.nsPrefixConflict(prefix, a.value) {
+			w.err = fmt.Errorf("stream: namespace prefix %q already bound to a different namespace in this element", prefix)
+			return
+		}
+		w.declareNS(prefix, a.value)
+	}
+	name, err := w.resolveName(e.name, true)
+	if err != nil {
+		w.err = err
+		return
+	}
+	for _, a := range pending {
+		if a.name == "xmlns" || strings.HasPrefix(a.name, "xmlns:") {
+			continue
+		}
+		an, err := w.resolveName(a.name, false)
+		if err != nil {
+			w.err = err
+			return
+		}
+		attrs = append(attrs, Attribute{Name: an, Value: a.value})
+	}
+	var namespaces []Namespace
+	if len(w.nsStack) > 0 {
+		decls := w.nsStack[len(w.nsStack)-1].decls
+		namespaces = make([]Namespace, len(decls))
+		for i, ns := range decls {
+			namespaces[i] = Namespace{Prefix: ns.prefix, URI: ns.uri}
+		}
+	}
+	e.resolved = name
+	w.err = w.backend.StartElement(name, namespaces, attrs)
+}
+
 // writeStr writes a raw string to the underlying writer.
 func (w *Writer) writeStr(s string) {
 	if !w.ensureWritable() {
@@ -364,6 +484,9 @@ func (w *Writer) closeTagIfOpen() {
 	if w.state != stateName {
 		return
 	}
+	if w.backend != nil && len(w.elemStack) > 0 {
+		w.reportStartTag(&w.elemStack[len(w.elemStack)-1])
+	}
 	// Emit any pending namespace declarations
 	w.emitPendingNS()
 	w.writeByte('>')
@@ -780,6 +903,10 @@ func (w *Writer) StartDocument(version, enc, standalone string) error {
 		w.writeByte(w.quoteChar)
 	}
 	w.writeStr("?>\n")
+	if w.backend != nil && w.err == nil {
+		w.started = true
+		w.err = w.backend.StartDocument(version, enc, standalone)
+	}
 	w.state = stateDocument
 	w.hasOutput = true
 	return w.err
@@ -819,6 +946,10 @@ func (w *Writer) EndDocument() error {
 	if w.indent == "" {
 		w.writeStr("\n")
 	}
+	if w.backendReady() {
+		w.err = w.backend.EndDocument()
+		w.started = false
+	}
 	w.state = stateNone
 	return w.Flush()
 }
@@ -848,6 +979,12 @@ func (w *Writer) StartElement(name string) error {
 	default:
 		return errors.New("stream: StartElement called in invalid state")
 	}
+	if w.state == stateDTD && w.backend != nil {
+		// The DOCTYPE must reach the Backend before the element does.
+		if err := w.EndDTD(); err != nil {
+			return err
+		}
+	}
 
 	// Mark parent as having children
 	w.markParentChild()
@@ -899,6 +1036,46 @@ func (w *Writer) StartElementNS(prefix, localName, namespaceURI string) error {
 	return w.err
 }
 
+// DeclareNamespace binds prefix to namespaceURI on the element whose start
+// tag is open, writing the xmlns declaration unless an ancestor already binds
+// prefix to the same URI. An empty prefix declares the default namespace,
+// and an empty namespaceURI with an empty prefix undeclares an inherited
+// default namespace (xmlns=""). Declarations made by StartElementNS and
+// StartAttributeNS share the same scope, so a prefix bound here is not
+// declared again by a later namespace-qualified attribute.
+func (w *Writer) DeclareNamespace(prefix, namespaceURI string) error {
+	if w.err != nil {
+		return w.err
+	}
+	if prefix != "" && !xmlchar.IsValidNCName(prefix) {
+		return fmt.Errorf("stream: invalid namespace prefix %q", prefix)
+	}
+	if prefix != "" && namespaceURI == "" {
+		return fmt.Errorf("stream: namespace prefix %q must not be undeclared", prefix)
+	}
+	if err := validateReservedNS("namespace", prefix, namespaceURI); err != nil {
+		return err
+	}
+	if err := validateXMLChars("namespace URI", namespaceURI); err != nil {
+		return err
+	}
+	if w.state != stateName {
+		return errors.New("stream: DeclareNamespace called outside element opening tag")
+	}
+	if w.nsPrefixConflict(prefix, namespaceURI) {
+		return fmt.Errorf("stream: namespace prefix %q already bound to a different namespace in this element", prefix)
+	}
+	switch {
+	case prefix == "xml":
+		// The xml prefix is bound implicitly and never declared.
+	case prefix == "" && namespaceURI == "" && !w.hasDefaultNSInScope():
+		// Nothing to undeclare.
+	default:
+		w.declareNS(prefix, namespaceURI)
+	}
+	return w.err
+}
+
 // EndElement closes the current element. Uses self-closing form "/>"
 // when the element has no content.
 func (w *Writer) EndElement() error {
@@ -920,6 +1097,9 @@ func (w *Writer) EndElement() error {
 
 	if w.state == stateName && entry.empty {
 		// Self-closing: emit pending NS then close
+		if w.backend != nil {
+			w.reportStartTag(&entry)
+		}
 		w.emitPendingNS()
 		w.writeStr("/>")
 	} else {
@@ -933,6 +1113,9 @@ func (w *Writer) EndElement() error {
 		w.writeStr(entry.name)
 		w.writeByte('>')
 	}
+	if w.backendReady() {
+		w.err = w.backend.EndElement(entry.resolved)
+	}
 
 	// Pop namespace scope
 	if len(w.nsStack) > 0 {
@@ -978,6 +1161,9 @@ func (w *Writer) FullEndElement() error {
 	w.writeStr("</")
 	w.writeStr(entry.name)
 	w.writeByte('>')
+	if w.backendReady() {
+		w.err = w.backend.EndElement(entry.resolved)
+	}
 
 	// Pop namespace scope
 	if len(w.nsStack) > 0 {
@@ -1054,6 +1240,8 @@ func (w *Writer) StartAttribute(name string) error {
 	w.writeStr(name)
 	w.writeByte('=')
 	w.writeByte(w.quoteChar)
+	w.attrName = name
+	w.capture = w.capture[:0]
 	w.stateStack = append(w.stateStack, w.state)
 	w.state = stateAttribute
 	return w.err
@@ -1110,6 +1298,9 @@ func (w *Writer) EndAttribute() error {
 		return errors.New("stream: EndAttribute called outside attribute")
 	}
 	w.writeByte(w.quoteChar)
+	if w.backend != nil {
+		w.attrs = append(w.attrs, pendingAttr{name: w.attrName, value: string(w.capture)})
+	}
 	// Restore previous state
 	w.popState(stateName)
 	return w.err
@@ -1171,6 +1362,7 @@ func (w *Writer) WriteString(content string) error {
 			w.elemStack[len(w.elemStack)-1].hasText = true
 		}
 		w.writeTextEscaped(content)
+		w.reportText(content)
 	case stateNone, stateText, stateDocument:
 		if err := w.validateContentChars("text", content); err != nil {
 			return err
@@ -1179,11 +1371,13 @@ func (w *Writer) WriteString(content string) error {
 			w.elemStack[len(w.elemStack)-1].hasText = true
 		}
 		w.writeTextEscaped(content)
+		w.reportText(content)
 	case stateAttribute:
 		if err := w.validateContentChars("attribute", content); err != nil {
 			return err
 		}
 		w.writeAttrEscaped(content)
+		w.captureContent(content)
 	case stateComment:
 		if err := validateXMLChars("comment", content); err != nil {
 			return err
@@ -1195,6 +1389,7 @@ func (w *Writer) WriteString(content string) error {
 			return errors.New("stream: comment content must not contain '--'")
 		}
 		w.writeStr(content)
+		w.captureContent(content)
 		if content != "" {
 			w.commentDash = strings.HasSuffix(content, "-")
 		}
@@ -1209,6 +1404,7 @@ func (w *Writer) WriteString(content string) error {
 			return errors.New("stream: processing instruction content must not contain '?>'")
 		}
 		w.writeStr(content)
+		w.captureContent(content)
 		if content != "" {
 			w.piQuestion = strings.HasSuffix(content, "?")
 		}
@@ -1218,12 +1414,28 @@ func (w *Writer) WriteString(content string) error {
 			return err
 		}
 		w.writeCDATAEscaped(content)
+		w.captureContent(content)
 	default:
 		return errors.New("stream: WriteString called in invalid state")
 	}
 	return w.err
 }
 
+// reportText reports text content to the Backend.
+func (w *Writer) reportText(content string) {
+	if content != "" && w.backendReady() {
+		w.err = w.backend.Text(content)
+	}
+}
+
+// captureContent collects the content of an attribute value, comment, PI or
+// CDATA section, which is reported to the Backend when it ends.
+func (w *Writer) captureContent(content string) {
+	if w.backend != nil {
+		w.capture = append(w.capture, content...)
+	}
+}
+
 // WriteRaw writes content directly without any escaping.
 // Callers must ensure the content is well-formed XML; passing
 // untrusted input may produce malformed output or introduce
@@ -1232,6 +1444,9 @@ func (w *Writer) WriteRaw(content string) error {
 	if w.err != nil {
 		return w.err
 	}
+	if w.backend != nil {
+		return errRawUnsupported
+	}
 	switch w.state {
 	case stateName:
 		w.closeTagIfOpen()
@@ -1252,6 +1467,59 @@ func (w *Writer) WriteRaw(content string) error {
 	return w.err
 }
 
+// WriteEntityRef writes a general entity reference (&name;) in element
+// content or an attribute value. The name is validated, so unlike WriteRaw
+// it cannot inject markup; declaring the entity is up to the caller.
+func (w *Writer) WriteEntityRef(name string) error {
+	if w.err != nil {
+		return w.err
+	}
+	// Entity names are NCNames in helium (see WriteDTDEntity).
+	if !xmlchar.IsValidNCName(name) {
+		return fmt.Errorf("stream: invalid entity name %q", name)
+	}
+	switch w.state {
+	case stateName, stateNone, stateText, stateDocument, stateAttribute:
+	default:
+		return errors.New("stream: WriteEntityRef called in invalid state")
+	}
+	if w.backend != nil {
+		return w.reportEntityRef(name)
+	}
+	return w.WriteRaw("&" + name + ";")
+}
+
+// predefinedEntities maps the names of the predefined entities to their
+// replacement text.
+var predefinedEntities = map[string]string{
+	"lt":   "<",
+	"gt":   ">",
+	"amp":  "&",
+	"apos": "'",
+	"quot": `"`,
+}
+
+// reportEntityRef is WriteEntityRef for a Writer with a Backend. In an
+// attribute value only the predefined entities can be expanded.
+func (w *Writer) reportEntityRef(name string) error {
+	if w.state == stateAttribute {
+		text, ok := predefinedEntities[name]
+		if !ok {
+			return fmt.Errorf("stream: entity %q cannot be expanded in an attribute value reported to a Backend: %w", name, errors.ErrUnsupported)
+		}
+		w.captureContent(text)
+		return nil
+	}
+	w.closeTagIfOpen()
+	if len(w.elemStack) > 0 {
+		w.elemStack[len(w.elemStack)-1].hasText = true
+	}
+	if w.backendReady() {
+		w.err = w.backend.EntityReference(name)
+	}
+	return w.err
+}
+
 // --- Comments ---
 
 // StartComment opens a comment (<!--).
@@ -1269,6 +1537,7 @@ func (w *Writer) StartComment() error {
 	}
 	w.markParentChild()
 	w.writeStr("<!--")
+	w.capture = w.capture[:0]
 	w.commentDash = false
 	w.stateStack = append(w.stateStack, w.state)
 	w.state = stateComment
@@ -1291,6 +1560,9 @@ func (w *Writer) EndComment() error {
 		w.writeStr("\n")
 		w.wroteNL = true
 	}
+	if w.backendReady() {
+		w.err = w.backend.Comment(string(w.capture))
+	}
 	w.popState(stateDocument)
 	return w.err
 }
@@ -1350,6 +1622,8 @@ func (w *Writer) StartPI(target string) error {
 	w.markParentChild()
 	w.writeStr("<?")
 	w.writeStr(target)
+	w.piTarget = target
+	w.capture = w.capture[:0]
 	w.piQuestion = false
 	w.stateStack = append(w.stateStack, w.state)
 	w.state = statePI
@@ -1369,6 +1643,9 @@ func (w *Writer) EndPI() error {
 		w.writeStr("\n")
 		w.wroteNL = true
 	}
+	if w.backendReady() {
+		w.err = w.backend.ProcessingInstruction(w.piTarget, string(w.capture))
+	}
 	w.popState(stateDocument)
 	return w.err
 }
@@ -1419,6 +1696,7 @@ func (w *Writer) StartCDATA() error {
 		w.elemStack[len(w.elemStack)-1].hasText = true
 	}
 	w.writeStr("<![CDATA[")
+	w.capture = w.capture[:0]
 	w.cdataBrackets = 0
 	w.stateStack = append(w.stateStack, w.state)
 	w.state = stateCDATA
@@ -1434,6 +1712,9 @@ func (w *Writer) EndCDATA() error {
 		return errors.New("stream: EndCDATA called outside CDATA section")
 	}
 	w.writeStr("]]>")
+	if w.backendReady() {
+		w.err = w.backend.CDATA(string(w.capture))
+	}
 	w.popState(stateText)
 	return w.err
 }
@@ -1531,6 +1812,7 @@ func (w *Writer) StartDTD(name, pubid, sysid string) error {
 		}
 		w.writeQuotedID(sysid)
 	}
+	w.dtd = [3]string{name, pubid, sysid}
 	w.state = stateDTD
 	return w.err
 }
@@ -1589,11 +1871,16 @@ func (w *Writer) markParentChild() {
 }
 
 // ensureDTDInternalSubset writes the opening " [" for the DTD internal
-// subset if it hasn't been written yet.
+// subset if it hasn't been written yet. A Writer with a Backend collects the
+// internal subset instead.
 func (w *Writer) ensureDTDInternalSubset() {
 	if w.state == stateDTD {
 		w.writeStr(" [")
 		w.state = stateDTDText
+		if w.backend != nil {
+			w.subset = &strings.Builder{}
+			w.out = w.subset
+		}
 	}
 }
 
@@ -1605,6 +1892,12 @@ func (w *Writer) EndDTD() error {
 	if w.state != stateDTD && w.state != stateDTDText {
 		return errors.New("stream: EndDTD called outside DTD")
 	}
+	var subset string
+	if w.subset != nil {
+		subset = w.subset.String()
+		w.subset = nil
+		w.out = nil
+	}
 	if w.state == stateDTDText {
 		w.writeStr("]>")
 	} else {
@@ -1614,6 +1907,9 @@ func (w *Writer) EndDTD() error {
 		w.writeStr("\n")
 		w.wroteNL = true
 	}
+	if w.backendReady() {
+		w.err = w.backend.DocumentType(w.dtd[0], w.dtd[1], w.dtd[2], subset)
+	}
 	w.state = stateDocument
 	return w.err
 }
@@ -1631,9 +1927,8 @@ func (w *Writer) WriteDTD(name, pubid, sysid, subset string) error {
 		return err
 	}
 	if subset != "" {
-		w.writeStr(" [")
+		w.ensureDTDInternalSubset()
 		w.writeStr(subset)
-		w.writeByte(']')
 	}
 	return w.EndDTD()
 }
@@ -1785,7 +2080,8 @@ func (w *Writer) WriteDTDNotation(name, pubid, sysid string) error {
 // --- Flush ---
 
 // Flush delegates to the underlying writer's Flush method if it
-// implements one (e.g. *bufio.Writer). It is a no-op otherwise.
+// implements one (e.g. *bufio.Writer), or to the Backend's. It is a no-op
+// otherwise.
 func (w *Writer) Flush() error {
 	if w.err != nil {
 		return w.err
@@ -1793,5 +2089,8 @@ func (w *Writer) Flush() error {
 	if f, ok := w.out.(interface{ Flush() error }); ok {
 		w.err = f.Flush()
 	}
+	if f, ok := w.backend.(interface{ Flush() error }); ok && w.err == nil {
+		w.err = f.Flush()
+	}
 	return w.err
 }
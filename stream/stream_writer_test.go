@@ -819,3 +819,70 @@ func TestEndAttributeOutside(t *testing.T) {
 	require.NoError(t, w.StartElement("root"))
 	require.Error(t, w.EndAttribute())
 }
+
+func TestDeclareNamespace(t *testing.T) {
+	t.Parallel()
+
+	t.Run("declares once per scope", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		w := stream.NewWriter(&buf)
+		require.NoError(t, w.StartElementNS("p", "root", "urn:p"))
+		require.NoError(t, w.DeclareNamespace("p", "urn:p"))
+		require.NoError(t, w.DeclareNamespace("q", "urn:q"))
+		require.NoError(t, w.DeclareNamespace("xml", "http://www.w3.org/XML/1998/namespace"))
+		require.NoError(t, w.WriteAttributeNS("q", "a", "urn:q", "1"))
+		require.NoError(t, w.StartElement("child"))
+		require.NoError(t, w.DeclareNamespace("q", "urn:q"))
+		require.NoError(t, w.DeclareNamespace("", ""))
+		require.NoError(t, w.EndElement())
+		require.NoError(t, w.EndElement())
+		require.NoError(t, w.Flush())
+		require.Equal(t, `<p:root q:a="1" xmlns:p="urn:p" xmlns:q="urn:q"><child/></p:root>`, buf.String())
+	})
+
+	t.Run("undeclares an inherited default namespace", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		w := stream.NewWriter(&buf)
+		require.NoError(t, w.StartElement("root"))
+		require.NoError(t, w.DeclareNamespace("", "urn:d"))
+		require.NoError(t, w.StartElement("child"))
+		require.NoError(t, w.DeclareNamespace("", ""))
+		require.NoError(t, w.EndElement())
+		require.NoError(t, w.EndElement())
+		require.NoError(t, w.Flush())
+		require.Equal(t, `<root xmlns="urn:d"><child xmlns=""/></root>`, buf.String())
+	})
+
+	t.Run("rejects invalid declarations", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		w := stream.NewWriter(&buf)
+		require.Error(t, w.DeclareNamespace("p", "urn:p"), "outside a start tag")
+		require.NoError(t, w.StartElement("root"))
+		require.Error(t, w.DeclareNamespace("p", ""))
+		require.Error(t, w.DeclareNamespace("a b", "urn:p"))
+		require.Error(t, w.DeclareNamespace("xmlns", "urn:p"))
+		require.Error(t, w.DeclareNamespace("p", `urn:"x`+"\x00"))
+		require.NoError(t, w.DeclareNamespace("p", "urn:p"))
+		require.Error(t, w.DeclareNamespace("p", "urn:other"))
+	})
+}
+
+func TestWriteEntityRef(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w := stream.NewWriter(&buf)
+	require.NoError(t, w.StartDocument("", "", ""))
+	require.NoError(t, w.StartDTD("root", "", ""))
+	require.Error(t, w.WriteEntityRef("e"), "inside the DTD")
+	require.NoError(t, w.WriteDTDEntity(false, "e", "x"))
+	require.NoError(t, w.EndDTD())
+	require.NoError(t, w.StartElement("root"))
+	require.NoError(t, w.WriteEntityRef("e"))
+	require.Error(t, w.WriteEntityRef("e;<x"))
+	require.NoError(t, w.EndDocument())
+	require.Equal(t, "<?xml version=\"1.0\"?>\n<!DOCTYPE root [<!ENTITY e \"x\">]><root>&e;</root>\n", buf.String())
+}
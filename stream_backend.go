@@ -0,0 +1,286 @@
+package helium
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/helium/sax"
+	"github.com/lestrrat-go/helium/stream"
+)
+
+// DocumentBackend is a stream.Backend that builds a Document, so the
+// stream.Writer API, which checks well-formedness as it goes, can construct
+// a tree as well as serialize one:
+//
+//	b := helium.NewDocumentBackend()
+//	w := stream.NewBackendWriter(b)
+//	// ... StartElementNS, WriteAttributeNS, WriteString, EndElement ...
+//	doc := b.Document()
+//
+// Adjacent text is merged into one Text node, as the parser does. Text
+// outside the document element is dropped when it is whitespace and is an
+// error otherwise, as is a second document element. The DOCTYPE is parsed
+// to build the internal subset.
+type DocumentBackend struct {
+	doc   *Document
+	stack []*Element
+}
+
+// NewDocumentBackend creates a DocumentBackend.
+func NewDocumentBackend() *DocumentBackend {
+	return &DocumentBackend{}
+}
+
+// Document returns the document built so far, or nil before the start of
+// the document has been reported.
+func (b *DocumentBackend) Document() *Document {
+	return b.doc
+}
+
+func (b *DocumentBackend) StartDocument(version, encoding, standalone string) error {
+	if version == "" {
+		version = "1.0"
+	}
+	sa := StandaloneImplicitNo
+	switch standalone {
+	case "yes":
+		sa = StandaloneExplicitYes
+	case "no":
+		sa = StandaloneExplicitNo
+	}
+	b.doc = NewDocument(version, encoding, sa)
+	b.stack = b.stack[:0]
+	return nil
+}
+
+func (b *DocumentBackend) EndDocument() error {
+	return nil
+}
+
+func (b *DocumentBackend) DocumentType(name, publicID, systemID, internalSubset string) error {
+	src, err := parseDoctype(context.Background(), name, publicID, systemID, internalSubset)
+	if err != nil {
+		return err
+	}
+	return CopyDTDInfo(src, b.doc)
+}
+
+// add adds n to the open element, or to the document.
+func (b *DocumentBackend) add(n Node) error {
+	if k := len(b.stack); k > 0 {
+		return b.stack[k-1].AddChild(n)
+	}
+	return b.doc.AddChild(n)
+}
+
+func (b *DocumentBackend) StartElement(name stream.Name, namespaces []stream.Namespace, attrs []stream.Attribute) error {
+	if len(b.stack) == 0 && b.doc.DocumentElement() != nil {
+		return fmt.Errorf("helium: second document element %q: %w", name.QName(), ErrInvalidOperation)
+	}
+	e, err := b.doc.CreateElement(name.LocalName)
+	if err != nil {
+		return err
+	}
+	for _, ns := range namespaces {
+		if err := e.DeclareNamespace(ns.Prefix, ns.URI); err != nil {
+			return err
+		}
+	}
+	if name.NamespaceURI != "" {
+		ns, err := b.doc.CreateNamespace(name.Prefix, name.NamespaceURI)
+		if err != nil {
+			return err
+		}
+		e.SetNamespace(ns)
+	}
+	for _, a := range attrs {
+		if a.NamespaceURI == "" {
+			if err := e.SetAttribute(a.LocalName, a.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		ns, err := b.doc.CreateNamespace(a.Prefix, a.NamespaceURI)
+		if err != nil {
+			return err
+		}
+		if err := e.SetAttributeNS(a.LocalName, a.Value, ns); err != nil {
+			return err
+		}
+	}
+	if len(b.stack) == 0 {
+		if err := b.doc.SetDocumentElement(e); err != nil {
+			return err
+		}
+	} else if err := b.add(e); err != nil {
+		return err
+	}
+	b.stack = append(b.stack, e)
+	return nil
+}
+
+func (b *DocumentBackend) EndElement(stream.Name) error {
+	if n := len(b.stack); n > 0 {
+		b.stack = b.stack[:n-1]
+	}
+	return nil
+}
+
+func (b *DocumentBackend) Text(text string) error {
+	if k := len(b.stack); k > 0 {
+		return b.stack[k-1].AppendText([]byte(text))
+	}
+	if strings.Trim(text, " \t\r\n") == "" {
+		return nil
+	}
+	return fmt.Errorf("helium: text outside the document element: %w", ErrInvalidOperation)
+}
+
+func (b *DocumentBackend) CDATA(text string) error {
+	return b.add(b.doc.CreateCDATASection([]byte(text)))
+}
+
+func (b *DocumentBackend) Comment(text string) error {
+	return b.add(b.doc.CreateComment([]byte(text)))
+}
+
+func (b *DocumentBackend) ProcessingInstruction(target, data string) error {
+	return b.add(b.doc.CreatePI(target, data))
+}
+
+func (b *DocumentBackend) EntityReference(name string) error {
+	ref, err := b.doc.CreateReference(name)
+	if err != nil {
+		return err
+	}
+	return b.add(ref)
+}
+
+// SAXBackend is a stream.Backend that fires the SAX2 events a parser would
+// fire for what a stream.Writer is given, so a document produced with the
+// Writer API can feed a sax.Filter chain, a validator or a
+// helium.TreeBuilder directly.
+//
+// The DOCTYPE is parsed and reported through InternalSubset, its
+// declarations, and ExternalSubset, as EmitSAX reports a DTD. A handler
+// error other than sax.ErrHandlerUnspecified is returned, and the Writer
+// keeps it.
+type SAXBackend struct {
+	ctx context.Context //nolint:containedctx // Backend methods have no ctx parameter
+	h   sax.SAX2Handler
+}
+
+// NewSAXBackend creates a SAXBackend that fires events at h with ctx.
+func NewSAXBackend(ctx context.Context, h sax.SAX2Handler) *SAXBackend {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &SAXBackend{ctx: ctx, h: h}
+}
+
+func (b *SAXBackend) StartDocument(string, string, string) error {
+	return saxResult(b.h.StartDocument(b.ctx))
+}
+
+func (b *SAXBackend) EndDocument() error {
+	return saxResult(b.h.EndDocument(b.ctx))
+}
+
+func (b *SAXBackend) DocumentType(name, publicID, systemID, internalSubset string) error {
+	doc, err := parseDoctype(b.ctx, name, publicID, systemID, internalSubset)
+	if err != nil {
+		return err
+	}
+	dtd := doc.IntSubset()
+	if dtd == nil {
+		return nil
+	}
+	f := sax.NewFilter(b.h)
+	f.SetOnSetDocumentLocator(sax.SetDocumentLocatorFunc(func(context.Context, sax.DocumentLocator) error { return nil }))
+	f.SetOnStartDocument(sax.StartDocumentFunc(func(context.Context) error { return nil }))
+	f.SetOnEndDocument(sax.EndDocumentFunc(func(context.Context) error { return nil }))
+	return EmitSAX(b.ctx, dtd, f)
+}
+
+func (b *SAXBackend) StartElement(name stream.Name, namespaces []stream.Namespace, attrs []stream.Attribute) error {
+	var nslist []sax.Namespace
+	if len(namespaces) > 0 {
+		nslist = make([]sax.Namespace, len(namespaces))
+		for i, ns := range namespaces {
+			nslist[i] = NewNamespace(ns.Prefix, ns.URI)
+		}
+	}
+	var attrlist []sax.Attribute
+	if len(attrs) > 0 {
+		attrlist = make([]sax.Attribute, len(attrs))
+		for i, a := range attrs {
+			attrlist[i] = attrData{localname: a.LocalName, prefix: a.Prefix, value: a.Value}
+		}
+	}
+	return saxResult(b.h.StartElementNS(b.ctx, name.LocalName, name.Prefix, name.NamespaceURI, nslist, attrlist))
+}
+
+func (b *SAXBackend) EndElement(name stream.Name) error {
+	return saxResult(b.h.EndElementNS(b.ctx, name.LocalName, name.Prefix, name.NamespaceURI))
+}
+
+func (b *SAXBackend) Text(text string) error {
+	return saxResult(b.h.Characters(b.ctx, []byte(text)))
+}
+
+func (b *SAXBackend) CDATA(text string) error {
+	return saxResult(b.h.CDataBlock(b.ctx, []byte(text)))
+}
+
+func (b *SAXBackend) Comment(text string) error {
+	return saxResult(b.h.Comment(b.ctx, []byte(text)))
+}
+
+func (b *SAXBackend) ProcessingInstruction(target, data string) error {
+	return saxResult(b.h.ProcessingInstruction(b.ctx, target, data))
+}
+
+func (b *SAXBackend) EntityReference(name string) error {
+	return saxResult(b.h.Reference(b.ctx, name))
+}
+
+// parseDoctype parses a DOCTYPE declaration reported to a backend into a
+// document whose internal subset holds its declarations.
+func parseDoctype(ctx context.Context, name, publicID, systemID, internalSubset string) (*Document, error) {
+	var src strings.Builder
+	src.WriteString("<!DOCTYPE ")
+	src.WriteString(name)
+	switch {
+	case publicID != "":
+		src.WriteString(" PUBLIC ")
+		writeQuotedLiteral(&src, publicID)
+		src.WriteByte(' ')
+		writeQuotedLiteral(&src, systemID)
+	case systemID != "":
+		src.WriteString(" SYSTEM ")
+		writeQuotedLiteral(&src, systemID)
+	}
+	if internalSubset != "" {
+		src.WriteString(" [")
+		src.WriteString(internalSubset)
+		src.WriteByte(']')
+	}
+	src.WriteString("><doc/>")
+	doc, err := NewParser().Parse(ctx, []byte(src.String()))
+	if err != nil {
+		return nil, fmt.Errorf("helium: document type declaration: %w", err)
+	}
+	return doc, nil
+}
+
+// writeQuotedLiteral writes s in whichever quote it does not contain.
+func writeQuotedLiteral(b *strings.Builder, s string) {
+	q := byte('"')
+	if strings.IndexByte(s, '"') >= 0 {
+		q = '\''
+	}
+	b.WriteByte(q)
+	b.WriteString(s)
+	b.WriteByte(q)
+}
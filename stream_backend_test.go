@@ -0,0 +1,118 @@
+package helium_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/sax"
+	"github.com/lestrrat-go/helium/stream"
+	"github.com/stretchr/testify/require"
+)
+
+// emitBackendDocument writes the same document whichever Backend w has.
+func emitBackendDocument(w *stream.Writer) error {
+	if err := w.StartDocument("1.0", "", ""); err != nil {
+		return err
+	}
+	if err := w.WriteDTD("r", "", "", `<!ENTITY e "ent">`); err != nil {
+		return err
+	}
+	if err := w.WriteComment(" top "); err != nil {
+		return err
+	}
+	if err := w.StartElementNS("", "r", "urn:r"); err != nil {
+		return err
+	}
+	if err := w.WriteAttributeNS("x", "a", "urn:x", `"v" & w`); err != nil {
+		return err
+	}
+	if err := w.WriteAttribute("xml:lang", "en"); err != nil {
+		return err
+	}
+	if err := w.WriteString("one "); err != nil {
+		return err
+	}
+	if err := w.WriteString("two"); err != nil {
+		return err
+	}
+	if err := w.StartElementNS("x", "c", "urn:x"); err != nil {
+		return err
+	}
+	if err := w.WriteCDATA("a<b"); err != nil {
+		return err
+	}
+	if err := w.EndElement(); err != nil {
+		return err
+	}
+	if err := w.WriteElementNS("", "d", "", "no namespace"); err != nil {
+		return err
+	}
+	if err := w.WriteEntityRef("e"); err != nil {
+		return err
+	}
+	if err := w.WritePI("pi", "data"); err != nil {
+		return err
+	}
+	return w.EndDocument()
+}
+
+func TestDocumentBackend(t *testing.T) {
+	t.Parallel()
+
+	t.Run("builds the document the Writer serializes", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		direct := stream.NewWriter(&buf)
+		require.NoError(t, emitBackendDocument(&direct))
+		parsed, err := helium.NewParser().Parse(t.Context(), buf.Bytes())
+		require.NoError(t, err)
+
+		b := helium.NewDocumentBackend()
+		w := stream.NewBackendWriter(b)
+		require.NoError(t, emitBackendDocument(&w))
+		built := b.Document()
+		require.NotNil(t, built)
+
+		want, err := helium.WriteString(parsed)
+		require.NoError(t, err)
+		got, err := helium.WriteString(built)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+
+		root := built.DocumentElement()
+		require.Equal(t, "urn:r", root.URI())
+		require.Equal(t, "one two", string(root.FirstChild().Content()))
+	})
+
+	t.Run("rejects a second document element", func(t *testing.T) {
+		t.Parallel()
+		w := stream.NewBackendWriter(helium.NewDocumentBackend())
+		require.NoError(t, w.WriteElement("a", ""))
+		require.NoError(t, w.StartElement("b"))
+		require.ErrorIs(t, w.EndElement(), helium.ErrInvalidOperation)
+	})
+
+	t.Run("rejects text outside the document element", func(t *testing.T) {
+		t.Parallel()
+		w := stream.NewBackendWriter(helium.NewDocumentBackend())
+		require.NoError(t, w.WriteString("\n"))
+		require.ErrorIs(t, w.WriteString("x"), helium.ErrInvalidOperation)
+	})
+}
+
+func TestSAXBackend(t *testing.T) {
+	t.Parallel()
+
+	var want bytes.Buffer
+	direct := stream.NewWriter(&want)
+	require.NoError(t, emitBackendDocument(&direct))
+
+	// WriterHandler turns the events back into Writer calls, so the bytes
+	// match those of a Writer given the calls directly.
+	var got bytes.Buffer
+	out := stream.NewWriter(&got)
+	w := stream.NewBackendWriter(helium.NewSAXBackend(t.Context(), sax.NewWriterHandler(&out)))
+	require.NoError(t, emitBackendDocument(&w))
+	require.Equal(t, want.String(), got.String())
+}
@@ -1,7 +1,7 @@
 package helium
 
 import (
-	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"io"
@@ -16,7 +16,6 @@ import (
 	"github.com/lestrrat-go/helium/internal/iofs"
 	"github.com/lestrrat-go/helium/internal/iolimit"
 	"github.com/lestrrat-go/helium/internal/lexicon"
-	"github.com/lestrrat-go/helium/internal/strcursor"
 	"github.com/lestrrat-go/helium/internal/uripath"
 	"github.com/lestrrat-go/helium/sax"
 )
@@ -121,7 +120,7 @@ func (t *TreeBuilder) StartElementNS(ctxif context.Context, localname, prefix, u
 		return err
 	}
 
-	e.SetLine(ctx.LineNumber())
+	e.line = ctx.LineNumber()
 
 	// When this element is being created as part of external entity
 	// expansion, record the entity's URI so base-uri() returns the
@@ -191,11 +190,11 @@ func (t *TreeBuilder) StartElementNS(ctxif context.Context, localname, prefix, u
 		aLocalName := a.LocalName()
 		aPrefix := a.Prefix()
 		if decl := lookupAttributeDecl(doc, aLocalName, aPrefix, elemName); decl != nil {
-			a.SetAType(decl.AType())
+			a.atype = decl.AType()
 		}
 		if registerIDs {
 			if a.Name() == lexicon.QNameXMLID || a.AType() == enum.AttrID {
-				doc.RegisterID(a.Value(), e)
+				doc.registerID(a.Value(), e)
 			}
 		}
 		return true
@@ -466,14 +465,17 @@ func baseRelativeFSName(primary, base string) (string, bool) {
 	return rel, true
 }
 
-// openExternalResource opens primary through the parser's fs.FS. primary is the
-// resolved resource name: for the direct entity/DTD paths it is the raw
-// (historical) name — a "file:" URI or an absolute path — tried verbatim first,
-// so [iofs.PermissiveRoot] (which wants the absolute path for os.Open) and a
-// caller FS keyed on the file-URI name are unchanged.
+// openExternalResource opens the resource at uri, which has been resolved
+// against the applicable base URI. A [ResourceResolver] is handed uri itself;
+// the parser's fs.FS is handed primary, the name the caller derived from uri
+// for it. For the direct entity/DTD paths primary is the raw (historical) name
+// — a "file:" URI or an absolute path — tried verbatim first, so
+// [iofs.PermissiveRoot] (which wants the absolute path for os.Open) and a
+// caller FS keyed on the file-URI name are unchanged; for catalog results it
+// is the local path of a "file:" URI (see [catalogOpenName]).
 //
 // This is the single enforcement point for the NONET network-access guard: it
-// runs [networkAccessForbidden] on primary AND on the derived retry name, so a
+// runs [networkAccessForbidden] on uri AND on the derived retry name, so a
 // base-relative retry that turns into a network-scheme name is refused just like
 // a network-scheme primary. A forbidden name returns [ErrNetworkAccessForbidden];
 // callers must treat that error distinctly from an ordinary open failure.
@@ -507,13 +509,37 @@ func baseRelativeFSName(primary, base string) (string, bool) {
 // is path-escape-safe but not a symlink sandbox. For symlink-safe confinement use
 // [os.Root.FS] (os.OpenRoot, Go 1.24+), which refuses any open that escapes the
 // root through a symlink.
-func (ctx *parserCtx) openExternalResource(primary string, retryEligible bool) (fs.File, error) {
-	if networkAccessForbidden(ctx, primary) {
+//
+// A [ResourceResolver] set with [Parser.ResourceResolver] replaces the fs.FS:
+// after the network guard, uri goes to it under ctxif, with no retry. The
+// returned Resource's URI is the name to use as the base of the content; the
+// fs.FS path leaves it empty.
+//
+// A [ResourceCache] set with [Parser.ResourceCache] sits in front of both,
+// keyed by uri, so a cached resource is served after the network guard and
+// without another open.
+func (ctx *parserCtx) openExternalResource(ctxif context.Context, uri, primary string, retryEligible bool) (*Resource, error) {
+	if networkAccessForbidden(ctx, uri) || networkAccessForbidden(ctx, primary) {
 		return nil, ErrNetworkAccessForbidden
 	}
+	if ctx.cache != nil {
+		load := ResourceResolverFunc(func(ctxif context.Context, _ string) (*Resource, error) {
+			return ctx.loadExternalResource(ctxif, uri, primary, retryEligible)
+		})
+		return ctx.cache.ResolverFor(ctx.cachePolicy(), load).ResolveResource(ctxif, uri) //nolint:wrapcheck // resolver errors propagate to caller verbatim
+	}
+	return ctx.loadExternalResource(ctxif, uri, primary, retryEligible)
+}
+
+// loadExternalResource is openExternalResource past the network guard and
+// the cache.
+func (ctx *parserCtx) loadExternalResource(ctxif context.Context, uri, primary string, retryEligible bool) (*Resource, error) {
+	if ctx.resolver != nil {
+		return ctx.resolver.ResolveResource(ctxif, uri) //nolint:wrapcheck // resolver errors propagate to caller verbatim
+	}
 	f, err := ctx.fsys.Open(primary)
 	if err == nil {
-		return f, nil
+		return &Resource{ReadCloser: f, Size: -1}, nil
 	}
 	if !retryEligible || !errors.Is(err, fs.ErrInvalid) {
 		return nil, err //nolint:wrapcheck // resolver errors propagate to caller verbatim
@@ -527,7 +553,7 @@ func (ctx *parserCtx) openExternalResource(primary string, retryEligible bool) (
 	}
 	f2, err2 := ctx.fsys.Open(rel)
 	if err2 == nil {
-		return f2, nil
+		return &Resource{ReadCloser: f2, Size: -1}, nil
 	}
 	return nil, err //nolint:wrapcheck // report the primary (resolved-name) error
 }
@@ -559,11 +585,11 @@ func (t *TreeBuilder) ExternalSubset(ctxif context.Context, name, eid, uri strin
 	retryEligible := systemIDRetryEligible(uri)
 
 	// Try catalog resolution first. A catalog may resolve the identifier to a
-	// "file:" URI, which is not a filesystem path; convert it before it reaches
-	// the base-URI joining and fsys.Open below (CAT-001).
+	// "file:" URI, which is not a filesystem path; it is converted only for
+	// fsys.Open below (CAT-001).
 	if ctx.catalog != nil {
 		if catalogURI := ctx.catalog.Resolve(ctxif, eid, uri); catalogURI != "" {
-			uri = catalogOpenName(catalogURI)
+			uri = catalogURI
 		}
 	}
 
@@ -588,10 +614,25 @@ func (t *TreeBuilder) ExternalSubset(ctxif context.Context, name, eid, uri strin
 	}
 
 	// resolved may be a "file:" URI (e.g. "file:///C:/dir/inc.dtd" from a
-	// drive-rooted base); convert it to a native path before Open, the same way
-	// a catalog-resolved file: URI is handled. A plain path is returned verbatim.
+	// drive-rooted base or a catalog); the fs.FS is handed its native path. A
+	// plain path is used verbatim. A resolver is handed resolved itself.
 	openName := catalogOpenName(resolved)
-	f, err := ctx.openExternalResource(openName, retryEligible)
+
+	// An external subset that parses the same for every document referring to
+	// it is cached parsed, not only loaded.
+	cacheable := ctx.extSubsetCacheable(t)
+	var policy extSubsetCachePolicy
+	if cacheable {
+		if networkAccessForbidden(ctx, resolved) {
+			return ErrNetworkAccessForbidden
+		}
+		policy = ctx.extSubsetCachePolicy()
+		if ctx.loadCachedExtSubset(policy, resolved, name, eid, uri) {
+			return nil
+		}
+	}
+
+	f, err := ctx.openExternalResource(ctxif, resolved, openName, retryEligible)
 	if errors.Is(err, ErrNetworkAccessForbidden) {
 		// A network-scheme name (primary or the base-relative retry) is refused
 		// hard while network access is forbidden, not downgraded to a warning.
@@ -608,6 +649,11 @@ func (t *TreeBuilder) ExternalSubset(ctxif context.Context, name, eid, uri strin
 		_ = ctx.warning(ctxif, "failed to load external DTD subset %q: %s", resolved, err)
 		return nil
 	}
+	// A resolver may report where the subset actually came from; references
+	// inside it resolve against that.
+	if f.URI != "" {
+		resolved = f.URI
+	}
 
 	// fs.FileInfo.Size() is only reliable for regular files: a valid fs.FS
 	// may stream or synthesize DTD content and report a non-regular,
@@ -645,16 +691,6 @@ func (t *TreeBuilder) ExternalSubset(ctxif context.Context, name, eid, uri strin
 		return readErr
 	}
 
-	// An external subset may begin with a TextDecl
-	// ('<?xml' VersionInfo? EncodingDecl S? '?>'). Consume it (and honor any
-	// declared encoding) before the declaration loop, which would otherwise
-	// reject the '<?xml' as a processing instruction whose target may not be
-	// "xml". This is the same treatment external parameter/general entities get.
-	data, textDeclVersion, err := ctx.decodeExternalPEContentVersion(ctxif, resolved, data)
-	if err != nil {
-		return err
-	}
-
 	doc := ctx.doc
 
 	// Create the external subset DTD
@@ -665,60 +701,12 @@ func (t *TreeBuilder) ExternalSubset(ctxif context.Context, name, eid, uri strin
 	dtd.doc = doc
 	doc.extSubset = dtd
 
-	// Parse markup declarations from the DTD content.
-	// Push content onto the input stack and loop until exhausted.
-	savedExternal := ctx.external
-	savedBaseURI := ctx.baseURI
-	savedDTDInputFloor := ctx.dtdInputFloor
-	ctx.external = true
-	ctx.baseURI = resolved
-
-	baseLen := ctx.inputTab.Len()
-	ctx.pushInputWithVersion(strcursor.NewByteCursor(bytes.NewReader(data)), textDeclVersion)
-	// The DTD cursor we just pushed is the enclosing content cursor for the
-	// shared declaration step: it lives one level above baseLen.
-	dtdFloor := ctx.inputTab.Len()
-	// skipBlanksPE expands parameter-entity references inside/adjacent to markup
-	// declarations by pushing their padded replacement text ABOVE this cursor and
-	// crossing back when the PE input is spent; it must never pop below this base
-	// (into the main document input), so record its depth as the floor.
-	ctx.dtdInputFloor = dtdFloor
-
-	// Restore parser state on every exit path, including the error returns
-	// below, and ensure our pushed input is always removed from the stack.
-	defer func() {
-		for ctx.inputTab.Len() > baseLen {
-			ctx.popInput()
-		}
-		ctx.external = savedExternal
-		ctx.baseURI = savedBaseURI
-		ctx.dtdInputFloor = savedDTDInputFloor
-	}()
-
-	// Parse the external subset declaration-by-declaration through the SHARED
-	// step used for INCLUDE-section bodies (parseExternalSubsetDeclStep), so a
-	// parameter-entity reference expands identically in both contexts: a
-	// blank-only skip (NOT skipBlanks, whose handlePEReference would consume a
-	// "%pe;" reference without expanding it), explicit parsePEReference
-	// expansion, spent-cursor cleanup, and a forward-progress guard that surfaces
-	// a malformed "<!BOGUS" while the external DTD cursor/baseURI are still
-	// active (so its location, not the main doctype's, is reported). A malformed
-	// or unterminated conditional section propagates as a fatal error.
-	for ctx.inputTab.Len() > baseLen {
-		top := ctx.adaptCursor(ctx.inputTab.PeekOne())
-		if top == nil || top.Done() {
-			break
-		}
-
-		stop, err := ctx.parseExternalSubsetDeclStep(ctxif, dtdFloor)
-		if err != nil {
-			return err
-		}
-		if stop {
-			break
-		}
+	if err := ctx.parseExternalSubsetContent(ctxif, resolved, data); err != nil {
+		return err
+	}
+	if cacheable && ctx.wellFormed {
+		ctx.storeExtSubset(policy, resolved, len(data))
 	}
-
 	return nil
 }
 
@@ -855,7 +843,15 @@ func (t *TreeBuilder) IgnorableWhitespace(ctxif context.Context, content []byte)
 
 func (t *TreeBuilder) NotationDecl(ctxif context.Context, name string, publicID string, systemID string) error {
 	ctx := t.pctx(ctxif)
-	dtd := ctx.doc.intSubset
+	// Mirror xmlSAX2NotationDecl: a notation belongs to the subset it is
+	// declared in.
+	var dtd *DTD
+	switch ctx.inSubset {
+	case inInternalSubset:
+		dtd = ctx.doc.intSubset
+	case inExternalSubset:
+		dtd = ctx.doc.extSubset
+	}
 	if dtd == nil {
 		return nil
 	}
@@ -886,15 +882,14 @@ func (t *TreeBuilder) ResolveEntity(ctxif context.Context, publicID string, syst
 	ctx := t.pctx(ctxif)
 	if ctx.catalog != nil {
 		if resolved := ctx.catalog.Resolve(ctxif, publicID, systemID); resolved != "" {
-			// A catalog may resolve to a "file:" URI; convert it to a local
-			// path before opening (CAT-001).
-			openName := catalogOpenName(resolved)
-			f, err := ctx.openExternalResource(openName, ctx.extRefRelative)
+			// A catalog may resolve to a "file:" URI; the fs.FS is handed its
+			// local path (CAT-001).
+			f, err := ctx.openExternalResource(ctxif, resolved, catalogOpenName(resolved), ctx.extRefRelative)
 			if errors.Is(err, ErrNetworkAccessForbidden) {
 				return nil, ErrNetworkAccessForbidden
 			}
 			if err == nil {
-				return &fileParseInput{ReadCloser: f, uri: resolved}, nil
+				return &fileParseInput{ReadCloser: f, uri: cmp.Or(f.URI, resolved)}, nil
 			}
 		}
 	}
@@ -905,12 +900,12 @@ func (t *TreeBuilder) ResolveEntity(ctxif context.Context, publicID string, syst
 	// caller FS keyed on that historical name still resolves; openExternalResource
 	// normalizes it only for the confined-FS base-relative retry.
 	if systemID != "" {
-		f, err := ctx.openExternalResource(systemID, ctx.extRefRelative)
+		f, err := ctx.openExternalResource(ctxif, systemID, systemID, ctx.extRefRelative)
 		if errors.Is(err, ErrNetworkAccessForbidden) {
 			return nil, ErrNetworkAccessForbidden
 		}
 		if err == nil {
-			return &fileParseInput{ReadCloser: f, uri: systemID}, nil
+			return &fileParseInput{ReadCloser: f, uri: cmp.Or(f.URI, systemID)}, nil
 		}
 	}
 
@@ -127,7 +127,7 @@ func (pctx *parserCtx) fastStartElement(localname, prefix, uri string, attrs []a
 	if err != nil {
 		return err
 	}
-	e.SetLine(pctx.LineNumber())
+	e.line = pctx.LineNumber()
 	if pctx.currentEntityURI != "" {
 		e.entityBaseURI = pctx.currentEntityURI
 	}
@@ -195,15 +195,15 @@ func (pctx *parserCtx) fastStartElement(localname, prefix, uri string, attrs []a
 
 		if needsAttrDeclLookup {
 			if decl := lookupAttributeDecl(doc, attr.localname, attr.prefix, elemName); decl != nil {
-				created.SetAType(decl.AType())
+				created.atype = decl.AType()
 				if registerIDs && decl.AType() == enum.AttrID {
-					doc.RegisterID(attr.value, e)
+					doc.registerID(attr.value, e)
 					continue
 				}
 			}
 		}
 		if registerIDs && attr.prefix == lexicon.PrefixXML && attr.localname == "id" {
-			doc.RegisterID(attr.value, e)
+			doc.registerID(attr.value, e)
 		}
 	}
 
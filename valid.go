@@ -327,10 +327,22 @@ func (e *DTDValidationError) ErrorLevel() ErrorLevel { return e.Level }
 
 // validCtx carries validation state through the document walk.
 type validCtx struct {
-	handler ErrorHandler
-	failed  bool
-	ids     map[string]bool // ID values seen (uniqueness check)
-	idrefs  map[string]bool // IDREF values to resolve (cross-ref check)
+	handler   ErrorHandler
+	intSubset *DTD // internal subset validated against, or nil
+	extSubset *DTD // external subset validated against, or nil
+	failed    bool
+	ids       map[string]bool // ID values seen (uniqueness check)
+	idrefs    map[string]bool // IDREF values to resolve (cross-ref check)
+}
+
+func newValidCtx(handler ErrorHandler, intSubset, extSubset *DTD) *validCtx {
+	return &validCtx{
+		handler:   handler,
+		intSubset: intSubset,
+		extSubset: extSubset,
+		ids:       make(map[string]bool),
+		idrefs:    make(map[string]bool),
+	}
 }
 
 func (vc *validCtx) addf(ctx context.Context, format string, args ...any) {
@@ -341,7 +353,7 @@ func (vc *validCtx) addf(ctx context.Context, format string, args ...any) {
 	})
 }
 
-// docDTDs returns the DTDs to search for declarations, internal subset first.
+// subsets returns the DTDs to search for declarations, internal subset first.
 // Both subsets are always searched, independent of standalone: a validating
 // processor reads the external subset and uses its element/attribute
 // declarations to validate document structure regardless of the standalone
@@ -350,25 +362,29 @@ func (vc *validCtx) addf(ctx context.Context, format string, args ...any) {
 // must not DEPEND on external declarations for attribute defaults or value
 // normalization — is enforced separately by checkStandaloneExternalDefaults and
 // checkStandaloneExternalNormalization, not by hiding the external declarations.
-func docDTDs(doc *Document) []*DTD {
+//
+// The subsets are the document's own when it is validated after parsing, or the
+// DTD a [DTDValidator] was created with, which then serves as the external
+// subset (libxml2: xmlValidateDtd).
+func (vc *validCtx) subsets() []*DTD {
 	var dtds []*DTD
-	if doc.intSubset != nil {
-		dtds = append(dtds, doc.intSubset)
+	if vc.intSubset != nil {
+		dtds = append(dtds, vc.intSubset)
 	}
-	if doc.extSubset != nil {
-		dtds = append(dtds, doc.extSubset)
+	if vc.extSubset != nil {
+		dtds = append(dtds, vc.extSubset)
 	}
 	return dtds
 }
 
-// lookupDTDEntity searches both intSubset and extSubset for a general entity
+// lookupEntity searches both intSubset and extSubset for a general entity
 // declaration, regardless of the document's standalone status. DTD validity
 // (VC: Entity Name) requires the referenced entity to be declared in either
 // subset — an unparsed entity in the external subset must be found even for a
 // standalone="yes" document — so this deliberately does NOT gate on standalone
 // the way Document.GetEntity does.
-func lookupDTDEntity(doc *Document, name string) (*Entity, bool) {
-	for _, dtd := range docDTDs(doc) {
+func (vc *validCtx) lookupEntity(name string) (*Entity, bool) {
+	for _, dtd := range vc.subsets() {
 		if ent, ok := dtd.LookupEntity(name); ok {
 			return ent, true
 		}
@@ -376,13 +392,24 @@ func lookupDTDEntity(doc *Document, name string) (*Entity, bool) {
 	return nil, false
 }
 
+// lookupAttributeDecl searches both intSubset and extSubset for the declaration
+// of attribute prefix:name on elem, internal subset first.
+func (vc *validCtx) lookupAttributeDecl(name, prefix, elem string) *AttributeDecl {
+	for _, dtd := range vc.subsets() {
+		if decl, ok := dtd.LookupAttribute(name, prefix, elem); ok {
+			return decl
+		}
+	}
+	return nil
+}
+
 // lookupElementDecl searches both intSubset and extSubset for an element
 // declaration. DTD validation compares raw qualified names, not namespaces, so a
 // prefixed element requires an <!ELEMENT> declaration for the SAME QName — there is
 // no fallback from `p:r` to an unprefixed `r` declaration (for an unprefixed
 // element, prefix is "" and this is the only lookup).
-func lookupElementDecl(doc *Document, name, prefix string) (*ElementDecl, *DTD) {
-	for _, dtd := range docDTDs(doc) {
+func (vc *validCtx) lookupElementDecl(name, prefix string) (*ElementDecl, *DTD) {
+	for _, dtd := range vc.subsets() {
 		if edecl, ok := dtd.LookupElement(name, prefix); ok {
 			return edecl, dtd
 		}
@@ -393,17 +420,16 @@ func lookupElementDecl(doc *Document, name, prefix string) (*ElementDecl, *DTD)
 // validateDocument validates a parsed document against its DTD.
 // This is the equivalent of libxml2's xmlValidateDocument.
 func validateDocument(ctx context.Context, doc *Document, handler ErrorHandler) error {
-	vctx := &validCtx{
-		handler: handler,
-		ids:     make(map[string]bool),
-		idrefs:  make(map[string]bool),
-	}
+	return validateDocumentWith(ctx, doc, newValidCtx(handler, doc.intSubset, doc.extSubset))
+}
 
+// validateDocumentWith validates doc against the subsets vctx carries.
+func validateDocumentWith(ctx context.Context, doc *Document, vctx *validCtx) error {
 	// VC: Element Declared (XML §3.2) / libxml2 XML_DTD_NO_DTD "no DTD found!".
 	// A validating processor must report a validity error for a document that
 	// has neither an internal nor an external subset — nothing declares its
-	// elements. This path is reached only under ValidateDTD(true).
-	if doc.intSubset == nil && doc.extSubset == nil {
+	// elements.
+	if vctx.intSubset == nil && vctx.extSubset == nil {
 		vctx.addf(ctx, "no DTD found")
 		return ErrNoDTDFound
 	}
@@ -411,11 +437,11 @@ func validateDocument(ctx context.Context, doc *Document, handler ErrorHandler)
 	// Check that the root element name matches the DTD name
 	if root := doc.DocumentElement(); root != nil {
 		var dtdName string
-		if doc.intSubset != nil {
-			dtdName = doc.intSubset.name
+		if vctx.intSubset != nil {
+			dtdName = vctx.intSubset.name
 		}
-		if dtdName == "" && doc.extSubset != nil {
-			dtdName = doc.extSubset.name
+		if dtdName == "" && vctx.extSubset != nil {
+			dtdName = vctx.extSubset.name
 		}
 		// The DOCTYPE name is the root element's qualified name (e.g. `p:r`), so
 		// compare against the element's QName, not just its local part.
@@ -426,7 +452,7 @@ func validateDocument(ctx context.Context, doc *Document, handler ErrorHandler)
 
 	// Validate the DTD declarations themselves (declaration-consistency VCs)
 	// before walking the instance tree.
-	validateDTDDeclarations(ctx, doc, vctx)
+	validateDTDDeclarations(ctx, vctx)
 
 	// VC: Standalone Document Declaration (XML §2.9) — attribute values normalized
 	// by an external-subset tokenized-type declaration (recorded during parsing).
@@ -471,11 +497,11 @@ func validateOneElement(ctx context.Context, doc *Document, elem *Element, vctx
 	// directly, so it runs independent of the declaration lookup below — the
 	// element is normally FOUND (both subsets are searched regardless of
 	// standalone), and the violation must still be reported.
-	if doc.standalone == StandaloneExplicitYes && doc.extSubset != nil {
-		checkStandaloneWhitespace(ctx, doc.extSubset, elem, name, vctx)
+	if doc.standalone == StandaloneExplicitYes && vctx.extSubset != nil {
+		checkStandaloneWhitespace(ctx, vctx.extSubset, elem, name, vctx)
 	}
 
-	edecl, dtd := lookupElementDecl(doc, name, elem.Prefix())
+	edecl, dtd := vctx.lookupElementDecl(name, elem.Prefix())
 	if edecl == nil {
 		vctx.addf(ctx, "element %s: no declaration found", name)
 		return
@@ -509,7 +535,7 @@ func validateElementAttributes(ctx context.Context, doc *Document, elem *Element
 
 	// Check all declared attributes from both subsets, dedup by QName
 	seen := make(map[string]bool)
-	for _, dtd := range docDTDs(doc) {
+	for _, dtd := range vctx.subsets() {
 		for _, adecl := range dtd.AttributesForElement(ename) {
 			akey := adecl.prefix + ":" + adecl.name
 			aname := adecl.name
@@ -562,7 +588,7 @@ func validateElementAttributes(ctx context.Context, doc *Document, elem *Element
 						vctx.idrefs[ref] = true
 					}
 				case enum.AttrEntity:
-					ent, ok := lookupDTDEntity(doc, val)
+					ent, ok := vctx.lookupEntity(val)
 					if !ok {
 						vctx.addf(ctx, "element %s: attribute %s references undeclared entity %q", ename, aname, val)
 					} else if ent.EntityType() != enum.ExternalGeneralUnparsedEntity {
@@ -570,7 +596,7 @@ func validateElementAttributes(ctx context.Context, doc *Document, elem *Element
 					}
 				case enum.AttrEntities:
 					for entName := range strings.FieldsSeq(val) {
-						ent, ok := lookupDTDEntity(doc, entName)
+						ent, ok := vctx.lookupEntity(entName)
 						if !ok {
 							vctx.addf(ctx, "element %s: attribute %s references undeclared entity %q", ename, aname, entName)
 						} else if ent.EntityType() != enum.ExternalGeneralUnparsedEntity {
@@ -579,7 +605,7 @@ func validateElementAttributes(ctx context.Context, doc *Document, elem *Element
 					}
 				case enum.AttrNotation:
 					notFound := true
-					for _, dtd := range docDTDs(doc) {
+					for _, dtd := range vctx.subsets() {
 						if _, ok := dtd.LookupNotation(val); ok {
 							notFound = false
 							break
@@ -619,7 +645,7 @@ func validateElementAttributes(ctx context.Context, doc *Document, elem *Element
 		}
 	}
 
-	validateElementNamespaceDecls(ctx, doc, elem, ename, vctx)
+	validateElementNamespaceDecls(ctx, elem, ename, vctx)
 }
 
 // validateElementNamespaceDecls enforces the Fixed Attribute Default VC on the
@@ -638,14 +664,14 @@ func validateElementAttributes(ctx context.Context, doc *Document, elem *Element
 // against a namespace-agnostic DTD. (This is why W3C hst-bh-005/hst-bh-006 —
 // which assert a namespace-UNAWARE processor rejects an undeclared xmlns:* — are
 // out of scope for helium's namespace-aware validator.)
-func validateElementNamespaceDecls(ctx context.Context, doc *Document, elem *Element, ename string, vctx *validCtx) {
+func validateElementNamespaceDecls(ctx context.Context, elem *Element, ename string, vctx *validCtx) {
 	for _, ns := range elem.Namespaces() {
 		declName, declPrefix, label := lexicon.PrefixXMLNS, "", lexicon.PrefixXMLNS
 		if p := ns.Prefix(); p != "" {
 			declName, declPrefix, label = p, lexicon.PrefixXMLNS, lexicon.PrefixXMLNS+":"+p
 		}
 
-		adecl := lookupAttributeDecl(doc, declName, declPrefix, ename)
+		adecl := vctx.lookupAttributeDecl(declName, declPrefix, ename)
 		if adecl == nil {
 			continue
 		}
@@ -709,12 +735,12 @@ func checkStandaloneExternalDefaults(ctx context.Context, doc *Document, elem *E
 	// recorded per-declaration (AttributeDecl.external), because an external-PE-
 	// supplied ATTLIST is registered in the internal subset's table yet is still
 	// external markup. An internal-subset declaration takes precedence (§3.3), and
-	// dtdSubsets orders internal first, so the first-seen declaration per attribute
+	// subsets orders internal first, so the first-seen declaration per attribute
 	// wins. ATTLIST declarations are keyed by the element's declared QName, so match
 	// by the instance element's QName (a declaration for `p:r` does not apply to `<r>`).
 	ename := elem.Name()
 	seen := make(map[string]struct{})
-	for _, dtd := range dtdSubsets(doc) {
+	for _, dtd := range vctx.subsets() {
 		for _, adecl := range dtd.AttributesForElement(ename) {
 			key := adecl.name + ":" + adecl.prefix
 			if _, dup := seen[key]; dup {
@@ -761,7 +787,9 @@ func attrExplicitlySpecified(elem *Element, name, prefix string) bool {
 // value); this flushes those records as validity errors. Mirrors libxml2's
 // XML_DTD_NOT_STANDALONE normalization report.
 func checkStandaloneExternalNormalization(ctx context.Context, doc *Document, vctx *validCtx) {
-	if doc.standalone != StandaloneExplicitYes {
+	// The records were made against the external subset the document was
+	// parsed with; they say nothing about a DTD supplied after the fact.
+	if doc.standalone != StandaloneExplicitYes || vctx.extSubset != doc.extSubset {
 		return
 	}
 	for _, v := range doc.standaloneNormAttrs {
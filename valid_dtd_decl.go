@@ -7,32 +7,12 @@ import (
 	"github.com/lestrrat-go/helium/enum"
 )
 
-// dtdSubsets returns the internal and external subsets to validate, in order.
-// Unlike docDTDs it does NOT honor standalone="yes": the declaration-consistency
-// VCs apply to every declaration the DTD contains regardless of the standalone
-// declaration (libxml2's xmlValidateDtdFinal scans both subsets unconditionally).
-func dtdSubsets(doc *Document) []*DTD {
-	var dtds []*DTD
-	if doc.intSubset != nil {
-		dtds = append(dtds, doc.intSubset)
-	}
-	if doc.extSubset != nil {
-		dtds = append(dtds, doc.extSubset)
-	}
-	return dtds
-}
-
 // notationDeclared reports whether a notation named name is declared in either
 // subset. Notation lookup is standalone-independent (libxml2:
 // xmlValidateNotationUse scans intSubset then extSubset).
-func notationDeclared(doc *Document, name string) bool {
-	if doc.intSubset != nil {
-		if _, ok := doc.intSubset.LookupNotation(name); ok {
-			return true
-		}
-	}
-	if doc.extSubset != nil {
-		if _, ok := doc.extSubset.LookupNotation(name); ok {
+func (vc *validCtx) notationDeclared(name string) bool {
+	for _, dtd := range vc.subsets() {
+		if _, ok := dtd.LookupNotation(name); ok {
 			return true
 		}
 	}
@@ -42,8 +22,7 @@ func notationDeclared(doc *Document, name string) bool {
 // validateDTDDeclarations validates the DTD declarations themselves — as opposed
 // to the instance tree — against the XML 1.0 validity constraints libxml2 checks
 // in xmlValidateElementDecl / xmlValidateAttributeDecl / xmlValidateDtdFinal. It
-// runs only under ValidateDTD(true), after the no-DTD guard in validateDocument,
-// and reports:
+// runs after the no-DTD guard in validateDocumentWith, and reports:
 //
 //   - No Duplicate Types (§3.2.2): a Mixed content model may not name the same
 //     element type twice.
@@ -55,8 +34,8 @@ func notationDeclared(doc *Document, name string) bool {
 //     ID attribute.
 //   - Notation Declared (§4.7): a notation named in a NOTATION attribute's
 //     enumeration, or in an unparsed entity's NDATA clause, must be declared.
-func validateDTDDeclarations(ctx context.Context, doc *Document, vctx *validCtx) {
-	subsets := dtdSubsets(doc)
+func validateDTDDeclarations(ctx context.Context, vctx *validCtx) {
+	subsets := vctx.subsets()
 
 	for _, dtd := range subsets {
 		for _, edecl := range dtd.elements {
@@ -64,11 +43,11 @@ func validateDTDDeclarations(ctx context.Context, doc *Document, vctx *validCtx)
 		}
 		for _, adecl := range dtd.attributes {
 			validateAttributeDeclLegal(ctx, adecl, vctx)
-			validateNotationEnumDeclared(ctx, doc, adecl, vctx)
-			validateNotationNotOnEmptyElement(ctx, doc, adecl, vctx)
+			validateNotationEnumDeclared(ctx, adecl, vctx)
+			validateNotationNotOnEmptyElement(ctx, adecl, vctx)
 		}
 		for name, ent := range dtd.entities {
-			validateUnparsedEntityNotation(ctx, doc, name, ent, vctx)
+			validateUnparsedEntityNotation(ctx, name, ent, vctx)
 		}
 	}
 
@@ -168,12 +147,12 @@ func attrHasDefaultValue(def enum.AttributeDefault) bool {
 // validateNotationEnumDeclared implements the Notation Declared VC (§4.7) for a
 // NOTATION attribute: every notation name listed in the attribute's enumeration
 // must be declared.
-func validateNotationEnumDeclared(ctx context.Context, doc *Document, adecl *AttributeDecl, vctx *validCtx) {
+func validateNotationEnumDeclared(ctx context.Context, adecl *AttributeDecl, vctx *validCtx) {
 	if adecl.atype != enum.AttrNotation {
 		return
 	}
 	for _, nname := range adecl.tree {
-		if !notationDeclared(doc, nname) {
+		if !vctx.notationDeclared(nname) {
 			vctx.addf(ctx, "element %s: attribute %s enumerates undeclared notation %q", adecl.elem, adecl.name, nname)
 		}
 	}
@@ -182,8 +161,8 @@ func validateNotationEnumDeclared(ctx context.Context, doc *Document, adecl *Att
 // elementDeclForAttr looks up the declaration of the element that owns adecl,
 // searching both subsets. It returns nil when the element is undeclared or only
 // forward-referenced (UndefinedElementType).
-func elementDeclForAttr(doc *Document, elemName string) *ElementDecl {
-	for _, dtd := range dtdSubsets(doc) {
+func (vc *validCtx) elementDeclForAttr(elemName string) *ElementDecl {
+	for _, dtd := range vc.subsets() {
 		if e, ok := dtd.GetElementDesc(elemName); ok && e.decltype != enum.UndefinedElementType {
 			return e
 		}
@@ -194,11 +173,11 @@ func elementDeclForAttr(doc *Document, elemName string) *ElementDecl {
 // validateNotationNotOnEmptyElement implements the No Notation on Empty Element
 // VC (§3.3.1): an attribute of type NOTATION must not be declared on an element
 // whose content type is EMPTY.
-func validateNotationNotOnEmptyElement(ctx context.Context, doc *Document, adecl *AttributeDecl, vctx *validCtx) {
+func validateNotationNotOnEmptyElement(ctx context.Context, adecl *AttributeDecl, vctx *validCtx) {
 	if adecl.atype != enum.AttrNotation {
 		return
 	}
-	edecl := elementDeclForAttr(doc, adecl.elem)
+	edecl := vctx.elementDeclForAttr(adecl.elem)
 	if edecl != nil && edecl.decltype == enum.EmptyElementType {
 		vctx.addf(ctx, "element %s: NOTATION attribute %s is not allowed on an EMPTY element", adecl.elem, adecl.name)
 	}
@@ -207,12 +186,12 @@ func validateNotationNotOnEmptyElement(ctx context.Context, doc *Document, adecl
 // validateUnparsedEntityNotation implements the Notation Declared VC (§4.7) for
 // an unparsed entity: the notation named in its NDATA clause must be declared.
 // For an unparsed entity the notation name is stored as the entity content.
-func validateUnparsedEntityNotation(ctx context.Context, doc *Document, name string, ent *Entity, vctx *validCtx) {
+func validateUnparsedEntityNotation(ctx context.Context, name string, ent *Entity, vctx *validCtx) {
 	if ent.EntityType() != enum.ExternalGeneralUnparsedEntity {
 		return
 	}
 	notation := string(ent.Content())
-	if notation != "" && !notationDeclared(doc, notation) {
+	if notation != "" && !vctx.notationDeclared(notation) {
 		vctx.addf(ctx, "entity %s: NDATA notation %q is not declared", name, notation)
 	}
 }
@@ -325,7 +325,7 @@ func TestStandalone(t *testing.T) {
 
 	// DTD validation uses element
 	// declarations from the external subset even for a standalone="yes" document
-	// (docDTDs searches both subsets regardless of standalone), while the §2.9
+	// (validCtx.subsets searches both subsets regardless of standalone), while the §2.9
 	// element-content-whitespace Standalone VC still rejects whitespace — text or
 	// CDATA — directly within an externally-declared element-content element.
 	t.Run("external element content", func(t *testing.T) {
@@ -121,6 +121,12 @@ type Writer struct {
 	// ancestor namespaces are supplied externally. Nil/empty leaves output
 	// byte-identical.
 	initialNSScope map[string]string
+	// preserveLexical reuses the source markup recorded by
+	// Parser.PreserveLexical for every node left unedited since parsing.
+	preserveLexical bool
+	// layout, when set, lays the document out as a Formatter does in place
+	// of the regular serialization.
+	layout *formatterConfig
 }
 
 // standaloneMode controls how the writer emits the standalone pseudo-attribute
@@ -187,6 +193,17 @@ type writeSession struct {
 	// serialized subtree still gets a declaration. It is nil until the first
 	// namespaced element, so a plain-XML dump allocates nothing.
 	nsScope map[string]string
+	// lexical is the source markup of the document being written when
+	// preserveLexical is on and the document carries it; nil otherwise.
+	lexical *lexicalInfo
+	// lexicalBypass is a node writeNode serializes through the regular path
+	// despite its record; see writeLexicalNode.
+	lexicalBypass Node
+	// lexicalRoot is the node being written; a run recorded on it would
+	// take in siblings outside the output.
+	lexicalRoot Node
+	// lexicalSkip holds the siblings a run already wrote, in order.
+	lexicalSkip []Node
 }
 
 // nsSaved records a prefix's prior binding in nsScope so it can be restored
@@ -846,6 +863,18 @@ func writerOmitDocumentChildTerminators(w any) any {
 	return ww.withoutDocumentChildTerminators()
 }
 
+// PreserveLexical controls whether nodes parsed with [Parser.PreserveLexical]
+// are written with their original markup. A node that was not edited since
+// parsing is reproduced byte for byte, including the XML declaration and the
+// whitespace between top-level nodes; edited and newly created nodes are
+// serialized as usual. Format and the other content options do not apply to
+// reproduced markup. Documents parsed without recording are unaffected.
+// Default: false
+func (w Writer) PreserveLexical(v bool) Writer {
+	w.preserveLexical = v
+	return w
+}
+
 // InheritedNamespaces seeds the serializer's namespace scope with bindings
 // (prefix -> URI; the empty prefix is the default namespace) treated as already
 // in force on an ancestor outside the serialized output. A node using such a
@@ -1022,6 +1051,12 @@ func (d Writer) writeTo(out io.Writer, node Node) error {
 	// override (an out-of-range character stays escaped by escapeNonASCII exactly
 	// as before).
 	s.seedNSScope()
+	if d.preserveLexical {
+		if doc := node.OwnerDocument(); doc != nil {
+			s.lexical = doc.lexical
+			s.lexicalRoot = node
+		}
+	}
 	return s.writeNode(out, node)
 }
 
@@ -1123,6 +1158,16 @@ func (d Writer) writeDoc(out io.Writer, doc *Document) error {
 		s.isXHTML = isXHTMLDTD(dtd)
 	}
 
+	if d.layout != nil {
+		s.lexical = doc.lexical
+		return s.writeLayoutDoc(out, doc)
+	}
+
+	if d.preserveLexical && doc.lexical != nil {
+		s.lexical = doc.lexical
+		return s.writeLexicalDoc(out, doc)
+	}
+
 	if err := s.writeNode(out, doc); err != nil {
 		return err
 	}
@@ -1191,6 +1236,17 @@ func (d *writeSession) dumpDocContent(out io.Writer, n Node) error {
 
 // writeNode is the internal implementation for node serialization.
 func (d *writeSession) writeNode(out io.Writer, n Node) error {
+	if len(d.lexicalSkip) > 0 && d.lexicalSkip[0] == n {
+		d.lexicalSkip = d.lexicalSkip[1:]
+		return nil
+	}
+	if d.lexical != nil && n != d.lexicalBypass {
+		// The layout writer places elements itself, so it cannot honor a
+		// run that spans several nodes.
+		if ln, ok := d.lexical.lookup(n); ok && (!ln.whole || d.layout == nil && n != d.lexicalRoot) {
+			return d.writeLexicalNode(out, n, ln)
+		}
+	}
 	var err error
 	switch n.Type() {
 	case DocumentNode, HTMLDocumentNode:
@@ -1483,35 +1539,10 @@ func (d *writeSession) writeNode(out io.Writer, n Node) error {
 				break
 			}
 			seenAttrs[akey] = struct{}{}
-			// The attribute name is emitted verbatim. checkAttributeName
-			// rejects names that would inject raw markup into the start tag.
-			if !d.checkAttributeName(attr.Name()) {
-				return d.err
-			}
-			// A prefixed attribute name whose prefix is bound to an empty
-			// namespace URI (constructible via SetAttributeNS with a
-			// CreateNamespace(prefix, "") binding) is likewise unreparseable.
-			if !d.checkNamespaceBinding("attribute name", attr.Name(), attr.Prefix(), attr.URI()) {
-				return d.err
-			}
-			d.writeString(out, " "+attr.Name()+`="`)
-			if d.err != nil {
-				return d.err
-			}
-			count := 0
-			for achld := range Children(attr) {
-				count++
-				if achld.Type() == TextNode {
-					if err := d.writeAttrValueContent(out, rawContent(achld)); err != nil {
-						return err
-					}
-				} else {
-					if err := d.writeNode(out, achld); err != nil {
-						return err
-					}
-				}
+			d.writeString(out, " ")
+			if err := d.writeAttribute(out, attr); err != nil {
+				return err
 			}
-			d.writeString(out, `"`)
 			a := attr.NextSibling()
 			if a == nil {
 				break
@@ -1591,6 +1622,38 @@ func (d *writeSession) writeNode(out io.Writer, n Node) error {
 	return d.err
 }
 
+// writeAttribute writes attr as name="value", without a leading separator.
+func (d *writeSession) writeAttribute(out io.Writer, attr *Attribute) error {
+	// The attribute name is emitted verbatim. checkAttributeName rejects names
+	// that would inject raw markup into the start tag.
+	if !d.checkAttributeName(attr.Name()) {
+		return d.err
+	}
+	// A prefixed attribute name whose prefix is bound to an empty namespace URI
+	// (constructible via SetAttributeNS with a CreateNamespace(prefix, "")
+	// binding) is likewise unreparseable.
+	if !d.checkNamespaceBinding("attribute name", attr.Name(), attr.Prefix(), attr.URI()) {
+		return d.err
+	}
+	d.writeString(out, attr.Name()+`="`)
+	if d.err != nil {
+		return d.err
+	}
+	for achld := range Children(attr) {
+		if achld.Type() == TextNode {
+			if err := d.writeAttrValueContent(out, rawContent(achld)); err != nil {
+				return err
+			}
+		} else {
+			if err := d.writeNode(out, achld); err != nil {
+				return err
+			}
+		}
+	}
+	d.writeString(out, `"`)
+	return d.err
+}
+
 // reconcileNamespaces runs after an element's own xmlns declarations (nslist)
 // are emitted. It records those declarations in the output namespace scope,
 // then for the element's active namespace and every namespaced attribute emits
@@ -0,0 +1,129 @@
+package helium
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/lestrrat-go/helium/internal/lexicon"
+)
+
+// writeLexicalDoc serializes a document that carries source markup (see
+// Parser.PreserveLexical). The XML declaration and the whitespace between
+// top-level nodes come from the source in place of the writer's own
+// declaration and newline terminators.
+func (d *writeSession) writeLexicalDoc(out io.Writer, doc *Document) error {
+	li := d.lexical
+	d.writeBytes(out, li.bom)
+	if !d.noDecl {
+		if li.declSig == lexicalSig(doc) {
+			d.writeBytes(out, li.decl)
+		} else if err := d.dumpDocContent(out, doc); err != nil {
+			return err
+		}
+	}
+
+	first := true
+	for child := range Children(doc) {
+		if d.skipDTD && child.Type() == DTDNode {
+			continue
+		}
+		// Whitespace before a top-level node belongs to the node's position, so
+		// it is kept even when the node itself was edited. A node without a
+		// record goes on a line of its own.
+		if ln, ok := li.nodes[child]; ok {
+			d.writeBytes(out, ln.lead)
+		} else if !first {
+			d.writeString(out, "\n")
+		}
+		first = false
+		if err := d.writeNode(out, child); err != nil {
+			return err
+		}
+	}
+	d.writeBytes(out, li.tail)
+	return d.err
+}
+
+// writeLexicalNode writes n, which is unchanged since parsing, from its source
+// markup. An element's content is written child by child, so an edited
+// descendant is serialized from the DOM while its unedited siblings keep their
+// markup. A run is written whole, and its other nodes skipped.
+func (d *writeSession) writeLexicalNode(out io.Writer, n Node, ln *lexicalNode) error {
+	e, ok := n.(*Element)
+	if !ok || ln.whole {
+		d.writeBytes(out, ln.raw)
+		if ln.whole {
+			d.lexicalSkip = append(d.lexicalSkip[:0], ln.rest...)
+		}
+		return d.err
+	}
+	if !d.lexicalNamespacesInScope(e) {
+		// The start tag relies on a declaration outside the output (a fragment
+		// written on its own); let the regular path write the tags and
+		// synthesize the declaration. Descendants still use their markup.
+		d.lexicalBypass = e
+		return d.writeNode(out, e)
+	}
+
+	var saved []nsSaved
+	for _, ns := range e.Namespaces() {
+		if ns.prefix == lexicon.PrefixXML || ns.prefix == lexicon.PrefixXMLNS {
+			continue
+		}
+		saved = d.nsScopePush(ns.prefix, ns.href, saved)
+	}
+	if saved != nil {
+		defer d.nsScopeRestore(saved)
+	}
+
+	start := ln.raw
+	if ln.end == nil {
+		if e.FirstChild() == nil {
+			d.writeBytes(out, start)
+			return d.err
+		}
+		// Content was added to an element written as an empty-element tag.
+		start = bytes.TrimRight(start[:len(start)-2], " \t\r\n")
+		d.writeBytes(out, start)
+		d.writeString(out, ">")
+	} else {
+		d.writeBytes(out, start)
+	}
+	for child := range Children(e) {
+		if err := d.writeNode(out, child); err != nil {
+			return err
+		}
+	}
+	if ln.end == nil {
+		d.writeString(out, "</"+e.Name()+">")
+	} else {
+		d.writeBytes(out, ln.end)
+	}
+	return d.err
+}
+
+// lexicalNamespacesInScope reports whether every prefix e's start tag uses is
+// either declared on e itself or bound to the same URI in the output.
+func (d *writeSession) lexicalNamespacesInScope(e *Element) bool {
+	declared := func(prefix, href string) bool {
+		if prefix == lexicon.PrefixXML || href == "" {
+			return true
+		}
+		for _, ns := range e.Namespaces() {
+			if ns.prefix == prefix {
+				return true
+			}
+		}
+		cur, ok := d.nsScope[prefix]
+		return ok && cur == href
+	}
+	if ns := e.Namespace(); ns != nil && !declared(ns.prefix, ns.href) {
+		return false
+	}
+	for attr := e.properties; attr != nil; attr = attr.NextAttribute() {
+		if ns := attr.ns; ns != nil && ns.prefix != "" && !declared(ns.prefix, ns.href) {
+			return false
+		}
+	}
+	return true
+}
@@ -79,6 +79,8 @@ type processorCfg struct {
 	noMarkers       bool
 	noBaseFixup     bool
 	resolver        Resolver
+	resources       helium.ResourceResolver
+	cache           *helium.ResourceCache
 	baseURI         string
 	errorHandler    helium.ErrorHandler
 	maxIncludeSize  int
@@ -132,6 +134,29 @@ func (p Processor) Resolver(r Resolver) Processor {
 	return p
 }
 
+// ResourceResolver sets a [helium.ResourceResolver] that loads included
+// resources in place of the [Resolver], under the ctx given to
+// [Processor.Process]. It takes precedence over [Processor.Resolver]. External
+// DTDs and entities of included documents load through it too, and a resource
+// it reports to have come from another URI is parsed with that URI as its
+// base.
+func (p Processor) ResourceResolver(r helium.ResourceResolver) Processor {
+	p = p.clone()
+	p.cfg.resources = r
+	return p
+}
+
+// ResourceCache sets a [helium.ResourceCache] that memoizes included
+// resources, keyed by resolved URI, across Process calls, and the external DTDs
+// and entities of included documents with them. A resource the cache holds
+// under the same resolver is served without consulting it again. The
+// per-include size caps apply to cached resources as to loaded ones.
+func (p Processor) ResourceCache(c *helium.ResourceCache) Processor {
+	p = p.clone()
+	p.cfg.cache = c
+	return p
+}
+
 // NewFSResolver returns a [Resolver] that opens hrefs through the given
 // [fs.FS]. The processor resolves each xi:include href against the
 // include's effective base URI before calling the resolver, so the href
@@ -236,6 +261,7 @@ func (p Processor) ErrorHandler(h helium.ErrorHandler) Processor {
 
 type docCacheEntry struct {
 	data []byte // raw bytes for re-parsing
+	uri  string // URI the bytes were loaded from
 	err  error
 }
 
@@ -248,6 +274,7 @@ type processor struct {
 	noMarkers       bool
 	noBaseFixup     bool
 	resolver        Resolver
+	cache           *helium.ResourceCache
 	baseURI         string
 	expanding       map[string]bool          // circular inclusion detection (set during recursive expansion)
 	docCache        map[string]docCacheEntry // cached raw bytes for XML documents
@@ -291,6 +318,7 @@ func (proc Processor) ProcessTree(ctx context.Context, node helium.Node) (int, e
 		noMarkers:       cfg.noMarkers,
 		noBaseFixup:     cfg.noBaseFixup,
 		resolver:        cfg.resolver,
+		cache:           cfg.cache,
 		baseURI:         cfg.baseURI,
 		errorHandler:    cfg.errorHandler,
 		maxIncludeSize:  cfg.maxIncludeSize,
@@ -308,6 +336,9 @@ func (proc Processor) ProcessTree(ctx context.Context, node helium.Node) (int, e
 		// or, preferably, a confined fs.FS (os.Root.FS).
 		p.resolver = NewFSResolver(iofs.DenyAll{})
 	}
+	if cfg.resources != nil {
+		p.resolver = resourceResolver{ctx: ctx, r: cfg.resources}
+	}
 
 	// Capture a resolver-free snapshot of the entry document so top-level
 	// same-document XPointer references can be evaluated against the original
@@ -474,7 +505,7 @@ func (p *processor) processInclude(ctx context.Context, inc *helium.Element) err
 		if resolved == "" {
 			err = fmt.Errorf("xi:include: text inclusion requires href")
 		} else {
-			err = p.includeText(inc, resolved, incBase)
+			err = p.includeText(ctx, inc, resolved, incBase)
 		}
 	default:
 		err = fmt.Errorf("xi:include: unsupported parse value %q", parse)
@@ -748,18 +779,25 @@ func (p *processor) mergeEntities(ctx context.Context, src, dst *helium.Document
 // responsible for negative-caching the returned (already wrapped) error.
 // readCapped fully drains the reader before returning, so closing it on return
 // is safe.
-func (p *processor) fetch(uri, base string) ([]byte, error) {
-	rc, err := p.resolve(uri, base)
+//
+// It also returns the URI the bytes were loaded from, which differs from uri
+// only when a [helium.ResourceResolver] reports so.
+func (p *processor) fetch(ctx context.Context, uri, base string) ([]byte, string, error) {
+	rc, err := p.resolve(ctx, uri, base)
 	if err != nil {
-		return nil, fmt.Errorf("xi:include: failed to resolve %q: %w", uri, err)
+		return nil, "", fmt.Errorf("xi:include: failed to resolve %q: %w", uri, err)
 	}
 	defer func() { _ = rc.Close() }()
 
+	loadedFrom := uri
+	if res, ok := rc.(*helium.Resource); ok && res.URI != "" {
+		loadedFrom = res.URI
+	}
 	data, err := p.readCapped(rc)
 	if err != nil {
-		return nil, fmt.Errorf("xi:include: error reading %q: %w", uri, err)
+		return nil, "", fmt.Errorf("xi:include: error reading %q: %w", uri, err)
 	}
-	return data, nil
+	return data, loadedFrom, nil
 }
 
 func (p *processor) loadXMLDoc(ctx context.Context, uri string, base string, substituteEntities bool) (*helium.Document, error) {
@@ -779,10 +817,10 @@ func (p *processor) loadXMLDoc(ctx context.Context, uri string, base string, sub
 		}
 		// Re-parse from cached bytes: each inclusion needs independent
 		// nodes since they get moved into the target document tree.
-		return p.parseXMLData(ctx, entry.data, uri, substituteEntities)
+		return p.parseXMLData(ctx, entry.data, entry.uri, substituteEntities)
 	}
 
-	data, err := p.fetch(uri, base)
+	data, loadedFrom, err := p.fetch(ctx, uri, base)
 	if err != nil {
 		p.docCache[cacheKey] = docCacheEntry{err: err}
 		return nil, err
@@ -790,18 +828,18 @@ func (p *processor) loadXMLDoc(ctx context.Context, uri string, base string, sub
 	if err := p.accountIncludedBytes(uri, len(data)); err != nil {
 		// Cache the bytes anyway so a later same-URI include hits the same
 		// aggregate guard, with no re-fetch.
-		p.docCache[cacheKey] = docCacheEntry{data: data}
+		p.docCache[cacheKey] = docCacheEntry{data: data, uri: loadedFrom}
 		return nil, err
 	}
 
-	doc, err := p.parseXMLData(ctx, data, uri, substituteEntities)
+	doc, err := p.parseXMLData(ctx, data, loadedFrom, substituteEntities)
 	if err != nil {
 		p.docCache[cacheKey] = docCacheEntry{err: err}
 		return nil, err
 	}
 
 	// Cache raw bytes for subsequent includes of the same URI
-	p.docCache[cacheKey] = docCacheEntry{data: data}
+	p.docCache[cacheKey] = docCacheEntry{data: data, uri: loadedFrom}
 	return doc, nil
 }
 
@@ -812,8 +850,21 @@ func (p *processor) loadXMLDoc(ctx context.Context, uri string, base string, sub
 // context (per the Resolver contract): resolvers MUST open href directly
 // and MUST NOT resolve it against base again, which would double-apply the
 // base directory (e.g. open dir/dir/inc.xml instead of dir/inc.xml).
-func (p *processor) resolve(uri, base string) (io.ReadCloser, error) {
-	return p.resolver.Resolve(uri, base) //nolint:wrapcheck // callers wrap with the URI for context
+func (p *processor) resolve(ctx context.Context, uri, base string) (io.ReadCloser, error) {
+	if p.cache == nil {
+		return p.resolver.Resolve(uri, base) //nolint:wrapcheck // callers wrap with the URI for context
+	}
+	load := helium.ResourceResolverFunc(func(_ context.Context, uri string) (*helium.Resource, error) {
+		rc, err := p.resolver.Resolve(uri, base)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // callers wrap with the URI for context
+		}
+		if res, ok := rc.(*helium.Resource); ok {
+			return res, nil
+		}
+		return &helium.Resource{ReadCloser: rc, Size: -1}, nil
+	})
+	return p.cache.ResolverFor(p.resolver, load).ResolveResource(ctx, uri) //nolint:wrapcheck // callers wrap with the URI for context
 }
 
 // readCapped reads all bytes from r but no more than the configured include-size
@@ -970,7 +1021,11 @@ func (p *processor) parseXMLData(ctx context.Context, data []byte, uri string, s
 	// path.Clean'd names. Without normalization a sandbox that accepts
 	// XInclude hrefs would spuriously reject the document's own external
 	// entities/DTDs.
-	if fr, ok := p.resolver.(fsBacked); ok {
+	if rr, ok := p.resolver.(resourceResolver); ok {
+		// The caller's ResourceResolver is the sandbox; inner references go
+		// through it just as the includes do.
+		parser = parser.BlockXXE(false).ResourceResolver(rr.r)
+	} else if fr, ok := p.resolver.(fsBacked); ok {
 		// NewParser now blocks external entity/DTD loading by default; lift that
 		// block so the included document's own external references resolve, but
 		// keep them confined to the resolver's sandbox FS (set below).
@@ -980,6 +1035,9 @@ func (p *processor) parseXMLData(ctx context.Context, data []byte, uri string, s
 		// inner SYSTEM references cannot reach the host (defense in depth).
 		parser = parser.FS(denyAllFS{})
 	}
+	if p.cache != nil {
+		parser = parser.ResourceCache(p.cache)
+	}
 	if substituteEntities {
 		parser = parser.SubstituteEntities(true)
 	}
@@ -990,8 +1048,8 @@ func (p *processor) parseXMLData(ctx context.Context, data []byte, uri string, s
 	return doc, nil
 }
 
-func (p *processor) includeText(inc *helium.Element, uri string, incBase string) error {
-	data, err := p.loadText(uri, incBase)
+func (p *processor) includeText(ctx context.Context, inc *helium.Element, uri string, incBase string) error {
+	data, err := p.loadText(ctx, uri, incBase)
 	if err != nil {
 		return err
 	}
@@ -1029,7 +1087,7 @@ func (p *processor) includeText(inc *helium.Element, uri string, incBase string)
 	return nil
 }
 
-func (p *processor) loadText(uri string, base string) ([]byte, error) {
+func (p *processor) loadText(ctx context.Context, uri string, base string) ([]byte, error) {
 	if entry, ok := p.txtCache[uri]; ok {
 		if entry.err != nil {
 			return nil, entry.err
@@ -1042,7 +1100,7 @@ func (p *processor) loadText(uri string, base string) ([]byte, error) {
 		return entry.data, nil
 	}
 
-	data, err := p.fetch(uri, base)
+	data, _, err := p.fetch(ctx, uri, base)
 	if err != nil {
 		p.txtCache[uri] = txtCacheEntry{err: err}
 		return nil, err
@@ -1734,6 +1792,22 @@ func (n normalizingFS) Open(name string) (fs.File, error) {
 	return n.fsys.Open(path.Clean(filepath.ToSlash(name))) //nolint:wrapcheck // passthrough; underlying FS errors propagate verbatim
 }
 
+// resourceResolver adapts a [helium.ResourceResolver] to a [Resolver] for
+// one Process call, whose ctx it carries. The ReadCloser it returns is the
+// *helium.Resource itself, so fetch can see where it was loaded from.
+type resourceResolver struct {
+	ctx context.Context //nolint:containedctx // scoped to a single Process call
+	r   helium.ResourceResolver
+}
+
+func (a resourceResolver) Resolve(href, _ string) (io.ReadCloser, error) {
+	res, err := a.r.ResolveResource(a.ctx, href)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // callers wrap with the URI for context
+	}
+	return res, nil
+}
+
 // denyAllFS is an fs.FS that refuses every open. It is threaded into the inner
 // parser used for included documents when the XInclude resolver is a custom
 // (non-fsResolver) implementation, so external entities/DTDs in the included
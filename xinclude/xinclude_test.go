@@ -1,6 +1,7 @@
 package xinclude_test
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
@@ -2238,3 +2239,64 @@ func TestXIncludeParserInjection(t *testing.T) {
 		require.Error(t, err, "injected parser's name-length limit must apply to included documents")
 	})
 }
+
+func TestXIncludeResourceResolver(t *testing.T) {
+	t.Parallel()
+
+	mem := helium.MemoryResourceResolver(map[string][]byte{
+		"part.xml": []byte(`<!DOCTYPE part [<!ENTITY e SYSTEM "e.txt">]><part>&e;</part>`),
+		"e.txt":    []byte(`entity`),
+		"note.txt": []byte(`text`),
+	})
+	type ctxKey struct{}
+	var uris []string
+	r := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+		require.Equal(t, "v", ctx.Value(ctxKey{}))
+		uris = append(uris, uri)
+		return mem.ResolveResource(ctx, uri)
+	})
+
+	doc := parseXML(t, `<root xmlns:xi="http://www.w3.org/2001/XInclude">`+
+		`<xi:include href="part.xml"/><xi:include href="note.txt" parse="text"/></root>`)
+	count, err := xinclude.NewProcessor().
+		Resolver(xinclude.NewFSResolver(fstest.MapFS{})).
+		ResourceResolver(r).
+		Parser(helium.NewParser().SubstituteEntities(true)).
+		NoXIncludeMarkers().NoBaseFixup().
+		Process(context.WithValue(t.Context(), ctxKey{}, "v"), doc)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	out, err := helium.WriteString(docElement(doc))
+	require.NoError(t, err)
+	require.Equal(t, `<root xmlns:xi="http://www.w3.org/2001/XInclude"><part>entity</part>text</root>`, out)
+	require.Equal(t, []string{"part.xml", "e.txt", "note.txt"}, uris)
+}
+
+func TestXIncludeResourceCache(t *testing.T) {
+	t.Parallel()
+
+	resolver := &countingXIncludeResolver{stringResolver: stringResolver{files: map[string]string{"part.xml": `<part/>`}}}
+	proc := xinclude.NewProcessor().
+		Resolver(resolver).
+		ResourceCache(helium.NewResourceCache(0, 0)).
+		NoXIncludeMarkers().NoBaseFixup()
+
+	for range 3 {
+		doc := parseXML(t, `<root xmlns:xi="http://www.w3.org/2001/XInclude"><xi:include href="part.xml"/></root>`)
+		count, err := proc.Process(t.Context(), doc)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+	}
+	require.Equal(t, 1, resolver.loads)
+}
+
+type countingXIncludeResolver struct {
+	stringResolver
+	loads int
+}
+
+func (r *countingXIncludeResolver) Resolve(href, base string) (io.ReadCloser, error) {
+	r.loads++
+	return r.stringResolver.Resolve(href, base)
+}
@@ -0,0 +1,628 @@
+package xmldiff
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	helium "github.com/lestrrat-go/helium"
+)
+
+// maxAlignCells bounds the table used to align two lists of children. Lists
+// whose table would be larger are aligned greedily instead.
+const maxAlignCells = 1 << 22
+
+// DefaultThreshold is the similarity, between 0 and 1, that two elements of
+// the same name must reach to be treated as versions of each other when
+// neither has an ID.
+const DefaultThreshold = 0.5
+
+type differConfig struct {
+	idAttrs   []string
+	threshold float64
+}
+
+// Differ computes the differences between two documents.
+// It uses clone-on-write semantics: each builder method returns a new
+// Differ sharing the underlying config until mutation.
+type Differ struct {
+	cfg *differConfig
+}
+
+// NewDiffer creates a Differ that treats attributes named "id" as IDs and
+// pairs elements at [DefaultThreshold].
+func NewDiffer() Differ {
+	return Differ{cfg: &differConfig{idAttrs: []string{"id"}, threshold: DefaultThreshold}}
+}
+
+func (d Differ) clone() Differ {
+	if d.cfg == nil {
+		return NewDiffer()
+	}
+	cp := *d.cfg
+	cp.idAttrs = slices.Clone(cp.idAttrs)
+	return Differ{cfg: &cp}
+}
+
+// IDAttributes sets the names of the unqualified attributes whose values
+// identify elements, replacing the default "id". Attributes declared as ID
+// in the DTD and xml:id are always used.
+func (d Differ) IDAttributes(names ...string) Differ {
+	d = d.clone()
+	d.cfg.idAttrs = slices.Clone(names)
+	return d
+}
+
+// Threshold sets the similarity, between 0 and 1, that two elements of the
+// same name without IDs must reach to be paired. Lower values describe more
+// changes as edits inside an element; higher values replace more elements
+// as a whole.
+func (d Differ) Threshold(v float64) Differ {
+	d = d.clone()
+	d.cfg.threshold = v
+	return d
+}
+
+// Diff returns an RFC 5261 patch document that turns a into b when applied
+// with [Patch]. Neither document is modified. The patch has an empty <diff>
+// element when the documents do not differ.
+func (d Differ) Diff(ctx context.Context, a, b *helium.Document) (*helium.Document, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if d.cfg == nil {
+		d = NewDiffer()
+	}
+	if a == nil || b == nil {
+		return nil, helium.ErrNilNode
+	}
+	work, err := helium.CopyDoc(a)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := helium.NewDefaultDocument()
+	root, err := patch.CreateElement("diff")
+	if err != nil {
+		return nil, err
+	}
+	if err := patch.AddChild(root); err != nil {
+		return nil, err
+	}
+
+	g := &differ{
+		ctx:     ctx,
+		match:   newMatcher(d.cfg),
+		work:    work,
+		patch:   patch,
+		root:    root,
+		bound:   make(map[string]string),
+		claimed: make(map[string]bool),
+	}
+	g.paths.bind = g.bind
+	if err := g.children(work, b); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// differ turns work, a copy of the old document, into the new one, and
+// records every edit as a patch operation. Selectors are computed on work
+// just before each edit, so they address nodes exactly as a patch applied
+// in order will find them.
+type differ struct {
+	ctx   context.Context
+	match *matcher
+	paths pathBuilder
+
+	work  *helium.Document
+	patch *helium.Document
+	root  *helium.Element // the <diff> element
+
+	bound   map[string]string // namespace URI to selector prefix
+	claimed map[string]bool   // selector prefixes in use
+}
+
+// bind returns the prefix selectors use for uri, declaring it on the <diff>
+// element the first time. hint, the prefix of the node being addressed, is
+// used when it is free.
+func (g *differ) bind(uri, hint string) string {
+	if isXMLNamespace(uri) {
+		return "xml"
+	}
+	if p, ok := g.bound[uri]; ok {
+		return p
+	}
+	p := hint
+	for i := 1; p == "" || p == "xml" || g.claimed[p]; i++ {
+		p = "ns" + strconv.Itoa(i)
+	}
+	// A fresh prefix on an element without a namespace cannot conflict.
+	_ = g.root.DeclareNamespace(p, uri)
+	g.bound[uri] = p
+	g.claimed[p] = true
+	return p
+}
+
+// op appends a new operation element to the patch.
+func (g *differ) op(name string, target helium.Node) (*helium.Element, error) {
+	e, err := g.patch.CreateElement(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.SetAttribute("sel", g.paths.path(target)); err != nil {
+		return nil, err
+	}
+	if err := g.root.AddChild(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// content copies nodes of the new document into op as its content, and
+// returns copies of them for work.
+func (g *differ) content(op *helium.Element, nodes []helium.Node) ([]helium.Node, error) {
+	copies := make([]helium.Node, 0, len(nodes))
+	for _, n := range nodes {
+		cp, err := helium.CopyNode(n, g.patch)
+		if err != nil {
+			return nil, err
+		}
+		if err := appendChildren(op, []helium.Node{cp}); err != nil {
+			return nil, err
+		}
+		cp, err = helium.CopyNode(n, g.work)
+		if err != nil {
+			return nil, err
+		}
+		copies = append(copies, cp)
+	}
+	return copies, nil
+}
+
+// textContent sets the content of op to the string s.
+func (g *differ) textContent(op *helium.Element, s string) error {
+	if s == "" {
+		return nil
+	}
+	return op.AddChild(g.patch.CreateText([]byte(s)))
+}
+
+// pair is one step of an alignment: a node of the old tree kept as the node
+// of the new one, a node removed (b is nil) or a node added (a is nil).
+type pair struct {
+	a, b helium.Node
+}
+
+// children edits the children of parent, a node of work, into those of
+// other.
+func (g *differ) children(parent, other helium.Node) error {
+	if err := g.ctx.Err(); err != nil {
+		return err
+	}
+	_, topLevel := parent.(*helium.Document)
+	as := slices.Collect(children(parent))
+	bs := slices.Collect(children(other))
+	steps := g.align(as, bs, topLevel)
+
+	var prev helium.Node // last child of parent already in its final place
+	for i := 0; i < len(steps); i++ {
+		s := steps[i]
+		switch {
+		case s.a != nil && s.b != nil:
+			n, err := g.update(s.a, s.b)
+			if err != nil {
+				return err
+			}
+			prev = n
+		case s.a != nil:
+			if _, err := g.op("remove", s.a); err != nil {
+				return err
+			}
+			helium.UnlinkNode(mutable(s.a))
+		default:
+			added := []helium.Node{s.b}
+			for i+1 < len(steps) && steps[i+1].a == nil {
+				i++
+				added = append(added, steps[i].b)
+			}
+			last, err := g.add(parent, prev, added)
+			if err != nil {
+				return err
+			}
+			prev = last
+		}
+	}
+	return nil
+}
+
+// add inserts nodes, taken from the new document, into parent after prev,
+// or at the start of parent when prev is nil. It returns the last node
+// inserted.
+func (g *differ) add(parent, prev helium.Node, nodes []helium.Node) (helium.Node, error) {
+	var anchor helium.Node
+	var pos string
+	switch {
+	case prev != nil:
+		anchor, pos = prev, "after"
+	case parent.FirstChild() == nil:
+		anchor = parent
+	default:
+		if _, ok := parent.(*helium.Document); ok {
+			// The document node cannot be selected for prepend; add before
+			// its first child instead.
+			for c := range children(parent) {
+				anchor, pos = c, "before"
+				break
+			}
+		} else {
+			anchor, pos = parent, "prepend"
+		}
+	}
+	op, err := g.op("add", anchor)
+	if err != nil {
+		return nil, err
+	}
+	if pos != "" {
+		if err := op.SetAttribute("pos", pos); err != nil {
+			return nil, err
+		}
+	}
+	copies, err := g.content(op, nodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := insert(pos, anchor, copies); err != nil {
+		return nil, err
+	}
+	return copies[len(copies)-1], nil
+}
+
+// update turns n, a node of work, into m, the node of the new document it
+// was paired with, and returns the node that now stands in its place.
+func (g *differ) update(n, m helium.Node) (helium.Node, error) {
+	if g.match.hash(n) == g.match.hash(m) {
+		return n, nil
+	}
+	if e, ok := n.(*helium.Element); ok {
+		if f, ok := m.(*helium.Element); ok && editable(e, f) {
+			return e, g.element(e, f)
+		}
+	}
+	return g.replace(n, m)
+}
+
+// replace replaces n, a node of work, with a copy of m.
+func (g *differ) replace(n, m helium.Node) (helium.Node, error) {
+	op, err := g.op("replace", n)
+	if err != nil {
+		return nil, err
+	}
+	copies, err := g.content(op, []helium.Node{m})
+	if err != nil {
+		return nil, err
+	}
+	if err := mutable(n).Replace(copies...); err != nil {
+		return nil, err
+	}
+	dropRedundantDeclarations(copies)
+	return copies[0], nil
+}
+
+// element edits e, an element of work, into f, an element of the new
+// document that e is editable into.
+func (g *differ) element(e, f *helium.Element) error {
+	// Only bindings that differ in scope are edited; a declaration that
+	// repeats one inherited from an ancestor is left alone.
+	rebound := false
+	for _, ns := range f.Namespaces() {
+		if ns.Prefix() == "" {
+			continue
+		}
+		if uri, ok := namespaceInScope(e, ns.Prefix()); ok && uri == ns.URI() {
+			continue
+		}
+		rebound = true
+		op, err := g.op("add", e)
+		if err != nil {
+			return err
+		}
+		if err := op.SetAttribute("type", "namespace::"+ns.Prefix()); err != nil {
+			return err
+		}
+		if err := g.textContent(op, ns.URI()); err != nil {
+			return err
+		}
+		if err := e.DeclareNamespace(ns.Prefix(), ns.URI()); err != nil {
+			return fmt.Errorf("xmldiff: declare namespace %q: %w", ns.Prefix(), err)
+		}
+	}
+
+	if err := g.attributes(e, f); err != nil {
+		return err
+	}
+	// Declarations f lacks go before the children are edited, so that the
+	// nodes added to them keep the declarations they need.
+	for _, ns := range slices.Clone(e.Namespaces()) {
+		if ns.Prefix() == "" {
+			continue
+		}
+		if _, ok := namespaceInScope(f, ns.Prefix()); ok {
+			continue
+		}
+		rebound = true
+		if err := g.removeNamespace(e, ns.Prefix()); err != nil {
+			return err
+		}
+	}
+	if rebound {
+		// The hashes of the children were taken with the old bindings.
+		g.match.forget(e)
+	}
+	return g.children(e, f)
+}
+
+func (g *differ) removeNamespace(e *helium.Element, prefix string) error {
+	op, err := g.patch.CreateElement("remove")
+	if err != nil {
+		return err
+	}
+	if err := op.SetAttribute("sel", g.paths.path(e)+"/namespace::"+prefix); err != nil {
+		return err
+	}
+	if err := g.root.AddChild(op); err != nil {
+		return err
+	}
+	e.RemoveNamespaceByPrefix(prefix)
+	return nil
+}
+
+// attributes edits the attributes of e, an element of work, into those of
+// f.
+func (g *differ) attributes(e, f *helium.Element) error {
+	for _, a := range e.Attributes() {
+		if findAttribute(f, a.URI(), a.LocalName()) != nil {
+			continue
+		}
+		if _, err := g.op("remove", a); err != nil {
+			return err
+		}
+		removeAttribute(e, a.URI(), a.LocalName())
+	}
+	for _, b := range f.Attributes() {
+		a := findAttribute(e, b.URI(), b.LocalName())
+		if a != nil {
+			if a.Value() == b.Value() {
+				continue
+			}
+			op, err := g.op("replace", a)
+			if err != nil {
+				return err
+			}
+			if err := g.textContent(op, b.Value()); err != nil {
+				return err
+			}
+		} else {
+			op, err := g.op("add", e)
+			if err != nil {
+				return err
+			}
+			if err := op.SetAttribute("type", "@"+g.paths.qname(b.URI(), b.Prefix(), b.LocalName())); err != nil {
+				return err
+			}
+			if err := g.textContent(op, b.Value()); err != nil {
+				return err
+			}
+		}
+		prefix := ""
+		if b.URI() != "" {
+			prefix = g.bind(b.URI(), b.Prefix())
+		}
+		if err := setAttribute(e, b.URI(), b.LocalName(), prefix, b.Value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// align pairs the children of two nodes. It keeps common leading and
+// trailing runs of identical subtrees and aligns what remains between them
+// for the highest total score, which the matcher assigns to each pairing.
+// Between two kept nodes, removals come before additions.
+func (g *differ) align(as, bs []helium.Node, topLevel bool) []pair {
+	var head, tail []pair
+	for len(as) > 0 && len(bs) > 0 && g.match.hash(as[0]) == g.match.hash(bs[0]) {
+		head = append(head, pair{as[0], bs[0]})
+		as, bs = as[1:], bs[1:]
+	}
+	for len(as) > 0 && len(bs) > 0 && g.match.hash(as[len(as)-1]) == g.match.hash(bs[len(bs)-1]) {
+		tail = append(tail, pair{as[len(as)-1], bs[len(bs)-1]})
+		as, bs = as[:len(as)-1], bs[:len(bs)-1]
+	}
+	slices.Reverse(tail)
+
+	var mid []pair
+	if (len(as)+1)*(len(bs)+1) <= maxAlignCells {
+		mid = g.alignOptimal(as, bs, topLevel)
+	} else {
+		mid = g.alignGreedy(as, bs, topLevel)
+	}
+
+	steps := head
+	var removed, added []pair
+	flush := func() {
+		steps = append(steps, removed...)
+		steps = append(steps, added...)
+		removed, added = removed[:0], added[:0]
+	}
+	for _, p := range mid {
+		switch {
+		case p.a == nil:
+			added = append(added, p)
+		case p.b == nil:
+			removed = append(removed, p)
+		default:
+			flush()
+			steps = append(steps, p)
+		}
+	}
+	flush()
+	return append(steps, tail...)
+}
+
+// alignOptimal aligns as and bs for the highest total score, like a longest
+// common subsequence weighted by the matcher's scores.
+func (g *differ) alignOptimal(as, bs []helium.Node, topLevel bool) []pair {
+	const (
+		stepRemove byte = iota
+		stepAdd
+		stepPair
+	)
+	n, m := len(as), len(bs)
+	w := m + 1
+	best := make([]float64, (n+1)*w)
+	how := make([]byte, (n+1)*w)
+	for i := 1; i <= n; i++ {
+		how[i*w] = stepRemove
+	}
+	for j := 1; j <= m; j++ {
+		how[j] = stepAdd
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			k := i*w + j
+			best[k], how[k] = best[k-w], stepRemove
+			if best[k-1] > best[k] {
+				best[k], how[k] = best[k-1], stepAdd
+			}
+			if s, ok := g.match.score(as[i-1], bs[j-1], topLevel); ok && best[k-w-1]+s > best[k] {
+				best[k], how[k] = best[k-w-1]+s, stepPair
+			}
+		}
+	}
+
+	steps := make([]pair, 0, max(n, m))
+	for i, j := n, m; i > 0 || j > 0; {
+		switch how[i*w+j] {
+		case stepPair:
+			steps = append(steps, pair{as[i-1], bs[j-1]})
+			i, j = i-1, j-1
+		case stepRemove:
+			steps = append(steps, pair{a: as[i-1]})
+			i--
+		default:
+			steps = append(steps, pair{b: bs[j-1]})
+			j--
+		}
+	}
+	slices.Reverse(steps)
+	return steps
+}
+
+// alignGreedy aligns lists too long for alignOptimal by walking both in
+// step, pairing nodes where the matcher allows it.
+func (g *differ) alignGreedy(as, bs []helium.Node, topLevel bool) []pair {
+	steps := make([]pair, 0, max(len(as), len(bs)))
+	i, j := 0, 0
+	for i < len(as) && j < len(bs) {
+		if _, ok := g.match.score(as[i], bs[j], topLevel); ok {
+			steps = append(steps, pair{as[i], bs[j]})
+		} else {
+			steps = append(steps, pair{a: as[i]}, pair{b: bs[j]})
+		}
+		i++
+		j++
+	}
+	for ; i < len(as); i++ {
+		steps = append(steps, pair{a: as[i]})
+	}
+	for ; j < len(bs); j++ {
+		steps = append(steps, pair{b: bs[j]})
+	}
+	return steps
+}
+
+// declares reports whether e itself declares prefix, bound to uri unless
+// uri is empty.
+func declares(e *helium.Element, prefix, uri string) bool {
+	for _, ns := range e.Namespaces() {
+		if ns.Prefix() == prefix && (uri == "" || ns.URI() == uri) {
+			return true
+		}
+	}
+	return false
+}
+
+func findAttribute(e *helium.Element, uri, local string) *helium.Attribute {
+	var found *helium.Attribute
+	e.ForEachAttribute(func(a *helium.Attribute) bool {
+		if a.URI() == uri && a.LocalName() == local {
+			found = a
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// editable reports whether e can be edited into f rather than replaced:
+// they have the same name, no child of either is an entity reference, the
+// same default namespace is in scope on both, as no selector can address
+// it, and no prefix e declares is bound to another namespace in scope on f
+// or needs a declaration on e where e uses it. Bindings are compared as in
+// scope, so a declaration one element repeats from its ancestors makes no
+// difference.
+func editable(e, f *helium.Element) bool {
+	if !sameName(e, f) || hasEntityRef(e) || hasEntityRef(f) {
+		return false
+	}
+	de, _ := namespaceInScope(e, "")
+	df, _ := namespaceInScope(f, "")
+	if de != df {
+		return false
+	}
+	for _, ns := range e.Namespaces() {
+		if ns.Prefix() == "" {
+			continue
+		}
+		if uri, ok := namespaceInScope(f, ns.Prefix()); ok && uri != ns.URI() {
+			return false
+		}
+	}
+	for _, ns := range f.Namespaces() {
+		if ns.Prefix() == "" {
+			continue
+		}
+		if uri, ok := namespaceInScope(e, ns.Prefix()); ok && uri == ns.URI() {
+			continue
+		}
+		if usesPrefix(e, ns.Prefix()) {
+			return false
+		}
+	}
+	return true
+}
+
+// usesPrefix reports whether the name of e or of one of its attributes has
+// the given prefix.
+func usesPrefix(e *helium.Element, prefix string) bool {
+	if e.Prefix() == prefix {
+		return true
+	}
+	used := false
+	e.ForEachAttribute(func(a *helium.Attribute) bool {
+		used = a.Prefix() == prefix
+		return !used
+	})
+	return used
+}
+
+func hasEntityRef(e *helium.Element) bool {
+	for c := range helium.Children(e) {
+		if c.Type() == helium.EntityRefNode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+// Package xmldiff computes tree-aware differences between XML documents and
+// applies them as patches.
+//
+// # Diffing
+//
+// Use [NewDiffer] to compare two documents:
+//
+//	patch, err := xmldiff.NewDiffer().
+//	    IDAttributes("id", "key").
+//	    Diff(ctx, before, after)
+//
+// The documents are compared as trees, not as lines of text. Children are
+// aligned by finding the sequence of matches that scores best: identical
+// subtrees first, then elements sharing an ID (an attribute declared as ID in
+// the DTD, xml:id, or one named with [Differ.IDAttributes]), then elements of
+// the same name whose content is similar enough (see [Differ.Threshold]).
+// Matched elements are compared recursively; everything else is removed or
+// added. The result is an XML patch document as defined by RFC 5261: a
+// <diff> element holding <add>, <replace> and <remove> operations whose sel
+// attributes are XPath selectors. Namespaces used by the selectors are
+// declared on the <diff> element.
+//
+// # Patching
+//
+// [Patch] applies such a document to a copy of a document, operation by
+// operation, as RFC 5261 prescribes. Each selector must locate exactly one
+// node; otherwise [ErrUnlocatedNode] is returned. Malformed operations fail
+// with [ErrInvalidPatch], and operations whose target or content do not fit
+// together with [ErrInvalidNodeTypes].
+//
+// Entity references are compared by name and are not addressable by a
+// selector, so an element whose children include one is replaced as a
+// whole when it changes. Document type declarations are not compared.
+//
+// # Examples
+//
+// Example code for this package lives in the examples/ directory at the
+// repository root (files prefixed with xmldiff_). Because examples are in a
+// separate test module they do not appear in the generated documentation.
+package xmldiff
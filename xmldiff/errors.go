@@ -0,0 +1,17 @@
+package xmldiff
+
+import "errors"
+
+// ErrInvalidPatch is returned by [Patch] when the patch document is not a
+// well-formed RFC 5261 patch: an unknown operation, a missing sel attribute,
+// or an unsupported pos, type or ws value.
+var ErrInvalidPatch = errors.New("xmldiff: invalid patch")
+
+// ErrUnlocatedNode is returned by [Patch] when a selector does not locate
+// exactly one node.
+var ErrUnlocatedNode = errors.New("xmldiff: selector does not locate a single node")
+
+// ErrInvalidNodeTypes is returned by [Patch] when an operation does not apply
+// to the kind of node its selector located, or when its content does not fit
+// the target, such as an element replaced by a comment.
+var ErrInvalidNodeTypes = errors.New("xmldiff: invalid node types")
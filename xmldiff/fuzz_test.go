@@ -0,0 +1,34 @@
+package xmldiff_test
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/c14n"
+	"github.com/lestrrat-go/helium/xmldiff"
+)
+
+func FuzzDiffRoundTrip(f *testing.F) {
+	f.Add(`<r><a id="1">t</a><b/></r>`, `<r><b/><a id="1">u</a></r>`, uint8(0))
+	f.Add(`<r xmlns:p="urn:p"><p:a p:x="1"/><!--c--></r>`, `<r xmlns:p="urn:q"><p:a p:x="2"/><?pi x?></r>`, uint8(1))
+	f.Add(`<r><q:e xmlns:q="urn:q" xmlns="urn:d">t<c/></q:e></r>`, `<r><q:e xmlns:q="urn:q">t<c xmlns:z="urn:z"/></q:e></r>`, uint8(2))
+	f.Add(`<a>x<![CDATA[y]]>z</a>`, `<b>x</b>`, uint8(0))
+
+	f.Fuzz(func(t *testing.T, a, b string, threshold uint8) {
+		if len(a) > 1<<16 || len(b) > 1<<16 {
+			return
+		}
+		for _, s := range []string{a, b} {
+			doc, err := helium.NewParser().Parse(t.Context(), []byte(s))
+			if err != nil || doc.IntSubset() != nil {
+				return
+			}
+			// The round trip is checked on canonical forms, which not every
+			// document has.
+			if _, err := c14n.NewCanonicalizer(c14n.C14N10).CanonicalizeTo(doc); err != nil {
+				return
+			}
+		}
+		diff(t, xmldiff.NewDiffer().Threshold(float64(threshold%5)/4), a, b)
+	})
+}
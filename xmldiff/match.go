@@ -0,0 +1,283 @@
+package xmldiff
+
+import (
+	"cmp"
+	"encoding/binary"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/enum"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+)
+
+// maxFeatures caps the number of features collected for an element when
+// estimating similarity, so that comparing two large subtrees stays cheap.
+// The features are gathered breadth first, which favors the parts of the
+// subtree closest to the element.
+const maxFeatures = 512
+
+// Alignment scores. Keeping an identical subtree beats pairing by ID, which
+// beats pairing by similarity; see matcher.score.
+const (
+	scoreIdentical = 4
+	scoreID        = 3
+	scoreSimilar   = 1
+)
+
+// matcher decides which nodes of two documents correspond. It caches the
+// subtree hashes and feature bags it computes; both are only valid until the
+// subtree changes, which the differ guarantees by aligning the children of
+// an element before editing any of them.
+type matcher struct {
+	idAttrs   []string
+	threshold float64
+
+	hashes map[helium.Node]uint64
+	bags   map[helium.Node]featureBag
+}
+
+func newMatcher(cfg *differConfig) *matcher {
+	return &matcher{
+		idAttrs:   cfg.idAttrs,
+		threshold: cfg.threshold,
+		hashes:    make(map[helium.Node]uint64),
+		bags:      make(map[helium.Node]featureBag),
+	}
+}
+
+// score rates n and m as a pair. It reports false when they cannot be paired
+// at all. topLevel is set for children of the document node, where the
+// document elements are always paired: a document element of the same name
+// is edited, and one of another name replaced, rather than removed and
+// added.
+func (mt *matcher) score(n, m helium.Node, topLevel bool) (float64, bool) {
+	if mt.hash(n) == mt.hash(m) {
+		return scoreIdentical, true
+	}
+	switch n := n.(type) {
+	case *helium.Element:
+		m, ok := m.(*helium.Element)
+		if !ok {
+			return 0, false
+		}
+		if !sameName(n, m) {
+			return scoreSimilar, topLevel
+		}
+		idN, idM := mt.id(n), mt.id(m)
+		if idN != "" && idM != "" {
+			if idN == idM {
+				return scoreID, true
+			}
+			return 0, false
+		}
+		s := mt.similarity(n, m)
+		if s < mt.threshold && !topLevel {
+			return 0, false
+		}
+		return scoreSimilar + s, true
+	case *helium.Text, *helium.CDATASection:
+		return scoreSimilar, isText(m)
+	case *helium.Comment:
+		_, ok := m.(*helium.Comment)
+		return scoreSimilar, ok
+	case *helium.ProcessingInstruction:
+		m, ok := m.(*helium.ProcessingInstruction)
+		return scoreSimilar, ok && n.Name() == m.Name()
+	}
+	return 0, false
+}
+
+// id returns the ID of e, or "" when it has none.
+func (mt *matcher) id(e *helium.Element) string {
+	var id string
+	e.ForEachAttribute(func(a *helium.Attribute) bool {
+		switch {
+		case a.AType() == enum.AttrID,
+			a.URI() == lexicon.NamespaceXML && a.LocalName() == "id",
+			a.URI() == "" && slices.Contains(mt.idAttrs, a.LocalName()):
+			id = a.Value()
+			return false
+		}
+		return true
+	})
+	return id
+}
+
+// hash returns a hash of the subtree at n that is equal for subtrees that
+// serialize the same up to attribute order, the prefixes of names and where
+// namespaces are declared: the namespaces in scope on each element are
+// hashed, not its own declarations, which copying a document may repeat.
+func (mt *matcher) hash(n helium.Node) uint64 {
+	if h, ok := mt.hashes[n]; ok {
+		return h
+	}
+	h := fnv.New64a()
+	var buf [8]byte
+	write := func(s string) {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+	_, _ = h.Write([]byte{byte(n.Type())})
+	switch n := n.(type) {
+	case *helium.Element:
+		write(n.URI())
+		write(n.LocalName())
+		attrs := n.Attributes()
+		slices.SortFunc(attrs, compareAttributes)
+		for _, a := range attrs {
+			write(a.URI())
+			write(a.LocalName())
+			write(a.Value())
+		}
+		for _, ns := range namespacesInScope(n) {
+			write("xmlns:" + ns.Prefix())
+			write(ns.URI())
+		}
+	case *helium.ProcessingInstruction:
+		write(n.Name())
+		write(string(n.Content()))
+	case *helium.Text, *helium.CDATASection, *helium.Comment:
+		write(string(n.Content()))
+	case *helium.EntityRef:
+		write(n.Name())
+	}
+	for c := range children(n) {
+		binary.LittleEndian.PutUint64(buf[:], mt.hash(c))
+		_, _ = h.Write(buf[:])
+	}
+	sum := h.Sum64()
+	mt.hashes[n] = sum
+	return sum
+}
+
+// forget drops the hashes of the subtree at n, whose namespaces in scope
+// changed.
+func (mt *matcher) forget(n helium.Node) {
+	delete(mt.hashes, n)
+	for c := range children(n) {
+		mt.forget(c)
+	}
+}
+
+func compareAttributes(x, y *helium.Attribute) int {
+	return cmp.Or(cmp.Compare(x.URI(), y.URI()), cmp.Compare(x.LocalName(), y.LocalName()))
+}
+
+// featureBag counts the features of a subtree: the names of its elements,
+// their attributes and the words of its text.
+type featureBag struct {
+	counts map[string]int
+	total  int
+}
+
+func (mt *matcher) bag(e *helium.Element) featureBag {
+	if b, ok := mt.bags[e]; ok {
+		return b
+	}
+	b := featureBag{counts: make(map[string]int)}
+	add := func(f string) bool {
+		if b.total >= maxFeatures {
+			return false
+		}
+		b.counts[f]++
+		b.total++
+		return true
+	}
+	queue := []helium.Node{e}
+	for len(queue) > 0 && b.total < maxFeatures {
+		n := queue[0]
+		queue = queue[1:]
+		switch n := n.(type) {
+		case *helium.Element:
+			// e counts its own name too, so that two elements of the same
+			// name always have something in common, however much their
+			// content differs.
+			add("<" + n.URI() + "|" + n.LocalName())
+			n.ForEachAttribute(func(a *helium.Attribute) bool {
+				return add("@" + a.URI() + "|" + a.LocalName() + "=" + a.Value())
+			})
+			queue = append(queue, slices.Collect(children(n))...)
+		case *helium.Text, *helium.CDATASection:
+			for _, w := range strings.Fields(string(n.Content())) {
+				if !add("#" + w) {
+					break
+				}
+			}
+		case *helium.Comment:
+			add("!" + string(n.Content()))
+		case *helium.ProcessingInstruction:
+			add("?" + n.Name())
+		}
+	}
+	mt.bags[e] = b
+	return b
+}
+
+// similarity returns the Dice coefficient of the feature bags of n and m,
+// which is 1 for elements with the same features.
+func (mt *matcher) similarity(n, m *helium.Element) float64 {
+	bn, bm := mt.bag(n), mt.bag(m)
+	if len(bm.counts) < len(bn.counts) {
+		bn, bm = bm, bn
+	}
+	common := 0
+	for f, c := range bn.counts {
+		common += min(c, bm.counts[f])
+	}
+	return 2 * float64(common) / float64(bn.total+bm.total)
+}
+
+// namespacesInScope returns the namespaces in scope on e, sorted by prefix.
+// A default namespace undeclared with xmlns="" is not in scope.
+func namespacesInScope(e *helium.Element) []*helium.Namespace {
+	var nss []*helium.Namespace
+	seen := make(map[string]struct{})
+	for n := helium.Node(e); n != nil; n = n.Parent() {
+		pe, ok := helium.AsNode[*helium.Element](n)
+		if !ok {
+			continue
+		}
+		for _, ns := range pe.Namespaces() {
+			if _, ok := seen[ns.Prefix()]; ok {
+				continue
+			}
+			seen[ns.Prefix()] = struct{}{}
+			if ns.URI() != "" {
+				nss = append(nss, ns)
+			}
+		}
+	}
+	slices.SortFunc(nss, func(x, y *helium.Namespace) int { return cmp.Compare(x.Prefix(), y.Prefix()) })
+	return nss
+}
+
+// namespaceInScope returns the namespace prefix is bound to on e, and false
+// when it is not bound.
+func namespaceInScope(e *helium.Element, prefix string) (string, bool) {
+	for n := helium.Node(e); n != nil; n = n.Parent() {
+		pe, ok := helium.AsNode[*helium.Element](n)
+		if !ok {
+			continue
+		}
+		for _, ns := range pe.Namespaces() {
+			if ns.Prefix() == prefix {
+				return ns.URI(), ns.URI() != ""
+			}
+		}
+	}
+	return "", false
+}
+
+func sameName(n, m *helium.Element) bool {
+	return n.LocalName() == m.LocalName() && n.URI() == m.URI()
+}
+
+func isText(n helium.Node) bool {
+	switch n.(type) {
+	case *helium.Text, *helium.CDATASection:
+		return true
+	}
+	return false
+}
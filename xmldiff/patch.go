@@ -0,0 +1,295 @@
+package xmldiff
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xpath1"
+)
+
+// Patch applies patch, an RFC 5261 patch document such as one produced by
+// [Differ.Diff], to a copy of doc and returns the copy. doc is not modified.
+//
+// The children of the patch's document element are applied in order, each
+// to the result of the ones before it:
+//
+//   - <add sel="..."> inserts its content as the last children of the
+//     selected element; pos="prepend" makes them the first children, and
+//     pos="before" or pos="after" makes them siblings of the selected node.
+//     With type="@name" it adds an attribute whose value is its text, and
+//     with type="namespace::prefix" a namespace declaration.
+//   - <replace sel="..."> replaces the selected element, comment or
+//     processing instruction with its single content node of the same kind,
+//     a text node with its text, and the value of an attribute or namespace
+//     declaration with its text.
+//   - <remove sel="..."> removes the selected node, attribute or namespace
+//     declaration. ws="before", "after" or "both" also removes the adjacent
+//     whitespace-only text nodes.
+//
+// Selectors are XPath 1.0 location paths that must each locate exactly one
+// node, with prefixes resolved against the namespaces in scope in the patch.
+// Whitespace-only text around the single content node of a replace is
+// ignored. An operation that fails stops the patch with an error wrapping
+// [ErrInvalidPatch], [ErrUnlocatedNode] or [ErrInvalidNodeTypes].
+func Patch(ctx context.Context, doc, patch *helium.Document) (*helium.Document, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if doc == nil || patch == nil {
+		return nil, helium.ErrNilNode
+	}
+	root := patch.DocumentElement()
+	if root == nil {
+		return nil, fmt.Errorf("%w: no document element", ErrInvalidPatch)
+	}
+	out, err := helium.CopyDoc(doc)
+	if err != nil {
+		return nil, err
+	}
+	for op := range helium.ChildElements(root) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := apply(ctx, out, op); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func apply(ctx context.Context, doc *helium.Document, op *helium.Element) error {
+	sel, ok := op.GetAttribute("sel")
+	if !ok {
+		return fmt.Errorf("%w: <%s> without sel", ErrInvalidPatch, op.LocalName())
+	}
+	target, err := locate(ctx, doc, op, sel)
+	if err != nil {
+		return err
+	}
+	switch op.LocalName() {
+	case "add":
+		return applyAdd(doc, op, target)
+	case "replace":
+		return applyReplace(doc, op, target)
+	case "remove":
+		return applyRemove(op, target)
+	default:
+		return fmt.Errorf("%w: unknown operation <%s>", ErrInvalidPatch, op.Name())
+	}
+}
+
+// locate evaluates sel against doc, resolving prefixes with the namespaces
+// in scope at op.
+func locate(ctx context.Context, doc *helium.Document, op *helium.Element, sel string) (helium.Node, error) {
+	expr, err := xpath1.NewCompiler().Compile(sel)
+	if err != nil {
+		return nil, fmt.Errorf("%w: sel %q: %w", ErrInvalidPatch, sel, err)
+	}
+	nodes, err := xpath1.NewEvaluator().Namespaces(inScope(op)).Find(ctx, expr, doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: sel %q: %w", ErrInvalidPatch, sel, err)
+	}
+	if len(nodes) != 1 {
+		return nil, fmt.Errorf("%w: sel %q matched %d nodes", ErrUnlocatedNode, sel, len(nodes))
+	}
+	return nodes[0], nil
+}
+
+// inScope returns the prefixed namespace bindings in scope at e.
+func inScope(e *helium.Element) map[string]string {
+	bindings := make(map[string]string)
+	for n := helium.Node(e); n != nil; n = n.Parent() {
+		el, ok := n.(*helium.Element)
+		if !ok {
+			continue
+		}
+		for _, ns := range el.Namespaces() {
+			if _, shadowed := bindings[ns.Prefix()]; !shadowed && ns.Prefix() != "" {
+				bindings[ns.Prefix()] = ns.URI()
+			}
+		}
+	}
+	return bindings
+}
+
+func applyAdd(doc *helium.Document, opElem *helium.Element, target helium.Node) error {
+	if typ, ok := opElem.GetAttribute("type"); ok {
+		e, ok := target.(*helium.Element)
+		if !ok {
+			return fmt.Errorf("%w: add type=%q needs an element, found %s", ErrInvalidNodeTypes, typ, target.Type())
+		}
+		value := string(opElem.Content())
+		switch {
+		case strings.HasPrefix(typ, "@"):
+			uri, prefix, local, err := resolveQName(opElem, typ[1:])
+			if err != nil {
+				return err
+			}
+			if findAttribute(e, uri, local) != nil {
+				return fmt.Errorf("%w: attribute %s already exists", ErrInvalidPatch, typ[1:])
+			}
+			return setAttribute(e, uri, local, prefix, value)
+		case strings.HasPrefix(typ, "namespace::"):
+			prefix := strings.TrimPrefix(typ, "namespace::")
+			if declares(e, prefix, "") {
+				return fmt.Errorf("%w: namespace %s already declared", ErrInvalidPatch, prefix)
+			}
+			return e.DeclareNamespace(prefix, value)
+		default:
+			return fmt.Errorf("%w: add type=%q", ErrInvalidPatch, typ)
+		}
+	}
+
+	nodes, err := copyContent(doc, opElem)
+	if err != nil {
+		return err
+	}
+	pos, _ := opElem.GetAttribute("pos")
+	switch pos {
+	case "", "prepend":
+		switch target.(type) {
+		case *helium.Element, *helium.Document:
+		default:
+			return fmt.Errorf("%w: add needs an element, found %s", ErrInvalidNodeTypes, target.Type())
+		}
+	case "before", "after":
+		switch target.(type) {
+		case *helium.Attribute, *helium.NamespaceNodeWrapper, *helium.Document:
+			return fmt.Errorf("%w: add pos=%q cannot target a %s", ErrInvalidNodeTypes, pos, target.Type())
+		}
+	default:
+		return fmt.Errorf("%w: add pos=%q", ErrInvalidPatch, pos)
+	}
+	return insert(pos, target, nodes)
+}
+
+func applyReplace(doc *helium.Document, op *helium.Element, target helium.Node) error {
+	switch target := target.(type) {
+	case *helium.Attribute:
+		e := target.Parent().(*helium.Element) //nolint:forcetypeassert // attributes belong to elements
+		return setAttribute(e, target.URI(), target.LocalName(), target.Prefix(), string(op.Content()))
+	case *helium.NamespaceNodeWrapper:
+		e, prefix, err := declaration(target)
+		if err != nil {
+			return err
+		}
+		e.RemoveNamespaceByPrefix(prefix)
+		return e.DeclareNamespace(prefix, string(op.Content()))
+	case *helium.Text, *helium.CDATASection:
+		nodes, err := copyContent(doc, op)
+		if err != nil {
+			return err
+		}
+		if len(nodes) == 0 {
+			return fmt.Errorf("%w: text replaced with nothing", ErrInvalidNodeTypes)
+		}
+		for _, n := range nodes {
+			if !isText(n) {
+				return fmt.Errorf("%w: text replaced with a %s", ErrInvalidNodeTypes, n.Type())
+			}
+		}
+		return mutable(target).Replace(nodes...)
+	case *helium.Element, *helium.Comment, *helium.ProcessingInstruction:
+		var content []helium.Node
+		for c := range helium.Children(op) {
+			if !isBlank(c) {
+				content = append(content, c)
+			}
+		}
+		if len(content) != 1 || content[0].Type() != target.Type() {
+			return fmt.Errorf("%w: a %s must be replaced with exactly one node of the same kind", ErrInvalidNodeTypes, target.Type())
+		}
+		cp, err := helium.CopyNode(content[0], doc)
+		if err != nil {
+			return err
+		}
+		if err := mutable(target).Replace(cp); err != nil {
+			return err
+		}
+		dropRedundantDeclarations([]helium.Node{cp})
+		return nil
+	default:
+		return fmt.Errorf("%w: cannot replace a %s", ErrInvalidNodeTypes, target.Type())
+	}
+}
+
+func applyRemove(op *helium.Element, target helium.Node) error {
+	switch target := target.(type) {
+	case *helium.Attribute:
+		e := target.Parent().(*helium.Element) //nolint:forcetypeassert // attributes belong to elements
+		removeAttribute(e, target.URI(), target.LocalName())
+		return nil
+	case *helium.NamespaceNodeWrapper:
+		e, prefix, err := declaration(target)
+		if err != nil {
+			return err
+		}
+		e.RemoveNamespaceByPrefix(prefix)
+		return nil
+	case *helium.Document:
+		return fmt.Errorf("%w: cannot remove the document node", ErrInvalidNodeTypes)
+	}
+
+	var blanks []helium.Node
+	ws, _ := op.GetAttribute("ws")
+	switch ws {
+	case "":
+	case "before", "after", "both":
+		if ws != "after" {
+			blanks = append(blanks, target.PrevSibling())
+		}
+		if ws != "before" {
+			blanks = append(blanks, target.NextSibling())
+		}
+		for _, b := range blanks {
+			if b == nil || !isBlank(b) {
+				return fmt.Errorf("%w: ws=%q but no whitespace text node there", ErrInvalidPatch, ws)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: remove ws=%q", ErrInvalidPatch, ws)
+	}
+	for _, n := range append(blanks, target) {
+		helium.UnlinkNode(mutable(n))
+	}
+	return nil
+}
+
+// declaration returns the element declaring the namespace node n selects
+// and its prefix. Only declarations made on the element itself qualify.
+func declaration(n *helium.NamespaceNodeWrapper) (*helium.Element, string, error) {
+	e, ok := n.Parent().(*helium.Element)
+	if !ok || !declares(e, n.Name(), "") {
+		return nil, "", fmt.Errorf("%w: namespace %q is not declared on the selected element", ErrUnlocatedNode, n.Name())
+	}
+	return e, n.Name(), nil
+}
+
+// copyContent copies the children of op into doc.
+func copyContent(doc *helium.Document, op *helium.Element) ([]helium.Node, error) {
+	var nodes []helium.Node
+	for c := range helium.Children(op) {
+		cp, err := helium.CopyNode(c, doc)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, cp)
+	}
+	return nodes, nil
+}
+
+// resolveQName splits qname and resolves its prefix against the namespaces
+// in scope at e.
+func resolveQName(e *helium.Element, qname string) (uri, prefix, local string, err error) {
+	prefix, local, ok := strings.Cut(qname, ":")
+	if !ok {
+		return "", "", qname, nil
+	}
+	ns := helium.LookupNSByPrefix(e, prefix)
+	if ns == nil {
+		return "", "", "", fmt.Errorf("%w: undeclared prefix %q", ErrInvalidPatch, prefix)
+	}
+	return ns.URI(), prefix, local, nil
+}
@@ -0,0 +1,219 @@
+package xmldiff
+
+import (
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+)
+
+// children yields the children of n that take part in a diff: everything
+// except document type declarations, which no selector can address.
+func children(n helium.Node) iter.Seq[helium.Node] {
+	return func(yield func(helium.Node) bool) {
+		for c := range helium.Children(n) {
+			if c.Type() == helium.DTDNode {
+				continue
+			}
+			if !yield(c) {
+				return
+			}
+		}
+	}
+}
+
+// The insertion helpers below splice nodes in with Replace, which, unlike
+// AddChild and AddSibling, never merges adjacent text nodes. Diff and Patch
+// both edit through them, so the node positions a diff computes its
+// selectors from are the ones the patch later finds.
+
+// insert links nodes in relative to anchor as an add operation with the
+// given pos does, then drops the declarations they no longer need there.
+func insert(pos string, anchor helium.Node, nodes []helium.Node) error {
+	var err error
+	switch pos {
+	case "before":
+		err = insertBefore(anchor, nodes)
+	case "after":
+		err = insertAfter(anchor, nodes)
+	case "prepend":
+		err = prependChildren(anchor, nodes)
+	default:
+		err = appendChildren(anchor, nodes)
+	}
+	if err != nil {
+		return err
+	}
+	dropRedundantDeclarations(nodes)
+	return nil
+}
+
+// dropRedundantDeclarations removes the namespace declarations of the
+// elements among nodes that repeat a binding already in scope where they now
+// stand. Copies carry every declaration they need to stand alone; once
+// linked in, most of them are moot.
+func dropRedundantDeclarations(nodes []helium.Node) {
+	for _, n := range nodes {
+		e, ok := n.(*helium.Element)
+		if !ok {
+			continue
+		}
+		parent, ok := e.Parent().(*helium.Element)
+		if !ok {
+			continue
+		}
+		for _, ns := range slices.Clone(e.Namespaces()) {
+			if in := helium.LookupNSByPrefix(parent, ns.Prefix()); in != nil && in.URI() == ns.URI() {
+				e.RemoveNamespaceByPrefix(ns.Prefix())
+			}
+		}
+	}
+}
+
+// insertAfter links nodes in after anchor, in order.
+func insertAfter(anchor helium.Node, nodes []helium.Node) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+	return mutable(anchor).Replace(append([]helium.Node{anchor}, nodes...)...)
+}
+
+// insertBefore links nodes in before anchor, in order.
+func insertBefore(anchor helium.Node, nodes []helium.Node) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+	return mutable(anchor).Replace(append(nodes[:len(nodes):len(nodes)], anchor)...)
+}
+
+// appendChildren links nodes in as the last children of parent.
+func appendChildren(parent helium.Node, nodes []helium.Node) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+	if last := parent.LastChild(); last != nil {
+		return insertAfter(last, nodes)
+	}
+	if err := mutable(parent).AddChild(nodes[0]); err != nil {
+		return err
+	}
+	return insertAfter(nodes[0], nodes[1:])
+}
+
+// prependChildren links nodes in as the first children of parent.
+func prependChildren(parent helium.Node, nodes []helium.Node) error {
+	if first := parent.FirstChild(); first != nil {
+		return insertBefore(first, nodes)
+	}
+	return appendChildren(parent, nodes)
+}
+
+func mutable(n helium.Node) helium.MutableNode {
+	return n.(helium.MutableNode) //nolint:forcetypeassert // every tree node is mutable
+}
+
+// setAttribute creates or replaces the attribute {uri}local of e. A
+// namespace in scope for uri is reused; otherwise prefix is declared for it
+// on e.
+func setAttribute(e *helium.Element, uri, local, prefix, value string) error {
+	if uri == "" {
+		return e.SetAttribute(local, value)
+	}
+	ns := helium.LookupNSByHref(e, uri)
+	if ns == nil || ns.Prefix() == "" {
+		if err := e.DeclareNamespace(prefix, uri); err != nil {
+			return err
+		}
+		ns = helium.LookupNSByPrefix(e, prefix)
+	}
+	return e.SetAttributeNS(local, value, ns)
+}
+
+// removeAttribute removes the attribute {uri}local of e.
+func removeAttribute(e *helium.Element, uri, local string) bool {
+	if uri == "" {
+		return e.RemoveAttribute(local)
+	}
+	return e.RemoveAttributeNS(local, uri)
+}
+
+// pathBuilder writes XPath selectors for nodes of a document. Namespace URIs
+// are bound to prefixes through bind, which declares them where the
+// selectors will be read.
+type pathBuilder struct {
+	bind func(uri, hint string) string
+}
+
+// path returns a location path that selects n, and only n, in its document
+// as it is now.
+func (pb *pathBuilder) path(n helium.Node) string {
+	if _, ok := n.(*helium.Document); ok {
+		return "/"
+	}
+	base := pb.path(n.Parent())
+	if base != "/" {
+		base += "/"
+	}
+	switch n := n.(type) {
+	case *helium.Attribute:
+		return base + "@" + pb.qname(n.URI(), n.Prefix(), n.LocalName())
+	case *helium.Element:
+		return base + pb.qname(n.URI(), n.Prefix(), n.LocalName()) + position(n, func(s helium.Node) bool {
+			e, ok := s.(*helium.Element)
+			return ok && sameName(e, n)
+		})
+	case *helium.Text, *helium.CDATASection:
+		return base + "text()" + position(n, isText)
+	case *helium.Comment:
+		return base + "comment()" + position(n, func(s helium.Node) bool {
+			return s.Type() == helium.CommentNode
+		})
+	case *helium.ProcessingInstruction:
+		return base + "processing-instruction('" + n.Name() + "')" + position(n, func(s helium.Node) bool {
+			pi, ok := s.(*helium.ProcessingInstruction)
+			return ok && pi.Name() == n.Name()
+		})
+	}
+	return base + "node()" + position(n, func(helium.Node) bool { return true })
+}
+
+func (pb *pathBuilder) qname(uri, prefix, local string) string {
+	if uri == "" {
+		return local
+	}
+	return pb.bind(uri, prefix) + ":" + local
+}
+
+// position returns the predicate that picks n out of the siblings like it,
+// or "" when there are none.
+func position(n helium.Node, like func(helium.Node) bool) string {
+	index, count := 0, 0
+	for s := range helium.Children(n.Parent()) {
+		if !like(s) {
+			continue
+		}
+		count++
+		if s == n {
+			index = count
+		}
+	}
+	if count == 1 {
+		return ""
+	}
+	return "[" + strconv.Itoa(index) + "]"
+}
+
+// isBlank reports whether n is a text node holding only whitespace.
+func isBlank(n helium.Node) bool {
+	t, ok := n.(*helium.Text)
+	return ok && strings.TrimLeft(string(t.Content()), " \t\r\n") == ""
+}
+
+// isXMLNamespace reports whether uri is the namespace bound to the xml prefix,
+// which is never declared.
+func isXMLNamespace(uri string) bool {
+	return uri == lexicon.NamespaceXML
+}
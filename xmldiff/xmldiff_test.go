@@ -0,0 +1,322 @@
+package xmldiff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/c14n"
+	"github.com/lestrrat-go/helium/xmldiff"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, s string) *helium.Document {
+	t.Helper()
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(s))
+	require.NoError(t, err)
+	return doc
+}
+
+func serialize(t *testing.T, n helium.Node) string {
+	t.Helper()
+	var sb strings.Builder
+	require.NoError(t, helium.NewWriter().XMLDeclaration(false).WriteTo(&sb, n))
+	return sb.String()
+}
+
+func canonical(t *testing.T, doc *helium.Document) string {
+	t.Helper()
+	out, err := c14n.NewCanonicalizer(c14n.C14N10).Comments().CanonicalizeTo(doc)
+	require.NoError(t, err)
+	return string(out)
+}
+
+// diff returns the serialized patch from a to b, and checks that the patch,
+// once reparsed, turns a into b.
+func diff(t *testing.T, d xmldiff.Differ, a, b string) string {
+	t.Helper()
+	docA, docB := parse(t, a), parse(t, b)
+	patch, err := d.Diff(t.Context(), docA, docB)
+	require.NoError(t, err)
+	text := serialize(t, patch)
+
+	out, err := xmldiff.Patch(t.Context(), docA, parse(t, text))
+	require.NoError(t, err, "patch: %s", text)
+	require.Equal(t, canonical(t, docB), canonical(t, out), "patch: %s", text)
+	require.Equal(t, serialize(t, parse(t, a)), serialize(t, docA), "Patch modified its input")
+	return text
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "identical",
+			a:    `<r><a x="1">t</a><!--c--></r>`,
+			b:    `<r><a x="1">t</a><!--c--></r>`,
+			want: `<diff/>`,
+		},
+		{
+			name: "attribute order is not a difference",
+			a:    `<r a="1" b="2"/>`,
+			b:    `<r b="2" a="1"/>`,
+			want: `<diff/>`,
+		},
+		{
+			name: "text",
+			a:    `<r><p>one</p><p>two</p></r>`,
+			b:    `<r><p>one</p><p>three</p></r>`,
+			want: `<diff><replace sel="/r/p[2]/text()">three</replace></diff>`,
+		},
+		{
+			name: "attributes",
+			a:    `<r a="1" b="2"/>`,
+			b:    `<r b="3" c="4"/>`,
+			want: `<diff><remove sel="/r/@a"/><replace sel="/r/@b">3</replace><add sel="/r" type="@c">4</add></diff>`,
+		},
+		{
+			name: "insert in the middle",
+			a:    `<r><a/><c/></r>`,
+			b:    `<r><a/><b/><c/></r>`,
+			want: `<diff><add sel="/r/a" pos="after"><b/></add></diff>`,
+		},
+		{
+			name: "insert at the start",
+			a:    `<r><b/></r>`,
+			b:    `<r><a/><b/></r>`,
+			want: `<diff><add sel="/r" pos="prepend"><a/></add></diff>`,
+		},
+		{
+			name: "insert into empty element",
+			a:    `<r/>`,
+			b:    `<r><a/>x</r>`,
+			want: `<diff><add sel="/r"><a/>x</add></diff>`,
+		},
+		{
+			name: "remove",
+			a:    `<r><a/><b/><a/></r>`,
+			b:    `<r><a/><a/></r>`,
+			want: `<diff><remove sel="/r/b"/></diff>`,
+		},
+		{
+			name: "repeated names are indexed",
+			a:    `<r><i>1</i><i>2</i><i>3</i></r>`,
+			b:    `<r><i>1</i><i>3</i></r>`,
+			want: `<diff><remove sel="/r/i[2]"/></diff>`,
+		},
+		{
+			name: "document element renamed",
+			a:    `<r><a/></r>`,
+			b:    `<s><a/></s>`,
+			want: `<diff><replace sel="/r"><s><a/></s></replace></diff>`,
+		},
+		{
+			name: "comments and processing instructions",
+			a:    `<?p one?><r><!--a--><?q x?></r>`,
+			b:    `<?p two?><r><!--b--><?q x?></r>`,
+			want: `<diff><replace sel="/processing-instruction('p')"><?p two?></replace><replace sel="/r/comment()"><!--b--></replace></diff>`,
+		},
+		{
+			name: "namespaces",
+			a:    `<r xmlns="urn:a"><x:i xmlns:x="urn:x" x:v="1"/></r>`,
+			b:    `<r xmlns="urn:a"><x:i xmlns:x="urn:x" x:v="2"/><j/></r>`,
+			want: `<diff xmlns:ns1="urn:a" xmlns:x="urn:x"><replace sel="/ns1:r/x:i/@x:v">2</replace><add sel="/ns1:r/x:i" pos="after"><j xmlns="urn:a"/></add></diff>`,
+		},
+		{
+			name: "namespace declarations",
+			a:    `<r xmlns:a="urn:a"/>`,
+			b:    `<r xmlns:b="urn:b"/>`,
+			want: `<diff><add sel="/r" type="namespace::b">urn:b</add><remove sel="/r/namespace::a"/></diff>`,
+		},
+		{
+			name: "identical in a default namespace",
+			a:    `<r xmlns="urn:x"><a/></r>`,
+			b:    `<r xmlns="urn:x"><a/></r>`,
+			want: `<diff/>`,
+		},
+		{
+			name: "identical with a prefix",
+			a:    `<r xmlns:p="urn:p"><p:a/></r>`,
+			b:    `<r xmlns:p="urn:p"><p:a/></r>`,
+			want: `<diff/>`,
+		},
+		{
+			name: "remove in a default namespace",
+			a:    `<r xmlns="urn:x"><a>1</a><a>2</a><a>3</a></r>`,
+			b:    `<r xmlns="urn:x"><a>1</a><a>3</a></r>`,
+			want: `<diff xmlns:ns1="urn:x"><remove sel="/ns1:r/ns1:a[2]"/></diff>`,
+		},
+		{
+			name: "declaration inherited by the children",
+			a:    `<r><a/></r>`,
+			b:    `<r xmlns:q="urn:q"><a/></r>`,
+			want: `<diff><add sel="/r" type="namespace::q">urn:q</add></diff>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, strings.TrimSpace(diff(t, xmldiff.NewDiffer(), tt.a, tt.b)))
+		})
+	}
+}
+
+func TestDiffMatching(t *testing.T) {
+	t.Parallel()
+
+	t.Run("by ID", func(t *testing.T) {
+		t.Parallel()
+		// Every item changes entirely, but the IDs tie them together, so each
+		// is edited in place instead of removed and added.
+		a := `<r><item id="1"><name>Apple</name></item><item id="2"><name>Pear</name></item></r>`
+		b := `<r><item id="1"><name>Quince</name></item><item id="2"><name>Fig</name></item></r>`
+		got := diff(t, xmldiff.NewDiffer(), a, b)
+		require.Equal(t, `<diff><replace sel="/r/item[1]/name/text()">Quince</replace><replace sel="/r/item[2]/name/text()">Fig</replace></diff>`, strings.TrimSpace(got))
+	})
+
+	t.Run("different IDs are never paired", func(t *testing.T) {
+		t.Parallel()
+		a := `<r><item id="1">same</item></r>`
+		b := `<r><item id="2">same</item></r>`
+		got := diff(t, xmldiff.NewDiffer().IDAttributes("id"), a, b)
+		require.Equal(t, `<diff><remove sel="/r/item"/><add sel="/r"><item id="2">same</item></add></diff>`, strings.TrimSpace(got))
+
+		// Without ID attributes the items are similar enough to be edited.
+		got = diff(t, xmldiff.NewDiffer().IDAttributes(), a, b)
+		require.Equal(t, `<diff><replace sel="/r/item/@id">2</replace></diff>`, strings.TrimSpace(got))
+	})
+
+	t.Run("by similarity", func(t *testing.T) {
+		t.Parallel()
+		// The second paragraph of b is an edit of the first of a, not of
+		// the unrelated paragraph inserted before it.
+		a := `<doc><p>the quick brown fox jumps</p></doc>`
+		b := `<doc><p>lorem ipsum</p><p>the quick brown fox <b>leaps</b></p></doc>`
+		got := diff(t, xmldiff.NewDiffer(), a, b)
+		require.Contains(t, got, `<add sel="/doc" pos="prepend"><p>lorem ipsum</p></add>`)
+
+		// A threshold above 1 pairs nothing that is not identical.
+		got = diff(t, xmldiff.NewDiffer().Threshold(1.1), a, b)
+		require.Equal(t, `<diff><remove sel="/doc/p"/><add sel="/doc"><p>lorem ipsum</p><p>the quick brown fox <b>leaps</b></p></add></diff>`, strings.TrimSpace(got))
+	})
+
+	t.Run("xml:id", func(t *testing.T) {
+		t.Parallel()
+		a := `<r><s xml:id="a">x</s></r>`
+		b := `<r><s xml:id="b">x</s></r>`
+		got := diff(t, xmldiff.NewDiffer(), a, b)
+		require.Equal(t, `<diff><remove sel="/r/s"/><add sel="/r"><s xml:id="b">x</s></add></diff>`, strings.TrimSpace(got))
+	})
+}
+
+func TestDiffRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	pairs := [][2]string{
+		{`<r>x<b/>y</r>`, `<r>xy</r>`},
+		{`<r>xy</r>`, `<r>x<b/>y</r>`},
+		{`<r>x<b/>y</r>`, `<r>x<!--c-->y</r>`},
+		{`<r><a/><b/><c/></r>`, `<r><c/><b/><a/></r>`},
+		{`<r><![CDATA[a<b]]></r>`, `<r>a&lt;c</r>`},
+		{"<r>\n  <a>1</a>\n  <b>2</b>\n</r>", "<r>\n  <b>2</b>\n  <c>3</c>\n</r>"},
+		{`<!--head--><r/><!--tail-->`, `<r/>`},
+		{`<r/>`, `<!--head--><?pi?><r/>`},
+		{`<r xmlns:p="urn:p"><p:a/></r>`, `<r xmlns:q="urn:q"><q:a/></r>`},
+		{`<r a="&amp;&lt;"/>`, `<r a="&quot;'"/>`},
+		{`<r><t><u><v>deep</v></u></t></r>`, `<r><t><u><v>deeper</v><w/></u></t></r>`},
+	}
+	for _, p := range pairs {
+		diff(t, xmldiff.NewDiffer(), p[0], p[1])
+		diff(t, xmldiff.NewDiffer(), p[1], p[0])
+	}
+}
+
+func TestPatch(t *testing.T) {
+	t.Parallel()
+
+	const doc = `<r xmlns:x="urn:x"><a/> <b x:k="v"/> <c>text</c></r>`
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+		err   error
+	}{
+		{
+			name:  "add before",
+			patch: `<diff><add sel="/r/b" pos="before"><n/></add></diff>`,
+			want:  `<r xmlns:x="urn:x"><a/> <n/><b x:k="v"/> <c>text</c></r>`,
+		},
+		{
+			name:  "remove with whitespace",
+			patch: `<diff><remove sel="/r/b" ws="before"/></diff>`,
+			want:  `<r xmlns:x="urn:x"><a/> <c>text</c></r>`,
+		},
+		{
+			name:  "selector prefixes come from the patch",
+			patch: `<diff xmlns:y="urn:x"><replace sel="/r/b/@y:k">w</replace></diff>`,
+			want:  `<r xmlns:x="urn:x"><a/> <b x:k="w"/> <c>text</c></r>`,
+		},
+		{
+			name:  "replace element ignores surrounding whitespace",
+			patch: "<diff><replace sel=\"/r/c\">\n  <d/>\n</replace></diff>",
+			want:  `<r xmlns:x="urn:x"><a/> <b x:k="v"/> <d/></r>`,
+		},
+		{
+			name:  "add namespaced attribute",
+			patch: `<diff xmlns:z="urn:z"><add sel="/r/a" type="@z:k">1</add></diff>`,
+			want:  `<r xmlns:x="urn:x"><a xmlns:z="urn:z" z:k="1"/> <b x:k="v"/> <c>text</c></r>`,
+		},
+		{
+			name:  "unlocated",
+			patch: `<diff><remove sel="/r/missing"/></diff>`,
+			err:   xmldiff.ErrUnlocatedNode,
+		},
+		{
+			name:  "ambiguous",
+			patch: `<diff><remove sel="/r/*"/></diff>`,
+			err:   xmldiff.ErrUnlocatedNode,
+		},
+		{
+			name:  "unknown operation",
+			patch: `<diff><move sel="/r/a"/></diff>`,
+			err:   xmldiff.ErrInvalidPatch,
+		},
+		{
+			name:  "missing sel",
+			patch: `<diff><remove/></diff>`,
+			err:   xmldiff.ErrInvalidPatch,
+		},
+		{
+			name:  "bad ws",
+			patch: `<diff><remove sel="/r/a" ws="before"/></diff>`,
+			err:   xmldiff.ErrInvalidPatch,
+		},
+		{
+			name:  "element replaced by comment",
+			patch: `<diff><replace sel="/r/a"><!--no--></replace></diff>`,
+			err:   xmldiff.ErrInvalidNodeTypes,
+		},
+		{
+			name:  "existing attribute added",
+			patch: `<diff xmlns:x="urn:x"><add sel="/r/b" type="@x:k">1</add></diff>`,
+			err:   xmldiff.ErrInvalidPatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			out, err := xmldiff.Patch(t.Context(), parse(t, doc), parse(t, tt.patch))
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, strings.TrimSpace(serialize(t, out)))
+		})
+	}
+}
@@ -60,6 +60,11 @@ const (
 	ExcC14N10Comments = "http://www.w3.org/2001/10/xml-exc-c14n#WithComments"
 	C14N11URI         = "http://www.w3.org/2006/12/xml-c14n11"
 	C14N11Comments    = "http://www.w3.org/2006/12/xml-c14n11#WithComments"
+	// C14N20URI is Canonical XML 2.0, the canonicalization of the XML
+	// Signature 2.0 profile. Its parameters (comments among them) are
+	// c14n2:* child elements of the CanonicalizationMethod or Transform
+	// rather than separate URIs; see [C14N20Parameters].
+	C14N20URI = "http://www.w3.org/2010/xml-c14n2"
 )
 
 // Transform URIs.
@@ -2,6 +2,7 @@ package xmldsig1
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -134,6 +135,53 @@ func (r limitedReferenceResolver) ResolveReference(ctx context.Context, uri stri
 	return data, nil
 }
 
+// ResourceReferenceResolver returns a [ReferenceResolver] that dereferences
+// external Reference URIs through r, so the resolver a caller already uses to
+// sandbox parsing and schema loading governs detached content too. The joined
+// URI is handed to r unchanged. The read is bounded like
+// [FSReferenceResolver]'s and composes with [LimitReferenceResolver]. A
+// resource r refuses with [helium.ErrResourceTooLarge] fails with
+// [ErrReferenceTooLarge]; any other error from r is wrapped in
+// [ErrReferenceNotFound].
+func ResourceReferenceResolver(r helium.ResourceResolver) ReferenceResolver {
+	return resourceReferenceResolver{r: r}
+}
+
+type resourceReferenceResolver struct {
+	r helium.ResourceResolver
+}
+
+func (r resourceReferenceResolver) ResolveReference(ctx context.Context, uri string) ([]byte, error) {
+	return r.resolveReferenceWithLimit(ctx, uri, maxReferenceBytes)
+}
+
+func (r resourceReferenceResolver) resolveReferenceWithLimit(ctx context.Context, uri string, maxBytes int) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	res, err := r.r.ResolveResource(ctx, uri)
+	if err != nil {
+		return nil, resourceReferenceError(uri, err)
+	}
+	defer res.Close()
+
+	data, err := io.ReadAll(io.LimitReader(res, int64(maxBytes)+1))
+	if err != nil {
+		return nil, resourceReferenceError(uri, err)
+	}
+	if len(data) > maxBytes {
+		return nil, fmt.Errorf("%w: external reference %q exceeds %d bytes", ErrReferenceTooLarge, uri, maxBytes)
+	}
+	return data, nil
+}
+
+func resourceReferenceError(uri string, err error) error {
+	if errors.Is(err, helium.ErrResourceTooLarge) {
+		return fmt.Errorf("%w: external reference %q: %w", ErrReferenceTooLarge, uri, err)
+	}
+	return fmt.Errorf("%w: cannot open external reference %q: %w", ErrReferenceNotFound, uri, err)
+}
+
 func (r fsReferenceResolver) ResolveReference(ctx context.Context, uri string) ([]byte, error) {
 	return r.resolveReferenceWithLimit(ctx, uri, maxReferenceBytes)
 }
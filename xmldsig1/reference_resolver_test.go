@@ -268,3 +268,38 @@ func TestVerifyExternalEnvelopedRejected(t *testing.T) {
 		SignDetached(t.Context(), doc, []byte("secret"))
 	require.ErrorIs(t, err, xmldsig1.ErrUnsupportedTransform)
 }
+
+func TestResourceReferenceResolver(t *testing.T) {
+	const uri = "http://www.w3.org/TR/xml-stylesheet"
+	target := readInterop(t, "xml-stylesheet")
+	resources := helium.MemoryResourceResolver(map[string][]byte{uri: target})
+
+	t.Run("verifies through a resource resolver", func(t *testing.T) {
+		doc, err := helium.NewParser().Parse(t.Context(), readInterop(t, "signature-external-dsa.xml"))
+		require.NoError(t, err)
+
+		result, err := xmldsig1.NewVerifier(dsaKeySource()).
+			AllowSHA1(true).
+			ReferenceResolver(xmldsig1.ResourceReferenceResolver(resources)).
+			Verify(t.Context(), doc)
+		require.NoError(t, err)
+		require.Len(t, result.References, 1)
+	})
+
+	t.Run("missing resource", func(t *testing.T) {
+		_, err := xmldsig1.ResourceReferenceResolver(resources).ResolveReference(t.Context(), "urn:missing")
+		require.ErrorIs(t, err, xmldsig1.ErrReferenceNotFound)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("limits compose", func(t *testing.T) {
+		r := xmldsig1.LimitReferenceResolver(xmldsig1.ResourceReferenceResolver(resources), 16)
+		_, err := r.ResolveReference(t.Context(), uri)
+		require.ErrorIs(t, err, xmldsig1.ErrReferenceTooLarge)
+
+		r = xmldsig1.ResourceReferenceResolver(helium.LimitResourceResolver(resources, 16))
+		_, err = r.ResolveReference(t.Context(), uri)
+		require.ErrorIs(t, err, xmldsig1.ErrReferenceTooLarge)
+		require.ErrorIs(t, err, helium.ErrResourceTooLarge)
+	})
+}
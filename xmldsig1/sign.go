@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/c14n"
 )
 
 func signEnveloped(ctx context.Context, cfg *signerConfig, doc *helium.Document, parent *helium.Element, key any) error {
@@ -514,6 +515,11 @@ func buildSignatureSkeleton(doc *helium.Document, cfg *signerConfig) (*helium.El
 	if err := c14nMethod.SetAttribute("Algorithm", cfg.c14nMethod); err != nil {
 		return nil, nil, nil, err
 	}
+	if cfg.c14nMethod == C14N20URI && cfg.c14n2 != nil {
+		if err := appendC14N20Parameters(doc, c14nMethod, *cfg.c14n2); err != nil {
+			return nil, nil, nil, err
+		}
+	}
 	if err := signedInfo.AddChild(c14nMethod); err != nil {
 		return nil, nil, nil, err
 	}
@@ -687,6 +693,11 @@ func processReference(ctx context.Context, cfg *signerConfig, doc *helium.Docume
 					return err
 				}
 			}
+			if c2, ok := t.(c14n20Transform); ok {
+				if err := appendC14N20Parameters(doc, tElem, c2.params); err != nil {
+					return err
+				}
+			}
 			if err := transformsElem.AddChild(tElem); err != nil {
 				return err
 			}
@@ -730,6 +741,99 @@ func processReference(ctx context.Context, cfg *signerConfig, doc *helium.Docume
 	return signedInfo.AddChild(refElem)
 }
 
+// appendC14N20Parameters writes the parameters of Canonical XML 2.0 that
+// differ from the specification's defaults as c14n2:* children of parent, a
+// CanonicalizationMethod or Transform element.
+func appendC14N20Parameters(doc *helium.Document, parent *helium.Element, params C14N20Parameters) error {
+	newParam := func(name string) (*helium.Element, error) {
+		e, err := doc.CreateElement(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.SetActiveNamespace("c14n2", C14N20URI); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	addParam := func(name, value string) error {
+		e, err := newParam(name)
+		if err != nil {
+			return err
+		}
+		if err := e.DeclareNamespace("c14n2", C14N20URI); err != nil {
+			return err
+		}
+		if err := e.AddChild(doc.CreateText([]byte(value))); err != nil {
+			return err
+		}
+		return parent.AddChild(e)
+	}
+
+	if params.Comments {
+		if err := addParam("IgnoreComments", "false"); err != nil {
+			return err
+		}
+	}
+	if params.TrimTextNodes {
+		if err := addParam("TrimTextNodes", "true"); err != nil {
+			return err
+		}
+	}
+	switch params.PrefixRewrite {
+	case c14n.PrefixRewriteNone:
+	case c14n.PrefixRewriteSequential:
+		if err := addParam("PrefixRewrite", "sequential"); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: unsupported c14n2:PrefixRewrite %d", ErrUnsupportedTransform, params.PrefixRewrite)
+	}
+
+	if len(params.QNameAwareElements)+len(params.QNameAwareQualifiedAttrs)+len(params.QNameAwareUnqualifiedAttrs)+len(params.QNameAwareXPathElements) == 0 {
+		return nil
+	}
+	qnameAware, err := newParam("QNameAware")
+	if err != nil {
+		return err
+	}
+	if err := qnameAware.DeclareNamespace("c14n2", C14N20URI); err != nil {
+		return err
+	}
+	addName := func(name string, attrs ...string) error {
+		e, err := newParam(name)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(attrs); i += 2 {
+			if err := e.SetAttribute(attrs[i], attrs[i+1]); err != nil {
+				return err
+			}
+		}
+		return qnameAware.AddChild(e)
+	}
+	for _, n := range params.QNameAwareElements {
+		if err := addName("Element", "Name", n.Name, "NS", n.NS); err != nil {
+			return err
+		}
+	}
+	for _, n := range params.QNameAwareQualifiedAttrs {
+		if err := addName("QualifiedAttr", "Name", n.Name, "NS", n.NS); err != nil {
+			return err
+		}
+	}
+	for _, n := range params.QNameAwareUnqualifiedAttrs {
+		if err := addName("UnqualifiedAttr", "Name", n.Name, "ParentName", n.ParentName, "ParentNS", n.ParentNS); err != nil {
+			return err
+		}
+	}
+	for _, n := range params.QNameAwareXPathElements {
+		if err := addName("XPathElement", "Name", n.Name, "NS", n.NS); err != nil {
+			return err
+		}
+	}
+	return parent.AddChild(qnameAware)
+}
+
 // computeAndSetSignatureValue canonicalizes SignedInfo, signs it, and sets
 // the SignatureValue element text.
 func computeAndSetSignatureValue(ctx context.Context, cfg *signerConfig, sigElem *helium.Element, signedInfo, sigValueElem *helium.Element, doc *helium.Document, key any) error {
@@ -746,9 +850,9 @@ func computeAndSetSignatureValue(ctx context.Context, cfg *signerConfig, sigElem
 	var canonical []byte
 	var err error
 	if sigElem.Parent() == nil {
-		canonical, err = canonicalizeDetachedSubtree(ctx, cfg.c14nMethod, sigElem, signedInfo, nil)
+		canonical, err = canonicalizeDetachedSubtree(ctx, cfg.c14nMethod, sigElem, signedInfo, &c14nParameters{c14n2: cfg.c14n2})
 	} else {
-		canonical, err = canonicalizeSubtree(ctx, cfg.c14nMethod, signedInfo, nil)
+		canonical, err = canonicalizeSubtree(ctx, cfg.c14nMethod, signedInfo, &c14nParameters{c14n2: cfg.c14n2})
 	}
 	if err != nil {
 		return err
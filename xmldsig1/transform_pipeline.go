@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/lestrrat-go/helium/c14n"
 	"github.com/lestrrat-go/helium/internal/xmlbase64"
 
 	helium "github.com/lestrrat-go/helium"
@@ -106,8 +107,11 @@ func transformSteps(ref ReferenceConfig) []transformStep {
 	steps := make([]transformStep, len(ref.Transforms))
 	for i, t := range ref.Transforms {
 		step := transformStep{algorithm: t.URI()}
-		if exc, ok := t.(excC14NTransform); ok {
-			step.prefixes = exc.prefixes
+		switch t := t.(type) {
+		case excC14NTransform:
+			step.prefixes = t.prefixes
+		case c14n20Transform:
+			step.c14n2 = &t.params
 		}
 		steps[i] = step
 	}
@@ -147,6 +151,15 @@ func contractForTransform(step transformStep) (transformContract, error) {
 	switch step.algorithm {
 	case C14N10, C14N10Comments, ExcC14N10, ExcC14N10Comments, C14N11URI, C14N11Comments:
 		return transformContract{input: transformValueNodeSet, output: transformValueOctets}, nil
+	case C14N20URI:
+		if step.c14n2 != nil {
+			switch step.c14n2.PrefixRewrite {
+			case c14n.PrefixRewriteNone, c14n.PrefixRewriteSequential:
+			default:
+				return transformContract{}, fmt.Errorf("%w: unsupported c14n2:PrefixRewrite %d", ErrUnsupportedTransform, step.c14n2.PrefixRewrite)
+			}
+		}
+		return transformContract{input: transformValueNodeSet, output: transformValueOctets}, nil
 	case TransformBase64:
 		return transformContract{input: transformValueOctets, output: transformValueOctets}, nil
 	case TransformXPath:
@@ -304,8 +317,8 @@ func executeTransformPipeline(ctx context.Context, runtime transformRuntime, ini
 		}
 
 		switch step.algorithm {
-		case C14N10, C14N10Comments, ExcC14N10, ExcC14N10Comments, C14N11URI, C14N11Comments:
-			octets, err := canonicalizeNodeSetValue(ctx, step.algorithm, value.nodes, step.prefixes)
+		case C14N10, C14N10Comments, ExcC14N10, ExcC14N10Comments, C14N11URI, C14N11Comments, C14N20URI:
+			octets, err := canonicalizeNodeSetValue(ctx, step.algorithm, value.nodes, step.c14nParams())
 			if err != nil {
 				return nil, fmt.Errorf("transform %d (%s): %w", i, step.algorithm, err)
 			}
@@ -473,30 +486,33 @@ func applyEnvelopedTransform(ctx context.Context, value *nodeSetValue, sigElem *
 	return nil
 }
 
-func canonicalizeNodeSetValue(ctx context.Context, method string, value *nodeSetValue, prefixes []string) ([]byte, error) {
+func canonicalizeNodeSetValue(ctx context.Context, method string, value *nodeSetValue, params *c14nParameters) ([]byte, error) {
 	if value == nil || value.doc == nil {
 		return nil, fmt.Errorf("%w: transform node-set has no owning document", ErrUnsupportedTransform)
 	}
 	if value.referenceSelection {
 		method = effectiveC14NMethod(method, value.includeComments)
+		if !value.includeComments {
+			params = params.withoutComments()
+		}
 	}
 	if value.origin != nil && !value.materialized {
 		origin := value.origin
 		switch {
 		case origin.envelopedPending:
-			return canonicalizeEnveloped(ctx, method, origin.doc, origin.target, origin.sigElem, origin.wholeDoc, prefixes)
+			return canonicalizeEnveloped(ctx, method, origin.doc, origin.target, origin.sigElem, origin.wholeDoc, params)
 		case origin.wholeDoc:
-			return canonicalize(method, origin.doc, prefixes)
+			return canonicalize(method, origin.doc, params)
 		case origin.internalRoot != nil && isDescendantOrSelf(origin.target, origin.internalRoot):
-			return canonicalizeDetachedSubtree(ctx, method, origin.internalRoot, origin.target, prefixes)
+			return canonicalizeDetachedSubtree(ctx, method, origin.internalRoot, origin.target, params)
 		default:
-			return canonicalizeSubtree(ctx, method, origin.target, prefixes)
+			return canonicalizeSubtree(ctx, method, origin.target, params)
 		}
 	}
 	if !value.materialized {
 		return nil, fmt.Errorf("%w: transform node-set is not materialized", ErrUnsupportedTransform)
 	}
-	return canonicalizeNodeSet(method, value.nodes, value.doc, prefixes)
+	return canonicalizeNodeSet(method, value.nodes, value.doc, params)
 }
 
 func base64TransformNodeSetOctets(ctx context.Context, value *nodeSetValue) ([]byte, error) {
@@ -64,6 +64,96 @@ func ExcC14NTransform(prefixes ...string) Transform {
 	return excC14NTransform{prefixes: slices.Clone(prefixes)}
 }
 
+// C14N20Name names an element or a namespace-qualified attribute in the
+// QNameAware parameter of Canonical XML 2.0.
+type C14N20Name struct {
+	Name string
+	NS   string
+}
+
+// C14N20UnqualifiedAttr names an attribute in no namespace, by its name and
+// the element it appears on, in the QNameAware parameter of Canonical XML 2.0.
+type C14N20UnqualifiedAttr struct {
+	Name       string
+	ParentName string
+	ParentNS   string
+}
+
+// C14N20Parameters are the parameters of Canonical XML 2.0. The zero value is
+// the specification's defaults: comments ignored, text nodes kept as they
+// are, no prefix rewriting and no QName-aware content.
+type C14N20Parameters struct {
+	// Comments keeps comments in the canonical form (IgnoreComments=false).
+	Comments bool
+	// TrimTextNodes trims leading and trailing whitespace from text nodes.
+	TrimTextNodes bool
+	// PrefixRewrite selects how namespace prefixes are rewritten.
+	PrefixRewrite c14n.PrefixRewrite
+	// QNameAwareElements are the elements whose content is a QName.
+	QNameAwareElements []C14N20Name
+	// QNameAwareQualifiedAttrs are the namespace-qualified attributes whose
+	// value is a QName.
+	QNameAwareQualifiedAttrs []C14N20Name
+	// QNameAwareUnqualifiedAttrs are the attributes in no namespace whose
+	// value is a QName.
+	QNameAwareUnqualifiedAttrs []C14N20UnqualifiedAttr
+	// QNameAwareXPathElements are the elements whose content is an XPath
+	// expression.
+	QNameAwareXPathElements []C14N20Name
+}
+
+func (p C14N20Parameters) clone() C14N20Parameters {
+	p.QNameAwareElements = slices.Clone(p.QNameAwareElements)
+	p.QNameAwareQualifiedAttrs = slices.Clone(p.QNameAwareQualifiedAttrs)
+	p.QNameAwareUnqualifiedAttrs = slices.Clone(p.QNameAwareUnqualifiedAttrs)
+	p.QNameAwareXPathElements = slices.Clone(p.QNameAwareXPathElements)
+	return p
+}
+
+// apply configures canon, a C14N20 canonicalizer, with p.
+func (p C14N20Parameters) apply(canon c14n.Canonicalizer) c14n.Canonicalizer {
+	if p.Comments {
+		canon = canon.Comments()
+	}
+	if p.TrimTextNodes {
+		canon = canon.TrimTextNodes()
+	}
+	canon = canon.PrefixRewrite(p.PrefixRewrite)
+	for _, n := range p.QNameAwareElements {
+		canon = canon.QNameAwareElement(n.NS, n.Name)
+	}
+	for _, n := range p.QNameAwareQualifiedAttrs {
+		canon = canon.QNameAwareQualifiedAttribute(n.NS, n.Name)
+	}
+	for _, n := range p.QNameAwareUnqualifiedAttrs {
+		canon = canon.QNameAwareUnqualifiedAttribute(n.ParentNS, n.ParentName, n.Name)
+	}
+	for _, n := range p.QNameAwareXPathElements {
+		canon = canon.QNameAwareXPathElement(n.NS, n.Name)
+	}
+	return canon
+}
+
+// c14n20Transform applies Canonical XML 2.0 with its parameters.
+type c14n20Transform struct {
+	params C14N20Parameters
+}
+
+func (c14n20Transform) URI() string { return C14N20URI }
+
+// Parameters returns the Canonical XML 2.0 parameters of this transform. The
+// returned value shares no slices with the transform.
+func (t c14n20Transform) Parameters() C14N20Parameters { return t.params.clone() }
+
+// C14N20Transform returns a Canonical XML 2.0 transform with the given
+// parameters, which are written as c14n2:* children of the ds:Transform
+// element. The parameters are copied, so a later mutation of the caller's
+// slices cannot alter the returned transform. C14NTransform(C14N20URI) is the
+// same transform with the default parameters.
+func C14N20Transform(params C14N20Parameters) Transform {
+	return c14n20Transform{params: params.clone()}
+}
+
 // transformSnapshot is the immutable representation of a caller-defined
 // transform. Signing observes only the transform URI, so retaining the
 // caller's implementation would let later mutations change a configured
@@ -97,6 +187,8 @@ func cloneTransform(t Transform) Transform {
 		return nil
 	case excC14NTransform:
 		return excC14NTransform{prefixes: slices.Clone(t.prefixes)}
+	case c14n20Transform:
+		return c14n20Transform{params: t.params.clone()}
 	case envelopedTransform, c14nTransform:
 		return t
 	default:
@@ -110,6 +202,7 @@ func cloneTransform(t Transform) Transform {
 type transformStep struct {
 	algorithm string
 	prefixes  []string
+	c14n2     *C14N20Parameters
 	// xpathExpr and xpathNS carry an XPath filter transform's expression and its
 	// in-scope namespace bindings (from the ds:Transform/XPath element). They are
 	// populated only when algorithm == TransformXPath.
@@ -137,28 +230,71 @@ type xpathFilter struct {
 	hereNode helium.Node
 }
 
+// c14nParams returns the canonicalization parameters of the step.
+func (s transformStep) c14nParams() *c14nParameters {
+	return &c14nParameters{prefixes: s.prefixes, c14n2: s.c14n2}
+}
+
+// c14nParameters are the parameters a canonicalization method element
+// declares: the ec:InclusiveNamespaces PrefixList of exclusive c14n, or the
+// c14n2:* parameters of Canonical XML 2.0. A nil *c14nParameters declares
+// none.
+type c14nParameters struct {
+	prefixes []string
+	c14n2    *C14N20Parameters
+}
+
+// canonicalizer returns a canonicalizer for mode with p applied. Parameters
+// of another mode's algorithm are not applied; parsing has already rejected
+// them (see parseC14NParameters).
+func (p *c14nParameters) canonicalizer(mode c14n.Mode, comments bool) c14n.Canonicalizer {
+	canon := c14n.NewCanonicalizer(mode)
+	if comments {
+		canon = canon.Comments()
+	}
+	if p == nil {
+		return canon
+	}
+	switch mode {
+	case c14n.ExclusiveC14N10:
+		if len(p.prefixes) > 0 {
+			canon = canon.InclusiveNamespaces(p.prefixes)
+		}
+	case c14n.C14N20:
+		if p.c14n2 != nil {
+			canon = p.c14n2.apply(canon)
+		}
+	}
+	return canon
+}
+
+// withoutComments returns p with the comments of Canonical XML 2.0 turned
+// off. It is effectiveC14NMethod for the one algorithm that takes comments as
+// a parameter instead of a URI.
+func (p *c14nParameters) withoutComments() *c14nParameters {
+	if p == nil || p.c14n2 == nil || !p.c14n2.Comments {
+		return p
+	}
+	c14n2 := *p.c14n2
+	c14n2.Comments = false
+	return &c14nParameters{prefixes: p.prefixes, c14n2: &c14n2}
+}
+
 // canonicalize applies the appropriate c14n mode for the given method URI
 // to the document, returning the canonical bytes.
-func canonicalize(method string, doc *helium.Document, prefixes []string) ([]byte, error) {
+func canonicalize(method string, doc *helium.Document, params *c14nParameters) ([]byte, error) {
 	mode, comments, err := resolveC14NMode(method)
 	if err != nil {
 		return nil, err
 	}
-	canon := c14n.NewCanonicalizer(mode)
-	if comments {
-		canon = canon.Comments()
-	}
-	if mode == c14n.ExclusiveC14N10 && len(prefixes) > 0 {
-		canon = canon.InclusiveNamespaces(prefixes)
-	}
-	return canon.CanonicalizeTo(doc)
+	return params.canonicalizer(mode, comments).CanonicalizeTo(doc)
 }
 
 // canonicalizeSubtree canonicalizes a single element subtree by canonicalizing
 // the node-set of that subtree against its owning document. The node set goes
 // straight to c14n, so it is built with the reduced, mode-aware namespace
 // membership (see collectCanonicalizationNodes).
-func canonicalizeSubtree(ctx context.Context, method string, elem *helium.Element, prefixes []string) ([]byte, error) {
+func canonicalizeSubtree(ctx context.Context, method string, elem *helium.Element, params *c14nParameters) ([]byte, error) {
 	mode, comments, err := resolveC14NMode(method)
 	if err != nil {
 		return nil, err
@@ -167,7 +303,7 @@ func canonicalizeSubtree(ctx context.Context, method string, elem *helium.Elemen
 	if err != nil {
 		return nil, err
 	}
-	return canonicalizeNodeSetMode(mode, comments, nodes, elem.OwnerDocument(), prefixes)
+	return canonicalizeNodeSetMode(mode, comments, nodes, elem.OwnerDocument(), params)
 }
 
 // canonicalizeNodeSet canonicalizes an explicit node-set against doc using the
@@ -177,26 +313,19 @@ func canonicalizeSubtree(ctx context.Context, method string, elem *helium.Elemen
 // the method is a WithComments variant (see effectiveC14NMethod), so a
 // comment-excluding reference form never emits comments regardless of the c14n
 // method.
-func canonicalizeNodeSet(method string, nodes []helium.Node, doc *helium.Document, prefixes []string) ([]byte, error) {
+func canonicalizeNodeSet(method string, nodes []helium.Node, doc *helium.Document, params *c14nParameters) ([]byte, error) {
 	mode, comments, err := resolveC14NMode(method)
 	if err != nil {
 		return nil, err
 	}
-	return canonicalizeNodeSetMode(mode, comments, nodes, doc, prefixes)
+	return canonicalizeNodeSetMode(mode, comments, nodes, doc, params)
 }
 
 // canonicalizeNodeSetMode is the shared node-set -> octet call for a method URI
 // whose c14n mode is already resolved, so a caller that needed the mode to build
 // the node set does not resolve it twice.
-func canonicalizeNodeSetMode(mode c14n.Mode, comments bool, nodes []helium.Node, doc *helium.Document, prefixes []string) ([]byte, error) {
-	canon := c14n.NewCanonicalizer(mode).NodeSet(nodes)
-	if comments {
-		canon = canon.Comments()
-	}
-	if mode == c14n.ExclusiveC14N10 && len(prefixes) > 0 {
-		canon = canon.InclusiveNamespaces(prefixes)
-	}
-	return canon.CanonicalizeTo(doc)
+func canonicalizeNodeSetMode(mode c14n.Mode, comments bool, nodes []helium.Node, doc *helium.Document, params *c14nParameters) ([]byte, error) {
+	return params.canonicalizer(mode, comments).NodeSet(nodes).CanonicalizeTo(doc)
 }
 
 // collectDocumentNodes returns the whole-document node-set: every top-level
@@ -240,7 +369,7 @@ func collectDocumentNodes(ctx context.Context, doc *helium.Document) ([]helium.N
 func collectConvertedDocumentNodes(ctx context.Context, doc *helium.Document, consumerAlgorithm string) ([]helium.Node, error) {
 	mode, _, err := resolveC14NMode(consumerAlgorithm)
 	if err != nil {
-		// Not one of the six canonicalization URIs, so the consumer is the XPath
+		// Not one of the canonicalization URIs, so the consumer is the XPath
 		// filter and the set it is evaluated over must carry every namespace node.
 		return collectDocumentNodes(ctx, doc)
 	}
@@ -462,7 +591,7 @@ func removeSignatureNodes(ctx context.Context, nodes []helium.Node, sigElem *hel
 // of the subtree itself. Exclusive Canonical XML emits only visibly-utilized
 // namespaces and performs NO xml:* inheritance, so both an unused inherited
 // namespace and any inherited xml:* on the proxy leave its output byte-identical.
-func canonicalizeDetachedSubtree(ctx context.Context, method string, root, target *helium.Element, prefixes []string) ([]byte, error) {
+func canonicalizeDetachedSubtree(ctx context.Context, method string, root, target *helium.Element, params *c14nParameters) ([]byte, error) {
 	mode, _, err := resolveC14NMode(method)
 	if err != nil {
 		return nil, err
@@ -520,7 +649,7 @@ func canonicalizeDetachedSubtree(ctx context.Context, method string, root, targe
 	// a partial restore after such a caller-corrupted tree is not a defect here.
 	root.SetTreeDoc(tmp)
 
-	return canonicalizeSubtree(ctx, method, target, prefixes)
+	return canonicalizeSubtree(ctx, method, target, params)
 }
 
 // copyInheritedXMLAttrs copies the caller document element's inherited xml:*
@@ -596,7 +725,7 @@ func isDescendantOrSelf(n helium.Node, root *helium.Element) bool {
 // live target element is canonicalized (URI="#id"). The returned bytes are
 // byte-identical to canonicalizing the same tree with the Signature physically
 // detached.
-func canonicalizeEnveloped(ctx context.Context, method string, doc *helium.Document, target, sigElem *helium.Element, wholeDoc bool, prefixes []string) ([]byte, error) {
+func canonicalizeEnveloped(ctx context.Context, method string, doc *helium.Document, target, sigElem *helium.Element, wholeDoc bool, params *c14nParameters) ([]byte, error) {
 	clone, err := helium.CopyDoc(doc)
 	if err != nil {
 		return nil, err
@@ -647,12 +776,12 @@ func canonicalizeEnveloped(ctx context.Context, method string, doc *helium.Docum
 
 	// Whole-document reference: canonicalize the entire copy.
 	if wholeDoc {
-		return canonicalize(method, clone, prefixes)
+		return canonicalize(method, clone, params)
 	}
 
 	// Fragment reference: canonicalize the cloned subtree corresponding to the
 	// live target element.
-	return canonicalizeSubtree(ctx, method, cloneTarget, prefixes)
+	return canonicalizeSubtree(ctx, method, cloneTarget, params)
 }
 
 // childIndexPath returns the sequence of child indices that locate n starting
@@ -723,6 +852,9 @@ func resolveC14NMode(method string) (c14n.Mode, bool, error) {
 		return c14n.C14N11, false, nil
 	case C14N11Comments:
 		return c14n.C14N11, true, nil
+	case C14N20URI:
+		// Canonical XML 2.0 takes comments as a parameter (c14nParameters).
+		return c14n.C14N20, false, nil
 	default:
 		return 0, false, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, method)
 	}
@@ -790,6 +922,10 @@ func collectSubtreeNodes(ctx context.Context, n helium.Node) ([]helium.Node, err
 //     therefore keeps the element's own prefix and every attribute prefix in
 //     addition to the changed bindings.
 //
+// Canonical XML 2.0 ignores namespace nodes altogether: it declares what an
+// element visibly utilizes, like exclusive c14n, but decides utilization from
+// the document rather than the set. Either membership therefore suits it.
+//
 // A PrefixList needs no per-element handling of its own: the subtree root
 // carries the complete axis, so a listed prefix in scope there is rendered at
 // the root and suppressed by the rendered-namespace stack at every descendant
@@ -42,7 +42,7 @@ func canonicalizeSubtreeFullAxis(t *testing.T, method string, elem *helium.Eleme
 	require.NoError(t, err)
 	nodes, err := collectSubtreeNodes(t.Context(), elem)
 	require.NoError(t, err)
-	return canonicalizeNodeSetMode(mode, comments, nodes, elem.OwnerDocument(), prefixes)
+	return canonicalizeNodeSetMode(mode, comments, nodes, elem.OwnerDocument(), &c14nParameters{prefixes: prefixes})
 }
 
 // requireSameCanonicalBytes canonicalizes elem under every method and prefix
@@ -53,7 +53,7 @@ func requireSameCanonicalBytes(t *testing.T, label string, elem *helium.Element)
 	for _, method := range diffMethods {
 		for _, prefixes := range diffPrefixLists {
 			want, wantErr := canonicalizeSubtreeFullAxis(t, method, elem, prefixes)
-			got, gotErr := canonicalizeSubtree(t.Context(), method, elem, prefixes)
+			got, gotErr := canonicalizeSubtree(t.Context(), method, elem, &c14nParameters{prefixes: prefixes})
 			if wantErr != nil || gotErr != nil {
 				require.Equal(t, fmt.Sprint(wantErr), fmt.Sprint(gotErr),
 					"%s: %s prefixes=%v: error mismatch", label, method, prefixes)
@@ -1,8 +1,11 @@
 package xmldsig1_test
 
 import (
+	"strings"
 	"testing"
 
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/c14n"
 	"github.com/lestrrat-go/helium/xmldsig1"
 	"github.com/stretchr/testify/require"
 )
@@ -65,3 +68,53 @@ func TestSignVerifyNamespacedSubtree(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+// TestSignVerifyC14N20 signs with Canonical XML 2.0 for both SignedInfo and
+// the reference, then checks that the signature survives a change of the
+// signed content's namespace prefix, including one inside a QName-valued
+// attribute: sequential prefix rewriting makes prefix choice insignificant.
+func TestSignVerifyC14N20(t *testing.T) {
+	const xml = `<doc xmlns:pfx="urn:p"><pfx:target Id="x" kind="pfx:widget">
+  <!-- note -->
+  <pfx:child> v </pfx:child>
+</pfx:target></doc>`
+	params := xmldsig1.C14N20Parameters{
+		TrimTextNodes: true,
+		PrefixRewrite: c14n.PrefixRewriteSequential,
+		QNameAwareUnqualifiedAttrs: []xmldsig1.C14N20UnqualifiedAttr{
+			{Name: "kind", ParentName: "target", ParentNS: "urn:p"},
+		},
+	}
+	key := generateRSAKey(t)
+	doc := mustParseXML(t, xml)
+
+	signer := xmldsig1.NewSigner().
+		C14N20Canonicalization(params).
+		SignatureAlgorithm(xmldsig1.AlgRSASHA256).
+		Reference(xmldsig1.ReferenceConfig{
+			URI:             "#x",
+			DigestAlgorithm: xmldsig1.DigestSHA256,
+			Transforms:      []xmldsig1.Transform{xmldsig1.C14N20Transform(params)},
+		})
+	sigElem, err := signer.SignDetached(t.Context(), doc, key)
+	require.NoError(t, err)
+	require.NoError(t, doc.DocumentElement().AddChild(sigElem))
+
+	signed, err := helium.WriteString(doc)
+	require.NoError(t, err)
+	require.Contains(t, signed, `<c14n2:PrefixRewrite xmlns:c14n2="`+xmldsig1.C14N20URI+`">sequential</c14n2:PrefixRewrite>`)
+	require.Contains(t, signed, `<c14n2:UnqualifiedAttr Name="kind" ParentName="target" ParentNS="urn:p"/>`)
+
+	verifier := xmldsig1.NewVerifier(xmldsig1.StaticKey(&key.PublicKey))
+	_, err = verifier.Verify(t.Context(), doc)
+	require.NoError(t, err)
+
+	reprefixed := strings.NewReplacer("xmlns:pfx=", "xmlns:alt=", "pfx:", "alt:").Replace(signed)
+	require.NotEqual(t, signed, reprefixed)
+	_, err = verifier.Verify(t.Context(), mustParseXML(t, reprefixed))
+	require.NoError(t, err)
+
+	tampered := strings.Replace(signed, "pfx:widget", "pfx:gadget", 1)
+	_, err = verifier.Verify(t.Context(), mustParseXML(t, tampered))
+	require.ErrorIs(t, err, xmldsig1.ErrDigestMismatch)
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/c14n"
 	"github.com/lestrrat-go/helium/internal/domutil"
 	"github.com/lestrrat-go/helium/internal/xmlbase64"
 )
@@ -38,7 +39,7 @@ func isNilInterface(v any) bool {
 type parsedSignature struct {
 	signedInfoElem *helium.Element
 	c14nMethod     string
-	c14nPrefixes   []string // ec:InclusiveNamespaces PrefixList on CanonicalizationMethod
+	c14nParams     *c14nParameters // parameters on CanonicalizationMethod
 	signatureAlg   string
 	references     []parsedReference
 	signatureValue []byte
@@ -64,6 +65,7 @@ type preparedReference struct {
 type parsedTransform struct {
 	algorithm  string
 	prefixes   []string          // for Exclusive C14N InclusiveNamespaces
+	c14n2      *C14N20Parameters // for Canonical XML 2.0
 	xpathExpr  string            // for the XPath filter transform (ds:Transform/XPath text)
 	xpathNS    map[string]string // in-scope namespace bindings on the ds:Transform/XPath element
 	xpathHere  helium.Node       // the ds:XPath element bearing the expression (here() resolves to it)
@@ -220,9 +222,10 @@ func verifySignature(ctx context.Context, cfg *verifierConfig, doc *helium.Docum
 		return nil, err
 	}
 
-	// Canonicalize SignedInfo, honoring any ec:InclusiveNamespaces PrefixList
-	// declared on its CanonicalizationMethod (relevant for Exclusive C14N).
-	canonical, err := canonicalizeSubtree(ctx, parsed.c14nMethod, parsed.signedInfoElem, parsed.c14nPrefixes)
+	// Canonicalize SignedInfo, honoring the parameters declared on its
+	// CanonicalizationMethod (an ec:InclusiveNamespaces PrefixList for
+	// Exclusive C14N, c14n2:* parameters for Canonical XML 2.0).
+	canonical, err := canonicalizeSubtree(ctx, parsed.c14nMethod, parsed.signedInfoElem, parsed.c14nParams)
 	if err != nil {
 		return nil, err
 	}
@@ -617,11 +620,11 @@ func parseSignedInfo(ctx context.Context, budget *verifyBudget, elem *helium.Ele
 				return fmt.Errorf("%w: CanonicalizationMethod missing Algorithm", ErrInvalidSignature)
 			}
 			parsed.c14nMethod = alg
-			prefixes, err := parseCanonicalizationParameters(e, alg)
+			params, err := parseCanonicalizationParameters(e, alg)
 			if err != nil {
 				return err
 			}
-			parsed.c14nPrefixes = prefixes
+			parsed.c14nParams = params
 		case "SignatureMethod":
 			if sigMethodSeen {
 				return fmt.Errorf("%w: multiple SignatureMethod elements", ErrInvalidSignature)
@@ -733,15 +736,16 @@ func parseTransformList(ctx context.Context, transformsElem *helium.Element, max
 		}
 		// Validate the Transform's child elements by algorithm. For a supported
 		// transform those children are algorithm parameters; accepting an unknown
-		// one while processing as if it were absent would be fail-open. The only
-		// parameter helium honors is ec:InclusiveNamespaces under the exclusive
-		// c14n transforms; every other child — and ec:InclusiveNamespaces under a
-		// non-exclusive algorithm — is rejected fail-closed.
-		prefixes, err := parseInclusiveNamespaceParameters(te, alg, "Transform")
+		// one while processing as if it were absent would be fail-open. The
+		// parameters helium honors are ec:InclusiveNamespaces under the exclusive
+		// c14n transforms and the c14n2:* parameters under Canonical XML 2.0;
+		// every other child — and either kind under another algorithm — is
+		// rejected fail-closed.
+		params, err := parseC14NParameters(te, alg, "Transform")
 		if err != nil {
 			return nil, err
 		}
-		transforms = append(transforms, parsedTransform{algorithm: alg, prefixes: prefixes})
+		transforms = append(transforms, parsedTransform{algorithm: alg, prefixes: params.prefixes, c14n2: params.c14n2})
 	}
 	return transforms, nil
 }
@@ -844,13 +848,34 @@ func excInclusiveNamespacePrefixes(elem *helium.Element) ([]string, bool) {
 	return strings.Fields(pl), true
 }
 
-// parseCanonicalizationParameters extracts the ec:InclusiveNamespaces PrefixList
-// from a CanonicalizationMethod element and fails closed on any other child
-// element, which would be a canonicalization parameter we cannot honor. Silently
-// ignoring an unknown parameter would canonicalize SignedInfo differently from
-// what the signer intended, so it is rejected.
-func parseCanonicalizationParameters(elem *helium.Element, alg string) ([]string, error) {
-	return parseInclusiveNamespaceParameters(elem, alg, "CanonicalizationMethod")
+// parseCanonicalizationParameters extracts the parameters of a
+// CanonicalizationMethod element and fails closed on any child element that
+// is not a parameter of its algorithm, which would be a canonicalization
+// parameter we cannot honor. Silently ignoring an unknown parameter would
+// canonicalize SignedInfo differently from what the signer intended, so it is
+// rejected.
+func parseCanonicalizationParameters(elem *helium.Element, alg string) (*c14nParameters, error) {
+	return parseC14NParameters(elem, alg, "CanonicalizationMethod")
+}
+
+// parseC14NParameters validates the child elements of a CanonicalizationMethod
+// or Transform element as the parameters of alg: the c14n2:* parameters under
+// Canonical XML 2.0 (parseC14N20Parameters) and ec:InclusiveNamespaces under
+// every other algorithm (parseInclusiveNamespaceParameters). The result is
+// never nil.
+func parseC14NParameters(elem *helium.Element, alg, context string) (*c14nParameters, error) {
+	if alg == C14N20URI {
+		params, err := parseC14N20Parameters(elem, context)
+		if err != nil {
+			return nil, err
+		}
+		return &c14nParameters{c14n2: params}, nil
+	}
+	prefixes, err := parseInclusiveNamespaceParameters(elem, alg, context)
+	if err != nil {
+		return nil, err
+	}
+	return &c14nParameters{prefixes: prefixes}, nil
 }
 
 // parseInclusiveNamespaceParameters validates the child elements of a
@@ -906,6 +931,114 @@ func gateInclusiveNamespaces(alg string) error {
 	return nil
 }
 
+// parseC14N20Parameters reads the Canonical XML 2.0 parameters from the
+// c14n2:* children of a CanonicalizationMethod or Transform element. It is as
+// fail-closed as parseInclusiveNamespaceParameters: a child outside the c14n2
+// namespace, an unknown or repeated parameter, a value outside the ones the
+// specification defines, and the PrefixRewrite value "derived", which helium
+// does not implement, are all rejected rather than canonicalized as if absent.
+func parseC14N20Parameters(elem *helium.Element, context string) (*C14N20Parameters, error) {
+	var params C14N20Parameters
+	seen := make(map[string]struct{})
+	for c := elem.FirstChild(); c != nil; c = c.NextSibling() {
+		ce, ok := helium.AsNode[*helium.Element](c)
+		if !ok {
+			continue
+		}
+		name := domutil.LocalName(ce)
+		if !isC14N2NS(ce) {
+			return nil, fmt.Errorf("%w: unsupported %s parameter %s", ErrUnsupportedTransform, context, name)
+		}
+		if _, dup := seen[name]; dup {
+			return nil, fmt.Errorf("%w: multiple c14n2:%s under %s", ErrUnsupportedTransform, name, context)
+		}
+		seen[name] = struct{}{}
+		value := strings.TrimSpace(string(ce.Content()))
+		switch name {
+		case "IgnoreComments":
+			ignore, ok := parseXSBoolean(value)
+			if !ok {
+				return nil, fmt.Errorf("%w: invalid c14n2:IgnoreComments value %q", ErrUnsupportedTransform, value)
+			}
+			params.Comments = !ignore
+		case "TrimTextNodes":
+			trim, ok := parseXSBoolean(value)
+			if !ok {
+				return nil, fmt.Errorf("%w: invalid c14n2:TrimTextNodes value %q", ErrUnsupportedTransform, value)
+			}
+			params.TrimTextNodes = trim
+		case "PrefixRewrite":
+			switch value {
+			case "none":
+				params.PrefixRewrite = c14n.PrefixRewriteNone
+			case "sequential":
+				params.PrefixRewrite = c14n.PrefixRewriteSequential
+			default:
+				return nil, fmt.Errorf("%w: unsupported c14n2:PrefixRewrite value %q", ErrUnsupportedTransform, value)
+			}
+		case "QNameAware":
+			if err := parseC14N20QNameAware(ce, &params); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: unsupported %s parameter c14n2:%s", ErrUnsupportedTransform, context, name)
+		}
+	}
+	return &params, nil
+}
+
+// parseC14N20QNameAware adds the names listed by a c14n2:QNameAware parameter
+// to params. Each child names an element or attribute through required
+// attributes; a missing one is rejected.
+func parseC14N20QNameAware(elem *helium.Element, params *C14N20Parameters) error {
+	for c := elem.FirstChild(); c != nil; c = c.NextSibling() {
+		ce, ok := helium.AsNode[*helium.Element](c)
+		if !ok {
+			continue
+		}
+		name := domutil.LocalName(ce)
+		if !isC14N2NS(ce) {
+			return fmt.Errorf("%w: unsupported c14n2:QNameAware child %s", ErrUnsupportedTransform, name)
+		}
+		attrs := []string{"Name", "NS"}
+		if name == "UnqualifiedAttr" {
+			attrs = []string{"Name", "ParentName", "ParentNS"}
+		}
+		values := make([]string, len(attrs))
+		for i, attr := range attrs {
+			v, ok := ce.GetAttribute(attr)
+			if !ok {
+				return fmt.Errorf("%w: c14n2:%s missing %s", ErrUnsupportedTransform, name, attr)
+			}
+			values[i] = v
+		}
+		switch name {
+		case "Element":
+			params.QNameAwareElements = append(params.QNameAwareElements, C14N20Name{Name: values[0], NS: values[1]})
+		case "QualifiedAttr":
+			params.QNameAwareQualifiedAttrs = append(params.QNameAwareQualifiedAttrs, C14N20Name{Name: values[0], NS: values[1]})
+		case "XPathElement":
+			params.QNameAwareXPathElements = append(params.QNameAwareXPathElements, C14N20Name{Name: values[0], NS: values[1]})
+		case "UnqualifiedAttr":
+			params.QNameAwareUnqualifiedAttrs = append(params.QNameAwareUnqualifiedAttrs, C14N20UnqualifiedAttr{Name: values[0], ParentName: values[1], ParentNS: values[2]})
+		default:
+			return fmt.Errorf("%w: unsupported c14n2:QNameAware child %s", ErrUnsupportedTransform, name)
+		}
+	}
+	return nil
+}
+
+// parseXSBoolean parses the xs:boolean lexical forms a parameter value takes.
+func parseXSBoolean(s string) (bool, bool) {
+	switch s {
+	case "true", "1":
+		return true, true
+	case "false", "0":
+		return false, true
+	}
+	return false, false
+}
+
 // maxXPathFilterExpressionBytes bounds the length of one ds:Transform/XPath
 // filter expression. parseXPathTransform applies it, so an over-length
 // expression is refused where it is read off the document and never reaches the
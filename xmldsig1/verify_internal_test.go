@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/c14n"
 	"github.com/lestrrat-go/helium/internal/domutil"
 	"github.com/stretchr/testify/require"
 )
@@ -806,7 +807,7 @@ func TestCloneNilConfig(t *testing.T) {
 // SignatureValue check.
 func reSignSignedInfo(t *testing.T, doc *helium.Document, sigElem, signedInfo *helium.Element, prefixes []string, key *rsa.PrivateKey) {
 	t.Helper()
-	canonical, err := canonicalizeSubtree(t.Context(), ExcC14N10, signedInfo, prefixes)
+	canonical, err := canonicalizeSubtree(t.Context(), ExcC14N10, signedInfo, &c14nParameters{prefixes: prefixes})
 	require.NoError(t, err)
 	sigBytes, err := signBytes(AlgRSASHA256, key, canonical, false)
 	require.NoError(t, err)
@@ -1061,7 +1062,82 @@ func TestVerifyAcceptsInclusiveNamespacesOnExclusiveC14N(t *testing.T) {
 			doc := mustParse(t, si)
 			var parsed parsedSignature
 			require.NoError(t, parseSignedInfo(context.Background(), testVerifyBudget(), doc.DocumentElement(), &parsed))
-			require.Equal(t, []string{"extra", "ns2"}, parsed.c14nPrefixes)
+			require.Equal(t, []string{"extra", "ns2"}, parsed.c14nParams.prefixes)
 		})
 	}
 }
+
+// TestVerifyC14N20Parameters covers the c14n2:* parameters of a Canonical XML
+// 2.0 CanonicalizationMethod: they are read into C14N20Parameters, and any
+// parameter helium cannot honor is rejected as fail-closed as
+// ec:InclusiveNamespaces is.
+func TestVerifyC14N20Parameters(t *testing.T) {
+	parse := func(t *testing.T, params string) (parsedSignature, error) {
+		t.Helper()
+		si := `<ds:SignedInfo xmlns:ds="` + dsigNS + `" xmlns:c14n2="` + C14N20URI + `">` +
+			`<ds:CanonicalizationMethod Algorithm="` + C14N20URI + `">` + params + `</ds:CanonicalizationMethod>` +
+			`<ds:SignatureMethod Algorithm="` + AlgRSASHA256 + `"/>` +
+			`<ds:Reference URI="">` +
+			`<ds:DigestMethod Algorithm="` + DigestSHA256 + `"/>` +
+			`<ds:DigestValue>AA==</ds:DigestValue>` +
+			`</ds:Reference>` +
+			`</ds:SignedInfo>`
+		doc := mustParse(t, si)
+		var parsed parsedSignature
+		err := parseSignedInfo(context.Background(), testVerifyBudget(), doc.DocumentElement(), &parsed)
+		return parsed, err
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		parsed, err := parse(t, ``)
+		require.NoError(t, err)
+		require.Equal(t, &C14N20Parameters{}, parsed.c14nParams.c14n2)
+	})
+
+	t.Run("all parameters", func(t *testing.T) {
+		parsed, err := parse(t, `<c14n2:IgnoreComments> false </c14n2:IgnoreComments>`+
+			`<c14n2:TrimTextNodes>1</c14n2:TrimTextNodes>`+
+			`<c14n2:PrefixRewrite>sequential</c14n2:PrefixRewrite>`+
+			`<c14n2:QNameAware>`+
+			`<c14n2:Element Name="e" NS="urn:e"/>`+
+			`<c14n2:QualifiedAttr Name="type" NS="urn:xsi"/>`+
+			`<c14n2:UnqualifiedAttr Name="ref" ParentName="p" ParentNS=""/>`+
+			`<c14n2:XPathElement Name="XPath" NS="`+dsigNS+`"/>`+
+			`</c14n2:QNameAware>`)
+		require.NoError(t, err)
+		require.Equal(t, &C14N20Parameters{
+			Comments:                   true,
+			TrimTextNodes:              true,
+			PrefixRewrite:              c14n.PrefixRewriteSequential,
+			QNameAwareElements:         []C14N20Name{{Name: "e", NS: "urn:e"}},
+			QNameAwareQualifiedAttrs:   []C14N20Name{{Name: "type", NS: "urn:xsi"}},
+			QNameAwareUnqualifiedAttrs: []C14N20UnqualifiedAttr{{Name: "ref", ParentName: "p"}},
+			QNameAwareXPathElements:    []C14N20Name{{Name: "XPath", NS: dsigNS}},
+		}, parsed.c14nParams.c14n2)
+	})
+
+	rejected := map[string]string{
+		"derived prefix rewrite": `<c14n2:PrefixRewrite>derived</c14n2:PrefixRewrite>`,
+		"invalid boolean":        `<c14n2:TrimTextNodes>yes</c14n2:TrimTextNodes>`,
+		"repeated parameter":     `<c14n2:TrimTextNodes>true</c14n2:TrimTextNodes><c14n2:TrimTextNodes>false</c14n2:TrimTextNodes>`,
+		"unknown parameter":      `<c14n2:Unknown/>`,
+		"foreign parameter":      `<x:TrimTextNodes xmlns:x="urn:x">true</x:TrimTextNodes>`,
+		"inclusive namespaces":   `<ec:InclusiveNamespaces xmlns:ec="` + ExcC14N10 + `" PrefixList="a"/>`,
+		"missing NS":             `<c14n2:QNameAware><c14n2:Element Name="e"/></c14n2:QNameAware>`,
+		"unknown QName-aware":    `<c14n2:QNameAware><c14n2:Text Name="e" NS=""/></c14n2:QNameAware>`,
+	}
+	for name, params := range rejected {
+		t.Run(name, func(t *testing.T) {
+			_, err := parse(t, params)
+			require.ErrorIs(t, err, ErrUnsupportedTransform)
+		})
+	}
+
+	t.Run("parameters under another algorithm", func(t *testing.T) {
+		doc := mustParse(t, `<ds:Transforms xmlns:ds="`+dsigNS+`"><ds:Transform Algorithm="`+ExcC14N10+`">`+
+			`<c14n2:TrimTextNodes xmlns:c14n2="`+C14N20URI+`">true</c14n2:TrimTextNodes>`+
+			`</ds:Transform></ds:Transforms>`)
+		_, err := parseTransformList(context.Background(), doc.DocumentElement(), 0)
+		require.ErrorIs(t, err, ErrUnsupportedTransform)
+	})
+}
@@ -58,6 +58,7 @@ func NewEnvelopedReferenceByID(id string) ReferenceConfig {
 type signerConfig struct {
 	signatureAlgorithm string
 	c14nMethod         string
+	c14n2              *C14N20Parameters
 	references         []ReferenceConfig
 	keyInfoBuilder     KeyInfoBuilder
 	signatureID        string
@@ -126,9 +127,23 @@ func (s Signer) SignatureAlgorithm(alg string) Signer {
 }
 
 // CanonicalizationMethod sets the canonicalization algorithm for SignedInfo.
+// [C14N20URI] selects Canonical XML 2.0 with its default parameters; see
+// [Signer.C14N20Canonicalization] to set them.
 func (s Signer) CanonicalizationMethod(method string) Signer {
 	s = s.clone()
 	s.cfg.c14nMethod = method
+	s.cfg.c14n2 = nil
+	return s
+}
+
+// C14N20Canonicalization canonicalizes SignedInfo with Canonical XML 2.0
+// under params, which are written as c14n2:* children of the
+// CanonicalizationMethod element. The parameters are copied.
+func (s Signer) C14N20Canonicalization(params C14N20Parameters) Signer {
+	s = s.clone()
+	s.cfg.c14nMethod = C14N20URI
+	params = params.clone()
+	s.cfg.c14n2 = &params
 	return s
 }
 
@@ -743,6 +758,13 @@ func isExcC14NNS(e *helium.Element) bool {
 	return elementNamespaceURI(e) == ExcC14N10
 }
 
+// isC14N2NS reports whether e is in the Canonical XML 2.0 namespace
+// (http://www.w3.org/2010/xml-c14n2), which holds the parameters of the
+// algorithm of the same URI.
+func isC14N2NS(e *helium.Element) bool {
+	return elementNamespaceURI(e) == C14N20URI
+}
+
 func elementNamespaceURI(e *helium.Element) string {
 	name := e.Name()
 	prefix := ""
@@ -535,6 +535,22 @@ func TestCipherReference(t *testing.T) {
 		requireSecret(t, nodes)
 	})
 
+	t.Run("an external URI resolves through ResourceReferenceResolver", func(t *testing.T) {
+		sessionKey := newSessionKey(t)
+		resources := helium.MemoryResourceResolver(map[string][]byte{externalCipherPath: cipherRefCiphertext(t, sessionKey)})
+		elem := cipherRefDoc(t, cipherReferenceXML(externalCipherURI, ""), "")
+		nodes, err := xmlenc1.NewDecryptor().
+			SessionKey(sessionKey).
+			CipherReferenceResolver(xmlenc1.ResourceReferenceResolver(resources)).
+			Decrypt(t.Context(), elem)
+		require.NoError(t, err)
+		requireSecret(t, nodes)
+
+		_, err = xmlenc1.ResourceReferenceResolver(resources).ResolveReference(t.Context(), "missing.bin")
+		require.ErrorIs(t, err, xmlenc1.ErrReferenceNotFound)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
 	// A same-document reference never reaches the resolver, so configuring one
 	// changes nothing about how those four forms resolve.
 	t.Run("a resolver does not change same-document resolution", func(t *testing.T) {
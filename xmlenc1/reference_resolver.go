@@ -115,6 +115,31 @@ func FSReferenceResolver(fsys fs.FS, root string) ReferenceResolver {
 	return fsReferenceResolver{fsys: fsys, root: root}
 }
 
+// ResourceReferenceResolver returns a [ReferenceResolver] that serves external
+// CipherReference URIs through r, so the resolver a caller already uses to
+// sandbox parsing and schema loading governs cipher text too. The joined URI
+// is handed to r unchanged, and the resource is returned as the stream; the
+// decrypt's allowance still bounds how much of it is read. An error from r is
+// wrapped in [ErrReferenceNotFound].
+func ResourceReferenceResolver(r helium.ResourceResolver) ReferenceResolver {
+	return resourceReferenceResolver{r: r}
+}
+
+type resourceReferenceResolver struct {
+	r helium.ResourceResolver
+}
+
+func (r resourceReferenceResolver) ResolveReference(ctx context.Context, uri string) (io.ReadCloser, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+	res, err := r.r.ResolveResource(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot open external reference %q: %w", ErrReferenceNotFound, uri, err)
+	}
+	return res, nil
+}
+
 // fsReferenceResolver resolves external CipherReference URIs as
 // slash-separated paths inside a fs.FS, below a declared document-space root.
 type fsReferenceResolver struct {
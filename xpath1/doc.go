@@ -24,6 +24,15 @@
 // [*Result] contains a type discriminant ([Result.Type]) and typed fields:
 // [Result.NodeSet], [Result.Bool], [Result.Number], [Result.String].
 //
+// # Document Indexes
+//
+// When a document's index is enabled with helium.Document.EnableIndex, the
+// evaluator answers //name and descendant::name from it instead of walking
+// the document. A leading predicate of the form [@attr = value] or
+// [text() = value], where value is a string literal or a variable holding a
+// string or a node-set, is looked up by value as well. Steps whose
+// predicates depend on position, such as //item[1], are walked as before.
+//
 // # Examples
 //
 // Example code for this package lives in the examples/ directory at the
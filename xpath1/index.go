@@ -0,0 +1,320 @@
+package xpath1
+
+import (
+	"context"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+	ixpath "github.com/lestrrat-go/helium/internal/xpath"
+)
+
+// evalIndexedSteps answers the leading steps of a location path from the
+// document index when nodes is a single document whose index is enabled (see
+// helium.Document.EnableIndex). It handles //name and descendant::name with
+// position-independent predicates, and looks up a leading [@attr = value] or
+// [text() = value] predicate by value. It returns the number of steps it
+// consumed, which is zero when the steps have to be evaluated by walking.
+func evalIndexedSteps(ctx context.Context, ec *evalContext, nodes []helium.Node, steps []Step) ([]helium.Node, int, error) {
+	if len(nodes) != 1 {
+		return nil, 0, nil
+	}
+	doc, ok := nodes[0].(*helium.Document)
+	if !ok {
+		return nil, 0, nil
+	}
+	idx := doc.Index()
+	if idx == nil {
+		return nil, 0, nil
+	}
+	step, test, consumed := indexableStep(steps)
+	if consumed == 0 {
+		return nil, 0, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	uri, ok := elementTestURI(test, ec)
+	if !ok {
+		// An unbound prefix matches no element.
+		return nil, consumed, nil
+	}
+
+	preds := step.Predicates
+	var matched []helium.Node
+	looked := false
+	if len(preds) > 0 {
+		var err error
+		matched, looked, err = lookupPredicate(ctx, ec, idx, uri, test.Local, preds[0])
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if looked {
+		preds = preds[1:]
+	} else {
+		elems := idx.Elements(uri, test.Local)
+		matched = make([]helium.Node, len(elems))
+		for i, e := range elems {
+			matched[i] = e
+		}
+	}
+	if err := ec.countOps(len(matched)); err != nil {
+		return nil, 0, err
+	}
+	var err error
+	for _, pred := range preds {
+		matched, err = applyPredicate(ctx, ec, matched, pred)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if len(matched) > maxNodeSetLength {
+		return nil, 0, ErrNodeSetLimit
+	}
+	return matched, consumed, nil
+}
+
+// indexableStep returns the element step that selects the same nodes from
+// the document as the leading steps, its name test, and how many steps that
+// is: two for //name, which is descendant-or-self::node()/child::name, and
+// one for descendant::name and descendant-or-self::name. Predicates of the
+// step must not depend on the position of a node, which differs between the
+// siblings //name counts among and the flat list the index returns.
+func indexableStep(steps []Step) (Step, NameTest, int) {
+	if len(steps) == 0 {
+		return Step{}, NameTest{}, 0
+	}
+	step, consumed := steps[0], 1
+	if tt, ok := step.NodeTest.(TypeTest); ok && tt.Type == NodeTestNode && step.Axis == AxisDescendantOrSelf && len(step.Predicates) == 0 {
+		if len(steps) < 2 || steps[1].Axis != AxisChild {
+			return Step{}, NameTest{}, 0
+		}
+		step, consumed = steps[1], 2
+	} else if step.Axis != AxisDescendant && step.Axis != AxisDescendantOrSelf {
+		return Step{}, NameTest{}, 0
+	}
+	test, ok := step.NodeTest.(NameTest)
+	if !ok || test.Local == "*" {
+		return Step{}, NameTest{}, 0
+	}
+	for _, pred := range step.Predicates {
+		if !positionFreePredicate(pred) {
+			return Step{}, NameTest{}, 0
+		}
+	}
+	return step, test, consumed
+}
+
+// positionFreePredicate reports whether pred selects a node regardless of its
+// position: it cannot evaluate to a number, and neither it nor the
+// expressions it evaluates in the same context call position() or last().
+func positionFreePredicate(pred Expr) bool {
+	switch e := pred.(type) {
+	case BinaryExpr:
+		switch e.Op {
+		case TokenOr, TokenAnd, TokenEquals, TokenNotEquals, TokenLess, TokenLessEq, TokenGreater, TokenGreaterEq:
+			return positionFree(e)
+		}
+		return false
+	case FunctionCall:
+		switch e.Name {
+		case "count", "sum", "floor", "ceiling", "round", "number", "string-length":
+			return false
+		}
+		return positionFree(e)
+	case *LocationPath, PathExpr, UnionExpr, FilterExpr, LiteralExpr:
+		return positionFree(e)
+	}
+	return false
+}
+
+// positionFree reports whether expr, evaluated for a node, does not depend on
+// the node's position or the size of its node set. Predicates nested in
+// location paths and filters have a context of their own and may.
+func positionFree(expr Expr) bool {
+	switch e := expr.(type) {
+	case *LocationPath, LiteralExpr, NumberExpr, VariableExpr:
+		return true
+	case BinaryExpr:
+		return positionFree(e.Left) && positionFree(e.Right)
+	case UnaryExpr:
+		return positionFree(e.Operand)
+	case UnionExpr:
+		return positionFree(e.Left) && positionFree(e.Right)
+	case FilterExpr:
+		return positionFree(e.Expr)
+	case PathExpr:
+		return positionFree(e.Filter)
+	case FunctionCall:
+		// Functions other than the built-ins can read the position from
+		// their FunctionContext.
+		if e.Prefix != "" || e.Name == "position" || e.Name == "last" {
+			return false
+		}
+		if _, ok := builtinFunctions[e.Name]; !ok {
+			return false
+		}
+		for _, arg := range e.Args {
+			if !positionFree(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// lookupPredicate answers pred, the first predicate of a //name step, from
+// the index when it is @attr = value or text() = value, where value is a
+// string literal, or a variable holding a string or a node set. It returns
+// the elements named {uri}local that satisfy it, in document order, and
+// whether pred was answered.
+func lookupPredicate(ctx context.Context, ec *evalContext, idx *helium.Index, uri, local string, pred Expr) ([]helium.Node, bool, error) {
+	e, ok := pred.(BinaryExpr)
+	if !ok || e.Op != TokenEquals {
+		return nil, false, nil
+	}
+	operand, valueExpr := e.Left, e.Right
+	step, ok := singleChildStep(operand)
+	if !ok {
+		operand, valueExpr = e.Right, e.Left
+		if step, ok = singleChildStep(operand); !ok {
+			return nil, false, nil
+		}
+	}
+	values, ok := lookupValues(ec, valueExpr)
+	if !ok {
+		return nil, false, nil
+	}
+
+	matchesName := func(n helium.Node) bool {
+		return ixpath.NodeNamespaceURI(n) == uri && ixpath.LocalNameOf(n) == local
+	}
+	switch test := step.NodeTest.(type) {
+	case NameTest:
+		if step.Axis != AxisAttribute || test.Local == "*" {
+			return nil, false, nil
+		}
+		attrURI, ok := attributeTestURI(test, ec)
+		if !ok {
+			return []helium.Node{}, true, nil
+		}
+		if len(values) == 1 {
+			elems := idx.ElementsByAttribute(attrURI, test.Local, values[0])
+			matched := make([]helium.Node, 0, len(elems))
+			for _, el := range elems {
+				if matchesName(el) {
+					matched = append(matched, el)
+				}
+			}
+			return matched, true, nil
+		}
+		found := make(map[helium.Node]struct{})
+		for _, v := range values {
+			for _, el := range idx.ElementsByAttribute(attrURI, test.Local, v) {
+				found[el] = struct{}{}
+			}
+		}
+		return keepFound(ctx, idx.Elements(uri, local), found)
+	case TypeTest:
+		if step.Axis != AxisChild || test.Type != NodeTestText {
+			return nil, false, nil
+		}
+		found := make(map[helium.Node]struct{})
+		for _, v := range values {
+			for _, t := range idx.TextNodes(v) {
+				if p := t.Parent(); p != nil && matchesName(p) {
+					found[p] = struct{}{}
+				}
+			}
+		}
+		return keepFound(ctx, idx.Elements(uri, local), found)
+	}
+	return nil, false, nil
+}
+
+// keepFound returns the elements of elems that are in found, keeping the
+// document order of elems.
+func keepFound(ctx context.Context, elems []*helium.Element, found map[helium.Node]struct{}) ([]helium.Node, bool, error) {
+	matched := make([]helium.Node, 0, len(found))
+	if len(found) == 0 {
+		return matched, true, nil
+	}
+	for _, e := range elems {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		if _, ok := found[e]; ok {
+			matched = append(matched, e)
+		}
+	}
+	return matched, true, nil
+}
+
+// singleChildStep returns the step of expr when it is a relative location
+// path of one step without predicates, such as @sku or text().
+func singleChildStep(expr Expr) (Step, bool) {
+	lp, ok := expr.(*LocationPath)
+	if !ok || lp.Absolute || len(lp.Steps) != 1 || len(lp.Steps[0].Predicates) != 0 {
+		return Step{}, false
+	}
+	return lp.Steps[0], true
+}
+
+// lookupValues returns the strings a node set is compared with by expr: the
+// literal, the string held by a variable, or the string-values of the nodes
+// held by a variable. Numbers and booleans compare differently and are not
+// looked up, and neither is a variable that fails to evaluate, so that the
+// predicate reports the error as it would without the index.
+func lookupValues(ec *evalContext, expr Expr) ([]string, bool) {
+	switch e := expr.(type) {
+	case LiteralExpr:
+		return []string{e.Value}, true
+	case VariableExpr:
+		r, err := evalVariableExpr(ec, e)
+		if err != nil {
+			return nil, false
+		}
+		switch r.Type {
+		case StringResult:
+			return []string{r.String}, true
+		case NodeSetResult:
+			values := make([]string, len(r.NodeSet))
+			for i, n := range r.NodeSet {
+				values[i] = ixpath.StringValue(n)
+			}
+			return values, true
+		}
+	}
+	return nil, false
+}
+
+// elementTestURI returns the namespace URI an element name test matches, as
+// matchNameTestByLocalAndPrefix resolves it, and false for an unbound
+// prefix.
+func elementTestURI(test NameTest, ec *evalContext) (string, bool) {
+	if test.Prefix == "" {
+		return ec.defaultElemNS, true
+	}
+	return prefixURI(test.Prefix, ec)
+}
+
+// attributeTestURI is elementTestURI for an attribute name test, which has
+// no default namespace.
+func attributeTestURI(test NameTest, ec *evalContext) (string, bool) {
+	if test.Prefix == "" {
+		return "", true
+	}
+	return prefixURI(test.Prefix, ec)
+}
+
+// prefixURI resolves prefix the way matchPrefix does.
+func prefixURI(prefix string, ec *evalContext) (string, bool) {
+	if uri, ok := ec.namespaces[prefix]; ok {
+		return uri, true
+	}
+	if prefix == lexicon.PrefixXML {
+		return lexicon.NamespaceXML, true
+	}
+	return "", false
+}
@@ -0,0 +1,128 @@
+package xpath1_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xpath1"
+	"github.com/stretchr/testify/require"
+)
+
+const indexTestDoc = `<doc xmlns:p="urn:p">
+  <item sku="A" kind="x">first</item>
+  <p:item p:sku="A">second</p:item>
+  <group>
+    <item sku="B">first</item>
+    <item sku="A" kind="y"><![CDATA[third]]></item>
+  </group>
+  <ref sku="B"/>
+</doc>`
+
+// TestIndexedEvaluation checks that expressions give the same node sets with
+// the document index enabled as without it.
+func TestIndexedEvaluation(t *testing.T) {
+	doc := parseXML(t, indexTestDoc)
+	refs, err := xpath1.Find(t.Context(), doc, "//ref")
+	require.NoError(t, err)
+	eval := xpath1.NewEvaluator().
+		Namespaces(map[string]string{"p": "urn:p"}).
+		Variables(map[string]any{"one": "B", "refs": refs, "num": float64(1)})
+
+	exprs := []string{
+		"//item",
+		"//p:item",
+		"//u:item",
+		"//item[@sku='A']",
+		"//item['A'=@sku]",
+		"//p:item[@p:sku='A']",
+		"//item[@sku=$one]",
+		"//item[@sku=$refs/@sku]",
+		"//item[@sku=$num]",
+		"//item[@sku='A'][@kind='y']",
+		"//item[@kind][@sku='A']",
+		"//item[text()='first']",
+		"//item[text()='third']",
+		"//item[.='first']",
+		"//item[1]",
+		"//item[last()]",
+		"//item[@sku='A' and position()=1]",
+		"//item[count(@*)]",
+		"//item[count(@*) = 2]",
+		"//item[not(@kind)]",
+		"//item[@sku = //ref/@sku]",
+		"//item/@sku",
+		"//group/item[@sku='B']",
+		"descendant::item[@sku='A']",
+		"descendant-or-self::item",
+		"count(//item[@sku='A'])",
+	}
+	want := make([]*xpath1.Result, len(exprs))
+	for i, expr := range exprs {
+		r, err := eval.Evaluate(t.Context(), xpath1.MustCompile(expr), doc)
+		require.NoError(t, err, expr)
+		want[i] = r
+	}
+	doc.EnableIndex()
+	for i, expr := range exprs {
+		r, err := eval.Evaluate(t.Context(), xpath1.MustCompile(expr), doc)
+		require.NoError(t, err, expr)
+		require.Equal(t, want[i], r, expr)
+	}
+}
+
+func TestIndexedEvaluationMutation(t *testing.T) {
+	doc := parseXML(t, indexTestDoc)
+	doc.EnableIndex()
+	expr := xpath1.MustCompile("//item[@sku='C']")
+
+	nodes, err := xpath1.NewEvaluator().Find(t.Context(), expr, doc)
+	require.NoError(t, err)
+	require.Empty(t, nodes)
+
+	first := doc.DocumentElement().FirstChild().NextSibling().(*helium.Element)
+	require.NoError(t, first.SetAttribute("sku", "C"))
+	nodes, err = xpath1.NewEvaluator().Find(t.Context(), expr, doc)
+	require.NoError(t, err)
+	require.Equal(t, []helium.Node{first}, nodes)
+}
+
+// TestIndexedEvaluationSetNamespace checks that a namespace changed through
+// SetNamespace, which reports no error, still invalidates the index.
+func TestIndexedEvaluationSetNamespace(t *testing.T) {
+	doc := parseXML(t, `<r xmlns:a="urn:a"><item/></r>`)
+	doc.EnableIndex()
+	nodes, err := xpath1.Find(t.Context(), doc, "//item")
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+
+	root := doc.DocumentElement()
+	item := root.FirstChild().(*helium.Element)
+	item.SetNamespace(root.Namespaces()[0])
+	nodes, err = xpath1.Find(t.Context(), doc, "//item")
+	require.NoError(t, err)
+	require.Empty(t, nodes)
+}
+
+// TestIndexedEvaluationOpLimit checks that an indexed lookup is charged for
+// the nodes it returns rather than for a walk of the document.
+func TestIndexedEvaluationOpLimit(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("<doc>")
+	for i := range 1000 {
+		fmt.Fprintf(&b, `<item sku="%d"/>`, i)
+	}
+	b.WriteString("</doc>")
+	doc := parseXML(t, b.String())
+	expr := xpath1.MustCompile("//item[@sku='500']")
+	eval := xpath1.NewEvaluator().OpLimit(100)
+
+	_, err := eval.Find(t.Context(), expr, doc)
+	require.ErrorIs(t, err, xpath1.ErrOpLimit)
+
+	doc.EnableIndex()
+	nodes, err := eval.Find(t.Context(), expr, doc)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+}
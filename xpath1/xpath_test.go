@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/lestrrat-go/helium"
 	"github.com/lestrrat-go/helium/xpath1"
 	"github.com/stretchr/testify/require"
 )
@@ -159,3 +160,23 @@ func TestRootStringConversion(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 43.0, r.Number)
 }
+
+func TestFrozenDocument(t *testing.T) {
+	doc := parseXML(t, `<root xmlns:p="urn:x"><p:item n="1">a</p:item><p:item n="2">b</p:item><!-- c --></root>`)
+	frozen, err := helium.Freeze(doc)
+	require.NoError(t, err)
+
+	eval := xpath1.NewEvaluator().Namespaces(map[string]string{"p": nsURIX})
+	for expr, want := range map[string]string{
+		`string(//p:item[@n="2"])`:             "b",
+		`name(/root/*[last()])`:                "p:item",
+		`string(count(//namespace::p))`:        "3",
+		`string(//comment())`:                  " c ",
+		`string(sum(//@n))`:                    "3",
+		`local-name(//p:item[1]/following::*)`: "item",
+	} {
+		res, err := eval.Evaluate(t.Context(), xpath1.MustCompile(expr), frozen)
+		require.NoError(t, err, expr)
+		require.Equal(t, want, res.String, expr)
+	}
+}
@@ -39,6 +39,15 @@
 // array: namespaces. Custom functions can be registered via
 // [Evaluator.Functions] or [Evaluator.FunctionResolver].
 //
+// # Document Indexes
+//
+// When a document's index is enabled with helium.Document.EnableIndex, the
+// evaluator answers //name and descendant::name from it instead of walking
+// the document, as long as the step's predicates test attributes only, as
+// in //item[@sku = 'X'] or //item[@kind]. A leading comparison with a string
+// literal is looked up by value, unless the evaluation carries schema type
+// annotations.
+//
 // # Examples
 //
 // Example code for this package lives in the examples/ directory at the
@@ -53,8 +53,9 @@ type evalContext struct {
 	defaultDecimalFormat *DecimalFormat
 	decimalFormats       map[QualifiedName]DecimalFormat
 	docCache             map[string]helium.Node
-	baseURI              string      // static base URI for resolving relative URIs
-	uriResolver          URIResolver // custom URI resolver for fn:unparsed-text, fn:doc, etc.
+	baseURI              string                  // static base URI for resolving relative URIs
+	uriResolver          URIResolver             // custom URI resolver for fn:unparsed-text, fn:doc, etc.
+	resourceResolver     helium.ResourceResolver // replaces uriResolver when set
 	collectionResolver   CollectionResolver
 	// httpClient is intentionally stored here (not only in Context) so that
 	// built-in functions can access it through getFnContext without an extra
@@ -178,8 +178,18 @@ func evalVMLocationPath(evalFn exprEvaluator, ctx context.Context, ec *evalConte
 		nodes = []helium.Node{ec.node}
 	}
 
-	var err error
-	for _, step := range lp.Steps {
+	steps := lp.Steps
+	for len(steps) > 0 {
+		indexed, consumed, err := evalIndexedSteps(evalFn, ctx, ec, nodes, steps)
+		if err != nil {
+			return nil, err
+		}
+		if consumed > 0 {
+			nodes, steps = indexed, steps[consumed:]
+			continue
+		}
+		step := steps[0]
+		steps = steps[1:]
 		if len(step.Predicates) > 0 {
 			nodes, err = evalVMStepWithPredicates(evalFn, ctx, ec, nodes, step)
 		} else {
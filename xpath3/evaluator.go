@@ -48,6 +48,7 @@ type evaluatorCfg struct {
 	decimalFormats         map[QualifiedName]DecimalFormat
 	baseURI                string
 	uriResolver            URIResolver
+	resourceResolver       helium.ResourceResolver
 	collectionResolver     CollectionResolver
 	httpClient             *http.Client
 	position               int
@@ -238,6 +239,15 @@ func (e Evaluator) URIResolver(r URIResolver) Evaluator {
 	return e
 }
 
+// ResourceResolver sets a [helium.ResourceResolver] for fn:unparsed-text,
+// fn:doc, fn:json-doc, etc. Unlike a [URIResolver], it receives the ctx of
+// the evaluation. It takes precedence over [Evaluator.URIResolver].
+func (e Evaluator) ResourceResolver(r helium.ResourceResolver) Evaluator {
+	e = e.clone()
+	e.cfg.resourceResolver = r
+	return e
+}
+
 // CollectionResolver sets a custom resolver for fn:collection.
 func (e Evaluator) CollectionResolver(r CollectionResolver) Evaluator {
 	e = e.clone()
@@ -473,6 +483,7 @@ func (e Evaluator) newEvalCtx(node helium.Node) *evalContext {
 	ec.variableResolver = cfg.variableResolver
 	ec.functionResolver = cfg.functionResolver
 	ec.uriResolver = cfg.uriResolver
+	ec.resourceResolver = cfg.resourceResolver
 	ec.collectionResolver = cfg.collectionResolver
 	ec.httpClient = cfg.httpClient
 	ec.maxResourceBytes = cfg.maxResourceBytes
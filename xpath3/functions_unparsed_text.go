@@ -3,6 +3,9 @@ package xpath3
 import (
 	"context"
 	"errors"
+	"io"
+
+	"github.com/lestrrat-go/helium"
 
 	"github.com/lestrrat-go/helium/internal/unparsedtext"
 )
@@ -25,12 +28,29 @@ func unparsedTextConfig(ctx context.Context) *unparsedtext.Config {
 		HTTPClient: ec.httpClient,
 		MaxBytes:   ec.maxResourceBytes,
 	}
-	if ec.uriResolver != nil {
+	if ec.resourceResolver != nil {
+		cfg.URIResolver = resourceURIResolver{ctx: ctx, r: ec.resourceResolver}
+	} else if ec.uriResolver != nil {
 		cfg.URIResolver = ec.uriResolver
 	}
 	return cfg
 }
 
+// resourceURIResolver adapts a [helium.ResourceResolver] to a [URIResolver]
+// for one function call, whose ctx it carries.
+type resourceURIResolver struct {
+	ctx context.Context //nolint:containedctx // scoped to a single function call
+	r   helium.ResourceResolver
+}
+
+func (a resourceURIResolver) ResolveURI(uri string) (io.ReadCloser, error) {
+	res, err := a.r.ResolveResource(a.ctx, uri)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // unparsedtext maps the error to an XPath error code
+	}
+	return res, nil
+}
+
 func wrapUnparsedTextError(err error) error {
 	if err == nil {
 		return nil
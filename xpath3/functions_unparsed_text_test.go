@@ -1,10 +1,12 @@
 package xpath3_test
 
 import (
+	"context"
 	"io"
 	"strings"
 	"testing"
 
+	"github.com/lestrrat-go/helium"
 	"github.com/lestrrat-go/helium/xpath3"
 	"github.com/stretchr/testify/require"
 )
@@ -101,3 +103,35 @@ func TestFnUnparsedTextLines_WithinMaxNodes(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, lines, res.Sequence().Len())
 }
+
+// A ResourceResolver receives the ctx of the evaluation and takes precedence
+// over a URIResolver.
+func TestFnUnparsedText_ResourceResolver(t *testing.T) {
+	type ctxKey struct{}
+	mem := helium.MemoryResourceResolver(map[string][]byte{"http://example.com/a.txt": []byte("from resolver")})
+	var seen any
+	r := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+		seen = ctx.Value(ctxKey{})
+		return mem.ResolveResource(ctx, uri)
+	})
+
+	compiled, err := xpath3.NewCompiler().Compile("unparsed-text('http://example.com/a.txt')")
+	require.NoError(t, err)
+
+	ctx := context.WithValue(t.Context(), ctxKey{}, "v")
+	res, err := xpath3.NewEvaluator(xpath3.DefaultEvaluatorOptions).
+		URIResolver(stringURIResolver{content: "from URIResolver"}).
+		ResourceResolver(r).
+		Evaluate(ctx, compiled, nil)
+	require.NoError(t, err)
+	s, ok := res.IsString()
+	require.True(t, ok)
+	require.Equal(t, "from resolver", s)
+	require.Equal(t, "v", seen)
+
+	state := xpath3.NewEvaluator(xpath3.DefaultEvaluatorOptions).ResourceResolver(r).NewEvalState(nil)
+	seen = nil
+	_, err = compiled.EvaluateReuse(ctx, state, nil)
+	require.NoError(t, err)
+	require.Equal(t, "v", seen)
+}
@@ -0,0 +1,155 @@
+package xpath3
+
+import (
+	"context"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/lexicon"
+)
+
+// evalIndexedSteps answers the leading steps of a compiled location path
+// from the document index when nodes is a single document whose index is
+// enabled (see helium.Document.EnableIndex). It handles //name and
+// descendant::name whose predicates are all [@attr = 'value'] or [@attr],
+// and looks up the first [@attr = 'value'] by value. It returns the number of steps it consumed,
+// which is zero when the steps have to be evaluated by walking.
+func evalIndexedSteps(evalFn exprEvaluator, ctx context.Context, ec *evalContext, nodes []helium.Node, steps []vmLocationStep) ([]helium.Node, int, error) {
+	idx := documentIndex(nodes)
+	if idx == nil {
+		return nil, 0, nil
+	}
+	step, test, consumed := indexableStep(steps)
+	if consumed == 0 {
+		return nil, 0, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	uri, ok := nameTestURI(test, ec, false)
+	if !ok {
+		// An unbound prefix matches no element.
+		return nil, consumed, nil
+	}
+
+	preds := step.Predicates
+	var matched []helium.Node
+	if pred, attrTest, ok := lookupPredicate(preds, ec); ok {
+		preds = preds[1:]
+		if attrURI, ok := nameTestURI(attrTest, ec, true); ok {
+			for _, e := range idx.ElementsByAttribute(attrURI, attrTest.Local, pred.Value) {
+				if e.URI() == uri && e.LocalName() == test.Local {
+					matched = append(matched, e)
+				}
+			}
+		}
+	} else {
+		elems := idx.Elements(uri, test.Local)
+		matched = make([]helium.Node, len(elems))
+		for i, e := range elems {
+			matched[i] = e
+		}
+	}
+	if err := ec.countOps(ctx, len(matched)); err != nil {
+		return nil, 0, err
+	}
+	var err error
+	for _, pred := range preds {
+		matched, err = applyVMPredicate(evalFn, ctx, ec, matched, pred)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if len(matched) > ec.maxNodes {
+		return nil, 0, ErrNodeSetLimit
+	}
+	return matched, consumed, nil
+}
+
+// documentIndex returns the index of the document nodes consists of, or nil
+// when nodes is not a single document or its index is not enabled.
+func documentIndex(nodes []helium.Node) *helium.Index {
+	if len(nodes) != 1 {
+		return nil
+	}
+	doc, ok := nodes[0].(*helium.Document)
+	if !ok {
+		return nil
+	}
+	return doc.Index()
+}
+
+// indexableStep returns the element step that selects the same nodes from
+// the document as the leading steps, its name test, and how many steps that
+// is: two for //name, which is descendant-or-self::node()/child::name, and
+// one for descendant::name and descendant-or-self::name. The predicates of
+// the step must test attributes only, so that they do not depend on the
+// position of a node, which differs between the siblings //name counts among
+// and the flat list the index returns.
+func indexableStep(steps []vmLocationStep) (vmLocationStep, NameTest, int) {
+	if len(steps) == 0 {
+		return vmLocationStep{}, NameTest{}, 0
+	}
+	step, consumed := steps[0], 1
+	if tt, ok := step.NodeTest.(TypeTest); ok && tt.Kind == NodeKindNode && step.Axis == AxisDescendantOrSelf && len(step.Predicates) == 0 {
+		if len(steps) < 2 || steps[1].Axis != AxisChild {
+			return vmLocationStep{}, NameTest{}, 0
+		}
+		step, consumed = steps[1], 2
+	} else if step.Axis != AxisDescendant && step.Axis != AxisDescendantOrSelf {
+		return vmLocationStep{}, NameTest{}, 0
+	}
+	test, ok := step.NodeTest.(NameTest)
+	if !ok || test.Local == "*" || test.Prefix == "*" {
+		return vmLocationStep{}, NameTest{}, 0
+	}
+	for _, pred := range step.Predicates {
+		switch pred.(type) {
+		case vmAttributeEqualsStringPredicateExpr, vmAttributeExistsPredicateExpr:
+		default:
+			return vmLocationStep{}, NameTest{}, 0
+		}
+	}
+	return step, test, consumed
+}
+
+// lookupPredicate returns the first of preds and its attribute name test
+// when the index can answer it: an [@attr = 'value'] with a plain attribute
+// name, compared as a string because no node carries a type annotation.
+func lookupPredicate(preds []Expr, ec *evalContext) (vmAttributeEqualsStringPredicateExpr, NameTest, bool) {
+	if len(preds) == 0 || len(ec.typeAnnotations) > 0 {
+		return vmAttributeEqualsStringPredicateExpr{}, NameTest{}, false
+	}
+	pred, ok := preds[0].(vmAttributeEqualsStringPredicateExpr)
+	if !ok {
+		return vmAttributeEqualsStringPredicateExpr{}, NameTest{}, false
+	}
+	test, ok := pred.NodeTest.(NameTest)
+	if !ok || test.Local == "*" || test.Prefix == "*" {
+		return vmAttributeEqualsStringPredicateExpr{}, NameTest{}, false
+	}
+	return pred, test, true
+}
+
+// nameTestURI returns the namespace URI a name test with a concrete
+// namespace matches, as matchNameTest resolves it, and false for an unbound
+// prefix. An unprefixed attribute test is in no namespace.
+func nameTestURI(test NameTest, ec *evalContext, isAttr bool) (string, bool) {
+	switch {
+	case test.URI != "":
+		return test.URI, true
+	case test.Prefix == "" && isAttr:
+		return "", true
+	case test.Prefix == "":
+		return ec.namespaces[""], true
+	}
+	if uri, ok := ec.namespaces[test.Prefix]; ok {
+		return uri, true
+	}
+	if test.Prefix == lexicon.PrefixXML {
+		return lexicon.NamespaceXML, true
+	}
+	if uri, ok := defaultPrefixNS[test.Prefix]; ok {
+		return uri, true
+	}
+	return "", false
+}
@@ -0,0 +1,113 @@
+package xpath3_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xpath3"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIndexedEvaluation checks that expressions give the same results with
+// the document index enabled as without it.
+func TestIndexedEvaluation(t *testing.T) {
+	doc := mustParseXML(t, `<doc xmlns:p="urn:p" xmlns="urn:d">
+  <item sku="A" kind="x">first</item>
+  <p:item p:sku="A">second</p:item>
+  <group>
+    <item sku="B">first</item>
+    <item sku="A" kind="y">third</item>
+  </group>
+</doc>`)
+	eval := xpath3.NewEvaluator(xpath3.DefaultEvaluatorOptions).
+		Namespaces(map[string]string{"p": "urn:p", "d": "urn:d"})
+
+	exprs := []string{
+		"//d:item",
+		"//item",
+		"//p:item",
+		"//Q{urn:d}item[@sku='A']",
+		"//d:item[@sku='A']",
+		"//d:item['A'=@sku]",
+		"//d:item[@sku eq 'A']",
+		"//p:item[@p:sku='A']",
+		"//d:item[@sku='A'][@kind='y']",
+		"//d:item[@kind][@sku='A']",
+		"//d:item[@sku='A'][1]",
+		"//d:item[1]",
+		"//d:item[text()='first']",
+		"//*:item[@sku='A']",
+		"//d:item/@sku",
+		"//d:group/d:item[@sku='B']",
+		"descendant::d:item[@sku='A']",
+		"count(//d:item[@sku='A'])",
+		"//d:item[@sku='A'] ! string(.)",
+	}
+	run := func() []string {
+		out := make([]string, len(exprs))
+		for i, expr := range exprs {
+			var b strings.Builder
+			for item := range evalExprWithEval(t, eval, doc, expr).Items() {
+				if n, ok := item.(xpath3.NodeItem); ok {
+					fmt.Fprintf(&b, "%p ", n.Node)
+					continue
+				}
+				fmt.Fprintf(&b, "%v ", item)
+			}
+			out[i] = b.String()
+		}
+		return out
+	}
+	want := run()
+	doc.EnableIndex()
+	require.Equal(t, want, run())
+}
+
+func TestIndexedEvaluationMutation(t *testing.T) {
+	doc := mustParseXML(t, `<doc><item sku="A"/><item sku="B"/></doc>`)
+	doc.EnableIndex()
+	compiled, err := xpath3.NewCompiler().Compile("//item[@sku='C']")
+	require.NoError(t, err)
+	eval := xpath3.NewEvaluator(xpath3.DefaultEvaluatorOptions)
+
+	r, err := eval.Evaluate(t.Context(), compiled, doc)
+	require.NoError(t, err)
+	nodes, err := r.Nodes()
+	require.NoError(t, err)
+	require.Empty(t, nodes)
+
+	second := doc.DocumentElement().LastChild().(*helium.Element)
+	require.NoError(t, second.SetAttribute("sku", "C"))
+	r, err = eval.Evaluate(t.Context(), compiled, doc)
+	require.NoError(t, err)
+	nodes, err = r.Nodes()
+	require.NoError(t, err)
+	require.Equal(t, []helium.Node{second}, nodes)
+}
+
+// TestIndexedEvaluationOpLimit checks that an indexed lookup is charged for
+// the nodes it returns rather than for a walk of the document.
+func TestIndexedEvaluationOpLimit(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("<doc>")
+	for i := range 1000 {
+		fmt.Fprintf(&b, `<item sku="%d"/>`, i)
+	}
+	b.WriteString("</doc>")
+	doc := mustParseXML(t, b.String())
+	compiled, err := xpath3.NewCompiler().Compile("//item[@sku='500']")
+	require.NoError(t, err)
+	eval := xpath3.NewEvaluator(xpath3.DefaultEvaluatorOptions).OpLimit(100)
+
+	_, err = eval.Evaluate(t.Context(), compiled, doc)
+	require.ErrorIs(t, err, xpath3.ErrOpLimit)
+
+	doc.EnableIndex()
+	r, err := eval.Evaluate(t.Context(), compiled, doc)
+	require.NoError(t, err)
+	nodes, err := r.Nodes()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+}
@@ -125,7 +125,7 @@ func (vc *validationContext) checkAssertions(ctx context.Context, elem *helium.E
 			}
 			res, err := ev.Evaluate(ctx, a.compiled, root)
 			if err != nil {
-				vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem),
+				vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem),
 					fmt.Sprintf("Failed to evaluate the assertion '%s': %v.", a.Test, err))
 				if firstErr == nil {
 					firstErr = fmt.Errorf("assertion evaluation failed")
@@ -134,7 +134,7 @@ func (vc *validationContext) checkAssertions(ctx context.Context, elem *helium.E
 			}
 			ok, err := xpath3.EBV(res.Sequence())
 			if err != nil {
-				vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem),
+				vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem),
 					fmt.Sprintf("Failed to evaluate the assertion '%s': %v.", a.Test, err))
 				if firstErr == nil {
 					firstErr = fmt.Errorf("assertion evaluation failed")
@@ -142,7 +142,7 @@ func (vc *validationContext) checkAssertions(ctx context.Context, elem *helium.E
 				continue
 			}
 			if !ok {
-				vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem),
+				vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem),
 					fmt.Sprintf("The assertion '%s' is not satisfied.", a.Test))
 				if firstErr == nil {
 					firstErr = fmt.Errorf("assertion not satisfied")
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	helium "github.com/lestrrat-go/helium"
 	"github.com/lestrrat-go/helium/internal/lexicon"
 	valuepkg "github.com/lestrrat-go/helium/internal/xsd/value"
 	"github.com/lestrrat-go/helium/xpath3"
@@ -137,7 +138,7 @@ func builtinAtomicForType(value string, valueNS map[string]string, local string)
 // error. An assertion is satisfied only if its effective boolean value is true;
 // a dynamic evaluation error (an absent focus, or a failed cast inside the test)
 // makes it unsatisfied.
-func checkSimpleTypeAssertions(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, elemName, filename string, line int, vc *validationContext) error {
+func checkSimpleTypeAssertions(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, elemName, filename string, node helium.Node, vc *validationContext) error {
 	var hasAssertion bool
 	for cur := range baseChain(td) {
 		if cur.Facets != nil && len(cur.Facets.Assertions) > 0 {
@@ -174,7 +175,7 @@ func checkSimpleTypeAssertions(ctx context.Context, value string, valueNS map[st
 				ok, err = xpath3.EBV(res.Sequence())
 			}
 			if err != nil {
-				vc.reportValidityError(ctx, filename, line, elemName,
+				vc.reportValidityError(ctx, filename, node, elemName,
 					fmt.Sprintf("Failed to evaluate the assertion '%s': %v.", a.Test, err))
 				if firstErr == nil {
 					firstErr = fmt.Errorf("assertion evaluation failed")
@@ -182,7 +183,7 @@ func checkSimpleTypeAssertions(ctx context.Context, value string, valueNS map[st
 				continue
 			}
 			if !ok {
-				vc.reportValidityError(ctx, filename, line, elemName,
+				vc.reportValidityError(ctx, filename, node, elemName,
 					fmt.Sprintf("The assertion '%s' is not satisfied.", a.Test))
 				if firstErr == nil {
 					firstErr = fmt.Errorf("assertion not satisfied")
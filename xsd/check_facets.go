@@ -379,7 +379,7 @@ func (c *compiler) checkFacetValueAgainstBase(ctx context.Context, td *TypeDef,
 		// namespace context so a prefixed bound (e.g. a QName-typed q:z) binds the
 		// prefix declared at its own facet element, not a sibling's.
 		sub := &validationContext{schema: c.schema, errorHandler: helium.NilErrorHandler{}, suppressDepth: 1, version: c.version}
-		if validateValue(ctx, *rf.value, rf.ns, base, "", "", 0, sub) == nil {
+		if validateValue(ctx, *rf.value, rf.ns, base, "", "", nil, sub) == nil {
 			continue
 		}
 		msg := fmt.Sprintf("The value '%s' of the facet '%s' is not a valid value of the base type '%s'.",
@@ -443,7 +443,7 @@ func (c *compiler) checkEnumValueAgainstBase(ctx context.Context, td *TypeDef, f
 		// member is not a valid instance of the base type, so the enumeration facet
 		// is in error.
 		sub := &validationContext{schema: c.schema, errorHandler: helium.NilErrorHandler{}, suppressDepth: 1, version: c.version}
-		if validateValue(ctx, ev, enumNS, base, "", "", 0, sub) == nil {
+		if validateValue(ctx, ev, enumNS, base, "", "", nil, sub) == nil {
 			continue
 		}
 		msg := fmt.Sprintf("The value '%s' of the facet 'enumeration' is not a valid value of the base type '%s'.",
@@ -685,7 +685,7 @@ func (c *compiler) enumLiteralHasUnboundQName(ctx context.Context, ev string, en
 			// QName/NOTATION carrier accepts it only with a bound prefix, so a
 			// successful match means the prefix is bound.
 			sub := &validationContext{schema: c.schema, errorHandler: helium.NilErrorHandler{}, suppressDepth: 1, version: c.version}
-			if validateValue(ctx, ev, enumNS, member, "", "", 0, sub) == nil {
+			if validateValue(ctx, ev, enumNS, member, "", "", nil, sub) == nil {
 				return false
 			}
 		}
@@ -750,7 +750,7 @@ func (c *compiler) enumLiteralNamesUndeclaredNotation(ctx context.Context, ev st
 			// very check this performs), so a bare-NOTATION member selects the literal
 			// and the token must then name a declared notation.
 			sub := &validationContext{schema: c.schema, errorHandler: helium.NilErrorHandler{}, suppressDepth: 1, version: c.version}
-			if validateValue(ctx, ev, enumNS, member, "", "", 0, sub) != nil {
+			if validateValue(ctx, ev, enumNS, member, "", "", nil, sub) != nil {
 				continue
 			}
 			return c.enumLiteralNamesUndeclaredNotation(ctx, ev, enumNS, member, resolveVariety(member))
@@ -48,9 +48,11 @@ type compiler struct {
 	// an explicit <xs:openContent> (TypeDef.pendingDefaultOpenContent) so it applies
 	// only to types declared in the same document.
 	defaultOpenContent *OpenContent
-	baseDir            string         // directory of the schema file, for resolving relative paths
-	fsys               fs.FS          // filesystem for loading xs:include/xs:import/xs:redefine targets
-	parser             *helium.Parser // parser governing parse policy for nested include/import/redefine schemas
+	baseDir            string                // directory of the schema file, for resolving relative paths
+	fsys               fs.FS                 // filesystem for loading xs:include/xs:import/xs:redefine targets
+	parser             *helium.Parser        // parser governing parse policy for nested include/import/redefine schemas
+	cache              *helium.ResourceCache // holds nested schemas parsed, when set
+	cachePolicy        schemaCachePolicy     // scopes the nested schemas in cache
 	// unresolved type references: maps from element/type QName to the type ref string
 	typeRefs map[*TypeDef]QName
 	// redefineOrigSeq is a monotonic counter minting a UNIQUE synthetic key for
@@ -623,6 +625,53 @@ func (c *compiler) parse(ctx context.Context, data []byte) (*helium.Document, er
 	return p.Parse(ctx, data)
 }
 
+// parseNested parses the nested schema document loaded from path. With a
+// cache, the parsed document is shared with every compile loading path under
+// the same loader and parser, so it is read-only; a document with vc:
+// directives is copied, since conditional inclusion prunes it.
+func (c *compiler) parseNested(ctx context.Context, path string, data []byte) (*helium.Document, error) {
+	if c.cache == nil {
+		return c.parse(ctx, data)
+	}
+	doc, err := c.cache.Document(c.cachePolicy, path, func() (*helium.Document, error) {
+		return c.parse(ctx, data)
+	})
+	if err != nil || !doc.IsReadOnly() || !documentHasVCDirective(findDocumentElement(doc)) {
+		return doc, err
+	}
+	return helium.CopyDoc(doc)
+}
+
+// schemaCachePolicy is what a parsed nested schema document cached in a
+// [helium.ResourceCache] depends on besides its path: the FS or resolver it
+// was loaded through, and the parser that parsed it.
+type schemaCachePolicy struct {
+	loader any
+	parser *helium.Parser
+}
+
+func newSchemaCachePolicy(fsys fs.FS, resolver helium.ResourceResolver, parser *helium.Parser) schemaCachePolicy {
+	if resolver != nil {
+		return schemaCachePolicy{loader: resolver, parser: parser}
+	}
+	return schemaCachePolicy{loader: fsys, parser: parser}
+}
+
+// schemaLoaderFS returns the fs.FS nested schemas are loaded through: fsys,
+// or resolver under ctx when one is set, behind cache when one is set.
+func schemaLoaderFS(ctx context.Context, fsys fs.FS, resolver helium.ResourceResolver, cache *helium.ResourceCache) fs.FS {
+	if resolver != nil {
+		if cache != nil {
+			resolver = cache.Resolver(resolver)
+		}
+		return helium.ResourceFS(ctx, resolver)
+	}
+	if cache == nil {
+		return fsys
+	}
+	return cache.FS(ctx, fsys)
+}
+
 func defaultSchemaParser() helium.Parser {
 	return helium.NewParser().SubstituteEntities(true)
 }
@@ -647,24 +696,32 @@ func compileSchema(ctx context.Context, doc *helium.Document, baseDir string, cf
 		fsys = cfg.fsys
 	}
 	var parser *helium.Parser
+	var resolver helium.ResourceResolver
+	var cache *helium.ResourceCache
 	if cfg != nil {
 		parser = cfg.parser
+		resolver = cfg.resolver
+		cache = cfg.cache
 	}
 	c := &compiler{
 		schema: &Schema{
-			elements:      make(map[QName]*ElementDecl),
-			types:         make(map[QName]*TypeDef),
-			groups:        make(map[QName]*ModelGroup),
-			attrGroups:    make(map[QName][]*AttrUse),
-			globalAttrs:   make(map[QName]*AttrUse),
-			substGroups:   make(map[QName][]*ElementDecl),
-			loaderFS:      fsys,
-			loaderBaseDir: baseDir,
-			loaderParser:  parser,
+			elements:       make(map[QName]*ElementDecl),
+			types:          make(map[QName]*TypeDef),
+			groups:         make(map[QName]*ModelGroup),
+			attrGroups:     make(map[QName][]*AttrUse),
+			globalAttrs:    make(map[QName]*AttrUse),
+			substGroups:    make(map[QName][]*ElementDecl),
+			loaderFS:       fsys,
+			loaderBaseDir:  baseDir,
+			loaderParser:   parser,
+			loaderResolver: resolver,
+			loaderCache:    cache,
 		},
 		baseDir:                   baseDir,
-		fsys:                      fsys,
+		fsys:                      schemaLoaderFS(ctx, fsys, resolver, cache),
 		parser:                    parser,
+		cache:                     cache,
+		cachePolicy:               newSchemaCachePolicy(fsys, resolver, parser),
 		typeRefs:                  make(map[*TypeDef]QName),
 		recoveryBaseTypes:         make(map[*TypeDef]bool),
 		elemRefs:                  make(map[*ElementDecl]QName),
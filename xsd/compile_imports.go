@@ -782,7 +782,7 @@ func (c *compiler) loadInclude(ctx context.Context, location string, includeElem
 		return fmt.Errorf("xsd: failed to load include %q: %w", location, err)
 	}
 
-	doc, err := c.parse(ctx, data)
+	doc, err := c.parseNested(ctx, path, data)
 	if err != nil {
 		return fmt.Errorf("xsd: failed to parse include %q: %w: %w", location, errSchemaContentInvalid, err)
 	}
@@ -1069,7 +1069,7 @@ func (c *compiler) loadRedefine(ctx context.Context, location string, redefineEl
 		return fmt.Errorf("xsd: failed to load redefine %q: %w", location, err)
 	}
 
-	doc, err := c.parse(ctx, data)
+	doc, err := c.parseNested(ctx, path, data)
 	if err != nil {
 		return fmt.Errorf("xsd: failed to parse redefine %q: %w: %w", location, errSchemaContentInvalid, err)
 	}
@@ -1986,7 +1986,7 @@ func (c *compiler) loadImport(ctx context.Context, location, ns string, importEl
 		return fmt.Errorf("xsd: failed to load import %q: %w", location, err)
 	}
 
-	doc, err := c.parse(ctx, data)
+	doc, err := c.parseNested(ctx, path, data)
 	if err != nil {
 		return fmt.Errorf("xsd: failed to parse import %q: %w: %w", location, errSchemaContentInvalid, err)
 	}
@@ -2018,6 +2018,8 @@ func (c *compiler) loadImport(ctx context.Context, location, ns string, importEl
 		baseDir:                   schemaBaseDir(path),
 		fsys:                      c.fsys,
 		parser:                    c.parser,
+		cache:                     c.cache,
+		cachePolicy:               c.cachePolicy,
 		typeRefs:                  make(map[*TypeDef]QName),
 		elemRefs:                  make(map[*ElementDecl]QName),
 		elemRefSources:            make(map[*ElementDecl]elemRefSource),
@@ -44,6 +44,11 @@
 // delivered to the [helium.ErrorHandler] configured via
 // [Validator.ErrorHandler].
 //
+// [NewValidatingWriter] validates a document while a stream.Writer produces
+// it, failing the call that writes an invalid element or attribute. See
+// [StreamValidator] for the checks it cannot make. This is a helium
+// extension not present in libxml2.
+//
 // # Error Handling
 //
 // Both [Compiler] and [Validator] accept an [helium.ErrorHandler] via the
@@ -13,12 +13,21 @@ import (
 //
 // AttributeName is empty for element-level errors; non-empty when the error
 // concerns a specific attribute on the element.
+//
+// Position is the source extent of the offending attribute, or of the element
+// for element-level errors. It is only set when the instance document was
+// parsed with [helium.Parser.TrackPositions].
+//
+// Expected lists the particles that would have been accepted where a child
+// element was not expected or was missing. It is set by [StreamValidator].
 type ValidationError struct {
-	Filename      string // source filename
-	Line          int    // line number in the source document
-	Element       string // element name
-	AttributeName string // attribute name (optional)
-	Message       string // human-readable error description
+	Filename      string          // source filename
+	Line          int             // line number in the source document
+	Element       string          // element name
+	AttributeName string          // attribute name (optional)
+	Message       string          // human-readable error description
+	Position      helium.Position // source position (optional)
+	Expected      []string        // expected particles (optional)
 }
 
 // Error implements the error interface and produces libxml2-compatible output.
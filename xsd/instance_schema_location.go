@@ -20,7 +20,7 @@ func schemaWithInstanceHints(ctx context.Context, base *Schema, doc *helium.Docu
 	if base == nil || doc == nil || base.loaderFS == nil {
 		return base
 	}
-	if _, denyAll := base.loaderFS.(iofs.DenyAll); denyAll {
+	if _, denyAll := base.loaderFS.(iofs.DenyAll); denyAll && base.loaderResolver == nil {
 		return base
 	}
 
@@ -95,7 +95,7 @@ func collectInstanceSchemaHints(doc *helium.Document) []instanceSchemaHint {
 }
 
 func compileInstanceHintSchema(ctx context.Context, base *Schema, path string) *Schema {
-	data, err := readInstanceHintSchema(base.loaderFS, path)
+	data, err := readInstanceHintSchema(schemaLoaderFS(ctx, base.loaderFS, base.loaderResolver, base.loaderCache), path)
 	if err != nil {
 		return nil
 	}
@@ -111,6 +111,8 @@ func compileInstanceHintSchema(ctx context.Context, base *Schema, path string) *
 
 	cfg := &compileConfig{
 		fsys:       base.loaderFS,
+		resolver:   base.loaderResolver,
+		cache:      base.loaderCache,
 		parser:     base.loaderParser,
 		version:    base.version,
 		versionSet: true,
@@ -2633,7 +2633,7 @@ func (c *compiler) checkAttrUseConstraints(ctx context.Context) {
 		// schema-aware cast (`castable as t:T`) needs the schema declarations — a nil
 		// schema would make that cast fail closed and reject a valid default/fixed.
 		vc := &validationContext{schema: c.schema, errorHandler: helium.NilErrorHandler{}, version: c.version}
-		if err := validateValue(ctx, *val, it.src.nsMap, td, "", "", 0, vc); err != nil {
+		if err := validateValue(ctx, *val, it.src.nsMap, td, "", "", nil, vc); err != nil {
 			msg := fmt.Sprintf("The value '%s' is not a valid value of the atomic type '%s'.", *val, typeDisplayName(td))
 			c.schemaError(ctx, schemaParserErrorAttr(c.diagSourceOrRecorded(it.src.source), it.src.line, it.src.local, "attribute", it.src.local, msg))
 		}
@@ -2732,7 +2732,7 @@ func (c *compiler) checkElementDeclConstraints(ctx context.Context) {
 		// facet is handled exactly as for an attribute default/fixed. A plain simpleType
 		// passes through that path unchanged (the nested-base walk is a no-op).
 		vc := &validationContext{schema: c.schema, errorHandler: helium.NilErrorHandler{}, version: c.version}
-		if err := vc.validateSimpleContentValue(ctx, *val, it.src.nsMap, std, it.src.local, it.src.line); err != nil {
+		if err := vc.validateSimpleContentValue(ctx, *val, it.src.nsMap, std, it.src.local, nil); err != nil {
 			msg := fmt.Sprintf("The value '%s' is not a valid value of the atomic type '%s'.", *val, typeDisplayName(effectiveContentSimpleType(std)))
 			c.schemaError(ctx, schemaElemDeclError(c.diagSourceOrRecorded(it.src.source), it.src.line, it.src.local, msg))
 		}
@@ -1507,7 +1507,7 @@ func (vc *validationContext) validateContentModelOpen(ctx context.Context, elem
 		declaredNames := collectEmittingModelElementNames(mg, vc.schema)
 		for _, ch := range leftover {
 			if declaredNames[QName{Local: ch.name, NS: ch.ns}] {
-				vc.reportValidityError(ctx, vc.filename, ch.elem.Line(), ch.displayName, "This element is not expected.")
+				vc.reportValidityError(ctx, vc.filename, ch.elem, ch.displayName, "This element is not expected.")
 				return fmt.Errorf("unexpected element")
 			}
 		}
@@ -1690,7 +1690,7 @@ func (vc *validationContext) validateOpenChildren(ctx context.Context, parent *h
 	}
 	if consumed < len(open) {
 		ce := open[consumed]
-		vc.reportValidityError(ctx, vc.filename, ce.elem.Line(), ce.displayName, "This element is not expected.")
+		vc.reportValidityError(ctx, vc.filename, ce.elem, ce.displayName, "This element is not expected.")
 		return fmt.Errorf("unexpected element")
 	}
 	return nil
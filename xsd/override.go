@@ -481,7 +481,7 @@ func (c *compiler) overrideLoadTarget(ctx context.Context, location string, srcE
 		return matched, fmt.Errorf("xsd: failed to load override %q: %w", location, err)
 	}
 
-	doc, err := c.parse(ctx, data)
+	doc, err := c.parseNested(ctx, path, data)
 	if err != nil {
 		return matched, fmt.Errorf("xsd: failed to parse override %q: %w: %w", location, errSchemaContentInvalid, err)
 	}
@@ -0,0 +1,113 @@
+package xsd_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xsd"
+	"github.com/stretchr/testify/require"
+)
+
+type resourceCtxKey struct{}
+
+// TestCompilerResourceResolver verifies that a helium.ResourceResolver loads
+// xs:include targets at compile time and xsi:schemaLocation hints at
+// validation time, each under the ctx of the call that triggered the load.
+func TestCompilerResourceResolver(t *testing.T) {
+	t.Parallel()
+
+	mem := helium.MemoryResourceResolver(map[string][]byte{
+		"inc.xsd": []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:t">
+  <xs:element name="doc"><xs:complexType><xs:anyAttribute namespace="##other" processContents="strict"/></xs:complexType></xs:element>
+</xs:schema>`),
+		"hint.xsd": []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:hint" attributeFormDefault="qualified">
+  <xs:attribute name="att" type="xs:int"/>
+</xs:schema>`),
+	})
+	var mu sync.Mutex
+	seen := map[string]any{}
+	r := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+		mu.Lock()
+		seen[uri] = ctx.Value(resourceCtxKey{})
+		mu.Unlock()
+		return mem.ResolveResource(ctx, uri)
+	})
+
+	schemaDoc, err := helium.NewParser().Parse(t.Context(), []byte(
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:t"><xs:include schemaLocation="inc.xsd"/></xs:schema>`))
+	require.NoError(t, err)
+	schema, err := xsd.NewCompiler().
+		BaseDir(".").
+		ResourceResolver(r).
+		Compile(context.WithValue(t.Context(), resourceCtxKey{}, "compile"), schemaDoc)
+	require.NoError(t, err)
+
+	parse := func(att string) *helium.Document {
+		doc, err := helium.NewParser().BaseURI("doc.xml").Parse(t.Context(), []byte(`<t:doc xmlns:t="urn:t"
+  xmlns:h="urn:hint" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+  xsi:schemaLocation="urn:hint hint.xsd" h:att="`+att+`"/>`))
+		require.NoError(t, err)
+		return doc
+	}
+	ctx := context.WithValue(t.Context(), resourceCtxKey{}, "validate")
+	require.NoError(t, xsd.NewValidator(schema).Validate(ctx, parse("1")))
+	require.Error(t, xsd.NewValidator(schema).Validate(ctx, parse("x")))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, map[string]any{"inc.xsd": "compile", "hint.xsd": "validate"}, seen)
+}
+
+// countingResolver counts the resources it loads.
+type countingResolver struct {
+	next  helium.ResourceResolver
+	mu    sync.Mutex
+	loads int
+}
+
+func (r *countingResolver) ResolveResource(ctx context.Context, uri string) (*helium.Resource, error) {
+	r.mu.Lock()
+	r.loads++
+	r.mu.Unlock()
+	return r.next.ResolveResource(ctx, uri)
+}
+
+// TestCompilerResourceCache verifies that a helium.ResourceCache shared by
+// compiles serves a nested schema loaded and parsed once, and only to
+// compilers loading through the same resolver.
+func TestCompilerResourceCache(t *testing.T) {
+	t.Parallel()
+
+	resources := map[string][]byte{
+		"inc.xsd": []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="doc" type="xs:string"/></xs:schema>`),
+	}
+	r := &countingResolver{next: helium.MemoryResourceResolver(resources)}
+	cache := helium.NewResourceCache(0, 0)
+	compile := func(compiler xsd.Compiler) (*xsd.Schema, error) {
+		schemaDoc, err := helium.NewParser().Parse(t.Context(), []byte(
+			`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:include schemaLocation="inc.xsd"/></xs:schema>`))
+		require.NoError(t, err)
+		return compiler.Compile(t.Context(), schemaDoc)
+	}
+
+	compiler := xsd.NewCompiler().BaseDir(".").ResourceResolver(r).ResourceCache(cache)
+	for range 3 {
+		schema, err := compile(compiler)
+		require.NoError(t, err)
+		_, ok := schema.LookupElement("doc", "")
+		require.True(t, ok)
+	}
+	require.Equal(t, 1, r.loads)
+	// inc.xsd is held loaded and parsed.
+	require.Equal(t, 2, cache.Len())
+
+	// A compiler whose resolver cannot load inc.xsd is not served it.
+	empty := helium.MemoryResourceResolver(nil)
+	schema, err := compile(xsd.NewCompiler().BaseDir(".").ResourceResolver(empty).ResourceCache(cache))
+	if err == nil {
+		_, ok := schema.LookupElement("doc", "")
+		require.False(t, ok)
+	}
+}
@@ -1,6 +1,7 @@
 package xsd
 
 import (
+	"cmp"
 	"io/fs"
 	"slices"
 
@@ -61,6 +62,8 @@ type Schema struct {
 	loaderFS          fs.FS
 	loaderBaseDir     string
 	loaderParser      *helium.Parser
+	loaderResolver    helium.ResourceResolver
+	loaderCache       *helium.ResourceCache
 }
 
 // LookupElement returns the global element declaration for the given name.
@@ -101,24 +104,45 @@ func (s *Schema) NamedTypes() []QName {
 	for qn := range s.types {
 		names = append(names, qn)
 	}
-	slices.SortFunc(names, func(a, b QName) int {
-		if a.NS != b.NS {
-			if a.NS < b.NS {
-				return -1
-			}
-			return 1
-		}
-		if a.Local < b.Local {
-			return -1
-		}
-		if a.Local > b.Local {
-			return 1
-		}
-		return 0
-	})
+	slices.SortFunc(names, compareQName)
 	return names
 }
 
+// Elements returns the schema's global element declarations, sorted by
+// namespace and then local name.
+func (s *Schema) Elements() []*ElementDecl {
+	return sortedDecls(s.elements)
+}
+
+// Attributes returns the schema's global attribute declarations, sorted by
+// namespace and then local name.
+func (s *Schema) Attributes() []*AttrUse {
+	return sortedDecls(s.globalAttrs)
+}
+
+func sortedDecls[T any](m map[QName]T) []T {
+	if len(m) == 0 {
+		return nil
+	}
+	names := make([]QName, 0, len(m))
+	for qn := range m {
+		names = append(names, qn)
+	}
+	slices.SortFunc(names, compareQName)
+	decls := make([]T, len(names))
+	for i, qn := range names {
+		decls[i] = m[qn]
+	}
+	return decls
+}
+
+func compareQName(a, b QName) int {
+	if c := cmp.Compare(a.NS, b.NS); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Local, b.Local)
+}
+
 // TargetNamespace returns the schema's target namespace.
 func (s *Schema) TargetNamespace() string {
 	return s.targetNamespace
@@ -251,7 +251,7 @@ func (d schemaDecls) validateCast(ctx context.Context, value, typeName string, n
 		errorHandler:  helium.NilErrorHandler{},
 		suppressDepth: 1,
 	}
-	return validateValue(ctx, value, nsMap, td, "", "", 0, vc)
+	return validateValue(ctx, value, nsMap, td, "", "", nil, vc)
 }
 
 // ListItemType returns the item type name for a list type.
@@ -0,0 +1,39 @@
+package xsd_test
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/helium/xsd"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSchemaGlobalDecls verifies that Elements and Attributes list the
+// global declarations in namespace and then local-name order, and leave out
+// local declarations.
+func TestSchemaGlobalDecls(t *testing.T) {
+	t.Parallel()
+
+	schema := mustCompileFixedMixedSchema(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:b">
+  <xs:element name="zeta" type="xs:string"/>
+  <xs:element name="alpha">
+    <xs:complexType>
+      <xs:sequence><xs:element name="local" type="xs:int"/></xs:sequence>
+      <xs:attribute name="inner" type="xs:string"/>
+    </xs:complexType>
+  </xs:element>
+  <xs:attribute name="lang" type="xs:language"/>
+  <xs:attribute name="id" type="xs:ID"/>
+</xs:schema>`)
+
+	var elems []xsd.QName
+	for _, decl := range schema.Elements() {
+		elems = append(elems, decl.Name)
+	}
+	require.Equal(t, []xsd.QName{{Local: "alpha", NS: "urn:b"}, {Local: "zeta", NS: "urn:b"}}, elems)
+
+	var attrs []xsd.QName
+	for _, use := range schema.Attributes() {
+		attrs = append(attrs, use.Name)
+	}
+	require.Equal(t, []xsd.QName{{Local: "id", NS: "urn:b"}, {Local: "lang", NS: "urn:b"}}, attrs)
+}
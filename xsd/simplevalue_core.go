@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strings"
 
+	helium "github.com/lestrrat-go/helium"
 	"github.com/lestrrat-go/helium/internal/lexicon"
 	valuepkg "github.com/lestrrat-go/helium/internal/xsd/value"
 )
@@ -152,10 +153,10 @@ func collapseSpaces(s string) string {
 }
 
 // validateValue validates a text value against a simple type definition.
-func validateValue(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, elemName, filename string, line int, vc *validationContext) error {
+func validateValue(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, elemName, filename string, node helium.Node, vc *validationContext) error {
 	if qn, ok := missingTypeRef(td); ok {
 		if vc != nil {
-			vc.reportValidityError(ctx, filename, line, elemName, missingTypeRefMessage(qn))
+			vc.reportValidityError(ctx, filename, node, elemName, missingTypeRefMessage(qn))
 		}
 		return fmt.Errorf("unresolved type definition")
 	}
@@ -163,14 +164,14 @@ func validateValue(ctx context.Context, value string, valueNS map[string]string,
 	// Apply whitespace normalization per the type's whiteSpace facet.
 	trimmed := normalizeWhiteSpace(value, resolveWhiteSpace(td))
 
-	if err := validateValueByVariety(ctx, value, trimmed, valueNS, td, elemName, filename, line, vc); err != nil {
+	if err := validateValueByVariety(ctx, value, trimmed, valueNS, td, elemName, filename, node, vc); err != nil {
 		return err
 	}
 
 	// XSD 1.1 <xs:assertion> simple-type facet: evaluated only after the value is
 	// known lexically and facet valid, with $value bound to the typed value.
 	if vc.version == Version11 {
-		return checkSimpleTypeAssertions(ctx, trimmed, valueNS, td, elemName, filename, line, vc)
+		return checkSimpleTypeAssertions(ctx, trimmed, valueNS, td, elemName, filename, node, vc)
 	}
 	return nil
 }
@@ -178,15 +179,15 @@ func validateValue(ctx context.Context, value string, valueNS map[string]string,
 // validateValueByVariety validates a value's lexical space and facets per td's
 // variety, excluding the XSD 1.1 assertion facet (handled by validateValue once
 // the value is otherwise valid).
-func validateValueByVariety(ctx context.Context, value, trimmed string, valueNS map[string]string, td *TypeDef, elemName, filename string, line int, vc *validationContext) error {
+func validateValueByVariety(ctx context.Context, value, trimmed string, valueNS map[string]string, td *TypeDef, elemName, filename string, node helium.Node, vc *validationContext) error {
 	// Check if this is a list type.
 	if resolveVariety(td) == TypeVarietyList {
-		return validateListValue(ctx, trimmed, valueNS, td, elemName, filename, line, vc)
+		return validateListValue(ctx, trimmed, valueNS, td, elemName, filename, node, vc)
 	}
 
 	// Check if this is a union type.
 	if resolveVariety(td) == TypeVarietyUnion {
-		return validateUnionValue(ctx, value, valueNS, td, elemName, filename, line, vc)
+		return validateUnionValue(ctx, value, valueNS, td, elemName, filename, node, vc)
 	}
 
 	// Find the builtin base type by walking the BaseType chain.
@@ -195,11 +196,11 @@ func validateValueByVariety(ctx context.Context, value, trimmed string, valueNS
 	// Validate against the builtin type's lexical space.
 	if err := validateBuiltinValue(trimmed, builtinLocal, vc.version); err != nil {
 		if acceptsXSD10LegacyGMonthInstance(trimmed, builtinLocal, td, vc) {
-			return validateFacets(ctx, trimmed, valueNS, td, builtinLocal, elemName, filename, line, vc)
+			return validateFacets(ctx, trimmed, valueNS, td, builtinLocal, elemName, filename, node, vc)
 		}
 		typeName := typeDisplayName(td)
 		msg := fmt.Sprintf("'%s' is not a valid value of the atomic type '%s'.", trimmed, typeName)
-		vc.reportValidityError(ctx, filename, line, elemName, msg)
+		vc.reportValidityError(ctx, filename, node, elemName, msg)
 		return err
 	}
 
@@ -210,13 +211,13 @@ func validateValueByVariety(ctx context.Context, value, trimmed string, valueNS
 		if _, err := resolveLexicalQName(trimmed, valueNS); err != nil {
 			typeName := typeDisplayName(td)
 			msg := fmt.Sprintf("'%s' is not a valid value of the atomic type '%s'.", trimmed, typeName)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			return err
 		}
 	}
 
 	// Validate facets along the type chain.
-	return validateFacets(ctx, trimmed, valueNS, td, builtinLocal, elemName, filename, line, vc)
+	return validateFacets(ctx, trimmed, valueNS, td, builtinLocal, elemName, filename, node, vc)
 }
 
 func acceptsXSD10LegacyGMonthInstance(value, builtinLocal string, td *TypeDef, vc *validationContext) bool {
@@ -276,7 +277,7 @@ func resolveUnionMembers(td *TypeDef) []*TypeDef {
 
 // validateUnionValue validates a value against a union type by trying each member type.
 // If all member types fail, a union-level error is reported.
-func validateUnionValue(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, elemName, filename string, line int, vc *validationContext) error {
+func validateUnionValue(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, elemName, filename string, node helium.Node, vc *validationContext) error {
 	members := resolveUnionMembers(td)
 	oldAllowLegacyGMonth := vc.allowXSD10LegacyGMonthInstance
 	vc.allowXSD10LegacyGMonthInstance = oldAllowLegacyGMonth && typeChainHasNoFacets(td)
@@ -310,12 +311,12 @@ func validateUnionValue(ctx context.Context, value string, valueNS map[string]st
 		}
 
 		vc.suppressDepth++
-		enumErr := checkUnionEnumeration(ctx, value, valueNS, cur, elemName, filename, line, vc)
+		enumErr := checkUnionEnumeration(ctx, value, valueNS, cur, elemName, filename, node, vc)
 		vc.suppressDepth--
 		if enumErr != nil {
 			typeName := unionTypeDisplayName(td)
 			msg := fmt.Sprintf("'%s' is not a valid value of the %s.", trimmed, typeName)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			return enumErr
 		}
 
@@ -339,12 +340,12 @@ func validateUnionValue(ctx context.Context, value string, valueNS map[string]st
 		nonEnum.Enumeration = nil
 		nonEnum.EnumerationNS = nil
 		vc.suppressDepth++
-		err := checkFacets(ctx, trimmed, valueNS, &nonEnum, memberLocal, memberWS, elemName, filename, line, vc)
+		err := checkFacets(ctx, trimmed, valueNS, &nonEnum, memberLocal, memberWS, elemName, filename, node, vc)
 		vc.suppressDepth--
 		if err != nil {
 			typeName := unionTypeDisplayName(td)
 			msg := fmt.Sprintf("'%s' is not a valid value of the %s.", trimmed, typeName)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			return err
 		}
 	}
@@ -353,7 +354,7 @@ func validateUnionValue(ctx context.Context, value string, valueNS map[string]st
 	// Suppress per-member errors; only report union-level on total failure.
 	for _, member := range members {
 		vc.suppressDepth++
-		err := validateValue(ctx, value, valueNS, member, elemName, filename, line, vc)
+		err := validateValue(ctx, value, valueNS, member, elemName, filename, node, vc)
 		vc.suppressDepth--
 		if err == nil {
 			return nil
@@ -364,7 +365,7 @@ func validateUnionValue(ctx context.Context, value string, valueNS map[string]st
 	// Use raw value (not trimmed) for the error message to match libxml2 behavior.
 	typeName := unionTypeDisplayName(td)
 	msg := fmt.Sprintf("'%s' is not a valid value of the %s.", value, typeName)
-	vc.reportValidityError(ctx, filename, line, elemName, msg)
+	vc.reportValidityError(ctx, filename, node, elemName, msg)
 	return fmt.Errorf("union validation failed")
 }
 
@@ -376,7 +377,7 @@ func validateUnionValue(ctx context.Context, value string, valueNS map[string]st
 // look-alikes — a literal active in a string member is not value-equal to an
 // instance active in a numeric member. Each literal's prefixes resolve against its
 // captured EnumerationNS bindings; the instance's against valueNS.
-func checkUnionEnumeration(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, elemName, filename string, line int, vc *validationContext) error {
+func checkUnionEnumeration(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, elemName, filename string, node helium.Node, vc *validationContext) error {
 	if td.Facets == nil || len(td.Facets.Enumeration) == 0 {
 		return nil
 	}
@@ -391,7 +392,7 @@ func checkUnionEnumeration(ctx context.Context, value string, valueNS map[string
 	}
 	set := "'" + strings.Join(td.Facets.Enumeration, "', '") + "'"
 	msg := fmt.Sprintf("[facet 'enumeration'] The value '%s' is not an element of the set {%s}.", value, set)
-	vc.reportValidityError(ctx, filename, line, elemName, msg)
+	vc.reportValidityError(ctx, filename, node, elemName, msg)
 	return fmt.Errorf("enumeration")
 }
 
@@ -417,7 +418,7 @@ func resolveVariety(td *TypeDef) TypeVariety {
 }
 
 // validateListValue validates a space-separated list value against a list type.
-func validateListValue(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, elemName, filename string, line int, vc *validationContext) error {
+func validateListValue(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, elemName, filename string, node helium.Node, vc *validationContext) error {
 	oldAllowLegacyGMonth := vc.allowXSD10LegacyGMonthInstance
 	vc.allowXSD10LegacyGMonthInstance = oldAllowLegacyGMonth && typeChainHasNoFacets(td)
 	defer func() {
@@ -436,7 +437,7 @@ func validateListValue(ctx context.Context, value string, valueNS map[string]str
 	itemCount := len(items)
 	itemType := resolveItemType(td)
 	if qn, ok := missingTypeRef(itemType); ok {
-		vc.reportValidityError(ctx, filename, line, elemName, missingTypeRefMessage(qn))
+		vc.reportValidityError(ctx, filename, node, elemName, missingTypeRefMessage(qn))
 		return fmt.Errorf("unresolved type definition")
 	}
 
@@ -446,17 +447,17 @@ func validateListValue(ctx context.Context, value string, valueNS map[string]str
 		if cur.Facets != nil {
 			if cur.Facets.Length != nil && itemCount != *cur.Facets.Length {
 				msg := fmt.Sprintf("[facet 'length'] The value has a length of '%d'; this differs from the allowed length of '%d'.", itemCount, *cur.Facets.Length)
-				vc.reportValidityError(ctx, filename, line, elemName, msg)
+				vc.reportValidityError(ctx, filename, node, elemName, msg)
 				facetErr = fmt.Errorf("length")
 			}
 			if cur.Facets.MinLength != nil && itemCount < *cur.Facets.MinLength {
 				msg := fmt.Sprintf("[facet 'minLength'] The value has a length of '%d'; this underruns the allowed minimum length of '%d'.", itemCount, *cur.Facets.MinLength)
-				vc.reportValidityError(ctx, filename, line, elemName, msg)
+				vc.reportValidityError(ctx, filename, node, elemName, msg)
 				facetErr = fmt.Errorf("minLength")
 			}
 			if cur.Facets.MaxLength != nil && itemCount > *cur.Facets.MaxLength {
 				msg := fmt.Sprintf("[facet 'maxLength'] The value has a length of '%d'; this exceeds the allowed maximum length of '%d'.", itemCount, *cur.Facets.MaxLength)
-				vc.reportValidityError(ctx, filename, line, elemName, msg)
+				vc.reportValidityError(ctx, filename, node, elemName, msg)
 				facetErr = fmt.Errorf("maxLength")
 			}
 		}
@@ -471,10 +472,10 @@ func validateListValue(ctx context.Context, value string, valueNS map[string]str
 		if cur.Facets == nil {
 			continue
 		}
-		if err := checkListEnumeration(ctx, value, valueNS, cur.Facets, itemType, elemName, filename, line, vc); err != nil {
+		if err := checkListEnumeration(ctx, value, valueNS, cur.Facets, itemType, elemName, filename, node, vc); err != nil {
 			facetErr = err
 		}
-		if err := checkListPattern(ctx, value, cur.Facets, elemName, filename, line, vc); err != nil {
+		if err := checkListPattern(ctx, value, cur.Facets, elemName, filename, node, vc); err != nil {
 			facetErr = err
 		}
 	}
@@ -482,7 +483,7 @@ func validateListValue(ctx context.Context, value string, valueNS map[string]str
 	if facetErr != nil {
 		typeName := typeQualifiedName(td)
 		msg := fmt.Sprintf("'%s' is not a valid value of the list type '%s'.", value, typeName)
-		vc.reportValidityError(ctx, filename, line, elemName, msg)
+		vc.reportValidityError(ctx, filename, node, elemName, msg)
 		return facetErr
 	}
 
@@ -491,7 +492,7 @@ func validateListValue(ctx context.Context, value string, valueNS map[string]str
 	// QName/NOTATION resolves item prefixes against the instance's namespaces.
 	if itemType != nil {
 		for _, item := range items {
-			if err := validateValue(ctx, item, valueNS, itemType, elemName, filename, line, vc); err != nil {
+			if err := validateValue(ctx, item, valueNS, itemType, elemName, filename, node, vc); err != nil {
 				return err
 			}
 		}
@@ -558,7 +559,7 @@ func typeDisplayName(td *TypeDef) string {
 // enumeration "1 2" accepts the value-equal instance "01 +2". QName/NOTATION item
 // types resolve the instance items against valueNS and each member's items against
 // the member's captured EnumerationNS bindings.
-func checkListEnumeration(ctx context.Context, value string, valueNS map[string]string, fs *FacetSet, itemType *TypeDef, elemName, filename string, line int, vc *validationContext) error {
+func checkListEnumeration(ctx context.Context, value string, valueNS map[string]string, fs *FacetSet, itemType *TypeDef, elemName, filename string, node helium.Node, vc *validationContext) error {
 	if len(fs.Enumeration) == 0 {
 		return nil
 	}
@@ -573,13 +574,13 @@ func checkListEnumeration(ctx context.Context, value string, valueNS map[string]
 	}
 	set := "'" + strings.Join(fs.Enumeration, "', '") + "'"
 	msg := fmt.Sprintf("[facet 'enumeration'] The value '%s' is not an element of the set {%s}.", value, set)
-	vc.reportValidityError(ctx, filename, line, elemName, msg)
+	vc.reportValidityError(ctx, filename, node, elemName, msg)
 	return fmt.Errorf("enumeration")
 }
 
 // checkListPattern enforces the pattern facet on the whole-list value. Multiple
 // patterns in the same restriction step are ORed, matching checkFacets.
-func checkListPattern(ctx context.Context, value string, fs *FacetSet, elemName, filename string, line int, vc *validationContext) error {
+func checkListPattern(ctx context.Context, value string, fs *FacetSet, elemName, filename string, node helium.Node, vc *validationContext) error {
 	if len(fs.Patterns) == 0 {
 		return nil
 	}
@@ -604,18 +605,18 @@ func checkListPattern(ctx context.Context, value string, fs *FacetSet, elemName,
 	} else {
 		msg = fmt.Sprintf("[facet 'pattern'] The value '%s' is not accepted by the patterns '%s'.", value, strings.Join(fs.Patterns, "', '"))
 	}
-	vc.reportValidityError(ctx, filename, line, elemName, msg)
+	vc.reportValidityError(ctx, filename, node, elemName, msg)
 	return fmt.Errorf("pattern")
 }
 
 // validateFacets checks all applicable facets for a type and its ancestors.
-func validateFacets(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, builtinLocal, elemName, filename string, line int, vc *validationContext) error {
+func validateFacets(ctx context.Context, value string, valueNS map[string]string, td *TypeDef, builtinLocal, elemName, filename string, node helium.Node, vc *validationContext) error {
 	// Collect all facets along the type chain (most derived first).
 	var anyErr error
 	ws := resolveWhiteSpace(td)
 	for cur := range baseChain(td) {
 		if cur.Facets != nil {
-			if err := checkFacets(ctx, value, valueNS, cur.Facets, builtinLocal, ws, elemName, filename, line, vc); err != nil {
+			if err := checkFacets(ctx, value, valueNS, cur.Facets, builtinLocal, ws, elemName, filename, node, vc); err != nil {
 				anyErr = err
 			}
 		}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	helium "github.com/lestrrat-go/helium"
 	"github.com/lestrrat-go/helium/internal/lexicon"
 	"github.com/lestrrat-go/helium/internal/xsd/value"
 )
@@ -127,7 +128,7 @@ func enumerationValueEqual(v, ev, builtinLocal string) bool {
 	return ok && cmp == 0
 }
 
-func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs *FacetSet, builtinLocal, whiteSpace, elemName, filename string, line int, vc *validationContext) error {
+func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs *FacetSet, builtinLocal, whiteSpace, elemName, filename string, node helium.Node, vc *validationContext) error {
 	var anyErr error
 
 	// Enumeration.
@@ -185,7 +186,7 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 		if !found {
 			set := "'" + strings.Join(fs.Enumeration, "', '") + "'"
 			msg := fmt.Sprintf("[facet 'enumeration'] The value '%s' is not an element of the set {%s}.", val, set)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			anyErr = fmt.Errorf("enumeration")
 		}
 	}
@@ -194,7 +195,7 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 	if fs.MinInclusive != nil {
 		if !checkMinInclusive(val, *fs.MinInclusive, builtinLocal) {
 			msg := fmt.Sprintf("[facet 'minInclusive'] The value '%s' is less than the minimum value allowed ('%s').", val, *fs.MinInclusive)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			anyErr = fmt.Errorf("minInclusive")
 		}
 	}
@@ -203,7 +204,7 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 	if fs.MaxInclusive != nil {
 		if !checkMaxInclusive(val, *fs.MaxInclusive, builtinLocal) {
 			msg := fmt.Sprintf("[facet 'maxInclusive'] The value '%s' is greater than the maximum value allowed ('%s').", val, *fs.MaxInclusive)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			anyErr = fmt.Errorf("maxInclusive")
 		}
 	}
@@ -212,7 +213,7 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 	if fs.MinExclusive != nil {
 		if !checkMinExclusive(val, *fs.MinExclusive, builtinLocal) {
 			msg := fmt.Sprintf("[facet 'minExclusive'] The value '%s' must be greater than '%s'.", val, *fs.MinExclusive)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			anyErr = fmt.Errorf("minExclusive")
 		}
 	}
@@ -221,7 +222,7 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 	if fs.MaxExclusive != nil {
 		if !checkMaxExclusive(val, *fs.MaxExclusive, builtinLocal) {
 			msg := fmt.Sprintf("[facet 'maxExclusive'] The value '%s' must be less than '%s'.", val, *fs.MaxExclusive)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			anyErr = fmt.Errorf("maxExclusive")
 		}
 	}
@@ -231,7 +232,7 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 		digits := value.CountTotalDigits(val)
 		if digits > *fs.TotalDigits {
 			msg := fmt.Sprintf("[facet 'totalDigits'] The value '%s' has more digits than are allowed ('%d').", val, *fs.TotalDigits)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			anyErr = fmt.Errorf("totalDigits")
 		}
 	}
@@ -241,7 +242,7 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 		frac := value.CountFractionDigits(val)
 		if frac > *fs.FractionDigits {
 			msg := fmt.Sprintf("[facet 'fractionDigits'] The value '%s' has more fractional digits than are allowed ('%d').", val, *fs.FractionDigits)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			anyErr = fmt.Errorf("fractionDigits")
 		}
 	}
@@ -261,7 +262,7 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 	if fs.Length != nil && lengthApplies {
 		if valueLen != *fs.Length {
 			msg := fmt.Sprintf("[facet 'length'] The value has a length of '%d'; this differs from the allowed length of '%d'.", valueLen, *fs.Length)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			anyErr = fmt.Errorf("length")
 		}
 	}
@@ -269,7 +270,7 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 	if fs.MinLength != nil && lengthApplies {
 		if valueLen < *fs.MinLength {
 			msg := fmt.Sprintf("[facet 'minLength'] The value has a length of '%d'; this underruns the allowed minimum length of '%d'.", valueLen, *fs.MinLength)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			anyErr = fmt.Errorf("minLength")
 		}
 	}
@@ -277,7 +278,7 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 	if fs.MaxLength != nil && lengthApplies {
 		if valueLen > *fs.MaxLength {
 			msg := fmt.Sprintf("[facet 'maxLength'] The value has a length of '%d'; this exceeds the allowed maximum length of '%d'.", valueLen, *fs.MaxLength)
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			anyErr = fmt.Errorf("maxLength")
 		}
 	}
@@ -288,13 +289,13 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 		case attrValRequired:
 			if !hasTimezone {
 				msg := fmt.Sprintf("[facet 'explicitTimezone'] The value '%s' must have an explicit timezone.", val)
-				vc.reportValidityError(ctx, filename, line, elemName, msg)
+				vc.reportValidityError(ctx, filename, node, elemName, msg)
 				anyErr = fmt.Errorf("explicitTimezone")
 			}
 		case attrValProhibited:
 			if hasTimezone {
 				msg := fmt.Sprintf("[facet 'explicitTimezone'] The value '%s' must not have an explicit timezone.", val)
-				vc.reportValidityError(ctx, filename, line, elemName, msg)
+				vc.reportValidityError(ctx, filename, node, elemName, msg)
 				anyErr = fmt.Errorf("explicitTimezone")
 			}
 		}
@@ -324,7 +325,7 @@ func checkFacets(ctx context.Context, val string, valueNS map[string]string, fs
 			} else {
 				msg = fmt.Sprintf("[facet 'pattern'] The value '%s' is not accepted by the patterns '%s'.", val, strings.Join(fs.Patterns, "', '"))
 			}
-			vc.reportValidityError(ctx, filename, line, elemName, msg)
+			vc.reportValidityError(ctx, filename, node, elemName, msg)
 			anyErr = fmt.Errorf("pattern")
 		}
 	}
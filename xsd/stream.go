@@ -0,0 +1,650 @@
+package xsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/internal/xmlchar"
+	"github.com/lestrrat-go/helium/stream"
+)
+
+// StreamValidator is a [stream.Backend] that validates a document against a
+// schema while a [stream.Writer] produces it, so a generator learns about an
+// invalid element or attribute at the call that writes it instead of after
+// the whole document exists. Put it before the backend that stores the
+// output, so that [stream.Tee] stops before invalid content is written:
+//
+//	var buf bytes.Buffer
+//	out := stream.NewWriter(&buf)
+//	w := stream.NewBackendWriter(stream.Tee(xsd.NewStreamValidator(ctx, schema), stream.WriterBackend(&out)))
+//
+// [NewValidatingWriter] builds that pipeline. Only the open elements and
+// what ID/IDREF checking needs are kept, so memory grows with the number of
+// IDs in the document, not with its size.
+//
+// A Writer reports a start tag once its attributes are complete, so a bad
+// element or attribute fails the first call after the start tag's last
+// attribute. The error unwraps to a [*ValidationError]; when a child element
+// is not allowed, or an element ends while required children are missing,
+// its Expected field lists the particles that would have been accepted.
+//
+// ID/IDREF integrity is checked as well: a duplicate ID fails the call that
+// writes it, and an IDREF that matches no ID fails EndDocument, so the IDs
+// and the IDREFs not yet matched are kept until then.
+//
+// Identity constraints (xs:key, xs:unique, xs:keyref) and XSD 1.1
+// assertions on elements with element content need the whole subtree of
+// their element. An element declared with identity constraints, or whose
+// type has assertions and element content, fails with an error wrapping
+// [errors.ErrUnsupported]; use [Validator] on the finished document for
+// such schemas. A reference to an entity other than the predefined ones
+// cannot be validated without its replacement text and fails the same way.
+type StreamValidator struct {
+	ctx      context.Context //nolint:containedctx // Backend methods have no ctx parameter
+	schema   *Schema
+	vc       *validationContext
+	errs     *streamErrors
+	models   *contentModels
+	doc      *helium.Document
+	ids      *idCollector
+	stack    []*streamFrame
+	rootSeen bool
+}
+
+// streamLabel is the Filename of the errors a StreamValidator reports.
+const streamLabel = "(stream)"
+
+// streamFrameMode is how the content of an open element is validated.
+type streamFrameMode int
+
+const (
+	frameSkip   streamFrameMode = iota // not assessed: skip wildcard, or lax with no declaration or type
+	frameLax                           // child elements are assessed laxly (no model group, e.g. xs:anyType)
+	frameModel                         // child elements are matched against the content model
+	frameValue                         // simple or empty content, checked at the end tag
+	frameNilled                        // xsi:nil="true": no content at all
+)
+
+// streamFrame is an open element.
+type streamFrame struct {
+	// elem holds the element's name, attributes and namespace declarations,
+	// linked under its parent's elem so the existing element checks see the
+	// in-scope namespaces and inherited attributes.
+	elem   *helium.Element
+	decl   *ElementDecl
+	td     *TypeDef
+	mode   streamFrameMode
+	model  *cmNode
+	open   *OpenContent
+	suffix bool // the suffix open content has started
+	text   strings.Builder
+}
+
+// streamErrors collects the validity errors of one event.
+type streamErrors struct {
+	errs []error
+}
+
+func (c *streamErrors) Handle(_ context.Context, err error) {
+	if le, ok := err.(interface{ ErrorLevel() helium.ErrorLevel }); ok && le.ErrorLevel() < helium.ErrorLevelError {
+		return
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		err = ve
+	}
+	c.errs = append(c.errs, err)
+}
+
+// NewStreamValidator creates a StreamValidator for schema. ctx is passed to
+// the checks the validator runs.
+func NewStreamValidator(ctx context.Context, schema *Schema) *StreamValidator {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &StreamValidator{ctx: ctx, schema: schema}
+}
+
+// NewValidatingWriter returns a [stream.Writer] that validates the document
+// against schema as it is written and passes valid events on to out. An
+// invalid event is not written; the error it causes is returned and kept by
+// the Writer, as with any backend error.
+func NewValidatingWriter(ctx context.Context, out *stream.Writer, schema *Schema) stream.Writer {
+	return stream.NewBackendWriter(stream.Tee(NewStreamValidator(ctx, schema), stream.WriterBackend(out)))
+}
+
+func (s *StreamValidator) StartDocument(string, string, string) error {
+	if s.schema == nil {
+		return ErrNilSchema
+	}
+	s.errs = &streamErrors{}
+	s.vc = newValidationContext(s.schema, &validateConfig{}, streamLabel, s.errs)
+	s.models = newContentModels(s.schema)
+	s.doc = helium.NewDocument("1.0", "", helium.StandaloneImplicitNo)
+	s.ids = &idCollector{ids: make(map[string]helium.Node), valid: true}
+	s.stack = s.stack[:0]
+	s.rootSeen = false
+	return nil
+}
+
+func (s *StreamValidator) EndDocument() error {
+	if !s.rootSeen {
+		return fmt.Errorf("xsd: the document has no document element: %w", ErrValidationFailed)
+	}
+	s.vc.resolveIDRefs(s.ctx, s.ids)
+	return s.result(nil)
+}
+
+func (s *StreamValidator) DocumentType(string, string, string, string) error {
+	return nil
+}
+
+func (s *StreamValidator) StartElement(name stream.Name, namespaces []stream.Namespace, attrs []stream.Attribute) error {
+	if s.vc == nil {
+		return ErrNilSchema
+	}
+	var parent *streamFrame
+	if n := len(s.stack); n > 0 {
+		parent = s.stack[n-1]
+	} else if s.rootSeen {
+		return fmt.Errorf("xsd: second document element %q: %w", name.QName(), ErrValidationFailed)
+	}
+	elem, err := s.scratchElement(parent, name, namespaces, attrs)
+	if err != nil {
+		return err
+	}
+	f := &streamFrame{elem: elem}
+	s.stack = append(s.stack, f)
+	if parent == nil {
+		s.rootSeen = true
+		err = s.assessRoot(f)
+	} else {
+		err = s.assessChild(parent, f)
+	}
+	if err == nil && len(s.errs.errs) == 0 {
+		s.collectIDs(func() { s.vc.collectAttributeIDs(s.ctx, s.ids, f.elem) })
+	}
+	return s.result(err)
+}
+
+// collectIDs runs collect, which records ID and IDREF values, and drops the
+// IDREFs it recorded that match an ID already seen.
+func (s *StreamValidator) collectIDs(collect func()) {
+	n := len(s.ids.refs)
+	collect()
+	pending := slices.DeleteFunc(s.ids.refs[n:], func(r idRefOccurrence) bool {
+		_, ok := s.ids.ids[r.value]
+		return ok
+	})
+	s.ids.refs = s.ids.refs[:n+len(pending)]
+}
+
+// scratchElement builds the element the checks run on.
+func (s *StreamValidator) scratchElement(parent *streamFrame, name stream.Name, namespaces []stream.Namespace, attrs []stream.Attribute) (*helium.Element, error) {
+	e, err := s.doc.CreateElement(name.LocalName)
+	if err != nil {
+		return nil, err
+	}
+	for _, ns := range namespaces {
+		if err := e.DeclareNamespace(ns.Prefix, ns.URI); err != nil {
+			return nil, err
+		}
+	}
+	if name.NamespaceURI != "" {
+		ns, err := s.doc.CreateNamespace(name.Prefix, name.NamespaceURI)
+		if err != nil {
+			return nil, err
+		}
+		e.SetNamespace(ns)
+	}
+	for _, a := range attrs {
+		if a.NamespaceURI == "" {
+			if err := e.SetAttribute(a.LocalName, a.Value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		ns, err := s.doc.CreateNamespace(a.Prefix, a.NamespaceURI)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.SetAttributeNS(a.LocalName, a.Value, ns); err != nil {
+			return nil, err
+		}
+	}
+	if parent != nil {
+		if err := parent.elem.AddChild(e); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// assessRoot mirrors validateRootElement.
+func (s *StreamValidator) assessRoot(f *streamFrame) error {
+	vc := s.vc
+	edecl, ok := s.schema.LookupElement(f.elem.LocalName(), f.elem.URI())
+	if !ok {
+		td, err := vc.resolveXsiType(s.ctx, f.elem, nil, false)
+		if err != nil {
+			return err
+		}
+		if td == nil {
+			vc.reportValidityError(s.ctx, vc.filename, f.elem, f.elem.LocalName(), "No matching global declaration available for the validation root.")
+			return fmt.Errorf("no matching global declaration")
+		}
+		return s.assessUndeclared(f, td)
+	}
+	if edecl.Abstract {
+		vc.reportValidityError(s.ctx, vc.filename, f.elem, elemDisplayName(f.elem), msgAbstractElement)
+		return fmt.Errorf("abstract element")
+	}
+	return s.assessDecl(f, edecl, nil)
+}
+
+// assessChild matches a child element against its parent's content and
+// decides how the child is validated.
+func (s *StreamValidator) assessChild(parent, f *streamFrame) error {
+	vc := s.vc
+	local, ns := f.elem.LocalName(), f.elem.URI()
+	switch parent.mode {
+	case frameSkip:
+		f.mode = frameSkip
+		return nil
+	case frameNilled:
+		vc.reportValidityError(s.ctx, vc.filename, f.elem, elemDisplayName(f.elem),
+			"This element is not expected, because the element '"+elemDisplayName(parent.elem)+"' is nilled.")
+		return fmt.Errorf("content in nilled element")
+	case frameValue:
+		if parent.td.ContentType == ContentTypeSimple {
+			vc.reportValidityError(s.ctx, vc.filename, parent.elem, parent.elem.LocalName(),
+				"Element content is not allowed, because the content type is a simple type definition.")
+			return fmt.Errorf("element content not allowed")
+		}
+		vc.reportValidityError(s.ctx, vc.filename, f.elem, local, "This element is not expected.")
+		return fmt.Errorf("not expected")
+	}
+	if parent.td != nil && parent.td.ContentType == ContentTypeMixed && parent.decl != nil && parent.decl.Fixed != nil {
+		msg := fmt.Sprintf("Element children are not allowed because the element declaration has a fixed value constraint '%s'.", *parent.decl.Fixed)
+		vc.reportValidityError(s.ctx, vc.filename, parent.elem, elemDisplayName(parent.elem), msg)
+		return fmt.Errorf("fixed value constraint")
+	}
+	if parent.mode == frameLax {
+		edecl := lookupElemDecl(f.elem, s.schema)
+		if edecl != nil {
+			return s.assessDecl(f, edecl, nil)
+		}
+		if td, ok := vc.resolveXsiTypeQuiet(f.elem); ok {
+			return s.assessUndeclared(f, td)
+		}
+		f.mode = frameLax
+		return nil
+	}
+
+	var match cmMatch
+	d := s.models.notAllowed
+	if !parent.suffix {
+		d, match = s.models.derive(parent.model, local, ns)
+	}
+	if d.kind != cmNotAllowed {
+		parent.model = d
+	} else {
+		oc := parent.open
+		suffixOK := oc != nil && (oc.Mode == OpenContentInterleave || parent.suffix || parent.model.nullable)
+		if !suffixOK || !wildcardAllowsExpandedName(oc.Wildcard, local, ns, s.schema, false) {
+			s.notExpected(parent, f)
+			return fmt.Errorf("not expected")
+		}
+		if oc.Mode == OpenContentSuffix {
+			parent.suffix = true
+		}
+		match = cmMatch{wc: oc.Wildcard}
+	}
+	if match.wc != nil {
+		return s.assessWildcard(parent, f, match.wc)
+	}
+	return s.assessDecl(f, match.decl, nil)
+}
+
+// notExpected reports a child its parent's content model does not admit,
+// with the particles that would have been admitted.
+func (s *StreamValidator) notExpected(parent, f *streamFrame) {
+	var names []string
+	if !parent.suffix {
+		names = s.models.expected(parent.model)
+	}
+	if oc := parent.open; oc != nil && (oc.Mode == OpenContentInterleave || parent.suffix || parent.model.nullable) {
+		names = append(names, wildcardExpected(oc.Wildcard))
+	}
+	msg := "This element is not expected."
+	if len(names) > 0 {
+		msg = formatExpected(msg, names)
+	}
+	s.report(f.elem, msg, names)
+}
+
+// report sends a content-model error carrying the expected particles.
+func (s *StreamValidator) report(elem *helium.Element, msg string, expected []string) {
+	ve := &ValidationError{
+		Filename: s.vc.filename,
+		Element:  elemDisplayName(elem),
+		Message:  msg,
+		Expected: expected,
+	}
+	s.vc.errorHandler.Handle(s.ctx, newLeveledValidationError(ve, helium.ErrorLevelError))
+}
+
+// assessWildcard mirrors validateWildcardChild for a child admitted by wc.
+func (s *StreamValidator) assessWildcard(parent, f *streamFrame, wc *Wildcard) error {
+	vc := s.vc
+	if wc.ProcessContents == ProcessSkip {
+		f.mode = frameSkip
+		return nil
+	}
+	edecl := lookupElemDecl(f.elem, s.schema)
+	if edecl != nil {
+		return s.assessDecl(f, edecl, parent)
+	}
+	actual, ok := vc.resolveXsiTypeQuiet(f.elem)
+	if !ok {
+		if wc.ProcessContents == ProcessStrict {
+			vc.reportValidityError(s.ctx, vc.filename, f.elem, elemDisplayName(f.elem), "No matching global declaration available, but demanded by the strict wildcard.")
+			return fmt.Errorf("strict wildcard: no global element decl")
+		}
+		f.mode = frameLax
+		return nil
+	}
+	if err := s.checkConsistent(parent, f, actual); err != nil {
+		return err
+	}
+	return s.assessUndeclared(f, actual)
+}
+
+// checkConsistent runs the XSD 1.1 Element Declarations Consistent check
+// for a child a wildcard of parent admitted.
+func (s *StreamValidator) checkConsistent(parent, f *streamFrame, td *TypeDef) error {
+	if parent == nil || parent.td == nil {
+		return nil
+	}
+	prev := s.vc.edcType
+	s.vc.edcType = parent.td
+	defer func() { s.vc.edcType = prev }()
+	child := childElem{elem: f.elem, name: f.elem.LocalName(), ns: f.elem.URI(), displayName: elemDisplayName(f.elem)}
+	return s.vc.validateWildcardElementConsistent(s.ctx, parent.td.ContentModel, child, td)
+}
+
+// assessDecl resolves the governing type of an element with a declaration,
+// as matchElementParticle does, and starts validating it. wildcardParent
+// is the parent whose wildcard admitted the element, if any.
+func (s *StreamValidator) assessDecl(f *streamFrame, edecl *ElementDecl, wildcardParent *streamFrame) error {
+	vc := s.vc
+	f.decl = edecl
+	if host := s.hostDecl(f); host != nil && len(host.IDCs) > 0 {
+		return fmt.Errorf("xsd: identity constraints of element %q cannot be checked while streaming: %w", elemDisplayName(f.elem), errors.ErrUnsupported)
+	}
+	declType := effectiveDeclType(edecl, s.schema)
+	if err := vc.rejectMissingTypeRef(s.ctx, f.elem, declType); err != nil {
+		return err
+	}
+	declType = vc.applyTypeAlternatives(s.ctx, f.elem, edecl, declType)
+	td, err := vc.resolveXsiType(s.ctx, f.elem, declType, vc.hasTypeTable(edecl))
+	if err != nil {
+		return err
+	}
+	if td != declType && declType != nil && typeDerivationBlocked(td, declType, edecl.Block) {
+		vc.reportValidityError(s.ctx, vc.filename, f.elem, elemDisplayName(f.elem), "The xsi:type definition is blocked by the element declaration.")
+		return fmt.Errorf("blocked xsi:type")
+	}
+	if td != nil && td.Abstract {
+		vc.reportValidityError(s.ctx, vc.filename, f.elem, elemDisplayName(f.elem), msgAbstractType)
+		return fmt.Errorf("abstract type")
+	}
+	if err := s.checkConsistent(wildcardParent, f, td); err != nil {
+		return err
+	}
+	if td == nil {
+		f.mode = frameSkip
+		return nil
+	}
+	nilled, err := vc.checkXsiNil(s.ctx, f.elem, edecl)
+	if err != nil {
+		return err
+	}
+	if nilled {
+		// The element has no children yet, so this checks the declaration
+		// and the attributes; content is rejected as it arrives.
+		f.td = td
+		f.mode = frameNilled
+		return vc.validateNilledElement(s.ctx, f.elem, edecl, td)
+	}
+	return s.assessContent(f, edecl, td)
+}
+
+// assessUndeclared starts validating an element with no declaration whose
+// type came from xsi:type, as validateUndeclaredElementWithType does.
+func (s *StreamValidator) assessUndeclared(f *streamFrame, td *TypeDef) error {
+	vc := s.vc
+	if td.Abstract {
+		vc.reportValidityError(s.ctx, vc.filename, f.elem, elemDisplayName(f.elem), msgAbstractType)
+		return fmt.Errorf("abstract type")
+	}
+	if _, err := vc.checkXsiNil(s.ctx, f.elem, nil); err != nil {
+		return err
+	}
+	return s.assessContent(f, nil, td)
+}
+
+// assessContent checks the attributes and sets up content validation, as
+// validateElementContent and validateContentByType do for a whole element.
+func (s *StreamValidator) assessContent(f *streamFrame, edecl *ElementDecl, td *TypeDef) error {
+	vc := s.vc
+	f.decl = edecl
+	f.td = td
+	f.mode = frameValue
+	if err := vc.rejectMissingTypeRef(s.ctx, f.elem, td); err != nil {
+		return err
+	}
+	if vc.version == Version11 && isErrorType(td) {
+		vc.reportValidityError(s.ctx, vc.filename, f.elem, elemDisplayName(f.elem),
+			"The element is not valid: the conditional type assignment selected the type xs:error.")
+		return fmt.Errorf("xs:error type selected")
+	}
+	if err := vc.validateAttributes(s.ctx, f.elem, td); err != nil {
+		return err
+	}
+	s.contentMode(f, td)
+	if (f.mode == frameModel || f.mode == frameLax) && vc.version == Version11 && typeHasAssertions(td) {
+		return fmt.Errorf("xsd: assertions on element %q with element content cannot be checked while streaming: %w", elemDisplayName(f.elem), errors.ErrUnsupported)
+	}
+	return nil
+}
+
+// contentMode decides how the content of f, of type td, is validated.
+func (s *StreamValidator) contentMode(f *streamFrame, td *TypeDef) {
+	vc := s.vc
+	var oc *OpenContent
+	if vc.version == Version11 {
+		oc = td.OpenContent
+	}
+	switch td.ContentType {
+	case ContentTypeEmpty:
+		if oc == nil {
+			return
+		}
+	case ContentTypeElementOnly, ContentTypeMixed:
+		if td.ContentType == ContentTypeElementOnly && vc.version == Version11 && oc == nil &&
+			(td.ContentModel == nil || !modelGroupHasContent(td.ContentModel)) {
+			return
+		}
+		if td.ContentModel == nil && oc == nil {
+			if td.ContentType == ContentTypeMixed {
+				f.mode = frameLax
+			}
+			return
+		}
+	default:
+		return
+	}
+	f.mode = frameModel
+	f.open = oc
+	f.model = s.models.empty
+	if td.ContentModel != nil {
+		f.model = s.models.compile(td.ContentModel)
+	}
+	return
+}
+
+func (s *StreamValidator) EndElement(stream.Name) error {
+	n := len(s.stack)
+	if n == 0 {
+		return nil
+	}
+	f := s.stack[n-1]
+	s.stack = s.stack[:n-1]
+	defer s.release(f)
+	return s.result(s.finish(f))
+}
+
+// finish runs the checks that need the whole content of f.
+func (s *StreamValidator) finish(f *streamFrame) error {
+	vc := s.vc
+	if f.text.Len() > 0 {
+		if err := f.elem.AppendText([]byte(f.text.String())); err != nil {
+			return err
+		}
+	}
+	switch f.mode {
+	case frameModel:
+		if !f.suffix && !f.model.nullable {
+			names := s.models.expected(f.model)
+			msg := "Missing child element(s)."
+			if len(names) > 0 {
+				msg = formatExpected(msg, names)
+			}
+			s.report(f.elem, msg, names)
+			return fmt.Errorf("missing")
+		}
+		fallthrough
+	case frameLax:
+		if f.td != nil && f.td.ContentType == ContentTypeMixed && f.decl != nil && f.decl.Fixed != nil {
+			return vc.validateMixedFixed(s.ctx, f.elem, f.decl)
+		}
+	case frameValue:
+		if err := vc.validateContentByType(s.ctx, f.elem, f.decl, f.td); err != nil {
+			return err
+		}
+		if vc.version == Version11 {
+			if err := vc.checkAssertions(s.ctx, f.elem, f.decl, f.td); err != nil {
+				return err
+			}
+		}
+		if len(s.errs.errs) == 0 && f.td.ContentType == ContentTypeSimple && idFamilyType(f.td) {
+			s.collectIDs(func() { vc.collectContentID(s.ctx, s.ids, f.elem, f.td, s.hostDecl(f)) })
+		}
+	}
+	return nil
+}
+
+// hostDecl returns the declaration of f that carries its identity
+// constraints and value constraint, as idcHostDecl does: the declaration
+// the element matched, or the global one when that is a reference.
+func (s *StreamValidator) hostDecl(f *streamFrame) *ElementDecl {
+	if f.decl != nil && !f.decl.IsRef {
+		return f.decl
+	}
+	return lookupElemDecl(f.elem, s.schema)
+}
+
+// release unlinks the scratch element of a closed frame and drops what the
+// checks recorded about it.
+func (s *StreamValidator) release(f *streamFrame) {
+	vc := s.vc
+	e := f.elem
+	for _, a := range e.Attributes() {
+		delete(vc.attrInheritable, a)
+		delete(vc.actualAttrType, a)
+		delete(vc.assessedAttrs, a)
+		delete(vc.assertAnnotations, a)
+	}
+	delete(vc.actualElemType, e)
+	delete(vc.actualElemDecl, e)
+	delete(vc.assessedElemType, e)
+	delete(vc.skipContentNodes, e)
+	delete(vc.assertEffectiveValues, e)
+	delete(vc.assertAnnotations, e)
+	helium.UnlinkNode(e)
+}
+
+func (s *StreamValidator) Text(text string) error {
+	n := len(s.stack)
+	if n == 0 {
+		return nil
+	}
+	f := s.stack[n-1]
+	vc := s.vc
+	switch f.mode {
+	case frameNilled:
+		if vc.version == Version11 || !xmlchar.IsAllSpace([]byte(text)) {
+			vc.reportValidityError(s.ctx, vc.filename, f.elem, elemDisplayName(f.elem), "Character content is not allowed, because the element is nilled.")
+			return s.result(fmt.Errorf("content in nilled element"))
+		}
+	case frameValue:
+		f.text.WriteString(text)
+	case frameModel, frameLax:
+		if f.td == nil {
+			return nil
+		}
+		if f.td.ContentType != ContentTypeMixed {
+			if !xmlchar.IsAllSpace([]byte(text)) {
+				vc.reportValidityError(s.ctx, vc.filename, f.elem, elemDisplayName(f.elem), "Character content other than whitespace is not allowed because the content type is 'element-only'.")
+				return s.result(fmt.Errorf("text content in element-only type"))
+			}
+			return nil
+		}
+		if f.decl != nil && f.decl.Fixed != nil {
+			f.text.WriteString(text)
+		}
+	}
+	return nil
+}
+
+func (s *StreamValidator) CDATA(text string) error {
+	return s.Text(text)
+}
+
+func (s *StreamValidator) Comment(string) error {
+	return nil
+}
+
+func (s *StreamValidator) ProcessingInstruction(string, string) error {
+	return nil
+}
+
+func (s *StreamValidator) EntityReference(name string) error {
+	if n := len(s.stack); n == 0 || s.stack[n-1].mode == frameSkip {
+		return nil
+	}
+	return fmt.Errorf("xsd: entity reference &%s; cannot be validated while streaming: %w", name, errors.ErrUnsupported)
+}
+
+// result turns what the checks of one event reported into the error the
+// backend returns.
+func (s *StreamValidator) result(err error) error {
+	errs := s.errs.errs
+	s.errs.errs = nil
+	switch {
+	case errors.Is(err, errors.ErrUnsupported):
+		return err
+	case len(errs) == 1:
+		return errs[0]
+	case len(errs) > 1:
+		return errors.Join(errs...)
+	case err != nil:
+		return fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,379 @@
+package xsd
+
+import (
+	"slices"
+	"strconv"
+)
+
+// Incremental content-model matching for StreamValidator.
+//
+// The document matchers (matchSequence/matchChoice/matchAll and the bounded
+// backtracker) need every child of an element before they can decide, so they
+// cannot reject a child as it is written. The streaming matcher instead keeps
+// a residual content model per open element: the Brzozowski derivative of the
+// model with respect to the children seen so far. A child is accepted exactly
+// when the derivative is not cmNotAllowed, and the element may end exactly when
+// the residual is nullable, so both "not expected" and "missing" are decided
+// at the event that causes them, with no backtracking.
+//
+// Occurrence bounds stay symbolic (cmRepeat keeps min/max counters rather than
+// unrolling the particle), so maxOccurs="1000000" costs the same as
+// maxOccurs="2". Residuals are hash-consed so that equal alternatives collapse
+// and derivatives can be memoized by pointer; both tables are reset when they
+// grow past cmTableLimit, which only costs recomputation.
+
+// cmTableLimit bounds the interned-node and derivative tables of a
+// contentModels.
+const cmTableLimit = 1 << 16
+
+type cmKind uint8
+
+const (
+	cmNotAllowed cmKind = iota // matches nothing, not even the empty sequence
+	cmEmpty                    // matches only the empty sequence
+	cmElement                  // one element matching decl (or a substitute)
+	cmWildcard                 // one element allowed by wc
+	cmSequence                 // left followed by right
+	cmChoice                   // left or right
+	cmRepeat                   // left repeated min..max times
+	cmAll                      // xs:all members in any order, counts so far
+)
+
+// cmNode is an interned content-model expression.
+type cmNode struct {
+	kind     cmKind
+	left     *cmNode
+	right    *cmNode
+	decl     *ElementDecl
+	wc       *Wildcard
+	min, max int
+	all      *cmAllGroup
+	counts   []int // occurrences per member of all so far
+	nullable bool
+}
+
+// cmAllGroup is the flattened member list of an xs:all group.
+type cmAllGroup struct {
+	members []allMember
+}
+
+type cmKey struct {
+	kind        cmKind
+	left, right *cmNode
+	decl        *ElementDecl
+	wc          *Wildcard
+	min, max    int
+	all         *cmAllGroup
+	counts      string
+}
+
+type cmDerivKey struct {
+	node      *cmNode
+	local, ns string
+	wild      bool
+}
+
+// cmMatch is the particle term that admitted a child: an element
+// declaration (already resolved to a substitution-group member) or a
+// wildcard.
+type cmMatch struct {
+	decl *ElementDecl
+	wc   *Wildcard
+}
+
+type cmDeriv struct {
+	node  *cmNode
+	match cmMatch
+}
+
+// contentModels compiles and derives content models for one schema.
+type contentModels struct {
+	schema     *Schema
+	is11       bool
+	nodes      map[cmKey]*cmNode
+	derivs     map[cmDerivKey]cmDeriv
+	compiled   map[*ModelGroup]*cmNode
+	groups     map[*ModelGroup]*cmAllGroup
+	notAllowed *cmNode
+	empty      *cmNode
+}
+
+func newContentModels(schema *Schema) *contentModels {
+	m := &contentModels{
+		schema:     schema,
+		is11:       schema != nil && schema.version == Version11,
+		compiled:   make(map[*ModelGroup]*cmNode),
+		groups:     make(map[*ModelGroup]*cmAllGroup),
+		notAllowed: &cmNode{kind: cmNotAllowed},
+		empty:      &cmNode{kind: cmEmpty, nullable: true},
+	}
+	m.resetTables()
+	return m
+}
+
+func (m *contentModels) resetTables() {
+	m.nodes = make(map[cmKey]*cmNode)
+	m.derivs = make(map[cmDerivKey]cmDeriv)
+}
+
+// intern returns the canonical node equal to n.
+func (m *contentModels) intern(n *cmNode) *cmNode {
+	key := cmKey{kind: n.kind, left: n.left, right: n.right, decl: n.decl, wc: n.wc, min: n.min, max: n.max, all: n.all}
+	if n.counts != nil {
+		var b []byte
+		for _, c := range n.counts {
+			b = strconv.AppendInt(b, int64(c), 10)
+			b = append(b, ',')
+		}
+		key.counts = string(b)
+	}
+	if found, ok := m.nodes[key]; ok {
+		return found
+	}
+	if len(m.nodes) >= cmTableLimit {
+		m.resetTables()
+	}
+	m.nodes[key] = n
+	return n
+}
+
+func (m *contentModels) sequence(a, b *cmNode) *cmNode {
+	switch {
+	case a.kind == cmNotAllowed || b.kind == cmNotAllowed:
+		return m.notAllowed
+	case a.kind == cmEmpty:
+		return b
+	case b.kind == cmEmpty:
+		return a
+	}
+	return m.intern(&cmNode{kind: cmSequence, left: a, right: b, nullable: a.nullable && b.nullable})
+}
+
+// choice builds a right-nested choice of the distinct alternatives of a and
+// b, in order, so that equal residuals reached along different paths do
+// not accumulate.
+func (m *contentModels) choice(a, b *cmNode) *cmNode {
+	switch {
+	case a.kind == cmNotAllowed:
+		return b
+	case b.kind == cmNotAllowed:
+		return a
+	case a == b:
+		return a
+	}
+	alts := appendChoiceAlternatives(nil, a)
+	for _, n := range appendChoiceAlternatives(nil, b) {
+		if !slices.Contains(alts, n) {
+			alts = append(alts, n)
+		}
+	}
+	result := alts[len(alts)-1]
+	for i := len(alts) - 2; i >= 0; i-- {
+		result = m.intern(&cmNode{kind: cmChoice, left: alts[i], right: result, nullable: alts[i].nullable || result.nullable})
+	}
+	return result
+}
+
+func appendChoiceAlternatives(alts []*cmNode, n *cmNode) []*cmNode {
+	for n.kind == cmChoice {
+		alts = append(alts, n.left)
+		n = n.right
+	}
+	return append(alts, n)
+}
+
+func (m *contentModels) repeat(e *cmNode, minOccurs, maxOccurs int) *cmNode {
+	switch {
+	case maxOccurs == 0 || e.kind == cmEmpty:
+		return m.empty
+	case e.kind == cmNotAllowed:
+		if minOccurs == 0 {
+			return m.empty
+		}
+		return m.notAllowed
+	case minOccurs == 1 && maxOccurs == 1:
+		return e
+	}
+	return m.intern(&cmNode{kind: cmRepeat, left: e, min: minOccurs, max: maxOccurs, nullable: minOccurs == 0 || e.nullable})
+}
+
+func (m *contentModels) allNode(g *cmAllGroup, counts []int) *cmNode {
+	nullable := true
+	for i, member := range g.members {
+		if counts[i] < member.min {
+			nullable = false
+			break
+		}
+	}
+	return m.intern(&cmNode{kind: cmAll, all: g, counts: counts, nullable: nullable})
+}
+
+// compile returns the initial residual of a model group.
+func (m *contentModels) compile(mg *ModelGroup) *cmNode {
+	if n, ok := m.compiled[mg]; ok {
+		return n
+	}
+	// Mark the group while it is compiled so a cyclic group reference
+	// (rejected by the compiler, but never worth a stack overflow) matches
+	// nothing.
+	m.compiled[mg] = m.notAllowed
+	var body *cmNode
+	switch mg.Compositor {
+	case CompositorAll:
+		g := &cmAllGroup{members: flattenAllMembers(mg, m.is11)}
+		m.groups[mg] = g
+		body = m.allNode(g, make([]int, len(g.members)))
+	case CompositorChoice:
+		body = m.notAllowed
+		for i := len(mg.Particles) - 1; i >= 0; i-- {
+			body = m.choice(m.compileParticle(mg.Particles[i]), body)
+		}
+	default:
+		body = m.empty
+		for i := len(mg.Particles) - 1; i >= 0; i-- {
+			body = m.sequence(m.compileParticle(mg.Particles[i]), body)
+		}
+	}
+	n := m.repeat(body, mg.MinOccurs, mg.MaxOccurs)
+	m.compiled[mg] = n
+	return n
+}
+
+// compileParticle compiles one particle. The occurrence range of a nested
+// group lives on the ModelGroup itself, as the document matchers read it.
+func (m *contentModels) compileParticle(p *Particle) *cmNode {
+	switch term := p.Term.(type) {
+	case *ElementDecl:
+		return m.repeat(m.intern(&cmNode{kind: cmElement, decl: term}), p.MinOccurs, p.MaxOccurs)
+	case *Wildcard:
+		return m.repeat(m.intern(&cmNode{kind: cmWildcard, wc: term}), p.MinOccurs, p.MaxOccurs)
+	case *ModelGroup:
+		return m.compile(term)
+	}
+	return m.empty
+}
+
+// derive returns the residual of n after a child named {ns}local, and the
+// term that admitted it. An element declaration takes precedence over a
+// wildcard that would also admit the child (XSD 1.1 §3.8.4.2); under XSD
+// 1.0 the two never compete, since the schema obeys UPA.
+func (m *contentModels) derive(n *cmNode, local, ns string) (*cmNode, cmMatch) {
+	if d, match := m.deriveMode(n, local, ns, false); d.kind != cmNotAllowed {
+		return d, match
+	}
+	return m.deriveMode(n, local, ns, true)
+}
+
+func (m *contentModels) deriveMode(n *cmNode, local, ns string, wild bool) (*cmNode, cmMatch) {
+	key := cmDerivKey{node: n, local: local, ns: ns, wild: wild}
+	if d, ok := m.derivs[key]; ok {
+		return d.node, d.match
+	}
+	var match cmMatch
+	d := m.deriveNode(n, local, ns, wild, &match)
+	if len(m.derivs) >= cmTableLimit {
+		m.derivs = make(map[cmDerivKey]cmDeriv)
+	}
+	m.derivs[key] = cmDeriv{node: d, match: match}
+	return d, match
+}
+
+// deriveNode computes the derivative, recording the first admitting term in
+// match.
+func (m *contentModels) deriveNode(n *cmNode, local, ns string, wild bool, match *cmMatch) *cmNode {
+	switch n.kind {
+	case cmElement:
+		child := childElem{name: local, ns: ns}
+		if !elemMatchesDeclOrSubst(child, n.decl, m.schema) {
+			return m.notAllowed
+		}
+		if match.decl == nil && match.wc == nil {
+			match.decl = resolveSubstDecl(child, n.decl, m.schema)
+		}
+		return m.empty
+	case cmWildcard:
+		if !wild || !wildcardAllowsExpandedName(n.wc, local, ns, m.schema, false) {
+			return m.notAllowed
+		}
+		if match.decl == nil && match.wc == nil {
+			match.wc = n.wc
+		}
+		return m.empty
+	case cmSequence:
+		d := m.sequence(m.deriveNode(n.left, local, ns, wild, match), n.right)
+		if n.left.nullable {
+			d = m.choice(d, m.deriveNode(n.right, local, ns, wild, match))
+		}
+		return d
+	case cmChoice:
+		return m.choice(m.deriveNode(n.left, local, ns, wild, match), m.deriveNode(n.right, local, ns, wild, match))
+	case cmRepeat:
+		d := m.deriveNode(n.left, local, ns, wild, match)
+		if d.kind == cmNotAllowed {
+			return d
+		}
+		return m.sequence(d, m.repeat(n.left, max(n.min-1, 0), decOccurs(n.max)))
+	case cmAll:
+		d := m.notAllowed
+		for i, member := range n.all.members {
+			if member.max != Unbounded && n.counts[i] >= member.max {
+				continue
+			}
+			term := &cmNode{kind: cmElement, decl: member.ed}
+			if member.wc != nil {
+				term = &cmNode{kind: cmWildcard, wc: member.wc}
+			}
+			if m.deriveNode(term, local, ns, wild, match).kind == cmNotAllowed {
+				continue
+			}
+			counts := slices.Clone(n.counts)
+			counts[i]++
+			d = m.choice(d, m.allNode(n.all, counts))
+		}
+		return d
+	}
+	return m.notAllowed
+}
+
+func decOccurs(n int) int {
+	if n == Unbounded {
+		return n
+	}
+	return n - 1
+}
+
+// expected lists the particles that could admit the next child of n, in
+// the form the document matchers use for "Expected is ( ... )".
+func (m *contentModels) expected(n *cmNode) []string {
+	var names []string
+	m.appendExpected(&names, n)
+	return names
+}
+
+func (m *contentModels) appendExpected(names *[]string, n *cmNode) {
+	add := func(list ...string) {
+		for _, name := range list {
+			if !slices.Contains(*names, name) {
+				*names = append(*names, name)
+			}
+		}
+	}
+	switch n.kind {
+	case cmElement:
+		add(elementExpectedNamesWithSubst(n.decl, m.schema)...)
+	case cmWildcard:
+		add(wildcardExpected(n.wc))
+	case cmSequence:
+		m.appendExpected(names, n.left)
+		if n.left.nullable {
+			m.appendExpected(names, n.right)
+		}
+	case cmChoice:
+		m.appendExpected(names, n.left)
+		m.appendExpected(names, n.right)
+	case cmRepeat:
+		m.appendExpected(names, n.left)
+	case cmAll:
+		add(availableMemberNames(n.all.members, n.counts, m.schema)...)
+	}
+}
@@ -0,0 +1,335 @@
+package xsd_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	helium "github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/sax"
+	"github.com/lestrrat-go/helium/stream"
+	"github.com/lestrrat-go/helium/xsd"
+	"github.com/stretchr/testify/require"
+)
+
+const streamSchema = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="filing">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="header" type="xs:string"/>
+        <xs:element name="entry" maxOccurs="unbounded">
+          <xs:complexType>
+            <xs:simpleContent>
+              <xs:extension base="xs:decimal">
+                <xs:attribute name="code" type="xs:NCName" use="required"/>
+              </xs:extension>
+            </xs:simpleContent>
+          </xs:complexType>
+        </xs:element>
+        <xs:element name="footer" type="xs:string" minOccurs="0"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+func compileStreamSchema(t *testing.T) *xsd.Schema {
+	t.Helper()
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(streamSchema))
+	require.NoError(t, err)
+	schema, err := xsd.NewCompiler().Compile(t.Context(), doc)
+	require.NoError(t, err)
+	return schema
+}
+
+func TestValidatingWriter(t *testing.T) {
+	t.Parallel()
+	schema := compileStreamSchema(t)
+
+	newWriter := func(t *testing.T) (*stream.Writer, *bytes.Buffer) {
+		t.Helper()
+		var buf bytes.Buffer
+		out := stream.NewWriter(&buf)
+		w := xsd.NewValidatingWriter(t.Context(), &out, schema)
+		require.NoError(t, w.StartDocument("", "", ""))
+		require.NoError(t, w.StartElement("filing"))
+		return &w, &buf
+	}
+
+	t.Run("valid document", func(t *testing.T) {
+		t.Parallel()
+		w, buf := newWriter(t)
+		require.NoError(t, w.WriteElement("header", "Q3"))
+		for _, code := range []string{"a1", "b2"} {
+			require.NoError(t, w.StartElement("entry"))
+			require.NoError(t, w.WriteAttribute("code", code))
+			require.NoError(t, w.WriteString("12.50"))
+			require.NoError(t, w.EndElement())
+		}
+		require.NoError(t, w.EndElement())
+		require.NoError(t, w.EndDocument())
+		require.NoError(t, w.Flush())
+		require.Equal(t, `<?xml version="1.0"?>`+"\n"+`<filing><header>Q3</header><entry code="a1">12.50</entry><entry code="b2">12.50</entry></filing>`+"\n", buf.String())
+	})
+
+	t.Run("unexpected element fails at once", func(t *testing.T) {
+		t.Parallel()
+		w, _ := newWriter(t)
+		require.NoError(t, w.StartElement("entry"))
+		// The start tag is reported once its attributes are complete.
+		err := w.WriteString("1")
+		var ve *xsd.ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Equal(t, "entry", ve.Element)
+		require.Equal(t, []string{"header"}, ve.Expected)
+		require.Contains(t, ve.Message, "This element is not expected. Expected is ( header ).")
+		require.Equal(t, err, w.Error(), "the error is sticky")
+	})
+
+	t.Run("missing child", func(t *testing.T) {
+		t.Parallel()
+		w, _ := newWriter(t)
+		require.NoError(t, w.WriteElement("header", "Q3"))
+		err := w.EndElement()
+		var ve *xsd.ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Equal(t, "filing", ve.Element)
+		require.Equal(t, []string{"entry"}, ve.Expected)
+		require.Contains(t, ve.Message, "Missing child element(s).")
+	})
+
+	t.Run("invalid attribute value", func(t *testing.T) {
+		t.Parallel()
+		w, _ := newWriter(t)
+		require.NoError(t, w.WriteElement("header", "Q3"))
+		require.NoError(t, w.StartElement("entry"))
+		require.NoError(t, w.WriteAttribute("code", "1a"))
+		var ve *xsd.ValidationError
+		require.ErrorAs(t, w.WriteString("1"), &ve)
+		require.Equal(t, "code", ve.AttributeName)
+	})
+
+	t.Run("invalid simple content", func(t *testing.T) {
+		t.Parallel()
+		w, _ := newWriter(t)
+		require.NoError(t, w.WriteElement("header", "Q3"))
+		require.NoError(t, w.StartElement("entry"))
+		require.NoError(t, w.WriteAttribute("code", "a1"))
+		require.NoError(t, w.WriteString("twelve"))
+		var ve *xsd.ValidationError
+		require.ErrorAs(t, w.EndElement(), &ve)
+		require.Equal(t, "entry", ve.Element)
+	})
+
+	t.Run("text in element-only content", func(t *testing.T) {
+		t.Parallel()
+		w, _ := newWriter(t)
+		require.NoError(t, w.WriteString("\n  "))
+		var ve *xsd.ValidationError
+		require.ErrorAs(t, w.WriteString("stray"), &ve)
+		require.Equal(t, "filing", ve.Element)
+	})
+
+	t.Run("entity reference", func(t *testing.T) {
+		t.Parallel()
+		w, _ := newWriter(t)
+		require.NoError(t, w.StartElement("header"))
+		require.ErrorIs(t, w.WriteEntityRef("company"), errors.ErrUnsupported)
+	})
+
+	t.Run("undeclared root", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		out := stream.NewWriter(&buf)
+		w := xsd.NewValidatingWriter(t.Context(), &out, schema)
+		require.NoError(t, w.StartDocument("", "", ""))
+		require.NoError(t, w.StartElement("report"))
+		var ve *xsd.ValidationError
+		require.ErrorAs(t, w.EndElement(), &ve)
+		require.NoError(t, out.Flush())
+		require.NotContains(t, buf.String(), "report", "invalid content is not passed on")
+	})
+}
+
+func TestValidatingWriterIDs(t *testing.T) {
+	t.Parallel()
+	const src = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="graph">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="node" maxOccurs="unbounded">
+          <xs:complexType>
+            <xs:attribute name="id" type="xs:ID" use="required"/>
+            <xs:attribute name="to" type="xs:IDREFS"/>
+          </xs:complexType>
+        </xs:element>
+        <xs:element name="start" type="xs:IDREF" minOccurs="0"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+	require.NoError(t, err)
+	schema, err := xsd.NewCompiler().Compile(t.Context(), doc)
+	require.NoError(t, err)
+
+	type node struct{ id, to string }
+	write := func(t *testing.T, nodes []node, start string) (*stream.Writer, error) {
+		t.Helper()
+		var buf bytes.Buffer
+		out := stream.NewWriter(&buf)
+		w := xsd.NewValidatingWriter(t.Context(), &out, schema)
+		require.NoError(t, w.StartDocument("", "", ""))
+		require.NoError(t, w.StartElement("graph"))
+		for _, n := range nodes {
+			require.NoError(t, w.StartElement("node"))
+			require.NoError(t, w.WriteAttribute("id", n.id))
+			if n.to != "" {
+				require.NoError(t, w.WriteAttribute("to", n.to))
+			}
+			if err := w.EndElement(); err != nil {
+				return &w, err
+			}
+		}
+		if start != "" {
+			if err := w.WriteElement("start", start); err != nil {
+				return &w, err
+			}
+		}
+		if err := w.EndElement(); err != nil {
+			return &w, err
+		}
+		return &w, w.EndDocument()
+	}
+
+	t.Run("forward and backward references", func(t *testing.T) {
+		t.Parallel()
+		_, err := write(t, []node{{"a", "b c"}, {"b", "a"}, {"c", ""}}, "a")
+		require.NoError(t, err)
+	})
+
+	t.Run("duplicate ID fails at once", func(t *testing.T) {
+		t.Parallel()
+		w, err := write(t, []node{{"a", ""}, {"a", ""}}, "")
+		var ve *xsd.ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve.Message, "the ID value 'a' (attribute 'id') is already defined")
+		require.Equal(t, err, w.Error())
+	})
+
+	t.Run("dangling IDREF fails at the end", func(t *testing.T) {
+		t.Parallel()
+		_, err := write(t, []node{{"a", "b"}}, "z")
+		require.Error(t, err)
+		require.ErrorContains(t, err, "There is no ID/IDREF binding for the IDREF 'b' (attribute 'to').")
+		require.ErrorContains(t, err, "There is no ID/IDREF binding for the IDREF 'z'.")
+	})
+}
+
+func TestValidatingWriterUnsupported(t *testing.T) {
+	t.Parallel()
+	compile := func(t *testing.T, src string, v xsd.Version) *xsd.Schema {
+		t.Helper()
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(src))
+		require.NoError(t, err)
+		schema, err := xsd.NewCompiler().Version(v).Compile(t.Context(), doc)
+		require.NoError(t, err)
+		return schema
+	}
+	start := func(t *testing.T, schema *xsd.Schema) stream.Writer {
+		t.Helper()
+		var buf bytes.Buffer
+		out := stream.NewWriter(&buf)
+		w := xsd.NewValidatingWriter(t.Context(), &out, schema)
+		require.NoError(t, w.StartDocument("", "", ""))
+		require.NoError(t, w.StartElement("list"))
+		return w
+	}
+
+	t.Run("identity constraints", func(t *testing.T) {
+		t.Parallel()
+		schema := compile(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="list">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="item" type="xs:string" maxOccurs="unbounded"/>
+      </xs:sequence>
+    </xs:complexType>
+    <xs:unique name="items">
+      <xs:selector xpath="item"/>
+      <xs:field xpath="."/>
+    </xs:unique>
+  </xs:element>
+</xs:schema>`, xsd.Version10)
+		w := start(t, schema)
+		err := w.StartElement("item")
+		require.ErrorIs(t, err, errors.ErrUnsupported)
+		require.NotErrorIs(t, err, xsd.ErrValidationFailed)
+	})
+
+	t.Run("assertions on element content", func(t *testing.T) {
+		t.Parallel()
+		schema := compile(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="list">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="item" type="xs:string" maxOccurs="unbounded"/>
+      </xs:sequence>
+      <xs:assert test="count(item) le 3"/>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`, xsd.Version11)
+		w := start(t, schema)
+		require.ErrorIs(t, w.StartElement("item"), errors.ErrUnsupported)
+	})
+}
+
+func TestValidatingWriterNilSchema(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	out := stream.NewWriter(&buf)
+	w := xsd.NewValidatingWriter(t.Context(), &out, nil)
+	require.ErrorIs(t, w.StartDocument("", "", ""), xsd.ErrNilSchema)
+}
+
+// TestStreamValidatorGoldenAgreement replays every golden instance through a
+// validating Writer and checks that it accepts exactly the documents the
+// tree validator accepts.
+func TestStreamValidatorGoldenAgreement(t *testing.T) {
+	t.Parallel()
+	for _, tc := range discoverTests(t) {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if reason := shouldSkip(tc.name); reason != "" {
+				t.Skipf("skipping: %s", reason)
+			}
+			schema, err := xsd.NewCompiler().FS(helium.PermissiveFS()).CompileFile(t.Context(), tc.xsdPath)
+			if err != nil {
+				t.Skip("schema does not compile")
+			}
+			data, err := os.ReadFile(tc.xmlPath)
+			require.NoError(t, err)
+			doc, err := helium.NewParser().Parse(t.Context(), data)
+			if err != nil {
+				t.Skip("instance is not well-formed")
+			}
+			domErr := xsd.NewValidator(schema).Validate(t.Context(), doc)
+
+			var buf bytes.Buffer
+			out := stream.NewWriter(&buf)
+			w := xsd.NewValidatingWriter(t.Context(), &out, schema)
+			err = helium.EmitSAX(t.Context(), doc, sax.NewWriterHandler(&w))
+			if err == nil {
+				err = w.Error()
+			}
+			if errors.Is(err, errors.ErrUnsupported) {
+				t.Skip("instance needs an unsupported stream check")
+			}
+			if domErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+		})
+	}
+}
@@ -429,7 +429,7 @@ func fixedUnionActiveMember(ctx context.Context, value string, valueNS map[strin
 			version:                        version,
 			allowXSD10LegacyGMonthInstance: allowXSD10LegacyGMonthInstance,
 		}
-		if validateValue(ctx, value, valueNS, member, "", "", 0, vc) != nil {
+		if validateValue(ctx, value, valueNS, member, "", "", nil, vc) != nil {
 			continue
 		}
 		// The member accepts the value. If it is itself a union, recurse to find
@@ -668,32 +668,39 @@ func (ve *validationErrors) Handle(_ context.Context, err error) {
 	ve.errors = append(ve.errors, err.Error())
 }
 
-// reportValidityError formats a validation error and sends it to the ErrorHandler.
-func (vc *validationContext) reportValidityError(ctx context.Context, file string, line int, elemName, msg string) {
+// reportValidityError formats a validation error about node and sends it to
+// the ErrorHandler. node may be nil when the value being validated has no
+// instance node.
+func (vc *validationContext) reportValidityError(ctx context.Context, file string, node helium.Node, elemName, msg string) {
 	if vc.suppressDepth > 0 {
 		return
 	}
 	ve := &ValidationError{
 		Filename: file,
-		Line:     line,
 		Element:  elemName,
 		Message:  msg,
 	}
+	if node != nil {
+		ve.Line = node.Line()
+		ve.Position, _ = helium.PositionOf(node)
+	}
 	vc.errorHandler.Handle(ctx, newLeveledValidationError(ve, helium.ErrorLevelError))
 }
 
-// reportValidityErrorAttr formats an attribute validation error and sends it to the ErrorHandler.
-func (vc *validationContext) reportValidityErrorAttr(ctx context.Context, file string, line int, elemName, attrName, msg string) {
+// reportValidityErrorAttr formats a validation error about attribute a of elem
+// and sends it to the ErrorHandler.
+func (vc *validationContext) reportValidityErrorAttr(ctx context.Context, file string, elem *helium.Element, a *helium.Attribute, msg string) {
 	if vc.suppressDepth > 0 {
 		return
 	}
 	ve := &ValidationError{
 		Filename:      file,
-		Line:          line,
-		Element:       elemName,
-		AttributeName: attrName,
+		Line:          elem.Line(),
+		Element:       elemDisplayName(elem),
+		AttributeName: attrDisplayName(a),
 		Message:       msg,
 	}
+	ve.Position, _ = helium.PositionOf(a)
 	vc.errorHandler.Handle(ctx, newLeveledValidationError(ve, helium.ErrorLevelError))
 }
 
@@ -711,7 +718,7 @@ func (td *TypeDef) Validate(ctx context.Context, value string, nsMap map[string]
 	vc := &validationContext{
 		errorHandler: helium.NilErrorHandler{},
 	}
-	return validateValue(ctx, value, nsMap, td, "", "", 0, vc)
+	return validateValue(ctx, value, nsMap, td, "", "", nil, vc)
 }
 
 // ValidateElement validates an element's content against this type definition.
@@ -898,7 +905,7 @@ func (vc *validationContext) validateRootElement(ctx context.Context, elem *heli
 			return vc.validateUndeclaredElementWithType(ctx, elem, td)
 		}
 		msg := "No matching global declaration available for the validation root."
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), local, msg)
+		vc.reportValidityError(ctx, vc.filename, elem, local, msg)
 		return fmt.Errorf("no matching global declaration")
 	}
 
@@ -909,7 +916,7 @@ func (vc *validationContext) validateRootElement(ctx context.Context, elem *heli
 	// nillable flag — stays the member's. This mirrors the particle paths.
 	if edecl.Abstract {
 		msg := msgAbstractElement
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 		return fmt.Errorf("abstract element")
 	}
 	declType := effectiveDeclType(edecl, vc.schema)
@@ -938,12 +945,12 @@ func (vc *validationContext) validateRootElement(ctx context.Context, elem *heli
 	// {prohibited substitutions} (cvc-elt.4.3).
 	if td != declType && typeDerivationBlocked(td, declType, edecl.Block) {
 		msg := "The xsi:type definition is blocked by the element declaration."
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 		return fmt.Errorf("blocked xsi:type")
 	}
 	if td != nil && td.Abstract {
 		msg := msgAbstractType
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 		return fmt.Errorf("abstract type")
 	}
 
@@ -964,7 +971,7 @@ func (vc *validationContext) validateRootElement(ctx context.Context, elem *heli
 
 func (vc *validationContext) validateUndeclaredElementWithType(ctx context.Context, elem *helium.Element, td *TypeDef) error {
 	if td != nil && td.Abstract {
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msgAbstractType)
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msgAbstractType)
 		return fmt.Errorf("abstract type")
 	}
 	vc.annotateElement(ctx, elem, td, true)
@@ -987,7 +994,7 @@ func (vc *validationContext) validateElementContent(ctx context.Context, elem *h
 	// it governs is invalid. This is the single choke point for every type-selection
 	// site (root and the per-child content-model matches).
 	if vc.version == Version11 && isErrorType(td) {
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem),
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem),
 			"The element is not valid: the conditional type assignment selected the type xs:error.")
 		return fmt.Errorf("xs:error type selected")
 	}
@@ -1017,12 +1024,12 @@ func (vc *validationContext) rejectMissingTypeRef(ctx context.Context, elem *hel
 		return nil
 	}
 	elemName := ""
-	line := 0
+	var node helium.Node
 	if elem != nil {
 		elemName = elemDisplayName(elem)
-		line = elem.Line()
+		node = elem
 	}
-	vc.reportValidityError(ctx, vc.filename, line, elemName, missingTypeRefMessage(qn))
+	vc.reportValidityError(ctx, vc.filename, node, elemName, missingTypeRefMessage(qn))
 	return fmt.Errorf("unresolved type definition")
 }
 
@@ -1044,7 +1051,7 @@ func (vc *validationContext) rejectNonWhitespaceText(ctx context.Context, elem *
 		// strings.TrimSpace (which strips all Unicode space) must not be used.
 		if !xmlchar.IsAllSpace(child.Content()) {
 			msg := "Character content other than whitespace is not allowed because the content type is 'element-only'."
-			vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+			vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 			return fmt.Errorf("text content in element-only type")
 		}
 	}
@@ -1164,7 +1171,7 @@ func (vc *validationContext) validateMixedFixed(ctx context.Context, elem *heliu
 	// and can never represent a valid fixed value.
 	if invalid {
 		msg := fmt.Sprintf("The element content could not be evaluated against the fixed value constraint '%s' (cyclic entity expansion).", *edecl.Fixed)
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 		return fmt.Errorf("fixed value constraint")
 	}
 	// Clause 5.1: neither element nor character children — the fixed value is
@@ -1177,14 +1184,14 @@ func (vc *validationContext) validateMixedFixed(ctx context.Context, elem *heliu
 	// Clause 5.2.2.1: a fixed value constraint forbids element children.
 	if hasElem {
 		msg := fmt.Sprintf("Element children are not allowed because the element declaration has a fixed value constraint '%s'.", *edecl.Fixed)
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 		return fmt.Errorf("fixed value constraint")
 	}
 	// Clause 5.2.2.2.2: the initial value of a mixed-content element must equal
 	// the fixed value (string comparison of the canonical lexical representation).
 	if initial != *edecl.Fixed {
 		msg := fmt.Sprintf("The element content '%s' does not match the fixed value constraint '%s'.", initial, *edecl.Fixed)
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 		return fmt.Errorf("fixed value constraint")
 	}
 	return nil
@@ -1258,14 +1265,14 @@ func (vc *validationContext) annotateAnyTypeChildren(ctx context.Context, elem *
 		// for a global element assessed through xs:anyType too. The blocked set unions
 		// the element declaration's block with the declared type's block.
 		if td != declType && declType != nil && typeDerivationBlocked(td, declType, edecl.Block) {
-			vc.reportValidityError(ctx, vc.filename, ce.Line(), elemDisplayName(ce),
+			vc.reportValidityError(ctx, vc.filename, ce, elemDisplayName(ce),
 				"The xsi:type definition is blocked by the element declaration.")
 			contentErr = fmt.Errorf("blocked xsi:type")
 			continue
 		}
 		if td != nil && td.Abstract {
 			msg := msgAbstractType
-			vc.reportValidityError(ctx, vc.filename, ce.Line(), elemDisplayName(ce), msg)
+			vc.reportValidityError(ctx, vc.filename, ce, elemDisplayName(ce), msg)
 			contentErr = fmt.Errorf("abstract type")
 			continue
 		}
@@ -1341,7 +1348,7 @@ func (vc *validationContext) validateSimpleContent(ctx context.Context, elem *he
 	// Simple content types must not have child elements.
 	for child := range helium.Children(elem) {
 		if child.Type() == helium.ElementNode {
-			vc.reportValidityError(ctx, vc.filename, elem.Line(), elem.LocalName(),
+			vc.reportValidityError(ctx, vc.filename, elem, elem.LocalName(),
 				"Element content is not allowed, because the content type is a simple type definition.")
 			return fmt.Errorf("element content not allowed")
 		}
@@ -1393,7 +1400,7 @@ func (vc *validationContext) validateSimpleContent(ctx context.Context, elem *he
 		// effective content simple type, so the raw declared type is passed here.
 		if !fixedValueMatchesForInstance(ctx, value, *edecl.Fixed, fixedType, collectNSContext(elem), edecl.FixedNS, vc.schema, vc.version, vc.allowXSD10LegacyGMonthInstance) {
 			msg := fmt.Sprintf("The element content '%s' does not match the fixed value constraint '%s'.", value, *edecl.Fixed)
-			vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+			vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 			return fmt.Errorf("fixed value constraint")
 		}
 	}
@@ -1409,7 +1416,7 @@ func (vc *validationContext) validateSimpleContent(ctx context.Context, elem *he
 	// gating and instance-context resolution, byte-identical.
 	if vc.version == Version11 {
 		valueNS := effectiveValueNS(elem, edecl, isEmpty)
-		return vc.validateSimpleContentValue(ctx, effectiveValue, valueNS, td, elemDisplayName(elem), elem.Line())
+		return vc.validateSimpleContentValue(ctx, effectiveValue, valueNS, td, elemDisplayName(elem), elem)
 	}
 
 	// XSD 1.0: validate the text value against the type. simpleContentNeedsValidation
@@ -1418,7 +1425,7 @@ func (vc *validationContext) validateSimpleContent(ctx context.Context, elem *he
 	// of a named faceted simple type (whose facets live on the base, not on td itself)
 	// still enforces the base type's minLength/maxLength/etc.
 	if td != nil && simpleContentNeedsValidation(td) {
-		return validateValue(ctx, effectiveValue, collectNSContext(elem), td, elemDisplayName(elem), vc.filename, elem.Line(), vc)
+		return validateValue(ctx, effectiveValue, collectNSContext(elem), td, elemDisplayName(elem), vc.filename, elem, vc)
 	}
 
 	return nil
@@ -1434,17 +1441,17 @@ func (vc *validationContext) validateSimpleContent(ctx context.Context, elem *he
 // exactly the same constraints the instance value does. displayName/line are used
 // only for diagnostics (the schema error is emitted by the caller, so a suppressed
 // validationContext drops the inner reports).
-func (vc *validationContext) validateSimpleContentValue(ctx context.Context, value string, ns map[string]string, td *TypeDef, displayName string, line int) error {
+func (vc *validationContext) validateSimpleContentValue(ctx context.Context, value string, ns map[string]string, td *TypeDef, displayName string, node helium.Node) error {
 	effTD := effectiveContentSimpleType(td)
 	if simpleContentNeedsValidation(effTD) {
-		if err := validateValue(ctx, value, ns, effTD, displayName, vc.filename, line, vc); err != nil {
+		if err := validateValue(ctx, value, ns, effTD, displayName, vc.filename, node, vc); err != nil {
 			return err
 		}
 	}
-	return vc.validateNestedSimpleContentBases(ctx, value, ns, td, effTD, displayName, line)
+	return vc.validateNestedSimpleContentBases(ctx, value, ns, td, effTD, displayName, node)
 }
 
-func (vc *validationContext) validateNestedSimpleContentBases(ctx context.Context, value string, ns map[string]string, td, effTD *TypeDef, displayName string, line int) error {
+func (vc *validationContext) validateNestedSimpleContentBases(ctx context.Context, value string, ns map[string]string, td, effTD *TypeDef, displayName string, node helium.Node) error {
 	visited := make(map[*TypeDef]struct{})
 	for cur := td; cur != nil && cur.IsSimpleContent; cur = cur.BaseType {
 		if _, seen := visited[cur]; seen {
@@ -1467,7 +1474,7 @@ func (vc *validationContext) validateNestedSimpleContentBases(ctx context.Contex
 		if baseContent == effTD || !simpleContentNeedsValidation(baseContent) {
 			continue
 		}
-		if err := validateValue(ctx, value, ns, baseContent, displayName, vc.filename, line, vc); err != nil {
+		if err := validateValue(ctx, value, ns, baseContent, displayName, vc.filename, node, vc); err != nil {
 			return err
 		}
 	}
@@ -1595,15 +1602,15 @@ func (vc *validationContext) validateEmptyContent(ctx context.Context, elem *hel
 			if !ok {
 				continue
 			}
-			vc.reportValidityError(ctx, vc.filename, ce.Line(), ce.LocalName(), "This element is not expected.")
+			vc.reportValidityError(ctx, vc.filename, ce, ce.LocalName(), "This element is not expected.")
 			return fmt.Errorf("not expected")
 		case helium.TextNode, helium.CDATASectionNode:
 			if strict {
-				vc.reportValidityError(ctx, vc.filename, elem.Line(), elem.LocalName(), "Character content is not allowed, because the content type is empty.")
+				vc.reportValidityError(ctx, vc.filename, elem, elem.LocalName(), "Character content is not allowed, because the content type is empty.")
 				return fmt.Errorf("not expected")
 			}
 			if !xmlchar.IsAllSpace(child.Content()) {
-				vc.reportValidityError(ctx, vc.filename, elem.Line(), elem.LocalName(), "Character content is not allowed, because the type definition is simple.")
+				vc.reportValidityError(ctx, vc.filename, elem, elem.LocalName(), "Character content is not allowed, because the type definition is simple.")
 				return fmt.Errorf("not expected")
 			}
 		}
@@ -1860,7 +1867,7 @@ func (vc *validationContext) validateAttributes(ctx context.Context, elem *heliu
 			}
 			ad := attrDisplayName(a)
 			msg := fmt.Sprintf("The attribute '%s' is not allowed.", ad)
-			vc.reportValidityErrorAttr(ctx, vc.filename, elem.Line(), elemDisplayName(elem), ad, msg)
+			vc.reportValidityErrorAttr(ctx, vc.filename, elem, a, msg)
 			hasErr = true
 		}
 		if hasErr {
@@ -1946,7 +1953,7 @@ func (vc *validationContext) validateAttributes(ctx context.Context, elem *heliu
 				if err := vc.validateDeclaredXsiAttrValue(a, elem); err != nil {
 					ad := attrDisplayName(a)
 					msg := fmt.Sprintf("The value '%s' is not valid for the type of attribute '%s'.", a.Value(), ad)
-					vc.reportValidityErrorAttr(ctx, vc.filename, elem.Line(), elemDisplayName(elem), ad, msg)
+					vc.reportValidityErrorAttr(ctx, vc.filename, elem, a, msg)
 					hasErr = true
 					continue
 				}
@@ -1972,9 +1979,8 @@ func (vc *validationContext) validateAttributes(ctx context.Context, elem *heliu
 					fixedMatches = fixedValueMatchesForInstance(ctx, a.Value(), *au.Fixed, attrTD, collectNSContext(elem), au.FixedNS, vc.schema, vc.version, vc.allowXSD10LegacyGMonthInstance)
 				}
 				if !fixedMatches {
-					ad := attrDisplayName(a)
 					msg := fmt.Sprintf("The value '%s' does not match the fixed value constraint '%s'.", a.Value(), *au.Fixed)
-					vc.reportValidityErrorAttr(ctx, vc.filename, elem.Line(), elemDisplayName(elem), ad, msg)
+					vc.reportValidityErrorAttr(ctx, vc.filename, elem, a, msg)
 					hasErr = true
 				}
 			}
@@ -1984,10 +1990,10 @@ func (vc *validationContext) validateAttributes(ctx context.Context, elem *heliu
 			// type is associated only for the fixed-value comparison just done), so
 			// skip the generic check to avoid validating the same value twice.
 			if tdOK && attrTD.ContentType == ContentTypeSimple && !declaredXsiValueChecked {
-				if err := validateValue(ctx, a.Value(), collectNSContext(elem), attrTD, elemDisplayName(elem), vc.filename, elem.Line(), &validationContext{schema: vc.schema, version: vc.version, errorHandler: helium.NilErrorHandler{}, allowXSD10LegacyGMonthInstance: vc.allowXSD10LegacyGMonthInstance}); err != nil {
+				if err := validateValue(ctx, a.Value(), collectNSContext(elem), attrTD, elemDisplayName(elem), vc.filename, elem, &validationContext{schema: vc.schema, version: vc.version, errorHandler: helium.NilErrorHandler{}, allowXSD10LegacyGMonthInstance: vc.allowXSD10LegacyGMonthInstance}); err != nil {
 					ad := attrDisplayName(a)
 					msg := fmt.Sprintf("The value '%s' is not valid for the type of attribute '%s'.", a.Value(), ad)
-					vc.reportValidityErrorAttr(ctx, vc.filename, elem.Line(), elemDisplayName(elem), ad, msg)
+					vc.reportValidityErrorAttr(ctx, vc.filename, elem, a, msg)
 					hasErr = true
 				}
 			}
@@ -2010,7 +2016,7 @@ func (vc *validationContext) validateAttributes(ctx context.Context, elem *heliu
 		if _, prohib := prohibited[aqn]; prohib && vc.version == Version11 {
 			ad := attrDisplayName(a)
 			msg := fmt.Sprintf("The attribute '%s' is not allowed.", ad)
-			vc.reportValidityErrorAttr(ctx, vc.filename, elem.Line(), elemDisplayName(elem), ad, msg)
+			vc.reportValidityErrorAttr(ctx, vc.filename, elem, a, msg)
 			hasErr = true
 			continue
 		}
@@ -2023,7 +2029,7 @@ func (vc *validationContext) validateAttributes(ctx context.Context, elem *heliu
 		}
 		ad := attrDisplayName(a)
 		msg := fmt.Sprintf("The attribute '%s' is not allowed.", ad)
-		vc.reportValidityErrorAttr(ctx, vc.filename, elem.Line(), elemDisplayName(elem), ad, msg)
+		vc.reportValidityErrorAttr(ctx, vc.filename, elem, a, msg)
 		hasErr = true
 	}
 
@@ -2034,7 +2040,7 @@ func (vc *validationContext) validateAttributes(ctx context.Context, elem *heliu
 		}
 		if _, ok := present[au.Name]; !ok {
 			msg := fmt.Sprintf("The attribute '%s' is required but missing.", au.Name.Local)
-			vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+			vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 			hasErr = true
 		}
 	}
@@ -2374,9 +2380,8 @@ func (vc *validationContext) validateWildcardAttr(ctx context.Context, a *helium
 
 	if !found {
 		if wc.ProcessContents == ProcessStrict {
-			ad := attrDisplayName(a)
 			msg := "No matching global attribute declaration available, but demanded by the strict wildcard."
-			vc.reportValidityErrorAttr(ctx, vc.filename, elem.Line(), elemDisplayName(elem), ad, msg)
+			vc.reportValidityErrorAttr(ctx, vc.filename, elem, a, msg)
 			return fmt.Errorf("strict wildcard: no global attr")
 		}
 		// Lax: no global declaration found — skip validation.
@@ -2408,19 +2413,17 @@ func (vc *validationContext) validateWildcardAttr(ctx context.Context, a *helium
 	// global fixed attribute must still satisfy its fixed value, in the declared
 	// type's value space (mirroring the non-wildcard attribute path).
 	if globalAttr.Fixed != nil && !fixedValueMatchesForInstance(ctx, a.Value(), *globalAttr.Fixed, attrTD, collectNSContext(elem), globalAttr.FixedNS, vc.schema, vc.version, vc.allowXSD10LegacyGMonthInstance) {
-		ad := attrDisplayName(a)
 		msg := fmt.Sprintf("The value '%s' does not match the fixed value constraint '%s'.", a.Value(), *globalAttr.Fixed)
-		vc.reportValidityErrorAttr(ctx, vc.filename, elem.Line(), elemDisplayName(elem), ad, msg)
+		vc.reportValidityErrorAttr(ctx, vc.filename, elem, a, msg)
 		return fmt.Errorf("fixed value constraint")
 	}
 
 	if ok && attrTD.ContentType == ContentTypeSimple {
 		value := a.Value()
-		if err := validateValue(ctx, value, collectNSContext(elem), attrTD, elemDisplayName(elem), vc.filename, elem.Line(), &validationContext{schema: vc.schema, version: vc.version, errorHandler: helium.NilErrorHandler{}, allowXSD10LegacyGMonthInstance: vc.allowXSD10LegacyGMonthInstance}); err != nil {
-			ad := attrDisplayName(a)
+		if err := validateValue(ctx, value, collectNSContext(elem), attrTD, elemDisplayName(elem), vc.filename, elem, &validationContext{schema: vc.schema, version: vc.version, errorHandler: helium.NilErrorHandler{}, allowXSD10LegacyGMonthInstance: vc.allowXSD10LegacyGMonthInstance}); err != nil {
 			typeName := typeDisplayName(attrTD)
 			msg := fmt.Sprintf("'%s' is not a valid value of the atomic type '%s'.", strings.TrimSpace(value), typeName)
-			vc.reportValidityErrorAttr(ctx, vc.filename, elem.Line(), elemDisplayName(elem), ad, msg)
+			vc.reportValidityErrorAttr(ctx, vc.filename, elem, a, msg)
 			return err
 		}
 	}
@@ -2757,14 +2760,14 @@ func (vc *validationContext) checkXsiNil(ctx context.Context, elem *helium.Eleme
 			return true, nil
 		case "false", "0":
 			if edecl != nil && !edecl.Nillable {
-				vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem),
+				vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem),
 					"Element is not nillable.")
 				return false, fmt.Errorf("element not nillable")
 			}
 			return false, nil
 		}
 		msg := fmt.Sprintf("'%s' is not a valid value of the atomic type 'xs:boolean'.", v)
-		vc.reportValidityErrorAttr(ctx, vc.filename, elem.Line(), elemDisplayName(elem), attrDisplayName(a), msg)
+		vc.reportValidityErrorAttr(ctx, vc.filename, elem, a, msg)
 		return false, fmt.Errorf("invalid xsi:nil value %q", a.Value())
 	}
 	return false, nil
@@ -2780,13 +2783,13 @@ func (vc *validationContext) validateNilledElement(ctx context.Context, elem *he
 	// XSD 1.1: a governing type of xs:error has an empty value space, so the element
 	// is invalid regardless of xsi:nil — the nilled path must NOT let it through.
 	if vc.version == Version11 && isErrorType(td) {
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), dn,
+		vc.reportValidityError(ctx, vc.filename, elem, dn,
 			"The element is not valid: the conditional type assignment selected the type xs:error.")
 		return fmt.Errorf("xs:error type selected")
 	}
 
 	if !edecl.Nillable {
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), dn,
+		vc.reportValidityError(ctx, vc.filename, elem, dn,
 			"Element is not nillable.")
 		return fmt.Errorf("element not nillable")
 	}
@@ -2797,7 +2800,7 @@ func (vc *validationContext) validateNilledElement(ctx context.Context, elem *he
 	// is only the combination with an actual xsi:nil="true" instance that is
 	// invalid.
 	if edecl.Fixed != nil {
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), dn,
+		vc.reportValidityError(ctx, vc.filename, elem, dn,
 			"The element cannot be nilled because there is a fixed value constraint defined for it.")
 		return fmt.Errorf("nilled element with fixed value constraint")
 	}
@@ -2822,7 +2825,7 @@ func (vc *validationContext) validateNilledElement(ctx context.Context, elem *he
 			if !ok {
 				continue
 			}
-			vc.reportValidityError(ctx, vc.filename, ce.Line(), elemDisplayName(ce),
+			vc.reportValidityError(ctx, vc.filename, ce, elemDisplayName(ce),
 				"This element is not expected, because the element '"+dn+"' is nilled.")
 			return fmt.Errorf("content in nilled element")
 		case helium.TextNode, helium.CDATASectionNode:
@@ -2830,7 +2833,7 @@ func (vc *validationContext) validateNilledElement(ctx context.Context, elem *he
 			// tolerates insignificant whitespace (matching libxml2); XSD 1.1 rejects
 			// any character content, including whitespace-only.
 			if vc.version == Version11 || !xmlchar.IsAllSpace(child.Content()) {
-				vc.reportValidityError(ctx, vc.filename, elem.Line(), dn,
+				vc.reportValidityError(ctx, vc.filename, elem, dn,
 					"Character content is not allowed, because the element is nilled.")
 				return fmt.Errorf("content in nilled element")
 			}
@@ -2897,7 +2900,7 @@ func (vc *validationContext) resolveXsiType(ctx context.Context, elem *helium.El
 	xsiTypeVal = normalizeWhiteSpace(xsiTypeVal, "collapse")
 	if err := validateQName(xsiTypeVal); err != nil {
 		msg := fmt.Sprintf("The value '%s' of the xsi:type attribute does not resolve to a type definition.", xsiTypeVal)
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 		return nil, fmt.Errorf("xsi:type not a valid QName")
 	}
 
@@ -2909,7 +2912,7 @@ func (vc *validationContext) resolveXsiType(ctx context.Context, elem *helium.El
 		ns = lookupNS(elem, prefix)
 		if ns == "" {
 			msg := fmt.Sprintf("The value '%s' of the xsi:type attribute does not resolve to a type definition.", xsiTypeVal)
-			vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+			vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 			return nil, fmt.Errorf("xsi:type prefix not bound")
 		}
 	} else {
@@ -2920,7 +2923,7 @@ func (vc *validationContext) resolveXsiType(ctx context.Context, elem *helium.El
 	td, ok := vc.schema.LookupType(local, ns)
 	if !ok {
 		msg := fmt.Sprintf("The value '%s' of the xsi:type attribute does not resolve to a type definition.", xsiTypeVal)
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 		return nil, fmt.Errorf("xsi:type not found")
 	}
 
@@ -2933,7 +2936,7 @@ func (vc *validationContext) resolveXsiType(ctx context.Context, elem *helium.El
 	if declaredType != nil && !isXsiTypeDerivedFromDeclared(td, declaredType) {
 		msg := fmt.Sprintf("The type definition '%s' is not validly derived from the type definition '%s'.",
 			typeDisplayName(td), typeDisplayName(declaredType))
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 		return nil, fmt.Errorf("xsi:type not derived")
 	}
 
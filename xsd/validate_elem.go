@@ -226,7 +226,7 @@ func (vc *validationContext) matchChoice(ctx context.Context, parent *helium.Ele
 	if reps < minReps {
 		names := particleNames(mg.Particles, vc.schema)
 		msg := formatExpected("Missing child element(s).", names)
-		vc.reportValidityError(ctx, vc.filename, parent.Line(), elemDisplayName(parent), msg)
+		vc.reportValidityError(ctx, vc.filename, parent, elemDisplayName(parent), msg)
 		return pos - startPos, fmt.Errorf("missing")
 	}
 
@@ -322,7 +322,7 @@ func (vc *validationContext) matchAll10(ctx context.Context, parent *helium.Elem
 		if len(expected) > 0 {
 			msg = formatExpected("This element is not expected.", expected)
 		}
-		vc.reportValidityError(ctx, vc.filename, child.elem.Line(), child.displayName, msg)
+		vc.reportValidityError(ctx, vc.filename, child.elem, child.displayName, msg)
 		return consumed, fmt.Errorf("unexpected element")
 	}
 
@@ -350,7 +350,7 @@ func (vc *validationContext) matchAll10(ctx context.Context, parent *helium.Elem
 	if hasRequired {
 		unseen := unseenParticleNames(mg.Particles, seen, vc.schema)
 		msg := formatExpected("Missing child element(s).", unseen)
-		vc.reportValidityError(ctx, vc.filename, parent.Line(), elemDisplayName(parent), msg)
+		vc.reportValidityError(ctx, vc.filename, parent, elemDisplayName(parent), msg)
 		return consumed, fmt.Errorf("missing")
 	}
 
@@ -432,7 +432,7 @@ func (vc *validationContext) matchAll11(ctx context.Context, parent *helium.Elem
 		if len(expected) > 0 {
 			msg = formatExpected("This element is not expected.", expected)
 		}
-		vc.reportValidityError(ctx, vc.filename, child.elem.Line(), child.displayName, msg)
+		vc.reportValidityError(ctx, vc.filename, child.elem, child.displayName, msg)
 		return consumed, fmt.Errorf("unexpected element")
 	}
 
@@ -449,7 +449,7 @@ func (vc *validationContext) matchAll11(ctx context.Context, parent *helium.Elem
 		}
 		unseen := underMinMemberNames(members, counts, vc.schema)
 		msg := formatExpected("Missing child element(s).", unseen)
-		vc.reportValidityError(ctx, vc.filename, parent.Line(), elemDisplayName(parent), msg)
+		vc.reportValidityError(ctx, vc.filename, parent, elemDisplayName(parent), msg)
 		return consumed, fmt.Errorf("missing")
 	}
 
@@ -487,7 +487,7 @@ func (vc *validationContext) validateAllMatchedChild(ctx context.Context, child
 	// concrete substitution-group member may appear). The 1.1 matcher already
 	// filters this at allMemberForChild, so this never fires there.
 	if actualDecl.Abstract {
-		vc.reportValidityError(ctx, vc.filename, child.elem.Line(), elemDisplayName(child.elem), msgAbstractElement)
+		vc.reportValidityError(ctx, vc.filename, child.elem, elemDisplayName(child.elem), msgAbstractElement)
 		return fmt.Errorf("abstract element")
 	}
 	declType := effectiveDeclType(actualDecl, vc.schema)
@@ -504,12 +504,12 @@ func (vc *validationContext) validateAllMatchedChild(ctx context.Context, child
 	// the element declaration's block and the declared type's {prohibited
 	// substitutions}.
 	if td != declType && declType != nil && typeDerivationBlocked(td, declType, actualDecl.Block) {
-		vc.reportValidityError(ctx, vc.filename, child.elem.Line(), elemDisplayName(child.elem),
+		vc.reportValidityError(ctx, vc.filename, child.elem, elemDisplayName(child.elem),
 			"The xsi:type definition is blocked by the element declaration.")
 		return fmt.Errorf("blocked xsi:type")
 	}
 	if td != nil && td.Abstract {
-		vc.reportValidityError(ctx, vc.filename, child.elem.Line(), elemDisplayName(child.elem), msgAbstractType)
+		vc.reportValidityError(ctx, vc.filename, child.elem, elemDisplayName(child.elem), msgAbstractType)
 		return fmt.Errorf("abstract type")
 	}
 	vc.annotateElement(ctx, child.elem, td, true)
@@ -662,7 +662,7 @@ func (vc *validationContext) matchContentModelFull(ctx context.Context, parent *
 	// Check for unconsumed children.
 	if consumed < len(children) {
 		ce := children[consumed]
-		vc.reportValidityError(ctx, vc.filename, ce.elem.Line(), ce.displayName, "This element is not expected.")
+		vc.reportValidityError(ctx, vc.filename, ce.elem, ce.displayName, "This element is not expected.")
 		return fmt.Errorf("unexpected element")
 	}
 
@@ -741,7 +741,7 @@ func (vc *validationContext) matchElementParticle(ctx context.Context, parent *h
 			// There IS a child but it doesn't match — "This element is not expected."
 			child := children[pos+count]
 			msg := formatExpected("This element is not expected.", expectedNames)
-			vc.reportValidityError(ctx, vc.filename, child.elem.Line(), child.displayName, msg)
+			vc.reportValidityError(ctx, vc.filename, child.elem, child.displayName, msg)
 		} else {
 			// No more children at all — "Missing child element(s)."
 			// When the sequence contains wildcards, suppress "Expected is" since the
@@ -752,7 +752,7 @@ func (vc *validationContext) matchElementParticle(ctx context.Context, parent *h
 			} else {
 				msg = formatExpected("Missing child element(s).", expectedNames)
 			}
-			vc.reportValidityError(ctx, vc.filename, parent.Line(), elemDisplayName(parent), msg)
+			vc.reportValidityError(ctx, vc.filename, parent, elemDisplayName(parent), msg)
 		}
 		return count, fmt.Errorf("missing")
 	}
@@ -782,13 +782,13 @@ func (vc *validationContext) matchElementParticle(ctx context.Context, parent *h
 		// the element declaration's block and the declared type's block.
 		if td != declType && declType != nil && typeDerivationBlocked(td, declType, actualDecl.Block) {
 			msg := "The xsi:type definition is blocked by the element declaration."
-			vc.reportValidityError(ctx, vc.filename, child.elem.Line(), elemDisplayName(child.elem), msg)
+			vc.reportValidityError(ctx, vc.filename, child.elem, elemDisplayName(child.elem), msg)
 			contentErr = fmt.Errorf("blocked xsi:type")
 			continue
 		}
 		if td != nil && td.Abstract {
 			msg := msgAbstractType
-			vc.reportValidityError(ctx, vc.filename, child.elem.Line(), elemDisplayName(child.elem), msg)
+			vc.reportValidityError(ctx, vc.filename, child.elem, elemDisplayName(child.elem), msg)
 			contentErr = fmt.Errorf("abstract type")
 			continue
 		}
@@ -1192,9 +1192,9 @@ func (vc *validationContext) matchWildcardParticle(ctx context.Context, parent *
 	if count < p.MinOccurs {
 		msg := fmt.Sprintf("This element is not expected. Expected is ( %s ).", wildcardExpected(wc))
 		if pos < len(children) {
-			vc.reportValidityError(ctx, vc.filename, children[pos].elem.Line(), children[pos].displayName, msg)
+			vc.reportValidityError(ctx, vc.filename, children[pos].elem, children[pos].displayName, msg)
 		} else {
-			vc.reportValidityError(ctx, vc.filename, parent.Line(), elemDisplayName(parent), msg)
+			vc.reportValidityError(ctx, vc.filename, parent, elemDisplayName(parent), msg)
 		}
 		return count, fmt.Errorf("wildcard not matched")
 	}
@@ -1254,7 +1254,7 @@ func (vc *validationContext) validateWildcardChild(ctx context.Context, wc *Wild
 				return vc.validateUndeclaredElementWithType(ctx, child.elem, actual)
 			}
 			msg := "No matching global declaration available, but demanded by the strict wildcard."
-			vc.reportValidityError(ctx, vc.filename, child.elem.Line(), child.displayName, msg)
+			vc.reportValidityError(ctx, vc.filename, child.elem, child.displayName, msg)
 			// Strict assessment FAILED (no declaration), so the element AND its whole
 			// subtree are NOT schema-assessed — exactly like skip content. Walk it
 			// with annotateSkipChildren (canonicalization-only: records pass-2
@@ -1294,12 +1294,12 @@ func (vc *validationContext) validateWildcardChild(ctx context.Context, wc *Wild
 	// a strict wildcard-matched global element too. The blocked set unions the
 	// element declaration's block with the declared type's {prohibited substitutions}.
 	if td != declType && declType != nil && typeDerivationBlocked(td, declType, edecl.Block) {
-		vc.reportValidityError(ctx, vc.filename, child.elem.Line(), elemDisplayName(child.elem),
+		vc.reportValidityError(ctx, vc.filename, child.elem, elemDisplayName(child.elem),
 			"The xsi:type definition is blocked by the element declaration.")
 		return fmt.Errorf("blocked xsi:type")
 	}
 	if td != nil && td.Abstract {
-		vc.reportValidityError(ctx, vc.filename, child.elem.Line(), elemDisplayName(child.elem), msgAbstractType)
+		vc.reportValidityError(ctx, vc.filename, child.elem, elemDisplayName(child.elem), msgAbstractType)
 		return fmt.Errorf("abstract type")
 	}
 	if err := vc.validateWildcardElementConsistent(ctx, edcScope, child, td); err != nil {
@@ -1340,7 +1340,7 @@ func (vc *validationContext) validateWildcardElementConsistent(ctx context.Conte
 			continue
 		}
 		msg := fmt.Sprintf("The wildcard-matched element's governing type definition is not validly substitutable for the locally declared type definition of element '%s'.", child.displayName)
-		vc.reportValidityError(ctx, vc.filename, child.elem.Line(), child.displayName, msg)
+		vc.reportValidityError(ctx, vc.filename, child.elem, child.displayName, msg)
 		return fmt.Errorf("wildcard element declaration inconsistent")
 	}
 	return nil
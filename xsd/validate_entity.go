@@ -169,6 +169,6 @@ func (vc *validationContext) checkEntityToken(ctx context.Context, doc *helium.D
 	if attr != "" {
 		msg = fmt.Sprintf("There is no unparsed entity declared for the ENTITY value '%s' (attribute '%s').", tok, attr)
 	}
-	vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+	vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 	return false
 }
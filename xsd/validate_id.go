@@ -106,73 +106,12 @@ func (vc *validationContext) validateIDIDREF(ctx context.Context, doc *helium.Do
 		// such elements avoids piling a spurious duplicate/dangling on top of the
 		// real structural error.
 		if td != nil && td.ContentType == ContentTypeSimple && idFamilyType(td) && !hasChildElement(elem) {
-			hostDecl := vc.idcHostDecl(elem)
-			if hostDecl == nil || !hostDecl.Nillable || !isXsiNilTrue(elem) {
-				raw := elemTextContent(elem)
-				// A default/fixed value is only the element's value when the content is
-				// genuinely empty (no text, no children — children already excluded
-				// above).
-				if raw == "" && hostDecl != nil {
-					if hostDecl.Fixed != nil {
-						raw = *hostDecl.Fixed
-					} else if hostDecl.Default != nil {
-						raw = *hostDecl.Default
-					}
-				}
-				vc.collectIDFromValue(ctx, col, td, raw, idOwner(elem, true), elem, elem, "")
-			}
+			vc.collectContentID(ctx, col, elem, td, vc.idcHostDecl(elem))
 		}
 
 		// Attributes typed as ID/IDREF (including via list/union). An attribute ID
 		// is owned by its bearing element.
-		idAttrCount := 0
-		for _, a := range elem.Attributes() {
-			if vc.isSpecialAttr(a) {
-				// A DECLARED special-attribute use that was genuinely assessed in pass 1
-				// still participates in the document-wide ID/IDREF pass — an attribute ID
-				// identifies its bearing element regardless of namespace. In XSD 1.0 a
-				// declared `ref="xml:id"` is typed as xs:ID (via xmlNamespaceAttrType) and
-				// annotated, so its value must be collected for uniqueness/integrity like
-				// any xs:ID attribute; a declared xsi: attribute is assessed the same way
-				// (its non-ID type is simply filtered below). An UNDECLARED special
-				// attribute (an undeclared xml:id, xmlns, or an unassessed xsi: attr) is
-				// never assessed, so it stays skipped.
-				if _, assessed := vc.assessedAttrs[a]; !assessed {
-					continue
-				}
-			}
-			atd := vc.attrTypeForID(a)
-			if atd == nil || !idFamilyType(atd) {
-				continue
-			}
-			// An attribute counts toward the XSD 1.0 one-ID-attribute cap iff its
-			// value contributes at least one xs:ID leaf under the SAME list/union
-			// active-member decomposition the collection uses (so a union(xs:int,
-			// xs:ID) attribute counts only when its value is an ID, and a list of
-			// xs:ID counts) — keeping the cap consistent with the uniqueness table.
-			if vc.collectIDFromValue(ctx, col, atd, a.Value(), elem, a, elem, attrDisplayName(a)) {
-				idAttrCount++
-			}
-		}
-		// This is the INSTANCE manifestation of the one-ID-per-element rule: >1
-		// ID-typed attribute actually PRESENT on an element. It covers the current
-		// targets (attZ014a/attZ014b supply their two ID attributes via a wildcard,
-		// so the element instance carries two IDs) and every constructible case where
-		// two ID attributes co-occur. Two related XSD 1.0 SCHEMA-COMPONENT rules are
-		// DEFERRED (compile-time, not yet enforced):
-		//   (i) the static Schema Component Constraint that a complex type must not
-		//       have two or more ID-typed attribute USES even when one/both are
-		//       optional and never both present in any instance (Part 1 §3.4.6). The
-		//       instance cap here does not reject such a type at compile time.
-		//   (ii) the full "wild IDs" rule — a declared ID attribute use together with
-		//       a wildcard-admitted global ID attribute is invalid even when the
-		//       declared use is ABSENT in the instance. The instance-present case is
-		//       covered by this cap; the declared-absent static case is not.
-		if vc.version == Version10 && idAttrCount > 1 {
-			col.valid = false
-			vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem),
-				"An element may have at most one attribute of type ID.")
-		}
+		vc.collectAttributeIDs(ctx, col, elem)
 		return nil
 	})); err != nil {
 		// A tree cycle (ErrWalkCycle) leaves the walk partial; the document
@@ -181,6 +120,88 @@ func (vc *validationContext) validateIDIDREF(ctx context.Context, doc *helium.Do
 	}
 
 	// Resolve all collected references now that every ID value is known.
+	vc.resolveIDRefs(ctx, col)
+	return col.valid
+}
+
+// collectContentID records the ID/IDREF values of the simple content of elem,
+// typed td. hostDecl is the declaration of elem, if any; it supplies the
+// default or fixed value of an empty element. A confirmed nilled element has
+// no value and contributes nothing.
+func (vc *validationContext) collectContentID(ctx context.Context, col *idCollector, elem *helium.Element, td *TypeDef, hostDecl *ElementDecl) {
+	if hostDecl != nil && hostDecl.Nillable && isXsiNilTrue(elem) {
+		return
+	}
+	raw := elemTextContent(elem)
+	// A default/fixed value is only the element's value when the content is
+	// genuinely empty (no text, no children — children are excluded by the
+	// caller).
+	if raw == "" && hostDecl != nil {
+		if hostDecl.Fixed != nil {
+			raw = *hostDecl.Fixed
+		} else if hostDecl.Default != nil {
+			raw = *hostDecl.Default
+		}
+	}
+	vc.collectIDFromValue(ctx, col, td, raw, idOwner(elem, true), elem, elem, "")
+}
+
+// collectAttributeIDs records the ID/IDREF values of the assessed attributes
+// of elem, and enforces the XSD 1.0 limit of one ID attribute per element.
+func (vc *validationContext) collectAttributeIDs(ctx context.Context, col *idCollector, elem *helium.Element) {
+	idAttrCount := 0
+	for _, a := range elem.Attributes() {
+		if vc.isSpecialAttr(a) {
+			// A DECLARED special-attribute use that was genuinely assessed in pass 1
+			// still participates in the document-wide ID/IDREF pass — an attribute ID
+			// identifies its bearing element regardless of namespace. In XSD 1.0 a
+			// declared `ref="xml:id"` is typed as xs:ID (via xmlNamespaceAttrType) and
+			// annotated, so its value must be collected for uniqueness/integrity like
+			// any xs:ID attribute; a declared xsi: attribute is assessed the same way
+			// (its non-ID type is simply filtered below). An UNDECLARED special
+			// attribute (an undeclared xml:id, xmlns, or an unassessed xsi: attr) is
+			// never assessed, so it stays skipped.
+			if _, assessed := vc.assessedAttrs[a]; !assessed {
+				continue
+			}
+		}
+		atd := vc.attrTypeForID(a)
+		if atd == nil || !idFamilyType(atd) {
+			continue
+		}
+		// An attribute counts toward the XSD 1.0 one-ID-attribute cap iff its
+		// value contributes at least one xs:ID leaf under the SAME list/union
+		// active-member decomposition the collection uses (so a union(xs:int,
+		// xs:ID) attribute counts only when its value is an ID, and a list of
+		// xs:ID counts) — keeping the cap consistent with the uniqueness table.
+		if vc.collectIDFromValue(ctx, col, atd, a.Value(), elem, a, elem, attrDisplayName(a)) {
+			idAttrCount++
+		}
+	}
+	// This is the INSTANCE manifestation of the one-ID-per-element rule: >1
+	// ID-typed attribute actually PRESENT on an element. It covers the current
+	// targets (attZ014a/attZ014b supply their two ID attributes via a wildcard,
+	// so the element instance carries two IDs) and every constructible case where
+	// two ID attributes co-occur. Two related XSD 1.0 SCHEMA-COMPONENT rules are
+	// DEFERRED (compile-time, not yet enforced):
+	//   (i) the static Schema Component Constraint that a complex type must not
+	//       have two or more ID-typed attribute USES even when one/both are
+	//       optional and never both present in any instance (Part 1 §3.4.6). The
+	//       instance cap here does not reject such a type at compile time.
+	//   (ii) the full "wild IDs" rule — a declared ID attribute use together with
+	//       a wildcard-admitted global ID attribute is invalid even when the
+	//       declared use is ABSENT in the instance. The instance-present case is
+	//       covered by this cap; the declared-absent static case is not.
+	if vc.version == Version10 && idAttrCount > 1 {
+		col.valid = false
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem),
+			"An element may have at most one attribute of type ID.")
+	}
+}
+
+// resolveIDRefs reports every queued xs:IDREF that matches no collected
+// xs:ID value.
+func (vc *validationContext) resolveIDRefs(ctx context.Context, col *idCollector) {
 	for _, r := range col.refs {
 		if _, ok := col.ids[r.value]; ok {
 			continue
@@ -190,9 +211,8 @@ func (vc *validationContext) validateIDIDREF(ctx context.Context, doc *helium.Do
 		if r.attr != "" {
 			msg = fmt.Sprintf("There is no ID/IDREF binding for the IDREF '%s' (attribute '%s').", r.value, r.attr)
 		}
-		vc.reportValidityError(ctx, vc.filename, r.elem.Line(), elemDisplayName(r.elem), msg)
+		vc.reportValidityError(ctx, vc.filename, r.elem, elemDisplayName(r.elem), msg)
 	}
-	return col.valid
 }
 
 // collectIDNodes records every node whose PSVI is-id property is true into out.
@@ -385,7 +405,7 @@ func (vc *validationContext) recordID(ctx context.Context, col *idCollector, tok
 		if attr != "" {
 			msg = fmt.Sprintf("Duplicate key-sequence; the ID value '%s' (attribute '%s') is already defined elsewhere in the document.", tok, attr)
 		}
-		vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+		vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 		return
 	}
 	col.ids[tok] = owner
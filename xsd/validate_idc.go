@@ -68,7 +68,7 @@ func (vc *validationContext) validateIDConstraints(ctx context.Context, elem *he
 			// (Malformed XPaths are also rejected at schema compile time, so this
 			// path normally fires only on a genuine evaluation failure.)
 			msg := fmt.Sprintf("Failed to evaluate identity-constraint '%s': %s", idc.Name, err)
-			vc.reportValidityError(ctx, vc.filename, elem.Line(), elemDisplayName(elem), msg)
+			vc.reportValidityError(ctx, vc.filename, elem, elemDisplayName(elem), msg)
 			lastErr = err
 			continue
 		}
@@ -325,7 +325,7 @@ func (vc *validationContext) evaluateIDC(ctx context.Context, ev xpath1.Evaluato
 						idcName := idcDisplayName(idc, vc.schema)
 						msg := fmt.Sprintf("The XPath '%s' of a field of %s identity-constraint '%s' evaluates to a node whose type is not simple.",
 							fieldXPath, idcKindName(idc.Kind), idcName)
-						vc.reportValidityError(ctx, vc.filename, entry.elem.Line(), elemDisplayName(entry.elem), msg)
+						vc.reportValidityError(ctx, vc.filename, entry.elem, elemDisplayName(entry.elem), msg)
 					}
 					table.fieldType = true
 					allPresent = false
@@ -341,7 +341,7 @@ func (vc *validationContext) evaluateIDC(ctx context.Context, ev xpath1.Evaluato
 						idcName := idcDisplayName(idc, vc.schema)
 						msg := fmt.Sprintf("The XPath '%s' of a field of %s identity-constraint '%s' evaluates to a node-set with more than one member.",
 							fieldXPath, idcKindName(idc.Kind), idcName)
-						vc.reportValidityError(ctx, vc.filename, entry.elem.Line(), elemDisplayName(entry.elem), msg)
+						vc.reportValidityError(ctx, vc.filename, entry.elem, elemDisplayName(entry.elem), msg)
 					}
 					table.fieldError = true
 					allPresent = false
@@ -392,7 +392,7 @@ func (vc *validationContext) evaluateIDC(ctx context.Context, ev xpath1.Evaluato
 			table.keyMissing = true
 			idcName := idcDisplayName(idc, vc.schema)
 			msg := fmt.Sprintf("Not all fields of key identity-constraint '%s' evaluate to a node.", idcName)
-			vc.reportValidityError(ctx, vc.filename, entry.elem.Line(), elemDisplayName(entry.elem), msg)
+			vc.reportValidityError(ctx, vc.filename, entry.elem, elemDisplayName(entry.elem), msg)
 		}
 	}
 
@@ -424,7 +424,7 @@ func (vc *validationContext) checkUniqueness(ctx context.Context, table *idcTabl
 			msg := fmt.Sprintf("Duplicate key-sequence %s in unique identity-constraint '%s'.",
 				formatKeyDisplay(entry.values), idcName)
 			if entry.elem != nil {
-				vc.reportValidityError(ctx, vc.filename, entry.elem.Line(), elemName, msg)
+				vc.reportValidityError(ctx, vc.filename, entry.elem, elemName, msg)
 			}
 			lastErr = fmt.Errorf("duplicate key-sequence")
 		}
@@ -451,7 +451,7 @@ func (vc *validationContext) checkKeyRef(ctx context.Context, keyrefTable, refTa
 			msg := fmt.Sprintf("No match found for key-sequence %s of keyref '%s'.",
 				formatKeyDisplay(entry.values), idcName)
 			if entry.elem != nil {
-				vc.reportValidityError(ctx, vc.filename, entry.elem.Line(), elemName, msg)
+				vc.reportValidityError(ctx, vc.filename, entry.elem, elemName, msg)
 			}
 			lastErr = fmt.Errorf("keyref not found")
 		}
@@ -691,7 +691,7 @@ func (vc *validationContext) typeAcceptsValue(ctx context.Context, td *TypeDef,
 		schema:                         vc.schema,
 		allowXSD10LegacyGMonthInstance: vc.allowXSD10LegacyGMonthInstance,
 	}
-	return validateValue(ctx, raw, fieldNodeNSContext(fieldNode), td, "", "", 0, tvc) == nil
+	return validateValue(ctx, raw, fieldNodeNSContext(fieldNode), td, "", "", nil, tvc) == nil
 }
 
 // fieldNodeNSContext returns the in-scope namespace bindings visible at an IDC
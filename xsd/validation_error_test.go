@@ -50,3 +50,40 @@ func TestValidationError_AsTyped(t *testing.T) {
 	require.Equal(t, "age", ve.Element)
 	require.NotEmpty(t, ve.Message)
 }
+
+// With helium.Parser.TrackPositions, an attribute error locates the offending
+// attribute value precisely.
+func TestValidationError_Position(t *testing.T) {
+	t.Parallel()
+	schemaXML := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="root">
+    <xs:complexType>
+      <xs:attribute name="age" type="xs:int"/>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+	instanceXML := "<root\n  age='ten'/>"
+
+	schemaDOC, err := helium.NewParser().Parse(t.Context(), []byte(schemaXML))
+	require.NoError(t, err)
+	schema, err := xsd.NewCompiler().Compile(t.Context(), schemaDOC)
+	require.NoError(t, err)
+
+	doc, err := helium.NewParser().TrackPositions(true).Parse(t.Context(), []byte(instanceXML))
+	require.NoError(t, err)
+
+	collector := helium.NewErrorCollector(t.Context(), helium.ErrorLevelNone)
+	_ = xsd.NewValidator(schema).ErrorHandler(collector).Validate(t.Context(), doc)
+
+	var ve *xsd.ValidationError
+	for _, e := range collector.Errors() {
+		if errors.As(e, &ve) {
+			break
+		}
+	}
+	require.NotNil(t, ve)
+	require.Equal(t, "age", ve.AttributeName)
+	require.Equal(t, 2, ve.Line)
+	require.Equal(t, helium.Location{Line: 2, Column: 3, Offset: 8}, ve.Position.Start)
+	require.Equal(t, "ten", instanceXML[ve.Position.ValueStart.Offset:ve.Position.ValueEnd.Offset])
+}
@@ -85,8 +85,10 @@ type compileConfig struct {
 	// used as the XPath fn:static-base-uri() source when the parsed document carries
 	// no URL of its own. Distinct from label, which is only a diagnostic source.
 	schemaURI    string
-	fsys         fs.FS          // filesystem for loading xs:include/xs:import/xs:redefine targets
-	parser       *helium.Parser // parser governing schema-document parse policy
+	fsys         fs.FS                   // filesystem for loading xs:include/xs:import/xs:redefine targets
+	resolver     helium.ResourceResolver // replaces fsys when set
+	cache        *helium.ResourceCache   // memoizes nested schemas across compiles
+	parser       *helium.Parser          // parser governing schema-document parse policy
 	errorHandler helium.ErrorHandler
 }
 
@@ -200,6 +202,30 @@ func (c Compiler) FS(fsys fs.FS) Compiler {
 	return c
 }
 
+// ResourceResolver sets a [helium.ResourceResolver] that loads
+// xs:include/xs:import/xs:redefine targets, and schemas named by
+// xsi:schemaLocation hints at validation time, in place of [Compiler.FS]. It
+// receives the ctx of the Compile or Validate call, and the same names the FS
+// would, and takes precedence over it.
+func (c Compiler) ResourceResolver(r helium.ResourceResolver) Compiler {
+	c = c.clone()
+	c.cfg.resolver = r
+	return c
+}
+
+// ResourceCache sets a [helium.ResourceCache] that memoizes the schema
+// documents pulled in via xs:include, xs:import and xs:redefine, and by
+// xsi:schemaLocation hints, across compiles and validations, both as loaded
+// and as parsed. A document the cache holds under the same [Compiler.FS] or
+// [Compiler.ResourceResolver] is served without consulting it again, and
+// parsed by the same [Compiler.Parser] it is not parsed again. The
+// nested-schema byte cap applies to cached documents too.
+func (c Compiler) ResourceCache(rc *helium.ResourceCache) Compiler {
+	c = c.clone()
+	c.cfg.cache = rc
+	return c
+}
+
 // Parser sets the [helium.Parser] used to parse XSD schema documents — the
 // top-level schema in [Compiler.CompileFile] as well as every schema pulled in
 // via xs:include, xs:import, and xs:redefine. When unset, the compiler uses a
@@ -93,6 +93,8 @@ type Compiler struct {
 type xsltCompilerCfg struct {
 	baseURI               string
 	uriResolver           URIResolver
+	resourceResolver      helium.ResourceResolver
+	cache                 *helium.ResourceCache
 	packageResolver       PackageResolver
 	staticParams          *Parameters
 	importSchemas         []*xsd.Schema
@@ -130,6 +132,29 @@ func (c Compiler) URIResolver(r URIResolver) Compiler {
 	return c
 }
 
+// ResourceResolver sets a [helium.ResourceResolver] for loading external
+// stylesheets, schemas and documents during compilation. Unlike a
+// [URIResolver], it receives the ctx of the Compile call. It takes precedence
+// over [Compiler.URIResolver], and fn:transform calls in the compiled
+// stylesheet keep using it under that ctx.
+func (c Compiler) ResourceResolver(r helium.ResourceResolver) Compiler {
+	c = c.clone()
+	c.cfg.resourceResolver = r
+	return c
+}
+
+// ResourceCache sets a [helium.ResourceCache] that memoizes the stylesheet
+// modules, schemas and documents loaded during compilation, keyed by resolved
+// URI, across compiles. A resource the cache holds under the same
+// [Compiler.URIResolver] or [Compiler.ResourceResolver] is served without
+// consulting it again. With neither set nothing is loaded, so nothing is
+// cached either.
+func (c Compiler) ResourceCache(rc *helium.ResourceCache) Compiler {
+	c = c.clone()
+	c.cfg.cache = rc
+	return c
+}
+
 // PackageResolver sets a package resolver for xsl:use-package references.
 func (c Compiler) PackageResolver(r PackageResolver) Compiler {
 	c = c.clone()
@@ -250,7 +275,7 @@ func (c Compiler) Compile(ctx context.Context, doc *helium.Document) (*Styleshee
 	if doc == nil {
 		return nil, errNilDocument
 	}
-	return compile(ctx, doc, c.toCompileConfig())
+	return compile(ctx, doc, c.toCompileConfig(ctx))
 }
 
 // MustCompile is like Compile but panics on error.
@@ -265,7 +290,7 @@ func (c Compiler) MustCompile(ctx context.Context, doc *helium.Document) *Styles
 
 // toCompileConfig converts the Compiler config to the internal compileConfig
 // used by the existing compile function.
-func (c Compiler) toCompileConfig() *compileConfig {
+func (c Compiler) toCompileConfig(ctx context.Context) *compileConfig {
 	if c.cfg == nil {
 		return &compileConfig{}
 	}
@@ -278,6 +303,18 @@ func (c Compiler) toCompileConfig() *compileConfig {
 		allowExternalEntities: c.cfg.allowExternalEntities,
 		parser:                c.cfg.parser,
 	}
+	if c.cfg.resourceResolver != nil {
+		cfg.resolver = resourceURIResolver{ctx: ctx, r: c.cfg.resourceResolver}
+	}
+	if c.cfg.cache != nil && cfg.resolver != nil {
+		// Scope the cache to the configured resolver, not to the per-compile
+		// adapter around it.
+		policy := any(c.cfg.uriResolver)
+		if c.cfg.resourceResolver != nil {
+			policy = c.cfg.resourceResolver
+		}
+		cfg.resolver = resourceURIResolver{ctx: ctx, r: c.cfg.cache.ResolverFor(policy, uriResourceResolver{r: cfg.resolver})}
+	}
 	if c.cfg.staticParams != nil {
 		cfg.staticParams = maps.Clone(c.cfg.staticParams.toMap())
 	}
@@ -87,6 +87,7 @@ type invocationConfig struct {
 	annotationHandler   AnnotationHandler
 	collectionResolver  xpath3.CollectionResolver
 	uriResolver         xpath3.URIResolver
+	resourceResolver    helium.ResourceResolver
 	httpClient          *http.Client
 	baseOutputURI       string
 	sourceSchemas       []*xsd.Schema
@@ -305,6 +306,16 @@ func (inv Invocation) URIResolver(r xpath3.URIResolver) Invocation {
 	return inv
 }
 
+// ResourceResolver sets a [helium.ResourceResolver] used by the same
+// functions as [Invocation.URIResolver]. Unlike a URIResolver, it receives
+// the ctx of the transformation. It takes precedence over
+// Invocation.URIResolver.
+func (inv Invocation) ResourceResolver(r helium.ResourceResolver) Invocation {
+	inv = inv.clone()
+	inv.cfg.resourceResolver = r
+	return inv
+}
+
 // HTTPClient sets the HTTP client used to fetch http/https URIs for
 // fn:doc / fn:unparsed-text / fn:json-doc when no URIResolver is supplied.
 // The caller owns the client's transport, timeouts, and redirect policy.
@@ -425,7 +436,7 @@ func (inv Invocation) Do(ctx context.Context) (*helium.Document, error) {
 	if err := inv.validate(); err != nil {
 		return nil, err
 	}
-	tcfg := inv.toTransformConfig()
+	tcfg := inv.toTransformConfig(ctx)
 	doc, err := executeTransform(ctx, inv.cfg.source, inv.cfg.ss, tcfg)
 	inv.cfg.resolved.store(tcfg.resolvedOutputDef)
 	return doc, err
@@ -460,7 +471,7 @@ func (inv Invocation) WriteTo(ctx context.Context, w io.Writer) error {
 	if err := inv.validate(); err != nil {
 		return err
 	}
-	tcfg := inv.toTransformConfig()
+	tcfg := inv.toTransformConfig(ctx)
 	resultDoc, err := executeTransform(ctx, inv.cfg.source, inv.cfg.ss, tcfg)
 	inv.cfg.resolved.store(tcfg.resolvedOutputDef)
 	if err != nil {
@@ -536,7 +547,7 @@ func (inv Invocation) validate() error {
 
 // toTransformConfig converts the Invocation config to the internal
 // transformConfig used by executeTransform.
-func (inv Invocation) toTransformConfig() *transformConfig {
+func (inv Invocation) toTransformConfig(ctx context.Context) *transformConfig {
 	c := inv.cfg
 	tcfg := &transformConfig{
 		collectionResolver: c.collectionResolver,
@@ -547,6 +558,9 @@ func (inv Invocation) toTransformConfig() *transformConfig {
 		onMultipleMatch:    c.onMultipleMatch.String(),
 		traceWriter:        c.traceWriter,
 	}
+	if c.resourceResolver != nil {
+		tcfg.uriResolver = resourceURIResolver{ctx: ctx, r: c.resourceResolver}
+	}
 
 	// Resource cap: a non-zero explicit per-invocation setting wins; an explicit
 	// 0 (or no setting at all) inherits the cap configured on the Compiler
@@ -1,6 +1,7 @@
 package xslt3
 
 import (
+	"context"
 	"io"
 	"net/http"
 
@@ -27,6 +28,43 @@ func (a xpathURIResolverAdapter) ResolveURI(uri string) (io.ReadCloser, error) {
 	return a.r.Resolve(uri)
 }
 
+// resourceURIResolver adapts a [helium.ResourceResolver] to both a
+// [URIResolver] and an [xpath3.URIResolver] for one Compile or transformation
+// call, whose ctx it carries.
+type resourceURIResolver struct {
+	ctx context.Context //nolint:containedctx // scoped to a single Compile or transformation call
+	r   helium.ResourceResolver
+}
+
+func (a resourceURIResolver) Resolve(uri string) (io.ReadCloser, error) {
+	res, err := a.r.ResolveResource(a.ctx, uri)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // callers wrap with the URI for context
+	}
+	return res, nil
+}
+
+func (a resourceURIResolver) ResolveURI(uri string) (io.ReadCloser, error) {
+	return a.Resolve(uri)
+}
+
+// uriResourceResolver adapts a [URIResolver] to a [helium.ResourceResolver],
+// so a [helium.ResourceCache] can sit in front of it.
+type uriResourceResolver struct {
+	r URIResolver
+}
+
+func (a uriResourceResolver) ResolveResource(_ context.Context, uri string) (*helium.Resource, error) {
+	rc, err := a.r.Resolve(uri)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // callers wrap with the URI for context
+	}
+	if res, ok := rc.(*helium.Resource); ok {
+		return res, nil
+	}
+	return &helium.Resource{ReadCloser: rc, Size: -1}, nil
+}
+
 // PackageResolver resolves package name URIs to file paths or readers.
 // Used during compilation when xsl:use-package is encountered.
 type PackageResolver interface {
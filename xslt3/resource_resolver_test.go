@@ -0,0 +1,78 @@
+package xslt3_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/lestrrat-go/helium"
+	"github.com/lestrrat-go/helium/xslt3"
+	"github.com/stretchr/testify/require"
+)
+
+type resourceCtxKey struct{}
+
+// TestResourceResolver verifies that a helium.ResourceResolver loads
+// stylesheet modules at compile time and fn:unparsed-text resources at
+// transformation time, each under the ctx of the call that triggered the load.
+func TestResourceResolver(t *testing.T) {
+	t.Parallel()
+
+	mem := helium.MemoryResourceResolver(map[string][]byte{
+		"mem://pkg/inc.xsl": []byte(`<xsl:stylesheet version="3.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+  <xsl:template match="/"><out><xsl:value-of select="unparsed-text('data.txt')"/></out></xsl:template>
+</xsl:stylesheet>`),
+		"mem://pkg/data.txt": []byte("payload"),
+	})
+	var mu sync.Mutex
+	seen := map[string]any{}
+	r := helium.ResourceResolverFunc(func(ctx context.Context, uri string) (*helium.Resource, error) {
+		mu.Lock()
+		seen[uri] = ctx.Value(resourceCtxKey{})
+		mu.Unlock()
+		return mem.ResolveResource(ctx, uri)
+	})
+
+	doc, err := helium.NewParser().Parse(t.Context(), []byte(
+		`<xsl:stylesheet version="3.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform"><xsl:include href="inc.xsl"/></xsl:stylesheet>`))
+	require.NoError(t, err)
+	ss, err := xslt3.NewCompiler().
+		BaseURI("mem://pkg/main.xsl").
+		ResourceResolver(r).
+		Compile(context.WithValue(t.Context(), resourceCtxKey{}, "compile"), doc)
+	require.NoError(t, err)
+
+	out, err := ss.Transform(parseTransformSource(t)).
+		ResourceResolver(r).
+		Serialize(context.WithValue(t.Context(), resourceCtxKey{}, "transform"))
+	require.NoError(t, err)
+	require.Contains(t, out, "<out>payload</out>")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, map[string]any{
+		"mem://pkg/inc.xsl":  "compile",
+		"mem://pkg/data.txt": "transform",
+	}, seen)
+}
+
+// TestCompilerResourceCache verifies that a helium.ResourceCache shared by
+// compiles serves a stylesheet module loaded once.
+func TestCompilerResourceCache(t *testing.T) {
+	t.Parallel()
+
+	resolver := &recordingCompileResolver{files: map[string][]byte{"mem://pkg/inc.xsl": []byte(childModule)}}
+	compiler := xslt3.NewCompiler().
+		BaseURI("mem://pkg/main.xsl").
+		URIResolver(resolver).
+		ResourceCache(helium.NewResourceCache(0, 0))
+
+	for range 3 {
+		doc, err := helium.NewParser().Parse(t.Context(), []byte(
+			`<xsl:stylesheet version="3.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform"><xsl:include href="inc.xsl"/></xsl:stylesheet>`))
+		require.NoError(t, err)
+		_, err = compiler.Compile(t.Context(), doc)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []string{"mem://pkg/inc.xsl"}, resolver.requests)
+}